@@ -32,6 +32,16 @@ type IndexConfig struct {
 	AttrsToIndex []IndexableAttr
 }
 
+// Contains returns true iff the given attribute is present in `AttrsToIndex`
+func (c *IndexConfig) Contains(indexableAttr IndexableAttr) bool {
+	for _, a := range c.AttrsToIndex {
+		if a == indexableAttr {
+			return true
+		}
+	}
+	return false
+}
+
 var (
 	// ErrNotFoundInIndex is used to indicate missing entry in the index
 	ErrNotFoundInIndex = l.NotFoundInIndexErr("")
@@ -40,6 +50,19 @@ var (
 	ErrAttrNotIndexed = errors.New("attribute not indexed")
 )
 
+// SnapshotInfo holds the details of the last block of a ledger snapshot, from which a block store is bootstrapped
+type SnapshotInfo struct {
+	LastBlockNum      uint64
+	LastBlockHash     []byte
+	PreviousBlockHash []byte
+}
+
+// TxIDsIterator iterates over the ids of the transactions that are committed before a ledger snapshot. An empty id is
+// returned once all the ids are consumed
+type TxIDsIterator interface {
+	Next() (string, error)
+}
+
 // BlockStoreProvider provides an handle to a BlockStore
 type BlockStoreProvider interface {
 	CreateBlockStore(ledgerid string) (BlockStore, error)
@@ -34,7 +34,7 @@ type blockfileStream struct {
 // it starts from a given file offset and continues with the next
 // file segment until the end of the last segment (`endFileNum`)
 type blockStream struct {
-	rootDir           string
+	openFileStream    func(fileNum int, startOffset int64) (*blockfileStream, error)
 	currentFileNum    int
 	endFileNum        int
 	currentFileStream *blockfileStream
@@ -52,7 +52,12 @@ type blockPlacementInfo struct {
 // blockfileStream functions
 ////////////////////////////////////
 func newBlockfileStream(rootDir string, fileNum int, startOffset int64) (*blockfileStream, error) {
-	filePath := deriveBlockfilePath(rootDir, fileNum)
+	return newBlockfileStreamForPath(deriveBlockfilePath(rootDir, fileNum), fileNum, startOffset)
+}
+
+// newBlockfileStreamForPath opens a stream on the block file with the given number that is present at the given path,
+// which differs from the path derived from the ledger's directory for a block file that is fetched from the archive
+func newBlockfileStreamForPath(filePath string, fileNum int, startOffset int64) (*blockfileStream, error) {
 	logger.Debugf("newBlockfileStream(): filePath=[%s], startOffset=[%d]", filePath, startOffset)
 	var file *os.File
 	var err error
@@ -146,11 +151,19 @@ func (s *blockfileStream) close() error {
 // blockStream functions
 ////////////////////////////////////
 func newBlockStream(rootDir string, startFileNum int, startOffset int64, endFileNum int) (*blockStream, error) {
-	startFileStream, err := newBlockfileStream(rootDir, startFileNum, startOffset)
+	return newBlockStreamWithOpener(func(fileNum int, startOffset int64) (*blockfileStream, error) {
+		return newBlockfileStream(rootDir, fileNum, startOffset)
+	}, startFileNum, startOffset, endFileNum)
+}
+
+// newBlockStreamWithOpener constructs a blockStream that opens each of the block files via the given function
+func newBlockStreamWithOpener(openFileStream func(fileNum int, startOffset int64) (*blockfileStream, error),
+	startFileNum int, startOffset int64, endFileNum int) (*blockStream, error) {
+	startFileStream, err := openFileStream(startFileNum, startOffset)
 	if err != nil {
 		return nil, err
 	}
-	return &blockStream{rootDir, startFileNum, endFileNum, startFileStream}, nil
+	return &blockStream{openFileStream, startFileNum, endFileNum, startFileStream}, nil
 }
 
 func (s *blockStream) moveToNextBlockfileStream() error {
@@ -159,7 +172,7 @@ func (s *blockStream) moveToNextBlockfileStream() error {
 		return err
 	}
 	s.currentFileNum++
-	if s.currentFileStream, err = newBlockfileStream(s.rootDir, s.currentFileNum, 0); err != nil {
+	if s.currentFileStream, err = s.openFileStream(s.currentFileNum, 0); err != nil {
 		return err
 	}
 	return nil
@@ -0,0 +1,293 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fsblkstorage
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/hyperledger/fabric/common/ledger/util"
+	"github.com/pkg/errors"
+)
+
+const (
+	// fetchedDirName is the name of the directory, within the directory of a ledger, that holds the block files
+	// fetched back from the archive
+	fetchedDirName = "fetched"
+	// maxFetchedBlockfiles is the number of the fetched block files that are kept locally, the least recently fetched
+	// ones being removed first
+	maxFetchedBlockfiles = 4
+)
+
+// ArchiveStore is an object store (e.g., S3, GCS, or MinIO) to which the older block files are archived. The names of the
+// objects are of the form `<ledgerid>/blockfile_<num>`
+type ArchiveStore interface {
+	// Upload stores the contents of the local file at the given path as the object with the given name, replacing the
+	// existing object, if any
+	Upload(name string, localPath string) error
+	// Download writes the contents of the object with the given name to the local file at the given path
+	Download(name string, localPath string) error
+}
+
+// NewDirArchiveStore returns an `ArchiveStore` that keeps the objects as the files under the given directory. This is
+// intended for an object store that is mounted as a file system (e.g., via s3fs or gcsfuse) and for the tests
+func NewDirArchiveStore(dir string) ArchiveStore {
+	return &dirArchiveStore{dir}
+}
+
+type dirArchiveStore struct {
+	dir string
+}
+
+func (s *dirArchiveStore) Upload(name string, localPath string) error {
+	objectPath := filepath.Join(s.dir, name)
+	if err := os.MkdirAll(filepath.Dir(objectPath), 0755); err != nil {
+		return errors.Wrapf(err, "error creating the archive dir for object [%s]", name)
+	}
+	// write to a temporary file first, so that a failed upload does not leave a partial object
+	tmpPath := objectPath + ".tmp"
+	if err := copyFile(localPath, tmpPath); err != nil {
+		return err
+	}
+	return errors.Wrapf(os.Rename(tmpPath, objectPath), "error renaming the archived object [%s]", name)
+}
+
+func (s *dirArchiveStore) Download(name string, localPath string) error {
+	return copyFile(filepath.Join(s.dir, name), localPath)
+}
+
+func copyFile(srcPath, destPath string) error {
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return errors.Wrapf(err, "error opening file [%s]", srcPath)
+	}
+	defer src.Close()
+	dest, err := os.OpenFile(destPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0660)
+	if err != nil {
+		return errors.Wrapf(err, "error creating file [%s]", destPath)
+	}
+	defer dest.Close()
+	if _, err := io.Copy(dest, src); err != nil {
+		return errors.Wrapf(err, "error copying file [%s] to [%s]", srcPath, destPath)
+	}
+	return errors.Wrapf(dest.Sync(), "error syncing file [%s]", destPath)
+}
+
+// blockfileArchiver archives the older block files of a ledger in the background and fetches them back on demand
+type blockfileArchiver struct {
+	ledgerID   string
+	conf       *ArchiveConf
+	fetchedDir string
+	// lock is held in the read mode while opening a local block file and in the write mode while removing a local
+	// block file
+	lock sync.RWMutex
+	// fetchLock serializes the fetching of the block files from the archive, along with the opening and the eviction of
+	// the fetched block files. This does not block the reads of the local block files
+	fetchLock sync.Mutex
+	// archiveLock serializes the archiving passes
+	archiveLock     sync.Mutex
+	nextFileToCheck int
+	trigger         chan struct{}
+	done            chan struct{}
+	wg              sync.WaitGroup
+}
+
+func newBlockfileArchiver(ledgerID, rootDir string, conf *ArchiveConf) (*blockfileArchiver, error) {
+	fetchedDir := filepath.Join(rootDir, fetchedDirName)
+	if _, err := util.CreateDirIfMissing(fetchedDir); err != nil {
+		return nil, errors.Wrapf(err, "error creating the dir [%s]", fetchedDir)
+	}
+	return &blockfileArchiver{
+		ledgerID:   ledgerID,
+		conf:       conf,
+		fetchedDir: fetchedDir,
+		trigger:    make(chan struct{}, 1),
+		done:       make(chan struct{}),
+	}, nil
+}
+
+// start starts the background goroutine that archives the eligible block files whenever triggered
+func (a *blockfileArchiver) start(mgr *blockfileMgr) {
+	if a.conf.FetchOnly {
+		return
+	}
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		for {
+			select {
+			case <-a.trigger:
+				if err := mgr.archiveBlockfiles(); err != nil {
+					logger.Errorf("Error while archiving the block files of ledger [%s]: %+v", a.ledgerID, err)
+				}
+			case <-a.done:
+				return
+			}
+		}
+	}()
+	a.triggerArchiving()
+}
+
+// triggerArchiving notifies the background goroutine without blocking. A notification that arrives while one is still
+// pending is dropped, as the pending one covers it
+func (a *blockfileArchiver) triggerArchiving() {
+	select {
+	case a.trigger <- struct{}{}:
+	default:
+	}
+}
+
+func (a *blockfileArchiver) stop() {
+	close(a.done)
+	a.wg.Wait()
+}
+
+func (a *blockfileArchiver) objectName(fileNum int) string {
+	return a.ledgerID + "/" + blockfilePrefix + fmt.Sprintf("%06d", fileNum)
+}
+
+// openBlockfile invokes the given function with the path of the block file with the given number, fetching the block file
+// from the archive if it is not present locally. The block file stays at the path until the function returns
+func (a *blockfileArchiver) openBlockfile(rootDir string, fileNum int, open func(filePath string) error) error {
+	localPath := deriveBlockfilePath(rootDir, fileNum)
+	a.lock.RLock()
+	exists, _, err := util.FileExists(localPath)
+	if err == nil && exists {
+		err = open(localPath)
+	}
+	a.lock.RUnlock()
+	if err != nil || exists {
+		return err
+	}
+
+	a.fetchLock.Lock()
+	defer a.fetchLock.Unlock()
+	fetchedPath := deriveBlockfilePath(a.fetchedDir, fileNum)
+	if exists, _, err = util.FileExists(fetchedPath); err != nil {
+		return err
+	}
+	if !exists {
+		if err := a.fetch(fileNum, fetchedPath); err != nil {
+			return err
+		}
+	}
+	return open(fetchedPath)
+}
+
+func (a *blockfileArchiver) fetch(fileNum int, fetchedPath string) error {
+	if err := a.evictFetchedBlockfiles(maxFetchedBlockfiles - 1); err != nil {
+		return err
+	}
+	logger.Debugf("Fetching block file [%d] of ledger [%s] from the archive", fileNum, a.ledgerID)
+	tmpPath := fetchedPath + ".tmp"
+	if err := a.conf.Store.Download(a.objectName(fileNum), tmpPath); err != nil {
+		os.Remove(tmpPath)
+		return errors.WithMessage(err, "error fetching the block file from the archive")
+	}
+	return errors.Wrapf(os.Rename(tmpPath, fetchedPath), "error renaming the fetched block file [%s]", tmpPath)
+}
+
+// evictFetchedBlockfiles removes the least recently fetched block files so that no more than the given number remain
+func (a *blockfileArchiver) evictFetchedBlockfiles(maxRemaining int) error {
+	filesInfo, err := ioutil.ReadDir(a.fetchedDir)
+	if err != nil {
+		return errors.Wrapf(err, "error reading dir [%s]", a.fetchedDir)
+	}
+	sort.Slice(filesInfo, func(i, j int) bool { return filesInfo[i].ModTime().Before(filesInfo[j].ModTime()) })
+	for i := 0; i < len(filesInfo)-maxRemaining; i++ {
+		if err := os.Remove(filepath.Join(a.fetchedDir, filesInfo[i].Name())); err != nil {
+			return errors.Wrapf(err, "error removing the fetched block file [%s]", filesInfo[i].Name())
+		}
+	}
+	return nil
+}
+
+// archiveBlockfiles uploads to the archive, and removes locally, the block files that hold only the blocks older than the
+// most recent `ArchiveConf.RetainedBlocks` blocks. The block file that holds the oldest of the retained blocks is determined
+// via the index, which therefore is required to include `IndexableAttrBlockNum`
+func (mgr *blockfileMgr) archiveBlockfiles() error {
+	a := mgr.archiver
+	if a.conf.FetchOnly {
+		return nil
+	}
+	a.archiveLock.Lock()
+	defer a.archiveLock.Unlock()
+
+	height := mgr.getBlockchainInfo().Height
+	if height == 0 || height <= a.conf.RetainedBlocks {
+		return nil
+	}
+	oldestRetainedBlockNum := height - a.conf.RetainedBlocks
+	if a.conf.RetainedBlocks == 0 {
+		oldestRetainedBlockNum = height - 1
+	}
+	loc, err := mgr.index.getBlockLocByBlockNum(oldestRetainedBlockNum)
+	if err != nil {
+		return errors.WithMessage(err, "error finding the block file of the oldest retained block")
+	}
+
+	for ; a.nextFileToCheck < loc.fileSuffixNum; a.nextFileToCheck++ {
+		localPath := deriveBlockfilePath(mgr.rootDir, a.nextFileToCheck)
+		exists, _, err := util.FileExists(localPath)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			// archived already
+			continue
+		}
+		if err := a.conf.Store.Upload(a.objectName(a.nextFileToCheck), localPath); err != nil {
+			return errors.WithMessage(err, "error uploading the block file to the archive")
+		}
+		a.lock.Lock()
+		err = os.Remove(localPath)
+		a.lock.Unlock()
+		if err != nil {
+			return errors.Wrapf(err, "error removing the archived block file [%s]", localPath)
+		}
+		logger.Infof("Archived block file [%d] of ledger [%s]", a.nextFileToCheck, a.ledgerID)
+	}
+	return nil
+}
+
+// openBlockfileStream opens a stream on the block file with the given number, which may have been archived
+func (mgr *blockfileMgr) openBlockfileStream(fileNum int, startOffset int64) (*blockfileStream, error) {
+	if mgr.archiver == nil {
+		return newBlockfileStream(mgr.rootDir, fileNum, startOffset)
+	}
+	var stream *blockfileStream
+	err := mgr.archiver.openBlockfile(mgr.rootDir, fileNum, func(filePath string) error {
+		var err error
+		stream, err = newBlockfileStreamForPath(filePath, fileNum, startOffset)
+		return err
+	})
+	return stream, err
+}
+
+// openBlockfileReader opens a reader on the block file with the given number, which may have been archived
+func (mgr *blockfileMgr) openBlockfileReader(fileNum int) (*blockfileReader, error) {
+	if mgr.archiver == nil {
+		return newBlockfileReader(deriveBlockfilePath(mgr.rootDir, fileNum))
+	}
+	var reader *blockfileReader
+	err := mgr.archiver.openBlockfile(mgr.rootDir, fileNum, func(filePath string) error {
+		var err error
+		reader, err = newBlockfileReader(filePath)
+		return err
+	})
+	return reader, err
+}
+
+// newBlockStream constructs a blockStream that reads the block files, which may have been archived, starting at the given file
+func (mgr *blockfileMgr) newBlockStream(startFileNum int, startOffset int64, endFileNum int) (*blockStream, error) {
+	return newBlockStreamWithOpener(mgr.openBlockfileStream, startFileNum, startOffset, endFileNum)
+}
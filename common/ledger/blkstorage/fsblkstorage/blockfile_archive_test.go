@@ -0,0 +1,173 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fsblkstorage
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/hyperledger/fabric/common/ledger/testutil"
+	"github.com/hyperledger/fabric/common/ledger/util"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlockfileArchive(t *testing.T) {
+	archiveDir, err := ioutil.TempDir("", "fsblkstorage-archive")
+	assert.NoError(t, err)
+	defer os.RemoveAll(archiveDir)
+	// a max block file size of one byte places every block in a block file of its own, starting with the file number 1
+	conf := NewConfWithArchive(testPath(), 1, &ArchiveConf{Store: NewDirArchiveStore(archiveDir), RetainedBlocks: 3})
+	env := newTestEnv(t, conf)
+	defer env.Cleanup()
+	w := newTestBlockfileWrapper(env, "testLedger")
+	blocks := testutil.ConstructTestBlocks(t, 10)
+	w.addBlocks(blocks)
+	assert.NoError(t, w.blockfileMgr.archiveBlockfiles())
+
+	// the blocks 7, 8, and 9 are retained in the block files 8, 9, and 10
+	for fileNum := 0; fileNum <= 10; fileNum++ {
+		exists, _, err := util.FileExists(deriveBlockfilePath(w.blockfileMgr.rootDir, fileNum))
+		assert.NoError(t, err)
+		assert.Equal(t, fileNum >= 8, exists, "local block file [%d]", fileNum)
+		exists, _, err = util.FileExists(filepath.Join(archiveDir, "testLedger", filepath.Base(deriveBlockfilePath("", fileNum))))
+		assert.NoError(t, err)
+		assert.Equal(t, fileNum < 8, exists, "archived block file [%d]", fileNum)
+	}
+
+	// the archived blocks are retrieved transparently
+	w.testGetBlockByNumber(blocks, 0)
+	w.testGetBlockByHash(blocks)
+	for _, block := range blocks {
+		txEnv, err := w.blockfileMgr.retrieveTransactionByBlockNumTranNum(block.Header.Number, 0)
+		assert.NoError(t, err)
+		assert.NotNil(t, txEnv)
+	}
+	verifyBlocksItr(t, w.blockfileMgr, blocks)
+	fetchedFiles, err := ioutil.ReadDir(filepath.Join(w.blockfileMgr.rootDir, fetchedDirName))
+	assert.NoError(t, err)
+	assert.Len(t, fetchedFiles, maxFetchedBlockfiles)
+
+	// archiving again is a no-op and the archived block files remain retrievable after reopening the block store
+	assert.NoError(t, w.blockfileMgr.archiveBlockfiles())
+	w.close()
+	env.provider.Close()
+	env = newTestEnv(t, conf)
+	w = newTestBlockfileWrapper(env, "testLedger")
+	defer w.close()
+	w.testGetBlockByNumber(blocks, 0)
+
+	// a block file that is missing in the archive results in an error
+	assert.NoError(t, w.blockfileMgr.archiver.evictFetchedBlockfiles(0))
+	assert.NoError(t, os.Remove(filepath.Join(archiveDir, "testLedger", filepath.Base(deriveBlockfilePath("", 1)))))
+	_, err = w.blockfileMgr.retrieveBlockByNumber(0)
+	assert.Contains(t, err.Error(), "error fetching the block file from the archive")
+	w.testGetBlockByNumber(blocks[1:], 1)
+}
+
+func TestBlockfileArchiveFetchOnly(t *testing.T) {
+	archiveDir, err := ioutil.TempDir("", "fsblkstorage-archive")
+	assert.NoError(t, err)
+	defer os.RemoveAll(archiveDir)
+	blockStorageDir := testPath()
+	conf := NewConfWithArchive(blockStorageDir, 1, &ArchiveConf{Store: NewDirArchiveStore(archiveDir), RetainedBlocks: 3})
+	env := newTestEnv(t, conf)
+	defer env.Cleanup()
+	w := newTestBlockfileWrapper(env, "testLedger")
+	blocks := testutil.ConstructTestBlocks(t, 10)
+	w.addBlocks(blocks)
+	assert.NoError(t, w.blockfileMgr.archiveBlockfiles())
+	w.close()
+	env.provider.Close()
+
+	// the block files archived already are fetched but no more block files are archived
+	conf = NewConfWithArchive(blockStorageDir, 1, &ArchiveConf{Store: NewDirArchiveStore(archiveDir), RetainedBlocks: 1, FetchOnly: true})
+	env = newTestEnv(t, conf)
+	w = newTestBlockfileWrapper(env, "testLedger")
+	defer w.close()
+	assert.NoError(t, w.blockfileMgr.archiveBlockfiles())
+	for fileNum := 8; fileNum <= 10; fileNum++ {
+		exists, _, err := util.FileExists(deriveBlockfilePath(w.blockfileMgr.rootDir, fileNum))
+		assert.NoError(t, err)
+		assert.True(t, exists, "local block file [%d]", fileNum)
+	}
+	w.testGetBlockByNumber(blocks, 0)
+}
+
+func TestBlockfileArchiveFetchDoesNotBlockLocalReads(t *testing.T) {
+	archiveDir, err := ioutil.TempDir("", "fsblkstorage-archive")
+	assert.NoError(t, err)
+	defer os.RemoveAll(archiveDir)
+	store := &blockingArchiveStore{
+		ArchiveStore:      NewDirArchiveStore(archiveDir),
+		downloadStarted:   make(chan struct{}),
+		downloadUnblocked: make(chan struct{}),
+	}
+	conf := NewConfWithArchive(testPath(), 1, &ArchiveConf{Store: store, RetainedBlocks: 3})
+	env := newTestEnv(t, conf)
+	defer env.Cleanup()
+	w := newTestBlockfileWrapper(env, "testLedger")
+	defer w.close()
+	blocks := testutil.ConstructTestBlocks(t, 10)
+	w.addBlocks(blocks)
+	assert.NoError(t, w.blockfileMgr.archiveBlockfiles())
+
+	fetchDone := make(chan error, 1)
+	go func() {
+		_, err := w.blockfileMgr.retrieveBlockByNumber(0)
+		fetchDone <- err
+	}()
+	<-store.downloadStarted
+	// the local block files remain readable while the archived block file is being downloaded
+	block, err := w.blockfileMgr.retrieveBlockByNumber(9)
+	assert.NoError(t, err)
+	assert.Equal(t, blocks[9], block)
+	close(store.downloadUnblocked)
+	assert.NoError(t, <-fetchDone)
+}
+
+func TestBlockfileArchiverInitError(t *testing.T) {
+	conf := NewConfWithArchive(testPath(), 1, &ArchiveConf{Store: NewDirArchiveStore(testPath())})
+	env := newTestEnv(t, conf)
+	defer env.Cleanup()
+	// a file in place of the dir for the fetched block files fails the initialization of the archiver
+	ledgerDir := conf.getLedgerBlockDir("testLedger")
+	assert.NoError(t, os.MkdirAll(ledgerDir, 0755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(ledgerDir, fetchedDirName), nil, 0644))
+	_, err := env.provider.OpenBlockStore("testLedger")
+	assert.Contains(t, err.Error(), "error initializing the block file archiver")
+}
+
+// blockingArchiveStore blocks the first download until `downloadUnblocked` is closed
+type blockingArchiveStore struct {
+	ArchiveStore
+	downloadStarted   chan struct{}
+	downloadUnblocked chan struct{}
+	once              sync.Once
+}
+
+func (s *blockingArchiveStore) Download(name string, localPath string) error {
+	s.once.Do(func() {
+		close(s.downloadStarted)
+		<-s.downloadUnblocked
+	})
+	return s.ArchiveStore.Download(name, localPath)
+}
+
+func verifyBlocksItr(t *testing.T, mgr *blockfileMgr, expectedBlocks []*common.Block) {
+	itr, err := mgr.retrieveBlocks(0)
+	assert.NoError(t, err)
+	defer itr.Close()
+	for _, expectedBlock := range expectedBlocks {
+		block, err := itr.Next()
+		assert.NoError(t, err)
+		assert.Equal(t, expectedBlock, block)
+	}
+}
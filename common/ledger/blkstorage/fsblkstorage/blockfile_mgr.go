@@ -44,6 +44,9 @@ type blockfileMgr struct {
 	cpInfoCond        *sync.Cond
 	currentFileWriter *blockfileWriter
 	bcInfo            atomic.Value
+	archiver          *blockfileArchiver
+	// bootstrappingSnapshotInfo is the info of the snapshot from which the block store was bootstrapped, if any
+	bootstrappingSnapshotInfo *blkstorage.SnapshotInfo
 }
 
 /*
@@ -87,7 +90,7 @@ At start up a new manager:
 		-- If index and file system are not in sync, syncs index from the FS
   *)  Updates blockchain info used by the APIs
 */
-func newBlockfileMgr(id string, conf *Conf, indexConfig *blkstorage.IndexConfig, indexStore *leveldbhelper.DBHandle) *blockfileMgr {
+func newBlockfileMgr(id string, conf *Conf, indexConfig *blkstorage.IndexConfig, indexStore *leveldbhelper.DBHandle) (*blockfileMgr, error) {
 	logger.Debugf("newBlockfileMgr() initializing file-based block storage for ledger: %s ", id)
 	//Determine the root directory for the blockfile storage, if it does not exist create it
 	rootDir := conf.getLedgerBlockDir(id)
@@ -97,6 +100,11 @@ func newBlockfileMgr(id string, conf *Conf, indexConfig *blkstorage.IndexConfig,
 	}
 	// Instantiate the manager, i.e. blockFileMgr structure
 	mgr := &blockfileMgr{rootDir: rootDir, conf: conf, db: indexStore}
+	if conf.archiveConf != nil {
+		if mgr.archiver, err = newBlockfileArchiver(id, rootDir, conf.archiveConf); err != nil {
+			return nil, errors.WithMessage(err, "error initializing the block file archiver")
+		}
+	}
 
 	// cp = checkpointInfo, retrieve from the database the file suffix or number of where blocks were stored.
 	// It also retrieves the current size of that file and the last block number that was written to that file.
@@ -105,6 +113,9 @@ func newBlockfileMgr(id string, conf *Conf, indexConfig *blkstorage.IndexConfig,
 	if err != nil {
 		panic(fmt.Sprintf("Could not get block file info for current block file from db: %s", err))
 	}
+	if mgr.bootstrappingSnapshotInfo, err = mgr.loadBootstrappingSnapshotInfo(); err != nil {
+		panic(fmt.Sprintf("Could not get bootstrapping snapshot info from db: %s", err))
+	}
 	if cpInfo == nil {
 		logger.Info(`Getting block information from block storage`)
 		if cpInfo, err = constructCheckpointInfoFromBlockFiles(rootDir); err != nil {
@@ -113,7 +124,7 @@ func newBlockfileMgr(id string, conf *Conf, indexConfig *blkstorage.IndexConfig,
 		logger.Debugf("Info constructed by scanning the blocks dir = %s", spew.Sdump(cpInfo))
 	} else {
 		logger.Debug(`Synching block information from block storage (if needed)`)
-		syncCPInfoFromFS(rootDir, cpInfo)
+		syncCPInfoFromFS(rootDir, cpInfo, mgr.bootstrappingSnapshotInfo != nil)
 	}
 	err = mgr.saveCurrentInfo(cpInfo, true)
 	if err != nil {
@@ -163,16 +174,26 @@ func newBlockfileMgr(id string, conf *Conf, indexConfig *blkstorage.IndexConfig,
 			Height:            cpInfo.lastBlockNumber + 1,
 			CurrentBlockHash:  lastBlockHash,
 			PreviousBlockHash: previousBlockHash}
+	} else if snapshotInfo := mgr.bootstrappingSnapshotInfo; snapshotInfo != nil {
+		//If the block store is bootstrapped from a snapshot and no block is added yet, the last block is the one of the snapshot
+		bcInfo = &common.BlockchainInfo{
+			Height:            snapshotInfo.LastBlockNum + 1,
+			CurrentBlockHash:  snapshotInfo.LastBlockHash,
+			PreviousBlockHash: snapshotInfo.PreviousBlockHash}
 	}
 	mgr.bcInfo.Store(bcInfo)
-	return mgr
+	if mgr.archiver != nil {
+		mgr.archiver.start(mgr)
+	}
+	return mgr, nil
 }
 
 //cp = checkpointInfo, from the database gets the file suffix and the size of
 // the file of where the last block was written.  Also retrieves contains the
 // last block number that was written.  At init
 //checkpointInfo:latestFileChunkSuffixNum=[0], latestFileChunksize=[0], lastBlockNumber=[0]
-func syncCPInfoFromFS(rootDir string, cpInfo *checkpointInfo) {
+//For a block store bootstrapped from a snapshot, the empty chain holds the last block of the snapshot as the last block number
+func syncCPInfoFromFS(rootDir string, cpInfo *checkpointInfo, bootstrappedFromSnapshot bool) {
 	logger.Debugf("Starting checkpoint=%s", cpInfo)
 	//Checks if the file suffix of where the last block was written exists
 	filePath := deriveBlockfilePath(rootDir, cpInfo.latestFileChunkSuffixNum)
@@ -199,7 +220,7 @@ func syncCPInfoFromFS(rootDir string, cpInfo *checkpointInfo) {
 		return
 	}
 	//Updates the checkpoint info for the actual last block number stored and it's end location
-	if cpInfo.isChainEmpty {
+	if cpInfo.isChainEmpty && !bootstrappedFromSnapshot {
 		cpInfo.lastBlockNumber = uint64(numBlocks - 1)
 	} else {
 		cpInfo.lastBlockNumber += uint64(numBlocks)
@@ -213,6 +234,9 @@ func deriveBlockfilePath(rootDir string, suffixNum int) string {
 }
 
 func (mgr *blockfileMgr) close() {
+	if mgr.archiver != nil {
+		mgr.archiver.stop()
+	}
 	mgr.currentFileWriter.close()
 }
 
@@ -322,6 +346,9 @@ func (mgr *blockfileMgr) addBlock(block *common.Block) error {
 	//update the checkpoint info (for storage) and the blockchain info (for APIs) in the manager
 	mgr.updateCheckpoint(newCPInfo)
 	mgr.updateBlockchainInfo(blockHash, block)
+	if mgr.archiver != nil {
+		mgr.archiver.triggerArchiving()
+	}
 	return nil
 }
 
@@ -368,7 +395,7 @@ func (mgr *blockfileMgr) syncIndex() error {
 
 	//open a blockstream to the file location that was stored in the index
 	var stream *blockStream
-	if stream, err = newBlockStream(mgr.rootDir, startFileNum, int64(startOffset), endFileNum); err != nil {
+	if stream, err = mgr.newBlockStream(startFileNum, int64(startOffset), endFileNum); err != nil {
 		return err
 	}
 	var blockBytes []byte
@@ -552,7 +579,7 @@ func (mgr *blockfileMgr) fetchTransactionEnvelope(lp *fileLocPointer) (*common.E
 }
 
 func (mgr *blockfileMgr) fetchBlockBytes(lp *fileLocPointer) ([]byte, error) {
-	stream, err := newBlockfileStream(mgr.rootDir, lp.fileSuffixNum, int64(lp.offset))
+	stream, err := mgr.openBlockfileStream(lp.fileSuffixNum, int64(lp.offset))
 	if err != nil {
 		return nil, err
 	}
@@ -565,8 +592,7 @@ func (mgr *blockfileMgr) fetchBlockBytes(lp *fileLocPointer) ([]byte, error) {
 }
 
 func (mgr *blockfileMgr) fetchRawBytes(lp *fileLocPointer) ([]byte, error) {
-	filePath := deriveBlockfilePath(mgr.rootDir, lp.fileSuffixNum)
-	reader, err := newBlockfileReader(filePath)
+	reader, err := mgr.openBlockfileReader(lp.fileSuffixNum)
 	if err != nil {
 		return nil, err
 	}
@@ -14,6 +14,7 @@ import (
 	"github.com/hyperledger/fabric/common/ledger/blkstorage"
 	"github.com/hyperledger/fabric/common/ledger/util"
 	"github.com/hyperledger/fabric/common/ledger/util/leveldbhelper"
+	"github.com/hyperledger/fabric/core/ledger"
 	ledgerUtil "github.com/hyperledger/fabric/core/ledger/util"
 	"github.com/hyperledger/fabric/protos/common"
 	"github.com/hyperledger/fabric/protos/peer"
@@ -185,7 +186,8 @@ func (index *blockIndex) markDuplicateTxids(blockIdxInfo *blockIdxInfo) error {
 		}
 
 		loc, err := index.getTxLoc(txid)
-		if loc != nil { // txid is duplicate of a previous tx in the index
+		if _, isTxBeforeSnapshot := err.(ledger.TxBeforeSnapshotErr); loc != nil || isTxBeforeSnapshot {
+			// txid is duplicate of a previous tx in the index, including the ones that precede the bootstrapping snapshot
 			txIdxInfo.isDuplicate = true
 			continue
 		}
@@ -240,6 +242,9 @@ func (index *blockIndex) getTxLoc(txID string) (*fileLocPointer, error) {
 	if b == nil {
 		return nil, blkstorage.ErrNotFoundInIndex
 	}
+	if bytes.Equal(b, txIDFromSnapshotMarker) {
+		return nil, ledger.TxBeforeSnapshotErr(txID)
+	}
 	txFLP := &fileLocPointer{}
 	txFLP.unmarshal(b)
 	return txFLP, nil
@@ -56,7 +56,7 @@ func (itr *blocksItr) initStream() error {
 	if lp, err = itr.mgr.index.getBlockLocByBlockNum(itr.blockNumToRetrieve); err != nil {
 		return err
 	}
-	if itr.stream, err = newBlockStream(itr.mgr.rootDir, lp.fileSuffixNum, int64(lp.offset), -1); err != nil {
+	if itr.stream, err = itr.mgr.newBlockStream(lp.fileSuffixNum, int64(lp.offset), -1); err != nil {
 		return err
 	}
 	return nil
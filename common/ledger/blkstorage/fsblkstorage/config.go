@@ -30,6 +30,19 @@ const (
 type Conf struct {
 	blockStorageDir  string
 	maxBlockfileSize int
+	archiveConf      *ArchiveConf
+}
+
+// ArchiveConf encapsulates the configurations for archiving the older block files to an `ArchiveStore`
+type ArchiveConf struct {
+	// Store is where the block files are archived
+	Store ArchiveStore
+	// RetainedBlocks is the number of the most recent blocks that are always kept locally. A block file is archived and removed
+	// locally once all of its blocks are older than these. The block file that holds the last block is never archived
+	RetainedBlocks uint64
+	// FetchOnly disables the archiving, while the block files that are archived already are still fetched on demand. This is
+	// meant for the offline use, so that the block files are not moved while being read
+	FetchOnly bool
 }
 
 // NewConf constructs new `Conf`.
@@ -38,7 +51,14 @@ func NewConf(blockStorageDir string, maxBlockfileSize int) *Conf {
 	if maxBlockfileSize <= 0 {
 		maxBlockfileSize = defaultMaxBlockfileSize
 	}
-	return &Conf{blockStorageDir, maxBlockfileSize}
+	return &Conf{blockStorageDir, maxBlockfileSize, nil}
+}
+
+// NewConfWithArchive constructs new `Conf` that archives the older block files as per the given `ArchiveConf`
+func NewConfWithArchive(blockStorageDir string, maxBlockfileSize int, archiveConf *ArchiveConf) *Conf {
+	conf := NewConf(blockStorageDir, maxBlockfileSize)
+	conf.archiveConf = archiveConf
+	return conf
 }
 
 func (conf *Conf) getIndexDir() string {
@@ -33,8 +33,12 @@ type fsBlockStore struct {
 
 // NewFsBlockStore constructs a `FsBlockStore`
 func newFsBlockStore(id string, conf *Conf, indexConfig *blkstorage.IndexConfig,
-	dbHandle *leveldbhelper.DBHandle) *fsBlockStore {
-	return &fsBlockStore{id, conf, newBlockfileMgr(id, conf, indexConfig, dbHandle)}
+	dbHandle *leveldbhelper.DBHandle) (*fsBlockStore, error) {
+	fileMgr, err := newBlockfileMgr(id, conf, indexConfig, dbHandle)
+	if err != nil {
+		return nil, err
+	}
+	return &fsBlockStore{id, conf, fileMgr}, nil
 }
 
 // AddBlock adds a new block
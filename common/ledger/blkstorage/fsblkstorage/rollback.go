@@ -0,0 +1,145 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fsblkstorage
+
+import (
+	"os"
+
+	"github.com/hyperledger/fabric/common/ledger/blkstorage"
+	"github.com/hyperledger/fabric/common/ledger/util"
+	"github.com/hyperledger/fabric/common/ledger/util/leveldbhelper"
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/pkg/errors"
+)
+
+// RollbackToBlock removes the blocks after the given block from the block store of the given ledger, along with their entries
+// in the index. The index is required to include `IndexableAttrBlockNum`. The index and the checkpoint are updated first and
+// the block files are truncated afterwards; if the rollback is interrupted, it should be run again. For this reason, the block
+// files are truncated even when the given block is the last block as per the checkpoint. The block files that hold the removed
+// blocks are expected to be present locally, i.e., not archived. For a block store bootstrapped from a snapshot, the given block
+// is required to be after the last block of the snapshot. This is meant for the offline use, while the block store is not opened
+// otherwise
+func RollbackToBlock(conf *Conf, indexConfig *blkstorage.IndexConfig, ledgerID string, targetBlockNum uint64) error {
+	if !indexConfig.Contains(blkstorage.IndexableAttrBlockNum) {
+		return errors.Errorf("rollback requires the index [%s] to be enabled", blkstorage.IndexableAttrBlockNum)
+	}
+	dbProvider := leveldbhelper.NewProvider(&leveldbhelper.Conf{DBPath: conf.getIndexDir()})
+	defer dbProvider.Close()
+	// the archiving is not enabled for the rollback, so that it does not remove the block files being truncated
+	mgr, err := newBlockfileMgr(ledgerID, NewConf(conf.blockStorageDir, conf.maxBlockfileSize), indexConfig, dbProvider.GetDBHandle(ledgerID))
+	if err != nil {
+		return err
+	}
+	closed := false
+	defer func() {
+		if !closed {
+			mgr.close()
+		}
+	}()
+
+	height := mgr.getBlockchainInfo().Height
+	if height == 0 || targetBlockNum >= height {
+		return errors.Errorf("target block number [%d] should be less than the height [%d] of ledger [%s]",
+			targetBlockNum, height, ledgerID)
+	}
+	if snapshotInfo := mgr.bootstrappingSnapshotInfo; snapshotInfo != nil && targetBlockNum <= snapshotInfo.LastBlockNum {
+		return errors.Errorf("target block number [%d] should be greater than the last block number [%d] of the snapshot from which ledger [%s] was created",
+			targetBlockNum, snapshotInfo.LastBlockNum, ledgerID)
+	}
+	targetLoc, err := mgr.index.getBlockLocByBlockNum(targetBlockNum)
+	if err != nil {
+		return err
+	}
+	targetFilePath := deriveBlockfilePath(mgr.rootDir, targetLoc.fileSuffixNum)
+	exists, _, err := util.FileExists(targetFilePath)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errors.Errorf("block file [%s] of the target block is not present locally", targetFilePath)
+	}
+	stream, err := newBlockStream(mgr.rootDir, targetLoc.fileSuffixNum, int64(targetLoc.offset), mgr.cpInfo.latestFileChunkSuffixNum)
+	if err != nil {
+		return err
+	}
+	defer stream.close()
+	if _, err := stream.nextBlockBytes(); err != nil {
+		return err
+	}
+	targetEndOffset := stream.currentFileStream.currentOffset
+
+	batch := leveldbhelper.NewUpdateBatch()
+	for {
+		blockBytes, err := stream.nextBlockBytes()
+		if err != nil {
+			return err
+		}
+		if blockBytes == nil {
+			break
+		}
+		info, err := extractSerializedBlockInfo(blockBytes)
+		if err != nil {
+			return err
+		}
+		blockNum := info.blockHeader.Number
+		batch.Delete(constructBlockHashKey(info.blockHeader.Hash()))
+		batch.Delete(constructBlockNumKey(blockNum))
+		for txNum, txOffset := range info.txOffsets {
+			batch.Delete(constructBlockNumTranNumKey(blockNum, uint64(txNum)))
+			// the entries by the tx id are retained if they refer to an earlier block, i.e., for a duplicate tx id
+			txLoc, err := mgr.index.getTxLoc(txOffset.txID)
+			if _, isTxBeforeSnapshot := err.(ledger.TxBeforeSnapshotErr); isTxBeforeSnapshot ||
+				err == blkstorage.ErrNotFoundInIndex || err == blkstorage.ErrAttrNotIndexed {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if txLoc.fileSuffixNum < targetLoc.fileSuffixNum ||
+				(txLoc.fileSuffixNum == targetLoc.fileSuffixNum && int64(txLoc.offset) < targetEndOffset) {
+				continue
+			}
+			batch.Delete(constructTxIDKey(txOffset.txID))
+			batch.Delete(constructBlockTxIDKey(txOffset.txID))
+			batch.Delete(constructTxValidationCodeIDKey(txOffset.txID))
+		}
+	}
+	cpInfo := &checkpointInfo{
+		latestFileChunkSuffixNum: targetLoc.fileSuffixNum,
+		latestFileChunksize:      int(targetEndOffset),
+		isChainEmpty:             false,
+		lastBlockNumber:          targetBlockNum,
+	}
+	cpInfoBytes, err := cpInfo.marshal()
+	if err != nil {
+		return err
+	}
+	batch.Put(blkMgrInfoKey, cpInfoBytes)
+	batch.Put(indexCheckpointKey, encodeBlockNum(targetBlockNum))
+	if err := mgr.db.WriteBatch(batch, true); err != nil {
+		return err
+	}
+
+	mgr.close()
+	closed = true
+	// the later block files are found by listing the dir, as opposed to via the checkpoint, so that the ones left behind by
+	// an interrupted rollback are removed too
+	lastFileNum, err := retrieveLastFileSuffix(mgr.rootDir)
+	if err != nil {
+		return err
+	}
+	for fileNum := lastFileNum; fileNum > targetLoc.fileSuffixNum; fileNum-- {
+		if err := os.Remove(deriveBlockfilePath(mgr.rootDir, fileNum)); err != nil && !os.IsNotExist(err) {
+			return errors.Wrapf(err, "error removing block file [%d]", fileNum)
+		}
+	}
+	if err := os.Truncate(targetFilePath, targetEndOffset); err != nil {
+		return errors.Wrapf(err, "error truncating block file [%s]", targetFilePath)
+	}
+	logger.Infof("Rolled back block store of ledger [%s] from height [%d] to block [%d]", ledgerID, height, targetBlockNum)
+	return nil
+}
@@ -0,0 +1,134 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fsblkstorage
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hyperledger/fabric/common/ledger/blkstorage"
+	"github.com/hyperledger/fabric/common/ledger/testutil"
+	"github.com/hyperledger/fabric/common/ledger/util"
+	putil "github.com/hyperledger/fabric/protos/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRollbackToBlock(t *testing.T) {
+	for _, maxBlockfileSize := range []int{0, 1} {
+		conf := NewConf(testPath(), maxBlockfileSize)
+		env := newTestEnv(t, conf)
+		indexConfig := env.provider.indexConfig
+		w := newTestBlockfileWrapper(env, "testLedger")
+		blocks := testutil.ConstructTestBlocks(t, 10)
+		w.addBlocks(blocks)
+		w.close()
+		env.provider.Close()
+
+		assert.Contains(t, RollbackToBlock(conf, indexConfig, "testLedger", 10).Error(),
+			"target block number [10] should be less than the height [10] of ledger [testLedger]")
+		assert.NoError(t, RollbackToBlock(conf, indexConfig, "testLedger", 9))
+		assert.NoError(t, RollbackToBlock(conf, indexConfig, "testLedger", 4))
+		// running the rollback again has no further effect
+		assert.NoError(t, RollbackToBlock(conf, indexConfig, "testLedger", 4))
+
+		env = newTestEnv(t, conf)
+		w = newTestBlockfileWrapper(env, "testLedger")
+		assert.Equal(t, uint64(5), w.blockfileMgr.getBlockchainInfo().Height)
+		w.testGetBlockByNumber(blocks[:5], 0)
+		for _, block := range blocks[5:] {
+			_, err := w.blockfileMgr.retrieveBlockByHash(block.Header.Hash())
+			assert.Equal(t, blkstorage.ErrNotFoundInIndex, err)
+			_, err = w.blockfileMgr.retrieveTransactionByBlockNumTranNum(block.Header.Number, 0)
+			assert.Equal(t, blkstorage.ErrNotFoundInIndex, err)
+			txEnv, err := putil.GetEnvelopeFromBlock(block.Data.Data[0])
+			assert.NoError(t, err)
+			chdr, err := putil.ChannelHeader(txEnv)
+			assert.NoError(t, err)
+			_, err = w.blockfileMgr.retrieveTransactionByID(chdr.TxId)
+			assert.Equal(t, blkstorage.ErrNotFoundInIndex, err)
+		}
+		if maxBlockfileSize == 1 {
+			exists, _, err := util.FileExists(deriveBlockfilePath(w.blockfileMgr.rootDir, 6))
+			assert.NoError(t, err)
+			assert.False(t, exists)
+		}
+
+		// the rolled back blocks can be committed again
+		w.addBlocks(blocks[5:])
+		w.testGetBlockByNumber(blocks, 0)
+		w.testGetBlockByHash(blocks)
+		w.close()
+		env.Cleanup()
+	}
+}
+
+func TestRollbackToBlockAfterInterruption(t *testing.T) {
+	for _, maxBlockfileSize := range []int{0, 1} {
+		conf := NewConf(testPath(), maxBlockfileSize)
+		env := newTestEnv(t, conf)
+		indexConfig := env.provider.indexConfig
+		w := newTestBlockfileWrapper(env, "testLedger")
+		blocks := testutil.ConstructTestBlocks(t, 10)
+		w.addBlocks(blocks)
+		w.close()
+		env.provider.Close()
+
+		// simulate a rollback that is interrupted after updating the index and the checkpoint but before truncating the
+		// block files, by restoring the block files as they were before the rollback
+		blocksDir := conf.getLedgerBlockDir("testLedger")
+		backupDir, err := ioutil.TempDir("", "fsblkstorage-rollback")
+		assert.NoError(t, err)
+		copyBlockfiles(t, blocksDir, backupDir)
+		assert.NoError(t, RollbackToBlock(conf, indexConfig, "testLedger", 4))
+		expectedBlockfiles := blockfileSizes(t, blocksDir)
+		copyBlockfiles(t, backupDir, blocksDir)
+		os.RemoveAll(backupDir)
+
+		// running the rollback again removes the blocks left behind
+		assert.NoError(t, RollbackToBlock(conf, indexConfig, "testLedger", 4))
+		assert.Equal(t, expectedBlockfiles, blockfileSizes(t, blocksDir))
+		env = newTestEnv(t, conf)
+		w = newTestBlockfileWrapper(env, "testLedger")
+		assert.Equal(t, uint64(5), w.blockfileMgr.getBlockchainInfo().Height)
+		w.addBlocks(blocks[5:])
+		w.testGetBlockByNumber(blocks, 0)
+		w.testGetBlockByHash(blocks)
+		w.close()
+		env.Cleanup()
+	}
+}
+
+func copyBlockfiles(t *testing.T, srcDir, destDir string) {
+	filesInfo, err := ioutil.ReadDir(srcDir)
+	assert.NoError(t, err)
+	for _, fileInfo := range filesInfo {
+		if fileInfo.IsDir() {
+			continue
+		}
+		assert.NoError(t, copyFile(filepath.Join(srcDir, fileInfo.Name()), filepath.Join(destDir, fileInfo.Name())))
+	}
+}
+
+func blockfileSizes(t *testing.T, dir string) map[string]int64 {
+	filesInfo, err := ioutil.ReadDir(dir)
+	assert.NoError(t, err)
+	sizes := map[string]int64{}
+	for _, fileInfo := range filesInfo {
+		if !fileInfo.IsDir() {
+			sizes[fileInfo.Name()] = fileInfo.Size()
+		}
+	}
+	return sizes
+}
+
+func TestRollbackToBlockRequiresBlockNumIndex(t *testing.T) {
+	conf := NewConf(testPath(), 0)
+	indexConfig := &blkstorage.IndexConfig{AttrsToIndex: []blkstorage.IndexableAttr{blkstorage.IndexableAttrBlockHash}}
+	assert.EqualError(t, RollbackToBlock(conf, indexConfig, "testLedger", 0), "rollback requires the index [BlockNum] to be enabled")
+}
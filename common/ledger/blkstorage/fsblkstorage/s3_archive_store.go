@@ -0,0 +1,192 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fsblkstorage
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	s3SigningAlgorithm = "AWS4-HMAC-SHA256"
+	s3TimeFormat       = "20060102T150405Z"
+	s3DateFormat       = "20060102"
+	// emptyPayloadHash is the hex encoded SHA256 hash of an empty payload
+	emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+// S3Conf encapsulates the configurations for an object store that supports the S3 API, such as AWS S3, MinIO, or GCS
+// (via its XML API with the HMAC keys)
+type S3Conf struct {
+	// Endpoint is the URL of the object store, e.g., `https://s3.us-east-1.amazonaws.com`, `https://storage.googleapis.com`,
+	// or `http://minio:9000`. The objects are addressed in the path style, i.e., `<Endpoint>/<Bucket>/<name>`
+	Endpoint string
+	// Region is the region of the bucket, e.g., `us-east-1`. For MinIO and GCS, `us-east-1` and `auto` can be used
+	Region string
+	// Bucket is the existing bucket to which the block files are archived
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
+// NewS3ArchiveStore returns an `ArchiveStore` that keeps the objects in the given bucket of an object store that supports
+// the S3 API. The requests are signed with the AWS signature version 4
+func NewS3ArchiveStore(conf *S3Conf) ArchiveStore {
+	return &s3ArchiveStore{conf: conf, client: &http.Client{}}
+}
+
+type s3ArchiveStore struct {
+	conf   *S3Conf
+	client *http.Client
+}
+
+func (s *s3ArchiveStore) Upload(name string, localPath string) error {
+	file, err := os.Open(localPath)
+	if err != nil {
+		return errors.Wrapf(err, "error opening file [%s]", localPath)
+	}
+	defer file.Close()
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return errors.Wrapf(err, "error reading file [%s]", localPath)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return errors.Wrapf(err, "error seeking file [%s]", localPath)
+	}
+	req, err := http.NewRequest(http.MethodPut, s.objectURL(name), file)
+	if err != nil {
+		return errors.Wrapf(err, "error creating the request for uploading object [%s]", name)
+	}
+	req.ContentLength = size
+	resp, err := s.do(req, hex.EncodeToString(hash.Sum(nil)))
+	if err != nil {
+		return errors.WithMessage(err, fmt.Sprintf("error uploading object [%s]", name))
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (s *s3ArchiveStore) Download(name string, localPath string) error {
+	req, err := http.NewRequest(http.MethodGet, s.objectURL(name), nil)
+	if err != nil {
+		return errors.Wrapf(err, "error creating the request for downloading object [%s]", name)
+	}
+	resp, err := s.do(req, emptyPayloadHash)
+	if err != nil {
+		return errors.WithMessage(err, fmt.Sprintf("error downloading object [%s]", name))
+	}
+	defer resp.Body.Close()
+	dest, err := os.OpenFile(localPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0660)
+	if err != nil {
+		return errors.Wrapf(err, "error creating file [%s]", localPath)
+	}
+	defer dest.Close()
+	if _, err := io.Copy(dest, resp.Body); err != nil {
+		return errors.Wrapf(err, "error downloading object [%s] to file [%s]", name, localPath)
+	}
+	return errors.Wrapf(dest.Sync(), "error syncing file [%s]", localPath)
+}
+
+func (s *s3ArchiveStore) objectURL(name string) string {
+	return strings.TrimSuffix(s.conf.Endpoint, "/") + "/" + s3EncodePath(s.conf.Bucket+"/"+name)
+}
+
+// do signs and sends the given request. A response with a status other than 200 is returned as an error
+func (s *s3ArchiveStore) do(req *http.Request, payloadHash string) (*http.Response, error) {
+	signS3Request(req, payloadHash, s.conf, time.Now())
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "error sending the request to the object store")
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+		return nil, errors.Errorf("object store responded with status [%s]: %s", resp.Status, body)
+	}
+	return resp, nil
+}
+
+// signS3Request adds to the given request the headers `X-Amz-Date`, `X-Amz-Content-Sha256`, and `Authorization` as per
+// the AWS signature version 4. All the headers present on the request, along with the host, are signed
+func signS3Request(req *http.Request, payloadHash string, conf *S3Conf, now time.Time) {
+	now = now.UTC()
+	amzTime := now.Format(s3TimeFormat)
+	req.Header.Set("X-Amz-Date", amzTime)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	headerNames := make([]string, 0, len(headers))
+	for name := range headers {
+		headerNames = append(headerNames, name)
+	}
+	sort.Strings(headerNames)
+	var canonicalHeaders bytes.Buffer
+	for _, name := range headerNames {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(headerNames, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		s3EncodePath(req.URL.Path),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := now.Format(s3DateFormat) + "/" + conf.Region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{s3SigningAlgorithm, amzTime, scope, sha256Hex([]byte(canonicalRequest))}, "\n")
+
+	signingKey := hmacSHA256([]byte("AWS4"+conf.SecretAccessKey), now.Format(s3DateFormat))
+	for _, part := range []string{conf.Region, "s3", "aws4_request"} {
+		signingKey = hmacSHA256(signingKey, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3SigningAlgorithm, conf.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// s3EncodePath encodes the given path as per RFC 3986, leaving the unreserved characters and the slashes as is
+func s3EncodePath(path string) string {
+	var encoded bytes.Buffer
+	for _, b := range []byte(path) {
+		if ('A' <= b && b <= 'Z') || ('a' <= b && b <= 'z') || ('0' <= b && b <= '9') ||
+			b == '-' || b == '_' || b == '.' || b == '~' || b == '/' {
+			encoded.WriteByte(b)
+			continue
+		}
+		fmt.Fprintf(&encoded, "%%%02X", b)
+	}
+	return encoded.String()
+}
+
+func sha256Hex(data []byte) string {
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
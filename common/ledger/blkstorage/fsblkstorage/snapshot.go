@@ -0,0 +1,149 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fsblkstorage
+
+import (
+	"bytes"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/common/ledger/blkstorage"
+	"github.com/hyperledger/fabric/common/ledger/util/leveldbhelper"
+	"github.com/pkg/errors"
+)
+
+var (
+	bootstrappingSnapshotInfoKey = []byte("bootstrappingSnapshotInfo")
+	// txIDFromSnapshotMarker is the value of the txid-index entry of a transaction that precedes the snapshot from which the
+	// block store is bootstrapped. Unlike a marshalled fileLocPointer, which holds three varints, it is a single byte
+	txIDFromSnapshotMarker = []byte{0}
+)
+
+var maxTxIDsPerImportBatch = 10000
+
+// BootstrapFromSnapshot creates the block store of the given ledger from a snapshot, instead of from the genesis block, and
+// returns the block store. The first block to be added to the block store is the one after the last block of the snapshot.
+// The given ids of the transactions that precede the snapshot are added to the index, so that a later transaction reusing
+// one of them is detected as a duplicate. The block store is expected not to exist already
+func (p *FsBlockstoreProvider) BootstrapFromSnapshot(ledgerid string, snapshotInfo *blkstorage.SnapshotInfo,
+	txIDs blkstorage.TxIDsIterator) (blkstorage.BlockStore, error) {
+	exists, err := p.Exists(ledgerid)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, errors.Errorf("block store of ledger [%s] already exists", ledgerid)
+	}
+	indexStoreHandle := p.leveldbProvider.GetDBHandle(ledgerid)
+	if err := bootstrapIndexStore(indexStoreHandle, p.indexConfig, snapshotInfo, txIDs); err != nil {
+		return nil, err
+	}
+	return newFsBlockStore(ledgerid, p.conf, p.indexConfig, indexStoreHandle)
+}
+
+// bootstrapIndexStore writes the ids of the transactions in batches, followed by the snapshot info and the checkpoint info
+// in the last batch. The checkpoint marks the chain as empty, with the last block of the snapshot as the last block
+func bootstrapIndexStore(db *leveldbhelper.DBHandle, indexConfig *blkstorage.IndexConfig, snapshotInfo *blkstorage.SnapshotInfo,
+	txIDs blkstorage.TxIDsIterator) error {
+	existingCPInfoBytes, err := db.Get(blkMgrInfoKey)
+	if err != nil {
+		return err
+	}
+	if existingCPInfoBytes != nil {
+		return errors.New("the index of the block store is not empty")
+	}
+	batch := leveldbhelper.NewUpdateBatch()
+	if indexConfig.Contains(blkstorage.IndexableAttrTxID) {
+		for {
+			txID, err := txIDs.Next()
+			if err != nil {
+				return err
+			}
+			if txID == "" {
+				break
+			}
+			batch.Put(constructTxIDKey(txID), txIDFromSnapshotMarker)
+			if batch.Len() < maxTxIDsPerImportBatch {
+				continue
+			}
+			if err := db.WriteBatch(batch, false); err != nil {
+				return err
+			}
+			batch = leveldbhelper.NewUpdateBatch()
+		}
+	}
+	snapshotInfoBytes, err := marshalSnapshotInfo(snapshotInfo)
+	if err != nil {
+		return err
+	}
+	cpInfoBytes, err := (&checkpointInfo{isChainEmpty: true, lastBlockNumber: snapshotInfo.LastBlockNum}).marshal()
+	if err != nil {
+		return err
+	}
+	batch.Put(bootstrappingSnapshotInfoKey, snapshotInfoBytes)
+	batch.Put(blkMgrInfoKey, cpInfoBytes)
+	return db.WriteBatch(batch, true)
+}
+
+// BootstrappingSnapshotInfo returns the info of the snapshot from which the block store was bootstrapped, or nil if the
+// block store was created from the genesis block
+func (store *fsBlockStore) BootstrappingSnapshotInfo() *blkstorage.SnapshotInfo {
+	return store.fileMgr.bootstrappingSnapshotInfo
+}
+
+// ForEachTxIDBeforeSnapshot invokes the given function for each of the ids of the transactions that precede the snapshot from
+// which the block store was bootstrapped, in the order of the ids
+func (store *fsBlockStore) ForEachTxIDBeforeSnapshot(f func(txID string) error) error {
+	itr := store.fileMgr.db.GetIterator([]byte{txIDIdxKeyPrefix}, []byte{txIDIdxKeyPrefix + 1})
+	defer itr.Release()
+	for itr.Next() {
+		if !bytes.Equal(itr.Value(), txIDFromSnapshotMarker) {
+			continue
+		}
+		if err := f(string(itr.Key()[1:])); err != nil {
+			return err
+		}
+	}
+	return errors.Wrap(itr.Error(), "error while iterating over the txid-index")
+}
+
+func (mgr *blockfileMgr) loadBootstrappingSnapshotInfo() (*blkstorage.SnapshotInfo, error) {
+	b, err := mgr.db.Get(bootstrappingSnapshotInfoKey)
+	if b == nil || err != nil {
+		return nil, err
+	}
+	return unmarshalSnapshotInfo(b)
+}
+
+func marshalSnapshotInfo(snapshotInfo *blkstorage.SnapshotInfo) ([]byte, error) {
+	buffer := proto.NewBuffer([]byte{})
+	if err := buffer.EncodeVarint(snapshotInfo.LastBlockNum); err != nil {
+		return nil, err
+	}
+	if err := buffer.EncodeRawBytes(snapshotInfo.LastBlockHash); err != nil {
+		return nil, err
+	}
+	if err := buffer.EncodeRawBytes(snapshotInfo.PreviousBlockHash); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func unmarshalSnapshotInfo(b []byte) (*blkstorage.SnapshotInfo, error) {
+	buffer := proto.NewBuffer(b)
+	snapshotInfo := &blkstorage.SnapshotInfo{}
+	var err error
+	if snapshotInfo.LastBlockNum, err = buffer.DecodeVarint(); err != nil {
+		return nil, err
+	}
+	if snapshotInfo.LastBlockHash, err = buffer.DecodeRawBytes(true); err != nil {
+		return nil, err
+	}
+	if snapshotInfo.PreviousBlockHash, err = buffer.DecodeRawBytes(true); err != nil {
+		return nil, err
+	}
+	return snapshotInfo, nil
+}
@@ -0,0 +1,166 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fsblkstorage
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/common/ledger/blkstorage"
+	"github.com/hyperledger/fabric/common/ledger/testutil"
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/util"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/peer"
+	"github.com/stretchr/testify/assert"
+)
+
+type testTxIDsIterator struct {
+	txIDs []string
+}
+
+func (itr *testTxIDsIterator) Next() (string, error) {
+	if len(itr.txIDs) == 0 {
+		return "", nil
+	}
+	txID := itr.txIDs[0]
+	itr.txIDs = itr.txIDs[1:]
+	return txID, nil
+}
+
+func TestBootstrapFromSnapshot(t *testing.T) {
+	defer func(max int) { maxTxIDsPerImportBatch = max }(maxTxIDsPerImportBatch)
+	maxTxIDsPerImportBatch = 2
+	for _, maxBlockfileSize := range []int{0, 1} {
+		conf := NewConf(testPath(), maxBlockfileSize)
+		env := newTestEnv(t, conf)
+		defer env.Cleanup()
+
+		// blocks 0 to 4 precede the snapshot and the blocks 5 and 6 are added afterwards. The block 5 reuses the tx id
+		// of a transaction in the snapshot
+		bg, gb := testutil.NewBlockGenerator(t, "testLedger", false)
+		blocks := []*common.Block{gb}
+		txIDsBeforeSnapshot := []string{}
+		for blockNum := 1; blockNum <= 4; blockNum++ {
+			txIDs := []string{fmt.Sprintf("tx-%d-0", blockNum), fmt.Sprintf("tx-%d-1", blockNum)}
+			txIDsBeforeSnapshot = append(txIDsBeforeSnapshot, txIDs...)
+			blocks = append(blocks, nextBlockWithTxIDs(t, bg, txIDs))
+		}
+		blocks = append(blocks, nextBlockWithTxIDs(t, bg, []string{"tx-2-0", "tx-5-1"}))
+		blocks = append(blocks, nextBlockWithTxIDs(t, bg, []string{"tx-6-0"}))
+		snapshotInfo := &blkstorage.SnapshotInfo{
+			LastBlockNum:      4,
+			LastBlockHash:     blocks[4].Header.Hash(),
+			PreviousBlockHash: blocks[4].Header.PreviousHash,
+		}
+
+		store, err := env.provider.BootstrapFromSnapshot("testLedger", snapshotInfo,
+			&testTxIDsIterator{append([]string(nil), txIDsBeforeSnapshot...)})
+		assert.NoError(t, err)
+		verifyBootstrappedBlockStore(t, store, snapshotInfo, txIDsBeforeSnapshot, blocks[4])
+		store.Shutdown()
+
+		// the block store is opened as bootstrapped after a restart
+		store, err = env.provider.OpenBlockStore("testLedger")
+		assert.NoError(t, err)
+		verifyBootstrappedBlockStore(t, store, snapshotInfo, txIDsBeforeSnapshot, blocks[4])
+		// a block that does not follow the snapshot is rejected
+		assert.Contains(t, store.AddBlock(blocks[4]).Error(), "block number should have been 5 but was 4")
+		assert.NoError(t, store.AddBlock(blocks[5]))
+		assert.NoError(t, store.AddBlock(blocks[6]))
+		store.Shutdown()
+
+		store, err = env.provider.OpenBlockStore("testLedger")
+		assert.NoError(t, err)
+		verifyBootstrappedBlockStore(t, store, snapshotInfo, txIDsBeforeSnapshot, blocks[6])
+		for _, block := range blocks[5:] {
+			retrievedBlock, err := store.RetrieveBlockByNumber(block.Header.Number)
+			assert.NoError(t, err)
+			assert.Equal(t, block, retrievedBlock)
+		}
+		itr, err := store.RetrieveBlocks(5)
+		assert.NoError(t, err)
+		for _, block := range blocks[5:] {
+			retrievedBlock, err := itr.Next()
+			assert.NoError(t, err)
+			assert.Equal(t, block, retrievedBlock)
+		}
+		itr.Close()
+		// the reused tx id continues to refer to the transaction in the snapshot
+		_, err = store.RetrieveTxByID("tx-2-0")
+		assert.Equal(t, ledger.TxBeforeSnapshotErr("tx-2-0"), err)
+		txEnv, err := store.RetrieveTxByID("tx-5-1")
+		assert.NoError(t, err)
+		assert.Equal(t, blocks[5].Data.Data[1], txEnvBytes(t, txEnv))
+		store.Shutdown()
+
+		_, err = env.provider.BootstrapFromSnapshot("testLedger", snapshotInfo, &testTxIDsIterator{})
+		assert.EqualError(t, err, "block store of ledger [testLedger] already exists")
+
+		env.provider.Close()
+		indexConfig := env.provider.indexConfig
+		assert.EqualError(t, RollbackToBlock(conf, indexConfig, "testLedger", 4),
+			"target block number [4] should be greater than the last block number [4] of the snapshot from which ledger [testLedger] was created")
+		assert.NoError(t, RollbackToBlock(conf, indexConfig, "testLedger", 5))
+		env = newTestEnv(t, conf)
+		store, err = env.provider.OpenBlockStore("testLedger")
+		assert.NoError(t, err)
+		verifyBootstrappedBlockStore(t, store, snapshotInfo, txIDsBeforeSnapshot, blocks[5])
+		_, err = store.RetrieveTxByID("tx-6-0")
+		assert.Equal(t, blkstorage.ErrNotFoundInIndex, err)
+		store.Shutdown()
+	}
+}
+
+func verifyBootstrappedBlockStore(t *testing.T, store blkstorage.BlockStore, snapshotInfo *blkstorage.SnapshotInfo,
+	txIDsBeforeSnapshot []string, lastBlock *common.Block) {
+	bcInfo, err := store.GetBlockchainInfo()
+	assert.NoError(t, err)
+	assert.Equal(t, &common.BlockchainInfo{
+		Height:            lastBlock.Header.Number + 1,
+		CurrentBlockHash:  lastBlock.Header.Hash(),
+		PreviousBlockHash: lastBlock.Header.PreviousHash,
+	}, bcInfo)
+	assert.Equal(t, snapshotInfo, store.(*fsBlockStore).BootstrappingSnapshotInfo())
+
+	_, err = store.RetrieveBlockByNumber(snapshotInfo.LastBlockNum)
+	assert.Equal(t, blkstorage.ErrNotFoundInIndex, err)
+	for _, txID := range txIDsBeforeSnapshot {
+		_, err := store.RetrieveTxByID(txID)
+		assert.Equal(t, ledger.TxBeforeSnapshotErr(txID), err)
+	}
+	_, err = store.RetrieveTxByID("non-existent-txid")
+	assert.Equal(t, blkstorage.ErrNotFoundInIndex, err)
+
+	var txIDs []string
+	assert.NoError(t, store.(*fsBlockStore).ForEachTxIDBeforeSnapshot(func(txID string) error {
+		txIDs = append(txIDs, txID)
+		return nil
+	}))
+	expectedTxIDs := append([]string(nil), txIDsBeforeSnapshot...)
+	sort.Strings(expectedTxIDs)
+	assert.Equal(t, expectedTxIDs, txIDs)
+}
+
+func nextBlockWithTxIDs(t *testing.T, bg *testutil.BlockGenerator, txIDs []string) *common.Block {
+	simulationResults := [][]byte{}
+	for range txIDs {
+		simulationResults = append(simulationResults, testutil.ConstructRandomBytes(t, 10))
+	}
+	block := bg.NextBlockWithTxid(simulationResults, txIDs)
+	block.Metadata.Metadata[common.BlockMetadataIndex_TRANSACTIONS_FILTER] =
+		util.NewTxValidationFlagsSetValue(len(txIDs), peer.TxValidationCode_VALID)
+	return block
+}
+
+func txEnvBytes(t *testing.T, txEnv *common.Envelope) []byte {
+	b, err := proto.Marshal(txEnv)
+	assert.NoError(t, err)
+	return b
+}
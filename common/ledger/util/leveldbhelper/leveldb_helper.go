@@ -7,7 +7,6 @@ SPDX-License-Identifier: Apache-2.0
 package leveldbhelper
 
 import (
-	"fmt"
 	"sync"
 
 	"github.com/hyperledger/fabric/common/flogging"
@@ -39,6 +38,8 @@ type DB struct {
 	db      *leveldb.DB
 	dbState dbState
 	mux     sync.Mutex
+	// readOnly, if set, causes an existing db to be opened in the read-only mode, in which the writes fail
+	readOnly bool
 
 	readOpts        *opt.ReadOptions
 	writeOptsNoSync *opt.WriteOptions
@@ -62,23 +63,37 @@ func CreateDB(conf *Conf) *DB {
 
 // Open opens the underlying db
 func (dbInst *DB) Open() {
+	if err := dbInst.open(); err != nil {
+		panic(err.Error())
+	}
+}
+
+// open is same as the function `Open` except that the error is returned instead of panicking
+func (dbInst *DB) open() error {
 	dbInst.mux.Lock()
 	defer dbInst.mux.Unlock()
 	if dbInst.dbState == opened {
-		return
+		return nil
 	}
 	dbOpts := &opt.Options{}
 	dbPath := dbInst.conf.DBPath
 	var err error
-	var dirEmpty bool
-	if dirEmpty, err = util.CreateDirIfMissing(dbPath); err != nil {
-		panic(fmt.Sprintf("Error creating dir if missing: %s", err))
+	if dbInst.readOnly {
+		// a db opened in the read-only mode is never created
+		dbOpts.ReadOnly = true
+		dbOpts.ErrorIfMissing = true
+	} else {
+		var dirEmpty bool
+		if dirEmpty, err = util.CreateDirIfMissing(dbPath); err != nil {
+			return errors.Errorf("Error creating dir if missing: %s", err)
+		}
+		dbOpts.ErrorIfMissing = !dirEmpty
 	}
-	dbOpts.ErrorIfMissing = !dirEmpty
 	if dbInst.db, err = leveldb.OpenFile(dbPath, dbOpts); err != nil {
-		panic(fmt.Sprintf("Error opening leveldb: %s", err))
+		return errors.Errorf("Error opening leveldb: %s", err)
 	}
 	dbInst.dbState = opened
+	return nil
 }
 
 // Close closes the underlying db
@@ -143,6 +158,37 @@ func (dbInst *DB) GetIterator(startKey []byte, endKey []byte) iterator.Iterator
 	return dbInst.db.NewIterator(&goleveldbutil.Range{Start: startKey, Limit: endKey}, dbInst.readOpts)
 }
 
+// ApproximateSize returns the approximate size, in bytes, of the file system space used by the keys in the range
+// between the startKey (inclusive) and the endKey (exclusive). The data that is not yet flushed from the memory
+// to the file system is not accounted for
+func (dbInst *DB) ApproximateSize(startKey []byte, endKey []byte) (uint64, error) {
+	sizes, err := dbInst.db.SizeOf([]goleveldbutil.Range{{Start: startKey, Limit: endKey}})
+	if err != nil {
+		return 0, errors.Wrap(err, "error while computing the approximate size of leveldb key range")
+	}
+	return uint64(sizes.Sum()), nil
+}
+
+// CompactRange compacts the underlying storage for the keys in the range between the startKey (inclusive) and the
+// endKey (exclusive), discarding the deleted and the overwritten data. A nil startKey and a nil endKey represent the
+// beginning and the end of the db respectively
+func (dbInst *DB) CompactRange(startKey []byte, endKey []byte) error {
+	if err := dbInst.db.CompactRange(goleveldbutil.Range{Start: startKey, Limit: endKey}); err != nil {
+		return errors.Wrap(err, "error while compacting leveldb key range")
+	}
+	return nil
+}
+
+// GetSnapshot returns a snapshot of the current state of the db. The reads via the snapshot are not affected by the
+// subsequent writes to the db. The snapshot should be released after the use
+func (dbInst *DB) GetSnapshot() (*leveldb.Snapshot, error) {
+	snapshot, err := dbInst.db.GetSnapshot()
+	if err != nil {
+		return nil, errors.Wrap(err, "error while acquiring leveldb snapshot")
+	}
+	return snapshot, nil
+}
+
 // WriteBatch writes a batch
 func (dbInst *DB) WriteBatch(batch *leveldb.Batch, sync bool) error {
 	wo := dbInst.writeOptsNoSync
@@ -20,8 +20,11 @@ import (
 	"bytes"
 	"sync"
 
+	"github.com/pkg/errors"
 	"github.com/syndtr/goleveldb/leveldb"
 	"github.com/syndtr/goleveldb/leveldb/iterator"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	goleveldbutil "github.com/syndtr/goleveldb/leveldb/util"
 )
 
 var dbNameKeySep = []byte{0x00}
@@ -41,6 +44,18 @@ func NewProvider(conf *Conf) *Provider {
 	return &Provider{db, make(map[string]*DBHandle), sync.Mutex{}}
 }
 
+// NewReadOnlyProvider constructs a Provider over an existing leveldb, opened in the read-only mode. Unlike the function
+// `NewProvider`, a failure in opening the leveldb (e.g., if the leveldb does not exist or is locked by another process) is
+// returned as an error
+func NewReadOnlyProvider(dbPath string) (*Provider, error) {
+	db := CreateDB(&Conf{DBPath: dbPath})
+	db.readOnly = true
+	if err := db.open(); err != nil {
+		return nil, err
+	}
+	return &Provider{db, make(map[string]*DBHandle), sync.Mutex{}}, nil
+}
+
 // GetDBHandle returns a handle to a named db
 func (p *Provider) GetDBHandle(dbName string) *DBHandle {
 	p.mux.Lock()
@@ -53,6 +68,30 @@ func (p *Provider) GetDBHandle(dbName string) *DBHandle {
 	return dbHandle
 }
 
+// DBNames returns, in sorted order, the names of the dbs that contain at least one key, irrespective of whether a
+// handle has been obtained for the db. Only the first key of each db is visited; the remaining keys are skipped via a seek
+func (p *Provider) DBNames() ([]string, error) {
+	itr := p.db.GetIterator(nil, nil)
+	defer itr.Release()
+	var dbNames []string
+	for ok := itr.First(); ok; {
+		levelKey := itr.Key()
+		sepIndex := bytes.Index(levelKey, dbNameKeySep)
+		if sepIndex < 0 {
+			// not a key of a named db
+			ok = itr.Next()
+			continue
+		}
+		dbName := string(levelKey[:sepIndex])
+		dbNames = append(dbNames, dbName)
+		ok = itr.Seek(append([]byte(dbName), lastKeyIndicator))
+	}
+	if err := itr.Error(); err != nil {
+		return nil, errors.Wrap(err, "error while iterating leveldb for the db names")
+	}
+	return dbNames, nil
+}
+
 // Close closes the underlying leveldb
 func (p *Provider) Close() {
 	p.db.Close()
@@ -113,6 +152,109 @@ func (h *DBHandle) GetIterator(startKey []byte, endKey []byte) *Iterator {
 	return &Iterator{h.db.GetIterator(sKey, eKey)}
 }
 
+// DeleteRange deletes all the keys of the named db between the startKey (inclusive) and the endKey (exclusive), with the
+// same semantics of the nil keys as in the function `GetIterator`, and returns the number of keys deleted. The keys are
+// deleted in a single atomic write, which is built directly from the iteration over the range, as opposed to an `UpdateBatch`
+func (h *DBHandle) DeleteRange(startKey []byte, endKey []byte, sync bool) (int, error) {
+	return h.DeleteRangeInBatches(startKey, endKey, 0, sync)
+}
+
+// DeleteRangeInBatches deletes the keys in the same way as the function `DeleteRange`, except that the keys are deleted in
+// multiple writes of up to maxKeysPerBatch keys each, so that the memory used for a large range is bounded. Hence, the deletion
+// of the range is not atomic. A maxKeysPerBatch of zero deletes all the keys in a single write
+func (h *DBHandle) DeleteRangeInBatches(startKey []byte, endKey []byte, maxKeysPerBatch int, sync bool) (int, error) {
+	sKey := constructLevelKey(h.dbName, startKey)
+	eKey := constructLevelKey(h.dbName, endKey)
+	if endKey == nil {
+		eKey[len(eKey)-1] = lastKeyIndicator
+	}
+	itr := h.db.GetIterator(sKey, eKey)
+	defer itr.Release()
+	numDeleted := 0
+	levelBatch := &leveldb.Batch{}
+	for itr.Next() {
+		levelBatch.Delete(itr.Key())
+		if levelBatch.Len() == maxKeysPerBatch {
+			if err := h.db.WriteBatch(levelBatch, sync); err != nil {
+				return numDeleted, err
+			}
+			numDeleted += levelBatch.Len()
+			levelBatch.Reset()
+		}
+	}
+	if err := itr.Error(); err != nil {
+		return numDeleted, errors.Wrapf(err, "error while iterating the leveldb range [%#v] - [%#v]", startKey, endKey)
+	}
+	if levelBatch.Len() == 0 {
+		return numDeleted, nil
+	}
+	if err := h.db.WriteBatch(levelBatch, sync); err != nil {
+		return numDeleted, err
+	}
+	return numDeleted + levelBatch.Len(), nil
+}
+
+// ApproximateSize returns the approximate size, in bytes, of the file system space used by the named db.
+// See function `DB.ApproximateSize` for more details
+func (h *DBHandle) ApproximateSize() (uint64, error) {
+	sKey := constructLevelKey(h.dbName, nil)
+	eKey := constructLevelKey(h.dbName, nil)
+	eKey[len(eKey)-1] = lastKeyIndicator
+	return h.db.ApproximateSize(sKey, eKey)
+}
+
+// Compact compacts the underlying storage for the keys of the named db. See function `DB.CompactRange` for more details
+func (h *DBHandle) Compact() error {
+	sKey := constructLevelKey(h.dbName, nil)
+	eKey := constructLevelKey(h.dbName, nil)
+	eKey[len(eKey)-1] = lastKeyIndicator
+	return h.db.CompactRange(sKey, eKey)
+}
+
+// GetSnapshot returns a snapshot of the current state of the named db. See function `DB.GetSnapshot` for more details
+func (h *DBHandle) GetSnapshot() (*Snapshot, error) {
+	snapshot, err := h.db.GetSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	return &Snapshot{h.dbName, snapshot, h.db.readOpts}, nil
+}
+
+// Snapshot is a read-only, point-in-time view of a named db
+type Snapshot struct {
+	dbName   string
+	snapshot *leveldb.Snapshot
+	readOpts *opt.ReadOptions
+}
+
+// Get returns the value for the given key, as of the time the snapshot was taken
+func (s *Snapshot) Get(key []byte) ([]byte, error) {
+	value, err := s.snapshot.Get(constructLevelKey(s.dbName, key), s.readOpts)
+	if err == leveldb.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "error retrieving leveldb key [%#v] from snapshot", key)
+	}
+	return value, nil
+}
+
+// GetIterator is same as the function `DBHandle.GetIterator` except that the iteration is over the
+// keys as of the time the snapshot was taken
+func (s *Snapshot) GetIterator(startKey []byte, endKey []byte) *Iterator {
+	sKey := constructLevelKey(s.dbName, startKey)
+	eKey := constructLevelKey(s.dbName, endKey)
+	if endKey == nil {
+		eKey[len(eKey)-1] = lastKeyIndicator
+	}
+	return &Iterator{s.snapshot.NewIterator(&goleveldbutil.Range{Start: sKey, Limit: eKey}, s.readOpts)}
+}
+
+// Release releases the snapshot. The snapshot should not be used after the release
+func (s *Snapshot) Release() {
+	s.snapshot.Release()
+}
+
 // UpdateBatch encloses the details of multiple `updates`
 type UpdateBatch struct {
 	KVs map[string][]byte
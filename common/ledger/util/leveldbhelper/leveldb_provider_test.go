@@ -18,6 +18,7 @@ package leveldbhelper
 
 import (
 	"fmt"
+	"os"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -90,6 +91,56 @@ func TestBatchedUpdates(t *testing.T) {
 	}
 }
 
+func TestApproximateSize(t *testing.T) {
+	env := newTestProviderEnv(t, testDBPath)
+	defer env.cleanup()
+
+	db1 := env.provider.GetDBHandle("db1")
+	batch := NewUpdateBatch()
+	for i := 0; i < 1000; i++ {
+		batch.Put([]byte(createTestKey(i)), make([]byte, 1024))
+	}
+	assert.NoError(t, db1.WriteBatch(batch, true))
+	// reopen the db so that the data is flushed from the memory to the file system
+	env.provider.Close()
+	env.provider = NewProvider(&Conf{env.path})
+
+	size, err := env.provider.GetDBHandle("db1").ApproximateSize()
+	assert.NoError(t, err)
+	assert.True(t, size > 0)
+	size, err = env.provider.GetDBHandle("db2").ApproximateSize()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), size)
+}
+
+func TestCompact(t *testing.T) {
+	env := newTestProviderEnv(t, testDBPath)
+	defer env.cleanup()
+
+	db1 := env.provider.GetDBHandle("db1")
+	db2 := env.provider.GetDBHandle("db2")
+	batch := NewUpdateBatch()
+	for i := 0; i < 1000; i++ {
+		batch.Put([]byte(createTestKey(i)), make([]byte, 1024))
+	}
+	assert.NoError(t, db1.WriteBatch(batch, true))
+	assert.NoError(t, db2.WriteBatch(batch, true))
+	batch = NewUpdateBatch()
+	for i := 0; i < 1000; i++ {
+		batch.Delete([]byte(createTestKey(i)))
+	}
+	assert.NoError(t, db1.WriteBatch(batch, true))
+
+	assert.NoError(t, db1.Compact())
+	size, err := db1.ApproximateSize()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), size)
+	// the compaction of a db does not affect the data of the other dbs
+	val, err := db2.Get([]byte(createTestKey(999)))
+	assert.NoError(t, err)
+	assert.Len(t, val, 1024)
+}
+
 func testDBBasicWriteAndReads(t *testing.T, dbNames ...string) {
 	env := newTestProviderEnv(t, testDBPath)
 	defer env.cleanup()
@@ -172,3 +223,148 @@ func createTestValues(dbname string, start int, end int) []string {
 	}
 	return values
 }
+
+func TestSnapshot(t *testing.T) {
+	env := newTestProviderEnv(t, testDBPath)
+	defer env.cleanup()
+
+	db1 := env.provider.GetDBHandle("db1")
+	db2 := env.provider.GetDBHandle("db2")
+	batch := NewUpdateBatch()
+	batch.Put([]byte("key1"), []byte("value1"))
+	batch.Put([]byte("key2"), []byte("value2"))
+	assert.NoError(t, db1.WriteBatch(batch, true))
+	assert.NoError(t, db2.WriteBatch(batch, true))
+
+	snapshot, err := db1.GetSnapshot()
+	assert.NoError(t, err)
+	defer snapshot.Release()
+	batch = NewUpdateBatch()
+	batch.Delete([]byte("key1"))
+	batch.Put([]byte("key2"), []byte("value2-updated"))
+	batch.Put([]byte("key3"), []byte("value3"))
+	assert.NoError(t, db1.WriteBatch(batch, true))
+
+	val, err := snapshot.Get([]byte("key1"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("value1"), val)
+	val, err = snapshot.Get([]byte("key3"))
+	assert.NoError(t, err)
+	assert.Nil(t, val)
+	// the snapshot covers only the keys of the named db
+	checkItrResults(t, snapshot.GetIterator(nil, nil), []string{"key1", "key2"}, []string{"value1", "value2"})
+	checkItrResults(t, db1.GetIterator(nil, nil), []string{"key2", "key3"}, []string{"value2-updated", "value3"})
+}
+
+func TestDeleteRange(t *testing.T) {
+	env := newTestProviderEnv(t, testDBPath)
+	defer env.cleanup()
+
+	db1 := env.provider.GetDBHandle("db1")
+	db2 := env.provider.GetDBHandle("db2")
+	batch := NewUpdateBatch()
+	for _, k := range []string{"key1", "key2", "key3", "key4"} {
+		batch.Put([]byte(k), []byte("value-"+k))
+	}
+	assert.NoError(t, db1.WriteBatch(batch, true))
+	assert.NoError(t, db2.WriteBatch(batch, true))
+
+	numDeleted, err := db1.DeleteRange([]byte("key2"), []byte("key4"), true)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, numDeleted)
+	checkItrResults(t, db1.GetIterator(nil, nil), []string{"key1", "key4"}, []string{"value-key1", "value-key4"})
+
+	numDeleted, err = db1.DeleteRange([]byte("key2"), []byte("key4"), true)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, numDeleted)
+
+	numDeleted, err = db1.DeleteRange(nil, nil, true)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, numDeleted)
+	checkItrResults(t, db1.GetIterator(nil, nil), nil, nil)
+	// the keys of the other dbs are not deleted
+	checkItrResults(t, db2.GetIterator(nil, nil), []string{"key1", "key2", "key3", "key4"},
+		[]string{"value-key1", "value-key2", "value-key3", "value-key4"})
+}
+
+func TestDeleteRangeInBatches(t *testing.T) {
+	env := newTestProviderEnv(t, testDBPath)
+	defer env.cleanup()
+
+	db1 := env.provider.GetDBHandle("db1")
+	db2 := env.provider.GetDBHandle("db2")
+	batch := NewUpdateBatch()
+	for i := 0; i < 10; i++ {
+		batch.Put([]byte(createTestKey(i)), []byte(createTestValue("db", i)))
+	}
+	assert.NoError(t, db1.WriteBatch(batch, true))
+	assert.NoError(t, db2.WriteBatch(batch, true))
+
+	numDeleted, err := db1.DeleteRangeInBatches([]byte(createTestKey(2)), []byte(createTestKey(9)), 3, true)
+	assert.NoError(t, err)
+	assert.Equal(t, 7, numDeleted)
+	checkItrResults(t, db1.GetIterator(nil, nil),
+		[]string{createTestKey(0), createTestKey(1), createTestKey(9)},
+		[]string{createTestValue("db", 0), createTestValue("db", 1), createTestValue("db", 9)})
+
+	// the number of keys is a multiple of the batch size
+	numDeleted, err = db1.DeleteRangeInBatches(nil, nil, 3, true)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, numDeleted)
+	checkItrResults(t, db1.GetIterator(nil, nil), nil, nil)
+	// the keys of the other dbs are not deleted
+	checkItrResults(t, db2.GetIterator(nil, nil), createTestKeys(0, 9), createTestValues("db", 0, 9))
+}
+
+func TestDBNames(t *testing.T) {
+	env := newTestProviderEnv(t, testDBPath)
+	defer env.cleanup()
+	p := env.provider
+
+	dbNames, err := p.DBNames()
+	assert.NoError(t, err)
+	assert.Nil(t, dbNames)
+
+	for _, dbName := range []string{"db2", "db1", "db10"} {
+		batch := NewUpdateBatch()
+		for i := 0; i < 3; i++ {
+			batch.Put([]byte(createTestKey(i)), []byte(createTestValue(dbName, i)))
+		}
+		assert.NoError(t, p.GetDBHandle(dbName).WriteBatch(batch, true))
+	}
+	// a handle without any key does not make a db
+	p.GetDBHandle("db3")
+	dbNames, err = p.DBNames()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"db1", "db10", "db2"}, dbNames)
+
+	_, err = p.GetDBHandle("db10").DeleteRange(nil, nil, true)
+	assert.NoError(t, err)
+	dbNames, err = p.DBNames()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"db1", "db2"}, dbNames)
+}
+
+func TestReadOnlyProvider(t *testing.T) {
+	env := newTestProviderEnv(t, testDBPath)
+	defer env.cleanup()
+	assert.NoError(t, env.provider.GetDBHandle("db1").Put([]byte("key1"), []byte("value1"), true))
+
+	// the db is locked by the provider that opened it for writes
+	_, err := NewReadOnlyProvider(testDBPath)
+	assert.Error(t, err)
+	env.provider.Close()
+
+	p, err := NewReadOnlyProvider(testDBPath)
+	assert.NoError(t, err)
+	defer p.Close()
+	value, err := p.GetDBHandle("db1").Get([]byte("key1"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("value1"), value)
+	assert.Error(t, p.GetDBHandle("db1").Put([]byte("key2"), []byte("value2"), true))
+
+	_, err = NewReadOnlyProvider(testDBPath + "-missing")
+	assert.Error(t, err)
+	_, err = os.Stat(testDBPath + "-missing")
+	assert.True(t, os.IsNotExist(err))
+}
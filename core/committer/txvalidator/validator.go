@@ -483,8 +483,10 @@ func (v *TxValidator) checkTxIdDupsLedger(tIdx int, chdr *common.ChannelHeader,
 	_, err := ldgr.GetTransactionByID(txID)
 
 	// if returned error is nil, it means that there is already a tx in
-	// the ledger with the supplied id
-	if err == nil {
+	// the ledger with the supplied id. A tx that precedes the snapshot from
+	// which the ledger was created is a duplicate as well
+	_, isTxBeforeSnapshotErrType := err.(ledger.TxBeforeSnapshotErr)
+	if err == nil || isTxBeforeSnapshotErrType {
 		logger.Error("Duplicate transaction found, ", txID, ", skipping")
 		return &blockValidationResult{
 			tIdx:           tIdx,
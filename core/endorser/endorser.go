@@ -389,7 +389,9 @@ func (e *Endorser) preProcess(signedProp *pb.SignedProposal) (*validateResult, e
 
 		// Here we handle uniqueness check and ACLs for proposals targeting a chain
 		// Notice that ValidateProposalMessage has already verified that TxID is computed properly
-		if _, err = e.s.GetTransactionByID(chainID, txid); err == nil {
+		// A tx that precedes the snapshot from which the ledger was created is reported as such by the ledger
+		_, err = e.s.GetTransactionByID(chainID, txid)
+		if _, beforeSnapshot := errors.Cause(err).(ledger.TxBeforeSnapshotErr); err == nil || beforeSnapshot {
 			// increment failure due to duplicate transactions. Useful for catching replay attacks in
 			// addition to benign retries
 			e.Metrics.DuplicateTxsFailure.With(meterLabels...).Add(1)
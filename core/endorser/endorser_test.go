@@ -347,6 +347,27 @@ func TestEndorserDupTXId(t *testing.T) {
 	assert.EqualValues(t, 1, fakeMetrics.duplicateTxsFailure.AddArgsForCall(0))
 }
 
+func TestEndorserDupTXIdBeforeSnapshot(t *testing.T) {
+	es := endorser.NewEndorserServer(pvtEmptyDistributor, &em.MockSupport{
+		GetApplicationConfigBoolRv: true,
+		GetApplicationConfigRv:     &mc.MockApplication{CapabilitiesRv: &mc.MockApplicationCapabilities{}},
+		ChaincodeDefinitionRv:      &ccprovider.ChaincodeData{Escc: "ESCC"},
+		ExecuteResp:                &pb.Response{Status: 200, Payload: utils.MarshalOrPanic(&pb.ProposalResponse{Response: &pb.Response{}})},
+		GetTxSimulatorRv: &mockccprovider.MockTxSim{
+			GetTxSimulationResultsRv: &ledger.TxSimulationResults{
+				PubSimulationResults: &rwset.TxReadWriteSet{},
+			},
+		},
+		GetTransactionByIDErr: errors.WithMessage(ledger.TxBeforeSnapshotErr("txid"), "GetTransactionByID failed"),
+	}, platforms.NewRegistry(&golang.Platform{}), &disabled.Provider{})
+
+	signedProp := getSignedProp("ccid", "0", t)
+
+	pResp, err := es.ProcessProposal(context.Background(), signedProp)
+	assert.Error(t, err)
+	assert.EqualValues(t, 500, pResp.Response.Status)
+	assert.Regexp(t, "duplicate transaction found", pResp.Response.Message)
+}
 func TestEndorserBadACL(t *testing.T) {
 	es := endorser.NewEndorserServer(pvtEmptyDistributor, &em.MockSupport{
 		GetApplicationConfigBoolRv: true,
@@ -0,0 +1,76 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/pkg/errors"
+)
+
+// maxAnnotationSize limits the size of a note attached via the function `AnnotateVersion`
+const maxAnnotationSize = 4096
+
+// AnnotateVersion implements function in the interface 'Mgr'. It attaches the given note to the collection config of the
+// chaincode committed at exactly the given block in the default namespace, replacing the existing note, if any; an empty
+// note removes the existing note. The note is returned in the field `Annotation` of the collection config info by the
+// queries of the `Retriever` that return the version. The notes are recorded under the same key and block number as the
+// collection configs, in a separate namespace, so that the encoding of the collection configs is not affected. The pending
+// asynchronous writes, if any, are applied before the annotation
+func (m *mgr) AnnotateVersion(ledgerID, chaincodeName string, blockNum uint64, note string) error {
+	if len(note) > maxAnnotationSize {
+		return errors.Errorf("size [%d] of the annotation exceeds the limit [%d]", len(note), maxAnnotationSize)
+	}
+	if err := m.WaitForPendingWrites(); err != nil {
+		return err
+	}
+	dbHandle := m.dbProvider.getDB(ledgerID)
+	key := constructCollectionConfigKey(chaincodeName)
+	configKV, err := dbHandle.entryAt(blockNum, collectionConfigNamespace, key)
+	if err != nil {
+		return err
+	}
+	if configKV == nil {
+		return errors.Errorf("no collection config of chaincode [%s] is committed at block [%d] of ledger [%s]",
+			chaincodeName, blockNum, ledgerID)
+	}
+	batch := newBatch()
+	if note == "" {
+		batch.Delete(encodeCompositeKey(annotationNamespace, key, blockNum))
+	} else {
+		batch.add(annotationNamespace, key, blockNum, []byte(note))
+	}
+	return dbHandle.writeBatch(batch, true)
+}
+
+// resolveCollectionConfig adds the implicit collections to the given persisted (explicit) collection config and attaches
+// the annotation of the version, if any (see function `Mgr.AnnotateVersion`)
+func (r *retriever) resolveCollectionConfig(
+	chaincodeName string,
+	explicitConfig *ledger.CollectionConfigInfo,
+	filter implicitCollectionFilter,
+) (*ledger.CollectionConfigInfo, error) {
+	resolvedConfig, err := r.addImplicitCollections(chaincodeName, explicitConfig, filter)
+	if err != nil || resolvedConfig == nil || explicitConfig == nil {
+		return resolvedConfig, err
+	}
+	return r.withAnnotation(chaincodeName, resolvedConfig)
+}
+
+// withAnnotation returns the given collection config info along with the note attached to the version, if any. The
+// given info may be shared (e.g., by the cache) and hence, a copy is returned if there is a note
+func (r *retriever) withAnnotation(chaincodeName string, collConfig *ledger.CollectionConfigInfo) (*ledger.CollectionConfigInfo, error) {
+	if r.namespace != collectionConfigNamespace {
+		return collConfig, nil
+	}
+	annotationKV, err := r.dbHandle.entryAt(collConfig.CommittingBlockNum, annotationNamespace, constructCollectionConfigKey(chaincodeName))
+	if err != nil || annotationKV == nil {
+		return collConfig, err
+	}
+	annotatedConfig := *collConfig
+	annotatedConfig.Annotation = string(annotationKV.value)
+	return &annotatedConfig, nil
+}
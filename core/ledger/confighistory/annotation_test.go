@@ -0,0 +1,82 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/mock"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnnotateVersion(t *testing.T) {
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(NewMemStoreProvider()), WithCacheSize(10))
+	defer m.Close()
+
+	for _, blockNum := range []uint64{10, 20} {
+		testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, "chaincode1",
+			sampleCollectionConfigPackage("coll", blockNum))
+		assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: blockNum}))
+	}
+	retriever := m.GetRetriever("ledger1", &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 100}})
+
+	// absent by default
+	collConfig, err := retriever.MostRecentCollectionConfigBelow(50, "chaincode1")
+	assert.NoError(t, err)
+	assert.Equal(t, "", collConfig.Annotation)
+
+	assert.NoError(t, m.AnnotateVersion("ledger1", "chaincode1", 10, "approved by change board CR-1234"))
+	assert.NoError(t, m.AnnotateVersion("ledger1", "chaincode1", 20, "approved by change board CR-1250"))
+	collConfig, err = retriever.CollectionConfigAt(10, "chaincode1")
+	assert.NoError(t, err)
+	assert.Equal(t, "approved by change board CR-1234", collConfig.Annotation)
+	// the annotation does not affect the collection config
+	assert.True(t, proto.Equal(sampleCollectionConfigPackage("coll", 10), collConfig.CollectionConfig))
+	collConfig, err = retriever.ExplicitCollectionConfigAt(10, "chaincode1")
+	assert.NoError(t, err)
+	assert.Equal(t, "approved by change board CR-1234", collConfig.Annotation)
+	collConfig, err = retriever.MostRecentCollectionConfigBelow(50, "chaincode1")
+	assert.NoError(t, err)
+	assert.Equal(t, "approved by change board CR-1250", collConfig.Annotation)
+
+	// a note is replaced and removed, without affecting the cached config
+	assert.NoError(t, m.AnnotateVersion("ledger1", "chaincode1", 20, "rolled back"))
+	collConfig, err = retriever.MostRecentCollectionConfigBelow(50, "chaincode1")
+	assert.NoError(t, err)
+	assert.Equal(t, "rolled back", collConfig.Annotation)
+	assert.NoError(t, m.AnnotateVersion("ledger1", "chaincode1", 20, ""))
+	collConfig, err = retriever.MostRecentCollectionConfigBelow(50, "chaincode1")
+	assert.NoError(t, err)
+	assert.Equal(t, "", collConfig.Annotation)
+
+	t.Run("no-version-at-block", func(t *testing.T) {
+		err := m.AnnotateVersion("ledger1", "chaincode1", 15, "note")
+		assert.EqualError(t, err, "no collection config of chaincode [chaincode1] is committed at block [15] of ledger [ledger1]")
+		err = m.AnnotateVersion("ledger1", "chaincode2", 10, "note")
+		assert.EqualError(t, err, "no collection config of chaincode [chaincode2] is committed at block [10] of ledger [ledger1]")
+	})
+
+	t.Run("oversized-note", func(t *testing.T) {
+		err := m.AnnotateVersion("ledger1", "chaincode1", 10, strings.Repeat("x", maxAnnotationSize+1))
+		assert.EqualError(t, err, "size [4097] of the annotation exceeds the limit [4096]")
+	})
+
+	t.Run("deleted-with-chaincode-history", func(t *testing.T) {
+		// two collection configs, the latest pointer is not counted, and the remaining annotation
+		numDeleted, err := m.DeleteChaincodeHistory("ledger1", "chaincode1")
+		assert.NoError(t, err)
+		assert.Equal(t, 3, numDeleted)
+		empty, err := m.dbProvider.getDB("ledger1").isEmpty()
+		assert.NoError(t, err)
+		assert.True(t, empty)
+	})
+}
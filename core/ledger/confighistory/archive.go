@@ -0,0 +1,66 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/pkg/errors"
+)
+
+// ExportArchive implements function in the interface 'Mgr'. It writes the collection configs of the given ledger to the writer
+// as a zip archive, for inspection by humans, e.g., in a support bundle. Each version of the collection config of a chaincode is
+// written as a separate JSON file at the path `<chaincode>/<blockNum>.json`, where the block number is the one at which the
+// version is committed. The chaincode name is escaped as a path segment, so that a name cannot introduce a directory of its own.
+// The entries are streamed from the db to the writer and the archive is not held in the memory. Unlike the function
+// `ExportConfigHistory`, the archive cannot be imported back. The pending asynchronous writes, if any, are applied before the export
+func (m *mgr) ExportArchive(ledgerID string, w io.Writer) error {
+	if err := m.WaitForPendingWrites(); err != nil {
+		return err
+	}
+	zipWriter := zip.NewWriter(w)
+	marshaler := &jsonpb.Marshaler{Indent: "  "}
+	itr := m.dbProvider.getDB(ledgerID).GetIterator(encodeNamespaceRange(collectionConfigNamespace))
+	defer itr.Release()
+	for itr.Next() {
+		kv := &compositeKV{decodeCompositeKey(itr.Key()), itr.Value()}
+		chaincodeName, ok := chaincodeNameFromCollectionConfigKey(kv.ns, kv.key)
+		if !ok {
+			continue
+		}
+		info, err := compositeKVToCollectionConfig(kv)
+		if err != nil {
+			return err
+		}
+		path := fmt.Sprintf("%s/%d.json", archivePathSegment(chaincodeName), info.CommittingBlockNum)
+		fileWriter, err := zipWriter.Create(path)
+		if err != nil {
+			return errors.Wrapf(err, "error while adding the file [%s] to the archive", path)
+		}
+		if err := marshaler.Marshal(fileWriter, info.CollectionConfig); err != nil {
+			return errors.Wrapf(err, "error while writing the file [%s] to the archive", path)
+		}
+	}
+	if err := itr.Error(); err != nil {
+		return errors.Wrap(err, "error while iterating the config history db")
+	}
+	return errors.Wrap(zipWriter.Close(), "error while writing the archive")
+}
+
+// archivePathSegment escapes the given name for use as a single segment of a path in the archive
+func archivePathSegment(name string) string {
+	segment := url.PathEscape(name)
+	if segment == "." || segment == ".." {
+		segment = strings.Replace(segment, ".", "%2E", -1)
+	}
+	return segment
+}
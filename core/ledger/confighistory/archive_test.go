@@ -0,0 +1,71 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"testing"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/mock"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportArchive(t *testing.T) {
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(NewMemStoreProvider()))
+	defer m.Close()
+
+	expectedConfigs := map[string]*common.CollectionConfigPackage{}
+	for _, ccName := range []string{"chaincode1", "chaincode2", "../evil", ".."} {
+		for _, blockNum := range []uint64{10, 20} {
+			collConfigPkg := sampleCollectionConfigPackage(ccName, blockNum)
+			testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, ccName, collConfigPkg)
+			assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: blockNum}))
+			expectedConfigs[fmt.Sprintf("%s/%d.json", archivePathSegment(ccName), blockNum)] = collConfigPkg
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	assert.NoError(t, m.ExportArchive("ledger1", buf))
+	zipReader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.NoError(t, err)
+	var paths []string
+	for _, file := range zipReader.File {
+		paths = append(paths, file.Name)
+		r, err := file.Open()
+		assert.NoError(t, err)
+		content, err := ioutil.ReadAll(r)
+		assert.NoError(t, err)
+		r.Close()
+		collConfigPkg := &common.CollectionConfigPackage{}
+		assert.NoError(t, jsonpb.Unmarshal(bytes.NewReader(content), collConfigPkg))
+		assert.True(t, proto.Equal(expectedConfigs[file.Name], collConfigPkg), "file=%s", file.Name)
+	}
+	sort.Strings(paths)
+	assert.Equal(t, []string{
+		"%2E%2E/10.json", "%2E%2E/20.json",
+		"..%2Fevil/10.json", "..%2Fevil/20.json",
+		"chaincode1/10.json", "chaincode1/20.json",
+		"chaincode2/10.json", "chaincode2/20.json",
+	}, paths)
+
+	t.Run("empty-ledger", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		assert.NoError(t, m.ExportArchive("ledger2", buf))
+		zipReader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		assert.NoError(t, err)
+		assert.Empty(t, zipReader.File)
+	})
+}
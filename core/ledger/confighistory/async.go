@@ -0,0 +1,143 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"sync"
+
+	"github.com/hyperledger/fabric/protos/common"
+)
+
+// writeRequest contains an update of the config history that is prepared during the processing of a block
+type writeRequest struct {
+	ledgerID    string
+	blockNum    uint64
+	batch       *batch
+	collConfigs map[string]*common.CollectionConfigPackage
+	// numCollConfigs is the number of the collection configs in the batch, across all the namespaces
+	numCollConfigs int
+}
+
+// asyncWriter applies the write requests, in the order in which they are enqueued, in a background goroutine.
+// The queue is bounded and the enqueuing blocks while the queue is full. The first failure in applying a write
+// request is retained and the subsequent write requests are discarded, as applying them would leave a gap in the history.
+// The number of the pending write requests of each ledger, the enqueuings that block, and the discarded write requests
+// are reported via the stats. Once closed, the enqueuing fails with `ErrMgrClosed`
+type asyncWriter struct {
+	queue chan *writeRequest
+	done  chan struct{}
+	stats *stats
+
+	// closeLock is held in the read mode while sending to the queue and in the write mode while closing the queue
+	closeLock sync.RWMutex
+	closed    bool
+
+	mux                sync.Mutex
+	cond               *sync.Cond
+	numPending         int
+	numPendingByLedger map[string]int
+	failure            error
+}
+
+func newAsyncWriter(queueSize int, write func(req *writeRequest) error, stats *stats) *asyncWriter {
+	w := &asyncWriter{
+		queue:              make(chan *writeRequest, queueSize),
+		done:               make(chan struct{}),
+		stats:              stats,
+		numPendingByLedger: map[string]int{},
+	}
+	w.cond = sync.NewCond(&w.mux)
+	go w.run(write)
+	return w
+}
+
+func (w *asyncWriter) run(write func(req *writeRequest) error) {
+	defer close(w.done)
+	for req := range w.queue {
+		w.mux.Lock()
+		failed := w.failure != nil
+		w.mux.Unlock()
+
+		var err error
+		if failed {
+			logger.Warningf("Discarding the config history of block [%d] of ledger [%s] due to an earlier failure", req.blockNum, req.ledgerID)
+			w.stats.incrementAsyncWritesDiscarded(req.ledgerID)
+		} else {
+			err = write(req)
+		}
+
+		w.mux.Lock()
+		if err != nil {
+			logger.Errorf("Error while writing the config history of block [%d] of ledger [%s]: %s", req.blockNum, req.ledgerID, err)
+			w.failure = err
+		}
+		w.numPending--
+		w.numPendingByLedger[req.ledgerID]--
+		w.stats.updateAsyncQueueDepth(req.ledgerID, w.numPendingByLedger[req.ledgerID])
+		if w.numPending == 0 {
+			w.cond.Broadcast()
+		}
+		w.mux.Unlock()
+	}
+}
+
+// enqueue adds the request to the queue. An error is returned, instead, if an earlier write request has failed or if
+// the writer is closed
+func (w *asyncWriter) enqueue(req *writeRequest) error {
+	w.closeLock.RLock()
+	defer w.closeLock.RUnlock()
+	if w.closed {
+		return ErrMgrClosed
+	}
+	w.mux.Lock()
+	if w.failure != nil {
+		defer w.mux.Unlock()
+		return w.failure
+	}
+	w.numPending++
+	w.numPendingByLedger[req.ledgerID]++
+	w.stats.updateAsyncQueueDepth(req.ledgerID, w.numPendingByLedger[req.ledgerID])
+	w.mux.Unlock()
+	select {
+	case w.queue <- req:
+	default:
+		w.stats.incrementAsyncWritesBlocked(req.ledgerID)
+		w.queue <- req
+	}
+	return nil
+}
+
+// waitForPendingWrites blocks until all the enqueued write requests are processed and
+// returns the error, if any, that was encountered while applying the write requests
+func (w *asyncWriter) waitForPendingWrites() error {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	for w.numPending > 0 {
+		w.cond.Wait()
+	}
+	return w.failure
+}
+
+// err returns the error, if any, that was encountered while applying the write requests
+func (w *asyncWriter) err() error {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	return w.failure
+}
+
+// close applies the pending write requests and stops the background goroutine. Closing again is a no-op
+func (w *asyncWriter) close() {
+	w.closeLock.Lock()
+	if w.closed {
+		w.closeLock.Unlock()
+		return
+	}
+	w.closed = true
+	close(w.queue)
+	w.closeLock.Unlock()
+	<-w.done
+}
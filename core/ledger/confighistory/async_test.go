@@ -0,0 +1,211 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/hyperledger/fabric/common/ledger/util/leveldbhelper"
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/mock"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAsyncWrites(t *testing.T) {
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	dummyLedgerInfoRetriever := &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 100}}
+	commitBlocks := func(m *mgr, blockNums ...uint64) {
+		for _, blockNum := range blockNums {
+			testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, "chaincode1",
+				sampleCollectionConfigPackage("coll", blockNum))
+			assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: blockNum}))
+		}
+	}
+
+	t.Run("eventually-consistent", func(t *testing.T) {
+		storeProvider := &blockingStoreProvider{StoreProvider: NewMemStoreProvider(), release: make(chan struct{})}
+		m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(storeProvider), WithAsyncWrites(10))
+		defer m.Close()
+		retriever := m.GetRetriever("ledger1", dummyLedgerInfoRetriever)
+
+		commitBlocks(m, 10, 20)
+		collConfig, err := retriever.CollectionConfigAt(10, "chaincode1")
+		assert.NoError(t, err)
+		assert.Nil(t, collConfig)
+
+		close(storeProvider.release)
+		assert.NoError(t, m.WaitForPendingWrites())
+		for _, blockNum := range []uint64{10, 20} {
+			collConfig, err := retriever.CollectionConfigAt(blockNum, "chaincode1")
+			assert.NoError(t, err)
+			assert.Equal(t, blockNum, collConfig.CommittingBlockNum)
+		}
+	})
+
+	t.Run("flush-on-close", func(t *testing.T) {
+		dbPath := "/tmp/fabric/core/ledger/confighistory"
+		deleteTestPath(t, dbPath)
+		defer deleteTestPath(t, dbPath)
+		m := newMgr(mockCCInfoProvider, dbPath, WithAsyncWrites(1))
+		commitBlocks(m, 10, 20, 30)
+		m.Close()
+
+		m = newMgr(mockCCInfoProvider, dbPath)
+		defer m.Close()
+		retriever := m.GetRetriever("ledger1", dummyLedgerInfoRetriever)
+		for _, blockNum := range []uint64{10, 20, 30} {
+			collConfig, err := retriever.CollectionConfigAt(blockNum, "chaincode1")
+			assert.NoError(t, err)
+			assert.Equal(t, blockNum, collConfig.CommittingBlockNum)
+		}
+	})
+
+	t.Run("write-failure", func(t *testing.T) {
+		storeProvider := &failingStoreProvider{StoreProvider: NewMemStoreProvider()}
+		m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(storeProvider), WithAsyncWrites(10))
+		defer m.Close()
+
+		commitBlocks(m, 10)
+		assert.EqualError(t, m.WaitForPendingWrites(), "write-failure")
+		testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, "chaincode1",
+			sampleCollectionConfigPackage("coll", 20))
+		err := m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: 20})
+		assert.EqualError(t, err, "write-failure")
+	})
+
+	t.Run("close-during-commits", func(t *testing.T) {
+		testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, "chaincode1",
+			sampleCollectionConfigPackage("coll", 1))
+		m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(NewMemStoreProvider()), WithAsyncWrites(1))
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for blockNum := uint64(1); ; blockNum++ {
+				err := m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: blockNum})
+				if err != nil {
+					assert.Equal(t, ErrMgrClosed, errors.Cause(err))
+					return
+				}
+			}
+		}()
+		m.Close()
+		wg.Wait()
+		err := m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: 1000000})
+		assert.Equal(t, ErrMgrClosed, errors.Cause(err))
+		assert.Equal(t, ErrMgrClosed, m.asyncWriter.enqueue(&writeRequest{ledgerID: "ledger1"}))
+		// closing again is a no-op
+		m.Close()
+	})
+
+	t.Run("sync-mode", func(t *testing.T) {
+		m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(NewMemStoreProvider()), WithAsyncWrites(0))
+		defer m.Close()
+		assert.Nil(t, m.asyncWriter)
+		commitBlocks(m, 10)
+		assert.NoError(t, m.WaitForPendingWrites())
+		collConfig, err := m.GetRetriever("ledger1", dummyLedgerInfoRetriever).CollectionConfigAt(10, "chaincode1")
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(10), collConfig.CommittingBlockNum)
+	})
+}
+
+func TestSyncWrites(t *testing.T) {
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, "chaincode1",
+		sampleCollectionConfigPackage("coll", 10))
+
+	testCases := []struct {
+		name         string
+		options      []Option
+		expectedSync bool
+	}{
+		{"default", nil, true},
+		{"sync-disabled", []Option{WithSyncWrites(false)}, false},
+		{"sync-disabled-async-mode", []Option{WithSyncWrites(false), WithAsyncWrites(10)}, false},
+		{"sync-enabled-async-mode", []Option{WithSyncWrites(true), WithAsyncWrites(10)}, true},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			storeProvider := &syncRecordingStoreProvider{StoreProvider: NewMemStoreProvider()}
+			m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(storeProvider), testCase.options...)
+			defer m.Close()
+			assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: 10}))
+			assert.NoError(t, m.WaitForPendingWrites())
+			assert.Equal(t, []bool{testCase.expectedSync}, storeProvider.syncFlags())
+		})
+	}
+}
+
+type blockingStoreProvider struct {
+	StoreProvider
+	release chan struct{}
+}
+
+func (p *blockingStoreProvider) GetStore(ledgerID string) Store {
+	return &blockingStore{Store: p.StoreProvider.GetStore(ledgerID), release: p.release}
+}
+
+// blockingStore blocks the writes until the release channel is closed
+type blockingStore struct {
+	Store
+	release chan struct{}
+}
+
+func (s *blockingStore) WriteBatch(batch *leveldbhelper.UpdateBatch, sync bool) error {
+	<-s.release
+	return s.Store.WriteBatch(batch, sync)
+}
+
+type failingStoreProvider struct {
+	StoreProvider
+}
+
+func (p *failingStoreProvider) GetStore(ledgerID string) Store {
+	return &failingStore{p.StoreProvider.GetStore(ledgerID)}
+}
+
+// failingStore fails all the writes
+type failingStore struct {
+	Store
+}
+
+func (s *failingStore) WriteBatch(batch *leveldbhelper.UpdateBatch, sync bool) error {
+	return errors.New("write-failure")
+}
+
+type syncRecordingStoreProvider struct {
+	StoreProvider
+	mux   sync.Mutex
+	syncs []bool
+}
+
+func (p *syncRecordingStoreProvider) GetStore(ledgerID string) Store {
+	return &syncRecordingStore{Store: p.StoreProvider.GetStore(ledgerID), provider: p}
+}
+
+func (p *syncRecordingStoreProvider) syncFlags() []bool {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	return p.syncs
+}
+
+// syncRecordingStore records the sync flag of each of the writes
+type syncRecordingStore struct {
+	Store
+	provider *syncRecordingStoreProvider
+}
+
+func (s *syncRecordingStore) WriteBatch(batch *leveldbhelper.UpdateBatch, sync bool) error {
+	s.provider.mux.Lock()
+	s.provider.syncs = append(s.provider.syncs, sync)
+	s.provider.mux.Unlock()
+	return s.Store.WriteBatch(batch, sync)
+}
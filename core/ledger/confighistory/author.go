@@ -0,0 +1,85 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"encoding/binary"
+
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/pkg/errors"
+)
+
+// addAuthors adds to the batch the submitters of the transactions that updated the given chaincodes of the default
+// namespace, as supplied in the trigger. The submitter of a chaincode is recorded under the same key and block number as
+// its collection config, in a separate namespace, so that the encoding of the collection configs is not affected
+func addAuthors(batch *batch, ccInfos []*ledger.DeployedChaincodeInfo, submitters map[string]map[string]*ledger.TxSubmitter, blockNum uint64) {
+	for _, ccInfo := range ccInfos {
+		submitter := submitterOf(submitters[collectionConfigNamespace], ccInfo.Name)
+		if submitter == nil {
+			continue
+		}
+		batch.add(authorNamespace, constructCollectionConfigKey(ccInfo.Name), blockNum, encodeAuthor(submitter))
+	}
+}
+
+// submitterOf returns the submitter of the update of the chaincode. The lscc writes the collection config of a chaincode
+// under the key "<chaincode name>~collection" and the rest of the chaincode definition under the key "<chaincode name>".
+// The former is preferred, as that carries the collection config
+func submitterOf(submitters map[string]*ledger.TxSubmitter, chaincodeName string) *ledger.TxSubmitter {
+	if submitter, ok := submitters[chaincodeName+collectionConfigKeySuffix]; ok {
+		return submitter
+	}
+	return submitters[chaincodeName]
+}
+
+// CollectionConfigAuthor implements function from the interface `Retriever`. It returns the submitter of the transaction that
+// committed the collection config of the chaincode at exactly the given block. An empty submitter is returned if the collection
+// config was committed without the info of the submitter (e.g., before the submitters were recorded) and a nil submitter is
+// returned if no collection config of the chaincode was committed at the block. The submitters are recorded only for the
+// chaincodes of the default namespace
+func (r *retriever) CollectionConfigAuthor(blockNum uint64, chaincodeName string) (*ledger.TxSubmitter, error) {
+	if err := r.checkBlockCommitted(blockNum); err != nil {
+		return nil, err
+	}
+	key := constructCollectionConfigKey(chaincodeName)
+	configKV, err := r.dbHandle.entryAt(blockNum, r.namespace, key)
+	if err != nil || configKV == nil {
+		return nil, err
+	}
+	if r.namespace != collectionConfigNamespace {
+		return &ledger.TxSubmitter{}, nil
+	}
+	authorKV, err := r.dbHandle.entryAt(blockNum, authorNamespace, key)
+	if err != nil {
+		return nil, err
+	}
+	if authorKV == nil {
+		return &ledger.TxSubmitter{}, nil
+	}
+	return decodeAuthor(authorKV.value)
+}
+
+// encodeAuthor encodes the submitter as the uvarint length of the MSP ID, followed by the MSP ID and the cert hash
+func encodeAuthor(submitter *ledger.TxSubmitter) []byte {
+	b := make([]byte, binary.MaxVarintLen64, binary.MaxVarintLen64+len(submitter.MSPID)+len(submitter.CertHash))
+	n := binary.PutUvarint(b, uint64(len(submitter.MSPID)))
+	b = append(b[:n], submitter.MSPID...)
+	return append(b, submitter.CertHash...)
+}
+
+func decodeAuthor(b []byte) (*ledger.TxSubmitter, error) {
+	mspIDLen, n := binary.Uvarint(b)
+	if n <= 0 || mspIDLen > uint64(len(b)-n) {
+		return nil, errors.Errorf("invalid author info [%#v]", b)
+	}
+	b = b[n:]
+	submitter := &ledger.TxSubmitter{MSPID: string(b[:mspIDLen])}
+	if certHash := b[mspIDLen:]; len(certHash) > 0 {
+		submitter.CertHash = append([]byte(nil), certHash...)
+	}
+	return submitter, nil
+}
@@ -0,0 +1,129 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/common/ledger/testutil"
+	"github.com/hyperledger/fabric/common/util"
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/rwsetutil"
+	"github.com/hyperledger/fabric/core/ledger/mock"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/msp"
+	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollectionConfigAuthor(t *testing.T) {
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	mockCCInfoProvider.UpdatedChaincodesReturns(
+		[]*ledger.ChaincodeLifecycleInfo{{Name: "chaincode1"}, {Name: "chaincode2"}, {Name: "chaincode3"}}, nil)
+	mockCCInfoProvider.ChaincodeInfoStub = func(ccName string, qe ledger.SimpleQueryExecutor) (*ledger.DeployedChaincodeInfo, error) {
+		return &ledger.DeployedChaincodeInfo{Name: ccName, CollectionConfigPkg: sampleCollectionConfigPackage(ccName, 10)}, nil
+	}
+	m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(NewMemStoreProvider()))
+	defer m.Close()
+
+	submitter1 := &ledger.TxSubmitter{MSPID: "Org1MSP", CertHash: []byte("cert-hash-1")}
+	submitter2 := &ledger.TxSubmitter{MSPID: "Org2MSP", CertHash: []byte("cert-hash-2")}
+	assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{
+		LedgerID:           "ledger1",
+		CommittingBlockNum: 10,
+		Submitters: map[string]map[string]*ledger.TxSubmitter{
+			"lscc": {
+				// the submitter of the collection config key is preferred over the one of the chaincode key
+				"chaincode1":            submitter2,
+				"chaincode1~collection": submitter1,
+				"chaincode2":            submitter2,
+			},
+			"ns1": {"chaincode3": submitter1},
+		},
+	}))
+
+	retriever := m.GetRetriever("ledger1", &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 20}})
+	expectedAuthors := map[string]*ledger.TxSubmitter{
+		"chaincode1": submitter1,
+		"chaincode2": submitter2,
+		// the entries without the submitter info are readable and return an empty author
+		"chaincode3": {},
+		"chaincode4": nil,
+	}
+	for ccName, expectedAuthor := range expectedAuthors {
+		author, err := retriever.CollectionConfigAuthor(10, ccName)
+		assert.NoError(t, err)
+		assert.Equal(t, expectedAuthor, author, "chaincode [%s]", ccName)
+	}
+	author, err := retriever.CollectionConfigAuthor(5, "chaincode1")
+	assert.NoError(t, err)
+	assert.Nil(t, author)
+	_, err = retriever.CollectionConfigAuthor(20, "chaincode1")
+	assert.EqualError(t, err, "The maximum block number committed [19] is less than the requested block number [20]")
+
+	// the author is removed along with the rest of the history of the chaincode
+	numDeleted, err := m.DeleteChaincodeHistory("ledger1", "chaincode1")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, numDeleted)
+	author, err = retriever.CollectionConfigAuthor(10, "chaincode1")
+	assert.NoError(t, err)
+	assert.Nil(t, author)
+}
+
+func TestRebuildRecordsAuthors(t *testing.T) {
+	ccInfoProvider := newLsccLikeCCInfoProvider()
+	m := newMgrWithDBProvider(ccInfoProvider, newDBProviderWithStore(NewMemStoreProvider()))
+	defer m.Close()
+
+	collConfigBytes, err := proto.Marshal(sampleCollectionConfigPackage("cc1", 1))
+	assert.NoError(t, err)
+	builder := rwsetutil.NewRWSetBuilder()
+	builder.AddToWriteSet("lscc", "cc1", []byte("cc1-data"))
+	builder.AddToWriteSet("lscc", "cc1~collection", collConfigBytes)
+	txSimulationResults, err := builder.GetTxSimulationResults()
+	assert.NoError(t, err)
+	pubSimulationBytes, err := txSimulationResults.GetPubSimulationBytes()
+	assert.NoError(t, err)
+	block := testutil.ConstructBlock(t, 1, nil, [][]byte{pubSimulationBytes}, false)
+
+	// set the creator of the transaction
+	env, err := utils.GetEnvelopeFromBlock(block.Data.Data[0])
+	assert.NoError(t, err)
+	payload, err := utils.GetPayload(env)
+	assert.NoError(t, err)
+	sigHdr, err := utils.GetSignatureHeader(payload.Header.SignatureHeader)
+	assert.NoError(t, err)
+	sigHdr.Creator = utils.MarshalOrPanic(&msp.SerializedIdentity{Mspid: "Org1MSP", IdBytes: []byte("cert1")})
+	payload.Header.SignatureHeader = utils.MarshalOrPanic(sigHdr)
+	env.Payload = utils.MarshalOrPanic(payload)
+	block.Data.Data[0] = utils.MarshalOrPanic(env)
+
+	genesisBlock := testutil.ConstructBlock(t, 0, nil, nil, false)
+	assert.NoError(t, m.RebuildFromBlocks("ledger1", &sliceBlockIterator{blocks: []*common.Block{genesisBlock, block}}))
+	author, err := m.GetRetriever("ledger1", &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 2}}).
+		CollectionConfigAuthor(1, "cc1")
+	assert.NoError(t, err)
+	assert.Equal(t, &ledger.TxSubmitter{MSPID: "Org1MSP", CertHash: util.ComputeSHA256([]byte("cert1"))}, author)
+}
+
+func TestAuthorEncoding(t *testing.T) {
+	for _, submitter := range []*ledger.TxSubmitter{
+		{MSPID: "Org1MSP", CertHash: []byte("cert-hash")},
+		{MSPID: "Org1MSP"},
+		{CertHash: []byte("cert-hash")},
+		{},
+	} {
+		decoded, err := decodeAuthor(encodeAuthor(submitter))
+		assert.NoError(t, err)
+		assert.Equal(t, submitter, decoded)
+	}
+	for _, invalid := range [][]byte{nil, {0x80}, {0x05, 'a'}} {
+		_, err := decodeAuthor(invalid)
+		assert.Error(t, err)
+	}
+}
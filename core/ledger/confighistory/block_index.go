@@ -0,0 +1,118 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// blockIndex maintains in memory, for each of the chaincodes in the default namespace, the lowest and the highest block numbers
+// at which a collection config of the chaincode is retained in the config history, so that the existence of a collection config
+// and the block of the most recent one can be established without reading the db. The index of a ledger is loaded from the db,
+// by skipping through the keys of the default namespace, on the first query for the ledger after the index is created or
+// invalidated. The index of a loaded ledger is updated on each write of the collection configs and is invalidated by the
+// operations that remove or replace the entries (e.g., pruning). A disabled index is not consulted and all its functions are no-ops
+type blockIndex struct {
+	enabled bool
+	mux     sync.Mutex
+	ledgers map[string]map[string]*blockRange
+}
+
+type blockRange struct {
+	oldest, latest uint64
+}
+
+func newBlockIndex(enabled bool) *blockIndex {
+	return &blockIndex{
+		enabled: enabled,
+		ledgers: map[string]map[string]*blockRange{},
+	}
+}
+
+// get returns the range of the blocks at which a collection config of the chaincode is retained. The returned range is nil if
+// the chaincode has no collection config
+func (i *blockIndex) get(ledgerID, chaincodeName string, dbHandle *db) (*blockRange, error) {
+	i.mux.Lock()
+	defer i.mux.Unlock()
+	ranges, ok := i.ledgers[ledgerID]
+	if !ok {
+		var err error
+		if ranges, err = loadBlockRanges(dbHandle); err != nil {
+			return nil, err
+		}
+		i.ledgers[ledgerID] = ranges
+	}
+	r, ok := ranges[chaincodeName]
+	if !ok {
+		return nil, nil
+	}
+	return &blockRange{r.oldest, r.latest}, nil
+}
+
+// update records the collection configs of the given chaincodes committed at the given block. This is expected to be invoked
+// after the collection configs are written to the db. If the index of the ledger is not loaded, the update is skipped, as the
+// written collection configs are picked up when the index is loaded
+func (i *blockIndex) update(ledgerID string, chaincodeNames []string, blockNum uint64) {
+	if !i.enabled {
+		return
+	}
+	i.mux.Lock()
+	defer i.mux.Unlock()
+	ranges, ok := i.ledgers[ledgerID]
+	if !ok {
+		return
+	}
+	for _, chaincodeName := range chaincodeNames {
+		r, ok := ranges[chaincodeName]
+		if !ok {
+			ranges[chaincodeName] = &blockRange{oldest: blockNum, latest: blockNum}
+			continue
+		}
+		if blockNum > r.latest {
+			r.latest = blockNum
+		}
+		if blockNum < r.oldest {
+			r.oldest = blockNum
+		}
+	}
+}
+
+// invalidate discards the index of the ledger, which is then loaded afresh from the db on the next query
+func (i *blockIndex) invalidate(ledgerID string) {
+	if !i.enabled {
+		return
+	}
+	i.mux.Lock()
+	defer i.mux.Unlock()
+	delete(i.ledgers, ledgerID)
+}
+
+func loadBlockRanges(dbHandle *db) (map[string]*blockRange, error) {
+	ranges := map[string]*blockRange{}
+	itr := dbHandle.GetIterator(encodeNamespaceRange(collectionConfigNamespace))
+	defer itr.Release()
+	for itr.Next() {
+		k := decodeCompositeKey(itr.Key())
+		chaincodeName, ok := chaincodeNameFromKey(k.key)
+		if !ok {
+			continue
+		}
+		// for a chaincode, the entries are ordered by the decreasing block numbers
+		r, ok := ranges[chaincodeName]
+		if !ok {
+			ranges[chaincodeName] = &blockRange{oldest: k.blockNum, latest: k.blockNum}
+			continue
+		}
+		r.oldest = k.blockNum
+	}
+	if err := itr.Error(); err != nil {
+		return nil, errors.Wrap(err, "error while iterating the config history db")
+	}
+	return ranges, nil
+}
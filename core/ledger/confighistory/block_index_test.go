@@ -0,0 +1,127 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/mock"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlockIndex(t *testing.T) {
+	dbPath := "/tmp/fabric/core/ledger/confighistory"
+	deleteTestPath(t, dbPath)
+	defer deleteTestPath(t, dbPath)
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	dummyLedgerInfoRetriever := &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 100}}
+
+	commit := func(t *testing.T, m *mgr, chaincodeName string, blockNum uint64) {
+		testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, chaincodeName,
+			sampleCollectionConfigPackage("coll", blockNum))
+		assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: blockNum}))
+	}
+	checkBlocks := func(t *testing.T, r Retriever, chaincodeName string, expectedOldest, expectedLatest uint64) {
+		oldest, ok, err := r.OldestConfigBlock(chaincodeName)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, expectedOldest, oldest)
+		latest, ok, err := r.LatestConfigBlock(chaincodeName)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, expectedLatest, latest)
+	}
+	checkAbsent := func(t *testing.T, r Retriever, chaincodeName string) {
+		_, ok, err := r.OldestConfigBlock(chaincodeName)
+		assert.NoError(t, err)
+		assert.False(t, ok)
+		_, ok, err = r.LatestConfigBlock(chaincodeName)
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	}
+
+	m := newMgr(mockCCInfoProvider, dbPath, WithBlockIndex())
+	commit(t, m, "chaincode1", 10)
+	commit(t, m, "chaincode1", 20)
+	commit(t, m, "chaincode2", 30)
+	r := m.GetRetriever("ledger1", dummyLedgerInfoRetriever)
+	checkBlocks(t, r, "chaincode1", 10, 20)
+	checkBlocks(t, r, "chaincode2", 30, 30)
+	checkAbsent(t, r, "chaincode3")
+
+	t.Run("updated-on-commit", func(t *testing.T) {
+		commit(t, m, "chaincode1", 40)
+		commit(t, m, "chaincode3", 50)
+		checkBlocks(t, r, "chaincode1", 10, 40)
+		checkBlocks(t, r, "chaincode3", 50, 50)
+	})
+
+	t.Run("loaded-after-restart", func(t *testing.T) {
+		m.Close()
+		m = newMgr(mockCCInfoProvider, dbPath, WithBlockIndex())
+		r = m.GetRetriever("ledger1", dummyLedgerInfoRetriever)
+		checkBlocks(t, r, "chaincode1", 10, 40)
+		checkBlocks(t, r, "chaincode2", 30, 30)
+		checkBlocks(t, r, "chaincode3", 50, 50)
+	})
+
+	t.Run("invalidated-on-prune", func(t *testing.T) {
+		results, err := m.PruneAllBelow(map[string]uint64{"ledger1": 25})
+		assert.NoError(t, err)
+		assert.NoError(t, results["ledger1"])
+		// the collection config in effect at the boundary is retained
+		checkBlocks(t, r, "chaincode1", 20, 40)
+		checkBlocks(t, r, "chaincode2", 30, 30)
+	})
+
+	t.Run("invalidated-on-delete", func(t *testing.T) {
+		_, err := m.DeleteChaincodeHistory("ledger1", "chaincode1")
+		assert.NoError(t, err)
+		checkAbsent(t, r, "chaincode1")
+		checkBlocks(t, r, "chaincode3", 50, 50)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		m.Close()
+		m = newMgr(mockCCInfoProvider, dbPath)
+		r = m.GetRetriever("ledger1", dummyLedgerInfoRetriever)
+		checkAbsent(t, r, "chaincode1")
+		checkBlocks(t, r, "chaincode2", 30, 30)
+		checkBlocks(t, r, "chaincode3", 50, 50)
+	})
+	m.Close()
+}
+
+func TestBlockIndexServedFromMemory(t *testing.T) {
+	storeProvider := &recordingStoreProvider{StoreProvider: NewMemStoreProvider()}
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(storeProvider), WithBlockIndex())
+	defer m.Close()
+
+	testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, "chaincode1", sampleCollectionConfigPackage("coll", 10))
+	assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: 10}))
+	r := m.GetRetriever("ledger1", &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 100}})
+
+	// the first query loads the index of the ledger
+	_, _, err := r.LatestConfigBlock("chaincode1")
+	assert.NoError(t, err)
+	store := storeProvider.stores[0]
+	numGets, numIterators := store.numGets, store.numIterators
+	assert.Equal(t, 1, numIterators)
+
+	latest, ok, err := r.LatestConfigBlock("chaincode1")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(10), latest)
+	_, ok, err = r.OldestConfigBlock("chaincode2")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, numGets, store.numGets)
+	assert.Equal(t, numIterators, store.numIterators)
+}
@@ -0,0 +1,35 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+// CollectionsWithBlockToLiveAt implements function from the interface `Retriever`. It returns, for the collection config of
+// the chaincode that is in effect at the given block (i.e., the one committed at or below the block), the block-to-live of each
+// of the collections that are purged automatically, i.e., the ones with a non-zero block-to-live, keyed by the collection name.
+// The implicit collections of the chaincode are included. An empty map is returned if no collection is purged automatically,
+// including the case where the chaincode has no collection config at the block
+func (r *retriever) CollectionsWithBlockToLiveAt(blockNum uint64, chaincodeName string) (map[string]uint64, error) {
+	if err := r.checkBlockCommitted(blockNum); err != nil {
+		return nil, err
+	}
+	// the block is committed and hence, it is lower than the max uint64
+	collConfig, err := r.mostRecentCollectionConfigBelow(blockNum+1, chaincodeName, nil)
+	if err != nil {
+		return nil, err
+	}
+	blockToLiveByColl := map[string]uint64{}
+	if collConfig == nil {
+		return blockToLiveByColl, nil
+	}
+	for _, config := range collConfig.CollectionConfig.Config {
+		staticConfig := config.GetStaticCollectionConfig()
+		if staticConfig == nil || staticConfig.BlockToLive == 0 {
+			continue
+		}
+		blockToLiveByColl[staticConfig.Name] = staticConfig.BlockToLive
+	}
+	return blockToLiveByColl, nil
+}
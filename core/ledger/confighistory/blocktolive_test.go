@@ -0,0 +1,60 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollectionsWithBlockToLiveAt(t *testing.T) {
+	mockCCInfoProvider := &implicitCollsCCInfoProvider{}
+	m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(NewMemStoreProvider()))
+	defer m.Close()
+
+	staticCollection := func(name string, blockToLive uint64) *common.CollectionConfig {
+		return &common.CollectionConfig{Payload: &common.CollectionConfig_StaticCollectionConfig{
+			StaticCollectionConfig: &common.StaticCollectionConfig{Name: name, BlockToLive: blockToLive}}}
+	}
+	for _, version := range []struct {
+		blockNum      uint64
+		collConfigPkg *common.CollectionConfigPackage
+	}{
+		{10, &common.CollectionConfigPackage{Config: []*common.CollectionConfig{staticCollection("coll1", 0), staticCollection("coll2", 100)}}},
+		{20, &common.CollectionConfigPackage{Config: []*common.CollectionConfig{staticCollection("coll1", 50), staticCollection("coll2", 0)}}},
+	} {
+		testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(&mockCCInfoProvider.DeployedChaincodeInfoProvider, "chaincode1", version.collConfigPkg)
+		assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: version.blockNum}))
+	}
+	retriever := m.GetRetriever("ledger1", &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 100}})
+
+	blockToLiveByColl, err := retriever.CollectionsWithBlockToLiveAt(5, "chaincode1")
+	assert.NoError(t, err)
+	assert.Empty(t, blockToLiveByColl)
+
+	// the collection config committed at the block is in effect at the block
+	blockToLiveByColl, err = retriever.CollectionsWithBlockToLiveAt(10, "chaincode1")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]uint64{"coll2": 100}, blockToLiveByColl)
+
+	blockToLiveByColl, err = retriever.CollectionsWithBlockToLiveAt(50, "chaincode1")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]uint64{"coll1": 50}, blockToLiveByColl)
+
+	implicitColl := sampleImplicitCollection("org1")
+	implicitColl.BlockToLive = 10
+	mockCCInfoProvider.ImplicitCollectionsReturns([]*common.StaticCollectionConfig{implicitColl, sampleImplicitCollection("org2")}, nil)
+	blockToLiveByColl, err = retriever.CollectionsWithBlockToLiveAt(50, "chaincode1")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]uint64{"coll1": 50, "_implicit_org_org1": 10}, blockToLiveByColl)
+
+	_, err = retriever.CollectionsWithBlockToLiveAt(100, "chaincode1")
+	assert.IsType(t, &ledger.ErrCollectionConfigNotYetAvailable{}, err)
+}
@@ -0,0 +1,80 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrCCInfoProviderUnavailable is returned, as the cause, by the operations that depend on the `ledger.DeployedChaincodeInfoProvider`
+// while the calls to the provider are suspended after the repeated failures (see function `WithCCInfoCircuitBreaker`)
+var ErrCCInfoProviderUnavailable = errors.New("the chaincode info provider is unavailable")
+
+// ccInfoBreaker suspends the calls to the `ledger.DeployedChaincodeInfoProvider` for a cooldown period once the number of the
+// consecutive failed calls reaches the threshold. After the cooldown, the calls are let through again; the first success closes
+// the circuit, whereas a failure suspends the calls for another cooldown period. A nil breaker lets all the calls through
+type ccInfoBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	clock     timeSource
+	stats     *stats
+
+	mutex               sync.Mutex
+	consecutiveFailures int
+	openUntil           time.Time
+}
+
+func newCCInfoBreaker(threshold int, cooldown time.Duration, clock timeSource, stats *stats) *ccInfoBreaker {
+	stats.updateCCInfoCircuitOpen(false)
+	return &ccInfoBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		clock:     clock,
+		stats:     stats,
+	}
+}
+
+// call invokes the given function, which calls the provider, unless the calls are suspended, in which case an error with the
+// cause `ErrCCInfoProviderUnavailable` is returned without invoking the function
+func (b *ccInfoBreaker) call(f func() error) error {
+	if b == nil {
+		return f()
+	}
+	b.mutex.Lock()
+	if now := b.clock.Now(); now.Before(b.openUntil) {
+		failures, remaining := b.consecutiveFailures, b.openUntil.Sub(now)
+		b.mutex.Unlock()
+		return errors.Wrapf(ErrCCInfoProviderUnavailable, "the calls to the chaincode info provider are suspended for another %s after [%d] consecutive failures",
+			remaining, failures)
+	}
+	b.mutex.Unlock()
+
+	err := f()
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if err == nil {
+		if b.consecutiveFailures >= b.threshold {
+			logger.Infof("Resuming the calls to the chaincode info provider after a successful call")
+			b.stats.updateCCInfoCircuitOpen(false)
+		}
+		b.consecutiveFailures = 0
+		return nil
+	}
+	b.consecutiveFailures++
+	if b.consecutiveFailures >= b.threshold {
+		b.openUntil = b.clock.Now().Add(b.cooldown)
+		logger.Warningf("Suspending the calls to the chaincode info provider for %s after [%d] consecutive failures, the last one being: %s",
+			b.cooldown, b.consecutiveFailures, err)
+		b.stats.updateCCInfoCircuitOpen(true)
+		b.stats.incrementCCInfoCircuitTrips()
+	}
+	return err
+}
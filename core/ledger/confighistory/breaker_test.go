@@ -0,0 +1,114 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/common/metrics"
+	"github.com/hyperledger/fabric/common/metrics/metricsfakes"
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCCInfoCircuitBreaker(t *testing.T) {
+	gauges := map[string]*metricsfakes.Gauge{}
+	counters := map[string]*metricsfakes.Counter{}
+	fakeProvider := &metricsfakes.Provider{}
+	fakeHistogram := &metricsfakes.Histogram{}
+	fakeHistogram.WithReturns(fakeHistogram)
+	fakeProvider.NewHistogramReturns(fakeHistogram)
+	fakeProvider.NewGaugeStub = func(opts metrics.GaugeOpts) metrics.Gauge {
+		g := &metricsfakes.Gauge{}
+		g.WithReturns(g)
+		gauges[opts.Name] = g
+		return g
+	}
+	fakeProvider.NewCounterStub = func(opts metrics.CounterOpts) metrics.Counter {
+		c := &metricsfakes.Counter{}
+		c.WithReturns(c)
+		counters[opts.Name] = c
+		return c
+	}
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	mockCCInfoProvider := &implicitCollsCCInfoProvider{}
+	m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(NewMemStoreProvider()),
+		withClock(clock), WithMetricsProvider(fakeProvider), WithCCInfoCircuitBreaker(2, time.Minute), WithLenientImplicitCollections())
+	defer m.Close()
+	circuitOpen := gauges["confighistory_ccinfo_circuit_open"]
+	circuitTrips := counters["confighistory_ccinfo_circuit_trips"]
+	assert.Equal(t, float64(0), circuitOpen.SetArgsForCall(circuitOpen.SetCallCount()-1))
+
+	testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(&mockCCInfoProvider.DeployedChaincodeInfoProvider, "chaincode1", sampleCollectionConfigPackage("coll", 10))
+	assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: 10}))
+	retriever := m.GetRetriever("ledger1", &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 100}})
+
+	// the consecutive failures below the threshold are returned as is
+	mockCCInfoProvider.ChaincodeInfoReturns(nil, errors.New("state-db-error"))
+	err := m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: 20})
+	assert.EqualError(t, err, "state-db-error")
+	assert.Equal(t, 0, circuitTrips.AddCallCount())
+	// the failure that reaches the threshold opens the circuit
+	mockCCInfoProvider.ImplicitCollectionsReturns(nil, errors.New("state-db-error"))
+	collConfig, err := retriever.CollectionConfigAt(10, "chaincode1")
+	assert.NoError(t, err)
+	assert.True(t, collConfig.ImplicitCollectionsIncomplete)
+	assert.Equal(t, 1, circuitTrips.AddCallCount())
+	assert.Equal(t, float64(1), circuitOpen.SetArgsForCall(circuitOpen.SetCallCount()-1))
+
+	// while the circuit is open, the provider is not called
+	numChaincodeInfoCalls := mockCCInfoProvider.ChaincodeInfoCallCount()
+	mockCCInfoProvider.ChaincodeInfoReturns(&ledger.DeployedChaincodeInfo{Name: "chaincode1", CollectionConfigPkg: sampleCollectionConfigPackage("coll", 20)}, nil)
+	err = m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: 20})
+	assert.Equal(t, ErrCCInfoProviderUnavailable, errors.Cause(err))
+	assert.Contains(t, err.Error(), "the calls to the chaincode info provider are suspended for another 1m0s after [2] consecutive failures")
+	assert.Equal(t, numChaincodeInfoCalls, mockCCInfoProvider.ChaincodeInfoCallCount())
+	_, err = retriever.ValidateImplicitCollections("chaincode1")
+	assert.Error(t, err)
+
+	// the queries that do not need the provider are not affected and the others degrade as configured
+	explicitConfig, err := retriever.ExplicitCollectionConfigAt(10, "chaincode1")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"coll-10"}, collNames(explicitConfig))
+	collConfig, err = retriever.CollectionConfigAt(10, "chaincode1")
+	assert.NoError(t, err)
+	assert.True(t, collConfig.ImplicitCollectionsIncomplete)
+
+	// after the cooldown, a successful call closes the circuit
+	clock.now = clock.now.Add(time.Minute)
+	assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: 20}))
+	assert.Equal(t, float64(0), circuitOpen.SetArgsForCall(circuitOpen.SetCallCount()-1))
+	mockCCInfoProvider.ImplicitCollectionsReturns(nil, nil)
+	collConfig, err = retriever.CollectionConfigAt(20, "chaincode1")
+	assert.NoError(t, err)
+	assert.False(t, collConfig.ImplicitCollectionsIncomplete)
+
+	// after the cooldown, a failure opens the circuit again only once the threshold is reached
+	mockCCInfoProvider.ChaincodeInfoReturns(nil, errors.New("state-db-error"))
+	for _, blockNum := range []uint64{30, 40} {
+		err = m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: blockNum})
+		assert.EqualError(t, err, "state-db-error")
+	}
+	assert.Equal(t, 2, circuitTrips.AddCallCount())
+	clock.now = clock.now.Add(time.Minute)
+	err = m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: 50})
+	assert.EqualError(t, err, "state-db-error")
+	assert.Equal(t, 3, circuitTrips.AddCallCount())
+
+	t.Run("disabled-by-default", func(t *testing.T) {
+		m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(NewMemStoreProvider()))
+		defer m.Close()
+		assert.Nil(t, m.ccInfoBreaker)
+		for i := 0; i < 10; i++ {
+			err := m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: 10})
+			assert.EqualError(t, err, "state-db-error")
+		}
+	})
+}
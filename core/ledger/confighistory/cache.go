@@ -0,0 +1,153 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/protos/common"
+)
+
+// configCache is an LRU cache that maintains the most recent persisted collection config of chaincodes.
+// A cache with a capacity of zero or less is disabled and all its functions are no-ops
+type configCache struct {
+	capacity int
+	mux      sync.Mutex
+	entries  map[cacheKey]*list.Element
+	lru      *list.List
+	// writeSeq is incremented on every modification made via the write path, i.e., other than the function `putIfNewer`
+	writeSeq uint64
+}
+
+type cacheKey struct {
+	ledgerID, chaincodeName string
+}
+
+type cacheEntry struct {
+	key  cacheKey
+	info *ledger.CollectionConfigInfo
+}
+
+func newConfigCache(capacity int) *configCache {
+	return &configCache{
+		capacity: capacity,
+		entries:  map[cacheKey]*list.Element{},
+		lru:      list.New(),
+	}
+}
+
+// get returns a copy of the most recent collection config of the chaincode, if present in the cache
+func (c *configCache) get(ledgerID, chaincodeName string) (*ledger.CollectionConfigInfo, bool) {
+	if c.capacity <= 0 {
+		return nil, false
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	elem, ok := c.entries[cacheKey{ledgerID, chaincodeName}]
+	if !ok {
+		return nil, false
+	}
+	c.lru.MoveToFront(elem)
+	return copyCollectionConfigInfo(elem.Value.(*cacheEntry).info), true
+}
+
+// put sets the most recent collection config of the chaincode, evicting the least recently used entry if required. This
+// is meant for the write path, which commits the collection config
+func (c *configCache) put(ledgerID, chaincodeName string, info *ledger.CollectionConfigInfo) {
+	if c.capacity <= 0 {
+		return
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.writeSeq++
+	c.set(cacheKey{ledgerID, chaincodeName}, info)
+}
+
+// readSeq returns the sequence number of the modifications made via the write path. The read path obtains this before
+// loading a collection config from the db and passes it to the function `putIfNewer`
+func (c *configCache) readSeq() uint64 {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return c.writeSeq
+}
+
+// putIfNewer sets the collection config of the chaincode that the read path loaded from the db. As the db is read outside
+// of the commit, the loaded collection config may be older than the one committed meanwhile. Hence, the collection config
+// is dropped if the cache has been modified via the write path since the given sequence number (see function `readSeq`),
+// and an existing entry that is committed at a later block is never replaced
+func (c *configCache) putIfNewer(ledgerID, chaincodeName string, info *ledger.CollectionConfigInfo, seq uint64) {
+	if c.capacity <= 0 {
+		return
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if c.writeSeq != seq {
+		return
+	}
+	key := cacheKey{ledgerID, chaincodeName}
+	if elem, ok := c.entries[key]; ok && elem.Value.(*cacheEntry).info.CommittingBlockNum >= info.CommittingBlockNum {
+		c.lru.MoveToFront(elem)
+		return
+	}
+	c.set(key, info)
+}
+
+func (c *configCache) set(key cacheKey, info *ledger.CollectionConfigInfo) {
+	info = copyCollectionConfigInfo(info)
+	if elem, ok := c.entries[key]; ok {
+		elem.Value.(*cacheEntry).info = info
+		c.lru.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(&cacheEntry{key, info})
+	if c.lru.Len() > c.capacity {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// remove removes the entry for the chaincode, if present
+func (c *configCache) remove(ledgerID, chaincodeName string) {
+	if c.capacity <= 0 {
+		return
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.writeSeq++
+	key := cacheKey{ledgerID, chaincodeName}
+	if elem, ok := c.entries[key]; ok {
+		c.lru.Remove(elem)
+		delete(c.entries, key)
+	}
+}
+
+// removeLedger removes the entries for all the chaincodes of the given ledger
+func (c *configCache) removeLedger(ledgerID string) {
+	if c.capacity <= 0 {
+		return
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.writeSeq++
+	for key, elem := range c.entries {
+		if key.ledgerID == ledgerID {
+			c.lru.Remove(elem)
+			delete(c.entries, key)
+		}
+	}
+}
+
+func copyCollectionConfigInfo(info *ledger.CollectionConfigInfo) *ledger.CollectionConfigInfo {
+	return &ledger.CollectionConfigInfo{
+		CollectionConfig:   proto.Clone(info.CollectionConfig).(*common.CollectionConfigPackage),
+		CommittingBlockNum: info.CommittingBlockNum,
+	}
+}
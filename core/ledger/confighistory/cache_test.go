@@ -0,0 +1,243 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/mock"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigCache(t *testing.T) {
+	info := func(blockNum uint64) *ledger.CollectionConfigInfo {
+		return &ledger.CollectionConfigInfo{
+			CollectionConfig:   sampleCollectionConfigPackage("coll", blockNum),
+			CommittingBlockNum: blockNum,
+		}
+	}
+
+	t.Run("disabled-cache", func(t *testing.T) {
+		c := newConfigCache(0)
+		c.put("ledger1", "cc1", info(1))
+		_, ok := c.get("ledger1", "cc1")
+		assert.False(t, ok)
+		c.remove("ledger1", "cc1")
+	})
+
+	t.Run("lru-eviction", func(t *testing.T) {
+		c := newConfigCache(2)
+		c.put("ledger1", "cc1", info(1))
+		c.put("ledger1", "cc2", info(2))
+		// access cc1 so that cc2 becomes the least recently used entry
+		_, ok := c.get("ledger1", "cc1")
+		assert.True(t, ok)
+		c.put("ledger2", "cc1", info(3))
+
+		_, ok = c.get("ledger1", "cc2")
+		assert.False(t, ok)
+		cached, ok := c.get("ledger1", "cc1")
+		assert.True(t, ok)
+		assert.Equal(t, uint64(1), cached.CommittingBlockNum)
+		cached, ok = c.get("ledger2", "cc1")
+		assert.True(t, ok)
+		assert.Equal(t, uint64(3), cached.CommittingBlockNum)
+
+		c.put("ledger1", "cc1", info(4))
+		cached, ok = c.get("ledger1", "cc1")
+		assert.True(t, ok)
+		assert.Equal(t, uint64(4), cached.CommittingBlockNum)
+
+		c.remove("ledger1", "cc1")
+		_, ok = c.get("ledger1", "cc1")
+		assert.False(t, ok)
+	})
+
+	t.Run("put-if-newer", func(t *testing.T) {
+		c := newConfigCache(2)
+		seq := c.readSeq()
+		c.putIfNewer("ledger1", "cc1", info(1), seq)
+		cached, ok := c.get("ledger1", "cc1")
+		assert.True(t, ok)
+		assert.Equal(t, uint64(1), cached.CommittingBlockNum)
+
+		// a config loaded before a write is dropped
+		seq = c.readSeq()
+		c.put("ledger1", "cc1", info(3))
+		c.putIfNewer("ledger1", "cc1", info(2), seq)
+		cached, _ = c.get("ledger1", "cc1")
+		assert.Equal(t, uint64(3), cached.CommittingBlockNum)
+		c.remove("ledger1", "cc1")
+		c.putIfNewer("ledger1", "cc1", info(2), seq)
+		_, ok = c.get("ledger1", "cc1")
+		assert.False(t, ok)
+
+		// an older config never replaces a newer one
+		c.put("ledger1", "cc1", info(3))
+		c.putIfNewer("ledger1", "cc1", info(2), c.readSeq())
+		cached, _ = c.get("ledger1", "cc1")
+		assert.Equal(t, uint64(3), cached.CommittingBlockNum)
+		c.putIfNewer("ledger1", "cc1", info(4), c.readSeq())
+		cached, _ = c.get("ledger1", "cc1")
+		assert.Equal(t, uint64(4), cached.CommittingBlockNum)
+	})
+
+	t.Run("returned-value-is-a-copy", func(t *testing.T) {
+		c := newConfigCache(1)
+		c.put("ledger1", "cc1", info(1))
+		cached, _ := c.get("ledger1", "cc1")
+		cached.CollectionConfig.Config = nil
+		cached, _ = c.get("ledger1", "cc1")
+		assert.True(t, proto.Equal(sampleCollectionConfigPackage("coll", 1), cached.CollectionConfig))
+	})
+}
+
+func TestMgrWithCache(t *testing.T) {
+	dbPath := "/tmp/fabric/core/ledger/confighistory"
+	deleteTestPath(t, dbPath)
+	defer deleteTestPath(t, dbPath)
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	m := newMgr(mockCCInfoProvider, dbPath, WithCacheSize(10))
+	defer m.Close()
+
+	for _, blockNum := range []uint64{5, 10} {
+		testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, "chaincode1",
+			sampleCollectionConfigPackage("ledger1", blockNum))
+		assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{
+			LedgerID:           "ledger1",
+			CommittingBlockNum: blockNum},
+		))
+	}
+	// writes keep the cache up to date
+	cached, ok := m.cache.get("ledger1", "chaincode1")
+	assert.True(t, ok)
+	assert.Equal(t, uint64(10), cached.CommittingBlockNum)
+
+	retriever := m.GetRetriever("ledger1", &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 100}})
+	m.cache.remove("ledger1", "chaincode1")
+	for queryBlockNum, expectedBlockNum := range map[uint64]uint64{50: 10, 11: 10, 10: 5, 6: 5} {
+		collConfig, err := retriever.MostRecentCollectionConfigBelow(queryBlockNum, "chaincode1")
+		assert.NoError(t, err)
+		assert.Equal(t, expectedBlockNum, collConfig.CommittingBlockNum)
+		assert.True(t, proto.Equal(sampleCollectionConfigPackage("ledger1", expectedBlockNum), collConfig.CollectionConfig))
+	}
+	collConfig, err := retriever.MostRecentCollectionConfigBelow(5, "chaincode1")
+	assert.NoError(t, err)
+	assert.Nil(t, collConfig)
+	for _, blockNum := range []uint64{5, 10} {
+		collConfig, err := retriever.CollectionConfigAt(blockNum, "chaincode1")
+		assert.NoError(t, err)
+		assert.Equal(t, blockNum, collConfig.CommittingBlockNum)
+	}
+	// reads populate the cache lazily
+	_, ok = m.cache.get("ledger1", "chaincode1")
+	assert.True(t, ok)
+
+	collConfig, err = retriever.MostRecentCollectionConfigBelow(50, "non-existing-chaincode")
+	assert.NoError(t, err)
+	assert.Nil(t, collConfig)
+	_, ok = m.cache.get("ledger1", "non-existing-chaincode")
+	assert.False(t, ok)
+}
+
+func TestMgrWithCacheConcurrentReadsAndCommits(t *testing.T) {
+	dbPath := "/tmp/fabric/core/ledger/confighistory"
+	deleteTestPath(t, dbPath)
+	defer deleteTestPath(t, dbPath)
+	chaincodes := []string{"chaincode1", "chaincode2", "chaincode3", "chaincode4"}
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	var updatedChaincodes []*ledger.ChaincodeLifecycleInfo
+	for _, ccName := range chaincodes {
+		updatedChaincodes = append(updatedChaincodes, &ledger.ChaincodeLifecycleInfo{Name: ccName})
+	}
+	mockCCInfoProvider.UpdatedChaincodesReturns(updatedChaincodes, nil)
+	var committingBlockNum uint64
+	var committingBlockNumLock sync.Mutex
+	mockCCInfoProvider.ChaincodeInfoStub = func(ccName string, qe ledger.SimpleQueryExecutor) (*ledger.DeployedChaincodeInfo, error) {
+		committingBlockNumLock.Lock()
+		defer committingBlockNumLock.Unlock()
+		return &ledger.DeployedChaincodeInfo{
+			Name:                ccName,
+			CollectionConfigPkg: sampleCollectionConfigPackage("coll", committingBlockNum),
+		}, nil
+	}
+	// a cache smaller than the number of the chaincodes causes the evictions and hence, the reads from the db
+	m := newMgr(mockCCInfoProvider, dbPath, WithCacheSize(2))
+	defer m.Close()
+	retriever := m.GetRetriever("ledger1", &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 1000}})
+
+	const lastBlockNum = 200
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				for _, ccName := range chaincodes {
+					_, err := retriever.MostRecentCollectionConfigBelow(lastBlockNum+1, ccName)
+					assert.NoError(t, err)
+				}
+			}
+		}()
+	}
+	for blockNum := uint64(1); blockNum <= lastBlockNum; blockNum++ {
+		committingBlockNumLock.Lock()
+		committingBlockNum = blockNum
+		committingBlockNumLock.Unlock()
+		assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: blockNum}))
+		// a read that loaded an older config before the commit does not replace the committed one
+		for _, ccName := range chaincodes {
+			if cached, ok := m.cache.get("ledger1", ccName); ok {
+				assert.Equal(t, blockNum, cached.CommittingBlockNum, "chaincode [%s]", ccName)
+			}
+		}
+	}
+	close(done)
+	wg.Wait()
+
+	for _, ccName := range chaincodes {
+		collConfig, err := retriever.MostRecentCollectionConfigBelow(lastBlockNum+1, ccName)
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(lastBlockNum), collConfig.CommittingBlockNum, "chaincode [%s]", ccName)
+	}
+}
+
+func TestPreload(t *testing.T) {
+	dbPath := "/tmp/fabric/core/ledger/confighistory"
+	deleteTestPath(t, dbPath)
+	defer deleteTestPath(t, dbPath)
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	m := newMgr(mockCCInfoProvider, dbPath)
+	testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, "chaincode1",
+		sampleCollectionConfigPackage("ledger1", 10))
+	assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: 10}))
+	// preload is a no-op when the cache is disabled
+	assert.NoError(t, m.Preload("ledger1", []string{"chaincode1"}))
+	m.Close()
+
+	// reopen, as after a peer restart, with the cache enabled
+	m = newMgr(mockCCInfoProvider, dbPath, WithCacheSize(10))
+	defer m.Close()
+	_, ok := m.cache.get("ledger1", "chaincode1")
+	assert.False(t, ok)
+	assert.NoError(t, m.Preload("ledger1", []string{"chaincode1", "chaincode-without-config"}))
+	cached, ok := m.cache.get("ledger1", "chaincode1")
+	assert.True(t, ok)
+	assert.Equal(t, uint64(10), cached.CommittingBlockNum)
+	_, ok = m.cache.get("ledger1", "chaincode-without-config")
+	assert.False(t, ok)
+}
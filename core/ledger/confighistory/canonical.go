@@ -0,0 +1,40 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"sort"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/protos/common"
+)
+
+// CanonicalConfigAt implements function from the interface `Retriever`. It returns the collection config that the function
+// `CollectionConfigAt` returns for the same arguments, with the collections sorted by their names and serialized deterministically,
+// so that the semantically identical collection configs yield identical bytes irrespective of the order in which the collections
+// are declared. This is intended for computing a content hash of the collection config of a chaincode, e.g., for checking that two
+// peers agree on it. Since the implicit collections are derived from the current state, the peers should be compared at the same
+// height, and since the deterministic serialization is stable only within a build, the peers should run the same version.
+// The returned bytes are meant only for the comparison and not for ingesting back the collection config; nil is returned if the
+// chaincode has no collection config at the block
+func (r *retriever) CanonicalConfigAt(blockNum uint64, chaincodeName string) ([]byte, error) {
+	collConfig, err := r.CollectionConfigAt(blockNum, chaincodeName)
+	if err != nil || collConfig == nil {
+		return nil, err
+	}
+	return marshalDeterministically(sortedCollectionConfigPackage(collConfig.CollectionConfig))
+}
+
+// sortedCollectionConfigPackage returns a copy of the given package with the collections sorted by the key used for
+// matching the collections (see function `collConfigKey`)
+func sortedCollectionConfigPackage(pkg *common.CollectionConfigPackage) *common.CollectionConfigPackage {
+	sorted := proto.Clone(pkg).(*common.CollectionConfigPackage)
+	sort.SliceStable(sorted.Config, func(i, j int) bool {
+		return collConfigKey(sorted.Config[i]) < collConfigKey(sorted.Config[j])
+	})
+	return sorted
+}
@@ -0,0 +1,66 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/mock"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanonicalConfigAt(t *testing.T) {
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(NewMemStoreProvider()))
+	defer m.Close()
+	ledgerInfoRetriever := &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 100}}
+
+	// the same collections are declared in a different order in the two ledgers
+	testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, "chaincode1",
+		collConfigPkg(coll("coll2", nil, 10), coll("coll1", nil, 0), coll("coll3", nil, 0)))
+	assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: 10}))
+	testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, "chaincode1",
+		collConfigPkg(coll("coll3", nil, 0), coll("coll1", nil, 0), coll("coll2", nil, 10)))
+	assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger2", CommittingBlockNum: 10}))
+
+	canonical1, err := m.GetRetriever("ledger1", ledgerInfoRetriever).CanonicalConfigAt(10, "chaincode1")
+	assert.NoError(t, err)
+	canonical2, err := m.GetRetriever("ledger2", ledgerInfoRetriever).CanonicalConfigAt(10, "chaincode1")
+	assert.NoError(t, err)
+	assert.Equal(t, canonical1, canonical2)
+	pkg := &common.CollectionConfigPackage{}
+	assert.NoError(t, proto.Unmarshal(canonical1, pkg))
+	assert.Equal(t, []string{"coll1", "coll2", "coll3"}, collNames(&ledger.CollectionConfigInfo{CollectionConfig: pkg}))
+
+	// the collection config returned by the retriever is not reordered in place
+	collConfig, err := m.GetRetriever("ledger1", ledgerInfoRetriever).CollectionConfigAt(10, "chaincode1")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"coll2", "coll1", "coll3"}, collNames(collConfig))
+
+	t.Run("different-configs", func(t *testing.T) {
+		testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, "chaincode1",
+			collConfigPkg(coll("coll3", nil, 0), coll("coll1", nil, 0), coll("coll2", nil, 20)))
+		assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger3", CommittingBlockNum: 10}))
+		canonical3, err := m.GetRetriever("ledger3", ledgerInfoRetriever).CanonicalConfigAt(10, "chaincode1")
+		assert.NoError(t, err)
+		assert.NotEqual(t, canonical1, canonical3)
+	})
+
+	t.Run("no-collection-config", func(t *testing.T) {
+		canonical, err := m.GetRetriever("ledger1", ledgerInfoRetriever).CanonicalConfigAt(20, "chaincode1")
+		assert.NoError(t, err)
+		assert.Nil(t, canonical)
+	})
+
+	t.Run("block-not-committed", func(t *testing.T) {
+		_, err := m.GetRetriever("ledger1", ledgerInfoRetriever).CanonicalConfigAt(200, "chaincode1")
+		assert.IsType(t, &ledger.ErrCollectionConfigNotYetAvailable{}, err)
+	})
+}
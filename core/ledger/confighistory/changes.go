@@ -0,0 +1,132 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/pkg/errors"
+)
+
+// changeFeedBufferSize is the number of the collection config changes that can be buffered for a change feed that
+// follows the commits
+const changeFeedBufferSize = 100
+
+// ConfigChangesSince implements function from the interface `Retriever`. It sends, over the returned channel, the persisted
+// (explicit) collection configs of all the chaincodes that are committed above the given block, in the increasing order of
+// blocks and, for a block, in the order of the chaincode names. This is intended for replicating the config history to an
+// external system incrementally, using the block of the last received change as the checkpoint.
+// If `follow` is false, the channel is closed after the changes already committed are sent. Otherwise, the channel keeps
+// receiving the changes as the blocks are committed. A change feed that follows the commits buffers up to a fixed number of the
+// changes that are not yet received; if a consumer falls further behind, the feed is ended by closing the channel, so that no
+// change is silently missed, and the consumer is expected to resume from its checkpoint. The feeds are also ended when the `Mgr`
+// is closed. Following the commits is supported only by the retrievers of the default namespace that are not snapshots.
+// The returned function stops the feed and closes the channel; it should be invoked once the feed is no longer consumed
+func (r *retriever) ConfigChangesSince(sinceBlock uint64, follow bool) (<-chan *ledger.ConfigChangeRecord, func(), error) {
+	var w *ledgerWatcher
+	if follow {
+		if r.watchers == nil {
+			return nil, nil, errors.Errorf("following the config changes of ledger [%s] is not supported by this retriever", r.ledgerID)
+		}
+		// the subscription precedes the scan, so that a block committed during the scan is either scanned or received
+		w = r.watchers.watchLedger(r.ledgerID)
+	}
+	committedChanges, err := r.committedChangesSince(sinceBlock)
+	if err != nil {
+		if w != nil {
+			r.watchers.cancelLedgerWatch(r.ledgerID, w)
+		}
+		return nil, nil, err
+	}
+
+	ch := make(chan *ledger.ConfigChangeRecord)
+	done := make(chan struct{})
+	var cancelOnce sync.Once
+	cancel := func() {
+		cancelOnce.Do(func() {
+			close(done)
+			if w != nil {
+				r.watchers.cancelLedgerWatch(r.ledgerID, w)
+			}
+		})
+	}
+	send := func(change *ledger.ConfigChangeRecord) bool {
+		select {
+		case ch <- change:
+			return true
+		case <-done:
+			return false
+		}
+	}
+	go func() {
+		defer close(ch)
+		scannedUpTo := sinceBlock
+		for _, change := range committedChanges {
+			if !send(change) {
+				return
+			}
+			scannedUpTo = change.Info.CommittingBlockNum
+		}
+		if w == nil {
+			return
+		}
+		for {
+			select {
+			case change, ok := <-w.ch:
+				if !ok {
+					return
+				}
+				// a block is written atomically and hence, the changes of a block are either all scanned or all received
+				if change.Info.CommittingBlockNum <= scannedUpTo {
+					continue
+				}
+				if !send(change) {
+					return
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return ch, cancel, nil
+}
+
+// committedChangesSince returns the collection configs committed above the given block, in the order of the function
+// `ConfigChangesSince`
+func (r *retriever) committedChangesSince(sinceBlock uint64) ([]*ledger.ConfigChangeRecord, error) {
+	startKey, endKey := encodeNamespaceRange(r.namespace)
+	itr := r.dbHandle.GetIterator(startKey, endKey)
+	defer itr.Release()
+	var changes []*ledger.ConfigChangeRecord
+	for itr.Next() {
+		k := decodeCompositeKey(itr.Key())
+		if k.blockNum <= sinceBlock {
+			continue
+		}
+		chaincodeName, ok := chaincodeNameFromKey(k.key)
+		if !ok {
+			continue
+		}
+		info, err := compositeKVToCollectionConfig(&compositeKV{k, itr.Value()})
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, &ledger.ConfigChangeRecord{ChaincodeName: chaincodeName, Info: info})
+	}
+	if err := itr.Error(); err != nil {
+		return nil, errors.Wrap(err, "error while iterating the config history db")
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Info.CommittingBlockNum != changes[j].Info.CommittingBlockNum {
+			return changes[i].Info.CommittingBlockNum < changes[j].Info.CommittingBlockNum
+		}
+		return changes[i].ChaincodeName < changes[j].ChaincodeName
+	})
+	return changes, nil
+}
@@ -0,0 +1,126 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/mock"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigChangesSince(t *testing.T) {
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(NewMemStoreProvider()))
+	defer m.Close()
+	commit := func(blockNum uint64, chaincodeNames ...string) {
+		var updatedCCs []*ledger.ChaincodeLifecycleInfo
+		for _, chaincodeName := range chaincodeNames {
+			updatedCCs = append(updatedCCs, &ledger.ChaincodeLifecycleInfo{Name: chaincodeName})
+		}
+		mockCCInfoProvider.UpdatedChaincodesReturns(updatedCCs, nil)
+		mockCCInfoProvider.ChaincodeInfoStub = func(chaincodeName string, qe ledger.SimpleQueryExecutor) (*ledger.DeployedChaincodeInfo, error) {
+			return &ledger.DeployedChaincodeInfo{Name: chaincodeName, CollectionConfigPkg: sampleCollectionConfigPackage(chaincodeName, blockNum)}, nil
+		}
+		assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: blockNum}))
+	}
+	type change struct {
+		chaincodeName string
+		blockNum      uint64
+	}
+	receive := func(t *testing.T, ch <-chan *ledger.ConfigChangeRecord, n int) []change {
+		var changes []change
+		for i := 0; i < n; i++ {
+			select {
+			case record, ok := <-ch:
+				if !assert.True(t, ok, "the channel is closed") {
+					return changes
+				}
+				assert.Equal(t, []string{fmt.Sprintf("%s-%d", record.ChaincodeName, record.Info.CommittingBlockNum)}, collNames(record.Info))
+				changes = append(changes, change{record.ChaincodeName, record.Info.CommittingBlockNum})
+			case <-time.After(10 * time.Second):
+				t.Fatal("timed out waiting for a change")
+			}
+		}
+		return changes
+	}
+	assertClosed := func(t *testing.T, ch <-chan *ledger.ConfigChangeRecord) {
+		select {
+		case _, ok := <-ch:
+			assert.False(t, ok)
+		case <-time.After(10 * time.Second):
+			t.Fatal("timed out waiting for the channel to be closed")
+		}
+	}
+
+	commit(10, "chaincode1")
+	commit(20, "chaincode2", "chaincode1")
+	commit(30, "chaincode2")
+	retriever := m.GetRetriever("ledger1", &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 100}})
+
+	t.Run("committed-changes", func(t *testing.T) {
+		ch, cancel, err := retriever.ConfigChangesSince(10, false)
+		assert.NoError(t, err)
+		defer cancel()
+		assert.Equal(t,
+			[]change{{"chaincode1", 20}, {"chaincode2", 20}, {"chaincode2", 30}},
+			receive(t, ch, 3),
+		)
+		assertClosed(t, ch)
+
+		ch, cancel, err = retriever.ConfigChangesSince(30, false)
+		assert.NoError(t, err)
+		defer cancel()
+		assertClosed(t, ch)
+	})
+
+	t.Run("follow", func(t *testing.T) {
+		ch, cancel, err := retriever.ConfigChangesSince(20, true)
+		assert.NoError(t, err)
+		assert.Equal(t, []change{{"chaincode2", 30}}, receive(t, ch, 1))
+		commit(40, "chaincode3", "chaincode1")
+		assert.Equal(t, []change{{"chaincode1", 40}, {"chaincode3", 40}}, receive(t, ch, 2))
+		cancel()
+		assertClosed(t, ch)
+		// cancelling again is a no-op
+		cancel()
+	})
+
+	t.Run("slow-consumer", func(t *testing.T) {
+		ch, cancel, err := retriever.ConfigChangesSince(40, true)
+		assert.NoError(t, err)
+		defer cancel()
+		for blockNum := uint64(50); blockNum < 50+2*changeFeedBufferSize; blockNum++ {
+			commit(blockNum, "chaincode4")
+		}
+		// the changes buffered before the overflow are received in order and then the feed ends
+		numReceived := 0
+		for record := range ch {
+			assert.Equal(t, uint64(50+numReceived), record.Info.CommittingBlockNum)
+			numReceived++
+		}
+		assert.True(t, numReceived > 0 && numReceived < 2*changeFeedBufferSize)
+	})
+
+	t.Run("follow-not-supported", func(t *testing.T) {
+		_, _, err := m.GetRetrieverForNamespace("ledger1", "_lifecycle", &dummyLedgerInfoRetriever{}).ConfigChangesSince(0, true)
+		assert.EqualError(t, err, "following the config changes of ledger [ledger1] is not supported by this retriever")
+	})
+
+	t.Run("mgr-closed", func(t *testing.T) {
+		m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(NewMemStoreProvider()))
+		ch, cancel, err := m.GetRetriever("ledger1", &dummyLedgerInfoRetriever{}).ConfigChangesSince(0, true)
+		assert.NoError(t, err)
+		defer cancel()
+		m.Close()
+		assertClosed(t, ch)
+	})
+}
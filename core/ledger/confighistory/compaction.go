@@ -0,0 +1,53 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"time"
+)
+
+// WithCompactionOnClose makes the function `Mgr.Close` compact the store of each of the ledgers opened since the `Mgr` was
+// created, so as to reclaim, at shutdown, the space held by the pruned and the deleted entries. The compaction of all the
+// stores together is bounded by the given timeout; if it does not finish in time, a warning is logged and the closing
+// proceeds without waiting any further. The stores that do not implement the interface `Compactor` are skipped.
+// A non-positive timeout disables the compaction, which is the default
+func WithCompactionOnClose(timeout time.Duration) Option {
+	return func(m *mgr) {
+		m.compactTimeout = timeout
+	}
+}
+
+// compactAll compacts the stores of the opened ledgers, one after the other, and returns either when all of them are
+// compacted or when the timeout expires, whichever happens first
+func (m *mgr) compactAll(timeout time.Duration) {
+	ledgerIDs := m.dbProvider.ledgerIDs()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for _, ledgerID := range ledgerIDs {
+			compactor, ok := m.dbProvider.getDB(ledgerID).Store.(Compactor)
+			if !ok {
+				continue
+			}
+			startTime := time.Now()
+			if err := compactor.Compact(); err != nil {
+				logger.Warningf("Error while compacting config history of ledger [%s]: %s", ledgerID, err)
+				continue
+			}
+			logger.Debugf("Compacted config history of ledger [%s] in %s", ledgerID, time.Since(startTime))
+		}
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+		logger.Infof("Compacted config history of [%d] ledgers", len(ledgerIDs))
+	case <-timer.C:
+		logger.Warningf("Compaction of config history did not finish within %s, proceeding to close without waiting for it", timeout)
+	}
+}
@@ -0,0 +1,151 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/mock"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompactionOnClose(t *testing.T) {
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, "chaincode1",
+		sampleCollectionConfigPackage("coll", 10))
+	openLedgers := func(m *mgr, ledgerIDs ...string) {
+		for _, ledgerID := range ledgerIDs {
+			assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: ledgerID, CommittingBlockNum: 10}))
+		}
+	}
+
+	t.Run("compacts-each-ledger", func(t *testing.T) {
+		storeProvider := &compactingStoreProvider{StoreProvider: NewMemStoreProvider(), failFor: "ledger2"}
+		m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(storeProvider), WithCompactionOnClose(time.Minute))
+		openLedgers(m, "ledger1", "ledger2", "ledger3")
+		m.Close()
+		assert.Equal(t, []string{"ledger1", "ledger2", "ledger3"}, storeProvider.compactedLedgers())
+	})
+
+	t.Run("disabled-by-default", func(t *testing.T) {
+		storeProvider := &compactingStoreProvider{StoreProvider: NewMemStoreProvider()}
+		m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(storeProvider))
+		openLedgers(m, "ledger1")
+		m.Close()
+		assert.Empty(t, storeProvider.compactedLedgers())
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		release := make(chan struct{})
+		defer close(release)
+		storeProvider := &compactingStoreProvider{StoreProvider: NewMemStoreProvider(), release: release}
+		m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(storeProvider), WithCompactionOnClose(50*time.Millisecond))
+		openLedgers(m, "ledger1")
+		closed := make(chan struct{})
+		go func() {
+			m.Close()
+			close(closed)
+		}()
+		select {
+		case <-closed:
+		case <-time.After(10 * time.Second):
+			t.Fatal("Close did not return after the compaction timed out")
+		}
+	})
+
+	t.Run("leveldb", func(t *testing.T) {
+		dbPath := "/tmp/fabric/core/ledger/confighistory"
+		deleteTestPath(t, dbPath)
+		defer deleteTestPath(t, dbPath)
+		m := newMgr(mockCCInfoProvider, dbPath, WithCompactionOnClose(time.Minute))
+		openLedgers(m, "ledger1")
+		m.Close()
+
+		m = newMgr(mockCCInfoProvider, dbPath)
+		defer m.Close()
+		retriever := m.GetRetriever("ledger1", &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 20}})
+		collConfig, err := retriever.CollectionConfigAt(10, "chaincode1")
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(10), collConfig.CommittingBlockNum)
+	})
+}
+
+func TestDeleteChaincodeHistoryCompacts(t *testing.T) {
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, "chaincode1",
+		sampleCollectionConfigPackage("coll", 10))
+	storeProvider := &compactingStoreProvider{StoreProvider: NewMemStoreProvider(), failFor: "ledger2"}
+	m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(storeProvider))
+	defer m.Close()
+	for _, ledgerID := range []string{"ledger1", "ledger2"} {
+		assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: ledgerID, CommittingBlockNum: 10}))
+	}
+
+	// nothing is compacted if nothing is deleted
+	numDeleted, err := m.DeleteChaincodeHistory("ledger1", "non-existing-chaincode")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, numDeleted)
+	assert.Empty(t, storeProvider.compactedLedgers())
+
+	numDeleted, err = m.DeleteChaincodeHistory("ledger1", "chaincode1")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, numDeleted)
+	assert.Equal(t, []string{"ledger1"}, storeProvider.compactedLedgers())
+	// a compaction failure does not fail the deletion
+	numDeleted, err = m.DeleteChaincodeHistory("ledger2", "chaincode1")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, numDeleted)
+	assert.Equal(t, []string{"ledger1", "ledger2"}, storeProvider.compactedLedgers())
+}
+
+type compactingStoreProvider struct {
+	StoreProvider
+	// failFor is the ledger for which the compaction fails
+	failFor string
+	// release, if not nil, blocks the compaction until it is closed
+	release chan struct{}
+
+	mux       sync.Mutex
+	compacted []string
+}
+
+func (p *compactingStoreProvider) GetStore(ledgerID string) Store {
+	return &compactingStore{Store: p.StoreProvider.GetStore(ledgerID), ledgerID: ledgerID, provider: p}
+}
+
+func (p *compactingStoreProvider) compactedLedgers() []string {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	compacted := append([]string(nil), p.compacted...)
+	sort.Strings(compacted)
+	return compacted
+}
+
+type compactingStore struct {
+	Store
+	ledgerID string
+	provider *compactingStoreProvider
+}
+
+func (s *compactingStore) Compact() error {
+	if s.provider.release != nil {
+		<-s.provider.release
+	}
+	s.provider.mux.Lock()
+	defer s.provider.mux.Unlock()
+	s.provider.compacted = append(s.provider.compacted, s.ledgerID)
+	if s.ledgerID == s.provider.failFor {
+		return errors.New("compaction-failure")
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/protos/common"
+)
+
+// CollectionConfigsEqual returns true if the two collection config packages are semantically equal.
+// The collections are matched by their names and hence, the order of collections in the packages does not matter.
+// The member orgs policies are compared structurally, i.e., two signature policies are considered equal if they
+// require the same principals in the same combinations, irrespective of the order of the identities in the envelope
+// or the order of the rules in an n-out-of policy
+func CollectionConfigsEqual(a, b *common.CollectionConfigPackage) bool {
+	if len(a.GetConfig()) != len(b.GetConfig()) {
+		return false
+	}
+	collsA, ok := collConfigsByKey(a)
+	if !ok {
+		return false
+	}
+	collsB, ok := collConfigsByKey(b)
+	if !ok {
+		return false
+	}
+	for key, collA := range collsA {
+		collB, ok := collsB[key]
+		if !ok || !collectionConfigEqual(collA, collB) {
+			return false
+		}
+	}
+	return true
+}
+
+// collConfigsByKey returns a map that contains the collection configs present in the package, keyed by the name of the
+// collection. The returned bool is false if more than one collection config in the package maps to the same key
+func collConfigsByKey(pkg *common.CollectionConfigPackage) (map[string]*common.CollectionConfig, bool) {
+	m := map[string]*common.CollectionConfig{}
+	for _, collConfig := range pkg.GetConfig() {
+		key := collConfigKey(collConfig)
+		if _, ok := m[key]; ok {
+			return nil, false
+		}
+		m[key] = collConfig
+	}
+	return m, true
+}
+
+func collConfigKey(collConfig *common.CollectionConfig) string {
+	if staticCollConfig := collConfig.GetStaticCollectionConfig(); staticCollConfig != nil {
+		return "static:" + staticCollConfig.Name
+	}
+	return "unknown:" + proto.CompactTextString(collConfig)
+}
+
+func collectionConfigEqual(a, b *common.CollectionConfig) bool {
+	staticA, staticB := a.GetStaticCollectionConfig(), b.GetStaticCollectionConfig()
+	if staticA == nil || staticB == nil {
+		return proto.Equal(a, b)
+	}
+	policyA, policyB := staticA.MemberOrgsPolicy, staticB.MemberOrgsPolicy
+	withoutPolicyA := proto.Clone(staticA).(*common.StaticCollectionConfig)
+	withoutPolicyB := proto.Clone(staticB).(*common.StaticCollectionConfig)
+	withoutPolicyA.MemberOrgsPolicy, withoutPolicyB.MemberOrgsPolicy = nil, nil
+	if !proto.Equal(withoutPolicyA, withoutPolicyB) {
+		return false
+	}
+	return collectionPoliciesEqual(policyA, policyB)
+}
+
+func collectionPoliciesEqual(a, b *common.CollectionPolicyConfig) bool {
+	envA, envB := a.GetSignaturePolicy(), b.GetSignaturePolicy()
+	if envA == nil || envB == nil {
+		return proto.Equal(a, b)
+	}
+	if envA.Version != envB.Version {
+		return false
+	}
+	canonicalA, ok := canonicalSignaturePolicy(envA.Rule, envA)
+	if !ok {
+		return proto.Equal(envA, envB)
+	}
+	canonicalB, ok := canonicalSignaturePolicy(envB.Rule, envB)
+	if !ok {
+		return false
+	}
+	return canonicalA == canonicalB
+}
+
+// canonicalSignaturePolicy returns a string form of the given signature policy rule that does not depend on the
+// order of the identities in the envelope or the order of the sub-rules of an n-out-of rule. In this form, a signed-by
+// rule embeds the principal it refers to, instead of an index into the identities. The returned bool is false if the
+// rule is malformed (e.g., refers to an identity that is not present in the envelope)
+func canonicalSignaturePolicy(rule *common.SignaturePolicy, env *common.SignaturePolicyEnvelope) (string, bool) {
+	switch t := rule.GetType().(type) {
+	case *common.SignaturePolicy_SignedBy:
+		if t.SignedBy < 0 || int(t.SignedBy) >= len(env.Identities) {
+			return "", false
+		}
+		principal := env.Identities[t.SignedBy]
+		return fmt.Sprintf("signedBy(%d:%s)", principal.PrincipalClassification, hex.EncodeToString(principal.Principal)), true
+	case *common.SignaturePolicy_NOutOf_:
+		var subRules []string
+		for _, subRule := range t.NOutOf.Rules {
+			canonicalSubRule, ok := canonicalSignaturePolicy(subRule, env)
+			if !ok {
+				return "", false
+			}
+			subRules = append(subRules, canonicalSubRule)
+		}
+		sort.Strings(subRules)
+		return fmt.Sprintf("outOf(%d,[%s])", t.NOutOf.N, strings.Join(subRules, ",")), true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,114 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"bytes"
+
+	"github.com/pkg/errors"
+)
+
+// LedgerComparison is the outcome of comparing the config histories of two ledgers, as returned by the function
+// `CompareLedgers`. The entries are matched by their keys and the counts cover all the entries of both the ledgers
+type LedgerComparison struct {
+	// NumMatching is the number of entries that are present in both the ledgers with identical values
+	NumMatching int
+	// NumDiffering is the number of entries that are present in both the ledgers with different values
+	NumDiffering int
+	// NumOnlyInA is the number of entries that are present only in the first ledger
+	NumOnlyInA int
+	// NumOnlyInB is the number of entries that are present only in the second ledger
+	NumOnlyInB int
+	// FirstDivergence is the divergence with the lowest key, or nil if the config histories are identical
+	FirstDivergence *Divergence
+}
+
+// Identical returns true if the config histories of the two ledgers contain exactly the same entries
+func (c *LedgerComparison) Identical() bool {
+	return c.FirstDivergence == nil
+}
+
+// Divergence describes an entry that differs between the config histories of two ledgers
+type Divergence struct {
+	// Namespace, Key, and BlockNum identify the entry
+	Namespace string
+	Key       string
+	BlockNum  uint64
+	// ValueA and ValueB are the stored bytes of the entry in the first and the second ledger respectively.
+	// A nil value means that the entry is absent in that ledger
+	ValueA []byte
+	ValueB []byte
+}
+
+// CompareLedgers implements function in the interface 'Mgr'. It walks the config histories of both the ledgers together
+// in the order of the keys and compares the stored bytes of the entries, so that any discrepancy is caught, including one
+// that is not visible via the retriever (e.g., a collection config that is semantically equal but encoded differently)
+func (m *mgr) CompareLedgers(ledgerA, ledgerB string) (*LedgerComparison, error) {
+	if err := m.WaitForPendingWrites(); err != nil {
+		return nil, err
+	}
+	itrA := m.dbProvider.getDB(ledgerA).GetIterator(nil, nil)
+	defer itrA.Release()
+	itrB := m.dbProvider.getDB(ledgerB).GetIterator(nil, nil)
+	defer itrB.Release()
+
+	comparison := &LedgerComparison{}
+	diverged := func(key, valueA, valueB []byte) {
+		if comparison.FirstDivergence != nil {
+			return
+		}
+		k := decodeCompositeKey(key)
+		comparison.FirstDivergence = &Divergence{
+			Namespace: k.ns,
+			Key:       k.key,
+			BlockNum:  k.blockNum,
+			ValueA:    valueA,
+			ValueB:    valueB,
+		}
+	}
+	okA, okB := itrA.Next(), itrB.Next()
+	for okA || okB {
+		var order int
+		switch {
+		case !okA:
+			order = 1
+		case !okB:
+			order = -1
+		default:
+			order = bytes.Compare(itrA.Key(), itrB.Key())
+		}
+		switch {
+		case order < 0:
+			comparison.NumOnlyInA++
+			diverged(itrA.Key(), copyBytes(itrA.Value()), nil)
+			okA = itrA.Next()
+		case order > 0:
+			comparison.NumOnlyInB++
+			diverged(itrB.Key(), nil, copyBytes(itrB.Value()))
+			okB = itrB.Next()
+		default:
+			if bytes.Equal(itrA.Value(), itrB.Value()) {
+				comparison.NumMatching++
+			} else {
+				comparison.NumDiffering++
+				diverged(itrA.Key(), copyBytes(itrA.Value()), copyBytes(itrB.Value()))
+			}
+			okA, okB = itrA.Next(), itrB.Next()
+		}
+	}
+	if err := itrA.Error(); err != nil {
+		return nil, errors.Wrapf(err, "error while iterating the config history db of ledger [%s]", ledgerA)
+	}
+	if err := itrB.Error(); err != nil {
+		return nil, errors.Wrapf(err, "error while iterating the config history db of ledger [%s]", ledgerB)
+	}
+	return comparison, nil
+}
+
+func copyBytes(b []byte) []byte {
+	return append([]byte{}, b...)
+}
@@ -0,0 +1,84 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareLedgers(t *testing.T) {
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(NewMemStoreProvider()))
+	defer m.Close()
+
+	for _, ledgerID := range []string{"ledger1", "ledger2"} {
+		for _, ccName := range []string{"chaincode1", "chaincode2"} {
+			for _, blockNum := range []uint64{10, 20} {
+				testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, ccName,
+					sampleCollectionConfigPackage(ccName, blockNum))
+				assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: ledgerID, CommittingBlockNum: blockNum}))
+			}
+		}
+	}
+	writeEntry := func(ledgerID, ccName string, blockNum uint64, value []byte) {
+		batch := newBatch()
+		batch.add(collectionConfigNamespace, constructCollectionConfigKey(ccName), blockNum, value)
+		assert.NoError(t, m.dbProvider.getDB(ledgerID).writeBatch(batch, true))
+	}
+
+	comparison, err := m.CompareLedgers("ledger1", "ledger2")
+	assert.NoError(t, err)
+	assert.True(t, comparison.Identical())
+	// 4 collection configs and the latest pointers of the 2 chaincodes
+	assert.Equal(t, &LedgerComparison{NumMatching: 6}, comparison)
+
+	// the entries are compared by their stored bytes
+	writeEntry("ledger2", "chaincode2", 20, []byte("tampered"))
+	// the first divergence is the one with the lowest key, irrespective of the order in which the divergences are introduced
+	writeEntry("ledger1", "chaincode1", 15, []byte("only-in-ledger1"))
+	writeEntry("ledger2", "chaincode3", 5, []byte("only-in-ledger2"))
+
+	comparison, err = m.CompareLedgers("ledger1", "ledger2")
+	assert.NoError(t, err)
+	assert.False(t, comparison.Identical())
+	assert.Equal(t, &LedgerComparison{
+		NumMatching:  5,
+		NumDiffering: 1,
+		NumOnlyInA:   1,
+		NumOnlyInB:   1,
+		FirstDivergence: &Divergence{
+			Namespace: collectionConfigNamespace,
+			Key:       constructCollectionConfigKey("chaincode1"),
+			BlockNum:  15,
+			ValueA:    []byte("only-in-ledger1"),
+		},
+	}, comparison)
+
+	comparison, err = m.CompareLedgers("ledger2", "ledger1")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, comparison.NumOnlyInA)
+	assert.Equal(t, 1, comparison.NumOnlyInB)
+	assert.Equal(t, []byte("only-in-ledger1"), comparison.FirstDivergence.ValueB)
+	assert.Nil(t, comparison.FirstDivergence.ValueA)
+
+	// the latest pointers of the two chaincodes are compared as well and sort before the collection configs
+	comparison, err = m.CompareLedgers("ledger1", "ledger3")
+	assert.NoError(t, err)
+	assert.Equal(t, &LedgerComparison{
+		NumOnlyInA: 7,
+		FirstDivergence: &Divergence{
+			Namespace: latestPointerNamespace(collectionConfigNamespace),
+			Key:       constructCollectionConfigKey("chaincode1"),
+			BlockNum:  0,
+			ValueA:    encodeBlockNum(20),
+		},
+	}, comparison)
+}
@@ -0,0 +1,114 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/common/cauthdsl"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/msp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollectionConfigsEqual(t *testing.T) {
+	org1, org2, org3 := memberPrincipal("org1"), memberPrincipal("org2"), memberPrincipal("org3")
+	s := cauthdsl.SignedBy
+
+	policyOrg1OrOrg2 := envelope(cauthdsl.Or(s(0), s(1)), org1, org2)
+	policyOrg2OrOrg1 := envelope(cauthdsl.Or(s(0), s(1)), org2, org1)
+	policyOrg1AndOrg2 := envelope(cauthdsl.And(s(0), s(1)), org1, org2)
+	// org3 OR (org1 AND org2)
+	nestedPolicy := envelope(cauthdsl.Or(s(2), cauthdsl.And(s(0), s(1))), org1, org2, org3)
+	// (org1 AND org2) OR org3 - with identities and rules in a different order
+	reorderedNestedPolicy := envelope(cauthdsl.Or(cauthdsl.And(s(2), s(0)), s(1)), org2, org3, org1)
+	// org1 OR (org3 AND org2)
+	differentNestedPolicy := envelope(cauthdsl.Or(s(0), cauthdsl.And(s(2), s(1))), org1, org2, org3)
+	malformedPolicy := envelope(s(5))
+
+	testCases := []struct {
+		name     string
+		a, b     *common.CollectionConfigPackage
+		expected bool
+	}{
+		{"both-nil", nil, nil, true},
+		{"nil-and-empty", nil, &common.CollectionConfigPackage{}, true},
+		{"identical",
+			collConfigPkg(coll("c1", policyOrg1OrOrg2, 0), coll("c2", nestedPolicy, 10)),
+			collConfigPkg(coll("c1", policyOrg1OrOrg2, 0), coll("c2", nestedPolicy, 10)), true},
+		{"reordered-collections",
+			collConfigPkg(coll("c1", policyOrg1OrOrg2, 0), coll("c2", nestedPolicy, 10)),
+			collConfigPkg(coll("c2", nestedPolicy, 10), coll("c1", policyOrg1OrOrg2, 0)), true},
+		{"reordered-identities",
+			collConfigPkg(coll("c1", policyOrg1OrOrg2, 0)),
+			collConfigPkg(coll("c1", policyOrg2OrOrg1, 0)), true},
+		{"reordered-nested-policy",
+			collConfigPkg(coll("c1", nestedPolicy, 0)),
+			collConfigPkg(coll("c1", reorderedNestedPolicy, 0)), true},
+		{"different-nested-policy",
+			collConfigPkg(coll("c1", nestedPolicy, 0)),
+			collConfigPkg(coll("c1", differentNestedPolicy, 0)), false},
+		{"different-policy",
+			collConfigPkg(coll("c1", policyOrg1OrOrg2, 0)),
+			collConfigPkg(coll("c1", policyOrg1AndOrg2, 0)), false},
+		{"missing-policy",
+			collConfigPkg(coll("c1", policyOrg1OrOrg2, 0)),
+			collConfigPkg(coll("c1", nil, 0)), false},
+		{"same-malformed-policy",
+			collConfigPkg(coll("c1", malformedPolicy, 0)),
+			collConfigPkg(coll("c1", malformedPolicy, 0)), true},
+		{"malformed-and-valid-policy",
+			collConfigPkg(coll("c1", malformedPolicy, 0)),
+			collConfigPkg(coll("c1", policyOrg1OrOrg2, 0)), false},
+		{"different-block-to-live",
+			collConfigPkg(coll("c1", policyOrg1OrOrg2, 0)),
+			collConfigPkg(coll("c1", policyOrg1OrOrg2, 5)), false},
+		{"different-collection-names",
+			collConfigPkg(coll("c1", policyOrg1OrOrg2, 0)),
+			collConfigPkg(coll("c2", policyOrg1OrOrg2, 0)), false},
+		{"different-number-of-collections",
+			collConfigPkg(coll("c1", policyOrg1OrOrg2, 0)),
+			collConfigPkg(coll("c1", policyOrg1OrOrg2, 0), coll("c2", policyOrg1OrOrg2, 0)), false},
+		{"duplicate-collection-names",
+			collConfigPkg(coll("c1", policyOrg1OrOrg2, 0), coll("c1", policyOrg1OrOrg2, 0)),
+			collConfigPkg(coll("c1", policyOrg1OrOrg2, 0), coll("c2", policyOrg1OrOrg2, 0)), false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, CollectionConfigsEqual(tc.a, tc.b))
+			assert.Equal(t, tc.expected, CollectionConfigsEqual(tc.b, tc.a))
+		})
+	}
+}
+
+func memberPrincipal(mspID string) *msp.MSPPrincipal {
+	return cauthdsl.SignedByMspMember(mspID).Identities[0]
+}
+
+func envelope(rule *common.SignaturePolicy, identities ...*msp.MSPPrincipal) *common.SignaturePolicyEnvelope {
+	return &common.SignaturePolicyEnvelope{Rule: rule, Identities: identities}
+}
+
+func coll(name string, policy *common.SignaturePolicyEnvelope, blockToLive uint64) *common.StaticCollectionConfig {
+	c := &common.StaticCollectionConfig{Name: name, BlockToLive: blockToLive}
+	if policy != nil {
+		c.MemberOrgsPolicy = &common.CollectionPolicyConfig{
+			Payload: &common.CollectionPolicyConfig_SignaturePolicy{SignaturePolicy: policy},
+		}
+	}
+	return c
+}
+
+func collConfigPkg(colls ...*common.StaticCollectionConfig) *common.CollectionConfigPackage {
+	pkg := &common.CollectionConfigPackage{}
+	for _, c := range colls {
+		pkg.Config = append(pkg.Config, &common.CollectionConfig{
+			Payload: &common.CollectionConfig_StaticCollectionConfig{StaticCollectionConfig: c},
+		})
+	}
+	return pkg
+}
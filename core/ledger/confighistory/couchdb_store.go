@@ -0,0 +1,317 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hyperledger/fabric/common/ledger/util/leveldbhelper"
+	"github.com/hyperledger/fabric/core/ledger/ledgerconfig"
+	"github.com/hyperledger/fabric/core/ledger/util/couchdb"
+	"github.com/pkg/errors"
+)
+
+// couchDBNamespace is used, in place of a chaincode name, for naming the CouchDB database that holds the config history of a
+// ledger. A chaincode name cannot start with an underscore and hence, the name does not collide with the databases of the state db
+const couchDBNamespace = "_confighistory"
+
+// NewCouchDBStoreProvider returns a `StoreProvider` that keeps the config history of each ledger in a separate database of the
+// given CouchDB instance, so that the deployments that run CouchDB for the state db can manage the config history alongside.
+// A key is stored as a document whose id is the hex encoding of the key, which CouchDB orders in the same way as the leveldb
+// based stores order the keys, and hence, the queries behave identically. The database of a ledger is created on the first use.
+// CouchDB does not apply the updates to multiple documents atomically, so a failure while writing a batch (e.g., a crash of the
+// peer) may leave the batch partially applied; such a config history can be recovered via the function `Mgr.RebuildFromBlocks`
+func NewCouchDBStoreProvider(couchInstance *couchdb.CouchInstance) StoreProvider {
+	return &couchDBStoreProvider{
+		newDatabase: func(dbName string) (couchDatabase, error) {
+			return couchdb.CreateCouchDatabase(couchInstance, dbName)
+		},
+		stores: map[string]*couchDBStore{},
+	}
+}
+
+// couchDatabase contains the functions of `couchdb.CouchDatabase` used by the `couchDBStore`
+type couchDatabase interface {
+	ReadDoc(id string) (*couchdb.CouchDoc, string, error)
+	ReadDocRange(startKey, endKey string, limit int32) ([]*couchdb.QueryResult, string, error)
+	BatchRetrieveDocumentMetadata(keys []string) ([]*couchdb.DocMetadata, error)
+	BatchUpdateDocuments(documents []*couchdb.CouchDoc) ([]*couchdb.BatchUpdateResponse, error)
+	EnsureFullCommit() (*couchdb.DBOperationResponse, error)
+	GetDatabaseInfo() (*couchdb.DBInfo, *couchdb.DBReturn, error)
+}
+
+type couchDBStoreProvider struct {
+	newDatabase func(dbName string) (couchDatabase, error)
+	mux         sync.Mutex
+	stores      map[string]*couchDBStore
+}
+
+// GetStore implements function from the interface `StoreProvider`
+func (p *couchDBStoreProvider) GetStore(ledgerID string) Store {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	s, ok := p.stores[ledgerID]
+	if !ok {
+		s = &couchDBStore{dbName: couchdb.ConstructNamespaceDBName(ledgerID, couchDBNamespace), newDatabase: p.newDatabase}
+		p.stores[ledgerID] = s
+	}
+	return s
+}
+
+// Close implements function from the interface `StoreProvider`. The CouchDB instance is owned by the caller and is left open
+func (p *couchDBStoreProvider) Close() {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	p.stores = map[string]*couchDBStore{}
+}
+
+// couchDBStore creates the database on the first use and, if the creation fails, retries on the subsequent uses,
+// as the interface `StoreProvider` does not allow for returning an error when the store is obtained
+type couchDBStore struct {
+	dbName      string
+	newDatabase func(dbName string) (couchDatabase, error)
+	mux         sync.Mutex
+	db          couchDatabase
+}
+
+// couchDBEntry is the document that holds a key-value pair. The value is encoded in base64 by the json encoding
+type couchDBEntry struct {
+	ID    string `json:"_id"`
+	Rev   string `json:"_rev,omitempty"`
+	Value []byte `json:"value,omitempty"`
+	// Deleted marks the document as deleted when it is written via the bulk api
+	Deleted bool `json:"_deleted,omitempty"`
+}
+
+func (s *couchDBStore) database() (couchDatabase, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if s.db == nil {
+		db, err := s.newDatabase(s.dbName)
+		if err != nil {
+			return nil, errors.WithMessage(err, fmt.Sprintf("error while creating the CouchDB database [%s] for the config history", s.dbName))
+		}
+		s.db = db
+	}
+	return s.db, nil
+}
+
+// Get implements function from the interface `Store`
+func (s *couchDBStore) Get(key []byte) ([]byte, error) {
+	db, err := s.database()
+	if err != nil {
+		return nil, err
+	}
+	doc, _, err := db.ReadDoc(hex.EncodeToString(key))
+	if err != nil || doc == nil {
+		return nil, err
+	}
+	entry, err := decodeCouchDBEntry(doc.JSONValue)
+	if err != nil {
+		return nil, err
+	}
+	return entry.Value, nil
+}
+
+// WriteBatch implements function from the interface `Store`. The documents are written in the chunks of the size configured
+// for the state db and, unlike the other stores, the chunks are not applied atomically (see function `NewCouchDBStoreProvider`)
+func (s *couchDBStore) WriteBatch(batch *leveldbhelper.UpdateBatch, sync bool) error {
+	db, err := s.database()
+	if err != nil {
+		return err
+	}
+	ids := make([]string, 0, len(batch.KVs))
+	values := make(map[string][]byte, len(batch.KVs))
+	for k, v := range batch.KVs {
+		id := hex.EncodeToString([]byte(k))
+		ids = append(ids, id)
+		values[id] = v
+	}
+	sort.Strings(ids)
+	maxBatchSize := ledgerconfig.GetMaxBatchUpdateSize()
+	if maxBatchSize <= 0 {
+		maxBatchSize = len(ids)
+	}
+	for len(ids) > 0 {
+		n := maxBatchSize
+		if n > len(ids) {
+			n = len(ids)
+		}
+		if err := s.writeChunk(db, ids[:n], values); err != nil {
+			return err
+		}
+		ids = ids[n:]
+	}
+	if sync {
+		if _, err := db.EnsureFullCommit(); err != nil {
+			return errors.WithMessage(err, fmt.Sprintf("error while syncing the CouchDB database [%s]", s.dbName))
+		}
+	}
+	return nil
+}
+
+// writeChunk writes the documents with the given ids. The current revisions of the documents are required for updating and
+// deleting the existing documents and hence, are retrieved first. The deletes of the non-existing documents are skipped
+func (s *couchDBStore) writeChunk(db couchDatabase, ids []string, values map[string][]byte) error {
+	metadata, err := db.BatchRetrieveDocumentMetadata(ids)
+	if err != nil {
+		return err
+	}
+	revs := map[string]string{}
+	for _, m := range metadata {
+		if m.ID != "" && m.Rev != "" {
+			revs[m.ID] = m.Rev
+		}
+	}
+	var docs []*couchdb.CouchDoc
+	for _, id := range ids {
+		entry := &couchDBEntry{ID: id, Rev: revs[id], Value: values[id]}
+		if entry.Value == nil {
+			if entry.Rev == "" {
+				continue
+			}
+			entry.Deleted = true
+		}
+		jsonValue, err := json.Marshal(entry)
+		if err != nil {
+			return errors.Wrap(err, "error while marshaling the config history document")
+		}
+		docs = append(docs, &couchdb.CouchDoc{JSONValue: jsonValue})
+	}
+	if len(docs) == 0 {
+		return nil
+	}
+	responses, err := db.BatchUpdateDocuments(docs)
+	if err != nil {
+		return err
+	}
+	for _, resp := range responses {
+		if !resp.Ok {
+			return errors.Errorf("error while writing the document [%s] to the CouchDB database [%s]: %s, %s", resp.ID, s.dbName, resp.Error, resp.Reason)
+		}
+	}
+	return nil
+}
+
+// GetIterator implements function from the interface `Store`. The range is read from CouchDB lazily, in the pages of the size
+// configured for the state db, as the iterator advances. Hence, a lookup that needs only the first few entries of a range reads
+// only the first page. As the pages are read at different times, the iterator may reflect the writes made after this call to
+// the part of the range not read yet. A failure in reading the range is reported by the function `Error` of the returned iterator
+func (s *couchDBStore) GetIterator(startKey []byte, endKey []byte) Iterator {
+	db, err := s.database()
+	if err != nil {
+		return &memIterator{index: -1, err: err}
+	}
+	// a nil key is represented by an empty string in the range query
+	return &couchDBIterator{
+		db:          db,
+		nextStartID: hex.EncodeToString(startKey),
+		endID:       hex.EncodeToString(endKey),
+		limit:       int32(ledgerconfig.GetInternalQueryLimit()),
+		index:       -1,
+	}
+}
+
+// couchDBIterator iterates over a range of the entries, reading the next page of the range from CouchDB once the entries of the
+// current page are consumed
+type couchDBIterator struct {
+	db          couchDatabase
+	nextStartID string
+	endID       string
+	limit       int32
+	exhausted   bool
+	keys        []string
+	values      [][]byte
+	index       int
+	err         error
+}
+
+func (itr *couchDBIterator) Next() bool {
+	for itr.index+1 >= len(itr.keys) {
+		if itr.exhausted || itr.err != nil {
+			itr.index = len(itr.keys)
+			return false
+		}
+		itr.readNextPage()
+	}
+	itr.index++
+	return true
+}
+
+func (itr *couchDBIterator) readNextPage() {
+	itr.keys, itr.values, itr.index = nil, nil, -1
+	results, nextStartID, err := itr.db.ReadDocRange(itr.nextStartID, itr.endID, itr.limit)
+	if err != nil {
+		itr.err = err
+		return
+	}
+	for _, result := range results {
+		// the ids that are not hex encoded keys, such as that of a design document, are not entries
+		key, err := hex.DecodeString(result.ID)
+		if err != nil || strings.HasPrefix(result.ID, "_") {
+			continue
+		}
+		entry, err := decodeCouchDBEntry(result.Value)
+		if err != nil {
+			itr.keys, itr.values, itr.err = nil, nil, err
+			return
+		}
+		itr.keys = append(itr.keys, string(key))
+		itr.values = append(itr.values, entry.Value)
+	}
+	if nextStartID == itr.endID || len(results) == 0 {
+		itr.exhausted = true
+	}
+	itr.nextStartID = nextStartID
+}
+
+func (itr *couchDBIterator) Key() []byte {
+	return []byte(itr.keys[itr.index])
+}
+
+func (itr *couchDBIterator) Value() []byte {
+	return itr.values[itr.index]
+}
+
+func (itr *couchDBIterator) Error() error {
+	return itr.err
+}
+
+func (itr *couchDBIterator) Release() {
+	itr.keys, itr.values = nil, nil
+	itr.index = 0
+	itr.exhausted = true
+}
+
+// ApproximateSize implements function from the interface `SizeEstimator`. The size is the size of the database file
+func (s *couchDBStore) ApproximateSize() (uint64, error) {
+	db, err := s.database()
+	if err != nil {
+		return 0, err
+	}
+	info, _, err := db.GetDatabaseInfo()
+	if err != nil {
+		return 0, err
+	}
+	return uint64(info.Sizes.File), nil
+}
+
+func decodeCouchDBEntry(jsonValue []byte) (*couchDBEntry, error) {
+	entry := &couchDBEntry{}
+	if err := json.Unmarshal(jsonValue, entry); err != nil {
+		return nil, errors.Wrap(err, "error while unmarshaling the config history document")
+	}
+	if entry.Value == nil {
+		entry.Value = []byte{}
+	}
+	return entry, nil
+}
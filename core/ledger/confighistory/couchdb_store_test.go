@@ -0,0 +1,260 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/hyperledger/fabric/common/ledger/util/leveldbhelper"
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/mock"
+	"github.com/hyperledger/fabric/core/ledger/util/couchdb"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCouchDBStore(t *testing.T) {
+	// small pages and batches exercise the paging of the range queries and the chunking of the writes
+	viper.Set("ledger.state.couchDBConfig.internalQueryLimit", 2)
+	viper.Set("ledger.state.couchDBConfig.maxBatchUpdateSize", 2)
+	defer viper.Reset()
+
+	provider, databases := newTestCouchDBStoreProvider()
+	store := provider.GetStore("ledger1")
+	assert.True(t, store == provider.GetStore("ledger1"))
+	assert.True(t, store != provider.GetStore("ledger2"))
+
+	batch := leveldbhelper.NewUpdateBatch()
+	for _, k := range []string{"key3", "key1", "key5", "key2", "key4"} {
+		batch.Put([]byte(k), []byte("val-"+k))
+	}
+	batch.Put([]byte{0xff, 0x00}, []byte{})
+	assert.NoError(t, store.WriteBatch(batch, true))
+	assert.Equal(t, 1, databases["ledger1__confighistory"].numSyncs)
+	checkMemStoreKeys(t, store, nil, nil, []string{"key1", "key2", "key3", "key4", "key5", string([]byte{0xff, 0x00})})
+	checkMemStoreKeys(t, store, []byte("key2"), []byte("key4"), []string{"key2", "key3"})
+	checkMemStoreKeys(t, store, []byte("key0"), []byte("key1"), nil)
+	checkMemStoreKeys(t, store, []byte("key45"), []byte{0xff}, []string{"key5"})
+
+	// the pages are read as the iterator advances
+	numRangeReads := databases["ledger1__confighistory"].numRangeReads
+	itr := store.GetIterator(nil, nil)
+	assert.Equal(t, numRangeReads, databases["ledger1__confighistory"].numRangeReads)
+	assert.True(t, itr.Next())
+	assert.Equal(t, "key1", string(itr.Key()))
+	assert.Equal(t, numRangeReads+1, databases["ledger1__confighistory"].numRangeReads)
+	keys := []string{string(itr.Key())}
+
+	batch = leveldbhelper.NewUpdateBatch()
+	batch.Delete([]byte("key2"))
+	batch.Delete([]byte("non-existing-key"))
+	batch.Put([]byte("key3"), []byte("new-val-key3"))
+	batch.Put([]byte("key0"), []byte("val-key0"))
+	assert.NoError(t, store.WriteBatch(batch, false))
+	assert.Equal(t, 1, databases["ledger1__confighistory"].numSyncs)
+	// the page already read is not affected by the subsequent writes, while the next pages reflect them
+	for itr.Next() {
+		keys = append(keys, string(itr.Key()))
+		if string(itr.Key()) == "key3" {
+			assert.Equal(t, []byte("new-val-key3"), itr.Value())
+		}
+	}
+	assert.NoError(t, itr.Error())
+	itr.Release()
+	assert.False(t, itr.Next())
+	assert.Equal(t, []string{"key1", "key2", "key3", "key4", "key5", string([]byte{0xff, 0x00})}, keys)
+	checkMemStoreKeys(t, store, nil, []byte{0xff}, []string{"key0", "key1", "key3", "key4", "key5"})
+
+	val, err := store.Get([]byte("key3"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("new-val-key3"), val)
+	val, err = store.Get([]byte{0xff, 0x00})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{}, val)
+	val, err = store.Get([]byte("key2"))
+	assert.NoError(t, err)
+	assert.Nil(t, val)
+
+	// a deleted key can be written again
+	batch = leveldbhelper.NewUpdateBatch()
+	batch.Put([]byte("key2"), []byte("val-key2"))
+	assert.NoError(t, store.WriteBatch(batch, true))
+	val, err = store.Get([]byte("key2"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("val-key2"), val)
+
+	size, err := store.(SizeEstimator).ApproximateSize()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(7), size)
+	checkMemStoreKeys(t, provider.GetStore("ledger2"), nil, nil, nil)
+}
+
+func TestCouchDBStoreErrors(t *testing.T) {
+	provider, databases := newTestCouchDBStoreProvider()
+	newDatabase := provider.newDatabase
+	provider.newDatabase = func(dbName string) (couchDatabase, error) {
+		return nil, errors.New("couchdb-unreachable")
+	}
+	store := provider.GetStore("ledger1")
+	_, err := store.Get([]byte("key1"))
+	assert.EqualError(t, err, "error while creating the CouchDB database [ledger1__confighistory] for the config history: couchdb-unreachable")
+	itr := store.GetIterator(nil, nil)
+	assert.False(t, itr.Next())
+	assert.EqualError(t, itr.Error(), "error while creating the CouchDB database [ledger1__confighistory] for the config history: couchdb-unreachable")
+
+	// the creation of the database is retried on the subsequent use
+	store.(*couchDBStore).newDatabase = newDatabase
+	batch := leveldbhelper.NewUpdateBatch()
+	batch.Put([]byte("key1"), []byte("val-key1"))
+	assert.NoError(t, store.WriteBatch(batch, true))
+
+	databases["ledger1__confighistory"].updateErr = errors.New("conflict")
+	assert.EqualError(t, store.WriteBatch(batch, true), "error while writing the document [6b657931] to the CouchDB database [ledger1__confighistory]: conflict, simulated")
+	databases["ledger1__confighistory"].rangeErr = errors.New("range-error")
+	itr = store.GetIterator(nil, nil)
+	assert.False(t, itr.Next())
+	assert.EqualError(t, itr.Error(), "range-error")
+}
+
+func TestMgrWithCouchDBStore(t *testing.T) {
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	provider, _ := newTestCouchDBStoreProvider()
+	mgr := NewMgrWithStore(mockCCInfoProvider, provider)
+	defer mgr.Close()
+	for _, blockNum := range []uint64{5, 10, 15} {
+		testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, "chaincode1", sampleCollectionConfigPackage("chaincode1", blockNum))
+		assert.NoError(t, mgr.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: blockNum}))
+	}
+	retriever := mgr.GetRetriever("ledger1", &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 100}})
+	for queryBlockNum, expectedBlockNum := range map[uint64]uint64{50: 15, 15: 10, 11: 10, 6: 5} {
+		collConfig, err := retriever.MostRecentCollectionConfigBelow(queryBlockNum, "chaincode1")
+		assert.NoError(t, err)
+		assert.Equal(t, expectedBlockNum, collConfig.CommittingBlockNum)
+		assert.Equal(t, sampleCollectionConfigPackage("chaincode1", expectedBlockNum).String(), collConfig.CollectionConfig.String())
+	}
+	collConfig, err := retriever.MostRecentCollectionConfigBelow(5, "chaincode1")
+	assert.NoError(t, err)
+	assert.Nil(t, collConfig)
+}
+
+func newTestCouchDBStoreProvider() (*couchDBStoreProvider, map[string]*fakeCouchDatabase) {
+	databases := map[string]*fakeCouchDatabase{}
+	provider := &couchDBStoreProvider{
+		newDatabase: func(dbName string) (couchDatabase, error) {
+			db, ok := databases[dbName]
+			if !ok {
+				db = &fakeCouchDatabase{docs: map[string]*couchDBEntry{}}
+				databases[dbName] = db
+			}
+			return db, nil
+		},
+		stores: map[string]*couchDBStore{},
+	}
+	return provider, databases
+}
+
+// fakeCouchDatabase mimics the semantics of the functions of `couchdb.CouchDatabase` used by the `couchDBStore`
+type fakeCouchDatabase struct {
+	docs      map[string]*couchDBEntry
+	revSeq    int
+	numSyncs  int
+	updateErr error
+	rangeErr  error
+	// numRangeReads is the number of the invocations of the function `ReadDocRange`
+	numRangeReads int
+}
+
+func (db *fakeCouchDatabase) ReadDoc(id string) (*couchdb.CouchDoc, string, error) {
+	entry, ok := db.docs[id]
+	if !ok {
+		return nil, "", nil
+	}
+	jsonValue, err := json.Marshal(entry)
+	return &couchdb.CouchDoc{JSONValue: jsonValue}, entry.Rev, err
+}
+
+func (db *fakeCouchDatabase) ReadDocRange(startKey, endKey string, limit int32) ([]*couchdb.QueryResult, string, error) {
+	db.numRangeReads++
+	if db.rangeErr != nil {
+		return nil, "", db.rangeErr
+	}
+	var ids []string
+	for id := range db.docs {
+		if id >= startKey && (endKey == "" || id < endKey) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	nextStartKey := endKey
+	if len(ids) > int(limit) {
+		nextStartKey = ids[limit]
+		ids = ids[:limit]
+	}
+	var results []*couchdb.QueryResult
+	for _, id := range ids {
+		jsonValue, err := json.Marshal(db.docs[id])
+		if err != nil {
+			return nil, "", err
+		}
+		results = append(results, &couchdb.QueryResult{ID: id, Value: jsonValue})
+	}
+	return results, nextStartKey, nil
+}
+
+func (db *fakeCouchDatabase) BatchRetrieveDocumentMetadata(keys []string) ([]*couchdb.DocMetadata, error) {
+	var metadata []*couchdb.DocMetadata
+	for _, key := range keys {
+		m := &couchdb.DocMetadata{}
+		if entry, ok := db.docs[key]; ok {
+			m.ID, m.Rev = key, entry.Rev
+		}
+		metadata = append(metadata, m)
+	}
+	return metadata, nil
+}
+
+func (db *fakeCouchDatabase) BatchUpdateDocuments(documents []*couchdb.CouchDoc) ([]*couchdb.BatchUpdateResponse, error) {
+	var responses []*couchdb.BatchUpdateResponse
+	for _, doc := range documents {
+		entry := &couchDBEntry{}
+		if err := json.Unmarshal(doc.JSONValue, entry); err != nil {
+			return nil, err
+		}
+		resp := &couchdb.BatchUpdateResponse{ID: entry.ID, Ok: true}
+		existing, ok := db.docs[entry.ID]
+		switch {
+		case db.updateErr != nil:
+			resp.Ok, resp.Error, resp.Reason = false, db.updateErr.Error(), "simulated"
+		case ok && existing.Rev != entry.Rev, !ok && entry.Rev != "":
+			resp.Ok, resp.Error = false, "conflict"
+		case entry.Deleted:
+			delete(db.docs, entry.ID)
+		default:
+			db.revSeq++
+			entry.Rev = fmt.Sprintf("%d-rev", db.revSeq)
+			db.docs[entry.ID] = entry
+		}
+		responses = append(responses, resp)
+	}
+	return responses, nil
+}
+
+func (db *fakeCouchDatabase) EnsureFullCommit() (*couchdb.DBOperationResponse, error) {
+	db.numSyncs++
+	return &couchdb.DBOperationResponse{Ok: true}, nil
+}
+
+func (db *fakeCouchDatabase) GetDatabaseInfo() (*couchdb.DBInfo, *couchdb.DBReturn, error) {
+	info := &couchdb.DBInfo{}
+	info.Sizes.File = len(db.docs)
+	return info, nil, nil
+}
@@ -0,0 +1,90 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// WriteAcrossLedgers applies the given batches to the config histories of the corresponding ledgers, in the increasing
+// order of the ledger ids, for the administrative flows that update more than one ledger together. The batch of each
+// ledger is written via the store of that ledger, as a separate write, even where the stores share the underlying db
+// (such as the single leveldb of all the ledgers, see `leveldbStoreProvider`), and hence, the writes are NOT atomic
+// across the ledgers. If the write to a ledger fails, the writes already applied to the preceding ledgers are rolled
+// back on a best-effort basis, by restoring the values that the written keys had before the write (i.e., deleting the
+// keys that did not exist). A crash of the peer midway, or a concurrent write to the same keys (e.g., by the commit of
+// a block), can still leave the ledgers partially updated. The returned `committed` lists the ledgers whose writes are
+// in effect when the function returns. On success, these are all the ledgers; on failure, these are the ledgers for
+// which the rollback itself failed, which are left for the caller to repair. The pending asynchronous writes, if any,
+// are applied before the batches. This function is not a part of the interface `Mgr`, because the batches are prepared
+// within this package
+func (m *mgr) WriteAcrossLedgers(updates map[string]*batch) (committed []string, err error) {
+	if err := m.WaitForPendingWrites(); err != nil {
+		return nil, err
+	}
+	var ledgerIDs []string
+	for ledgerID := range updates {
+		ledgerIDs = append(ledgerIDs, ledgerID)
+	}
+	sort.Strings(ledgerIDs)
+
+	undoBatches := map[string]*batch{}
+	for _, ledgerID := range ledgerIDs {
+		dbHandle := m.dbProvider.getDB(ledgerID)
+		undoBatch, err := dbHandle.undoBatchFor(updates[ledgerID])
+		if err == nil {
+			err = dbHandle.writeBatch(updates[ledgerID], m.syncWrites)
+		}
+		m.cache.removeLedger(ledgerID)
+		m.blockIndex.invalidate(ledgerID)
+		if err != nil {
+			return m.rollback(committed, undoBatches), errors.WithMessage(err,
+				fmt.Sprintf("error while writing to the config history of ledger [%s]", ledgerID))
+		}
+		committed = append(committed, ledgerID)
+		undoBatches[ledgerID] = undoBatch
+	}
+	return committed, nil
+}
+
+// rollback applies the undo batches of the given ledgers, in the reverse order of the writes, and returns the ledgers for
+// which the undo batch could not be applied
+func (m *mgr) rollback(ledgerIDs []string, undoBatches map[string]*batch) []string {
+	var notRolledBack []string
+	for i := len(ledgerIDs) - 1; i >= 0; i-- {
+		ledgerID := ledgerIDs[i]
+		err := m.dbProvider.getDB(ledgerID).writeBatch(undoBatches[ledgerID], m.syncWrites)
+		m.cache.removeLedger(ledgerID)
+		m.blockIndex.invalidate(ledgerID)
+		if err != nil {
+			logger.Errorf("Error while rolling back the write to the config history of ledger [%s]: %s", ledgerID, err)
+			notRolledBack = append(notRolledBack, ledgerID)
+		}
+	}
+	sort.Strings(notRolledBack)
+	return notRolledBack
+}
+
+// undoBatchFor returns a batch that restores the current values of the keys written by the given batch
+func (d *db) undoBatchFor(b *batch) (*batch, error) {
+	undoBatch := newBatch()
+	for key := range b.KVs {
+		value, err := d.Get([]byte(key))
+		if err != nil {
+			return nil, err
+		}
+		if value == nil {
+			undoBatch.Delete([]byte(key))
+			continue
+		}
+		undoBatch.Put([]byte(key), value)
+	}
+	return undoBatch, nil
+}
@@ -0,0 +1,113 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/common/ledger/util/leveldbhelper"
+	"github.com/hyperledger/fabric/core/ledger/mock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteAcrossLedgers(t *testing.T) {
+	key := constructCollectionConfigKey("chaincode1")
+	setup := func(t *testing.T, storeProvider StoreProvider) *mgr {
+		m := newMgrWithDBProvider(&mock.DeployedChaincodeInfoProvider{}, newDBProviderWithStore(storeProvider))
+		for _, ledgerID := range []string{"ledger1", "ledger2", "ledger3"} {
+			b := newBatch()
+			b.add(collectionConfigNamespace, key, 10, []byte("old-value"))
+			assert.NoError(t, m.dbProvider.getDB(ledgerID).writeBatch(b, true))
+		}
+		return m
+	}
+	updates := func() map[string]*batch {
+		updates := map[string]*batch{}
+		for _, ledgerID := range []string{"ledger1", "ledger2", "ledger3"} {
+			b := newBatch()
+			b.add(collectionConfigNamespace, key, 10, []byte("new-value"))
+			b.add(collectionConfigNamespace, key, 20, []byte("new-value"))
+			updates[ledgerID] = b
+		}
+		return updates
+	}
+	checkValues := func(t *testing.T, m *mgr, ledgerID string, expectedAt10, expectedAt20 []byte) {
+		dbHandle := m.dbProvider.getDB(ledgerID)
+		for blockNum, expected := range map[uint64][]byte{10: expectedAt10, 20: expectedAt20} {
+			v, err := dbHandle.Get(encodeCompositeKey(collectionConfigNamespace, key, blockNum))
+			assert.NoError(t, err)
+			assert.Equal(t, expected, v, "ledger=%s, block=%d", ledgerID, blockNum)
+		}
+	}
+
+	t.Run("all-committed", func(t *testing.T) {
+		m := setup(t, NewMemStoreProvider())
+		defer m.Close()
+		committed, err := m.WriteAcrossLedgers(updates())
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"ledger1", "ledger2", "ledger3"}, committed)
+		for _, ledgerID := range committed {
+			checkValues(t, m, ledgerID, []byte("new-value"), []byte("new-value"))
+		}
+	})
+
+	t.Run("rolled-back", func(t *testing.T) {
+		storeProvider := &selectivelyFailingStoreProvider{StoreProvider: NewMemStoreProvider(), failingLedgers: map[string]int{}}
+		m := setup(t, storeProvider)
+		defer m.Close()
+		storeProvider.failingLedgers["ledger3"] = 0
+		committed, err := m.WriteAcrossLedgers(updates())
+		assert.EqualError(t, err, "error while writing to the config history of ledger [ledger3]: write-failure")
+		assert.Nil(t, committed)
+		for _, ledgerID := range []string{"ledger1", "ledger2", "ledger3"} {
+			checkValues(t, m, ledgerID, []byte("old-value"), nil)
+		}
+	})
+
+	t.Run("rollback-failure", func(t *testing.T) {
+		storeProvider := &selectivelyFailingStoreProvider{StoreProvider: NewMemStoreProvider(), failingLedgers: map[string]int{}}
+		m := setup(t, storeProvider)
+		defer m.Close()
+		// the write to ledger1 succeeds and the subsequent rollback fails
+		storeProvider.failingLedgers["ledger1"] = 1
+		storeProvider.failingLedgers["ledger3"] = 0
+		committed, err := m.WriteAcrossLedgers(updates())
+		assert.EqualError(t, err, "error while writing to the config history of ledger [ledger3]: write-failure")
+		assert.Equal(t, []string{"ledger1"}, committed)
+		checkValues(t, m, "ledger1", []byte("new-value"), []byte("new-value"))
+		checkValues(t, m, "ledger2", []byte("old-value"), nil)
+	})
+}
+
+type selectivelyFailingStoreProvider struct {
+	StoreProvider
+	// failingLedgers maps a ledger id to the number of writes that succeed before the writes start failing
+	failingLedgers map[string]int
+}
+
+func (p *selectivelyFailingStoreProvider) GetStore(ledgerID string) Store {
+	return &selectivelyFailingStore{Store: p.StoreProvider.GetStore(ledgerID), ledgerID: ledgerID, provider: p}
+}
+
+type selectivelyFailingStore struct {
+	Store
+	ledgerID string
+	provider *selectivelyFailingStoreProvider
+}
+
+func (s *selectivelyFailingStore) WriteBatch(batch *leveldbhelper.UpdateBatch, sync bool) error {
+	numSucceeding, ok := s.provider.failingLedgers[s.ledgerID]
+	if !ok {
+		return s.Store.WriteBatch(batch, sync)
+	}
+	if numSucceeding == 0 {
+		return errors.New("write-failure")
+	}
+	s.provider.failingLedgers[s.ledgerID] = numSucceeding - 1
+	return s.Store.WriteBatch(batch, sync)
+}
@@ -10,6 +10,8 @@ import (
 	"bytes"
 	"encoding/binary"
 	"math"
+	"sort"
+	"sync"
 
 	"github.com/hyperledger/fabric/common/ledger/util/leveldbhelper"
 	"github.com/pkg/errors"
@@ -30,12 +32,26 @@ type compositeKV struct {
 	value []byte
 }
 
+// ErrMgrClosed is returned by the functions of the `Mgr` and of the retrievers obtained from it, once the `Mgr` is closed
+var ErrMgrClosed = errors.New("config history manager is closed")
+
 type dbProvider struct {
-	*leveldbhelper.Provider
+	StoreProvider
+	mux   sync.Mutex
+	dbs   map[string]*db
+	state *openState
+}
+
+// openState tracks whether the provider is closed
+type openState struct {
+	sync.RWMutex
+	closed bool
 }
 
+// db wraps the store of a ledger and fails the operations with `ErrMgrClosed` once the provider is closed
 type db struct {
-	*leveldbhelper.DBHandle
+	Store
+	state *openState
 }
 
 type batch struct {
@@ -44,7 +60,26 @@ type batch struct {
 
 func newDBProvider(dbPath string) *dbProvider {
 	logger.Debugf("Opening db for config history: db path = %s", dbPath)
-	return &dbProvider{leveldbhelper.NewProvider(&leveldbhelper.Conf{DBPath: dbPath})}
+	return newDBProviderWithStore(newLeveldbStoreProvider(dbPath))
+}
+
+func newDBProviderWithStore(storeProvider StoreProvider) *dbProvider {
+	return &dbProvider{
+		StoreProvider: storeProvider,
+		dbs:           map[string]*db{},
+		state:         &openState{},
+	}
+}
+
+// Close marks the provider as closed, waiting for the in-progress operations on the stores, and closes the stores
+func (p *dbProvider) Close() {
+	p.state.Lock()
+	defer p.state.Unlock()
+	if p.state.closed {
+		return
+	}
+	p.state.closed = true
+	p.StoreProvider.Close()
 }
 
 func newBatch() *batch {
@@ -52,7 +87,26 @@ func newBatch() *batch {
 }
 
 func (p *dbProvider) getDB(id string) *db {
-	return &db{p.GetDBHandle(id)}
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	dbHandle, ok := p.dbs[id]
+	if !ok {
+		dbHandle = &db{Store: p.GetStore(id), state: p.state}
+		p.dbs[id] = dbHandle
+	}
+	return dbHandle
+}
+
+// ledgerIDs returns, in sorted order, the ids of the ledgers for which a db handle has been obtained
+func (p *dbProvider) ledgerIDs() []string {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	ids := make([]string, 0, len(p.dbs))
+	for id := range p.dbs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
 }
 
 func (b *batch) add(ns, key string, blockNum uint64, value []byte) {
@@ -61,6 +115,70 @@ func (b *batch) add(ns, key string, blockNum uint64, value []byte) {
 	b.Put(k, v)
 }
 
+// checkOpen acquires the read lock of the state and returns `ErrMgrClosed` if the provider is closed
+func (d *db) checkOpen() (func(), error) {
+	if d.state == nil {
+		return func() {}, nil
+	}
+	d.state.RLock()
+	if d.state.closed {
+		d.state.RUnlock()
+		return func() {}, ErrMgrClosed
+	}
+	return d.state.RUnlock, nil
+}
+
+// Get shadows the function `Store.Get`
+func (d *db) Get(key []byte) ([]byte, error) {
+	release, err := d.checkOpen()
+	defer release()
+	if err != nil {
+		return nil, err
+	}
+	return d.Store.Get(key)
+}
+
+// GetIterator shadows the function `Store.GetIterator`
+func (d *db) GetIterator(startKey []byte, endKey []byte) Iterator {
+	release, err := d.checkOpen()
+	defer release()
+	if err != nil {
+		return &closedIterator{}
+	}
+	return d.Store.GetIterator(startKey, endKey)
+}
+
+// GetReverseIterator is same as `GetIterator` but presents the keys in the decreasing order
+func (d *db) GetReverseIterator(startKey []byte, endKey []byte) Iterator {
+	release, err := d.checkOpen()
+	defer release()
+	if err != nil {
+		return &closedIterator{}
+	}
+	if reverseIterable, ok := d.Store.(ReverseIterable); ok {
+		return reverseIterable.GetReverseIterator(startKey, endKey)
+	}
+	return reversedIterator(d.Store.GetIterator(startKey, endKey))
+}
+
+// WriteBatch shadows the function `Store.WriteBatch`
+func (d *db) WriteBatch(batch *leveldbhelper.UpdateBatch, sync bool) error {
+	release, err := d.checkOpen()
+	defer release()
+	if err != nil {
+		return err
+	}
+	return d.Store.WriteBatch(batch, sync)
+}
+
+type closedIterator struct{}
+
+func (itr *closedIterator) Next() bool    { return false }
+func (itr *closedIterator) Key() []byte   { return nil }
+func (itr *closedIterator) Value() []byte { return nil }
+func (itr *closedIterator) Error() error  { return ErrMgrClosed }
+func (itr *closedIterator) Release()      {}
+
 func (d *db) writeBatch(batch *batch, sync bool) error {
 	return d.WriteBatch(batch.UpdateBatch, sync)
 }
@@ -71,9 +189,13 @@ func (d *db) mostRecentEntryBelow(blockNum uint64, ns, key string) (*compositeKV
 		return nil, errors.New("blockNum should be greater than 0")
 	}
 	startKey := encodeCompositeKey(ns, key, blockNum-1)
-	itr := d.GetIterator(startKey, nil)
+	stopKey := append(encodeCompositeKey(ns, key, 0), byte(0))
+	itr := d.GetIterator(startKey, stopKey)
 	defer itr.Release()
 	if !itr.Next() {
+		if err := itr.Error(); err != nil {
+			return nil, errors.Wrap(err, "error while iterating the config history db")
+		}
 		logger.Debugf("Key no entry found. Returning nil")
 		return nil, nil
 	}
@@ -81,6 +203,25 @@ func (d *db) mostRecentEntryBelow(blockNum uint64, ns, key string) (*compositeKV
 	return &compositeKV{k, v}, nil
 }
 
+// mostRecentEntriesAtOrBelow returns up to n most recent entries of the given <ns, key> at or below the given block
+func (d *db) mostRecentEntriesAtOrBelow(blockNum uint64, ns, key string, n int) ([]*compositeKV, error) {
+	logger.Debugf("mostRecentEntriesAtOrBelow() - {%s, %s, %d, %d}", ns, key, blockNum, n)
+	startKey := encodeCompositeKey(ns, key, blockNum)
+	stopKey := append(encodeCompositeKey(ns, key, 0), byte(0))
+	itr := d.GetIterator(startKey, stopKey)
+	defer itr.Release()
+	var kvs []*compositeKV
+	for len(kvs) < n && itr.Next() {
+		k := decodeCompositeKey(itr.Key())
+		v := append([]byte(nil), itr.Value()...)
+		kvs = append(kvs, &compositeKV{k, v})
+	}
+	if err := itr.Error(); err != nil {
+		return nil, errors.Wrap(err, "error while iterating the config history db")
+	}
+	return kvs, nil
+}
+
 func (d *db) entryAt(blockNum uint64, ns, key string) (*compositeKV, error) {
 	logger.Debugf("entryAt() - {%s, %s, %d}", ns, key, blockNum)
 	keyBytes := encodeCompositeKey(ns, key, blockNum)
@@ -95,6 +236,320 @@ func (d *db) entryAt(blockNum uint64, ns, key string) (*compositeKV, error) {
 	return &compositeKV{k, v}, nil
 }
 
+// forEachEntry invokes the given function for each of the entries in the db, in the order of the composite keys
+func (d *db) forEachEntry(f func(kv *compositeKV) error) error {
+	itr := d.GetIterator(nil, nil)
+	defer itr.Release()
+	for itr.Next() {
+		k := decodeCompositeKey(itr.Key())
+		v := append([]byte(nil), itr.Value()...)
+		if err := f(&compositeKV{k, v}); err != nil {
+			return err
+		}
+	}
+	return errors.Wrap(itr.Error(), "error while iterating the config history db")
+}
+
+// entriesInRange returns a page of the entries of the given <ns, key> in the range [startBlockNum, endBlockNum]
+func (d *db) entriesInRange(ns, key string, startBlockNum, endBlockNum uint64, limit int, direction Direction) ([]*compositeKV, bool, error) {
+	logger.Debugf("entriesInRange() - {%s, %s, %d, %d, %d, %s}", ns, key, startBlockNum, endBlockNum, limit, direction)
+	startKey := encodeCompositeKey(ns, key, endBlockNum)
+	stopKey := append(encodeCompositeKey(ns, key, startBlockNum), byte(0))
+	var itr Iterator
+	if direction == OldestFirst {
+		itr = d.GetReverseIterator(startKey, stopKey)
+	} else {
+		itr = d.GetIterator(startKey, stopKey)
+	}
+	defer itr.Release()
+	var kvs []*compositeKV
+	for itr.Next() {
+		if limit > 0 && len(kvs) == limit {
+			return kvs, true, nil
+		}
+		k := decodeCompositeKey(itr.Key())
+		v := append([]byte(nil), itr.Value()...)
+		kvs = append(kvs, &compositeKV{k, v})
+	}
+	if err := itr.Error(); err != nil {
+		return nil, false, errors.Wrap(err, "error while iterating the config history db")
+	}
+	return kvs, false, nil
+}
+
+// hasEntryInRange returns true if the given <ns, key> has an entry in the range [startBlockNum, endBlockNum]
+func (d *db) hasEntryInRange(ns, key string, startBlockNum, endBlockNum uint64) (bool, error) {
+	logger.Debugf("hasEntryInRange() - {%s, %s, %d, %d}", ns, key, startBlockNum, endBlockNum)
+	startKey := encodeCompositeKey(ns, key, endBlockNum)
+	stopKey := append(encodeCompositeKey(ns, key, startBlockNum), byte(0))
+	itr := d.GetIterator(startKey, stopKey)
+	defer itr.Release()
+	found := itr.Next()
+	if err := itr.Error(); err != nil {
+		return false, errors.Wrap(err, "error while iterating the config history db")
+	}
+	return found, nil
+}
+
+// blockNumsOf returns, in the increasing order, the block numbers of the entries of the given <ns, key>
+func (d *db) blockNumsOf(ns, key string) ([]uint64, error) {
+	logger.Debugf("blockNumsOf() - {%s, %s}", ns, key)
+	startKey := encodeCompositeKey(ns, key, math.MaxUint64)
+	stopKey := append(encodeCompositeKey(ns, key, 0), byte(0))
+	itr := d.GetIterator(startKey, stopKey)
+	defer itr.Release()
+	var blockNums []uint64
+	for itr.Next() {
+		blockNums = append(blockNums, decodeCompositeKey(itr.Key()).blockNum)
+	}
+	if err := itr.Error(); err != nil {
+		return nil, errors.Wrap(err, "error while iterating the config history db")
+	}
+	// the entries are ordered by the decreasing block numbers
+	for i, j := 0, len(blockNums)-1; i < j; i, j = i+1, j-1 {
+		blockNums[i], blockNums[j] = blockNums[j], blockNums[i]
+	}
+	return blockNums, nil
+}
+
+// oldestBlockNum returns the lowest block number of the entries of the given <ns, key>, if any
+func (d *db) oldestBlockNum(ns, key string) (uint64, bool, error) {
+	logger.Debugf("oldestBlockNum() - {%s, %s}", ns, key)
+	startKey := encodeCompositeKey(ns, key, math.MaxUint64)
+	stopKey := append(encodeCompositeKey(ns, key, 0), byte(0))
+	itr := d.GetIterator(startKey, stopKey)
+	defer itr.Release()
+	var lastKey []byte
+	for itr.Next() {
+		lastKey = append(lastKey[:0], itr.Key()...)
+	}
+	if err := itr.Error(); err != nil {
+		return 0, false, errors.Wrap(err, "error while iterating the config history db")
+	}
+	if lastKey == nil {
+		return 0, false, nil
+	}
+	return decodeCompositeKey(lastKey).blockNum, true, nil
+}
+
+// keysWithEntryAt returns the keys in the given namespace that have an entry at exactly the given block
+func (d *db) keysWithEntryAt(blockNum uint64, ns string) ([]string, error) {
+	logger.Debugf("keysWithEntryAt() - {%s, %d}", ns, blockNum)
+	startKey, endKey := encodeNamespaceRange(ns)
+	itr := d.GetIterator(startKey, endKey)
+	defer itr.Release()
+	var keys []string
+	for itr.Next() {
+		k := decodeCompositeKey(itr.Key())
+		if k.blockNum == blockNum {
+			keys = append(keys, k.key)
+		}
+	}
+	if err := itr.Error(); err != nil {
+		return nil, errors.Wrap(err, "error while iterating the config history db")
+	}
+	return keys, nil
+}
+
+// entriesAt returns the entries in the given namespace that are committed at exactly the given block
+func (d *db) entriesAt(blockNum uint64, ns string) ([]*compositeKV, error) {
+	logger.Debugf("entriesAt() - {%s, %d}", ns, blockNum)
+	startKey, endKey := encodeNamespaceRange(ns)
+	itr := d.GetIterator(startKey, endKey)
+	defer itr.Release()
+	var entries []*compositeKV
+	for itr.Next() {
+		k := decodeCompositeKey(itr.Key())
+		if k.blockNum == blockNum {
+			entries = append(entries, &compositeKV{k, append([]byte(nil), itr.Value()...)})
+		}
+	}
+	if err := itr.Error(); err != nil {
+		return nil, errors.Wrap(err, "error while iterating the config history db")
+	}
+	return entries, nil
+}
+
+// keysWithPrefix returns the distinct keys in the given namespace that start with the given prefix
+func (d *db) keysWithPrefix(ns, prefix string) ([]string, error) {
+	logger.Debugf("keysWithPrefix() - {%s, %s}", ns, prefix)
+	startKey := append([]byte(keyPrefix+ns), separatorByte)
+	startKey = append(startKey, []byte(prefix)...)
+	itr := d.GetIterator(startKey, nil)
+	defer itr.Release()
+	found := map[string]struct{}{}
+	var keys []string
+	for itr.Next() {
+		if !bytes.HasPrefix(itr.Key(), startKey) {
+			break
+		}
+		k := decodeCompositeKey(itr.Key())
+		if _, ok := found[k.key]; ok {
+			continue
+		}
+		found[k.key] = struct{}{}
+		keys = append(keys, k.key)
+	}
+	if err := itr.Error(); err != nil {
+		return nil, errors.Wrap(err, "error while iterating the config history db")
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// numEntries returns the number of entries in the db
+func (d *db) numEntries() (uint64, error) {
+	return d.numEntriesMatching(nil)
+}
+
+// numEntriesMatching returns the number of entries whose keys pass the filter. A nil filter matches all the entries
+func (d *db) numEntriesMatching(filter func(*compositeKey) bool) (uint64, error) {
+	itr := d.GetIterator(nil, nil)
+	defer itr.Release()
+	var n uint64
+	for itr.Next() {
+		if filter == nil || filter(decodeCompositeKey(itr.Key())) {
+			n++
+		}
+	}
+	if err := itr.Error(); err != nil {
+		return 0, errors.Wrap(err, "error while iterating the config history db")
+	}
+	return n, nil
+}
+
+// isEmpty returns true if the db does not contain any entry
+func (d *db) isEmpty() (bool, error) {
+	itr := d.GetIterator(nil, nil)
+	defer itr.Release()
+	empty := !itr.Next()
+	if err := itr.Error(); err != nil {
+		return false, errors.Wrap(err, "error while iterating the config history db")
+	}
+	return empty, nil
+}
+
+// maxBlockNum returns the highest block number across all the entries in the db, if any
+func (d *db) maxBlockNum() (uint64, bool, error) {
+	itr := d.GetIterator(nil, nil)
+	defer itr.Release()
+	var max uint64
+	found := false
+	for itr.Next() {
+		k := decodeCompositeKey(itr.Key())
+		if !found || k.blockNum > max {
+			max, found = k.blockNum, true
+		}
+	}
+	if err := itr.Error(); err != nil {
+		return 0, false, errors.Wrap(err, "error while iterating the config history db")
+	}
+	return max, found, nil
+}
+
+// pruneBelow deletes the entries below the given block except the most recent one at or below it, per <ns, key>
+func (d *db) pruneBelow(blockNum uint64) (int, error) {
+	logger.Debugf("pruneBelow() - {%d}", blockNum)
+	numDeleted := 0
+	err := d.forEachPrunableRange(blockNum, func(rangeStart, rangeEnd []byte) error {
+		n, err := d.deleteRange(rangeStart, rangeEnd)
+		numDeleted += n
+		return err
+	})
+	return numDeleted, err
+}
+
+// prunableSize returns the number and the size of the entries that `pruneBelow` would delete
+func (d *db) prunableSize(blockNum uint64) (int, uint64, error) {
+	numEntries, size := 0, uint64(0)
+	err := d.forEachPrunableRange(blockNum, func(rangeStart, rangeEnd []byte) error {
+		itr := d.GetIterator(rangeStart, rangeEnd)
+		defer itr.Release()
+		for itr.Next() {
+			numEntries++
+			size += uint64(len(itr.Key()) + len(itr.Value()))
+		}
+		return errors.Wrap(itr.Error(), "error while iterating the config history db")
+	})
+	return numEntries, size, err
+}
+
+// forEachPrunableRange invokes the given function with the range of the prunable entries of each <ns, key>
+func (d *db) forEachPrunableRange(blockNum uint64, f func(rangeStart, rangeEnd []byte) error) error {
+	var startKey []byte
+	for {
+		retained, err := d.firstEntryAtOrBelow(startKey, blockNum)
+		if err != nil || retained == nil {
+			return err
+		}
+		rangeStart := append(encodeCompositeKey(retained.ns, retained.key, retained.blockNum), byte(0))
+		rangeEnd := append(encodeCompositeKey(retained.ns, retained.key, 0), byte(0))
+		if err := f(rangeStart, rangeEnd); err != nil {
+			return err
+		}
+		startKey = rangeEnd
+	}
+}
+
+// firstEntryAtOrBelow returns the key of the first entry from the given key with a block number at or below the given one
+func (d *db) firstEntryAtOrBelow(startKey []byte, blockNum uint64) (*compositeKey, error) {
+	itr := d.GetIterator(startKey, nil)
+	defer itr.Release()
+	for itr.Next() {
+		if k := decodeCompositeKey(itr.Key()); k.blockNum <= blockNum {
+			return k, nil
+		}
+	}
+	if err := itr.Error(); err != nil {
+		return nil, errors.Wrap(err, "error while iterating the config history db")
+	}
+	return nil, nil
+}
+
+// deleteAllEntries deletes the entries of the given <ns, key> for all the block numbers and returns the number of entries deleted
+func (d *db) deleteAllEntries(ns, key string) (int, error) {
+	logger.Debugf("deleteAllEntries() - {%s, %s}", ns, key)
+	startKey := encodeCompositeKey(ns, key, math.MaxUint64)
+	stopKey := append(encodeCompositeKey(ns, key, 0), byte(0))
+	return d.deleteRange(startKey, stopKey)
+}
+
+// deleteAll deletes all the entries in the db and returns the number of entries deleted
+func (d *db) deleteAll() (int, error) {
+	logger.Debugf("deleteAll()")
+	return d.deleteRange(nil, nil)
+}
+
+// deleteRange deletes the entries in [startKey, endKey) and returns the number of entries deleted
+func (d *db) deleteRange(startKey, endKey []byte) (int, error) {
+	if rangeDeleter, ok := d.Store.(RangeDeleter); ok {
+		release, err := d.checkOpen()
+		defer release()
+		if err != nil {
+			return 0, err
+		}
+		n, err := rangeDeleter.DeleteRange(startKey, endKey, true)
+		return n, errors.Wrap(err, "error while deleting a range in the config history db")
+	}
+	return d.deleteRangeByKeys(startKey, endKey)
+}
+
+func (d *db) deleteRangeByKeys(startKey, endKey []byte) (int, error) {
+	batch := newBatch()
+	itr := d.GetIterator(startKey, endKey)
+	defer itr.Release()
+	for itr.Next() {
+		batch.Delete(append([]byte(nil), itr.Key()...))
+	}
+	if err := itr.Error(); err != nil {
+		return 0, errors.Wrap(err, "error while iterating the config history db")
+	}
+	if err := d.writeBatch(batch, true); err != nil {
+		return 0, err
+	}
+	return batch.Len(), nil
+}
+
 func encodeCompositeKey(ns, key string, blockNum uint64) []byte {
 	b := []byte(keyPrefix + ns)
 	b = append(b, separatorByte)
@@ -102,6 +557,13 @@ func encodeCompositeKey(ns, key string, blockNum uint64) []byte {
 	return append(b, encodeBlockNum(blockNum)...)
 }
 
+// encodeNamespaceRange returns the start key (inclusive) and the end key (exclusive) of the composite keys of the given namespace
+func encodeNamespaceRange(ns string) ([]byte, []byte) {
+	startKey := append([]byte(keyPrefix+ns), separatorByte)
+	endKey := append([]byte(keyPrefix+ns), separatorByte+1)
+	return startKey, endKey
+}
+
 func decodeCompositeKey(b []byte) *compositeKey {
 	blockNumStartIndex := len(b) - 8
 	nsKeyBytes, blockNumBytes := b[1:blockNumStartIndex], b[blockNumStartIndex:]
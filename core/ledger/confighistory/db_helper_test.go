@@ -8,6 +8,7 @@ package confighistory
 
 import (
 	"bytes"
+	"fmt"
 	"math"
 	"os"
 	"testing"
@@ -39,7 +40,15 @@ func TestQueries(t *testing.T) {
 	provider := newDBProvider(testDBPath)
 	defer deleteTestPath(t, testDBPath)
 
-	db := provider.getDB("ledger1")
+	t.Run("leveldb", func(t *testing.T) {
+		testQueries(t, provider.getDB("ledger1"))
+	})
+	t.Run("memstore", func(t *testing.T) {
+		testQueries(t, newDBProviderWithStore(NewMemStoreProvider()).getDB("ledger1"))
+	})
+}
+
+func testQueries(t *testing.T, db *db) {
 	// A query on an empty store
 	checkEntryAt(t, "testcase-query1", db, "ns1", "key1", 45, nil)
 	// test data
@@ -62,6 +71,94 @@ func TestQueries(t *testing.T) {
 	checkEntryAt(t, "testcase-query8", db, "ns1", "key1", 0, sampleData[4])
 	checkEntryAt(t, "testcase-query9", db, "ns1", "key1", 35, nil)
 	checkEntryAt(t, "testcase-query10", db, "ns1", "key1", 45, nil)
+
+	// a key that sorts before an existing key but does not have any entry of its own
+	checkRecentEntryBelow(t, "testcase-query11", db, "ns1", "key0", 45, nil)
+}
+
+func TestDeleteRange(t *testing.T) {
+	sampleData := []*compositeKV{
+		{&compositeKey{ns: "ns1", key: "key1", blockNum: 40}, []byte("val1_40")},
+		{&compositeKey{ns: "ns1", key: "key1", blockNum: 20}, []byte("val1_20")},
+		{&compositeKey{ns: "ns1", key: "key1", blockNum: 10}, []byte("val1_10")},
+		{&compositeKey{ns: "ns1", key: "key2", blockNum: 30}, []byte("val2_30")},
+		{&compositeKey{ns: "ns1", key: "key2", blockNum: 5}, []byte("val2_5")},
+		{&compositeKey{ns: "ns2", key: "key1", blockNum: 10}, []byte("val3_10")},
+	}
+	testCases := []struct {
+		name  string
+		store Store
+	}{
+		{"range-deleter", NewMemStoreProvider().GetStore("ledger1")},
+		// hides the function DeleteRange of the mem store
+		{"per-key-batch", struct{ Store }{NewMemStoreProvider().GetStore("ledger1")}},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			db := &db{Store: testCase.store}
+			populateDBWithSampleData(t, db, sampleData)
+
+			numPruned, err := db.pruneBelow(25)
+			assert.NoError(t, err)
+			assert.Equal(t, 1, numPruned)
+			checkEntryAt(t, "pruned", db, "ns1", "key1", 10, nil)
+			checkEntryAt(t, "retained", db, "ns1", "key1", 20, sampleData[1])
+			checkEntryAt(t, "retained-above", db, "ns1", "key1", 40, sampleData[0])
+
+			numDeleted, err := db.deleteAllEntries("ns1", "key1")
+			assert.NoError(t, err)
+			assert.Equal(t, 2, numDeleted)
+			checkRecentEntryBelow(t, "deleted", db, "ns1", "key1", 50, nil)
+			checkRecentEntryBelow(t, "other-key", db, "ns1", "key2", 50, sampleData[3])
+
+			numDeleted, err = db.deleteAll()
+			assert.NoError(t, err)
+			assert.Equal(t, 3, numDeleted)
+			empty, err := db.isEmpty()
+			assert.NoError(t, err)
+			assert.True(t, empty)
+		})
+	}
+}
+
+func BenchmarkDeleteAllEntries(b *testing.B) {
+	dbPath := "/tmp/fabric/core/ledger/confighistory/benchmark"
+	os.RemoveAll(dbPath)
+	defer os.RemoveAll(dbPath)
+	provider := newDBProvider(dbPath)
+	defer provider.Close()
+
+	const numVersions = 10000
+	key := constructCollectionConfigKey("chaincode1")
+	populate := func(b *testing.B, dbHandle *db) {
+		batch := newBatch()
+		for blockNum := uint64(1); blockNum <= numVersions; blockNum++ {
+			batch.add(collectionConfigNamespace, key, blockNum, []byte(fmt.Sprintf("value-%d", blockNum)))
+		}
+		if err := dbHandle.writeBatch(batch, true); err != nil {
+			b.Fatal(err)
+		}
+	}
+	testCases := []struct {
+		name  string
+		store Store
+	}{
+		{"range-delete", provider.GetStore("ledger1")},
+		{"per-key-batch", struct{ Store }{provider.GetStore("ledger2")}},
+	}
+	for _, testCase := range testCases {
+		b.Run(testCase.name, func(b *testing.B) {
+			dbHandle := &db{Store: testCase.store}
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				populate(b, dbHandle)
+				b.StartTimer()
+				if _, err := dbHandle.deleteAllEntries(collectionConfigNamespace, key); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
 }
 
 func populateDBWithSampleData(t *testing.T, db *db, sampledata []*compositeKV) {
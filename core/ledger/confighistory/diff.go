@@ -0,0 +1,111 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"github.com/hyperledger/fabric/protos/common"
+)
+
+// CollectionConfigDiffResult is the difference between the collection config of a chaincode in effect at a block and the latest
+// collection config of the chaincode, as returned by the function `Retriever.DiffFromLatest`
+type CollectionConfigDiffResult struct {
+	ChaincodeName string
+	// BlockNum is the block for which the diff is requested
+	BlockNum uint64
+	// FromBlockNum is the block at which the collection config in effect at `BlockNum` is committed. It is zero if the chaincode
+	// has no collection config at `BlockNum`, in which case all the collections of the latest collection config are reported as added
+	FromBlockNum uint64
+	// LatestBlockNum is the block at which the latest collection config is committed
+	LatestBlockNum uint64
+	// Added, Removed, and Modified are the names of the collections, in the order in which these appear in the latest collection
+	// config (for Added and Modified) or in the collection config in effect at `BlockNum` (for Removed)
+	Added    []string
+	Removed  []string
+	Modified []string
+}
+
+// Empty returns true if the collection config in effect at the block is same as the latest collection config
+func (d *CollectionConfigDiffResult) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Modified) == 0
+}
+
+// DiffCollectionConfigs compares the static collections of the two collection config packages by their names and returns the
+// names of the collections that are present only in `to` (added), present only in `from` (removed), and present in both but
+// not semantically equal (modified; see function `CollectionConfigsEqual`). Either of the packages may be nil
+func DiffCollectionConfigs(from, to *common.CollectionConfigPackage) (added, removed, modified []string) {
+	fromColls := staticCollectionsByName(from)
+	toColls := staticCollectionsByName(to)
+	for _, collConfig := range to.GetConfig() {
+		staticCollConfig := collConfig.GetStaticCollectionConfig()
+		if staticCollConfig == nil {
+			continue
+		}
+		fromCollConfig, ok := fromColls[staticCollConfig.Name]
+		switch {
+		case !ok:
+			added = append(added, staticCollConfig.Name)
+		case !collectionConfigEqual(fromCollConfig, collConfig):
+			modified = append(modified, staticCollConfig.Name)
+		}
+	}
+	for _, collConfig := range from.GetConfig() {
+		staticCollConfig := collConfig.GetStaticCollectionConfig()
+		if staticCollConfig == nil {
+			continue
+		}
+		if _, ok := toColls[staticCollConfig.Name]; !ok {
+			removed = append(removed, staticCollConfig.Name)
+		}
+	}
+	return added, removed, modified
+}
+
+func staticCollectionsByName(pkg *common.CollectionConfigPackage) map[string]*common.CollectionConfig {
+	m := map[string]*common.CollectionConfig{}
+	for _, collConfig := range pkg.GetConfig() {
+		if staticCollConfig := collConfig.GetStaticCollectionConfig(); staticCollConfig != nil {
+			m[staticCollConfig.Name] = collConfig
+		}
+	}
+	return m
+}
+
+// DiffFromLatest implements function from the interface `Retriever`. It compares the collection config of the chaincode in effect
+// at the given block, i.e., the most recent one committed at or below the block, with the latest collection config of the chaincode
+// (see function `DiffCollectionConfigs`). If the latest collection config is the one in effect at the block, an empty diff is returned
+// without decoding it twice. A nil result is returned if the chaincode has no collection config at all. As in the function
+// `DetectCollectionRemovals`, only the persisted (explicit) collections are compared
+func (r *retriever) DiffFromLatest(blockNum uint64, chaincodeName string) (*CollectionConfigDiffResult, error) {
+	if err := r.checkBlockCommitted(blockNum); err != nil {
+		return nil, err
+	}
+	latest, err := latestCollectionConfig(r.dbHandle, r.namespace, chaincodeName)
+	if err != nil || latest == nil {
+		return nil, r.withContext(err, chaincodeName, blockNum)
+	}
+	diff := &CollectionConfigDiffResult{
+		ChaincodeName:  chaincodeName,
+		BlockNum:       blockNum,
+		LatestBlockNum: latest.CommittingBlockNum,
+	}
+	if latest.CommittingBlockNum <= blockNum {
+		diff.FromBlockNum = latest.CommittingBlockNum
+		return diff, nil
+	}
+	// the block is below the latest one and hence, the next block does not overflow
+	from, err := r.explicitMostRecentCollectionConfigBelow(blockNum+1, chaincodeName)
+	if err != nil {
+		return nil, r.withContext(err, chaincodeName, blockNum)
+	}
+	var fromConfig *common.CollectionConfigPackage
+	if from != nil {
+		diff.FromBlockNum = from.CommittingBlockNum
+		fromConfig = from.CollectionConfig
+	}
+	diff.Added, diff.Removed, diff.Modified = DiffCollectionConfigs(fromConfig, latest.CollectionConfig)
+	return diff, nil
+}
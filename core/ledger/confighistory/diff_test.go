@@ -0,0 +1,110 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/common/cauthdsl"
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/mock"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffCollectionConfigs(t *testing.T) {
+	from := collConfigPkg(
+		coll("coll1", cauthdsl.SignedByAnyMember([]string{"org1", "org2"}), 0),
+		coll("coll2", nil, 10),
+		coll("coll3", nil, 0),
+	)
+	to := collConfigPkg(
+		coll("coll4", nil, 0),
+		// same policy with the identities in a different order
+		coll("coll1", cauthdsl.SignedByAnyMember([]string{"org2", "org1"}), 0),
+		coll("coll2", nil, 20),
+	)
+	added, removed, modified := DiffCollectionConfigs(from, to)
+	assert.Equal(t, []string{"coll4"}, added)
+	assert.Equal(t, []string{"coll3"}, removed)
+	assert.Equal(t, []string{"coll2"}, modified)
+
+	added, removed, modified = DiffCollectionConfigs(nil, to)
+	assert.Equal(t, []string{"coll4", "coll1", "coll2"}, added)
+	assert.Nil(t, removed)
+	assert.Nil(t, modified)
+
+	added, removed, modified = DiffCollectionConfigs(from, from)
+	assert.Nil(t, added)
+	assert.Nil(t, removed)
+	assert.Nil(t, modified)
+}
+
+func TestDiffFromLatest(t *testing.T) {
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(NewMemStoreProvider()))
+	defer m.Close()
+	for _, version := range []struct {
+		blockNum      uint64
+		collConfigPkg *common.CollectionConfigPackage
+	}{
+		{10, collConfigPkg(coll("coll1", nil, 0), coll("coll2", nil, 0))},
+		{20, collConfigPkg(coll("coll1", nil, 5), coll("coll3", nil, 0))},
+	} {
+		testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, "chaincode1", version.collConfigPkg)
+		assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: version.blockNum}))
+	}
+	retriever := m.GetRetriever("ledger1", &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 100}})
+
+	diff, err := retriever.DiffFromLatest(15, "chaincode1")
+	assert.NoError(t, err)
+	assert.Equal(t,
+		&CollectionConfigDiffResult{
+			ChaincodeName:  "chaincode1",
+			BlockNum:       15,
+			FromBlockNum:   10,
+			LatestBlockNum: 20,
+			Added:          []string{"coll3"},
+			Removed:        []string{"coll2"},
+			Modified:       []string{"coll1"},
+		},
+		diff,
+	)
+	assert.False(t, diff.Empty())
+
+	// the config committed at the block itself is in effect at the block
+	diff, err = retriever.DiffFromLatest(10, "chaincode1")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(10), diff.FromBlockNum)
+	assert.Equal(t, []string{"coll3"}, diff.Added)
+
+	for _, blockNum := range []uint64{20, 50} {
+		diff, err = retriever.DiffFromLatest(blockNum, "chaincode1")
+		assert.NoError(t, err)
+		assert.Equal(t, &CollectionConfigDiffResult{ChaincodeName: "chaincode1", BlockNum: blockNum, FromBlockNum: 20, LatestBlockNum: 20}, diff)
+		assert.True(t, diff.Empty())
+	}
+
+	t.Run("no-config-at-block", func(t *testing.T) {
+		diff, err := retriever.DiffFromLatest(5, "chaincode1")
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(0), diff.FromBlockNum)
+		assert.Equal(t, []string{"coll1", "coll3"}, diff.Added)
+		assert.Nil(t, diff.Removed)
+	})
+
+	t.Run("no-config", func(t *testing.T) {
+		diff, err := retriever.DiffFromLatest(50, "chaincode2")
+		assert.NoError(t, err)
+		assert.Nil(t, diff)
+	})
+
+	t.Run("block-not-committed", func(t *testing.T) {
+		_, err := retriever.DiffFromLatest(100, "chaincode1")
+		assert.IsType(t, &ledger.ErrCollectionConfigNotYetAvailable{}, err)
+	})
+}
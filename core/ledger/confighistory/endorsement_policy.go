@@ -0,0 +1,87 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/pkg/errors"
+)
+
+// addEndorsementPolicies adds to the batch the endorsement policies of the given chaincodes of the default namespace. The policy of
+// a chaincode is recorded at each block that updates the chaincode, irrespective of whether the chaincode has a collection config
+func addEndorsementPolicies(batch *batch, ccInfos []*ledger.DeployedChaincodeInfo, blockNum uint64) {
+	for _, ccInfo := range ccInfos {
+		if ccInfo.EndorsementPolicy == nil {
+			continue
+		}
+		batch.add(endorsementPolicyNamespace, constructCollectionConfigKey(ccInfo.Name), blockNum, encodeEndorsementPolicy(ccInfo))
+	}
+}
+
+// EndorsementPolicyAt implements function from the interface ledger.ConfigHistoryRetriever. It returns the endorsement policy of
+// the chaincode committed at exactly the given block and nil if the block did not update the chaincode. The endorsement policies
+// are recorded only for the chaincodes of the default namespace and hence, nil is returned by the retrievers of the other namespaces
+func (r *retriever) EndorsementPolicyAt(blockNum uint64, chaincodeName string) (*ledger.EndorsementPolicyInfo, error) {
+	if err := r.checkBlockCommitted(blockNum); err != nil {
+		return nil, err
+	}
+	if r.namespace != collectionConfigNamespace {
+		return nil, nil
+	}
+	kv, err := r.dbHandle.entryAt(blockNum, endorsementPolicyNamespace, constructCollectionConfigKey(chaincodeName))
+	if err != nil || kv == nil {
+		return nil, r.withPolicyContext(err, chaincodeName, blockNum)
+	}
+	return decodeEndorsementPolicy(kv.value, kv.blockNum)
+}
+
+// MostRecentEndorsementPolicyBelow implements function from the interface ledger.ConfigHistoryRetriever. It returns the most recent
+// endorsement policy of the chaincode committed below the given block, which is the policy in force for the transactions of the
+// given block. As in the function `EndorsementPolicyAt`, nil is returned by the retrievers of the namespaces other than the default
+func (r *retriever) MostRecentEndorsementPolicyBelow(blockNum uint64, chaincodeName string) (*ledger.EndorsementPolicyInfo, error) {
+	if r.namespace != collectionConfigNamespace {
+		return nil, nil
+	}
+	kv, err := r.dbHandle.mostRecentEntryBelow(blockNum, endorsementPolicyNamespace, constructCollectionConfigKey(chaincodeName))
+	if err != nil || kv == nil {
+		return nil, r.withPolicyContext(err, chaincodeName, blockNum)
+	}
+	return decodeEndorsementPolicy(kv.value, kv.blockNum)
+}
+
+// withPolicyContext is same as the function `withContext` except that the error is described as that of an endorsement policy query
+func (r *retriever) withPolicyContext(err error, chaincodeName string, blockNum uint64) error {
+	if err == nil {
+		return nil
+	}
+	return errors.WithMessage(err, fmt.Sprintf("error while retrieving the endorsement policy of chaincode [%s] for block [%d] of ledger [%s]",
+		chaincodeName, blockNum, r.ledgerID))
+}
+
+// encodeEndorsementPolicy encodes the uvarint length of the name of the validation plugin, followed by the name and the policy
+func encodeEndorsementPolicy(ccInfo *ledger.DeployedChaincodeInfo) []byte {
+	b := make([]byte, binary.MaxVarintLen64, binary.MaxVarintLen64+len(ccInfo.ValidationPlugin)+len(ccInfo.EndorsementPolicy))
+	n := binary.PutUvarint(b, uint64(len(ccInfo.ValidationPlugin)))
+	b = append(b[:n], ccInfo.ValidationPlugin...)
+	return append(b, ccInfo.EndorsementPolicy...)
+}
+
+func decodeEndorsementPolicy(b []byte, committingBlockNum uint64) (*ledger.EndorsementPolicyInfo, error) {
+	pluginLen, n := binary.Uvarint(b)
+	if n <= 0 || pluginLen > uint64(len(b)-n) {
+		return nil, errors.Errorf("invalid endorsement policy info [%#v]", b)
+	}
+	b = b[n:]
+	return &ledger.EndorsementPolicyInfo{
+		EndorsementPolicy:  append([]byte{}, b[pluginLen:]...),
+		ValidationPlugin:   string(b[:pluginLen]),
+		CommittingBlockNum: committingBlockNum,
+	}, nil
+}
@@ -0,0 +1,96 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/mock"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEndorsementPolicyHistory(t *testing.T) {
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(NewMemStoreProvider()))
+	defer m.Close()
+	mockCCInfoProvider.UpdatedChaincodesReturns([]*ledger.ChaincodeLifecycleInfo{{Name: "chaincode1"}}, nil)
+	for _, version := range []struct {
+		blockNum      uint64
+		collConfigPkg *common.CollectionConfigPackage
+		policy        string
+	}{
+		{10, sampleCollectionConfigPackage("coll", 10), "policy-10"},
+		// the policy is recorded even if the chaincode has no collection config
+		{20, nil, "policy-20"},
+		// a chaincode info without a policy records none
+		{30, sampleCollectionConfigPackage("coll", 30), ""},
+	} {
+		ccInfo := &ledger.DeployedChaincodeInfo{Name: "chaincode1", CollectionConfigPkg: version.collConfigPkg}
+		if version.policy != "" {
+			ccInfo.EndorsementPolicy, ccInfo.ValidationPlugin = []byte(version.policy), "vscc"
+		}
+		mockCCInfoProvider.ChaincodeInfoReturns(ccInfo, nil)
+		assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: version.blockNum}))
+	}
+	retriever := m.GetRetriever("ledger1", &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 100}})
+
+	policy, err := retriever.EndorsementPolicyAt(10, "chaincode1")
+	assert.NoError(t, err)
+	assert.Equal(t, &ledger.EndorsementPolicyInfo{EndorsementPolicy: []byte("policy-10"), ValidationPlugin: "vscc", CommittingBlockNum: 10}, policy)
+	policy, err = retriever.EndorsementPolicyAt(15, "chaincode1")
+	assert.NoError(t, err)
+	assert.Nil(t, policy)
+	policy, err = retriever.EndorsementPolicyAt(30, "chaincode1")
+	assert.NoError(t, err)
+	assert.Nil(t, policy)
+	_, err = retriever.EndorsementPolicyAt(100, "chaincode1")
+	assert.IsType(t, &ledger.ErrCollectionConfigNotYetAvailable{}, err)
+
+	for blockNum, expectedBlockNum := range map[uint64]uint64{11: 10, 20: 10, 21: 20, 50: 20} {
+		policy, err = retriever.MostRecentEndorsementPolicyBelow(blockNum, "chaincode1")
+		assert.NoError(t, err)
+		assert.Equal(t, expectedBlockNum, policy.CommittingBlockNum)
+	}
+	policy, err = retriever.MostRecentEndorsementPolicyBelow(10, "chaincode1")
+	assert.NoError(t, err)
+	assert.Nil(t, policy)
+	policy, err = retriever.MostRecentEndorsementPolicyBelow(50, "chaincode2")
+	assert.NoError(t, err)
+	assert.Nil(t, policy)
+
+	// the collection configs are not affected by the policy-only update
+	blockNums, err := retriever.ConfigBlockNumbers("chaincode1")
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{10, 30}, blockNums)
+
+	t.Run("other-namespace", func(t *testing.T) {
+		retriever := m.GetRetrieverForNamespace("ledger1", "_lifecycle", &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 100}})
+		policy, err := retriever.EndorsementPolicyAt(10, "chaincode1")
+		assert.NoError(t, err)
+		assert.Nil(t, policy)
+	})
+
+	t.Run("deleted-with-chaincode-history", func(t *testing.T) {
+		_, err := m.DeleteChaincodeHistory("ledger1", "chaincode1")
+		assert.NoError(t, err)
+		policy, err := retriever.MostRecentEndorsementPolicyBelow(50, "chaincode1")
+		assert.NoError(t, err)
+		assert.Nil(t, policy)
+	})
+}
+
+func TestDecodeEndorsementPolicy(t *testing.T) {
+	ccInfo := &ledger.DeployedChaincodeInfo{EndorsementPolicy: []byte("policy"), ValidationPlugin: "vscc"}
+	policy, err := decodeEndorsementPolicy(encodeEndorsementPolicy(ccInfo), 10)
+	assert.NoError(t, err)
+	assert.Equal(t, &ledger.EndorsementPolicyInfo{EndorsementPolicy: []byte("policy"), ValidationPlugin: "vscc", CommittingBlockNum: 10}, policy)
+
+	_, err = decodeEndorsementPolicy([]byte{0x05, 'v'}, 10)
+	assert.EqualError(t, err, "invalid endorsement policy info [[]byte{0x5, 0x76}]")
+}
@@ -0,0 +1,269 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"hash"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// exportFormatVersion is the version of the format produced by the function `ExportConfigHistory`.
+// The export starts with a header that contains the format version (uint32) and the number of entries (uint64), both
+// encoded in big-endian order. The header is followed by the entries, each encoded as the length of the key (uvarint),
+// the key, the length of the value (uvarint), and the value. The export ends with the SHA-256 of the header and the entries.
+// The keys are the composite keys, as stored in the db, and the entries appear in the order of the keys
+const exportFormatVersion = uint32(1)
+
+const (
+	exportHeaderSize = 4 + 8
+	// maxExportedFieldSize limits the size of a key or a value that the import accepts, so that a corrupted
+	// length does not result in an arbitrarily large allocation
+	maxExportedFieldSize = 64 * 1024 * 1024
+)
+
+// ExportConfigHistory implements function in the interface 'Mgr'. It writes all the entries of the config history of
+// the given ledger to the writer in a versioned format that includes a checksum. The pending asynchronous writes, if
+// any, are applied before the export. If the store implements the interface `Snapshotter`, the export is taken from a
+// snapshot and hence, is not affected by the concurrent commits; otherwise, an error is returned if the config history
+// changes during the export
+func (m *mgr) ExportConfigHistory(ledgerID string, w io.Writer) error {
+	return m.export(ledgerID, w, nil)
+}
+
+// ExportChaincode implements function in the interface 'Mgr'. It is same as the function `ExportConfigHistory` except that
+// only the entries of the given chaincode (in all the namespaces) are written. The format is same as that of the full export
+// and hence, the output can be imported via either of the functions `ImportConfigHistory` and `ImportChaincode`. The entries
+// of a chaincode are not contiguous in the db and hence, the entire config history of the ledger is scanned
+func (m *mgr) ExportChaincode(ledgerID, chaincodeName string, w io.Writer) error {
+	return m.export(ledgerID, w, chaincodeEntryFilter(chaincodeName))
+}
+
+// ImportConfigHistory implements function in the interface 'Mgr'. It loads the entries, as exported by the function
+// `ExportConfigHistory`, into the config history of the given ledger, which is expected to be empty. The entire input
+// is read and verified against the checksum before any entry is written; a corrupted input leaves the db unchanged
+func (m *mgr) ImportConfigHistory(ledgerID string, r io.Reader) error {
+	dbHandle := m.dbProvider.getDB(ledgerID)
+	empty, err := dbHandle.isEmpty()
+	if err != nil {
+		return err
+	}
+	if !empty {
+		return errors.Errorf("config history of ledger [%s] is not empty", ledgerID)
+	}
+	batch, err := readExport(r, nil)
+	if err != nil {
+		return err
+	}
+	if err := dbHandle.writeBatch(batch, true); err != nil {
+		return err
+	}
+	m.cache.removeLedger(ledgerID)
+	m.blockIndex.invalidate(ledgerID)
+	logger.Infof("Imported [%d] entries into config history of ledger [%s]", batch.Len(), ledgerID)
+	return nil
+}
+
+// ImportChaincode implements function in the interface 'Mgr'. It loads the entries of the given chaincode from the input,
+// as exported by either of the functions `ExportChaincode` and `ExportConfigHistory`, into the config history of the given
+// ledger; the entries of the other chaincodes in the input are skipped. The ledger is expected to contain no entry of the
+// chaincode. As in the function `ImportConfigHistory`, the input is verified against the checksum before any entry is written
+func (m *mgr) ImportChaincode(ledgerID, chaincodeName string, r io.Reader) error {
+	dbHandle := m.dbProvider.getDB(ledgerID)
+	filter := chaincodeEntryFilter(chaincodeName)
+	numExisting, err := dbHandle.numEntriesMatching(filter)
+	if err != nil {
+		return err
+	}
+	if numExisting > 0 {
+		return errors.Errorf("config history of ledger [%s] already contains [%d] entries of chaincode [%s]",
+			ledgerID, numExisting, chaincodeName)
+	}
+	batch, err := readExport(r, filter)
+	if err != nil {
+		return err
+	}
+	if err := dbHandle.writeBatch(batch, true); err != nil {
+		return err
+	}
+	m.cache.remove(ledgerID, chaincodeName)
+	m.blockIndex.invalidate(ledgerID)
+	logger.Infof("Imported [%d] entries of chaincode [%s] into config history of ledger [%s]", batch.Len(), chaincodeName, ledgerID)
+	return nil
+}
+
+// chaincodeEntryFilter returns a filter that selects the composite keys of the given chaincode, in any namespace
+func chaincodeEntryFilter(chaincodeName string) func(*compositeKey) bool {
+	key := constructCollectionConfigKey(chaincodeName)
+	return func(k *compositeKey) bool {
+		return k.key == key
+	}
+}
+
+// export writes the entries of the config history of the given ledger that pass the filter (all the entries if the filter is nil)
+func (m *mgr) export(ledgerID string, w io.Writer, filter func(*compositeKey) bool) error {
+	if err := m.WaitForPendingWrites(); err != nil {
+		return err
+	}
+	// the entries are counted and written from the same snapshot, if supported by the store, so that the blocks committed
+	// in the meantime do not fail the export
+	dbHandle := m.dbProvider.getDB(ledgerID)
+	snapshotDB, storeSnapshot, err := dbHandle.newSnapshotDB()
+	if err != nil {
+		return err
+	}
+	if snapshotDB != nil {
+		defer storeSnapshot.Release()
+		dbHandle = snapshotDB
+	}
+	numEntries, err := dbHandle.numEntriesMatching(filter)
+	if err != nil {
+		return err
+	}
+
+	hasher := sha256.New()
+	bufWriter := bufio.NewWriter(w)
+	out := io.MultiWriter(bufWriter, hasher)
+	header := make([]byte, exportHeaderSize)
+	binary.BigEndian.PutUint32(header[0:4], exportFormatVersion)
+	binary.BigEndian.PutUint64(header[4:], numEntries)
+	if _, err := out.Write(header); err != nil {
+		return errors.Wrap(err, "error while writing the export header")
+	}
+
+	numExported := uint64(0)
+	itr := dbHandle.GetIterator(nil, nil)
+	defer itr.Release()
+	for itr.Next() {
+		if filter != nil && !filter(decodeCompositeKey(itr.Key())) {
+			continue
+		}
+		if err := writeField(out, itr.Key()); err != nil {
+			return err
+		}
+		if err := writeField(out, itr.Value()); err != nil {
+			return err
+		}
+		numExported++
+	}
+	if err := itr.Error(); err != nil {
+		return errors.Wrap(err, "error while iterating the config history db")
+	}
+	if numExported != numEntries {
+		return errors.Errorf("config history of ledger [%s] changed during the export", ledgerID)
+	}
+	if _, err := bufWriter.Write(hasher.Sum(nil)); err != nil {
+		return errors.Wrap(err, "error while writing the export checksum")
+	}
+	return errors.Wrap(bufWriter.Flush(), "error while writing the export")
+}
+
+// readExport reads an export and returns a batch with the entries that pass the filter (all the entries if the filter is nil).
+// The entire input is verified against the checksum, irrespective of the filter
+func readExport(r io.Reader, filter func(*compositeKey) bool) (*batch, error) {
+	in := &hashingReader{r: bufio.NewReader(r), hash: sha256.New()}
+	header := make([]byte, exportHeaderSize)
+	if _, err := io.ReadFull(in, header); err != nil {
+		return nil, errors.Wrap(err, "error while reading the export header")
+	}
+	if version := binary.BigEndian.Uint32(header[0:4]); version != exportFormatVersion {
+		return nil, errors.Errorf("unsupported export format version [%d]", version)
+	}
+	numEntries := binary.BigEndian.Uint64(header[4:])
+
+	batch := newBatch()
+	for i := uint64(0); i < numEntries; i++ {
+		key, err := readField(in)
+		if err != nil {
+			return nil, err
+		}
+		value, err := readField(in)
+		if err != nil {
+			return nil, err
+		}
+		if err := validateExportedKey(key); err != nil {
+			return nil, err
+		}
+		if filter != nil && !filter(decodeCompositeKey(key)) {
+			continue
+		}
+		batch.Put(key, value)
+	}
+	expectedChecksum := in.hash.Sum(nil)
+	checksum := make([]byte, sha256.Size)
+	if _, err := io.ReadFull(in.r, checksum); err != nil {
+		return nil, errors.Wrap(err, "error while reading the export checksum")
+	}
+	if !bytes.Equal(checksum, expectedChecksum) {
+		return nil, errors.New("checksum mismatch, the export is corrupted")
+	}
+	if _, err := in.r.ReadByte(); err != io.EOF {
+		return nil, errors.New("unexpected data after the export checksum")
+	}
+	return batch, nil
+}
+
+// hashingReader adds the bytes read through it to the hash
+type hashingReader struct {
+	r    *bufio.Reader
+	hash hash.Hash
+}
+
+func (h *hashingReader) Read(p []byte) (int, error) {
+	n, err := h.r.Read(p)
+	h.hash.Write(p[:n])
+	return n, err
+}
+
+func (h *hashingReader) ReadByte() (byte, error) {
+	b, err := h.r.ReadByte()
+	if err == nil {
+		h.hash.Write([]byte{b})
+	}
+	return b, err
+}
+
+func writeField(w io.Writer, field []byte) error {
+	lenBytes := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(lenBytes, uint64(len(field)))
+	if _, err := w.Write(lenBytes[:n]); err != nil {
+		return errors.Wrap(err, "error while writing the export")
+	}
+	if _, err := w.Write(field); err != nil {
+		return errors.Wrap(err, "error while writing the export")
+	}
+	return nil
+}
+
+func readField(r *hashingReader) ([]byte, error) {
+	length, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "error while reading the export")
+	}
+	if length > maxExportedFieldSize {
+		return nil, errors.Errorf("invalid field length [%d] in the export", length)
+	}
+	field := make([]byte, length)
+	if _, err := io.ReadFull(r, field); err != nil {
+		return nil, errors.Wrap(err, "error while reading the export")
+	}
+	return field, nil
+}
+
+// validateExportedKey checks that the key is a well formed composite key
+func validateExportedKey(key []byte) error {
+	if len(key) < len(keyPrefix)+1+8 || !bytes.HasPrefix(key, []byte(keyPrefix)) ||
+		bytes.IndexByte(key[len(keyPrefix):len(key)-8], separatorByte) < 0 {
+		return errors.Errorf("invalid key [%#v] in the export", key)
+	}
+	return nil
+}
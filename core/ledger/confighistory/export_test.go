@@ -0,0 +1,209 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/mock"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportImportConfigHistory(t *testing.T) {
+	dbPath := "/tmp/fabric/core/ledger/confighistory"
+	deleteTestPath(t, dbPath)
+	defer deleteTestPath(t, dbPath)
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	mgr := newMgr(mockCCInfoProvider, dbPath)
+	defer mgr.Close()
+
+	for _, ccName := range []string{"chaincode1", "chaincode2"} {
+		for _, blockNum := range []uint64{5, 10, 15} {
+			testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, ccName,
+				sampleCollectionConfigPackage(ccName, blockNum))
+			assert.NoError(t, mgr.HandleStateUpdates(&ledger.StateUpdateTrigger{
+				LedgerID:           "ledger1",
+				CommittingBlockNum: blockNum},
+			))
+		}
+	}
+	export := &bytes.Buffer{}
+	assert.NoError(t, mgr.ExportConfigHistory("ledger1", export))
+	exportBytes := export.Bytes()
+
+	assertEmpty := func(t *testing.T, ledgerID string) {
+		empty, err := mgr.dbProvider.getDB(ledgerID).isEmpty()
+		assert.NoError(t, err)
+		assert.True(t, empty)
+	}
+
+	t.Run("round-trip", func(t *testing.T) {
+		assert.NoError(t, mgr.ImportConfigHistory("ledger2", bytes.NewReader(exportBytes)))
+		dummyLedgerInfoRetriever := &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 100}}
+		retriever := mgr.GetRetriever("ledger2", dummyLedgerInfoRetriever)
+		for _, ccName := range []string{"chaincode1", "chaincode2"} {
+			for _, blockNum := range []uint64{5, 10, 15} {
+				collConfig, err := retriever.CollectionConfigAt(blockNum, ccName)
+				assert.NoError(t, err)
+				assert.True(t, proto.Equal(sampleCollectionConfigPackage(ccName, blockNum), collConfig.CollectionConfig))
+			}
+		}
+		// re-exporting the imported ledger yields the same bytes
+		reExport := &bytes.Buffer{}
+		assert.NoError(t, mgr.ExportConfigHistory("ledger2", reExport))
+		assert.Equal(t, exportBytes, reExport.Bytes())
+	})
+
+	t.Run("non-empty-target", func(t *testing.T) {
+		err := mgr.ImportConfigHistory("ledger1", bytes.NewReader(exportBytes))
+		assert.EqualError(t, err, "config history of ledger [ledger1] is not empty")
+	})
+
+	t.Run("flipped-byte", func(t *testing.T) {
+		for _, i := range []int{exportHeaderSize + 5, len(exportBytes) / 2, len(exportBytes) - 1} {
+			corrupted := append([]byte(nil), exportBytes...)
+			corrupted[i] ^= 0x01
+			err := mgr.ImportConfigHistory("ledger3", bytes.NewReader(corrupted))
+			assert.Error(t, err)
+			assertEmpty(t, "ledger3")
+		}
+		corrupted := append([]byte(nil), exportBytes...)
+		corrupted[len(exportBytes)-1] ^= 0x01
+		err := mgr.ImportConfigHistory("ledger3", bytes.NewReader(corrupted))
+		assert.EqualError(t, err, "checksum mismatch, the export is corrupted")
+	})
+
+	t.Run("truncated-input", func(t *testing.T) {
+		for _, size := range []int{0, exportHeaderSize - 1, exportHeaderSize + 3, len(exportBytes) - 1} {
+			err := mgr.ImportConfigHistory("ledger3", bytes.NewReader(exportBytes[:size]))
+			assert.Error(t, err)
+			assertEmpty(t, "ledger3")
+		}
+	})
+
+	t.Run("trailing-data", func(t *testing.T) {
+		input := append(append([]byte(nil), exportBytes...), 0)
+		err := mgr.ImportConfigHistory("ledger3", bytes.NewReader(input))
+		assert.EqualError(t, err, "unexpected data after the export checksum")
+		assertEmpty(t, "ledger3")
+	})
+
+	t.Run("unsupported-version", func(t *testing.T) {
+		input := append([]byte(nil), exportBytes...)
+		binary.BigEndian.PutUint32(input[0:4], exportFormatVersion+1)
+		err := mgr.ImportConfigHistory("ledger3", bytes.NewReader(input))
+		assert.EqualError(t, err, "unsupported export format version [2]")
+		assertEmpty(t, "ledger3")
+	})
+
+	t.Run("invalidates-cache", func(t *testing.T) {
+		m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(NewMemStoreProvider()), WithCacheSize(10))
+		defer m.Close()
+		m.cache.put("ledger1", "chaincode1", &ledger.CollectionConfigInfo{
+			CollectionConfig:   sampleCollectionConfigPackage("stale", 1),
+			CommittingBlockNum: 1,
+		})
+		assert.NoError(t, m.ImportConfigHistory("ledger1", bytes.NewReader(exportBytes)))
+		_, ok := m.cache.get("ledger1", "chaincode1")
+		assert.False(t, ok)
+		dummyLedgerInfoRetriever := &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 100}}
+		collConfig, err := m.GetRetriever("ledger1", dummyLedgerInfoRetriever).MostRecentCollectionConfigBelow(100, "chaincode1")
+		assert.NoError(t, err)
+		assert.True(t, proto.Equal(sampleCollectionConfigPackage("chaincode1", 15), collConfig.CollectionConfig))
+	})
+
+	t.Run("empty-ledger", func(t *testing.T) {
+		emptyExport := &bytes.Buffer{}
+		assert.NoError(t, mgr.ExportConfigHistory("ledger4", emptyExport))
+		assert.Equal(t, exportHeaderSize+32, emptyExport.Len())
+		assert.NoError(t, mgr.ImportConfigHistory("ledger5", emptyExport))
+		assertEmpty(t, "ledger5")
+	})
+}
+
+func TestExportImportChaincode(t *testing.T) {
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	mgr := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(NewMemStoreProvider()))
+	defer mgr.Close()
+
+	for _, ccName := range []string{"chaincode1", "chaincode10", "chaincode2"} {
+		for _, blockNum := range []uint64{5, 10} {
+			testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, ccName,
+				sampleCollectionConfigPackage(ccName, blockNum))
+			assert.NoError(t, mgr.HandleStateUpdates(&ledger.StateUpdateTrigger{
+				LedgerID:           "ledger1",
+				CommittingBlockNum: blockNum},
+			))
+		}
+	}
+	export := &bytes.Buffer{}
+	assert.NoError(t, mgr.ExportChaincode("ledger1", "chaincode1", export))
+	exportBytes := export.Bytes()
+	// two collection config entries and the latest pointer
+	assert.Equal(t, uint64(3), binary.BigEndian.Uint64(exportBytes[4:exportHeaderSize]))
+
+	dummyLedgerInfoRetriever := &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 100}}
+	checkImported := func(t *testing.T, ledgerID string) {
+		retriever := mgr.GetRetriever(ledgerID, dummyLedgerInfoRetriever)
+		for _, blockNum := range []uint64{5, 10} {
+			collConfig, err := retriever.CollectionConfigAt(blockNum, "chaincode1")
+			assert.NoError(t, err)
+			assert.True(t, proto.Equal(sampleCollectionConfigPackage("chaincode1", blockNum), collConfig.CollectionConfig))
+		}
+		blockNum, ok, err := retriever.LatestConfigBlock("chaincode1")
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, uint64(10), blockNum)
+		for _, ccName := range []string{"chaincode10", "chaincode2"} {
+			blockNums, err := retriever.ConfigBlockNumbers(ccName)
+			assert.NoError(t, err)
+			assert.Nil(t, blockNums)
+		}
+	}
+
+	t.Run("round-trip", func(t *testing.T) {
+		assert.NoError(t, mgr.ImportChaincode("ledger2", "chaincode1", bytes.NewReader(exportBytes)))
+		checkImported(t, "ledger2")
+		reExport := &bytes.Buffer{}
+		assert.NoError(t, mgr.ExportChaincode("ledger2", "chaincode1", reExport))
+		assert.Equal(t, exportBytes, reExport.Bytes())
+	})
+
+	t.Run("into-full-import", func(t *testing.T) {
+		assert.NoError(t, mgr.ImportConfigHistory("ledger3", bytes.NewReader(exportBytes)))
+		checkImported(t, "ledger3")
+	})
+
+	t.Run("from-full-export", func(t *testing.T) {
+		fullExport := &bytes.Buffer{}
+		assert.NoError(t, mgr.ExportConfigHistory("ledger1", fullExport))
+		assert.NoError(t, mgr.ImportChaincode("ledger4", "chaincode1", fullExport))
+		checkImported(t, "ledger4")
+	})
+
+	t.Run("existing-entries", func(t *testing.T) {
+		err := mgr.ImportChaincode("ledger1", "chaincode1", bytes.NewReader(exportBytes))
+		assert.EqualError(t, err, "config history of ledger [ledger1] already contains [3] entries of chaincode [chaincode1]")
+		// the other chaincodes in the ledger do not prevent the import
+		assert.NoError(t, mgr.ImportChaincode("ledger2", "chaincode2", bytes.NewReader(exportBytes)))
+	})
+
+	t.Run("corrupted-input", func(t *testing.T) {
+		corrupted := append([]byte(nil), exportBytes...)
+		corrupted[len(exportBytes)-1] ^= 0x01
+		err := mgr.ImportChaincode("ledger5", "chaincode1", bytes.NewReader(corrupted))
+		assert.EqualError(t, err, "checksum mismatch, the export is corrupted")
+		empty, err := mgr.dbProvider.getDB("ledger5").isEmpty()
+		assert.NoError(t, err)
+		assert.True(t, empty)
+	})
+}
@@ -0,0 +1,158 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/pkg/errors"
+)
+
+// HeightScopedRetriever serves the collection config queries for a single block (see function `Retriever.AtHeight`).
+// The results are memoized per chaincode and all the queries share a single query executor. The retriever should be
+// closed after the use, for releasing the query executor
+type HeightScopedRetriever interface {
+	// BlockNum returns the block number to which the retriever is scoped
+	BlockNum() uint64
+	// CollectionConfigAt returns the collection config of the chaincode committed at the block, if any.
+	// See function `Retriever.CollectionConfigAt` for more details
+	CollectionConfigAt(chaincodeName string) (*ledger.CollectionConfigInfo, error)
+	// MostRecentCollectionConfigBelow returns the collection config of the chaincode that is in effect for the block.
+	// See function `Retriever.MostRecentCollectionConfigBelow` for more details
+	MostRecentCollectionConfigBelow(chaincodeName string) (*ledger.CollectionConfigInfo, error)
+	// Close releases the query executor. The retriever should not be used after the close
+	Close()
+}
+
+// AtHeight implements function from the interface `Retriever`. It returns a retriever for the series of queries made
+// for a single block across different chaincodes, such as for rendering the block in an explorer. The query executor,
+// which is required for computing the implicit collections and for the not deployed checks, is acquired on the first
+// such need and is shared by all the subsequent queries until the retriever is closed. Because a query executor may
+// hold off the commits to the state db, the retriever should be closed as soon as the series of queries is over.
+// The results, including the absence of a collection config, are memoized; the errors are not
+func (r *retriever) AtHeight(blockNum uint64) (HeightScopedRetriever, error) {
+	if err := r.checkBlockCommitted(blockNum); err != nil {
+		return nil, err
+	}
+	sharedQERetriever := &sharedQELedgerInfoRetriever{LedgerInfoRetriever: r.ledgerInfoRetriever}
+	scopedRetriever := *r
+	scopedRetriever.ledgerInfoRetriever = sharedQERetriever
+	return &heightScopedRetriever{
+		retriever: &scopedRetriever,
+		blockNum:  blockNum,
+		sharedQE:  sharedQERetriever,
+		memo:      map[heightScopedMemoKey]*ledger.CollectionConfigInfo{},
+	}, nil
+}
+
+type heightScopedMemoKey struct {
+	chaincodeName string
+	mostRecent    bool
+}
+
+type heightScopedRetriever struct {
+	retriever *retriever
+	blockNum  uint64
+	sharedQE  *sharedQELedgerInfoRetriever
+
+	mux    sync.Mutex
+	memo   map[heightScopedMemoKey]*ledger.CollectionConfigInfo
+	closed bool
+}
+
+// BlockNum implements function from the interface `HeightScopedRetriever`
+func (h *heightScopedRetriever) BlockNum() uint64 {
+	return h.blockNum
+}
+
+// CollectionConfigAt implements function from the interface `HeightScopedRetriever`
+func (h *heightScopedRetriever) CollectionConfigAt(chaincodeName string) (*ledger.CollectionConfigInfo, error) {
+	return h.lookup(heightScopedMemoKey{chaincodeName: chaincodeName}, h.retriever.CollectionConfigAt)
+}
+
+// MostRecentCollectionConfigBelow implements function from the interface `HeightScopedRetriever`
+func (h *heightScopedRetriever) MostRecentCollectionConfigBelow(chaincodeName string) (*ledger.CollectionConfigInfo, error) {
+	return h.lookup(heightScopedMemoKey{chaincodeName: chaincodeName, mostRecent: true}, h.retriever.MostRecentCollectionConfigBelow)
+}
+
+// Close implements function from the interface `HeightScopedRetriever`
+func (h *heightScopedRetriever) Close() {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	if h.closed {
+		return
+	}
+	h.closed = true
+	h.memo = nil
+	h.sharedQE.release()
+}
+
+// lookup serves the query from the memo or else, performs the query and memoizes the result. The lock is held during
+// the query, so that the concurrent queries for the same chaincode do not perform the query twice and the query
+// executor is not released while in use
+func (h *heightScopedRetriever) lookup(
+	key heightScopedMemoKey,
+	query func(blockNum uint64, chaincodeName string) (*ledger.CollectionConfigInfo, error),
+) (*ledger.CollectionConfigInfo, error) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	if h.closed {
+		return nil, errors.Errorf("the retriever for block [%d] of ledger [%s] is closed", h.blockNum, h.retriever.ledgerID)
+	}
+	collConfig, ok := h.memo[key]
+	if !ok {
+		var err error
+		if collConfig, err = query(h.blockNum, key.chaincodeName); err != nil {
+			return nil, err
+		}
+		h.memo[key] = collConfig
+	}
+	if collConfig == nil {
+		return nil, nil
+	}
+	// the memoized config is copied, so that a caller modifying the returned config does not affect the other callers
+	copied := *collConfig
+	copied.CollectionConfig = proto.Clone(collConfig.CollectionConfig).(*common.CollectionConfigPackage)
+	return &copied, nil
+}
+
+// sharedQELedgerInfoRetriever acquires a single query executor on the first call to the function `NewQueryExecutor`
+// and returns the same query executor for all the calls until released. The function `Done` of the returned query
+// executor is a no-op, so that the query functions of the type `retriever` can be reused as is
+type sharedQELedgerInfoRetriever struct {
+	LedgerInfoRetriever
+	qe ledger.QueryExecutor
+}
+
+func (s *sharedQELedgerInfoRetriever) NewQueryExecutor() (ledger.QueryExecutor, error) {
+	if s.qe == nil {
+		qe, err := s.LedgerInfoRetriever.NewQueryExecutor()
+		if err != nil {
+			return nil, err
+		}
+		s.qe = qe
+	}
+	return &sharedQueryExecutor{s.qe}, nil
+}
+
+func (s *sharedQELedgerInfoRetriever) release() {
+	if s.qe != nil {
+		s.qe.Done()
+		s.qe = nil
+	}
+}
+
+type sharedQueryExecutor struct {
+	ledger.QueryExecutor
+}
+
+// Done is a no-op; the query executor is released by the function `sharedQELedgerInfoRetriever.release`
+func (s *sharedQueryExecutor) Done() {
+}
@@ -0,0 +1,111 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAtHeight(t *testing.T) {
+	mockCCInfoProvider := &implicitCollsCCInfoProvider{}
+	storeProvider := &recordingStoreProvider{StoreProvider: NewMemStoreProvider()}
+	m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(storeProvider), WithChaincodeNotDeployedErrors())
+	defer m.Close()
+
+	testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(&mockCCInfoProvider.DeployedChaincodeInfoProvider, "chaincode1", sampleCollectionConfigPackage("coll", 10))
+	assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: 10}))
+	testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(&mockCCInfoProvider.DeployedChaincodeInfoProvider, "chaincode2", sampleCollectionConfigPackage("coll", 20))
+	assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: 20}))
+	mockCCInfoProvider.ImplicitCollectionsReturns([]*common.StaticCollectionConfig{sampleImplicitCollection("org1")}, nil)
+	mockCCInfoProvider.ChaincodeInfoReturns(nil, nil)
+
+	ledgerInfoRetriever := &countingLedgerInfoRetriever{
+		LedgerInfoRetriever: &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 100}},
+	}
+	retriever := m.GetRetriever("ledger1", ledgerInfoRetriever)
+
+	scoped, err := retriever.AtHeight(20)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(20), scoped.BlockNum())
+
+	for i := 0; i < 3; i++ {
+		collConfig, err := scoped.MostRecentCollectionConfigBelow("chaincode1")
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(10), collConfig.CommittingBlockNum)
+		assert.Equal(t, []string{"coll-10", "_implicit_org_org1"}, collNames(collConfig))
+
+		collConfig, err = scoped.CollectionConfigAt("chaincode2")
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(20), collConfig.CommittingBlockNum)
+
+		collConfig, err = scoped.CollectionConfigAt("chaincode1")
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(0), collConfig.CommittingBlockNum)
+		assert.Equal(t, []string{"_implicit_org_org1"}, collNames(collConfig))
+	}
+	// the memo serves the repeated queries without reading the store and all the queries share a single query executor
+	numGets := storeProvider.stores[0].numGets
+	_, err = scoped.MostRecentCollectionConfigBelow("chaincode1")
+	assert.NoError(t, err)
+	assert.Equal(t, numGets, storeProvider.stores[0].numGets)
+	assert.Equal(t, 1, ledgerInfoRetriever.numQueryExecutors)
+	assert.Equal(t, 0, ledgerInfoRetriever.numDone)
+
+	// the returned configs are copies of the memoized config
+	collConfig, err := scoped.MostRecentCollectionConfigBelow("chaincode1")
+	assert.NoError(t, err)
+	collConfig.CollectionConfig.Config = nil
+	collConfig, err = scoped.MostRecentCollectionConfigBelow("chaincode1")
+	assert.NoError(t, err)
+	assert.Len(t, collConfig.CollectionConfig.Config, 2)
+
+	// the errors are not memoized
+	mockCCInfoProvider.ImplicitCollectionsReturns(nil, errors.New("implicit-collections-error"))
+	_, err = scoped.CollectionConfigAt("chaincode3")
+	assert.Contains(t, err.Error(), "implicit-collections-error")
+	mockCCInfoProvider.ImplicitCollectionsReturns([]*common.StaticCollectionConfig{sampleImplicitCollection("org1")}, nil)
+	collConfig, err = scoped.CollectionConfigAt("chaincode3")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"_implicit_org_org1"}, collNames(collConfig))
+
+	scoped.Close()
+	scoped.Close()
+	assert.Equal(t, 1, ledgerInfoRetriever.numDone)
+	_, err = scoped.CollectionConfigAt("chaincode2")
+	assert.EqualError(t, err, "the retriever for block [20] of ledger [ledger1] is closed")
+
+	t.Run("block-not-committed", func(t *testing.T) {
+		_, err := retriever.AtHeight(100)
+		assert.IsType(t, &ledger.ErrCollectionConfigNotYetAvailable{}, err)
+	})
+}
+
+type countingLedgerInfoRetriever struct {
+	LedgerInfoRetriever
+	numQueryExecutors, numDone int
+}
+
+func (c *countingLedgerInfoRetriever) NewQueryExecutor() (ledger.QueryExecutor, error) {
+	c.numQueryExecutors++
+	qe, err := c.LedgerInfoRetriever.NewQueryExecutor()
+	return &countingQueryExecutor{QueryExecutor: qe, retriever: c}, err
+}
+
+type countingQueryExecutor struct {
+	ledger.QueryExecutor
+	retriever *countingLedgerInfoRetriever
+}
+
+func (c *countingQueryExecutor) Done() {
+	c.retriever.numDone++
+	c.QueryExecutor.Done()
+}
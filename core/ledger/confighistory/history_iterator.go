@@ -0,0 +1,49 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"math"
+
+	commonledger "github.com/hyperledger/fabric/common/ledger"
+	"github.com/pkg/errors"
+)
+
+// GetCollectionConfigHistory implements function from the interface ledger.ConfigHistoryRetriever. It returns an iterator over
+// all the persisted versions of the collection config of the chaincode, from the most recent to the oldest. Each of the results
+// is a `*ledger.CollectionConfigInfo` that contains only the persisted (explicit) collections, as in the function `AllCollectionConfigs`.
+// Unlike that function, the versions are read from the store as the iterator advances and hence, a history of any length is
+// streamed without holding it in the memory. The iterator presents the history as of the time of this call and should be closed
+// after the use
+func (r *retriever) GetCollectionConfigHistory(chaincodeName string) (commonledger.ResultsIterator, error) {
+	key := constructCollectionConfigKey(chaincodeName)
+	startKey := encodeCompositeKey(r.namespace, key, math.MaxUint64)
+	stopKey := append(encodeCompositeKey(r.namespace, key, 0), byte(0))
+	return &collectionConfigHistoryItr{itr: r.dbHandle.GetIterator(startKey, stopKey)}, nil
+}
+
+type collectionConfigHistoryItr struct {
+	itr Iterator
+}
+
+// Next implements function from the interface `commonledger.ResultsIterator`. The result is nil once the history is exhausted
+func (h *collectionConfigHistoryItr) Next() (commonledger.QueryResult, error) {
+	if !h.itr.Next() {
+		return nil, errors.Wrap(h.itr.Error(), "error while iterating the config history db")
+	}
+	kv := &compositeKV{decodeCompositeKey(h.itr.Key()), append([]byte(nil), h.itr.Value()...)}
+	info, err := compositeKVToCollectionConfig(kv)
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// Close implements function from the interface `commonledger.ResultsIterator`
+func (h *collectionConfigHistoryItr) Close() {
+	h.itr.Release()
+}
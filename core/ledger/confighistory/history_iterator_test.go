@@ -0,0 +1,56 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/mock"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetCollectionConfigHistory(t *testing.T) {
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(NewMemStoreProvider()))
+	defer m.Close()
+	for _, ccName := range []string{"chaincode1", "chaincode1a", "chaincode2"} {
+		for _, blockNum := range []uint64{10, 20, 30} {
+			testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, ccName, sampleCollectionConfigPackage(ccName, blockNum))
+			assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: blockNum}))
+		}
+	}
+	retriever := m.GetRetriever("ledger1", &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 100}})
+
+	itr, err := retriever.GetCollectionConfigHistory("chaincode1")
+	assert.NoError(t, err)
+	// a version committed after the iterator is obtained is not presented
+	testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, "chaincode1", sampleCollectionConfigPackage("chaincode1", 40))
+	assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: 40}))
+	var blockNums []uint64
+	for {
+		result, err := itr.Next()
+		assert.NoError(t, err)
+		if result == nil {
+			break
+		}
+		info := result.(*ledger.CollectionConfigInfo)
+		assert.Equal(t, []string{fmt.Sprintf("chaincode1-%d", info.CommittingBlockNum)}, collNames(info))
+		blockNums = append(blockNums, info.CommittingBlockNum)
+	}
+	itr.Close()
+	assert.Equal(t, []uint64{30, 20, 10}, blockNums)
+
+	itr, err = retriever.GetCollectionConfigHistory("chaincode3")
+	assert.NoError(t, err)
+	defer itr.Close()
+	result, err := itr.Next()
+	assert.NoError(t, err)
+	assert.Nil(t, result)
+}
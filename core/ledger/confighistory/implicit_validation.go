@@ -0,0 +1,117 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/pkg/errors"
+)
+
+// ImplicitValidationReport is the outcome of the validation of the implicit collections of a chaincode against the orgs of the
+// channel, as returned by the function `Retriever.ValidateImplicitCollections`
+type ImplicitValidationReport struct {
+	ChaincodeName string
+	// Height is the height of the ledger at which the validation is made
+	Height uint64
+	// ExpectedOrgs are the MSP IDs of the orgs of the channel, each of which is expected to have exactly one implicit collection
+	ExpectedOrgs []string
+	// MissingOrgs are the expected orgs that do not have an implicit collection
+	MissingOrgs []string
+	// DuplicateOrgs are the orgs that have more than one implicit collection
+	DuplicateOrgs []string
+	// UnexpectedOrgs are the orgs that have an implicit collection but are not the orgs of the channel
+	UnexpectedOrgs []string
+	// MalformedCollections are the names of the implicit collections whose member orgs policy does not name exactly one org
+	MalformedCollections []string
+}
+
+// Valid returns true if each of the expected orgs has exactly one implicit collection and there is no other implicit collection
+func (r *ImplicitValidationReport) Valid() bool {
+	return len(r.MissingOrgs) == 0 && len(r.DuplicateOrgs) == 0 && len(r.UnexpectedOrgs) == 0 && len(r.MalformedCollections) == 0
+}
+
+// ValidateImplicitCollections implements function from the interface `Retriever`. It checks, for the current height of the ledger,
+// that each of the orgs of the channel has exactly one implicit collection for the given chaincode and that there is no implicit
+// collection for any other org. Since the implicit collections are synthesized from the current orgs of the channel, a misconfigured
+// channel or MSP manifests otherwise only as the failures in accessing the private data, so this is intended for surfacing such
+// misconfigurations upfront. The orgs of the channel are obtained from the `ledger.DeployedChaincodeInfoProvider`, which is required
+// to implement the interface `ChannelOrgsProvider`; an error is returned otherwise. The orgs and the implicit collections are both
+// read from the same query executor and hence, are consistent with each other. The cap on the number of the implicit collections
+// (see function `WithMaxImplicitCollections`) does not apply to the validation
+func (r *retriever) ValidateImplicitCollections(chaincodeName string) (*ImplicitValidationReport, error) {
+	orgsProvider, ok := r.ccInfoProvider.(ChannelOrgsProvider)
+	if !ok {
+		return nil, errors.Errorf("the chaincode info provider of ledger [%s] does not report the orgs of the channel, which are required for validating the implicit collections",
+			r.ledgerID)
+	}
+	info, err := r.ledgerInfoRetriever.GetBlockchainInfo()
+	if err != nil {
+		return nil, err
+	}
+	qe, err := r.ledgerInfoRetriever.NewQueryExecutor()
+	if err != nil {
+		return nil, err
+	}
+	defer qe.Done()
+	var expectedOrgs []string
+	err = r.ccInfoBreaker.call(func() error {
+		expectedOrgs, err = orgsProvider.ChannelOrgs(r.ledgerID, qe)
+		return err
+	})
+	if err != nil {
+		return nil, errors.WithMessage(err, "error while retrieving the orgs of channel "+r.ledgerID)
+	}
+	var implicitColls []*common.StaticCollectionConfig
+	if implicitCollsProvider, ok := r.ccInfoProvider.(ImplicitCollectionsProvider); ok {
+		err = r.ccInfoBreaker.call(func() error {
+			implicitColls, err = implicitCollsProvider.ImplicitCollections(r.ledgerID, chaincodeName, qe)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	report := &ImplicitValidationReport{
+		ChaincodeName: chaincodeName,
+		Height:        info.Height,
+		ExpectedOrgs:  expectedOrgs,
+	}
+	// the orgs are reported in the order in which their implicit collections appear
+	var orgs []string
+	numCollsByOrg := map[string]int{}
+	for _, implicitColl := range implicitColls {
+		collOrgs, err := memberOrgs(implicitColl)
+		if err != nil {
+			return nil, err
+		}
+		if len(collOrgs) != 1 {
+			report.MalformedCollections = append(report.MalformedCollections, implicitColl.Name)
+			continue
+		}
+		if numCollsByOrg[collOrgs[0]] == 0 {
+			orgs = append(orgs, collOrgs[0])
+		}
+		numCollsByOrg[collOrgs[0]]++
+	}
+	expected := map[string]bool{}
+	for _, org := range expectedOrgs {
+		expected[org] = true
+		if numCollsByOrg[org] == 0 {
+			report.MissingOrgs = append(report.MissingOrgs, org)
+		}
+	}
+	for _, org := range orgs {
+		if !expected[org] {
+			report.UnexpectedOrgs = append(report.UnexpectedOrgs, org)
+		}
+		if numCollsByOrg[org] > 1 {
+			report.DuplicateOrgs = append(report.DuplicateOrgs, org)
+		}
+	}
+	return report, nil
+}
@@ -0,0 +1,92 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateImplicitCollections(t *testing.T) {
+	ccInfoProvider := &channelOrgsCCInfoProvider{orgs: []string{"org1", "org2", "org3"}}
+	m := newMgrWithDBProvider(ccInfoProvider, newDBProviderWithStore(NewMemStoreProvider()), WithMaxImplicitCollections(1))
+	defer m.Close()
+	retriever := m.GetRetriever("ledger1", &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 100}})
+
+	t.Run("valid", func(t *testing.T) {
+		ccInfoProvider.ImplicitCollectionsReturns([]*common.StaticCollectionConfig{
+			sampleImplicitCollection("org1"), sampleImplicitCollection("org2"), sampleImplicitCollection("org3"),
+		}, nil)
+		report, err := retriever.ValidateImplicitCollections("chaincode1")
+		assert.NoError(t, err)
+		assert.True(t, report.Valid())
+		assert.Equal(t,
+			&ImplicitValidationReport{ChaincodeName: "chaincode1", Height: 100, ExpectedOrgs: []string{"org1", "org2", "org3"}},
+			report,
+		)
+		channelName, chaincodeName, _ := ccInfoProvider.ImplicitCollectionsArgsForCall(0)
+		assert.Equal(t, "ledger1", channelName)
+		assert.Equal(t, "chaincode1", chaincodeName)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		duplicateColl := sampleImplicitCollection("org1")
+		duplicateColl.Name = "_implicit_org_org1_duplicate"
+		noPolicyColl := &common.StaticCollectionConfig{Name: "_implicit_org_unknown"}
+		ccInfoProvider.ImplicitCollectionsReturns([]*common.StaticCollectionConfig{
+			sampleImplicitCollection("org4"), sampleImplicitCollection("org1"), noPolicyColl, duplicateColl,
+		}, nil)
+		report, err := retriever.ValidateImplicitCollections("chaincode1")
+		assert.NoError(t, err)
+		assert.False(t, report.Valid())
+		assert.Equal(t, []string{"org2", "org3"}, report.MissingOrgs)
+		assert.Equal(t, []string{"org1"}, report.DuplicateOrgs)
+		assert.Equal(t, []string{"org4"}, report.UnexpectedOrgs)
+		assert.Equal(t, []string{"_implicit_org_unknown"}, report.MalformedCollections)
+	})
+
+	t.Run("no-implicit-collections", func(t *testing.T) {
+		ccInfoProvider.ImplicitCollectionsReturns(nil, nil)
+		report, err := retriever.ValidateImplicitCollections("chaincode1")
+		assert.NoError(t, err)
+		assert.False(t, report.Valid())
+		assert.Equal(t, []string{"org1", "org2", "org3"}, report.MissingOrgs)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		ccInfoProvider.ImplicitCollectionsReturns(nil, errors.New("implicit-collections-error"))
+		_, err := retriever.ValidateImplicitCollections("chaincode1")
+		assert.EqualError(t, err, "implicit-collections-error")
+
+		ccInfoProvider.err = errors.New("channel-orgs-error")
+		defer func() { ccInfoProvider.err = nil }()
+		_, err = retriever.ValidateImplicitCollections("chaincode1")
+		assert.EqualError(t, err, "error while retrieving the orgs of channel ledger1: channel-orgs-error")
+	})
+
+	t.Run("channel-orgs-not-supported", func(t *testing.T) {
+		m := newMgrWithDBProvider(&implicitCollsCCInfoProvider{}, newDBProviderWithStore(NewMemStoreProvider()))
+		defer m.Close()
+		_, err := m.GetRetriever("ledger1", &dummyLedgerInfoRetriever{}).ValidateImplicitCollections("chaincode1")
+		assert.EqualError(t, err, "the chaincode info provider of ledger [ledger1] does not report the orgs of the channel, which are required for validating the implicit collections")
+	})
+}
+
+// channelOrgsCCInfoProvider reports the given orgs as the orgs of any channel
+type channelOrgsCCInfoProvider struct {
+	implicitCollsCCInfoProvider
+	orgs []string
+	err  error
+}
+
+func (p *channelOrgsCCInfoProvider) ChannelOrgs(channelName string, qe ledger.SimpleQueryExecutor) ([]string, error) {
+	return p.orgs, p.err
+}
@@ -0,0 +1,69 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/pkg/errors"
+)
+
+// latestPointerNamespacePrefix prefixes the namespaces that hold the highest config block of each chaincode
+const latestPointerNamespacePrefix = "latest~"
+
+func latestPointerNamespace(ns string) string {
+	return latestPointerNamespacePrefix + ns
+}
+
+// addLatestPointers points the latest pointers of the given chaincodes to the given block, never moving a pointer backwards
+func addLatestPointers(dbHandle *db, batch *batch, ccInfosByNamespace map[string][]*ledger.DeployedChaincodeInfo, blockNum uint64) (
+	map[*ledger.DeployedChaincodeInfo]bool, error) {
+	superseded := map[*ledger.DeployedChaincodeInfo]bool{}
+	for ns, ccInfos := range ccInfosByNamespace {
+		for _, ccInfo := range ccInfos {
+			key := constructCollectionConfigKey(ccInfo.Name)
+			latest, ok, err := dbHandle.latestBlockNum(ns, key)
+			if err != nil {
+				return nil, err
+			}
+			if ok && latest > blockNum {
+				superseded[ccInfo] = true
+			}
+			if ok && latest >= blockNum {
+				continue
+			}
+			batch.add(latestPointerNamespace(ns), key, 0, encodeBlockNum(blockNum))
+		}
+	}
+	return superseded, nil
+}
+
+// latestBlockNum returns the block number held by the latest pointer of the given <ns, key>, if any
+func (d *db) latestBlockNum(ns, key string) (uint64, bool, error) {
+	v, err := d.Get(encodeCompositeKey(latestPointerNamespace(ns), key, 0))
+	if err != nil || v == nil {
+		return 0, false, err
+	}
+	if len(v) != 8 {
+		return 0, false, errors.Errorf("invalid latest pointer [%#v] for key [%s] in namespace [%s]", v, key, ns)
+	}
+	return decodeBlockNum(v), true, nil
+}
+
+// mostRecentEntryBelowWithPointer is same as `mostRecentEntryBelow` but reads the pointed entry directly when possible
+func (d *db) mostRecentEntryBelowWithPointer(blockNum uint64, ns, key string) (*compositeKV, error) {
+	latest, ok, err := d.latestBlockNum(ns, key)
+	if err != nil {
+		return nil, err
+	}
+	if ok && latest < blockNum {
+		kv, err := d.entryAt(latest, ns, key)
+		if err != nil || kv != nil {
+			return kv, err
+		}
+	}
+	return d.mostRecentEntryBelow(blockNum, ns, key)
+}
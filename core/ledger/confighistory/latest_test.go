@@ -0,0 +1,236 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"testing"
+
+	"github.com/hyperledger/fabric/common/ledger/util/leveldbhelper"
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/mock"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLatestPointer(t *testing.T) {
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	mgr := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(NewMemStoreProvider()))
+	defer mgr.Close()
+	dbHandle := mgr.dbProvider.getDB("ledger1")
+	key := constructCollectionConfigKey("chaincode1")
+
+	for _, blockNum := range []uint64{10, 20} {
+		testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, "chaincode1",
+			sampleCollectionConfigPackage("coll", blockNum))
+		assert.NoError(t, mgr.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: blockNum}))
+		latest, ok, err := dbHandle.latestBlockNum(collectionConfigNamespace, key)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, blockNum, latest)
+	}
+
+	t.Run("pointer-used", func(t *testing.T) {
+		kv, err := dbHandle.mostRecentEntryBelowWithPointer(100, collectionConfigNamespace, key)
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(20), kv.blockNum)
+		// a block at or below the pointer is served by the scan
+		kv, err = dbHandle.mostRecentEntryBelowWithPointer(20, collectionConfigNamespace, key)
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(10), kv.blockNum)
+		kv, err = dbHandle.mostRecentEntryBelowWithPointer(10, collectionConfigNamespace, key)
+		assert.NoError(t, err)
+		assert.Nil(t, kv)
+	})
+
+	t.Run("pointer-missing", func(t *testing.T) {
+		// the entries written before the pointers were introduced do not have a pointer
+		batch := newBatch()
+		batch.add(collectionConfigNamespace, constructCollectionConfigKey("chaincode2"), 5, []byte("value-5"))
+		assert.NoError(t, dbHandle.writeBatch(batch, true))
+		kv, err := dbHandle.mostRecentEntryBelowWithPointer(100, collectionConfigNamespace, constructCollectionConfigKey("chaincode2"))
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("value-5"), kv.value)
+	})
+
+	t.Run("pointer-stale", func(t *testing.T) {
+		batch := newBatch()
+		batch.add(latestPointerNamespace(collectionConfigNamespace), constructCollectionConfigKey("chaincode3"), 0, encodeBlockNum(50))
+		batch.add(collectionConfigNamespace, constructCollectionConfigKey("chaincode3"), 30, []byte("value-30"))
+		assert.NoError(t, dbHandle.writeBatch(batch, true))
+		kv, err := dbHandle.mostRecentEntryBelowWithPointer(100, collectionConfigNamespace, constructCollectionConfigKey("chaincode3"))
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("value-30"), kv.value)
+	})
+
+	t.Run("pointer-invalid", func(t *testing.T) {
+		batch := newBatch()
+		batch.add(latestPointerNamespace(collectionConfigNamespace), constructCollectionConfigKey("chaincode4"), 0, []byte("garbage"))
+		assert.NoError(t, dbHandle.writeBatch(batch, true))
+		_, err := dbHandle.mostRecentEntryBelowWithPointer(100, collectionConfigNamespace, constructCollectionConfigKey("chaincode4"))
+		assert.Contains(t, err.Error(), "invalid latest pointer")
+	})
+
+	t.Run("pointer-deleted-with-history", func(t *testing.T) {
+		_, err := mgr.DeleteChaincodeHistory("ledger1", "chaincode1")
+		assert.NoError(t, err)
+		_, ok, err := dbHandle.latestBlockNum(collectionConfigNamespace, key)
+		assert.NoError(t, err)
+		assert.False(t, ok)
+		dummyLedgerInfoRetriever := &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 100}}
+		collConfig, err := mgr.GetRetriever("ledger1", dummyLedgerInfoRetriever).MostRecentCollectionConfigBelow(100, "chaincode1")
+		assert.NoError(t, err)
+		assert.Nil(t, collConfig)
+	})
+}
+
+func BenchmarkMostRecentEntryBelow(b *testing.B) {
+	dbPath := "/tmp/fabric/core/ledger/confighistory/benchmark"
+	os.RemoveAll(dbPath)
+	defer os.RemoveAll(dbPath)
+	provider := newDBProvider(dbPath)
+	defer provider.Close()
+	dbHandle := provider.getDB("ledger1")
+
+	const numVersions = 10000
+	key := constructCollectionConfigKey("chaincode1")
+	batch := newBatch()
+	for blockNum := uint64(1); blockNum <= numVersions; blockNum++ {
+		batch.add(collectionConfigNamespace, key, blockNum, []byte(fmt.Sprintf("value-%d", blockNum)))
+	}
+	batch.add(latestPointerNamespace(collectionConfigNamespace), key, 0, encodeBlockNum(numVersions))
+	if err := dbHandle.writeBatch(batch, true); err != nil {
+		b.Fatal(err)
+	}
+
+	b.Run("reverse-scan", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := dbHandle.mostRecentEntryBelow(numVersions+1, collectionConfigNamespace, key); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("latest-pointer", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := dbHandle.mostRecentEntryBelowWithPointer(numVersions+1, collectionConfigNamespace, key); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestLatestPointerCrashConsistency(t *testing.T) {
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	setup := func(crashAt int) (*mgr, *crashingStore) {
+		store := &crashingStore{Store: NewMemStoreProvider().GetStore("ledger1"), crashAt: crashAt}
+		return newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(&singleStoreProvider{store})), store
+	}
+	commit := func(m *mgr, blockNum uint64) error {
+		testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, "chaincode1",
+			sampleCollectionConfigPackage("coll", blockNum))
+		return m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: blockNum})
+	}
+	key := constructCollectionConfigKey("chaincode1")
+	configKeyOf := func(blockNum uint64) string {
+		return string(encodeCompositeKey(collectionConfigNamespace, key, blockNum))
+	}
+	pointerKey := string(encodeCompositeKey(latestPointerNamespace(collectionConfigNamespace), key, 0))
+
+	// checkConsistency asserts that the latest pointer, if present, points to the most recent collection config and
+	// returns whether the pointer is present and whether any collection config is present
+	checkConsistency := func(t *testing.T, m *mgr) (bool, bool) {
+		dbHandle := m.dbProvider.getDB("ledger1")
+		latest, pointerPresent, err := dbHandle.latestBlockNum(collectionConfigNamespace, key)
+		assert.NoError(t, err)
+		mostRecent, err := dbHandle.mostRecentEntryBelow(math.MaxUint64, collectionConfigNamespace, key)
+		assert.NoError(t, err)
+		if pointerPresent {
+			if assert.NotNil(t, mostRecent, "a latest pointer without any collection config") {
+				assert.Equal(t, mostRecent.blockNum, latest)
+			}
+		}
+		return pointerPresent, mostRecent != nil
+	}
+
+	t.Run("crash-during-commit", func(t *testing.T) {
+		blockNums := []uint64{10, 20, 30}
+		for crashAt := 0; crashAt <= len(blockNums); crashAt++ {
+			m, store := setup(crashAt)
+			for _, blockNum := range blockNums {
+				if err := commit(m, blockNum); err != nil {
+					assert.EqualError(t, err, "simulated crash")
+					break
+				}
+			}
+			// the collection config, the latest pointer, and the author of a block are written in a single batch
+			assert.Len(t, store.appliedBatches, crashAt)
+			for i, batch := range store.appliedBatches {
+				assert.Contains(t, batch.KVs, configKeyOf(blockNums[i]))
+				assert.Contains(t, batch.KVs, pointerKey)
+			}
+			pointerPresent, configPresent := checkConsistency(t, m)
+			assert.Equal(t, configPresent, pointerPresent)
+			m.Close()
+		}
+	})
+
+	t.Run("crash-during-delete", func(t *testing.T) {
+		for crashAfter := 0; ; crashAfter++ {
+			m, store := setup(-1)
+			for _, blockNum := range []uint64{10, 20, 30} {
+				assert.NoError(t, commit(m, blockNum))
+			}
+			store.crashAt = len(store.appliedBatches) + crashAfter
+			_, err := m.DeleteChaincodeHistory("ledger1", "chaincode1")
+			pointerPresent, configPresent := checkConsistency(t, m)
+			m.Close()
+			if err == nil {
+				assert.False(t, pointerPresent)
+				assert.False(t, configPresent)
+				break
+			}
+			assert.Contains(t, err.Error(), "simulated crash")
+			// the pointer is deleted first and hence, is present only if nothing is deleted yet
+			assert.Equal(t, crashAfter == 0, pointerPresent)
+			assert.True(t, configPresent || crashAfter > 0)
+		}
+	})
+}
+
+// crashingStore simulates a crash of the peer at a write: the batch of that write and of all the subsequent writes
+// are not applied. A negative `crashAt` disables the crash. The store does not implement the optional interfaces, so
+// that all the writes go through the function `WriteBatch`
+type crashingStore struct {
+	Store
+	crashAt        int
+	appliedBatches []*leveldbhelper.UpdateBatch
+}
+
+func (s *crashingStore) WriteBatch(batch *leveldbhelper.UpdateBatch, sync bool) error {
+	if s.crashAt >= 0 && len(s.appliedBatches) >= s.crashAt {
+		return errors.New("simulated crash")
+	}
+	if err := s.Store.WriteBatch(batch, sync); err != nil {
+		return err
+	}
+	s.appliedBatches = append(s.appliedBatches, batch)
+	return nil
+}
+
+type singleStoreProvider struct {
+	store Store
+}
+
+func (p *singleStoreProvider) GetStore(ledgerID string) Store {
+	return p.store
+}
+
+func (p *singleStoreProvider) Close() {
+}
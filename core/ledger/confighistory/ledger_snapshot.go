@@ -0,0 +1,57 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// SnapshotDataFileName is the name of the file, within the directory of a ledger snapshot, that holds the config history of the ledger
+const SnapshotDataFileName = "confighistory.data"
+
+// ExportSnapshot implements function in the interface 'Mgr'. It writes all the entries of the config history of the given ledger,
+// in the format of the function `ExportConfigHistory`, to the file `SnapshotDataFileName` in the given directory of a ledger snapshot.
+// The file is synced to the disk before returning. The directory is expected to exist and not to contain the file already; on a
+// failure, the partially written file is removed, so that an incomplete snapshot is not mistaken for a complete one
+func (m *mgr) ExportSnapshot(ledgerID, snapshotDir string) error {
+	path := filepath.Join(snapshotDir, SnapshotDataFileName)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return errors.Wrapf(err, "error while creating the config history snapshot file [%s]", path)
+	}
+	err = m.ExportConfigHistory(ledgerID, f)
+	if err == nil {
+		err = errors.Wrapf(f.Sync(), "error while syncing the config history snapshot file [%s]", path)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = errors.Wrapf(closeErr, "error while closing the config history snapshot file [%s]", path)
+	}
+	if err != nil {
+		os.Remove(path)
+		return err
+	}
+	logger.Infof("Exported config history of ledger [%s] to the snapshot directory [%s]", ledgerID, snapshotDir)
+	return nil
+}
+
+// ImportFromSnapshot implements function in the interface 'Mgr'. It rebuilds the config history of the given ledger from the file
+// written by the function `ExportSnapshot` in the given directory of a ledger snapshot, when the ledger is created from the snapshot.
+// As in the function `ImportConfigHistory`, the config history of the ledger is expected to be empty and a corrupted file leaves it
+// unchanged
+func (m *mgr) ImportFromSnapshot(ledgerID, snapshotDir string) error {
+	path := filepath.Join(snapshotDir, SnapshotDataFileName)
+	f, err := os.Open(path)
+	if err != nil {
+		return errors.Wrapf(err, "error while opening the config history snapshot file [%s]", path)
+	}
+	defer f.Close()
+	return errors.WithMessage(m.ImportConfigHistory(ledgerID, f),
+		"error while importing the config history from the snapshot file ["+path+"]")
+}
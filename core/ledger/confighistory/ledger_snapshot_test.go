@@ -0,0 +1,70 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/mock"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportImportSnapshot(t *testing.T) {
+	snapshotDir, err := ioutil.TempDir("", "confighistorysnapshot")
+	assert.NoError(t, err)
+	defer os.RemoveAll(snapshotDir)
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	mgr := NewMgrWithStore(mockCCInfoProvider, NewMemStoreProvider())
+	defer mgr.Close()
+
+	for _, blockNum := range []uint64{5, 10} {
+		testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, "chaincode1",
+			sampleCollectionConfigPackage("chaincode1", blockNum))
+		assert.NoError(t, mgr.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: blockNum}))
+	}
+	assert.NoError(t, mgr.ExportSnapshot("ledger1", snapshotDir))
+	// an existing snapshot file is not overwritten
+	assert.Error(t, mgr.ExportSnapshot("ledger1", snapshotDir))
+
+	assert.NoError(t, mgr.ImportFromSnapshot("ledger2", snapshotDir))
+	retriever := mgr.GetRetriever("ledger2", &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 100}})
+	for _, blockNum := range []uint64{5, 10} {
+		collConfig, err := retriever.CollectionConfigAt(blockNum, "chaincode1")
+		assert.NoError(t, err)
+		assert.True(t, proto.Equal(sampleCollectionConfigPackage("chaincode1", blockNum), collConfig.CollectionConfig))
+	}
+	// the import requires an empty config history
+	assert.Contains(t, mgr.ImportFromSnapshot("ledger2", snapshotDir).Error(), "config history of ledger [ledger2] is not empty")
+
+	// a corrupted snapshot file leaves the config history unchanged
+	path := filepath.Join(snapshotDir, SnapshotDataFileName)
+	data, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	data[len(data)-1] ^= 0xff
+	assert.NoError(t, ioutil.WriteFile(path, data, 0644))
+	assert.Contains(t, mgr.ImportFromSnapshot("ledger3", snapshotDir).Error(), "checksum mismatch")
+	ledgersWithHistory, err := mgr.LedgersWithHistory()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"ledger1", "ledger2"}, ledgersWithHistory)
+
+	assert.Error(t, mgr.ImportFromSnapshot("ledger3", filepath.Join(snapshotDir, "missing")))
+
+	// a failed export does not leave a partial snapshot file
+	closedMgr := NewMgrWithStore(mockCCInfoProvider, NewMemStoreProvider())
+	closedMgr.Close()
+	otherSnapshotDir := filepath.Join(snapshotDir, "other")
+	assert.NoError(t, os.Mkdir(otherSnapshotDir, 0755))
+	assert.Error(t, closedMgr.ExportSnapshot("ledger1", otherSnapshotDir))
+	_, err = os.Stat(filepath.Join(otherSnapshotDir, SnapshotDataFileName))
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,82 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"go.uber.org/zap/zapcore"
+)
+
+const loggerName = "confighistory"
+
+// logLevelEnabled returns whether the messages of the given level are logged by the logger of this package. The level is
+// looked up in the active logging spec because the function `IsEnabledFor` of the logger does not take the level of the
+// individual loggers into account
+func logLevelEnabled(level zapcore.Level) bool {
+	return flogging.Global.Level(loggerName).Enabled(level)
+}
+
+// logSampler logs only one in every `rate` occurrences of each of the high-frequency log messages, which are told apart
+// by an event name, so that a busy ledger does not flood the logs with the same message (see function `WithLogSampling`)
+type logSampler struct {
+	rate   uint64
+	mutex  sync.Mutex
+	counts map[string]uint64
+}
+
+func newLogSampler(rate int) *logSampler {
+	if rate < 1 {
+		rate = 1
+	}
+	return &logSampler{
+		rate:   uint64(rate),
+		counts: map[string]uint64{},
+	}
+}
+
+// shouldLog counts an occurrence of the given event and returns whether the occurrence is to be logged at the given level.
+// The occurrences that are disabled by the level of the logger are not counted, so that the first occurrence after the level
+// is lowered is logged
+func (s *logSampler) shouldLog(level zapcore.Level, event string) bool {
+	if !logLevelEnabled(level) {
+		return false
+	}
+	if s.rate == 1 {
+		return true
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	count := s.counts[event]
+	s.counts[event] = count + 1
+	return count%s.rate == 0
+}
+
+// debugf logs the message at the debug level, if the occurrence of the given event is sampled
+func (s *logSampler) debugf(event string, template string, args ...interface{}) {
+	if s.shouldLog(zapcore.DebugLevel, event) {
+		logger.Debugf(s.annotate(template), args...)
+	}
+}
+
+// warningf logs the message at the warning level, if the occurrence of the given event is sampled
+func (s *logSampler) warningf(event string, template string, args ...interface{}) {
+	if s.shouldLog(zapcore.WarnLevel, event) {
+		logger.Warningf(s.annotate(template), args...)
+	}
+}
+
+// annotate appends the sampling rate to the template of a sampled message, so that the reader of the logs does not take the
+// logged occurrences for all the occurrences
+func (s *logSampler) annotate(template string) string {
+	if s.rate == 1 {
+		return template
+	}
+	return template + fmt.Sprintf(" (logged 1 in %d occurrences)", s.rate)
+}
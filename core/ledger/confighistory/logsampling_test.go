@@ -0,0 +1,69 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/mock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestLogSampler(t *testing.T) {
+	s := newLogSampler(3)
+	var sampled []bool
+	for i := 0; i < 7; i++ {
+		sampled = append(sampled, s.shouldLog(zapcore.DebugLevel, "event1"))
+	}
+	assert.Equal(t, []bool{true, false, false, true, false, false, true}, sampled)
+	// the events are counted separately
+	assert.True(t, s.shouldLog(zapcore.DebugLevel, "event2"))
+	assert.False(t, s.shouldLog(zapcore.DebugLevel, "event2"))
+	assert.Equal(t, "message (logged 1 in 3 occurrences)", s.annotate("message"))
+
+	t.Run("disabled-level", func(t *testing.T) {
+		flogging.ActivateSpec("confighistory=info")
+		defer flogging.ActivateSpec("confighistory=debug")
+		s := newLogSampler(2)
+		// the occurrences disabled by the log level are not counted
+		assert.False(t, s.shouldLog(zapcore.DebugLevel, "event1"))
+		assert.False(t, s.shouldLog(zapcore.DebugLevel, "event1"))
+		assert.True(t, s.shouldLog(zapcore.WarnLevel, "event1"))
+		flogging.ActivateSpec("confighistory=debug")
+		assert.False(t, s.shouldLog(zapcore.DebugLevel, "event1"))
+		assert.True(t, s.shouldLog(zapcore.DebugLevel, "event1"))
+	})
+
+	t.Run("no-sampling", func(t *testing.T) {
+		for _, rate := range []int{-1, 0, 1} {
+			s := newLogSampler(rate)
+			for i := 0; i < 3; i++ {
+				assert.True(t, s.shouldLog(zapcore.DebugLevel, "event1"))
+			}
+			assert.Equal(t, "message", s.annotate("message"))
+		}
+	})
+}
+
+func TestWithLogSampling(t *testing.T) {
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(NewMemStoreProvider()), WithLogSampling(10))
+	defer m.Close()
+	for blockNum := uint64(1); blockNum <= 15; blockNum++ {
+		assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: blockNum}))
+	}
+	// the blocks that do not update any chaincode are sampled
+	assert.Equal(t, uint64(15), m.logSampler.counts["no-updated-chaincodes"])
+
+	testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, "chaincode1", sampleCollectionConfigPackage("coll", 20))
+	assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: 20}))
+	r := m.GetRetriever("ledger1", &dummyLedgerInfoRetriever{})
+	assert.Equal(t, m.logSampler, r.(*retriever).logSampler)
+}
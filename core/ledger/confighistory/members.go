@@ -0,0 +1,54 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"fmt"
+)
+
+// ErrCollectionNotFound is returned by the function `Retriever.CollectionMembersAt` if the collection config of the chaincode
+// that is in effect at the requested block does not include the requested collection
+type ErrCollectionNotFound struct {
+	ChaincodeName  string
+	CollectionName string
+	BlockNum       uint64
+}
+
+func (e *ErrCollectionNotFound) Error() string {
+	return fmt.Sprintf("collection [%s] of chaincode [%s] is not found at block [%d]", e.CollectionName, e.ChaincodeName, e.BlockNum)
+}
+
+// CollectionMembersAt implements function from the interface `Retriever`. It returns the MSP IDs of the member orgs of the collection,
+// as per the collection config of the chaincode that is in effect at the given block (i.e., the one committed at or below the block).
+// The collection may be an explicit or an implicit collection of the chaincode. The member orgs are the orgs of all the principals of
+// the signature policy of the collection; the nested and the n-out-of rules of the policy refer to these principals by their index
+// and hence, an org is included irrespective of the rules that refer to it. `ErrCollectionNotFound` is returned if the collection
+// does not exist at the block, including the case where the chaincode has no collection config at the block
+func (r *retriever) CollectionMembersAt(blockNum uint64, chaincodeName, collectionName string) ([]string, error) {
+	if err := r.checkBlockCommitted(blockNum); err != nil {
+		return nil, err
+	}
+	// the block is committed and hence, it is lower than the max uint64
+	collConfig, err := r.mostRecentCollectionConfigBelow(blockNum+1, chaincodeName, nil)
+	if err != nil {
+		return nil, err
+	}
+	if collConfig != nil {
+		for _, config := range collConfig.CollectionConfig.Config {
+			staticConfig := config.GetStaticCollectionConfig()
+			if staticConfig == nil || staticConfig.Name != collectionName {
+				continue
+			}
+			orgs, err := memberOrgs(staticConfig)
+			if err != nil {
+				return nil, r.withContext(err, chaincodeName, blockNum)
+			}
+			return orgs, nil
+		}
+	}
+	return nil, &ErrCollectionNotFound{ChaincodeName: chaincodeName, CollectionName: collectionName, BlockNum: blockNum}
+}
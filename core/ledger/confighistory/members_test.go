@@ -0,0 +1,85 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/common/cauthdsl"
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/msp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollectionMembersAt(t *testing.T) {
+	mockCCInfoProvider := &implicitCollsCCInfoProvider{}
+	m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(NewMemStoreProvider()))
+	defer m.Close()
+
+	// org1 OR (2 out of org2, org3, org1)
+	nestedPolicy := envelope(
+		cauthdsl.Or(
+			cauthdsl.SignedBy(0),
+			cauthdsl.NOutOf(2, []*common.SignaturePolicy{cauthdsl.SignedBy(1), cauthdsl.SignedBy(2), cauthdsl.SignedBy(0)}),
+		),
+		memberPrincipal("org1"), memberPrincipal("org2"), memberPrincipal("org3"),
+	)
+	for _, version := range []struct {
+		blockNum      uint64
+		collConfigPkg *common.CollectionConfigPackage
+	}{
+		{10, collConfigPkg(coll("coll1", cauthdsl.SignedByAnyMember([]string{"org1", "org2"}), 0))},
+		{20, collConfigPkg(coll("coll1", nestedPolicy, 0), coll("coll2", nil, 0))},
+	} {
+		testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(&mockCCInfoProvider.DeployedChaincodeInfoProvider, "chaincode1", version.collConfigPkg)
+		assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: version.blockNum}))
+	}
+	retriever := m.GetRetriever("ledger1", &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 100}})
+
+	orgs, err := retriever.CollectionMembersAt(15, "chaincode1", "coll1")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"org1", "org2"}, orgs)
+
+	orgs, err = retriever.CollectionMembersAt(20, "chaincode1", "coll1")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"org1", "org2", "org3"}, orgs)
+
+	// a collection without a signature policy has no member orgs
+	orgs, err = retriever.CollectionMembersAt(20, "chaincode1", "coll2")
+	assert.NoError(t, err)
+	assert.Nil(t, orgs)
+
+	mockCCInfoProvider.ImplicitCollectionsReturns([]*common.StaticCollectionConfig{sampleImplicitCollection("org4")}, nil)
+	orgs, err = retriever.CollectionMembersAt(20, "chaincode1", "_implicit_org_org4")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"org4"}, orgs)
+
+	t.Run("not-found", func(t *testing.T) {
+		_, err := retriever.CollectionMembersAt(15, "chaincode1", "coll2")
+		assert.Equal(t, &ErrCollectionNotFound{ChaincodeName: "chaincode1", CollectionName: "coll2", BlockNum: 15}, err)
+		assert.EqualError(t, err, "collection [coll2] of chaincode [chaincode1] is not found at block [15]")
+		_, err = retriever.CollectionMembersAt(5, "chaincode1", "coll1")
+		assert.IsType(t, &ErrCollectionNotFound{}, err)
+		_, err = retriever.CollectionMembersAt(20, "chaincode2", "coll1")
+		assert.IsType(t, &ErrCollectionNotFound{}, err)
+	})
+
+	t.Run("block-not-committed", func(t *testing.T) {
+		_, err := retriever.CollectionMembersAt(100, "chaincode1", "coll1")
+		assert.IsType(t, &ledger.ErrCollectionConfigNotYetAvailable{}, err)
+	})
+
+	t.Run("invalid-policy", func(t *testing.T) {
+		badPolicy := envelope(cauthdsl.SignedBy(0), &msp.MSPPrincipal{PrincipalClassification: msp.MSPPrincipal_ANONYMITY})
+		testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(&mockCCInfoProvider.DeployedChaincodeInfoProvider, "chaincode1", collConfigPkg(coll("coll1", badPolicy, 0)))
+		assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: 30}))
+		_, err := retriever.CollectionMembersAt(30, "chaincode1", "coll1")
+		assert.EqualError(t, err, "error while retrieving the collection config of chaincode [chaincode1] for block [30] of ledger [ledger1]: "+
+			"error while extracting member orgs of collection coll1: invalid principal type 3")
+	})
+}
@@ -0,0 +1,220 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/hyperledger/fabric/common/ledger/util/leveldbhelper"
+)
+
+// NewMemStoreProvider returns a `StoreProvider` that keeps the config history in memory. This is intended for tests and
+// for the ephemeral ledgers of the embedders that do not need the config history to survive a restart. The stores
+// order the keys in the same way as the leveldb based stores and hence, the queries behave identically
+func NewMemStoreProvider() StoreProvider {
+	return &memStoreProvider{stores: map[string]*memStore{}}
+}
+
+type memStoreProvider struct {
+	mux    sync.Mutex
+	stores map[string]*memStore
+}
+
+// GetStore implements function from the interface `StoreProvider`
+func (p *memStoreProvider) GetStore(ledgerID string) Store {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	s, ok := p.stores[ledgerID]
+	if !ok {
+		s = &memStore{kvs: map[string][]byte{}}
+		p.stores[ledgerID] = s
+	}
+	return s
+}
+
+// LedgerIDs implements function from the interface `StoreLister`
+func (p *memStoreProvider) LedgerIDs() ([]string, error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	var ledgerIDs []string
+	for ledgerID, s := range p.stores {
+		s.mux.RLock()
+		if len(s.keys) > 0 {
+			ledgerIDs = append(ledgerIDs, ledgerID)
+		}
+		s.mux.RUnlock()
+	}
+	return ledgerIDs, nil
+}
+
+// Close implements function from the interface `StoreProvider`
+func (p *memStoreProvider) Close() {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	p.stores = map[string]*memStore{}
+}
+
+// memStore maintains the keys in a sorted slice in addition to a map, so that the range
+// iterations can locate the start of the range via a binary search
+type memStore struct {
+	mux  sync.RWMutex
+	kvs  map[string][]byte
+	keys []string
+}
+
+// Get implements function from the interface `Store`
+func (s *memStore) Get(key []byte) ([]byte, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	v, ok := s.kvs[string(key)]
+	if !ok {
+		return nil, nil
+	}
+	return append([]byte(nil), v...), nil
+}
+
+// ApproximateSize implements function from the interface `SizeEstimator`. The size is the sum of the sizes of the keys and the values
+func (s *memStore) ApproximateSize() (uint64, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	var size uint64
+	for k, v := range s.kvs {
+		size += uint64(len(k) + len(v))
+	}
+	return size, nil
+}
+
+// GetSnapshot implements function from the interface `Snapshotter`. The snapshot is a copy of the store
+func (s *memStore) GetSnapshot() (StoreSnapshot, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	snapshot := &memStore{kvs: make(map[string][]byte, len(s.kvs)), keys: append([]string(nil), s.keys...)}
+	for k, v := range s.kvs {
+		snapshot.kvs[k] = v
+	}
+	return &memStoreSnapshot{snapshot}, nil
+}
+
+type memStoreSnapshot struct {
+	*memStore
+}
+
+// Release implements function from the interface `StoreSnapshot`
+func (s *memStoreSnapshot) Release() {
+}
+
+// WriteBatch implements function from the interface `Store`
+func (s *memStore) WriteBatch(batch *leveldbhelper.UpdateBatch, sync bool) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	for k, v := range batch.KVs {
+		_, exists := s.kvs[k]
+		i := sort.SearchStrings(s.keys, k)
+		switch {
+		case v == nil && exists:
+			delete(s.kvs, k)
+			s.keys = append(s.keys[:i], s.keys[i+1:]...)
+		case v != nil && !exists:
+			s.keys = append(s.keys, "")
+			copy(s.keys[i+1:], s.keys[i:])
+			s.keys[i] = k
+			fallthrough
+		case v != nil:
+			s.kvs[k] = append([]byte(nil), v...)
+		}
+	}
+	return nil
+}
+
+// DeleteRange implements function from the interface `RangeDeleter`. The keys in the range are adjacent in the sorted
+// slice and hence, are removed from the slice at once
+func (s *memStore) DeleteRange(startKey []byte, endKey []byte, sync bool) (int, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	start, end := 0, len(s.keys)
+	if startKey != nil {
+		start = sort.SearchStrings(s.keys, string(startKey))
+	}
+	if endKey != nil {
+		end = sort.SearchStrings(s.keys, string(endKey))
+	}
+	if start >= end {
+		return 0, nil
+	}
+	for _, k := range s.keys[start:end] {
+		delete(s.kvs, k)
+	}
+	s.keys = append(s.keys[:start], s.keys[end:]...)
+	return end - start, nil
+}
+
+// GetIterator implements function from the interface `Store`. The returned iterator operates on a
+// snapshot of the range taken at the time of this call and hence, is not affected by the subsequent writes
+func (s *memStore) GetIterator(startKey []byte, endKey []byte) Iterator {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	start, end := 0, len(s.keys)
+	if startKey != nil {
+		start = sort.SearchStrings(s.keys, string(startKey))
+	}
+	if endKey != nil {
+		end = sort.SearchStrings(s.keys, string(endKey))
+	}
+	itr := &memIterator{index: -1}
+	for i := start; i < end; i++ {
+		itr.keys = append(itr.keys, s.keys[i])
+		itr.values = append(itr.values, s.kvs[s.keys[i]])
+	}
+	return itr
+}
+
+// GetReverseIterator implements function from the interface `ReverseIterable`
+func (s *memStore) GetReverseIterator(startKey []byte, endKey []byte) Iterator {
+	itr := s.GetIterator(startKey, endKey).(*memIterator)
+	reverseMemIterator(itr)
+	return itr
+}
+
+type memIterator struct {
+	keys   []string
+	values [][]byte
+	index  int
+	err    error
+}
+
+// reverseMemIterator reverses the order of the keys of an iterator that is not yet moved
+func reverseMemIterator(itr *memIterator) {
+	for i, j := 0, len(itr.keys)-1; i < j; i, j = i+1, j-1 {
+		itr.keys[i], itr.keys[j] = itr.keys[j], itr.keys[i]
+		itr.values[i], itr.values[j] = itr.values[j], itr.values[i]
+	}
+}
+
+func (itr *memIterator) Next() bool {
+	if itr.index < len(itr.keys) {
+		itr.index++
+	}
+	return itr.index < len(itr.keys)
+}
+
+func (itr *memIterator) Key() []byte {
+	return []byte(itr.keys[itr.index])
+}
+
+func (itr *memIterator) Value() []byte {
+	return itr.values[itr.index]
+}
+
+func (itr *memIterator) Error() error {
+	return itr.err
+}
+
+func (itr *memIterator) Release() {
+	itr.keys, itr.values = nil, nil
+	itr.index = 0
+}
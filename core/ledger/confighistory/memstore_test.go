@@ -0,0 +1,104 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/common/ledger/util/leveldbhelper"
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/mock"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemStore(t *testing.T) {
+	provider := NewMemStoreProvider()
+	store := provider.GetStore("ledger1")
+	assert.True(t, store == provider.GetStore("ledger1"))
+	assert.True(t, store != provider.GetStore("ledger2"))
+
+	batch := leveldbhelper.NewUpdateBatch()
+	for _, k := range []string{"key3", "key1", "key5", "key2", "key4"} {
+		batch.Put([]byte(k), []byte("val-"+k))
+	}
+	assert.NoError(t, store.WriteBatch(batch, true))
+	checkMemStoreKeys(t, store, nil, nil, []string{"key1", "key2", "key3", "key4", "key5"})
+	checkMemStoreKeys(t, store, []byte("key2"), []byte("key4"), []string{"key2", "key3"})
+	checkMemStoreKeys(t, store, []byte("key0"), []byte("key1"), nil)
+	checkMemStoreKeys(t, store, []byte("key45"), nil, []string{"key5"})
+
+	itr := store.GetIterator(nil, nil)
+	batch = leveldbhelper.NewUpdateBatch()
+	batch.Delete([]byte("key2"))
+	batch.Delete([]byte("non-existing-key"))
+	batch.Put([]byte("key3"), []byte("new-val-key3"))
+	batch.Put([]byte("key0"), []byte("val-key0"))
+	assert.NoError(t, store.WriteBatch(batch, true))
+	// an existing iterator is not affected by the subsequent writes
+	var keys []string
+	for itr.Next() {
+		keys = append(keys, string(itr.Key()))
+	}
+	itr.Release()
+	assert.Equal(t, []string{"key1", "key2", "key3", "key4", "key5"}, keys)
+	checkMemStoreKeys(t, store, nil, nil, []string{"key0", "key1", "key3", "key4", "key5"})
+
+	val, err := store.Get([]byte("key3"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("new-val-key3"), val)
+	val, err = store.Get([]byte("key2"))
+	assert.NoError(t, err)
+	assert.Nil(t, val)
+
+	numDeleted, err := store.(RangeDeleter).DeleteRange([]byte("key1"), []byte("key4"), true)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, numDeleted)
+	checkMemStoreKeys(t, store, nil, nil, []string{"key0", "key4", "key5"})
+	val, err = store.Get([]byte("key3"))
+	assert.NoError(t, err)
+	assert.Nil(t, val)
+
+	provider.Close()
+	checkMemStoreKeys(t, provider.GetStore("ledger1"), nil, nil, nil)
+}
+
+func TestMgrWithMemStore(t *testing.T) {
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	mgr := NewMgrWithStore(mockCCInfoProvider, NewMemStoreProvider())
+	defer mgr.Close()
+	for _, ccName := range []string{"chaincode1", "chaincode0"} {
+		for _, blockNum := range []uint64{5, 10, 15} {
+			testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, ccName, sampleCollectionConfigPackage(ccName, blockNum))
+			assert.NoError(t, mgr.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: blockNum}))
+		}
+	}
+	retriever := mgr.GetRetriever("ledger1", &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 100}})
+	for queryBlockNum, expectedBlockNum := range map[uint64]uint64{50: 15, 15: 10, 11: 10, 6: 5} {
+		collConfig, err := retriever.MostRecentCollectionConfigBelow(queryBlockNum, "chaincode1")
+		assert.NoError(t, err)
+		assert.Equal(t, expectedBlockNum, collConfig.CommittingBlockNum)
+		assert.Equal(t, sampleCollectionConfigPackage("chaincode1", expectedBlockNum).String(), collConfig.CollectionConfig.String())
+	}
+	collConfig, err := retriever.MostRecentCollectionConfigBelow(5, "chaincode1")
+	assert.NoError(t, err)
+	assert.Nil(t, collConfig)
+	collConfig, err = retriever.CollectionConfigAt(10, "chaincode0")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(10), collConfig.CommittingBlockNum)
+}
+
+func checkMemStoreKeys(t *testing.T, store Store, startKey, endKey []byte, expectedKeys []string) {
+	itr := store.GetIterator(startKey, endKey)
+	defer itr.Release()
+	var keys []string
+	for itr.Next() {
+		keys = append(keys, string(itr.Key()))
+	}
+	assert.NoError(t, itr.Error())
+	assert.Equal(t, expectedKeys, keys)
+}
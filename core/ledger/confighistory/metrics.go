@@ -0,0 +1,218 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"time"
+
+	"github.com/hyperledger/fabric/common/metrics"
+)
+
+type stats struct {
+	ccInfoLookupTime     metrics.Histogram
+	marshalTime          metrics.Histogram
+	writeTime            metrics.Histogram
+	asyncQueueDepth      metrics.Gauge
+	asyncWritesBlocked   metrics.Counter
+	asyncWritesDiscarded metrics.Counter
+	ccInfoCircuitOpen    metrics.Gauge
+	ccInfoCircuitTrips   metrics.Counter
+	collConfigsWritten   metrics.Counter
+	queries              metrics.Counter
+}
+
+func newStats(metricsProvider metrics.Provider) *stats {
+	return &stats{
+		ccInfoLookupTime:     metricsProvider.NewHistogram(ccInfoLookupTimeOpts),
+		marshalTime:          metricsProvider.NewHistogram(marshalTimeOpts),
+		writeTime:            metricsProvider.NewHistogram(writeTimeOpts),
+		asyncQueueDepth:      metricsProvider.NewGauge(asyncQueueDepthOpts),
+		asyncWritesBlocked:   metricsProvider.NewCounter(asyncWritesBlockedOpts),
+		asyncWritesDiscarded: metricsProvider.NewCounter(asyncWritesDiscardedOpts),
+		ccInfoCircuitOpen:    metricsProvider.NewGauge(ccInfoCircuitOpenOpts),
+		ccInfoCircuitTrips:   metricsProvider.NewCounter(ccInfoCircuitTripsOpts),
+		collConfigsWritten:   metricsProvider.NewCounter(collConfigsWrittenOpts),
+		queries:              metricsProvider.NewCounter(queriesOpts),
+	}
+}
+
+func (s *stats) updateCCInfoLookupTime(ledgerID string, timeTaken time.Duration) {
+	s.ccInfoLookupTime.With("channel", ledgerID).Observe(timeTaken.Seconds())
+}
+
+func (s *stats) updateMarshalTime(ledgerID string, timeTaken time.Duration) {
+	s.marshalTime.With("channel", ledgerID).Observe(timeTaken.Seconds())
+}
+
+func (s *stats) updateWriteTime(ledgerID string, timeTaken time.Duration) {
+	s.writeTime.With("channel", ledgerID).Observe(timeTaken.Seconds())
+}
+
+func (s *stats) updateAsyncQueueDepth(ledgerID string, depth int) {
+	s.asyncQueueDepth.With("channel", ledgerID).Set(float64(depth))
+}
+
+func (s *stats) incrementAsyncWritesBlocked(ledgerID string) {
+	s.asyncWritesBlocked.With("channel", ledgerID).Add(1)
+}
+
+func (s *stats) incrementAsyncWritesDiscarded(ledgerID string) {
+	s.asyncWritesDiscarded.With("channel", ledgerID).Add(1)
+}
+
+func (s *stats) updateCCInfoCircuitOpen(open bool) {
+	if open {
+		s.ccInfoCircuitOpen.Set(1)
+		return
+	}
+	s.ccInfoCircuitOpen.Set(0)
+}
+
+func (s *stats) incrementCCInfoCircuitTrips() {
+	s.ccInfoCircuitTrips.Add(1)
+}
+
+func (s *stats) addCollConfigsWritten(ledgerID string, numCollConfigs int) {
+	s.collConfigsWritten.With("channel", ledgerID).Add(float64(numCollConfigs))
+}
+
+// incrementQueries counts a query for the collection config of a chaincode as a hit if a collection config is found and as a miss otherwise
+func (s *stats) incrementQueries(ledgerID string, found bool) {
+	result := "miss"
+	if found {
+		result = "hit"
+	}
+	s.queries.With("channel", ledgerID, "result", result).Add(1)
+}
+
+var (
+	ccInfoLookupTimeOpts = metrics.HistogramOpts{
+		Namespace:    "ledger",
+		Subsystem:    "",
+		Name:         "confighistory_ccinfo_lookup_time",
+		Help:         "Time taken in seconds for retrieving the updated chaincodes and their collection configs while recording the config history.",
+		LabelNames:   []string{"channel"},
+		StatsdFormat: "%{#fqname}.%{channel}",
+		Buckets:      []float64{0.001, 0.005, 0.01, 0.015, 0.05, 0.1, 1},
+	}
+
+	marshalTimeOpts = metrics.HistogramOpts{
+		Namespace:    "ledger",
+		Subsystem:    "",
+		Name:         "confighistory_marshal_time",
+		Help:         "Time taken in seconds for marshaling the collection configs while recording the config history.",
+		LabelNames:   []string{"channel"},
+		StatsdFormat: "%{#fqname}.%{channel}",
+		Buckets:      []float64{0.001, 0.005, 0.01, 0.015, 0.05, 0.1, 1},
+	}
+
+	writeTimeOpts = metrics.HistogramOpts{
+		Namespace:    "ledger",
+		Subsystem:    "",
+		Name:         "confighistory_write_time",
+		Help:         "Time taken in seconds for writing the collection configs to the config history db.",
+		LabelNames:   []string{"channel"},
+		StatsdFormat: "%{#fqname}.%{channel}",
+		Buckets:      []float64{0.001, 0.005, 0.01, 0.015, 0.05, 0.1, 1},
+	}
+
+	asyncQueueDepthOpts = metrics.GaugeOpts{
+		Namespace:    "ledger",
+		Subsystem:    "",
+		Name:         "confighistory_async_queue_depth",
+		Help:         "Number of blocks whose config history is pending to be written asynchronously, including the one being written.",
+		LabelNames:   []string{"channel"},
+		StatsdFormat: "%{#fqname}.%{channel}",
+	}
+
+	asyncWritesBlockedOpts = metrics.CounterOpts{
+		Namespace:    "ledger",
+		Subsystem:    "",
+		Name:         "confighistory_async_writes_blocked",
+		Help:         "Number of blocks whose processing was blocked because the queue of the asynchronous config history writes was full.",
+		LabelNames:   []string{"channel"},
+		StatsdFormat: "%{#fqname}.%{channel}",
+	}
+
+	asyncWritesDiscardedOpts = metrics.CounterOpts{
+		Namespace:    "ledger",
+		Subsystem:    "",
+		Name:         "confighistory_async_writes_discarded",
+		Help:         "Number of blocks whose config history was discarded due to an earlier failure of an asynchronous config history write.",
+		LabelNames:   []string{"channel"},
+		StatsdFormat: "%{#fqname}.%{channel}",
+	}
+
+	ccInfoCircuitOpenOpts = metrics.GaugeOpts{
+		Namespace:    "ledger",
+		Subsystem:    "",
+		Name:         "confighistory_ccinfo_circuit_open",
+		Help:         "Whether the calls to the chaincode info provider are suspended after repeated failures (1) or not (0).",
+		StatsdFormat: "%{#fqname}",
+	}
+
+	ccInfoCircuitTripsOpts = metrics.CounterOpts{
+		Namespace:    "ledger",
+		Subsystem:    "",
+		Name:         "confighistory_ccinfo_circuit_trips",
+		Help:         "Number of times the calls to the chaincode info provider were suspended after repeated failures.",
+		StatsdFormat: "%{#fqname}",
+	}
+
+	collConfigsWrittenOpts = metrics.CounterOpts{
+		Namespace:    "ledger",
+		Subsystem:    "",
+		Name:         "confighistory_collection_configs_written",
+		Help:         "Number of collection configs written to the config history db.",
+		LabelNames:   []string{"channel"},
+		StatsdFormat: "%{#fqname}.%{channel}",
+	}
+
+	queriesOpts = metrics.CounterOpts{
+		Namespace:    "ledger",
+		Subsystem:    "",
+		Name:         "confighistory_queries",
+		Help:         "Number of queries for the collection config of a chaincode at a block, by whether a collection config was found (hit) or not (miss).",
+		LabelNames:   []string{"channel", "result"},
+		StatsdFormat: "%{#fqname}.%{channel}.%{result}",
+	}
+
+	sizeOpts = metrics.GaugeOpts{
+		Namespace:    "ledger",
+		Subsystem:    "",
+		Name:         "confighistory_size",
+		Help:         "Approximate size in bytes of the config history db.",
+		LabelNames:   []string{"channel"},
+		StatsdFormat: "%{#fqname}.%{channel}",
+	}
+)
+
+// reportSizePeriodically updates the size gauge for all the known ledgers every `interval`, until the manager is closed
+func (m *mgr) reportSizePeriodically(interval time.Duration) {
+	defer m.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			m.updateSizeMetrics()
+		case <-m.stopCh:
+			return
+		}
+	}
+}
+
+func (m *mgr) updateSizeMetrics() {
+	for _, ledgerID := range m.dbProvider.ledgerIDs() {
+		size, err := m.ApproximateSize(ledgerID)
+		if err != nil {
+			logger.Warningf("Error while computing the size of config history for ledger [%s]: %s", ledgerID, err)
+			continue
+		}
+		m.sizeGauge.With("channel", ledgerID).Set(float64(size))
+	}
+}
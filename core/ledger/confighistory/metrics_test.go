@@ -0,0 +1,284 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/common/metrics"
+	"github.com/hyperledger/fabric/common/metrics/metricsfakes"
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/mock"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApproximateSize(t *testing.T) {
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(NewMemStoreProvider()))
+	defer m.Close()
+
+	size, err := m.ApproximateSize("ledger1")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), size)
+
+	testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, "chaincode1",
+		sampleCollectionConfigPackage("coll", 10))
+	assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: 10}))
+	size, err = m.ApproximateSize("ledger1")
+	assert.NoError(t, err)
+	assert.True(t, size > 0)
+
+	dbPath := "/tmp/fabric/core/ledger/confighistory"
+	deleteTestPath(t, dbPath)
+	defer deleteTestPath(t, dbPath)
+	leveldbBasedMgr := newMgr(mockCCInfoProvider, dbPath)
+	defer leveldbBasedMgr.Close()
+	_, err = leveldbBasedMgr.ApproximateSize("ledger1")
+	assert.NoError(t, err)
+
+	storeProvider := &recordingStoreProvider{StoreProvider: NewMemStoreProvider()}
+	m1 := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(storeProvider))
+	defer m1.Close()
+	_, err = m1.ApproximateSize("ledger1")
+	assert.EqualError(t, err, "the store of the config history for ledger [ledger1] does not support size estimation")
+}
+
+func TestPhaseTimeMetrics(t *testing.T) {
+	histograms := map[string]*metricsfakes.Histogram{}
+	fakeProvider := &metricsfakes.Provider{}
+	fakeProvider.NewHistogramStub = func(opts metrics.HistogramOpts) metrics.Histogram {
+		h := &metricsfakes.Histogram{}
+		h.WithReturns(h)
+		histograms[opts.Name] = h
+		return h
+	}
+	fakeCounter := &metricsfakes.Counter{}
+	fakeCounter.WithReturns(fakeCounter)
+	fakeProvider.NewCounterReturns(fakeCounter)
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	clock := &tickingClock{now: time.Unix(1000, 0), step: time.Second}
+	m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(NewMemStoreProvider()),
+		WithMetricsProvider(fakeProvider), withClock(clock))
+	defer m.Close()
+
+	testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, "chaincode1",
+		sampleCollectionConfigPackage("coll", 10))
+	assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: 10}))
+
+	assert.Len(t, histograms, 3)
+	for _, name := range []string{"confighistory_ccinfo_lookup_time", "confighistory_marshal_time", "confighistory_write_time"} {
+		h := histograms[name]
+		if !assert.NotNil(t, h, name) {
+			continue
+		}
+		assert.Equal(t, []string{"channel", "ledger1"}, h.WithArgsForCall(0))
+		assert.Equal(t, 1, h.ObserveCallCount())
+		assert.Equal(t, float64(1), h.ObserveArgsForCall(0))
+	}
+
+	// a block without any collection config records only the lookup time
+	testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, "chaincode1", nil)
+	assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: 11}))
+	assert.Equal(t, 2, histograms["confighistory_ccinfo_lookup_time"].ObserveCallCount())
+	assert.Equal(t, 1, histograms["confighistory_marshal_time"].ObserveCallCount())
+	assert.Equal(t, 1, histograms["confighistory_write_time"].ObserveCallCount())
+}
+
+func TestWriteAndQueryMetrics(t *testing.T) {
+	counters := map[string]*metricsfakes.Counter{}
+	fakeProvider := &metricsfakes.Provider{}
+	fakeHistogram := &metricsfakes.Histogram{}
+	fakeHistogram.WithReturns(fakeHistogram)
+	fakeProvider.NewHistogramReturns(fakeHistogram)
+	fakeProvider.NewGaugeReturns(&metricsfakes.Gauge{})
+	fakeProvider.NewCounterStub = func(opts metrics.CounterOpts) metrics.Counter {
+		c := &metricsfakes.Counter{}
+		c.WithReturns(c)
+		counters[opts.Name] = c
+		return c
+	}
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(NewMemStoreProvider()),
+		WithMetricsProvider(fakeProvider), WithChaincodeNotDeployedErrors())
+	defer m.Close()
+
+	mockCCInfoProvider.UpdatedChaincodesReturns([]*ledger.ChaincodeLifecycleInfo{{Name: "chaincode1"}, {Name: "chaincode2"}}, nil)
+	mockCCInfoProvider.ChaincodeInfoStub = func(ccName string, qe ledger.SimpleQueryExecutor) (*ledger.DeployedChaincodeInfo, error) {
+		if ccName == "unknown" {
+			return nil, nil
+		}
+		return &ledger.DeployedChaincodeInfo{Name: ccName, CollectionConfigPkg: sampleCollectionConfigPackage(ccName, 10)}, nil
+	}
+	assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: 10}))
+	written := counters["confighistory_collection_configs_written"]
+	assert.Equal(t, 1, written.AddCallCount())
+	assert.Equal(t, []string{"channel", "ledger1"}, written.WithArgsForCall(0))
+	assert.Equal(t, float64(2), written.AddArgsForCall(0))
+
+	retriever := m.GetRetriever("ledger1", &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 20}})
+	queries := counters["confighistory_queries"]
+	_, err := retriever.MostRecentCollectionConfigBelow(20, "chaincode1")
+	assert.NoError(t, err)
+	_, err = retriever.CollectionConfigAt(10, "chaincode2")
+	assert.NoError(t, err)
+	_, err = retriever.CollectionConfigAt(15, "chaincode2")
+	assert.NoError(t, err)
+	_, err = retriever.MostRecentCollectionConfigBelow(20, "unknown")
+	assert.IsType(t, &ledger.ErrChaincodeNotDeployed{}, err)
+	// a failed query is not counted
+	_, err = retriever.CollectionConfigAt(50, "chaincode1")
+	assert.Error(t, err)
+
+	var results []string
+	for i := 0; i < queries.WithCallCount(); i++ {
+		labels := queries.WithArgsForCall(i)
+		assert.Equal(t, []string{"channel", "ledger1", "result"}, labels[:3])
+		results = append(results, labels[3])
+	}
+	assert.Equal(t, []string{"hit", "hit", "miss", "miss"}, results)
+}
+
+// tickingClock advances by `step` on every call to the function `Now`
+type tickingClock struct {
+	now  time.Time
+	step time.Duration
+}
+
+func (c *tickingClock) Now() time.Time {
+	c.now = c.now.Add(c.step)
+	return c.now
+}
+
+func TestSizeMetrics(t *testing.T) {
+	fakeGauge := &metricsfakes.Gauge{}
+	fakeGauge.WithReturns(fakeGauge)
+	fakeProvider := &metricsfakes.Provider{}
+	fakeProvider.NewGaugeReturns(fakeGauge)
+
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, "chaincode1",
+		sampleCollectionConfigPackage("coll", 10))
+
+	t.Run("periodic-update", func(t *testing.T) {
+		m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(NewMemStoreProvider()),
+			WithSizeMetrics(fakeProvider, time.Hour))
+		defer m.Close()
+		assert.Equal(t, sizeOpts, fakeProvider.NewGaugeArgsForCall(0))
+
+		assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: 10}))
+		m.updateSizeMetrics()
+		assert.Equal(t, 1, fakeGauge.SetCallCount())
+		assert.Equal(t, []string{"channel", "ledger1"}, fakeGauge.WithArgsForCall(0))
+		expectedSize, err := m.ApproximateSize("ledger1")
+		assert.NoError(t, err)
+		assert.Equal(t, float64(expectedSize), fakeGauge.SetArgsForCall(0))
+	})
+
+	t.Run("background-reporting", func(t *testing.T) {
+		m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(NewMemStoreProvider()),
+			WithSizeMetrics(fakeProvider, time.Millisecond))
+		assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: 10}))
+		numSetCalls := fakeGauge.SetCallCount()
+		for i := 0; i < 1000 && fakeGauge.SetCallCount() == numSetCalls; i++ {
+			time.Sleep(time.Millisecond)
+		}
+		assert.True(t, fakeGauge.SetCallCount() > numSetCalls)
+		m.Close()
+		// no more updates after close
+		numSetCalls = fakeGauge.SetCallCount()
+		time.Sleep(10 * time.Millisecond)
+		assert.Equal(t, numSetCalls, fakeGauge.SetCallCount())
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		fakeProvider := &metricsfakes.Provider{}
+		m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(NewMemStoreProvider()),
+			WithSizeMetrics(fakeProvider, 0))
+		defer m.Close()
+		assert.Equal(t, 0, fakeProvider.NewGaugeCallCount())
+		assert.Nil(t, m.stopCh)
+	})
+}
+
+func TestAsyncWriteMetrics(t *testing.T) {
+	newFakeProvider := func() (*metricsfakes.Provider, *metricsfakes.Gauge, map[string]*metricsfakes.Counter) {
+		fakeGauge := &metricsfakes.Gauge{}
+		fakeGauge.WithReturns(fakeGauge)
+		counters := map[string]*metricsfakes.Counter{}
+		fakeHistogram := &metricsfakes.Histogram{}
+		fakeHistogram.WithReturns(fakeHistogram)
+		fakeProvider := &metricsfakes.Provider{}
+		fakeProvider.NewHistogramReturns(fakeHistogram)
+		fakeProvider.NewGaugeReturns(fakeGauge)
+		fakeProvider.NewCounterStub = func(opts metrics.CounterOpts) metrics.Counter {
+			c := &metricsfakes.Counter{}
+			c.WithReturns(c)
+			counters[opts.Name] = c
+			return c
+		}
+		return fakeProvider, fakeGauge, counters
+	}
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, "chaincode1",
+		sampleCollectionConfigPackage("coll", 10))
+
+	t.Run("queue-depth-and-blocked-writes", func(t *testing.T) {
+		fakeProvider, fakeGauge, counters := newFakeProvider()
+		storeProvider := &blockingStoreProvider{StoreProvider: NewMemStoreProvider(), release: make(chan struct{})}
+		m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(storeProvider),
+			WithMetricsProvider(fakeProvider), WithAsyncWrites(1))
+		defer m.Close()
+		assert.Equal(t, asyncQueueDepthOpts, fakeProvider.NewGaugeArgsForCall(0))
+
+		// the queue holds one block and the writer holds another; the third block waits for a room in the queue
+		committed := make(chan struct{})
+		go func() {
+			defer close(committed)
+			for _, blockNum := range []uint64{10, 20, 30} {
+				assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: blockNum}))
+			}
+		}()
+		blocked := counters["confighistory_async_writes_blocked"]
+		for i := 0; i < 1000 && blocked.AddCallCount() == 0; i++ {
+			time.Sleep(time.Millisecond)
+		}
+		assert.NotEqual(t, 0, blocked.AddCallCount())
+		assert.Equal(t, []string{"channel", "ledger1"}, blocked.WithArgsForCall(0))
+		close(storeProvider.release)
+		<-committed
+		assert.NoError(t, m.WaitForPendingWrites())
+
+		var depths []float64
+		for i := 0; i < fakeGauge.SetCallCount(); i++ {
+			assert.Equal(t, []string{"channel", "ledger1"}, fakeGauge.WithArgsForCall(i))
+			depths = append(depths, fakeGauge.SetArgsForCall(i))
+		}
+		assert.Contains(t, depths, float64(3))
+		assert.Equal(t, float64(0), depths[len(depths)-1])
+		assert.Equal(t, 0, counters["confighistory_async_writes_discarded"].AddCallCount())
+	})
+
+	t.Run("discarded-writes", func(t *testing.T) {
+		fakeProvider, _, counters := newFakeProvider()
+		storeProvider := &blockingStoreProvider{StoreProvider: &failingStoreProvider{NewMemStoreProvider()}, release: make(chan struct{})}
+		m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(storeProvider),
+			WithMetricsProvider(fakeProvider), WithAsyncWrites(10))
+		defer m.Close()
+
+		for _, blockNum := range []uint64{10, 20, 30} {
+			assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: blockNum}))
+		}
+		close(storeProvider.release)
+		assert.EqualError(t, m.WaitForPendingWrites(), "write-failure")
+		discarded := counters["confighistory_async_writes_discarded"]
+		assert.Equal(t, 2, discarded.AddCallCount())
+		assert.Equal(t, []string{"channel", "ledger1"}, discarded.WithArgsForCall(0))
+		assert.Equal(t, 0, counters["confighistory_async_writes_blocked"].AddCallCount())
+	})
+}
@@ -7,47 +7,459 @@ SPDX-License-Identifier: Apache-2.0
 package confighistory
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/golang/protobuf/proto"
 	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/common/metrics"
+	"github.com/hyperledger/fabric/common/metrics/disabled"
 	"github.com/hyperledger/fabric/core/ledger"
 	"github.com/hyperledger/fabric/core/ledger/ledgerconfig"
 	"github.com/hyperledger/fabric/protos/common"
 	"github.com/hyperledger/fabric/protos/ledger/rwset/kvrwset"
 	"github.com/pkg/errors"
+	"go.uber.org/zap/zapcore"
 )
 
-var logger = flogging.MustGetLogger("confighistory")
+var logger = flogging.MustGetLogger(loggerName)
 
 const (
 	collectionConfigNamespace = "lscc" // lscc namespace was introduced in version 1.2 and we continue to use this in order to be compatible with existing data
+	collectionConfigKeySuffix = "~collection"
+	// authorNamespace holds the submitters of the transactions that committed the collection configs of the default namespace
+	authorNamespace = "author"
+	// annotationNamespace holds the notes attached by the operators to the collection configs of the default namespace
+	annotationNamespace = "annotation"
+	// endorsementPolicyNamespace holds the endorsement policies of the chaincodes of the default namespace
+	endorsementPolicyNamespace = "endorsementpolicy"
 )
 
 // Mgr should be registered as a state listener. The state listener builds the history and retriver helps in querying the history
 type Mgr interface {
 	ledger.StateListener
-	GetRetriever(ledgerID string, ledgerInfoRetriever LedgerInfoRetriever) ledger.ConfigHistoryRetriever
+	GetRetriever(ledgerID string, ledgerInfoRetriever LedgerInfoRetriever) Retriever
+	// GetRetrieverForNamespace returns a retriever for the chaincodes deployed via the given lifecycle namespace
+	GetRetrieverForNamespace(ledgerID, namespace string, ledgerInfoRetriever LedgerInfoRetriever) Retriever
+	// PruneAllBelow prunes the config history of each of the given ledgers below the given block
+	PruneAllBelow(boundaries map[string]uint64) (map[string]error, error)
+	// EstimatePruneSavings reports what pruning the given ledger below the given block would remove
+	EstimatePruneSavings(ledgerID string, blockNum uint64) (entriesRemovable int, approxBytes uint64, err error)
+	// Purge prunes the config history of the given ledger below the given block
+	Purge(ledgerID string, belowBlockNum uint64) (int, error)
+	// DeleteChaincodeHistory deletes the entire config history of the given chaincode
+	DeleteChaincodeHistory(ledgerID, chaincodeName string) (int, error)
+	// AnnotateVersion attaches a note to the collection config committed at the given block
+	AnnotateVersion(ledgerID, chaincodeName string, blockNum uint64, note string) error
+	// ForEachConfigEntry invokes the given function for each of the persisted collection configs
+	ForEachConfigEntry(f func(ledgerID, chaincodeName string, info *ledger.CollectionConfigInfo) error) error
+	// LedgersWithHistory returns the ids of the ledgers that have any config history
+	LedgersWithHistory() ([]string, error)
+	// Preload loads the most recent collection configs of the given chaincodes into the cache
+	Preload(ledgerID string, chaincodeNames []string) error
+	// ExportConfigHistory writes the config history of the given ledger to the writer
+	ExportConfigHistory(ledgerID string, w io.Writer) error
+	// ImportConfigHistory loads the config history of the given ledger from the reader
+	ImportConfigHistory(ledgerID string, r io.Reader) error
+	// ExportChaincode writes the config history of the given chaincode to the writer
+	ExportChaincode(ledgerID, chaincodeName string, w io.Writer) error
+	// ImportChaincode loads the config history of the given chaincode from the reader
+	ImportChaincode(ledgerID, chaincodeName string, r io.Reader) error
+	// ExportSnapshot writes the config history of the given ledger to a snapshot directory
+	ExportSnapshot(ledgerID, snapshotDir string) error
+	// ImportFromSnapshot rebuilds the config history of the given ledger from a snapshot directory
+	ImportFromSnapshot(ledgerID, snapshotDir string) error
+	// ExportArchive writes the collection configs of the given ledger to the writer as a zip archive
+	ExportArchive(ledgerID string, w io.Writer) error
+	// StreamConfigHistory sends the collection configs of the given ledger to the stream
+	StreamConfigHistory(ledgerID string, stream ConfigHistoryStream) error
+	// RebuildFromBlocks reconstructs the config history of the given ledger from the committed blocks
+	RebuildFromBlocks(ledgerID string, blockIter BlockIterator) error
+	// CompareLedgers compares the config histories of the two given ledgers entry by entry
+	CompareLedgers(ledgerA, ledgerB string) (*LedgerComparison, error)
+	// Reconcile reports the chaincodes whose most recent collection config differs from the current state
+	Reconcile(ledgerID string, ledgerInfoRetriever LedgerInfoRetriever) (*ReconcileReport, error)
+	// WatchChaincode subscribes to the changes in the collection config of the given chaincode
+	WatchChaincode(ledgerID, chaincodeName string) (<-chan *ledger.CollectionConfigInfo, func())
+	// RegisterListener registers a function that is invoked with each persisted collection config
+	RegisterListener(listener CollectionConfigListener)
+	// WaitForPendingWrites blocks until the pending asynchronous writes are applied
+	WaitForPendingWrites() error
+	// SelfTest checks that the config history of the given ledger can be read
+	SelfTest(ledgerID string) error
+	// ApproximateSize returns the approximate size, in bytes, of the config history of the given ledger
+	ApproximateSize(ledgerID string) (uint64, error)
 	Close()
 }
 
+// NamespacedChaincodeInfoProvider is an optional interface for a provider that maintains chaincodes in several namespaces
+type NamespacedChaincodeInfoProvider interface {
+	// ChaincodeInfoInNamespace is same as `ChaincodeInfo` but looks up the chaincode in the given namespace
+	ChaincodeInfoInNamespace(namespace, chaincodeName string, qe ledger.SimpleQueryExecutor) (*ledger.DeployedChaincodeInfo, error)
+}
+
+// ChannelOrgsProvider is an optional interface for a provider that reports the orgs of a channel
+type ChannelOrgsProvider interface {
+	// ChannelOrgs returns the MSP IDs of the orgs of the given channel
+	ChannelOrgs(channelName string, qe ledger.SimpleQueryExecutor) ([]string, error)
+}
+
+//go:generate counterfeiter -o mock/implicit_collections_provider.go -fake-name ImplicitCollectionsProvider . ImplicitCollectionsProvider
+
+// ImplicitCollectionsProvider is an optional interface for a provider whose chaincodes have implicit collections
+type ImplicitCollectionsProvider interface {
+	// ImplicitCollections returns a slice that contains one proto msg for each of the implicit collections
+	ImplicitCollections(channelName, chaincodeName string, qe ledger.SimpleQueryExecutor) ([]*common.StaticCollectionConfig, error)
+}
+
+// Retriever extends the interface `ledger.ConfigHistoryRetriever` with the functions specific to this package
+type Retriever interface {
+	ledger.ConfigHistoryRetriever
+	// RawEntryAt returns the composite key and the value, as stored in the db, of a collection config
+	RawEntryAt(blockNum uint64, chaincodeName string) (key []byte, value []byte, err error)
+	// CollectionConfigAtContext is same as `CollectionConfigAt` but traces the call under the given context
+	CollectionConfigAtContext(ctx context.Context, blockNum uint64, chaincodeName string) (*ledger.CollectionConfigInfo, error)
+	// MostRecentCollectionConfigBelowContext is same as `MostRecentCollectionConfigBelow` but traces the call under the given context
+	MostRecentCollectionConfigBelowContext(ctx context.Context, blockNum uint64, chaincodeName string) (*ledger.CollectionConfigInfo, error)
+	// CollectionConfigBefore returns the collection config in effect just before the given block is committed
+	CollectionConfigBefore(blockNum uint64, chaincodeName string) (*ledger.CollectionConfigInfo, error)
+	// CollectionConfigAtForOrg is same as `CollectionConfigAt` but filters the implicit collections by org
+	CollectionConfigAtForOrg(blockNum uint64, chaincodeName, mspID string) (*ledger.CollectionConfigInfo, error)
+	// MostRecentCollectionConfigBelowForOrg is same as `MostRecentCollectionConfigBelow` but filters the implicit collections by org
+	MostRecentCollectionConfigBelowForOrg(blockNum uint64, chaincodeName, mspID string) (*ledger.CollectionConfigInfo, error)
+	// CollectionConfigAtForCollections is same as `CollectionConfigAt` but includes only the given collections
+	CollectionConfigAtForCollections(blockNum uint64, chaincodeName string, collectionNames []string) (*ledger.CollectionConfigInfo, error)
+	// MostRecentCollectionConfigBelowForCollections is same as `MostRecentCollectionConfigBelow` but includes only the given collections
+	MostRecentCollectionConfigBelowForCollections(blockNum uint64, chaincodeName string, collectionNames []string) (*ledger.CollectionConfigInfo, error)
+	// ExplicitCollectionConfigAt is same as `CollectionConfigAt` but excludes the implicit collections
+	ExplicitCollectionConfigAt(blockNum uint64, chaincodeName string) (*ledger.CollectionConfigInfo, error)
+	// ChaincodesConfiguredAt returns the sorted names of the chaincodes whose collection config is committed at the given block
+	ChaincodesConfiguredAt(blockNum uint64) ([]string, error)
+	// AllCollectionConfigsAt returns the collection configs committed at the given block, keyed by the chaincode name
+	AllCollectionConfigsAt(blockNum uint64) (map[string]*ledger.CollectionConfigInfo, error)
+	// FindChaincodesByPrefix returns the sorted names of the chaincodes with a collection config that start with the given prefix
+	FindChaincodesByPrefix(prefix string) ([]string, error)
+	// CollectionConfigsInRange returns a page of the collection configs committed in the given range of blocks
+	CollectionConfigsInRange(chaincodeName string, startBlockNum, endBlockNum uint64, limit int, cursor string, direction Direction) (*CollectionConfigPage, error)
+	// AllCollectionConfigs returns a page of all the versions of the collection config of the chaincode
+	AllCollectionConfigs(chaincodeName string, limit int, cursor string, direction Direction) (*CollectionConfigPage, error)
+	// CollectionConfigWithPrevious returns the collection config in effect at the given block and the version before it
+	CollectionConfigWithPrevious(blockNum uint64, chaincodeName string) (current, previous *ledger.CollectionConfigInfo, err error)
+	// DetectCollectionRemovals reports the collections removed between the versions committed in the given range of blocks
+	DetectCollectionRemovals(chaincodeName string, fromBlock, toBlock uint64) ([]CollectionRemovalEvent, error)
+	// CollectionIntroducedAt returns the block at which the given collection first appears
+	CollectionIntroducedAt(chaincodeName, collectionName string) (uint64, bool, error)
+	// CollectionConfigAuthor returns the submitter of the transaction that committed the collection config at the given block
+	CollectionConfigAuthor(blockNum uint64, chaincodeName string) (*ledger.TxSubmitter, error)
+	// CollectionsWithBlockToLiveAt returns the block-to-live of the collections that are purged automatically
+	CollectionsWithBlockToLiveAt(blockNum uint64, chaincodeName string) (map[string]uint64, error)
+	// CollectionMembersAt returns the MSP IDs of the member orgs of the given collection
+	CollectionMembersAt(blockNum uint64, chaincodeName, collectionName string) ([]string, error)
+	// ConfigReportAt returns a report of the explicit and the implicit collections in effect at the given block
+	ConfigReportAt(blockNum uint64, chaincodeName string) (*ConfigReport, error)
+	// ConfigBlockNumbers returns, in the increasing order, the blocks at which a collection config of the chaincode is committed
+	ConfigBlockNumbers(chaincodeName string) ([]uint64, error)
+	// ConfigChangedBetween returns true if a collection config of the chaincode is committed in the range (fromBlock, toBlock]
+	ConfigChangedBetween(chaincodeName string, fromBlock, toBlock uint64) (bool, error)
+	// LatestConfigBlock returns the highest block at which a collection config of the chaincode is committed
+	LatestConfigBlock(chaincodeName string) (uint64, bool, error)
+	// OldestConfigBlock returns the lowest block at which a collection config of the chaincode is retained
+	OldestConfigBlock(chaincodeName string) (uint64, bool, error)
+	// CollectionConfigAtTime returns the collection config that was in effect at the given time
+	CollectionConfigAtTime(t time.Time, chaincodeName string) (*ledger.CollectionConfigInfo, error)
+	// Snapshot returns a retriever whose queries observe the config history as of the time of the call
+	Snapshot() (SnapshotRetriever, error)
+	// AtHeight returns a retriever that memoizes the results of the queries for the given block
+	AtHeight(blockNum uint64) (HeightScopedRetriever, error)
+	// DiffFromLatest compares the collection config in effect at the given block with the latest one
+	DiffFromLatest(blockNum uint64, chaincodeName string) (*CollectionConfigDiffResult, error)
+	// ConfigChangesSince sends the collection configs committed above the given block and, optionally, follows the commits
+	ConfigChangesSince(sinceBlock uint64, follow bool) (<-chan *ledger.ConfigChangeRecord, func(), error)
+	// WatchChaincode subscribes to the changes in the collection config of the given chaincode
+	WatchChaincode(chaincodeName string) (<-chan *ledger.CollectionConfigInfo, func(), error)
+	// ValidateImplicitCollections checks that the implicit collections of the chaincode match the orgs of the channel
+	ValidateImplicitCollections(chaincodeName string) (*ImplicitValidationReport, error)
+	// CanonicalConfigAt returns the collection config committed at the given block in a canonical byte form
+	CanonicalConfigAt(blockNum uint64, chaincodeName string) ([]byte, error)
+	// CheckConsistencyWithLedger returns an error if the config history is ahead of the ledger
+	CheckConsistencyWithLedger() error
+}
+
 type mgr struct {
 	ccInfoProvider ledger.DeployedChaincodeInfoProvider
 	dbProvider     *dbProvider
+	clock          timeSource
+	// skipCCInfoErrors, if set, causes the chaincodes for which the chaincode info cannot be retrieved to be
+	// skipped (with a warning) instead of failing the processing of the entire block
+	skipCCInfoErrors bool
+	// strictStateUpdates, if set, causes a trigger that does not update any chaincode to fail
+	strictStateUpdates bool
+	// lenientImplicitColls, if set, causes the retriever to return the explicit collection config, flagged as incomplete,
+	// if the implicit collections cannot be retrieved
+	lenientImplicitColls bool
+	// checkMonotonicity, if set, causes a collection config that is not above the most recent collection config of the
+	// chaincode to fail the processing of the block
+	checkMonotonicity bool
+	// replayOverwrites, if set, causes a collection config recorded again for a block to overwrite a different recorded one
+	replayOverwrites bool
+	// retentionBlocks, if non-zero, is the number of the most recent blocks for which the config history is retained
+	retentionBlocks  uint64
+	cache            *configCache
+	blockIndex       *blockIndex
+	stats            *stats
+	syncWrites       bool
+	tracer           Tracer
+	watchers         *watchers
+	asyncQueueSize   int
+	asyncWriter      *asyncWriter
+	sizeGauge        metrics.Gauge
+	sizeInterval     time.Duration
+	compactTimeout   time.Duration
+	maxConfigSize    int
+	writeRecords     bool
+	checkDeployed    bool
+	maxImplicitColls int
+	// trackedNamespaces, if not nil, restricts the config history to the chaincodes deployed via these namespaces
+	trackedNamespaces map[string]bool
+	logSampler        *logSampler
+	// breakerThreshold and breakerCooldown configure the ccInfoBreaker (see function `WithCCInfoCircuitBreaker`)
+	breakerThreshold int
+	breakerCooldown  time.Duration
+	ccInfoBreaker    *ccInfoBreaker
+	stopCh           chan struct{}
+	wg               sync.WaitGroup
+}
+
+// timeSource is the source of time for the features of `Mgr` that depend on time
+type timeSource interface {
+	Now() time.Time
+}
+
+type wallClock struct{}
+
+func (wallClock) Now() time.Time {
+	return time.Now()
+}
+
+// Option configures an optional behavior of `Mgr`
+type Option func(m *mgr)
+
+// withClock is a test hook that replaces the wall-clock
+func withClock(clock timeSource) Option {
+	return func(m *mgr) {
+		m.clock = clock
+	}
+}
+
+// withStrictStateUpdates is a test hook that fails a trigger that does not update any chaincode
+func withStrictStateUpdates() Option {
+	return func(m *mgr) {
+		m.strictStateUpdates = true
+	}
+}
+
+// WithLenientChaincodeInfoLookup skips, with a warning, the chaincodes whose chaincode info cannot be retrieved
+func WithLenientChaincodeInfoLookup() Option {
+	return func(m *mgr) {
+		m.skipCCInfoErrors = true
+	}
+}
+
+// WithLenientImplicitCollections returns the explicit collection config, flagged as incomplete, if the implicit collections cannot be retrieved
+func WithLenientImplicitCollections() Option {
+	return func(m *mgr) {
+		m.lenientImplicitColls = true
+	}
+}
+
+// WithMonotonicityCheck fails a block that writes a collection config not above the most recent one of the chaincode
+func WithMonotonicityCheck(enabled bool) Option {
+	return func(m *mgr) {
+		m.checkMonotonicity = enabled
+	}
+}
+
+// WithReplayOverwrites overwrites a collection config recorded for a replayed block if the new one differs
+func WithReplayOverwrites() Option {
+	return func(m *mgr) {
+		m.replayOverwrites = true
+	}
+}
+
+// WithRetention purges the config history below the given number of the most recent blocks after each write
+func WithRetention(retentionBlocks uint64) Option {
+	return func(m *mgr) {
+		m.retentionBlocks = retentionBlocks
+	}
+}
+
+// WithCacheSize caches the most recent collection config for up to the given number of chaincodes
+func WithCacheSize(size int) Option {
+	return func(m *mgr) {
+		m.cache = newConfigCache(size)
+	}
+}
+
+// WithBlockIndex serves `LatestConfigBlock` and `OldestConfigBlock` from an in-memory index
+func WithBlockIndex() Option {
+	return func(m *mgr) {
+		m.blockIndex = newBlockIndex(true)
+	}
+}
+
+// WithAsyncWrites writes the config history off the commit path via a queue of the given size
+func WithAsyncWrites(queueSize int) Option {
+	return func(m *mgr) {
+		m.asyncQueueSize = queueSize
+	}
+}
+
+// WithSyncWrites controls whether the writes are synced to the disk, which is the default
+func WithSyncWrites(sync bool) Option {
+	return func(m *mgr) {
+		m.syncWrites = sync
+	}
+}
+
+// WithMaxCollectionConfigSize fails a block that carries a collection config larger than the given size, in bytes
+func WithMaxCollectionConfigSize(size int) Option {
+	return func(m *mgr) {
+		m.maxConfigSize = size
+	}
+}
+
+// WithCollectionConfigRecords persists the collection configs as `CollectionConfigRecord`, which older peers cannot decode
+func WithCollectionConfigRecords(enabled bool) Option {
+	return func(m *mgr) {
+		m.writeRecords = enabled
+	}
+}
+
+// WithLogSampling logs only one in every `rate` occurrences of each of the high-frequency log messages
+func WithLogSampling(rate int) Option {
+	return func(m *mgr) {
+		m.logSampler = newLogSampler(rate)
+	}
+}
+
+// WithCCInfoCircuitBreaker suspends the calls to the chaincode info provider for `cooldown` after `threshold` consecutive failures
+func WithCCInfoCircuitBreaker(threshold int, cooldown time.Duration) Option {
+	return func(m *mgr) {
+		m.breakerThreshold = threshold
+		m.breakerCooldown = cooldown
+	}
+}
+
+// WithChaincodeNotDeployedErrors returns `ledger.ErrChaincodeNotDeployed` for a chaincode that was never deployed
+func WithChaincodeNotDeployedErrors() Option {
+	return func(m *mgr) {
+		m.checkDeployed = true
+	}
+}
+
+// WithMaxImplicitCollections caps the number of the implicit collections added to a collection config
+func WithMaxImplicitCollections(max int) Option {
+	return func(m *mgr) {
+		m.maxImplicitColls = max
+	}
+}
+
+// WithTrackedNamespaces restricts the config history to the chaincodes deployed via the given namespaces
+func WithTrackedNamespaces(namespaces []string) Option {
+	return func(m *mgr) {
+		if len(namespaces) == 0 {
+			m.trackedNamespaces = nil
+			return
+		}
+		m.trackedNamespaces = map[string]bool{}
+		for _, ns := range namespaces {
+			m.trackedNamespaces[ns] = true
+		}
+	}
+}
+
+// WithMetricsProvider sets the provider of the metrics of the config history
+func WithMetricsProvider(metricsProvider metrics.Provider) Option {
+	return func(m *mgr) {
+		m.stats = newStats(metricsProvider)
+	}
+}
+
+// WithSizeMetrics reports the approximate size of the config history of each ledger every `interval`
+func WithSizeMetrics(metricsProvider metrics.Provider, interval time.Duration) Option {
+	return func(m *mgr) {
+		if interval <= 0 {
+			return
+		}
+		m.sizeGauge = metricsProvider.NewGauge(sizeOpts)
+		m.sizeInterval = interval
+	}
 }
 
 // NewMgr constructs an instance that implements interface `Mgr`
-func NewMgr(ccInfoProvider ledger.DeployedChaincodeInfoProvider) Mgr {
-	return newMgr(ccInfoProvider, dbPath())
+func NewMgr(ccInfoProvider ledger.DeployedChaincodeInfoProvider, options ...Option) Mgr {
+	return newMgr(ccInfoProvider, dbPath(), options...)
+}
+
+// NewMgrWithStore constructs an instance that implements interface `Mgr` and persists
+// the config history in the stores supplied by the given `StoreProvider`
+func NewMgrWithStore(ccInfoProvider ledger.DeployedChaincodeInfoProvider, storeProvider StoreProvider, options ...Option) Mgr {
+	return newMgrWithDBProvider(ccInfoProvider, newDBProviderWithStore(storeProvider), options...)
 }
 
-func newMgr(ccInfoProvider ledger.DeployedChaincodeInfoProvider, dbPath string) Mgr {
-	return &mgr{ccInfoProvider, newDBProvider(dbPath)}
+func newMgr(ccInfoProvider ledger.DeployedChaincodeInfoProvider, dbPath string, options ...Option) *mgr {
+	return newMgrWithDBProvider(ccInfoProvider, newDBProvider(dbPath), options...)
+}
+
+func newMgrWithDBProvider(ccInfoProvider ledger.DeployedChaincodeInfoProvider, dbProvider *dbProvider, options ...Option) *mgr {
+	m := &mgr{
+		ccInfoProvider: ccInfoProvider,
+		dbProvider:     dbProvider,
+		clock:          wallClock{},
+		cache:          newConfigCache(0),
+		blockIndex:     newBlockIndex(false),
+		stats:          newStats(&disabled.Provider{}),
+		watchers:       newWatchers(),
+		syncWrites:     true,
+		tracer:         noopTracer{},
+		logSampler:     newLogSampler(1),
+	}
+	for _, optionFunc := range options {
+		optionFunc(m)
+	}
+	if m.breakerThreshold > 0 {
+		m.ccInfoBreaker = newCCInfoBreaker(m.breakerThreshold, m.breakerCooldown, m.clock, m.stats)
+	}
+	if m.asyncQueueSize > 0 {
+		m.asyncWriter = newAsyncWriter(m.asyncQueueSize, m.write, m.stats)
+	}
+	if m.sizeGauge != nil {
+		m.stopCh = make(chan struct{})
+		m.wg.Add(1)
+		go m.reportSizePeriodically(m.sizeInterval)
+	}
+	return m
 }
 
 // InterestedInNamespaces implements function from the interface ledger.StateListener
 func (m *mgr) InterestedInNamespaces() []string {
-	return m.ccInfoProvider.Namespaces()
+	if m.trackedNamespaces == nil {
+		return m.ccInfoProvider.Namespaces()
+	}
+	var namespaces []string
+	for _, ns := range m.ccInfoProvider.Namespaces() {
+		if m.trackedNamespaces[ns] {
+			namespaces = append(namespaces, ns)
+		}
+	}
+	return namespaces
 }
 
 // StateCommitDone implements function from the interface ledger.StateListener
@@ -59,120 +471,1002 @@ func (m *mgr) StateCommitDone(ledgerID string) {
 // In this implementation, the latest collection config package is retrieved via
 // ledger.DeployedChaincodeInfoProvider and is persisted as a separate entry in a separate db.
 // The composite key for the entry is a tuple of <blockNum, namespace, key>
-func (m *mgr) HandleStateUpdates(trigger *ledger.StateUpdateTrigger) error {
-	updatedCCs, err := m.ccInfoProvider.UpdatedChaincodes(convertToKVWrites(trigger.StateUpdates))
+func (m *mgr) HandleStateUpdates(trigger *ledger.StateUpdateTrigger) (err error) {
+	_, span := startSpan(context.Background(), m.tracer, "confighistory.HandleStateUpdates",
+		trigger.LedgerID, "", trigger.CommittingBlockNum)
+	defer endSpan(span, &err)
+	if m.asyncWriter != nil {
+		if err := m.asyncWriter.err(); err != nil {
+			return err
+		}
+	}
+	req, err := m.prepareWriteRequest(trigger)
+	if err != nil || req == nil {
+		return err
+	}
+	if m.asyncWriter != nil {
+		return m.asyncWriter.enqueue(req)
+	}
+	return m.write(req)
+}
+
+// prepareWriteRequest retrieves the collection configs of the chaincodes updated by the given trigger and prepares
+// the writes for persisting them. A nil request is returned if none of the updated chaincodes has a collection config
+func (m *mgr) prepareWriteRequest(trigger *ledger.StateUpdateTrigger) (*writeRequest, error) {
+	lookupStartTime := m.clock.Now()
+	kvWrites, err := convertToKVWrites(trigger.StateUpdates)
+	if err != nil {
+		return nil, err
+	}
+	if m.trackedNamespaces != nil {
+		for ns := range kvWrites {
+			if !m.trackedNamespaces[ns] {
+				delete(kvWrites, ns)
+			}
+		}
+		// in the absence of any writes, the updated chaincodes are attributed to the default namespace
+		if len(kvWrites) == 0 && (len(trigger.StateUpdates) != 0 || !m.trackedNamespaces[collectionConfigNamespace]) {
+			m.logSampler.debugf("untracked-namespaces", "Ignoring the state updates of block [%d] of ledger [%s] as none of the updated namespaces is tracked",
+				trigger.CommittingBlockNum, trigger.LedgerID)
+			return nil, nil
+		}
+	}
+	updatedCCsByNamespace, err := m.updatedChaincodes(kvWrites)
+	if err != nil {
+		return nil, err
+	}
+	if len(updatedCCsByNamespace) == 0 {
+		if m.strictStateUpdates {
+			return nil, errors.Errorf("none of the chaincodes is updated by the state updates of block [%d] of ledger [%s], writes = [%s]",
+				trigger.CommittingBlockNum, trigger.LedgerID, describeKVWrites(kvWrites))
+		}
+		m.logSampler.debugf("no-updated-chaincodes", "Ignoring the state updates of block [%d] of ledger [%s] as none of the chaincodes is updated, writes = [%s]",
+			trigger.CommittingBlockNum, trigger.LedgerID, describeKVWrites(kvWrites))
+		return nil, nil
+	}
+	updatedCCInfosByNamespace := map[string][]*ledger.DeployedChaincodeInfo{}
+	// the endorsement policies are recorded for the updated chaincodes of the default namespace, with or without a collection config
+	var policyCCInfos []*ledger.DeployedChaincodeInfo
+	for ns, updatedCCs := range updatedCCsByNamespace {
+		for _, cc := range updatedCCs {
+			ccInfo, err := m.chaincodeInfo(ns, cc.Name, trigger.PostCommitQueryExecutor)
+			if err != nil {
+				if !m.skipCCInfoErrors {
+					return nil, err
+				}
+				m.logSampler.warningf("chaincode-info-error", "Skipping the collection config of chaincode [%s] for block [%d] of ledger [%s] due to error in retrieving the chaincode info: %s",
+					cc.Name, trigger.CommittingBlockNum, trigger.LedgerID, err)
+				continue
+			}
+			if ccInfo.EndorsementPolicy != nil && ns == collectionConfigNamespace {
+				policyCCInfos = append(policyCCInfos, ccInfo)
+			}
+			if ccInfo.CollectionConfigPkg == nil {
+				continue
+			}
+			updatedCCInfosByNamespace[ns] = append(updatedCCInfosByNamespace[ns], ccInfo)
+		}
+	}
+	marshalStartTime := m.clock.Now()
+	m.stats.updateCCInfoLookupTime(trigger.LedgerID, marshalStartTime.Sub(lookupStartTime))
+	if len(updatedCCInfosByNamespace) == 0 && len(policyCCInfos) == 0 {
+		return nil, nil
+	}
+	if updatedCCInfosByNamespace, err = m.skipReplayedConfigs(trigger.LedgerID, updatedCCInfosByNamespace, trigger.CommittingBlockNum); err != nil {
+		return nil, err
+	}
+	if len(updatedCCInfosByNamespace) == 0 && len(policyCCInfos) == 0 {
+		return nil, nil
+	}
+	if m.checkMonotonicity {
+		if err := m.verifyMonotonicity(trigger.LedgerID, updatedCCInfosByNamespace, trigger.CommittingBlockNum); err != nil {
+			return nil, err
+		}
+	}
+	batch, err := prepareDBBatch(updatedCCInfosByNamespace, trigger.CommittingBlockNum, m.maxConfigSize, m.writeRecords)
 	if err != nil {
+		return nil, err
+	}
+	if logLevelEnabled(zapcore.DebugLevel) {
+		traceCollectionConfigs(trigger.LedgerID, updatedCCInfosByNamespace, trigger.CommittingBlockNum)
+	}
+	addAuthors(batch, updatedCCInfosByNamespace[collectionConfigNamespace], trigger.Submitters, trigger.CommittingBlockNum)
+	superseded, err := addLatestPointers(m.dbProvider.getDB(trigger.LedgerID), batch, updatedCCInfosByNamespace, trigger.CommittingBlockNum)
+	if err != nil {
+		return nil, err
+	}
+	addEndorsementPolicies(batch, policyCCInfos, trigger.CommittingBlockNum)
+	// the cache and the watchers cover only the default namespace and only the latest collection configs
+	updatedCollConfigs := map[string]*common.CollectionConfigPackage{}
+	for _, ccInfo := range updatedCCInfosByNamespace[collectionConfigNamespace] {
+		if !superseded[ccInfo] {
+			updatedCollConfigs[ccInfo.Name] = ccInfo.CollectionConfigPkg
+		}
+	}
+	numCollConfigs := 0
+	for _, ccInfos := range updatedCCInfosByNamespace {
+		numCollConfigs += len(ccInfos)
+	}
+	m.stats.updateMarshalTime(trigger.LedgerID, m.clock.Now().Sub(marshalStartTime))
+	return &writeRequest{
+		ledgerID:       trigger.LedgerID,
+		blockNum:       trigger.CommittingBlockNum,
+		batch:          batch,
+		collConfigs:    updatedCollConfigs,
+		numCollConfigs: numCollConfigs,
+	}, nil
+}
+
+// traceCollectionConfigs logs, at the debug level, the collection configs that are about to be persisted for the given block
+func traceCollectionConfigs(ledgerID string, ccInfosByNamespace map[string][]*ledger.DeployedChaincodeInfo, committingBlockNum uint64) {
+	for ns, ccInfos := range ccInfosByNamespace {
+		for _, ccInfo := range ccInfos {
+			logger.Debugf("Recording the collection config of chaincode [%s] of namespace [%s] with [%d] collections for block [%d] of ledger [%s]",
+				ccInfo.Name, ns, len(ccInfo.CollectionConfigPkg.Config), committingBlockNum, ledgerID)
+		}
+	}
+}
+
+// verifyMonotonicity returns an error if the db already contains a collection config of any of the given chaincodes at or
+// above the committing block (see function `WithMonotonicityCheck`)
+func (m *mgr) verifyMonotonicity(ledgerID string, ccInfosByNamespace map[string][]*ledger.DeployedChaincodeInfo, committingBlockNum uint64) error {
+	dbHandle := m.dbProvider.getDB(ledgerID)
+	for ns, ccInfos := range ccInfosByNamespace {
+		for _, ccInfo := range ccInfos {
+			mostRecent, err := dbHandle.mostRecentEntryBelowWithPointer(math.MaxUint64, ns, constructCollectionConfigKey(ccInfo.Name))
+			if err != nil {
+				return err
+			}
+			if mostRecent != nil && mostRecent.blockNum >= committingBlockNum {
+				return errors.Errorf("collection config of chaincode [%s] for block [%d] of ledger [%s] is not above the most recent collection config of the chaincode, committed at block [%d]",
+					ccInfo.Name, committingBlockNum, ledgerID, mostRecent.blockNum)
+			}
+		}
+	}
+	return nil
+}
+
+// updatedChaincodes returns the chaincodes updated by the given writes, keyed by the namespace whose writes updated them.
+// When the writes span multiple namespaces, the chaincode info provider is consulted separately for the writes of each
+// namespace, so that a chaincode is attributed to the namespace of the lifecycle that deployed it, even if another namespace
+// has a chaincode with the same name. The chaincodes updated in the absence of any writes are attributed to the default namespace
+func (m *mgr) updatedChaincodes(kvWrites map[string][]*kvrwset.KVWrite) (map[string][]*ledger.ChaincodeLifecycleInfo, error) {
+	updatedCCsByNamespace := map[string][]*ledger.ChaincodeLifecycleInfo{}
+	if len(kvWrites) <= 1 {
+		ns := collectionConfigNamespace
+		for writesNamespace := range kvWrites {
+			ns = writesNamespace
+		}
+		updatedCCs, err := m.ccInfoProvider.UpdatedChaincodes(kvWrites)
+		if err != nil || len(updatedCCs) == 0 {
+			return nil, err
+		}
+		updatedCCsByNamespace[ns] = updatedCCs
+		return updatedCCsByNamespace, nil
+	}
+	for ns, nsWrites := range kvWrites {
+		updatedCCs, err := m.ccInfoProvider.UpdatedChaincodes(map[string][]*kvrwset.KVWrite{ns: nsWrites})
+		if err != nil {
+			return nil, err
+		}
+		if len(updatedCCs) > 0 {
+			updatedCCsByNamespace[ns] = updatedCCs
+		}
+	}
+	return updatedCCsByNamespace, nil
+}
+
+// chaincodeInfo retrieves the info of the chaincode deployed via the given namespace. The namespace is passed on to the
+// chaincode info provider only if it implements `NamespacedChaincodeInfoProvider`
+func (m *mgr) chaincodeInfo(namespace, chaincodeName string, qe ledger.SimpleQueryExecutor) (ccInfo *ledger.DeployedChaincodeInfo, err error) {
+	err = m.ccInfoBreaker.call(func() error {
+		ccInfo, err = chaincodeInfo(m.ccInfoProvider, namespace, chaincodeName, qe)
+		return err
+	})
+	return ccInfo, err
+}
+
+func chaincodeInfo(ccInfoProvider ledger.DeployedChaincodeInfoProvider, namespace, chaincodeName string, qe ledger.SimpleQueryExecutor) (
+	*ledger.DeployedChaincodeInfo, error) {
+	if p, ok := ccInfoProvider.(NamespacedChaincodeInfoProvider); ok {
+		return p.ChaincodeInfoInNamespace(namespace, chaincodeName, qe)
+	}
+	return ccInfoProvider.ChaincodeInfo(chaincodeName, qe)
+}
+
+func (m *mgr) write(req *writeRequest) error {
+	writeStartTime := m.clock.Now()
+	dbHandle := m.dbProvider.getDB(req.ledgerID)
+	if err := dbHandle.writeBatch(req.batch, m.syncWrites); err != nil {
 		return err
 	}
-	if len(updatedCCs) == 0 {
-		logger.Errorf("Config history manager is expected to recieve events only if at least one chaincode is updated stateUpdates = %#v",
-			trigger.StateUpdates)
+	m.stats.updateWriteTime(req.ledgerID, m.clock.Now().Sub(writeStartTime))
+	m.stats.addCollConfigsWritten(req.ledgerID, req.numCollConfigs)
+	ccNames := make([]string, 0, len(req.collConfigs))
+	for ccName, collConfig := range req.collConfigs {
+		info := &ledger.CollectionConfigInfo{CollectionConfig: collConfig, CommittingBlockNum: req.blockNum}
+		m.cache.put(req.ledgerID, ccName, info)
+		m.watchers.notify(req.ledgerID, ccName, info)
+		ccNames = append(ccNames, ccName)
+	}
+	m.watchers.notifyLedger(req.ledgerID, req.blockNum, req.collConfigs)
+	m.watchers.notifyListeners(req.ledgerID, req.blockNum, req.collConfigs)
+	m.blockIndex.update(req.ledgerID, ccNames, req.blockNum)
+	m.purgeBeyondRetention(req.ledgerID, req.blockNum)
+	return nil
+}
+
+// WaitForPendingWrites implements function in the interface 'Mgr'
+func (m *mgr) WaitForPendingWrites() error {
+	if m.asyncWriter == nil {
 		return nil
 	}
-	updatedCollConfigs := map[string]*common.CollectionConfigPackage{}
-	for _, cc := range updatedCCs {
-		ccInfo, err := m.ccInfoProvider.ChaincodeInfo(cc.Name, trigger.PostCommitQueryExecutor)
+	return m.asyncWriter.waitForPendingWrites()
+}
+
+// ForEachConfigEntry implements function in the interface 'Mgr'. The ledgers are visited in the sorted order of ledger ids
+// and, within a ledger, the entries are visited in the sorted order of chaincode names and, for a chaincode, from the
+// most recent to the oldest entry. The supplied collection config info contains only the persisted (explicit) collections.
+// An error returned by the function stops the iteration and is returned to the caller
+func (m *mgr) ForEachConfigEntry(f func(ledgerID, chaincodeName string, info *ledger.CollectionConfigInfo) error) error {
+	for _, ledgerID := range m.dbProvider.ledgerIDs() {
+		err := m.dbProvider.getDB(ledgerID).forEachEntry(func(kv *compositeKV) error {
+			chaincodeName, ok := chaincodeNameFromCollectionConfigKey(kv.ns, kv.key)
+			if !ok {
+				return nil
+			}
+			info, err := compositeKVToCollectionConfig(kv)
+			if err != nil {
+				return err
+			}
+			return f(ledgerID, chaincodeName, info)
+		})
 		if err != nil {
 			return err
 		}
-		if ccInfo.CollectionConfigPkg == nil {
-			continue
+	}
+	return nil
+}
+
+// LedgersWithHistory implements function in the interface 'Mgr'. It returns, in the sorted order, the ids of the ledgers whose
+// config history contains at least one entry. If the store provider implements the interface `StoreLister`, this includes the
+// ledgers that have not been opened since the `Mgr` was created; otherwise, only the ledgers that have been opened are checked.
+// The pending asynchronous writes, if any, are applied before the check
+func (m *mgr) LedgersWithHistory() ([]string, error) {
+	if err := m.WaitForPendingWrites(); err != nil {
+		return nil, err
+	}
+	candidates := map[string]bool{}
+	for _, ledgerID := range m.dbProvider.ledgerIDs() {
+		candidates[ledgerID] = true
+	}
+	if lister, ok := m.dbProvider.StoreProvider.(StoreLister); ok {
+		ledgerIDs, err := lister.LedgerIDs()
+		if err != nil {
+			return nil, err
+		}
+		for _, ledgerID := range ledgerIDs {
+			candidates[ledgerID] = true
+		}
+	}
+	var ledgerIDs []string
+	for ledgerID := range candidates {
+		empty, err := m.dbProvider.getDB(ledgerID).isEmpty()
+		if err != nil {
+			return nil, err
+		}
+		if !empty {
+			ledgerIDs = append(ledgerIDs, ledgerID)
 		}
-		updatedCollConfigs[ccInfo.Name] = ccInfo.CollectionConfigPkg
 	}
-	if len(updatedCollConfigs) == 0 {
+	sort.Strings(ledgerIDs)
+	return ledgerIDs, nil
+}
+
+// Preload implements function in the interface 'Mgr'. This is intended to be invoked during the peer startup for the
+// frequently queried chaincodes so that the first queries after a restart are served from the cache. The chaincodes
+// that do not have any collection config are ignored. This is a no-op if the cache is not enabled
+func (m *mgr) Preload(ledgerID string, chaincodeNames []string) error {
+	if m.cache.capacity <= 0 {
+		logger.Debugf("Cache is not enabled, skipping preload of collection configs for ledger [%s]", ledgerID)
 		return nil
 	}
-	batch, err := prepareDBBatch(updatedCollConfigs, trigger.CommittingBlockNum)
-	if err != nil {
-		return err
+	dbHandle := m.dbProvider.getDB(ledgerID)
+	for _, chaincodeName := range chaincodeNames {
+		seq := m.cache.readSeq()
+		info, err := latestCollectionConfig(dbHandle, collectionConfigNamespace, chaincodeName)
+		if err != nil {
+			return err
+		}
+		if info == nil {
+			continue
+		}
+		m.cache.putIfNewer(ledgerID, chaincodeName, info, seq)
+	}
+	return nil
+}
+
+// GetRetriever returns an implementation of `Retriever` for the given ledger id. The returned retriever serves the
+// collection configs of the chaincodes deployed via the default lifecycle namespace (i.e., `lscc`)
+func (m *mgr) GetRetriever(ledgerID string, ledgerInfoRetriever LedgerInfoRetriever) Retriever {
+	return m.GetRetrieverForNamespace(ledgerID, collectionConfigNamespace, ledgerInfoRetriever)
+}
+
+// GetRetrieverForNamespace implements function in the interface 'Mgr'. The cache is used only for the default namespace;
+// for the other namespaces, the queries are always served from the persisted entries
+func (m *mgr) GetRetrieverForNamespace(ledgerID, namespace string, ledgerInfoRetriever LedgerInfoRetriever) Retriever {
+	r := &retriever{
+		ledgerID:             ledgerID,
+		namespace:            namespace,
+		ccInfoProvider:       m.ccInfoProvider,
+		dbHandle:             m.dbProvider.getDB(ledgerID),
+		ledgerInfoRetriever:  ledgerInfoRetriever,
+		cache:                m.cache,
+		blockIndex:           m.blockIndex,
+		tracer:               m.tracer,
+		checkDeployed:        m.checkDeployed,
+		maxImplicitColls:     m.maxImplicitColls,
+		lenientImplicitColls: m.lenientImplicitColls,
+		logSampler:           m.logSampler,
+		ccInfoBreaker:        m.ccInfoBreaker,
+		stats:                m.stats,
+	}
+	if namespace != collectionConfigNamespace {
+		r.cache = newConfigCache(0)
+		r.blockIndex = newBlockIndex(false)
+	} else {
+		r.watchers = m.watchers
 	}
-	dbHandle := m.dbProvider.getDB(trigger.LedgerID)
-	return dbHandle.writeBatch(batch, true)
+	return r
 }
 
-// GetRetriever returns an implementation of `ledger.ConfigHistoryRetriever` for the given ledger id.
-func (m *mgr) GetRetriever(ledgerID string, ledgerInfoRetriever LedgerInfoRetriever) ledger.ConfigHistoryRetriever {
-	return &retriever{dbHandle: m.dbProvider.getDB(ledgerID), ledgerInfoRetriever: ledgerInfoRetriever}
+// ApproximateSize implements function in the interface 'Mgr'
+func (m *mgr) ApproximateSize(ledgerID string) (uint64, error) {
+	dbHandle := m.dbProvider.getDB(ledgerID)
+	estimator, ok := dbHandle.Store.(SizeEstimator)
+	if !ok {
+		return 0, errors.Errorf("the store of the config history for ledger [%s] does not support size estimation", ledgerID)
+	}
+	release, err := dbHandle.checkOpen()
+	defer release()
+	if err != nil {
+		return 0, err
+	}
+	return estimator.ApproximateSize()
 }
 
 // Close implements the function in the interface 'Mgr'
 func (m *mgr) Close() {
+	if m.asyncWriter != nil {
+		m.asyncWriter.close()
+	}
+	if m.stopCh != nil {
+		close(m.stopCh)
+		m.wg.Wait()
+		m.stopCh = nil
+	}
+	m.watchers.closeAll()
+	if m.compactTimeout > 0 {
+		m.compactAll(m.compactTimeout)
+	}
 	m.dbProvider.Close()
 }
 
 type retriever struct {
-	ledgerInfoRetriever LedgerInfoRetriever
-	dbHandle            *db
+	ledgerID             string
+	namespace            string
+	ccInfoProvider       ledger.DeployedChaincodeInfoProvider
+	ledgerInfoRetriever  LedgerInfoRetriever
+	dbHandle             *db
+	cache                *configCache
+	blockIndex           *blockIndex
+	tracer               Tracer
+	checkDeployed        bool
+	maxImplicitColls     int
+	lenientImplicitColls bool
+	// watchers is nil for the retrievers whose change feeds cannot follow the commits (see function `ConfigChangesSince`)
+	watchers      *watchers
+	logSampler    *logSampler
+	ccInfoBreaker *ccInfoBreaker
+	stats         *stats
 }
 
 // MostRecentCollectionConfigBelow implements function from the interface ledger.ConfigHistoryRetriever
+// The returned collection config includes the implicit collections of the chaincode
 func (r *retriever) MostRecentCollectionConfigBelow(blockNum uint64, chaincodeName string) (*ledger.CollectionConfigInfo, error) {
-	compositeKV, err := r.dbHandle.mostRecentEntryBelow(blockNum, collectionConfigNamespace, constructCollectionConfigKey(chaincodeName))
+	return r.MostRecentCollectionConfigBelowContext(context.Background(), blockNum, chaincodeName)
+}
+
+// MostRecentCollectionConfigBelowContext implements function from the interface `Retriever`
+func (r *retriever) MostRecentCollectionConfigBelowContext(ctx context.Context, blockNum uint64, chaincodeName string) (
+	collConfig *ledger.CollectionConfigInfo, err error) {
+	_, span := startSpan(ctx, r.tracer, "confighistory.MostRecentCollectionConfigBelow", r.ledgerID, chaincodeName, blockNum)
+	defer endSpan(span, &err)
+	return r.mostRecentCollectionConfigBelow(blockNum, chaincodeName, nil)
+}
+
+// CollectionConfigAt implements function from the interface ledger.ConfigHistoryRetriever
+// The returned collection config includes the implicit collections of the chaincode
+func (r *retriever) CollectionConfigAt(blockNum uint64, chaincodeName string) (*ledger.CollectionConfigInfo, error) {
+	return r.CollectionConfigAtContext(context.Background(), blockNum, chaincodeName)
+}
+
+// CollectionConfigAtContext implements function from the interface `Retriever`
+func (r *retriever) CollectionConfigAtContext(ctx context.Context, blockNum uint64, chaincodeName string) (
+	collConfig *ledger.CollectionConfigInfo, err error) {
+	_, span := startSpan(ctx, r.tracer, "confighistory.CollectionConfigAt", r.ledgerID, chaincodeName, blockNum)
+	defer endSpan(span, &err)
+	return r.collectionConfigAt(blockNum, chaincodeName, nil)
+}
+
+// CollectionConfigBefore implements function from the interface `Retriever`. It returns the collection config of the chaincode
+// that is in effect just before the given block is committed, i.e., the most recent one committed strictly below the block, as
+// the function `MostRecentCollectionConfigBelow` does. In addition, like the function `CollectionConfigAt`, it returns
+// `ledger.ErrCollectionConfigNotYetAvailable` if the block is beyond the next block to be committed to the ledger. The returned
+// collection config includes the implicit collections of the chaincode
+func (r *retriever) CollectionConfigBefore(blockNum uint64, chaincodeName string) (*ledger.CollectionConfigInfo, error) {
+	info, err := r.ledgerInfoRetriever.GetBlockchainInfo()
+	if err != nil {
+		return nil, err
+	}
+	// the config in effect before a block is known once the preceding block is committed, i.e., for the blocks up to the height
+	if blockNum > info.Height {
+		if info.Height == 0 {
+			return nil, &ledger.ErrCollectionConfigNotYetAvailable{
+				Msg: fmt.Sprintf("No block is committed to the ledger yet, requested block number [%d]", blockNum)}
+		}
+		return nil, &ledger.ErrCollectionConfigNotYetAvailable{MaxBlockNumCommitted: info.Height - 1,
+			Msg: fmt.Sprintf("The next block to be committed [%d] is less than the requested block number [%d]", info.Height, blockNum)}
+	}
+	if blockNum == 0 {
+		return nil, nil
+	}
+	return r.mostRecentCollectionConfigBelow(blockNum, chaincodeName, nil)
+}
+
+// CollectionConfigAtForOrg implements function from the interface `Retriever`
+func (r *retriever) CollectionConfigAtForOrg(blockNum uint64, chaincodeName, mspID string) (*ledger.CollectionConfigInfo, error) {
+	return r.collectionConfigAt(blockNum, chaincodeName, belongsToOrg(mspID))
+}
+
+// MostRecentCollectionConfigBelowForOrg implements function from the interface `Retriever`
+func (r *retriever) MostRecentCollectionConfigBelowForOrg(blockNum uint64, chaincodeName, mspID string) (*ledger.CollectionConfigInfo, error) {
+	return r.mostRecentCollectionConfigBelow(blockNum, chaincodeName, belongsToOrg(mspID))
+}
+
+// CollectionConfigAtForCollections implements function from the interface `Retriever`. Both the explicit and the implicit
+// collections are filtered by the given names, which is intended for the callers that are authorized to see only some of the
+// collections of a chaincode. The names that do not match any collection are ignored. A nil list of names includes all the
+// collections, whereas an empty list includes none. If the filter excludes all the collections of an existing collection config,
+// a collection config with no collections is returned, so that the committing block is still reported. The implicit collections
+// are filtered before applying the cap set via the function `WithMaxImplicitCollections`
+func (r *retriever) CollectionConfigAtForCollections(blockNum uint64, chaincodeName string, collectionNames []string) (
+	*ledger.CollectionConfigInfo, error) {
+	if collectionNames == nil {
+		return r.CollectionConfigAt(blockNum, chaincodeName)
+	}
+	names := collectionNameSet(collectionNames)
+	collConfig, err := r.collectionConfigAt(blockNum, chaincodeName, hasName(names))
+	return selectCollections(collConfig, names), err
+}
+
+// MostRecentCollectionConfigBelowForCollections implements function from the interface `Retriever`. The names are applied as in
+// the function `CollectionConfigAtForCollections`
+func (r *retriever) MostRecentCollectionConfigBelowForCollections(blockNum uint64, chaincodeName string, collectionNames []string) (
+	*ledger.CollectionConfigInfo, error) {
+	if collectionNames == nil || blockNum == 0 {
+		return r.MostRecentCollectionConfigBelow(blockNum, chaincodeName)
+	}
+	names := collectionNameSet(collectionNames)
+	collConfig, err := r.mostRecentCollectionConfigBelow(blockNum, chaincodeName, hasName(names))
+	return selectCollections(collConfig, names), err
+}
+
+// ExplicitCollectionConfigAt implements function from the interface `Retriever`
+func (r *retriever) ExplicitCollectionConfigAt(blockNum uint64, chaincodeName string) (*ledger.CollectionConfigInfo, error) {
+	collConfig, err := r.explicitCollectionConfigAt(blockNum, chaincodeName)
+	if err == nil && collConfig != nil {
+		collConfig, err = r.withAnnotation(chaincodeName, collConfig)
+	}
+	return collConfig, r.withQueryStats(err, collConfig, chaincodeName, blockNum)
+}
+
+// withQueryStats counts the query in the stats (see function `stats.incrementQueries`) unless it failed, with an unknown chaincode
+// counted as a miss, and then adds the context to the error as the function `withContext` does
+func (r *retriever) withQueryStats(err error, collConfig *ledger.CollectionConfigInfo, chaincodeName string, blockNum uint64) error {
+	switch err.(type) {
+	case nil:
+		r.stats.incrementQueries(r.ledgerID, collConfig != nil)
+	case *ledger.ErrChaincodeNotDeployed:
+		r.stats.incrementQueries(r.ledgerID, false)
+	}
+	return r.withContext(err, chaincodeName, blockNum)
+}
+
+func (r *retriever) mostRecentCollectionConfigBelow(blockNum uint64, chaincodeName string, filter implicitCollectionFilter) (
+	collConfig *ledger.CollectionConfigInfo, err error) {
+	defer func() { err = r.withQueryStats(err, collConfig, chaincodeName, blockNum) }()
+	explicitConfig, err := r.explicitMostRecentCollectionConfigBelow(blockNum, chaincodeName)
+	if err != nil {
+		return nil, err
+	}
+	collConfig, err = r.resolveCollectionConfig(chaincodeName, explicitConfig, filter)
+	if err != nil || collConfig != nil {
+		return collConfig, err
+	}
+	return nil, r.checkChaincodeDeployed(blockNum, chaincodeName)
+}
+
+func (r *retriever) collectionConfigAt(blockNum uint64, chaincodeName string, filter implicitCollectionFilter) (
+	collConfig *ledger.CollectionConfigInfo, err error) {
+	defer func() { err = r.withQueryStats(err, collConfig, chaincodeName, blockNum) }()
+	explicitConfig, err := r.explicitCollectionConfigAt(blockNum, chaincodeName)
+	if err != nil {
+		return nil, err
+	}
+	collConfig, err = r.resolveCollectionConfig(chaincodeName, explicitConfig, filter)
+	if err != nil || collConfig != nil {
+		return collConfig, err
+	}
+	return nil, r.checkChaincodeDeployed(blockNum, chaincodeName)
+}
+
+func (r *retriever) explicitMostRecentCollectionConfigBelow(blockNum uint64, chaincodeName string) (*ledger.CollectionConfigInfo, error) {
+	if r.cache.capacity > 0 {
+		latest, ok := r.cache.get(r.ledgerID, chaincodeName)
+		if !ok {
+			seq := r.cache.readSeq()
+			var err error
+			if latest, err = latestCollectionConfig(r.dbHandle, r.namespace, chaincodeName); err != nil {
+				return nil, err
+			}
+			if latest != nil {
+				r.cache.putIfNewer(r.ledgerID, chaincodeName, latest, seq)
+			}
+		}
+		if latest != nil && latest.CommittingBlockNum < blockNum {
+			return latest, nil
+		}
+	}
+	compositeKV, err := r.dbHandle.mostRecentEntryBelowWithPointer(blockNum, r.namespace, constructCollectionConfigKey(chaincodeName))
 	if err != nil || compositeKV == nil {
 		return nil, err
 	}
 	return compositeKVToCollectionConfig(compositeKV)
 }
 
-// CollectionConfigAt implements function from the interface ledger.ConfigHistoryRetriever
-func (r *retriever) CollectionConfigAt(blockNum uint64, chaincodeName string) (*ledger.CollectionConfigInfo, error) {
-	info, err := r.ledgerInfoRetriever.GetBlockchainInfo()
+func (r *retriever) explicitCollectionConfigAt(blockNum uint64, chaincodeName string) (*ledger.CollectionConfigInfo, error) {
+	if err := r.checkBlockCommitted(blockNum); err != nil {
+		return nil, err
+	}
+	if latest, ok := r.cache.get(r.ledgerID, chaincodeName); ok && latest.CommittingBlockNum == blockNum {
+		return latest, nil
+	}
+	compositeKV, err := r.dbHandle.entryAt(blockNum, r.namespace, constructCollectionConfigKey(chaincodeName))
+	if err != nil || compositeKV == nil {
+		return nil, err
+	}
+	return compositeKVToCollectionConfig(compositeKV)
+}
+
+// ChaincodesConfiguredAt implements function from the interface `Retriever`
+func (r *retriever) ChaincodesConfiguredAt(blockNum uint64) ([]string, error) {
+	if err := r.checkBlockCommitted(blockNum); err != nil {
+		return nil, err
+	}
+	keys, err := r.dbHandle.keysWithEntryAt(blockNum, r.namespace)
 	if err != nil {
 		return nil, err
 	}
+	var chaincodeNames []string
+	for _, key := range keys {
+		if chaincodeName, ok := chaincodeNameFromKey(key); ok {
+			chaincodeNames = append(chaincodeNames, chaincodeName)
+		}
+	}
+	// the order of the keys may differ from the order of the chaincode names because of the key suffix
+	sort.Strings(chaincodeNames)
+	return chaincodeNames, nil
+}
+
+// AllCollectionConfigsAt implements function from the interface `Retriever`. The returned collection configs are the same as the ones
+// returned by the function `CollectionConfigAt` for each of the chaincodes returned by the function `ChaincodesConfiguredAt`, including
+// the implicit collections, but are read from the db in a single scan, so that the callers need not know the chaincodes beforehand.
+// An empty map is returned if no collection config is committed at the block
+func (r *retriever) AllCollectionConfigsAt(blockNum uint64) (map[string]*ledger.CollectionConfigInfo, error) {
+	if err := r.checkBlockCommitted(blockNum); err != nil {
+		return nil, err
+	}
+	entries, err := r.dbHandle.entriesAt(blockNum, r.namespace)
+	if err != nil {
+		return nil, errors.WithMessage(err, fmt.Sprintf("error while retrieving the collection configs for block [%d] of ledger [%s]", blockNum, r.ledgerID))
+	}
+	collConfigs := map[string]*ledger.CollectionConfigInfo{}
+	for _, entry := range entries {
+		chaincodeName, ok := chaincodeNameFromKey(entry.key)
+		if !ok {
+			continue
+		}
+		explicitConfig, err := compositeKVToCollectionConfig(entry)
+		if err != nil {
+			return nil, r.withContext(err, chaincodeName, blockNum)
+		}
+		collConfig, err := r.resolveCollectionConfig(chaincodeName, explicitConfig, nil)
+		if err != nil {
+			return nil, r.withContext(err, chaincodeName, blockNum)
+		}
+		collConfigs[chaincodeName] = collConfig
+	}
+	return collConfigs, nil
+}
+
+// FindChaincodesByPrefix implements function from the interface `Retriever`. The escaping of a chaincode name in the
+// collection config key is applied character by character and hence, the escaped prefix is a prefix of the key of
+// each of the matching chaincodes. An empty prefix matches all the chaincodes
+func (r *retriever) FindChaincodesByPrefix(prefix string) ([]string, error) {
+	keys, err := r.dbHandle.keysWithPrefix(r.namespace, chaincodeNameEscaper.Replace(prefix))
+	if err != nil {
+		return nil, err
+	}
+	var chaincodeNames []string
+	for _, key := range keys {
+		if chaincodeName, ok := chaincodeNameFromKey(key); ok {
+			chaincodeNames = append(chaincodeNames, chaincodeName)
+		}
+	}
+	// the order of the keys may differ from the order of the chaincode names because of the key suffix
+	sort.Strings(chaincodeNames)
+	return chaincodeNames, nil
+}
+
+// checkBlockCommitted returns `ledger.ErrCollectionConfigNotYetAvailable` if the given block is not yet committed to the ledger
+func (r *retriever) checkBlockCommitted(blockNum uint64) error {
+	info, err := r.ledgerInfoRetriever.GetBlockchainInfo()
+	if err != nil {
+		return err
+	}
+	if info.Height == 0 {
+		// no block is committed yet; the field `MaxBlockNumCommitted` cannot represent this and is left as zero
+		return &ledger.ErrCollectionConfigNotYetAvailable{
+			Msg: fmt.Sprintf("No block is committed to the ledger yet, requested block number [%d]", blockNum)}
+	}
 	maxCommittedBlockNum := info.Height - 1
 	if maxCommittedBlockNum < blockNum {
-		return nil, &ledger.ErrCollectionConfigNotYetAvailable{MaxBlockNumCommitted: maxCommittedBlockNum,
+		return &ledger.ErrCollectionConfigNotYetAvailable{MaxBlockNumCommitted: maxCommittedBlockNum,
 			Msg: fmt.Sprintf("The maximum block number committed [%d] is less than the requested block number [%d]", maxCommittedBlockNum, blockNum)}
 	}
+	return nil
+}
+
+// withContext adds the ledger id, the chaincode name, and the block number of a query to the error encountered by the query, so
+// that the error can be triaged from the logs. The typed errors defined in the package `ledger` are returned as is, because these
+// already carry the context and the callers detect these by their type; the wrapping by pkg/errors would hide these from `errors.As`
+func (r *retriever) withContext(err error, chaincodeName string, blockNum uint64) error {
+	switch err.(type) {
+	case nil, *ledger.ErrCollectionConfigNotYetAvailable, *ledger.ErrChaincodeNotDeployed:
+		return err
+	}
+	return errors.Wrapf(err, "error while retrieving the collection config of chaincode [%s] for block [%d] of ledger [%s]",
+		chaincodeName, blockNum, r.ledgerID)
+}
+
+// checkChaincodeDeployed returns `ledger.ErrChaincodeNotDeployed` if the check is enabled (see function `WithChaincodeNotDeployedErrors`)
+// and the chaincode has neither a collection config in the config history nor a chaincode info in the latest state
+func (r *retriever) checkChaincodeDeployed(blockNum uint64, chaincodeName string) error {
+	if !r.checkDeployed {
+		return nil
+	}
+	latest, err := latestCollectionConfig(r.dbHandle, r.namespace, chaincodeName)
+	if err != nil || latest != nil {
+		return err
+	}
+	qe, err := r.ledgerInfoRetriever.NewQueryExecutor()
+	if err != nil {
+		return err
+	}
+	defer qe.Done()
+	var ccInfo *ledger.DeployedChaincodeInfo
+	err = r.ccInfoBreaker.call(func() error {
+		ccInfo, err = chaincodeInfo(r.ccInfoProvider, r.namespace, chaincodeName, qe)
+		return err
+	})
+	if err != nil || ccInfo != nil {
+		return err
+	}
+	return &ledger.ErrChaincodeNotDeployed{ChaincodeName: chaincodeName, BlockNum: blockNum}
+}
+
+// implicitCollectionFilter is used for selecting a subset of the implicit collections. It returns true if the
+// supplied implicit collection is to be included
+type implicitCollectionFilter func(implicitColl *common.StaticCollectionConfig) (bool, error)
 
-	compositeKV, err := r.dbHandle.entryAt(blockNum, collectionConfigNamespace, constructCollectionConfigKey(chaincodeName))
+func belongsToOrg(mspID string) implicitCollectionFilter {
+	return func(implicitColl *common.StaticCollectionConfig) (bool, error) {
+		orgs, err := memberOrgs(implicitColl)
+		if err != nil {
+			return false, err
+		}
+		for _, org := range orgs {
+			if org == mspID {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+}
+
+func collectionNameSet(collectionNames []string) map[string]bool {
+	names := map[string]bool{}
+	for _, name := range collectionNames {
+		names[name] = true
+	}
+	return names
+}
+
+func hasName(names map[string]bool) implicitCollectionFilter {
+	return func(implicitColl *common.StaticCollectionConfig) (bool, error) {
+		return names[implicitColl.Name], nil
+	}
+}
+
+// selectCollections returns a copy of the given collection config that includes only the static collections with the given names
+func selectCollections(collConfig *ledger.CollectionConfigInfo, names map[string]bool) *ledger.CollectionConfigInfo {
+	if collConfig == nil {
+		return nil
+	}
+	selected := *collConfig
+	selected.CollectionConfig = &common.CollectionConfigPackage{}
+	for _, c := range collConfig.CollectionConfig.GetConfig() {
+		if staticCollConfig := c.GetStaticCollectionConfig(); staticCollConfig != nil && names[staticCollConfig.Name] {
+			selected.CollectionConfig.Config = append(selected.CollectionConfig.Config, c)
+		}
+	}
+	return &selected
+}
+
+// addImplicitCollections appends the implicit collections of the chaincode, as supplied by the `ImplicitCollectionsProvider`,
+// to the collection config that is retrieved from the config history. The implicit collections are not persisted in the
+// config history and are always derived from the latest state. A nil filter selects all the implicit collections, subject to
+// the cap set via the function `WithMaxImplicitCollections`.
+// If neither an explicit collection config nor an implicit collection exists, nil is returned
+func (r *retriever) addImplicitCollections(
+	chaincodeName string,
+	explicitConfig *ledger.CollectionConfigInfo,
+	filter implicitCollectionFilter,
+) (*ledger.CollectionConfigInfo, error) {
+	implicitColls, err := r.implicitCollections(chaincodeName)
+	if err != nil {
+		if !r.lenientImplicitColls || explicitConfig == nil {
+			return nil, err
+		}
+		r.logSampler.warningf("implicit-collections-error", "Returning the collection config of chaincode [%s] in ledger [%s] without the implicit collections: %s",
+			chaincodeName, r.ledgerID, err)
+		incompleteConfig := *explicitConfig
+		incompleteConfig.ImplicitCollectionsIncomplete = true
+		return &incompleteConfig, nil
+	}
+	if filter != nil {
+		var selectedColls []*common.StaticCollectionConfig
+		for _, implicitColl := range implicitColls {
+			selected, err := filter(implicitColl)
+			if err != nil {
+				return nil, err
+			}
+			if selected {
+				selectedColls = append(selectedColls, implicitColl)
+			}
+		}
+		implicitColls = selectedColls
+	}
+	if r.maxImplicitColls > 0 && len(implicitColls) > r.maxImplicitColls {
+		r.logSampler.debugf("implicit-collections-capped", "Omitting [%d] out of [%d] implicit collections of chaincode [%s] as the number exceeds the cap [%d]",
+			len(implicitColls)-r.maxImplicitColls, len(implicitColls), chaincodeName, r.maxImplicitColls)
+		implicitColls = implicitColls[:r.maxImplicitColls]
+	}
+	if len(implicitColls) == 0 {
+		return explicitConfig, nil
+	}
+
+	collConfigInfo := &ledger.CollectionConfigInfo{CollectionConfig: &common.CollectionConfigPackage{}}
+	if explicitConfig != nil {
+		collConfigInfo.CollectionConfig.Config = append(collConfigInfo.CollectionConfig.Config, explicitConfig.CollectionConfig.Config...)
+		collConfigInfo.CommittingBlockNum = explicitConfig.CommittingBlockNum
+	}
+	for _, implicitColl := range implicitColls {
+		collConfigInfo.CollectionConfig.Config = append(collConfigInfo.CollectionConfig.Config,
+			&common.CollectionConfig{
+				Payload: &common.CollectionConfig_StaticCollectionConfig{StaticCollectionConfig: implicitColl},
+			},
+		)
+	}
+	return collConfigInfo, nil
+}
+
+func (r *retriever) implicitCollections(chaincodeName string) ([]*common.StaticCollectionConfig, error) {
+	implicitCollsProvider, ok := r.ccInfoProvider.(ImplicitCollectionsProvider)
+	if !ok {
+		return nil, nil
+	}
+	qe, err := r.ledgerInfoRetriever.NewQueryExecutor()
+	if err != nil {
+		return nil, err
+	}
+	defer qe.Done()
+	var implicitColls []*common.StaticCollectionConfig
+	err = r.ccInfoBreaker.call(func() error {
+		implicitColls, err = implicitCollsProvider.ImplicitCollections(r.ledgerID, chaincodeName, qe)
+		return err
+	})
+	return implicitColls, err
+}
+
+// CheckConsistencyWithLedger implements function from the interface `Retriever`
+func (r *retriever) CheckConsistencyWithLedger() error {
+	maxStoredBlockNum, found, err := r.dbHandle.maxBlockNum()
+	if err != nil || !found {
+		return err
+	}
+	info, err := r.ledgerInfoRetriever.GetBlockchainInfo()
+	if err != nil {
+		return err
+	}
+	if info.Height == 0 || maxStoredBlockNum > info.Height-1 {
+		return errors.Errorf("config history for ledger [%s] contains an entry for block [%d] which is beyond the ledger height [%d]",
+			r.ledgerID, maxStoredBlockNum, info.Height)
+	}
+	return nil
+}
+
+// RawEntryAt implements function from the interface `Retriever`. The returned key is the composite key that would be
+// used for the entry, irrespective of whether the entry exists. A nil value is returned if the entry does not exist
+func (r *retriever) RawEntryAt(blockNum uint64, chaincodeName string) ([]byte, []byte, error) {
+	key := encodeCompositeKey(r.namespace, constructCollectionConfigKey(chaincodeName), blockNum)
+	value, err := r.dbHandle.Get(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return key, value, nil
+}
+
+// latestCollectionConfig returns the most recent persisted collection config of the chaincode in the given namespace
+func latestCollectionConfig(dbHandle *db, namespace, chaincodeName string) (*ledger.CollectionConfigInfo, error) {
+	compositeKV, err := dbHandle.mostRecentEntryBelowWithPointer(math.MaxUint64, namespace, constructCollectionConfigKey(chaincodeName))
 	if err != nil || compositeKV == nil {
 		return nil, err
 	}
 	return compositeKVToCollectionConfig(compositeKV)
 }
 
-func prepareDBBatch(chaincodeCollConfigs map[string]*common.CollectionConfigPackage, committingBlockNum uint64) (*batch, error) {
+// prepareDBBatch prepares the batch for persisting the collection configs of the given chaincodes. More than one entry for
+// a chaincode is tolerated only if all of them carry the same collection config, otherwise an error is returned, as the
+// divergent configs for a chaincode in a block indicate a bug in the chaincode lifecycle. An error is also returned if the
+// marshalled collection config of a chaincode is larger than maxConfigSize bytes; a non-positive maxConfigSize means no limit.
+// The collection configs are persisted as records if asRecords is true (see function `WithCollectionConfigRecords`)
+func prepareDBBatch(ccInfosByNamespace map[string][]*ledger.DeployedChaincodeInfo, committingBlockNum uint64, maxConfigSize int,
+	asRecords bool) (*batch, error) {
 	batch := newBatch()
-	for ccName, collConfig := range chaincodeCollConfigs {
-		key := constructCollectionConfigKey(ccName)
-		var configBytes []byte
-		var err error
-		if configBytes, err = proto.Marshal(collConfig); err != nil {
-			return nil, errors.WithStack(err)
+	for ns, ccInfos := range ccInfosByNamespace {
+		for _, ccInfo := range ccInfos {
+			key := constructCollectionConfigKey(ccInfo.Name)
+			var configBytes []byte
+			var err error
+			if configBytes, err = marshalDeterministically(ccInfo.CollectionConfigPkg); err != nil {
+				return nil, errors.WithStack(err)
+			}
+			if maxConfigSize > 0 && len(configBytes) > maxConfigSize {
+				return nil, errors.Errorf("size [%d bytes] of the collection config for chaincode [%s] in block [%d] exceeds the maximum allowed size [%d bytes]",
+					len(configBytes), ccInfo.Name, committingBlockNum, maxConfigSize)
+			}
+			value, err := encodeCollectionConfig(ccInfo.CollectionConfigPkg, configBytes, asRecords)
+			if err != nil {
+				return nil, err
+			}
+			existingValue, ok := batch.KVs[string(encodeCompositeKey(ns, key, committingBlockNum))]
+			if ok && !bytes.Equal(existingValue, value) {
+				return nil, errors.Errorf("conflicting collection configs for chaincode [%s] (key [%s]) in block [%d]",
+					ccInfo.Name, key, committingBlockNum)
+			}
+			batch.add(ns, key, committingBlockNum, value)
 		}
-		batch.add(collectionConfigNamespace, key, committingBlockNum, configBytes)
 	}
 	return batch, nil
 }
 
+// marshalDeterministically marshals the message such that the semantically identical messages produce identical bytes
+// within a build. Unmarshalling is not affected by this and hence, the entries written before by the non-deterministic
+// marshalling can still be decoded
+func marshalDeterministically(msg proto.Message) ([]byte, error) {
+	buffer := proto.NewBuffer(nil)
+	buffer.SetDeterministic(true)
+	if err := buffer.Marshal(msg); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// compositeKVToCollectionConfig decodes the collection config persisted in either of the formats (see function `decodeCollectionConfig`)
 func compositeKVToCollectionConfig(compositeKV *compositeKV) (*ledger.CollectionConfigInfo, error) {
-	conf := &common.CollectionConfigPackage{}
-	if err := proto.Unmarshal(compositeKV.value, conf); err != nil {
+	conf, err := decodeCollectionConfig(compositeKV.value)
+	if err != nil {
 		return nil, errors.Wrap(err, "error unmarshalling compositeKV to collection config")
 	}
 	return &ledger.CollectionConfigInfo{CollectionConfig: conf, CommittingBlockNum: compositeKV.blockNum}, nil
 }
 
+// chaincodeNameEscaper escapes the characters in a chaincode name that would otherwise make the collection config key
+// ambiguous. After escaping, the tilde in a key appears only in the suffix and hence, the key of a chaincode is never
+// a prefix of the key of another chaincode. The names allowed by the chaincode lifecycle do not contain any of these
+// characters and hence, their keys remain the same as in version 1.2
+var (
+	chaincodeNameEscaper   = strings.NewReplacer("%", "%25", "~", "%7E", "\x00", "%00")
+	chaincodeNameUnescaper = strings.NewReplacer("%25", "%", "%7E", "~", "%00", "\x00")
+)
+
 func constructCollectionConfigKey(chaincodeName string) string {
-	return chaincodeName + "~collection" // collection config key as in version 1.2 and we continue to use this in order to be compatible with existing data
+	return chaincodeNameEscaper.Replace(chaincodeName) + collectionConfigKeySuffix // collection config key as in version 1.2 and we continue to use this in order to be compatible with existing data
+}
+
+// chaincodeNameFromCollectionConfigKey is the inverse of the function `constructCollectionConfigKey`. The returned bool
+// is false if the supplied <ns, key> does not represent a collection config entry
+func chaincodeNameFromCollectionConfigKey(ns, key string) (string, bool) {
+	if ns != collectionConfigNamespace {
+		return "", false
+	}
+	return chaincodeNameFromKey(key)
+}
+
+// chaincodeNameFromKey is same as the function `chaincodeNameFromCollectionConfigKey` except that the key is not
+// required to be in the default namespace
+func chaincodeNameFromKey(key string) (string, bool) {
+	if !strings.HasSuffix(key, collectionConfigKeySuffix) {
+		return "", false
+	}
+	return chaincodeNameUnescaper.Replace(strings.TrimSuffix(key, collectionConfigKeySuffix)), true
 }
 
 func dbPath() string {
 	return ledgerconfig.GetConfigHistoryPath()
 }
 
-func convertToKVWrites(stateUpdates ledger.StateUpdates) map[string][]*kvrwset.KVWrite {
+// convertToKVWrites returns the state updates as the writes of the namespaces. The ledger supplies the updates of a
+// namespace as a `[]*kvrwset.KVWrite`; an error is returned if the updates of any namespace are of a different type
+func convertToKVWrites(stateUpdates ledger.StateUpdates) (map[string][]*kvrwset.KVWrite, error) {
 	m := map[string][]*kvrwset.KVWrite{}
 	for ns, updates := range stateUpdates {
-		m[ns] = updates.([]*kvrwset.KVWrite)
+		kvWrites, ok := updates.([]*kvrwset.KVWrite)
+		if !ok {
+			return nil, errors.Errorf("unexpected type [%T] of the state updates for namespace [%s], expected [%T]",
+				updates, ns, kvWrites)
+		}
+		m[ns] = kvWrites
 	}
-	return m
+	return m, nil
+}
+
+// describeKVWrites summarizes the given writes as the number of writes and deletes in each namespace, in the order of the
+// namespaces, for the logs and the errors. The keys and the values are not included
+func describeKVWrites(kvWrites map[string][]*kvrwset.KVWrite) string {
+	var namespaces []string
+	for ns := range kvWrites {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Strings(namespaces)
+	var descriptions []string
+	for _, ns := range namespaces {
+		numDeletes := 0
+		for _, w := range kvWrites[ns] {
+			if w.IsDelete {
+				numDeletes++
+			}
+		}
+		descriptions = append(descriptions, fmt.Sprintf("%s: %d writes, %d deletes", ns, len(kvWrites[ns])-numDeletes, numDeletes))
+	}
+	return strings.Join(descriptions, "; ")
 }
 
 // LedgerInfoRetriever retrieves the relevant info from ledger
 type LedgerInfoRetriever interface {
 	GetBlockchainInfo() (*common.BlockchainInfo, error)
+	GetBlockByNumber(blockNumber uint64) (*common.Block, error)
+	NewQueryExecutor() (ledger.QueryExecutor, error)
 }
@@ -10,12 +10,19 @@ import (
 	"fmt"
 	"math"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/common/cauthdsl"
 	"github.com/hyperledger/fabric/common/flogging"
 	"github.com/hyperledger/fabric/core/ledger"
+	chmock "github.com/hyperledger/fabric/core/ledger/confighistory/mock"
 	"github.com/hyperledger/fabric/core/ledger/mock"
 	"github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/ledger/rwset/kvrwset"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -113,6 +120,1173 @@ func TestMgr(t *testing.T) {
 		assert.True(t, ok)
 		assert.Equal(t, maxBlockNumberInLedger, typedErr.MaxBlockNumCommitted)
 	})
+
+	t.Run("test-api-RawEntryAt()", func(t *testing.T) {
+		retriever := mgr.GetRetriever("ledgerid1", dummyLedgerInfoRetriever)
+		key, value, err := retriever.RawEntryAt(10, chaincodeName)
+		assert.NoError(t, err)
+		assert.Equal(t, encodeCompositeKey("lscc", "chaincode1~collection", 10), key)
+		expectedValue, err := proto.Marshal(sampleCollectionConfigPackage("ledgerid1", 10))
+		assert.NoError(t, err)
+		assert.Equal(t, expectedValue, value)
+
+		key, value, err = retriever.RawEntryAt(11, chaincodeName)
+		assert.NoError(t, err)
+		assert.Equal(t, encodeCompositeKey("lscc", "chaincode1~collection", 11), key)
+		assert.Nil(t, value)
+	})
+}
+
+func TestMgrWithoutImplicitCollectionsProvider(t *testing.T) {
+	dbPath := "/tmp/fabric/core/ledger/confighistory"
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	env := newTestEnv(t, dbPath, mockCCInfoProvider)
+	mgr := env.mgr
+	defer env.cleanup()
+
+	testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, "chaincode1", sampleCollectionConfigPackage("coll", 10))
+	assert.NoError(t, mgr.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: 10}))
+
+	// the query executor is not created if the provider does not supply the implicit collections
+	retriever := mgr.GetRetriever("ledger1", &noQueryExecutorLedgerInfoRetriever{
+		dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 100}},
+	})
+	collConfig, err := retriever.CollectionConfigAt(10, "chaincode1")
+	assert.NoError(t, err)
+	assert.False(t, collConfig.ImplicitCollectionsIncomplete)
+	assert.Equal(t, []string{"coll-10"}, collNames(collConfig))
+}
+
+func TestMgrImplicitCollections(t *testing.T) {
+	dbPath := "/tmp/fabric/core/ledger/confighistory"
+	mockCCInfoProvider := &implicitCollsCCInfoProvider{}
+	env := newTestEnv(t, dbPath, mockCCInfoProvider)
+	mgr := env.mgr
+	defer env.cleanup()
+
+	explicitCollConfigPkg := sampleCollectionConfigPackage("explicit-coll", 10)
+	testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(&mockCCInfoProvider.DeployedChaincodeInfoProvider, "chaincode1", explicitCollConfigPkg)
+	assert.NoError(t, mgr.HandleStateUpdates(&ledger.StateUpdateTrigger{
+		LedgerID:           "ledger1",
+		CommittingBlockNum: 10},
+	))
+	implicitColls := []*common.StaticCollectionConfig{
+		sampleImplicitCollection("org1"),
+		sampleImplicitCollection("org2"),
+	}
+	mockCCInfoProvider.ImplicitCollectionsReturns(implicitColls, nil)
+
+	dummyLedgerInfoRetriever := &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 100}}
+	retriever := mgr.GetRetriever("ledger1", dummyLedgerInfoRetriever)
+
+	t.Run("all-implicit-collections", func(t *testing.T) {
+		collConfig, err := retriever.CollectionConfigAt(10, "chaincode1")
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(10), collConfig.CommittingBlockNum)
+		assert.Equal(t, []string{"explicit-coll-10", "_implicit_org_org1", "_implicit_org_org2"}, collNames(collConfig))
+
+		collConfig, err = retriever.MostRecentCollectionConfigBelow(50, "chaincode1")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"explicit-coll-10", "_implicit_org_org1", "_implicit_org_org2"}, collNames(collConfig))
+
+		channel, ccName, _ := mockCCInfoProvider.ImplicitCollectionsArgsForCall(0)
+		assert.Equal(t, "ledger1", channel)
+		assert.Equal(t, "chaincode1", ccName)
+	})
+
+	t.Run("implicit-collections-filtered-by-org", func(t *testing.T) {
+		collConfig, err := retriever.CollectionConfigAtForOrg(10, "chaincode1", "org2")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"explicit-coll-10", "_implicit_org_org2"}, collNames(collConfig))
+
+		collConfig, err = retriever.MostRecentCollectionConfigBelowForOrg(50, "chaincode1", "org3")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"explicit-coll-10"}, collNames(collConfig))
+	})
+
+	t.Run("collections-filtered-by-name", func(t *testing.T) {
+		collConfig, err := retriever.CollectionConfigAtForCollections(10, "chaincode1",
+			[]string{"_implicit_org_org2", "explicit-coll-10", "unknown-coll"})
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(10), collConfig.CommittingBlockNum)
+		assert.Equal(t, []string{"explicit-coll-10", "_implicit_org_org2"}, collNames(collConfig))
+
+		collConfig, err = retriever.MostRecentCollectionConfigBelowForCollections(50, "chaincode1", []string{"_implicit_org_org1"})
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(10), collConfig.CommittingBlockNum)
+		assert.Equal(t, []string{"_implicit_org_org1"}, collNames(collConfig))
+
+		collConfig, err = retriever.MostRecentCollectionConfigBelowForCollections(50, "chaincode1", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"explicit-coll-10", "_implicit_org_org1", "_implicit_org_org2"}, collNames(collConfig))
+
+		// the committing block is reported even if none of the collections is included
+		collConfig, err = retriever.CollectionConfigAtForCollections(10, "chaincode1", []string{})
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(10), collConfig.CommittingBlockNum)
+		assert.Empty(t, collNames(collConfig))
+
+		collConfig, err = retriever.CollectionConfigAtForCollections(20, "chaincode1", []string{"explicit-coll-10"})
+		assert.NoError(t, err)
+		assert.Nil(t, collConfig)
+
+		// the returned collection config is a copy, the unfiltered collection config is not affected
+		collConfig, err = retriever.CollectionConfigAt(10, "chaincode1")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"explicit-coll-10", "_implicit_org_org1", "_implicit_org_org2"}, collNames(collConfig))
+	})
+
+	t.Run("only-implicit-collections", func(t *testing.T) {
+		collConfig, err := retriever.CollectionConfigAtForOrg(20, "chaincode1", "org1")
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(0), collConfig.CommittingBlockNum)
+		assert.Equal(t, []string{"_implicit_org_org1"}, collNames(collConfig))
+
+		collConfig, err = retriever.CollectionConfigAtForOrg(20, "chaincode1", "org3")
+		assert.NoError(t, err)
+		assert.Nil(t, collConfig)
+	})
+
+	t.Run("explicit-collections-only", func(t *testing.T) {
+		collConfig, err := retriever.ExplicitCollectionConfigAt(10, "chaincode1")
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(10), collConfig.CommittingBlockNum)
+		assert.True(t, proto.Equal(explicitCollConfigPkg, collConfig.CollectionConfig))
+
+		collConfig, err = retriever.ExplicitCollectionConfigAt(20, "chaincode1")
+		assert.NoError(t, err)
+		assert.Nil(t, collConfig)
+
+		_, err = retriever.ExplicitCollectionConfigAt(200, "chaincode1")
+		assert.IsType(t, &ledger.ErrCollectionConfigNotYetAvailable{}, err)
+	})
+
+	t.Run("implicit-collections-error", func(t *testing.T) {
+		mockCCInfoProvider.ImplicitCollectionsReturns(nil, errors.New("implicit-collections-error"))
+		_, err := retriever.CollectionConfigAt(10, "chaincode1")
+		assert.EqualError(t, err,
+			"error while retrieving the collection config of chaincode [chaincode1] for block [10] of ledger [ledger1]: implicit-collections-error")
+	})
+}
+
+func TestLenientImplicitCollections(t *testing.T) {
+	mockCCInfoProvider := &implicitCollsCCInfoProvider{}
+	testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(&mockCCInfoProvider.DeployedChaincodeInfoProvider, "chaincode1",
+		sampleCollectionConfigPackage("explicit-coll", 10))
+	dummyLedgerInfoRetriever := &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 100}}
+	m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(NewMemStoreProvider()),
+		WithLenientImplicitCollections())
+	defer m.Close()
+	assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: 10}))
+	retriever := m.GetRetriever("ledger1", dummyLedgerInfoRetriever)
+
+	mockCCInfoProvider.ImplicitCollectionsReturns(nil, errors.New("implicit-collections-error"))
+	collConfig, err := retriever.CollectionConfigAt(10, "chaincode1")
+	assert.NoError(t, err)
+	assert.True(t, collConfig.ImplicitCollectionsIncomplete)
+	assert.Equal(t, uint64(10), collConfig.CommittingBlockNum)
+	assert.Equal(t, []string{"explicit-coll-10"}, collNames(collConfig))
+
+	collConfig, err = retriever.MostRecentCollectionConfigBelowForOrg(50, "chaincode1", "org1")
+	assert.NoError(t, err)
+	assert.True(t, collConfig.ImplicitCollectionsIncomplete)
+	assert.Equal(t, []string{"explicit-coll-10"}, collNames(collConfig))
+
+	// the persisted explicit config is not flagged
+	collConfig, err = retriever.ExplicitCollectionConfigAt(10, "chaincode1")
+	assert.NoError(t, err)
+	assert.False(t, collConfig.ImplicitCollectionsIncomplete)
+
+	// without an explicit config, there is nothing to return in place of the complete config
+	_, err = retriever.CollectionConfigAt(5, "chaincode1")
+	assert.EqualError(t, err,
+		"error while retrieving the collection config of chaincode [chaincode1] for block [5] of ledger [ledger1]: implicit-collections-error")
+
+	// the implicit collections are added once available
+	mockCCInfoProvider.ImplicitCollectionsReturns([]*common.StaticCollectionConfig{sampleImplicitCollection("org1")}, nil)
+	collConfig, err = retriever.CollectionConfigAt(10, "chaincode1")
+	assert.NoError(t, err)
+	assert.False(t, collConfig.ImplicitCollectionsIncomplete)
+	assert.Equal(t, []string{"explicit-coll-10", "_implicit_org_org1"}, collNames(collConfig))
+
+	t.Run("strict-by-default", func(t *testing.T) {
+		m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(NewMemStoreProvider()))
+		defer m.Close()
+		assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: 10}))
+		mockCCInfoProvider.ImplicitCollectionsReturns(nil, errors.New("implicit-collections-error"))
+		_, err := m.GetRetriever("ledger1", dummyLedgerInfoRetriever).CollectionConfigAt(10, "chaincode1")
+		assert.EqualError(t, err,
+			"error while retrieving the collection config of chaincode [chaincode1] for block [10] of ledger [ledger1]: implicit-collections-error")
+	})
+}
+
+func TestMaxImplicitCollections(t *testing.T) {
+	mockCCInfoProvider := &implicitCollsCCInfoProvider{}
+	testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(&mockCCInfoProvider.DeployedChaincodeInfoProvider, "chaincode1",
+		sampleCollectionConfigPackage("explicit-coll", 10))
+	mockCCInfoProvider.ImplicitCollectionsReturns([]*common.StaticCollectionConfig{
+		sampleImplicitCollection("org1"),
+		sampleImplicitCollection("org2"),
+		sampleImplicitCollection("org3"),
+	}, nil)
+	dummyLedgerInfoRetriever := &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 100}}
+
+	testCases := []struct {
+		name              string
+		max               int
+		expectedCollNames []string
+	}{
+		{"no-cap", 0, []string{"explicit-coll-10", "_implicit_org_org1", "_implicit_org_org2", "_implicit_org_org3"}},
+		{"cap-above-count", 5, []string{"explicit-coll-10", "_implicit_org_org1", "_implicit_org_org2", "_implicit_org_org3"}},
+		{"cap-below-count", 2, []string{"explicit-coll-10", "_implicit_org_org1", "_implicit_org_org2"}},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(NewMemStoreProvider()),
+				WithMaxImplicitCollections(testCase.max))
+			defer m.Close()
+			assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: 10}))
+			retriever := m.GetRetriever("ledger1", dummyLedgerInfoRetriever)
+			collConfig, err := retriever.CollectionConfigAt(10, "chaincode1")
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.expectedCollNames, collNames(collConfig))
+		})
+	}
+
+	t.Run("cap-applied-after-org-filter", func(t *testing.T) {
+		m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(NewMemStoreProvider()), WithMaxImplicitCollections(1))
+		defer m.Close()
+		assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: 10}))
+		collConfig, err := m.GetRetriever("ledger1", dummyLedgerInfoRetriever).CollectionConfigAtForOrg(10, "chaincode1", "org3")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"explicit-coll-10", "_implicit_org_org3"}, collNames(collConfig))
+	})
+}
+
+func TestCheckConsistencyWithLedger(t *testing.T) {
+	dbPath := "/tmp/fabric/core/ledger/confighistory"
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	env := newTestEnv(t, dbPath, mockCCInfoProvider)
+	mgr := env.mgr
+	defer env.cleanup()
+
+	dummyLedgerInfoRetriever := &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 0}}
+	retriever := mgr.GetRetriever("ledger1", dummyLedgerInfoRetriever)
+	assert.NoError(t, retriever.CheckConsistencyWithLedger())
+
+	for _, blockNum := range []uint64{10, 50} {
+		testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, "chaincode1",
+			sampleCollectionConfigPackage("coll", blockNum))
+		assert.NoError(t, mgr.HandleStateUpdates(&ledger.StateUpdateTrigger{
+			LedgerID:           "ledger1",
+			CommittingBlockNum: blockNum},
+		))
+	}
+
+	dummyLedgerInfoRetriever.info.Height = 51
+	assert.NoError(t, retriever.CheckConsistencyWithLedger())
+
+	dummyLedgerInfoRetriever.info.Height = 50
+	assert.EqualError(t, retriever.CheckConsistencyWithLedger(),
+		"config history for ledger [ledger1] contains an entry for block [50] which is beyond the ledger height [50]")
+
+	dummyLedgerInfoRetriever.info.Height = 0
+	assert.EqualError(t, retriever.CheckConsistencyWithLedger(),
+		"config history for ledger [ledger1] contains an entry for block [50] which is beyond the ledger height [0]")
+}
+
+func TestNoChaincodeUpdated(t *testing.T) {
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	mockCCInfoProvider.UpdatedChaincodesReturns(nil, nil)
+	trigger := &ledger.StateUpdateTrigger{
+		LedgerID: "ledger1",
+		StateUpdates: ledger.StateUpdates{
+			"lscc": []*kvrwset.KVWrite{
+				{Key: "key1", Value: []byte("value1")},
+				{Key: "key2", Value: []byte("value2")},
+				{Key: "key3", IsDelete: true},
+			},
+		},
+		CommittingBlockNum: 10,
+	}
+
+	t.Run("ignored-by-default", func(t *testing.T) {
+		m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(NewMemStoreProvider()))
+		defer m.Close()
+		assert.NoError(t, m.HandleStateUpdates(trigger))
+		empty, err := m.dbProvider.getDB("ledger1").isEmpty()
+		assert.NoError(t, err)
+		assert.True(t, empty)
+	})
+
+	t.Run("strict-mode", func(t *testing.T) {
+		m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(NewMemStoreProvider()), withStrictStateUpdates())
+		defer m.Close()
+		assert.EqualError(t, m.HandleStateUpdates(trigger),
+			"none of the chaincodes is updated by the state updates of block [10] of ledger [ledger1], writes = [lscc: 2 writes, 1 deletes]")
+	})
+}
+
+func TestMonotonicityCheck(t *testing.T) {
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, "chaincode1", sampleCollectionConfigPackage("coll", 1))
+	commit := func(m *mgr, blockNum uint64) error {
+		return m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: blockNum})
+	}
+
+	t.Run("enabled", func(t *testing.T) {
+		m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(NewMemStoreProvider()), WithMonotonicityCheck(true))
+		defer m.Close()
+		assert.NoError(t, commit(m, 10))
+		assert.NoError(t, commit(m, 20))
+		expectedErr := "collection config of chaincode [chaincode1] for block [%d] of ledger [ledger1] is not above the most recent " +
+			"collection config of the chaincode, committed at block [20]"
+		// the collection config already recorded for the block is skipped before the check
+		assert.NoError(t, commit(m, 20))
+		assert.EqualError(t, commit(m, 15), fmt.Sprintf(expectedErr, 15))
+		blockNums, err := m.GetRetriever("ledger1", &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 100}}).
+			ConfigBlockNumbers("chaincode1")
+		assert.NoError(t, err)
+		assert.Equal(t, []uint64{10, 20}, blockNums)
+		assert.NoError(t, commit(m, 21))
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(NewMemStoreProvider()))
+		defer m.Close()
+		assert.NoError(t, commit(m, 20))
+		assert.NoError(t, commit(m, 15))
+	})
+}
+
+func TestChaincodeInfoErrors(t *testing.T) {
+	dbPath := "/tmp/fabric/core/ledger/confighistory"
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	mockCCInfoProvider.UpdatedChaincodesReturns(
+		[]*ledger.ChaincodeLifecycleInfo{{Name: "chaincode1"}, {Name: "chaincode2"}},
+		nil,
+	)
+	mockCCInfoProvider.ChaincodeInfoStub = func(ccName string, qe ledger.SimpleQueryExecutor) (*ledger.DeployedChaincodeInfo, error) {
+		if ccName == "chaincode1" {
+			return nil, errors.New("chaincode-info-error")
+		}
+		return &ledger.DeployedChaincodeInfo{Name: ccName, CollectionConfigPkg: sampleCollectionConfigPackage(ccName, 10)}, nil
+	}
+	dummyLedgerInfoRetriever := &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 100}}
+
+	t.Run("strict-mode", func(t *testing.T) {
+		env := newTestEnv(t, dbPath, mockCCInfoProvider)
+		defer env.cleanup()
+		err := env.mgr.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: 10})
+		assert.EqualError(t, err, "chaincode-info-error")
+		collConfig, err := env.mgr.GetRetriever("ledger1", dummyLedgerInfoRetriever).CollectionConfigAt(10, "chaincode2")
+		assert.NoError(t, err)
+		assert.Nil(t, collConfig)
+	})
+
+	t.Run("lenient-mode", func(t *testing.T) {
+		deleteTestPath(t, dbPath)
+		m := newMgr(mockCCInfoProvider, dbPath, WithLenientChaincodeInfoLookup())
+		defer deleteTestPath(t, dbPath)
+		defer m.Close()
+		err := m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: 10})
+		assert.NoError(t, err)
+		retriever := m.GetRetriever("ledger1", dummyLedgerInfoRetriever)
+		collConfig, err := retriever.CollectionConfigAt(10, "chaincode1")
+		assert.NoError(t, err)
+		assert.Nil(t, collConfig)
+		collConfig, err = retriever.CollectionConfigAt(10, "chaincode2")
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(10), collConfig.CommittingBlockNum)
+	})
+}
+
+func TestChaincodeNotDeployedErrors(t *testing.T) {
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, "chaincode1",
+		sampleCollectionConfigPackage("coll", 10))
+	dummyLedgerInfoRetriever := &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 100}}
+
+	m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(NewMemStoreProvider()), WithChaincodeNotDeployedErrors())
+	defer m.Close()
+	assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: 10}))
+	mockCCInfoProvider.ChaincodeInfoStub = func(ccName string, qe ledger.SimpleQueryExecutor) (*ledger.DeployedChaincodeInfo, error) {
+		switch ccName {
+		case "chaincode-without-collections":
+			return &ledger.DeployedChaincodeInfo{Name: ccName}, nil
+		case "chaincode-info-error":
+			return nil, errors.New("chaincode-info-error")
+		}
+		return nil, nil
+	}
+	retriever := m.GetRetriever("ledger1", dummyLedgerInfoRetriever)
+
+	t.Run("never-deployed", func(t *testing.T) {
+		_, err := retriever.CollectionConfigAt(50, "unknown-chaincode")
+		assert.Equal(t, &ledger.ErrChaincodeNotDeployed{ChaincodeName: "unknown-chaincode", BlockNum: 50}, err)
+		assert.EqualError(t, err, "chaincode [unknown-chaincode] is not deployed at block [50]")
+		_, err = retriever.MostRecentCollectionConfigBelow(50, "unknown-chaincode")
+		assert.IsType(t, &ledger.ErrChaincodeNotDeployed{}, err)
+		_, _, err = retriever.CollectionConfigWithPrevious(50, "unknown-chaincode")
+		assert.IsType(t, &ledger.ErrChaincodeNotDeployed{}, err)
+	})
+
+	t.Run("deployed", func(t *testing.T) {
+		// a chaincode with a collection config in the history, but not at the block
+		collConfig, err := retriever.CollectionConfigAt(50, "chaincode1")
+		assert.NoError(t, err)
+		assert.Nil(t, collConfig)
+		collConfig, err = retriever.MostRecentCollectionConfigBelow(10, "chaincode1")
+		assert.NoError(t, err)
+		assert.Nil(t, collConfig)
+		// a chaincode that is deployed without collections
+		collConfig, err = retriever.MostRecentCollectionConfigBelow(50, "chaincode-without-collections")
+		assert.NoError(t, err)
+		assert.Nil(t, collConfig)
+	})
+
+	t.Run("chaincode-info-error", func(t *testing.T) {
+		_, err := retriever.CollectionConfigAt(50, "chaincode-info-error")
+		assert.EqualError(t, errors.Cause(err), "chaincode-info-error")
+	})
+
+	t.Run("disabled-by-default", func(t *testing.T) {
+		m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(NewMemStoreProvider()))
+		defer m.Close()
+		collConfig, err := m.GetRetriever("ledger1", dummyLedgerInfoRetriever).CollectionConfigAt(50, "unknown-chaincode")
+		assert.NoError(t, err)
+		assert.Nil(t, collConfig)
+	})
+}
+
+func TestUnexpectedStateUpdatesType(t *testing.T) {
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, "chaincode1",
+		sampleCollectionConfigPackage("coll", 10))
+	m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(NewMemStoreProvider()))
+	defer m.Close()
+
+	trigger := &ledger.StateUpdateTrigger{
+		LedgerID:           "ledger1",
+		StateUpdates:       ledger.StateUpdates{"lscc": map[string][]byte{"chaincode1": []byte("value")}},
+		CommittingBlockNum: 10,
+	}
+	assert.NotPanics(t, func() {
+		err := m.HandleStateUpdates(trigger)
+		assert.EqualError(t, err,
+			"unexpected type [map[string][]uint8] of the state updates for namespace [lscc], expected [[]*kvrwset.KVWrite]")
+	})
+	assert.Equal(t, 0, mockCCInfoProvider.UpdatedChaincodesCallCount())
+	collConfig, err := m.GetRetriever("ledger1", &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 100}}).
+		CollectionConfigAt(10, "chaincode1")
+	assert.NoError(t, err)
+	assert.Nil(t, collConfig)
+}
+
+func TestForEachConfigEntry(t *testing.T) {
+	dbPath := "/tmp/fabric/core/ledger/confighistory"
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	env := newTestEnv(t, dbPath, mockCCInfoProvider)
+	mgr := env.mgr
+	defer env.cleanup()
+
+	for _, ledgerID := range []string{"ledger2", "ledger1"} {
+		for _, ccName := range []string{"chaincode2", "chaincode1"} {
+			for _, blockNum := range []uint64{5, 10} {
+				testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, ccName,
+					sampleCollectionConfigPackage(ledgerID+ccName, blockNum))
+				assert.NoError(t, mgr.HandleStateUpdates(&ledger.StateUpdateTrigger{
+					LedgerID:           ledgerID,
+					CommittingBlockNum: blockNum},
+				))
+			}
+		}
+	}
+
+	var visited []string
+	err := mgr.ForEachConfigEntry(func(ledgerID, chaincodeName string, info *ledger.CollectionConfigInfo) error {
+		assert.True(t, proto.Equal(sampleCollectionConfigPackage(ledgerID+chaincodeName, info.CommittingBlockNum), info.CollectionConfig))
+		visited = append(visited, fmt.Sprintf("%s/%s/%d", ledgerID, chaincodeName, info.CommittingBlockNum))
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t,
+		[]string{
+			"ledger1/chaincode1/10", "ledger1/chaincode1/5", "ledger1/chaincode2/10", "ledger1/chaincode2/5",
+			"ledger2/chaincode1/10", "ledger2/chaincode1/5", "ledger2/chaincode2/10", "ledger2/chaincode2/5",
+		},
+		visited,
+	)
+
+	numVisited := 0
+	err = mgr.ForEachConfigEntry(func(ledgerID, chaincodeName string, info *ledger.CollectionConfigInfo) error {
+		numVisited++
+		if numVisited == 3 {
+			return errors.New("stop-iteration")
+		}
+		return nil
+	})
+	assert.EqualError(t, err, "stop-iteration")
+	assert.Equal(t, 3, numVisited)
+}
+
+func TestLedgersWithHistory(t *testing.T) {
+	dbPath := "/tmp/fabric/core/ledger/confighistory"
+	deleteTestPath(t, dbPath)
+	defer deleteTestPath(t, dbPath)
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, "chaincode1",
+		sampleCollectionConfigPackage("coll", 10))
+
+	m := newMgr(mockCCInfoProvider, dbPath)
+	for _, ledgerID := range []string{"ledger2", "ledger10", "ledger1"} {
+		assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: ledgerID, CommittingBlockNum: 10}))
+	}
+	m.GetRetriever("ledger3", &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 100}})
+	ledgerIDs, err := m.LedgersWithHistory()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"ledger1", "ledger10", "ledger2"}, ledgerIDs)
+	m.Close()
+
+	// after a restart, the ledgers are found without being opened
+	m = newMgr(mockCCInfoProvider, dbPath)
+	defer m.Close()
+	_, err = m.DeleteChaincodeHistory("ledger10", "chaincode1")
+	assert.NoError(t, err)
+	ledgerIDs, err = m.LedgersWithHistory()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"ledger1", "ledger2"}, ledgerIDs)
+
+	t.Run("store-provider-without-lister", func(t *testing.T) {
+		m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(struct{ StoreProvider }{NewMemStoreProvider()}))
+		defer m.Close()
+		ledgerIDs, err := m.LedgersWithHistory()
+		assert.NoError(t, err)
+		assert.Nil(t, ledgerIDs)
+		assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: 10}))
+		ledgerIDs, err = m.LedgersWithHistory()
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"ledger1"}, ledgerIDs)
+	})
+}
+
+func TestClosedMgr(t *testing.T) {
+	dbPath := "/tmp/fabric/core/ledger/confighistory"
+	deleteTestPath(t, dbPath)
+	defer deleteTestPath(t, dbPath)
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, "chaincode1",
+		sampleCollectionConfigPackage("coll", 10))
+	dummyLedgerInfoRetriever := &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 100}}
+
+	m := newMgr(mockCCInfoProvider, dbPath)
+	assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: 10}))
+	retrieverBeforeClose := m.GetRetriever("ledger1", dummyLedgerInfoRetriever)
+	_, err := retrieverBeforeClose.MostRecentCollectionConfigBelow(50, "chaincode1")
+	assert.NoError(t, err)
+	m.Close()
+	// closing again is a no-op
+	m.Close()
+
+	for _, retriever := range []Retriever{retrieverBeforeClose, m.GetRetriever("ledger1", dummyLedgerInfoRetriever)} {
+		_, err := retriever.MostRecentCollectionConfigBelow(50, "chaincode1")
+		assert.Equal(t, ErrMgrClosed, errors.Cause(err))
+		_, err = retriever.CollectionConfigAt(10, "chaincode1")
+		assert.Equal(t, ErrMgrClosed, errors.Cause(err))
+		_, err = retriever.ConfigBlockNumbers("chaincode1")
+		assert.Equal(t, ErrMgrClosed, errors.Cause(err))
+		_, err = retriever.Snapshot()
+		assert.Equal(t, ErrMgrClosed, errors.Cause(err))
+		_, err = retriever.MostRecentEndorsementPolicyBelow(50, "chaincode1")
+		assert.Equal(t, ErrMgrClosed, errors.Cause(err))
+		_, err = retriever.DetectCollectionRemovals("chaincode1", 5, 50)
+		assert.Equal(t, ErrMgrClosed, errors.Cause(err))
+	}
+	// the scan that serves the most recent entries, such as when there is no latest pointer, reports the closure too
+	_, err = m.dbProvider.getDB("ledger1").mostRecentEntryBelow(50, collectionConfigNamespace, constructCollectionConfigKey("chaincode1"))
+	assert.Equal(t, ErrMgrClosed, errors.Cause(err))
+	err = m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: 20})
+	assert.Equal(t, ErrMgrClosed, errors.Cause(err))
+	_, err = m.ApproximateSize("ledger1")
+	assert.EqualError(t, err, "config history manager is closed")
+}
+
+func TestRetrieverWithEmptyLedger(t *testing.T) {
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(NewMemStoreProvider()))
+	defer m.Close()
+	testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, "chaincode1",
+		sampleCollectionConfigPackage("chaincode1", 0))
+	assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: 0}))
+
+	// a height of zero must not be treated as if the block number math.MaxUint64 is committed
+	retriever := m.GetRetriever("ledger1", &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 0}})
+	for _, blockNum := range []uint64{0, 10, math.MaxUint64} {
+		collConfig, err := retriever.CollectionConfigAt(blockNum, "chaincode1")
+		assert.Nil(t, collConfig)
+		assert.IsType(t, &ledger.ErrCollectionConfigNotYetAvailable{}, err)
+		assert.EqualError(t, err, fmt.Sprintf("No block is committed to the ledger yet, requested block number [%d]", blockNum))
+		_, err = retriever.ChaincodesConfiguredAt(blockNum)
+		assert.IsType(t, &ledger.ErrCollectionConfigNotYetAvailable{}, err)
+	}
+}
+
+func TestCollectionConfigBefore(t *testing.T) {
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(NewMemStoreProvider()))
+	defer m.Close()
+	for _, blockNum := range []uint64{10, 20} {
+		testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, "chaincode1",
+			sampleCollectionConfigPackage("coll", blockNum))
+		assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: blockNum}))
+	}
+	retriever := m.GetRetriever("ledger1", &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 30}})
+
+	testCases := []struct {
+		blockNum              uint64
+		expectedCommittingNum uint64
+		expectNil             bool
+	}{
+		{0, 0, true},
+		{10, 0, true},
+		{11, 10, false},
+		{20, 10, false},
+		{21, 20, false},
+		// the block that is to be committed next
+		{30, 20, false},
+	}
+	for _, testCase := range testCases {
+		collConfig, err := retriever.CollectionConfigBefore(testCase.blockNum, "chaincode1")
+		assert.NoError(t, err)
+		if testCase.expectNil {
+			assert.Nil(t, collConfig, "blockNum=%d", testCase.blockNum)
+			continue
+		}
+		assert.Equal(t, testCase.expectedCommittingNum, collConfig.CommittingBlockNum, "blockNum=%d", testCase.blockNum)
+	}
+
+	_, err := retriever.CollectionConfigBefore(31, "chaincode1")
+	assert.Equal(t, &ledger.ErrCollectionConfigNotYetAvailable{MaxBlockNumCommitted: 29,
+		Msg: "The next block to be committed [30] is less than the requested block number [31]"}, err)
+
+	retriever = m.GetRetriever("ledger1", &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 0}})
+	collConfig, err := retriever.CollectionConfigBefore(0, "chaincode1")
+	assert.NoError(t, err)
+	assert.Nil(t, collConfig)
+	_, err = retriever.CollectionConfigBefore(1, "chaincode1")
+	assert.EqualError(t, err, "No block is committed to the ledger yet, requested block number [1]")
+}
+
+func TestRetrieverErrorContext(t *testing.T) {
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(NewMemStoreProvider()))
+	defer m.Close()
+	batch := newBatch()
+	batch.add(collectionConfigNamespace, constructCollectionConfigKey("chaincode1"), 10, []byte("garbage"))
+	assert.NoError(t, m.dbProvider.getDB("ledger1").writeBatch(batch, true))
+	retriever := m.GetRetriever("ledger1", &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 100}})
+
+	_, err := retriever.CollectionConfigAt(10, "chaincode1")
+	assert.Contains(t, err.Error(), "error while retrieving the collection config of chaincode [chaincode1] for block [10] of ledger [ledger1]: ")
+	_, err = retriever.MostRecentCollectionConfigBelow(50, "chaincode1")
+	assert.Contains(t, err.Error(), "error while retrieving the collection config of chaincode [chaincode1] for block [50] of ledger [ledger1]: ")
+	_, err = retriever.ExplicitCollectionConfigAt(10, "chaincode1")
+	assert.Contains(t, err.Error(), "error while retrieving the collection config of chaincode [chaincode1] for block [10] of ledger [ledger1]: ")
+	_, _, err = retriever.CollectionConfigWithPrevious(50, "chaincode1")
+	assert.Contains(t, err.Error(), "error while retrieving the collection config of chaincode [chaincode1] for block [50] of ledger [ledger1]: ")
+
+	// the typed errors are not wrapped
+	_, err = retriever.CollectionConfigAt(200, "chaincode1")
+	assert.IsType(t, &ledger.ErrCollectionConfigNotYetAvailable{}, err)
+	_, err = retriever.ExplicitCollectionConfigAt(200, "chaincode1")
+	assert.IsType(t, &ledger.ErrCollectionConfigNotYetAvailable{}, err)
+	_, _, err = retriever.CollectionConfigWithPrevious(200, "chaincode1")
+	assert.IsType(t, &ledger.ErrCollectionConfigNotYetAvailable{}, err)
+}
+
+func TestChaincodesConfiguredAt(t *testing.T) {
+	dbPath := "/tmp/fabric/core/ledger/confighistory"
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	env := newTestEnv(t, dbPath, mockCCInfoProvider)
+	mgr := env.mgr
+	defer env.cleanup()
+
+	updates := map[uint64][]string{
+		5:  {"chaincode1", "chaincode2"},
+		10: {"chaincode2"},
+		15: {"chaincode1", "chaincode1a", "chaincode3"},
+	}
+	for blockNum, ccNames := range updates {
+		for _, ccName := range ccNames {
+			testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, ccName,
+				sampleCollectionConfigPackage(ccName, blockNum))
+			assert.NoError(t, mgr.HandleStateUpdates(&ledger.StateUpdateTrigger{
+				LedgerID:           "ledger1",
+				CommittingBlockNum: blockNum},
+			))
+		}
+	}
+	// an entry in a different ledger should not be reported
+	testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, "chaincode4",
+		sampleCollectionConfigPackage("chaincode4", 10))
+	assert.NoError(t, mgr.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger2", CommittingBlockNum: 10}))
+
+	retriever := mgr.GetRetriever("ledger1", &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 20}})
+	expected := map[uint64][]string{
+		5:  {"chaincode1", "chaincode2"},
+		10: {"chaincode2"},
+		12: nil,
+		15: {"chaincode1", "chaincode1a", "chaincode3"},
+	}
+	for blockNum, expectedCCNames := range expected {
+		ccNames, err := retriever.ChaincodesConfiguredAt(blockNum)
+		assert.NoError(t, err)
+		assert.Equal(t, expectedCCNames, ccNames)
+	}
+
+	_, err := retriever.ChaincodesConfiguredAt(20)
+	assert.IsType(t, &ledger.ErrCollectionConfigNotYetAvailable{}, err)
+}
+
+func TestAllCollectionConfigsAt(t *testing.T) {
+	dbPath := "/tmp/fabric/core/ledger/confighistory"
+	mockCCInfoProvider := &implicitCollsCCInfoProvider{}
+	env := newTestEnv(t, dbPath, mockCCInfoProvider)
+	mgr := env.mgr
+	defer env.cleanup()
+
+	updates := map[uint64][]string{
+		5:  {"chaincode1", "chaincode2"},
+		15: {"chaincode1", "chaincode3"},
+	}
+	for blockNum, ccNames := range updates {
+		for _, ccName := range ccNames {
+			testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(&mockCCInfoProvider.DeployedChaincodeInfoProvider, ccName,
+				sampleCollectionConfigPackage(ccName, blockNum))
+			assert.NoError(t, mgr.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: blockNum}))
+		}
+	}
+	mockCCInfoProvider.ImplicitCollectionsReturns([]*common.StaticCollectionConfig{sampleImplicitCollection("org1")}, nil)
+
+	retriever := mgr.GetRetriever("ledger1", &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 20}})
+	collConfigs, err := retriever.AllCollectionConfigsAt(15)
+	assert.NoError(t, err)
+	assert.Len(t, collConfigs, 2)
+	for _, ccName := range []string{"chaincode1", "chaincode3"} {
+		expected, err := retriever.CollectionConfigAt(15, ccName)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, collConfigs[ccName])
+		assert.Equal(t, []string{ccName + "-15", "_implicit_org_org1"}, collNames(collConfigs[ccName]))
+	}
+
+	collConfigs, err = retriever.AllCollectionConfigsAt(10)
+	assert.NoError(t, err)
+	assert.Empty(t, collConfigs)
+
+	_, err = retriever.AllCollectionConfigsAt(20)
+	assert.IsType(t, &ledger.ErrCollectionConfigNotYetAvailable{}, err)
+
+	mockCCInfoProvider.ImplicitCollectionsReturns(nil, errors.New("implicit-collections-error"))
+	_, err = retriever.AllCollectionConfigsAt(5)
+	assert.Contains(t, err.Error(), "implicit-collections-error")
+}
+
+func TestFindChaincodesByPrefix(t *testing.T) {
+	dbPath := "/tmp/fabric/core/ledger/confighistory"
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	env := newTestEnv(t, dbPath, mockCCInfoProvider)
+	mgr := env.mgr
+	defer env.cleanup()
+
+	ccNames := []string{"mycc", "mycc1", "mycc~collection", "mycc%", "othercc"}
+	for i, ccName := range ccNames {
+		for _, blockNum := range []uint64{uint64(i + 1), uint64(i + 10)} {
+			testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, ccName,
+				sampleCollectionConfigPackage(ccName, blockNum))
+			assert.NoError(t, mgr.HandleStateUpdates(&ledger.StateUpdateTrigger{
+				LedgerID:           "ledger1",
+				CommittingBlockNum: blockNum},
+			))
+		}
+	}
+	testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, "mycc2",
+		sampleCollectionConfigPackage("mycc2", 10))
+	assert.NoError(t, mgr.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger2", CommittingBlockNum: 10}))
+
+	retriever := mgr.GetRetriever("ledger1", &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 20}})
+	expected := map[string][]string{
+		"":                {"mycc", "mycc%", "mycc1", "mycc~collection", "othercc"},
+		"my":              {"mycc", "mycc%", "mycc1", "mycc~collection"},
+		"mycc":            {"mycc", "mycc%", "mycc1", "mycc~collection"},
+		"mycc~":           {"mycc~collection"},
+		"mycc%":           {"mycc%"},
+		"mycc1":           {"mycc1"},
+		"mycc2":           nil,
+		"other":           {"othercc"},
+		"mycc~collection": {"mycc~collection"},
+		"x":               nil,
+	}
+	for prefix, expectedCCNames := range expected {
+		ccNames, err := retriever.FindChaincodesByPrefix(prefix)
+		assert.NoError(t, err)
+		assert.Equal(t, expectedCCNames, ccNames, "prefix [%s]", prefix)
+	}
+}
+
+func TestSameChaincodeNameInMultipleNamespaces(t *testing.T) {
+	ccInfoProvider := &multiNamespaceCCInfoProvider{}
+	ccInfoProvider.NamespacesReturns([]string{"lscc", "_lifecycle"})
+	m := newMgrWithDBProvider(ccInfoProvider, newDBProviderWithStore(NewMemStoreProvider()), WithCacheSize(10))
+	defer m.Close()
+
+	state := &replayedState{kvs: map[string]map[string][]byte{}}
+	commit := func(blockNum uint64, configs map[string]*common.CollectionConfigPackage) {
+		updates := ledger.StateUpdates{}
+		for ns, config := range configs {
+			configBytes, err := proto.Marshal(config)
+			assert.NoError(t, err)
+			updates[ns] = []*kvrwset.KVWrite{{Key: "mycc~collection", Value: configBytes}}
+		}
+		assert.NoError(t, state.apply(updates))
+		assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{
+			LedgerID:                "ledger1",
+			StateUpdates:            updates,
+			CommittingBlockNum:      blockNum,
+			PostCommitQueryExecutor: state,
+		}))
+	}
+	commit(10, map[string]*common.CollectionConfigPackage{
+		"lscc":       sampleCollectionConfigPackage("lscc-coll", 10),
+		"_lifecycle": sampleCollectionConfigPackage("lifecycle-coll", 10),
+	})
+	commit(20, map[string]*common.CollectionConfigPackage{
+		"_lifecycle": sampleCollectionConfigPackage("lifecycle-coll", 20),
+	})
+
+	ledgerInfoRetriever := &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 30}}
+	lsccRetriever := m.GetRetriever("ledger1", ledgerInfoRetriever)
+	lifecycleRetriever := m.GetRetrieverForNamespace("ledger1", "_lifecycle", ledgerInfoRetriever)
+
+	collConfig, err := lsccRetriever.CollectionConfigAt(10, "mycc")
+	assert.NoError(t, err)
+	assert.True(t, proto.Equal(sampleCollectionConfigPackage("lscc-coll", 10), collConfig.CollectionConfig))
+	collConfig, err = lsccRetriever.CollectionConfigAt(20, "mycc")
+	assert.NoError(t, err)
+	assert.Nil(t, collConfig)
+	collConfig, err = lsccRetriever.MostRecentCollectionConfigBelow(30, "mycc")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(10), collConfig.CommittingBlockNum)
+	assert.True(t, proto.Equal(sampleCollectionConfigPackage("lscc-coll", 10), collConfig.CollectionConfig))
+
+	collConfig, err = lifecycleRetriever.CollectionConfigAt(10, "mycc")
+	assert.NoError(t, err)
+	assert.True(t, proto.Equal(sampleCollectionConfigPackage("lifecycle-coll", 10), collConfig.CollectionConfig))
+	collConfig, err = lifecycleRetriever.MostRecentCollectionConfigBelow(30, "mycc")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(20), collConfig.CommittingBlockNum)
+	assert.True(t, proto.Equal(sampleCollectionConfigPackage("lifecycle-coll", 20), collConfig.CollectionConfig))
+
+	ccNames, err := lsccRetriever.ChaincodesConfiguredAt(20)
+	assert.NoError(t, err)
+	assert.Nil(t, ccNames)
+	ccNames, err = lifecycleRetriever.ChaincodesConfiguredAt(20)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"mycc"}, ccNames)
+	ccNames, err = lifecycleRetriever.FindChaincodesByPrefix("my")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"mycc"}, ccNames)
+
+	page, err := lifecycleRetriever.AllCollectionConfigs("mycc", 0, "", NewestFirst)
+	assert.NoError(t, err)
+	assert.Len(t, page.Configs, 2)
+	page, err = lsccRetriever.AllCollectionConfigs("mycc", 0, "", NewestFirst)
+	assert.NoError(t, err)
+	assert.Len(t, page.Configs, 1)
+}
+
+func TestTrackedNamespaces(t *testing.T) {
+	ccInfoProvider := &multiNamespaceCCInfoProvider{}
+	ccInfoProvider.NamespacesReturns([]string{"lscc", "_lifecycle"})
+
+	t.Run("all-namespaces-by-default", func(t *testing.T) {
+		m := newMgrWithDBProvider(ccInfoProvider, newDBProviderWithStore(NewMemStoreProvider()), WithTrackedNamespaces(nil))
+		defer m.Close()
+		assert.Equal(t, []string{"lscc", "_lifecycle"}, m.InterestedInNamespaces())
+	})
+
+	m := newMgrWithDBProvider(ccInfoProvider, newDBProviderWithStore(NewMemStoreProvider()),
+		WithTrackedNamespaces([]string{"_lifecycle", "unknown-namespace"}))
+	defer m.Close()
+	assert.Equal(t, []string{"_lifecycle"}, m.InterestedInNamespaces())
+
+	state := &replayedState{kvs: map[string]map[string][]byte{}}
+	updates := ledger.StateUpdates{}
+	for _, ns := range []string{"lscc", "_lifecycle"} {
+		configBytes, err := proto.Marshal(sampleCollectionConfigPackage(ns+"-coll", 10))
+		assert.NoError(t, err)
+		updates[ns] = []*kvrwset.KVWrite{{Key: "mycc~collection", Value: configBytes}}
+	}
+	assert.NoError(t, state.apply(updates))
+	assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{
+		LedgerID:                "ledger1",
+		StateUpdates:            updates,
+		CommittingBlockNum:      10,
+		PostCommitQueryExecutor: state,
+	}))
+	// the updates that touch only the untracked namespaces are ignored, including the ones without any writes
+	assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{
+		LedgerID:                "ledger1",
+		StateUpdates:            ledger.StateUpdates{"lscc": updates["lscc"]},
+		CommittingBlockNum:      20,
+		PostCommitQueryExecutor: state,
+	}))
+	assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{
+		LedgerID:                "ledger1",
+		CommittingBlockNum:      30,
+		PostCommitQueryExecutor: state,
+	}))
+
+	ledgerInfoRetriever := &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 40}}
+	page, err := m.GetRetriever("ledger1", ledgerInfoRetriever).AllCollectionConfigs("mycc", 0, "", NewestFirst)
+	assert.NoError(t, err)
+	assert.Empty(t, page.Configs)
+	page, err = m.GetRetrieverForNamespace("ledger1", "_lifecycle", ledgerInfoRetriever).AllCollectionConfigs("mycc", 0, "", NewestFirst)
+	assert.NoError(t, err)
+	assert.Len(t, page.Configs, 1)
+	assert.True(t, proto.Equal(sampleCollectionConfigPackage("_lifecycle-coll", 10), page.Configs[0].CollectionConfig))
+}
+
+// implicitCollsCCInfoProvider is a mock chaincode info provider that also supplies the implicit collections
+type implicitCollsCCInfoProvider struct {
+	mock.DeployedChaincodeInfoProvider
+	chmock.ImplicitCollectionsProvider
+}
+
+// multiNamespaceCCInfoProvider maintains the chaincodes in multiple namespaces. For a chaincode, the collection config
+// is read from the key "<chaincode>~collection" in the namespace in which the chaincode is deployed
+type multiNamespaceCCInfoProvider struct {
+	mock.DeployedChaincodeInfoProvider
+}
+
+func (p *multiNamespaceCCInfoProvider) UpdatedChaincodes(stateUpdates map[string][]*kvrwset.KVWrite) ([]*ledger.ChaincodeLifecycleInfo, error) {
+	var lifecycleInfo []*ledger.ChaincodeLifecycleInfo
+	for _, kvWrites := range stateUpdates {
+		for _, kvWrite := range kvWrites {
+			lifecycleInfo = append(lifecycleInfo, &ledger.ChaincodeLifecycleInfo{Name: strings.TrimSuffix(kvWrite.Key, "~collection")})
+		}
+	}
+	return lifecycleInfo, nil
+}
+
+func (p *multiNamespaceCCInfoProvider) ChaincodeInfoInNamespace(namespace, chaincodeName string, qe ledger.SimpleQueryExecutor) (*ledger.DeployedChaincodeInfo, error) {
+	configBytes, err := qe.GetState(namespace, chaincodeName+"~collection")
+	if err != nil || configBytes == nil {
+		return &ledger.DeployedChaincodeInfo{Name: chaincodeName}, err
+	}
+	collConfigPkg := &common.CollectionConfigPackage{}
+	if err := proto.Unmarshal(configBytes, collConfigPkg); err != nil {
+		return nil, err
+	}
+	return &ledger.DeployedChaincodeInfo{Name: chaincodeName, CollectionConfigPkg: collConfigPkg}, nil
+}
+
+func TestCollectionConfigKey(t *testing.T) {
+	// the keys of the names allowed by the chaincode lifecycle are same as in version 1.2
+	assert.Equal(t, "mycc~collection", constructCollectionConfigKey("mycc"))
+	assert.Equal(t, "my-cc_1~collection", constructCollectionConfigKey("my-cc_1"))
+
+	adversarialNames := []string{
+		"", "~", "~collection", "cc~collection", "cc~collection~collection", "cc~", "%", "%7E", "%25", "cc%7E",
+		"cc\x00", "\x00~collection", "cc~collectionX",
+	}
+	keys := map[string]string{}
+	for _, name := range adversarialNames {
+		key := constructCollectionConfigKey(name)
+		assert.Equal(t, 1, strings.Count(key, "~"), "key [%s] of chaincode [%s] contains a tilde outside the suffix", key, name)
+		assert.NotContains(t, key, "\x00")
+		decodedName, ok := chaincodeNameFromCollectionConfigKey(collectionConfigNamespace, key)
+		assert.True(t, ok)
+		assert.Equal(t, name, decodedName)
+		otherName, ok := keys[key]
+		assert.False(t, ok, "chaincodes [%s] and [%s] have the same key", name, otherName)
+		keys[key] = name
+	}
+	for key1, name1 := range keys {
+		for key2, name2 := range keys {
+			if name1 != name2 {
+				assert.False(t, strings.HasPrefix(key2, key1), "key of chaincode [%s] is a prefix of the key of chaincode [%s]", name1, name2)
+			}
+		}
+	}
+
+	_, ok := chaincodeNameFromCollectionConfigKey("another-ns", "cc~collection")
+	assert.False(t, ok)
+	_, ok = chaincodeNameFromCollectionConfigKey(collectionConfigNamespace, "cc")
+	assert.False(t, ok)
+}
+
+func TestMgrWithAdversarialChaincodeNames(t *testing.T) {
+	dbPath := "/tmp/fabric/core/ledger/confighistory"
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	env := newTestEnv(t, dbPath, mockCCInfoProvider)
+	mgr := env.mgr
+	defer env.cleanup()
+
+	ccNames := []string{"cc", "cc~collection", "cc~collectionX", "cc%7E", "cc~"}
+	for i, ccName := range ccNames {
+		blockNum := uint64(10 * (i + 1))
+		testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, ccName,
+			sampleCollectionConfigPackage(ccName, blockNum))
+		assert.NoError(t, mgr.HandleStateUpdates(&ledger.StateUpdateTrigger{
+			LedgerID:           "ledger1",
+			CommittingBlockNum: blockNum},
+		))
+	}
+
+	retriever := mgr.GetRetriever("ledger1", &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 100}})
+	for i, ccName := range ccNames {
+		blockNum := uint64(10 * (i + 1))
+		collConfig, err := retriever.MostRecentCollectionConfigBelow(math.MaxUint64, ccName)
+		assert.NoError(t, err)
+		assert.Equal(t, blockNum, collConfig.CommittingBlockNum)
+		assert.True(t, proto.Equal(sampleCollectionConfigPackage(ccName, blockNum), collConfig.CollectionConfig))
+
+		configuredCCs, err := retriever.ChaincodesConfiguredAt(blockNum)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{ccName}, configuredCCs)
+	}
+}
+
+func TestPrepareDBBatchIsDeterministic(t *testing.T) {
+	collConfigPkg := sampleCollectionConfigPackage("coll", 10)
+	collConfigPkg.Config = append(collConfigPkg.Config, &common.CollectionConfig{
+		Payload: &common.CollectionConfig_StaticCollectionConfig{StaticCollectionConfig: sampleImplicitCollection("org1")},
+	})
+	bytes1, err := marshalDeterministically(collConfigPkg)
+	assert.NoError(t, err)
+	bytes2, err := marshalDeterministically(proto.Clone(collConfigPkg))
+	assert.NoError(t, err)
+	assert.Equal(t, bytes1, bytes2)
+
+	batch1, err := prepareDBBatch(map[string][]*ledger.DeployedChaincodeInfo{
+		"lscc": {{Name: "chaincode1", CollectionConfigPkg: collConfigPkg}},
+	}, 10, 0, false)
+	assert.NoError(t, err)
+	batch2, err := prepareDBBatch(map[string][]*ledger.DeployedChaincodeInfo{
+		"lscc": {{Name: "chaincode1", CollectionConfigPkg: proto.Clone(collConfigPkg).(*common.CollectionConfigPackage)}},
+	}, 10, 0, false)
+	assert.NoError(t, err)
+	assert.Equal(t, batch1.KVs, batch2.KVs)
+
+	// the values written by the non-deterministic marshalling decode the same way
+	nonDeterministicBytes, err := proto.Marshal(collConfigPkg)
+	assert.NoError(t, err)
+	for _, value := range [][]byte{bytes1, nonDeterministicBytes} {
+		info, err := compositeKVToCollectionConfig(&compositeKV{&compositeKey{blockNum: 10}, value})
+		assert.NoError(t, err)
+		assert.True(t, proto.Equal(collConfigPkg, info.CollectionConfig))
+	}
+}
+
+func TestConflictingCollectionConfigs(t *testing.T) {
+	t.Run("prepare-batch", func(t *testing.T) {
+		ccInfos := []*ledger.DeployedChaincodeInfo{
+			{Name: "chaincode1", CollectionConfigPkg: sampleCollectionConfigPackage("coll", 10)},
+			{Name: "chaincode2", CollectionConfigPkg: sampleCollectionConfigPackage("coll", 10)},
+			// a duplicate entry with the same config is tolerated
+			{Name: "chaincode1", CollectionConfigPkg: sampleCollectionConfigPackage("coll", 10)},
+		}
+		batch, err := prepareDBBatch(map[string][]*ledger.DeployedChaincodeInfo{"lscc": ccInfos}, 10, 0, false)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, batch.Len())
+
+		// same named chaincodes in different namespaces do not conflict
+		batch, err = prepareDBBatch(map[string][]*ledger.DeployedChaincodeInfo{
+			"lscc":       ccInfos,
+			"_lifecycle": {{Name: "chaincode2", CollectionConfigPkg: sampleCollectionConfigPackage("coll", 11)}},
+		}, 10, 0, false)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, batch.Len())
+
+		ccInfos = append(ccInfos, &ledger.DeployedChaincodeInfo{Name: "chaincode2", CollectionConfigPkg: sampleCollectionConfigPackage("coll", 11)})
+		_, err = prepareDBBatch(map[string][]*ledger.DeployedChaincodeInfo{"lscc": ccInfos}, 10, 0, false)
+		assert.EqualError(t, err, "conflicting collection configs for chaincode [chaincode2] (key [chaincode2~collection]) in block [10]")
+	})
+
+	t.Run("handle-state-updates", func(t *testing.T) {
+		dbPath := "/tmp/fabric/core/ledger/confighistory"
+		mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+		env := newTestEnv(t, dbPath, mockCCInfoProvider)
+		defer env.cleanup()
+		mockCCInfoProvider.UpdatedChaincodesReturns(
+			[]*ledger.ChaincodeLifecycleInfo{{Name: "chaincode1"}, {Name: "chaincode1"}},
+			nil,
+		)
+		mockCCInfoProvider.ChaincodeInfoReturnsOnCall(0,
+			&ledger.DeployedChaincodeInfo{Name: "chaincode1", CollectionConfigPkg: sampleCollectionConfigPackage("coll", 1)}, nil)
+		mockCCInfoProvider.ChaincodeInfoReturnsOnCall(1,
+			&ledger.DeployedChaincodeInfo{Name: "chaincode1", CollectionConfigPkg: sampleCollectionConfigPackage("coll", 2)}, nil)
+		err := env.mgr.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: 10})
+		assert.EqualError(t, err, "conflicting collection configs for chaincode [chaincode1] (key [chaincode1~collection]) in block [10]")
+
+		collConfig, err := env.mgr.GetRetriever("ledger1", &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 100}}).
+			CollectionConfigAt(10, "chaincode1")
+		assert.NoError(t, err)
+		assert.Nil(t, collConfig)
+	})
+}
+
+func TestMaxCollectionConfigSize(t *testing.T) {
+	oversizedPkg := sampleCollectionConfigPackage(strings.Repeat("x", 2000), 10)
+	oversizedBytes, err := marshalDeterministically(oversizedPkg)
+	assert.NoError(t, err)
+	ccInfos := []*ledger.DeployedChaincodeInfo{
+		{Name: "chaincode1", CollectionConfigPkg: sampleCollectionConfigPackage("coll", 10)},
+		{Name: "chaincode2", CollectionConfigPkg: oversizedPkg},
+	}
+
+	t.Run("prepare-batch", func(t *testing.T) {
+		_, err := prepareDBBatch(map[string][]*ledger.DeployedChaincodeInfo{"lscc": ccInfos}, 10, 1024, false)
+		assert.EqualError(t, err, fmt.Sprintf("size [%d bytes] of the collection config for chaincode [chaincode2] in block [10] exceeds the maximum allowed size [1024 bytes]",
+			len(oversizedBytes)))
+		batch, err := prepareDBBatch(map[string][]*ledger.DeployedChaincodeInfo{"lscc": ccInfos}, 10, len(oversizedBytes), false)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, batch.Len())
+		batch, err = prepareDBBatch(map[string][]*ledger.DeployedChaincodeInfo{"lscc": ccInfos}, 10, 0, false)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, batch.Len())
+	})
+
+	t.Run("handle-state-updates", func(t *testing.T) {
+		mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+		mockCCInfoProvider.UpdatedChaincodesReturns([]*ledger.ChaincodeLifecycleInfo{{Name: "chaincode1"}, {Name: "chaincode2"}}, nil)
+		mockCCInfoProvider.ChaincodeInfoReturnsOnCall(0, ccInfos[0], nil)
+		mockCCInfoProvider.ChaincodeInfoReturnsOnCall(1, ccInfos[1], nil)
+		m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(NewMemStoreProvider()), WithMaxCollectionConfigSize(1024))
+		defer m.Close()
+
+		err := m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: 10})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "exceeds the maximum allowed size [1024 bytes]")
+		// none of the collection configs of the block are persisted
+		empty, err := m.dbProvider.getDB("ledger1").isEmpty()
+		assert.NoError(t, err)
+		assert.True(t, empty)
+	})
+}
+
+func TestMgrClock(t *testing.T) {
+	dbPath := "/tmp/fabric/core/ledger/confighistory"
+	defer os.RemoveAll(dbPath)
+
+	m := newMgr(&mock.DeployedChaincodeInfoProvider{}, dbPath)
+	assert.Equal(t, wallClock{}, m.clock)
+	m.Close()
+
+	fixedTime := time.Unix(1000, 0)
+	m = newMgr(&mock.DeployedChaincodeInfoProvider{}, dbPath, withClock(&fakeClock{now: fixedTime}))
+	defer m.Close()
+	assert.Equal(t, fixedTime, m.clock.Now())
 }
 
 type testEnv struct {
@@ -157,10 +1331,66 @@ func testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(
 	)
 }
 
+func sampleImplicitCollection(mspID string) *common.StaticCollectionConfig {
+	return &common.StaticCollectionConfig{
+		Name: "_implicit_org_" + mspID,
+		MemberOrgsPolicy: &common.CollectionPolicyConfig{
+			Payload: &common.CollectionPolicyConfig_SignaturePolicy{
+				SignaturePolicy: cauthdsl.SignedByMspMember(mspID),
+			},
+		},
+	}
+}
+
+func collNames(collConfigInfo *ledger.CollectionConfigInfo) []string {
+	var names []string
+	for _, collConfig := range collConfigInfo.CollectionConfig.Config {
+		names = append(names, collConfig.GetStaticCollectionConfig().Name)
+	}
+	return names
+}
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
 type dummyLedgerInfoRetriever struct {
-	info *common.BlockchainInfo
+	info   *common.BlockchainInfo
+	blocks map[uint64]*common.Block
 }
 
 func (d *dummyLedgerInfoRetriever) GetBlockchainInfo() (*common.BlockchainInfo, error) {
 	return d.info, nil
 }
+
+func (d *dummyLedgerInfoRetriever) GetBlockByNumber(blockNumber uint64) (*common.Block, error) {
+	block, ok := d.blocks[blockNumber]
+	if !ok {
+		return nil, errors.Errorf("block [%d] not found", blockNumber)
+	}
+	return block, nil
+}
+
+func (d *dummyLedgerInfoRetriever) NewQueryExecutor() (ledger.QueryExecutor, error) {
+	return &dummyQueryExecutor{}, nil
+}
+
+// noQueryExecutorLedgerInfoRetriever fails on any attempt to create a query executor
+type noQueryExecutorLedgerInfoRetriever struct {
+	dummyLedgerInfoRetriever
+}
+
+func (d *noQueryExecutorLedgerInfoRetriever) NewQueryExecutor() (ledger.QueryExecutor, error) {
+	return nil, errors.New("query executor not expected to be created")
+}
+
+type dummyQueryExecutor struct {
+	ledger.QueryExecutor
+}
+
+func (d *dummyQueryExecutor) Done() {
+}
@@ -0,0 +1,62 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/msp"
+	"github.com/pkg/errors"
+)
+
+// memberOrgs returns the MSP IDs of the orgs that are the members of the given collection. The member orgs are
+// extracted from the principals of the signature policy envelope that governs the membership of the collection.
+// Each MSP ID appears only once in the returned list and the order of the first appearance is preserved
+func memberOrgs(collConfig *common.StaticCollectionConfig) ([]string, error) {
+	sigPolicyEnvelope := collConfig.GetMemberOrgsPolicy().GetSignaturePolicy()
+	if sigPolicyEnvelope == nil {
+		return nil, nil
+	}
+	var orgs []string
+	seen := map[string]bool{}
+	for _, principal := range sigPolicyEnvelope.Identities {
+		mspID, err := principalMSPID(principal)
+		if err != nil {
+			return nil, errors.WithMessage(err, "error while extracting member orgs of collection "+collConfig.Name)
+		}
+		if !seen[mspID] {
+			seen[mspID] = true
+			orgs = append(orgs, mspID)
+		}
+	}
+	return orgs, nil
+}
+
+func principalMSPID(principal *msp.MSPPrincipal) (string, error) {
+	switch principal.PrincipalClassification {
+	case msp.MSPPrincipal_ROLE:
+		mspRole := &msp.MSPRole{}
+		if err := proto.Unmarshal(principal.Principal, mspRole); err != nil {
+			return "", errors.Wrap(err, "error unmarshalling MSPRole from principal")
+		}
+		return mspRole.MspIdentifier, nil
+	case msp.MSPPrincipal_IDENTITY:
+		identity := &msp.SerializedIdentity{}
+		if err := proto.Unmarshal(principal.Principal, identity); err != nil {
+			return "", errors.Wrap(err, "error unmarshalling SerializedIdentity from principal")
+		}
+		return identity.Mspid, nil
+	case msp.MSPPrincipal_ORGANIZATION_UNIT:
+		ou := &msp.OrganizationUnit{}
+		if err := proto.Unmarshal(principal.Principal, ou); err != nil {
+			return "", errors.Wrap(err, "error unmarshalling OrganizationUnit from principal")
+		}
+		return ou.MspIdentifier, nil
+	default:
+		return "", errors.Errorf("invalid principal type %d", int32(principal.PrincipalClassification))
+	}
+}
@@ -0,0 +1,49 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/common/cauthdsl"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/msp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemberOrgs(t *testing.T) {
+	identityBytes, err := proto.Marshal(&msp.SerializedIdentity{Mspid: "org3", IdBytes: []byte("cert")})
+	assert.NoError(t, err)
+	ouBytes, err := proto.Marshal(&msp.OrganizationUnit{MspIdentifier: "org4", OrganizationalUnitIdentifier: "ou"})
+	assert.NoError(t, err)
+
+	sigPolicyEnvelope := cauthdsl.SignedByAnyMember([]string{"org1", "org2", "org1"})
+	sigPolicyEnvelope.Identities = append(sigPolicyEnvelope.Identities,
+		&msp.MSPPrincipal{PrincipalClassification: msp.MSPPrincipal_IDENTITY, Principal: identityBytes},
+		&msp.MSPPrincipal{PrincipalClassification: msp.MSPPrincipal_ORGANIZATION_UNIT, Principal: ouBytes},
+	)
+	orgs, err := memberOrgs(coll("coll1", sigPolicyEnvelope, 0))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"org1", "org2", "org3", "org4"}, orgs)
+
+	orgs, err = memberOrgs(&common.StaticCollectionConfig{Name: "coll-without-policy"})
+	assert.NoError(t, err)
+	assert.Nil(t, orgs)
+
+	badEnvelope := &common.SignaturePolicyEnvelope{
+		Identities: []*msp.MSPPrincipal{{PrincipalClassification: msp.MSPPrincipal_ROLE, Principal: []byte("garbage")}},
+	}
+	_, err = memberOrgs(coll("coll2", badEnvelope, 0))
+	assert.Contains(t, err.Error(), "error while extracting member orgs of collection coll2: error unmarshalling MSPRole from principal")
+
+	badEnvelope = &common.SignaturePolicyEnvelope{
+		Identities: []*msp.MSPPrincipal{{PrincipalClassification: msp.MSPPrincipal_ANONYMITY}},
+	}
+	_, err = memberOrgs(coll("coll3", badEnvelope, 0))
+	assert.EqualError(t, err, "error while extracting member orgs of collection coll3: invalid principal type 3")
+}
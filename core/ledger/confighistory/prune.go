@@ -0,0 +1,152 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// PruneAllBelow implements function in the interface 'Mgr'. For each of the ledgers known to the manager
+// that has an entry in the supplied map, the config history is pruned such that the collection configs remain
+// answerable for the blocks at or above the corresponding block number. The returned map contains an entry for
+// each ledger present in the supplied map, with a nil value if the pruning of that ledger succeeded.
+// A failure in pruning one ledger does not stop the pruning of the remaining ledgers; however, a non-nil error
+// is returned if the pruning did not succeed for all the ledgers
+func (m *mgr) PruneAllBelow(boundaries map[string]uint64) (map[string]error, error) {
+	results := map[string]error{}
+	for _, ledgerID := range m.dbProvider.ledgerIDs() {
+		blockNum, ok := boundaries[ledgerID]
+		if !ok {
+			continue
+		}
+		numPruned, err := m.dbProvider.getDB(ledgerID).pruneBelow(blockNum)
+		// the pruning retains the most recent collection config of each chaincode but may raise the oldest one
+		m.blockIndex.invalidate(ledgerID)
+		if err != nil {
+			logger.Warningf("Error while pruning config history below block [%d] for ledger [%s]: %s", blockNum, ledgerID, err)
+			results[ledgerID] = err
+			continue
+		}
+		logger.Infof("Pruned [%d] entries below block [%d] from config history of ledger [%s]", numPruned, blockNum, ledgerID)
+		results[ledgerID] = nil
+	}
+
+	numFailed := 0
+	for ledgerID := range boundaries {
+		if _, ok := results[ledgerID]; !ok {
+			results[ledgerID] = errors.Errorf("ledger [%s] is not known to the config history manager", ledgerID)
+		}
+		if results[ledgerID] != nil {
+			numFailed++
+		}
+	}
+	if numFailed > 0 {
+		return results, errors.Errorf("pruning of config history failed for [%d] out of [%d] ledgers", numFailed, len(boundaries))
+	}
+	return results, nil
+}
+
+// Purge implements function in the interface 'Mgr'. It prunes the config history of the given ledger below the given block, as
+// the function `PruneAllBelow` does for each of the ledgers, and returns the number of the entries deleted. The collection configs
+// therefore remain answerable for the blocks at or above the given block. See function `WithRetention` for purging automatically
+func (m *mgr) Purge(ledgerID string, belowBlockNum uint64) (int, error) {
+	if !m.isKnownLedger(ledgerID) {
+		return 0, errors.Errorf("ledger [%s] is not known to the config history manager", ledgerID)
+	}
+	numPurged, err := m.dbProvider.getDB(ledgerID).pruneBelow(belowBlockNum)
+	m.blockIndex.invalidate(ledgerID)
+	if err != nil {
+		return 0, errors.WithMessage(err, fmt.Sprintf("error while purging config history below block [%d] for ledger [%s]", belowBlockNum, ledgerID))
+	}
+	logger.Debugf("Purged [%d] entries below block [%d] from config history of ledger [%s]", numPurged, belowBlockNum, ledgerID)
+	return numPurged, nil
+}
+
+// purgeBeyondRetention purges the config history of the given ledger that is beyond the retention window (see function `WithRetention`)
+// as of the given committed block. A failure is only logged, as the entries beyond the window are purged again with the next write
+func (m *mgr) purgeBeyondRetention(ledgerID string, blockNum uint64) {
+	if m.retentionBlocks == 0 || blockNum <= m.retentionBlocks {
+		return
+	}
+	if _, err := m.Purge(ledgerID, blockNum-m.retentionBlocks); err != nil {
+		logger.Warningf("Error while applying the retention of [%d] blocks to config history: %s", m.retentionBlocks, err)
+	}
+}
+
+func (m *mgr) isKnownLedger(ledgerID string) bool {
+	for _, id := range m.dbProvider.ledgerIDs() {
+		if id == ledgerID {
+			return true
+		}
+	}
+	return false
+}
+
+// EstimatePruneSavings implements function in the interface 'Mgr'. It returns the number of the entries that the function
+// `PruneAllBelow` would delete from the config history of the given ledger for the given block number, i.e., the entries below
+// the block number other than the most recent entry at or below the block number of each chaincode, and the approximate number of
+// bytes that these occupy, computed as the sum of the sizes of their keys and values. The actual space reclaimed by the store may
+// differ, as it depends on the encoding and the compaction of the store. The config history is only read and hence, this can be
+// called at any time for deciding the retention of the config history
+func (m *mgr) EstimatePruneSavings(ledgerID string, blockNum uint64) (entriesRemovable int, approxBytes uint64, err error) {
+	if m.isKnownLedger(ledgerID) {
+		return m.dbProvider.getDB(ledgerID).prunableSize(blockNum)
+	}
+	return 0, 0, errors.Errorf("ledger [%s] is not known to the config history manager", ledgerID)
+}
+
+// DeleteChaincodeHistory implements function in the interface 'Mgr'. All the collection config entries of the given
+// chaincode in the given ledger are deleted and the number of entries deleted is returned. The entries of the other
+// chaincodes are not affected. The pending asynchronous writes, if any, are applied before the deletion. If the store
+// implements the interface `Compactor`, it is compacted after the deletion
+func (m *mgr) DeleteChaincodeHistory(ledgerID, chaincodeName string) (int, error) {
+	if err := m.WaitForPendingWrites(); err != nil {
+		return 0, err
+	}
+	dbHandle := m.dbProvider.getDB(ledgerID)
+	key := constructCollectionConfigKey(chaincodeName)
+	// the latest pointer is deleted before the entries, so that a crash midway leaves the entries without a pointer, which
+	// the queries handle as the entries written before the pointers were introduced, and never a pointer without the entries.
+	// The pointer is an index over the entries and is not counted as an entry
+	pointerBatch := newBatch()
+	pointerBatch.Delete(encodeCompositeKey(latestPointerNamespace(collectionConfigNamespace), key, 0))
+	if err := dbHandle.writeBatch(pointerBatch, true); err != nil {
+		return 0, err
+	}
+	numDeleted, err := dbHandle.deleteAllEntries(collectionConfigNamespace, key)
+	m.cache.remove(ledgerID, chaincodeName)
+	m.blockIndex.invalidate(ledgerID)
+	if err != nil {
+		return 0, err
+	}
+	numAuthorDeleted, err := dbHandle.deleteAllEntries(authorNamespace, key)
+	if err != nil {
+		return 0, err
+	}
+	numDeleted += numAuthorDeleted
+	numAnnotationsDeleted, err := dbHandle.deleteAllEntries(annotationNamespace, key)
+	if err != nil {
+		return 0, err
+	}
+	numDeleted += numAnnotationsDeleted
+	numPoliciesDeleted, err := dbHandle.deleteAllEntries(endorsementPolicyNamespace, key)
+	if err != nil {
+		return 0, err
+	}
+	numDeleted += numPoliciesDeleted
+	logger.Infof("Deleted [%d] entries of chaincode [%s] from config history of ledger [%s]", numDeleted, chaincodeName, ledgerID)
+	// the space held by the deleted entries is reclaimed right away, if the store supports the compaction. A failure is only
+	// logged, as the entries are deleted already
+	if compactor, ok := dbHandle.Store.(Compactor); ok && numDeleted > 0 {
+		if err := compactor.Compact(); err != nil {
+			logger.Warningf("Error while compacting config history of ledger [%s] after deleting chaincode [%s]: %s", ledgerID, chaincodeName, err)
+		}
+	}
+	return numDeleted, nil
+}
@@ -0,0 +1,241 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/mock"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPruneBelow(t *testing.T) {
+	testDBPath := "/tmp/fabric/core/ledger/confighistory"
+	deleteTestPath(t, testDBPath)
+	provider := newDBProvider(testDBPath)
+	defer deleteTestPath(t, testDBPath)
+	defer provider.Close()
+
+	db := provider.getDB("ledger1")
+	sampleData := []*compositeKV{
+		{&compositeKey{ns: "ns1", key: "key1", blockNum: 40}, []byte("val1_40")},
+		{&compositeKey{ns: "ns1", key: "key1", blockNum: 30}, []byte("val1_30")},
+		{&compositeKey{ns: "ns1", key: "key1", blockNum: 20}, []byte("val1_20")},
+		{&compositeKey{ns: "ns1", key: "key1", blockNum: 10}, []byte("val1_10")},
+		{&compositeKey{ns: "ns1", key: "key2", blockNum: 30}, []byte("val2_30")},
+		{&compositeKey{ns: "ns1", key: "key2", blockNum: 15}, []byte("val2_15")},
+		{&compositeKey{ns: "ns1", key: "key2", blockNum: 5}, []byte("val2_5")},
+	}
+	populateDBWithSampleData(t, db, sampleData)
+
+	numPruned, err := db.pruneBelow(30)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, numPruned)
+	// for key1, the entry at block 30 is retained and for key2, the entry at block 30 is retained
+	checkEntryAt(t, "testcase-prune1", db, "ns1", "key1", 40, sampleData[0])
+	checkEntryAt(t, "testcase-prune2", db, "ns1", "key1", 30, sampleData[1])
+	checkEntryAt(t, "testcase-prune3", db, "ns1", "key1", 20, nil)
+	checkEntryAt(t, "testcase-prune4", db, "ns1", "key1", 10, nil)
+	checkEntryAt(t, "testcase-prune5", db, "ns1", "key2", 30, sampleData[4])
+	checkEntryAt(t, "testcase-prune6", db, "ns1", "key2", 15, nil)
+	checkEntryAt(t, "testcase-prune7", db, "ns1", "key2", 5, nil)
+
+	numPruned, err = db.pruneBelow(35)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, numPruned)
+	checkRecentEntryBelow(t, "testcase-prune8", db, "ns1", "key1", 36, sampleData[1])
+}
+
+func TestPruneAllBelow(t *testing.T) {
+	dbPath := "/tmp/fabric/core/ledger/confighistory"
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	env := newTestEnv(t, dbPath, mockCCInfoProvider)
+	mgr := env.mgr
+	defer env.cleanup()
+
+	for _, ledgerID := range []string{"ledger1", "ledger2"} {
+		for _, blockNum := range []uint64{5, 10, 15} {
+			testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, "chaincode1",
+				sampleCollectionConfigPackage(ledgerID, blockNum))
+			assert.NoError(t, mgr.HandleStateUpdates(&ledger.StateUpdateTrigger{
+				LedgerID:           ledgerID,
+				CommittingBlockNum: blockNum},
+			))
+		}
+	}
+
+	results, err := mgr.PruneAllBelow(map[string]uint64{"ledger1": 12, "ledger2": 15})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]error{"ledger1": nil, "ledger2": nil}, results)
+
+	dummyLedgerInfoRetriever := &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 100}}
+	retriever := mgr.GetRetriever("ledger1", dummyLedgerInfoRetriever)
+	collConfig, err := retriever.CollectionConfigAt(5, "chaincode1")
+	assert.NoError(t, err)
+	assert.Nil(t, collConfig)
+	collConfig, err = retriever.MostRecentCollectionConfigBelow(12, "chaincode1")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(10), collConfig.CommittingBlockNum)
+
+	retriever = mgr.GetRetriever("ledger2", dummyLedgerInfoRetriever)
+	collConfig, err = retriever.CollectionConfigAt(10, "chaincode1")
+	assert.NoError(t, err)
+	assert.Nil(t, collConfig)
+	collConfig, err = retriever.CollectionConfigAt(15, "chaincode1")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(15), collConfig.CommittingBlockNum)
+
+	results, err = mgr.PruneAllBelow(map[string]uint64{"ledger1": 20, "unknown-ledger": 20})
+	assert.EqualError(t, err, "pruning of config history failed for [1] out of [2] ledgers")
+	assert.NoError(t, results["ledger1"])
+	assert.EqualError(t, results["unknown-ledger"], "ledger [unknown-ledger] is not known to the config history manager")
+}
+
+func TestDeleteChaincodeHistory(t *testing.T) {
+	dbPath := "/tmp/fabric/core/ledger/confighistory"
+	deleteTestPath(t, dbPath)
+	defer deleteTestPath(t, dbPath)
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	mgr := newMgr(mockCCInfoProvider, dbPath, WithCacheSize(10))
+	defer mgr.Close()
+
+	ccNames := []string{"chaincode", "chaincode1", "chaincode1a"}
+	for _, ledgerID := range []string{"ledger1", "ledger2"} {
+		for _, ccName := range ccNames {
+			for _, blockNum := range []uint64{5, 10, 15} {
+				testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, ccName,
+					sampleCollectionConfigPackage(ccName, blockNum))
+				assert.NoError(t, mgr.HandleStateUpdates(&ledger.StateUpdateTrigger{
+					LedgerID:           ledgerID,
+					CommittingBlockNum: blockNum},
+				))
+			}
+		}
+	}
+
+	numDeleted, err := mgr.DeleteChaincodeHistory("ledger1", "chaincode1")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, numDeleted)
+	_, ok := mgr.cache.get("ledger1", "chaincode1")
+	assert.False(t, ok)
+
+	dummyLedgerInfoRetriever := &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 100}}
+	for _, ledgerID := range []string{"ledger1", "ledger2"} {
+		retriever := mgr.GetRetriever(ledgerID, dummyLedgerInfoRetriever)
+		for _, ccName := range ccNames {
+			page, err := retriever.AllCollectionConfigs(ccName, 0, "", NewestFirst)
+			assert.NoError(t, err)
+			if ledgerID == "ledger1" && ccName == "chaincode1" {
+				assert.Len(t, page.Configs, 0)
+				collConfig, err := retriever.MostRecentCollectionConfigBelow(100, ccName)
+				assert.NoError(t, err)
+				assert.Nil(t, collConfig)
+				continue
+			}
+			assert.Len(t, page.Configs, 3, "ledger=%s, chaincode=%s", ledgerID, ccName)
+		}
+	}
+
+	numDeleted, err = mgr.DeleteChaincodeHistory("ledger1", "chaincode1")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, numDeleted)
+}
+
+func TestEstimatePruneSavings(t *testing.T) {
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	mgr := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(NewMemStoreProvider()))
+	defer mgr.Close()
+
+	for _, ccName := range []string{"chaincode1", "chaincode2"} {
+		for _, blockNum := range []uint64{5, 10, 15} {
+			testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, ccName,
+				sampleCollectionConfigPackage(ccName, blockNum))
+			assert.NoError(t, mgr.HandleStateUpdates(&ledger.StateUpdateTrigger{
+				LedgerID:           "ledger1",
+				CommittingBlockNum: blockNum},
+			))
+		}
+	}
+
+	numEntries, size, err := mgr.EstimatePruneSavings("ledger1", 5)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, numEntries)
+	assert.Equal(t, uint64(0), size)
+
+	// for each chaincode, the entry at block 10 is retained and the entry at block 5 is removable
+	numEntries, size, err = mgr.EstimatePruneSavings("ledger1", 12)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, numEntries)
+	sizeBefore, err := mgr.ApproximateSize("ledger1")
+	assert.NoError(t, err)
+	results, err := mgr.PruneAllBelow(map[string]uint64{"ledger1": 12})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]error{"ledger1": nil}, results)
+	sizeAfter, err := mgr.ApproximateSize("ledger1")
+	assert.NoError(t, err)
+	assert.Equal(t, sizeBefore-sizeAfter, size)
+
+	numEntries, size, err = mgr.EstimatePruneSavings("ledger1", 12)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, numEntries)
+	assert.Equal(t, uint64(0), size)
+
+	_, _, err = mgr.EstimatePruneSavings("unknown-ledger", 12)
+	assert.EqualError(t, err, "ledger [unknown-ledger] is not known to the config history manager")
+}
+
+func TestPurge(t *testing.T) {
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(NewMemStoreProvider()))
+	defer m.Close()
+	for _, blockNum := range []uint64{10, 20, 30} {
+		testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, "chaincode1", sampleCollectionConfigPackage("coll", blockNum))
+		assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: blockNum}))
+	}
+	retriever := m.GetRetriever("ledger1", &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 100}})
+
+	numPurged, err := m.Purge("ledger1", 25)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, numPurged)
+	blockNums, err := retriever.ConfigBlockNumbers("chaincode1")
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{20, 30}, blockNums)
+	// the collection config in effect at the purge boundary remains answerable
+	collConfig, err := retriever.MostRecentCollectionConfigBelow(25, "chaincode1")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(20), collConfig.CommittingBlockNum)
+
+	_, err = m.Purge("ledger2", 25)
+	assert.EqualError(t, err, "ledger [ledger2] is not known to the config history manager")
+}
+
+func TestRetention(t *testing.T) {
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(NewMemStoreProvider()), WithRetention(15))
+	defer m.Close()
+	retriever := m.GetRetriever("ledger1", &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 100}})
+	for _, testCase := range []struct {
+		blockNum          uint64
+		expectedBlockNums []uint64
+	}{
+		// no purging until the window is filled
+		{10, []uint64{10}},
+		{15, []uint64{10, 15}},
+		// purged below block 5; the entry at block 10 is in effect at block 5 and is retained
+		{20, []uint64{10, 15, 20}},
+		// purged below block 25, which retains the entry at block 20 and removes the older ones
+		{40, []uint64{20, 40}},
+	} {
+		testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, "chaincode1", sampleCollectionConfigPackage("coll", testCase.blockNum))
+		assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: testCase.blockNum}))
+		blockNums, err := retriever.ConfigBlockNumbers("chaincode1")
+		assert.NoError(t, err)
+		assert.Equal(t, testCase.expectedBlockNums, blockNums)
+	}
+}
@@ -0,0 +1,220 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/pkg/errors"
+)
+
+// Direction is the order in which the paginated queries return the versions of a collection config
+type Direction int
+
+const (
+	// NewestFirst returns the versions in the decreasing order of the blocks at which these are committed
+	NewestFirst Direction = iota
+	// OldestFirst returns the versions in the increasing order of the blocks at which these are committed
+	OldestFirst
+)
+
+func (d Direction) String() string {
+	switch d {
+	case NewestFirst:
+		return "NewestFirst"
+	case OldestFirst:
+		return "OldestFirst"
+	default:
+		return fmt.Sprintf("Direction(%d)", int(d))
+	}
+}
+
+// CollectionConfigPage contains a page of the collection config history of a chaincode, ordered in the direction
+// of the query. If `Truncated` is true, more entries are available and these can be retrieved by supplying
+// `NextCursor` to the subsequent query. The configs contain only the persisted (explicit) collections
+type CollectionConfigPage struct {
+	Configs    []*ledger.CollectionConfigInfo
+	Truncated  bool
+	NextCursor string
+}
+
+// CollectionConfigsInRange implements function from the interface `Retriever`. It returns the collection configs of
+// the chaincode committed at the blocks in the range [startBlockNum, endBlockNum] (both inclusive), in the given direction.
+// At most `limit` entries are returned; a non-positive limit means no limit. An empty cursor starts the query from
+// `endBlockNum` (for `NewestFirst`) or `startBlockNum` (for `OldestFirst`) and a cursor returned by a previous query resumes
+// that query from the entry after the last returned entry. A cursor marks only the block of the last returned entry and hence,
+// supplying it to a query in the opposite direction pages back from that entry
+func (r *retriever) CollectionConfigsInRange(chaincodeName string, startBlockNum, endBlockNum uint64, limit int, cursor string,
+	direction Direction) (*CollectionConfigPage, error) {
+	if startBlockNum > endBlockNum {
+		return nil, errors.Errorf("invalid block range: start block [%d] is greater than end block [%d]", startBlockNum, endBlockNum)
+	}
+	if direction != NewestFirst && direction != OldestFirst {
+		return nil, errors.Errorf("invalid direction [%s]", direction)
+	}
+	if cursor != "" {
+		lastSeenBlockNum, err := decodeCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case direction == NewestFirst && lastSeenBlockNum <= startBlockNum:
+			return &CollectionConfigPage{}, nil
+		case direction == NewestFirst && lastSeenBlockNum-1 < endBlockNum:
+			endBlockNum = lastSeenBlockNum - 1
+		case direction == OldestFirst && lastSeenBlockNum >= endBlockNum:
+			return &CollectionConfigPage{}, nil
+		case direction == OldestFirst && lastSeenBlockNum+1 > startBlockNum:
+			startBlockNum = lastSeenBlockNum + 1
+		}
+	}
+	kvs, more, err := r.dbHandle.entriesInRange(r.namespace, constructCollectionConfigKey(chaincodeName), startBlockNum, endBlockNum, limit, direction)
+	if err != nil {
+		return nil, err
+	}
+	page := &CollectionConfigPage{}
+	for _, kv := range kvs {
+		info, err := compositeKVToCollectionConfig(kv)
+		if err != nil {
+			return nil, err
+		}
+		page.Configs = append(page.Configs, info)
+	}
+	if more {
+		page.Truncated = true
+		page.NextCursor = encodeCursor(kvs[len(kvs)-1].blockNum)
+	}
+	return page, nil
+}
+
+// AllCollectionConfigs implements function from the interface `Retriever`. It returns all the versions of the collection
+// config of the chaincode, in the given direction. See function `CollectionConfigsInRange` for the pagination parameters
+func (r *retriever) AllCollectionConfigs(chaincodeName string, limit int, cursor string, direction Direction) (*CollectionConfigPage, error) {
+	return r.CollectionConfigsInRange(chaincodeName, 0, math.MaxUint64, limit, cursor, direction)
+}
+
+// CollectionConfigWithPrevious implements function from the interface `Retriever`. It returns the collection config of the
+// chaincode that is in effect at the given block, i.e., the one committed at or below the block, and the version that
+// precedes it. Both are read in a single pass over the entries of the chaincode. The returned previous is nil if the current
+// is the first version, and both are nil if the chaincode has no collection config at the block (see function
+// `WithChaincodeNotDeployedErrors` for the case of a chaincode that is not deployed). As with the function
+// `CollectionConfigAt`, the returned collection configs include the implicit collections of the chaincode
+func (r *retriever) CollectionConfigWithPrevious(blockNum uint64, chaincodeName string) (
+	current, previous *ledger.CollectionConfigInfo, err error) {
+	defer func() { err = r.withContext(err, chaincodeName, blockNum) }()
+	if err := r.checkBlockCommitted(blockNum); err != nil {
+		return nil, nil, err
+	}
+	kvs, err := r.dbHandle.mostRecentEntriesAtOrBelow(blockNum, r.namespace, constructCollectionConfigKey(chaincodeName), 2)
+	if err != nil {
+		return nil, nil, err
+	}
+	var explicitConfigs []*ledger.CollectionConfigInfo
+	for _, kv := range kvs {
+		explicitConfig, err := compositeKVToCollectionConfig(kv)
+		if err != nil {
+			return nil, nil, err
+		}
+		explicitConfigs = append(explicitConfigs, explicitConfig)
+	}
+	if len(explicitConfigs) == 0 {
+		return nil, nil, r.checkChaincodeDeployed(blockNum, chaincodeName)
+	}
+	if current, err = r.resolveCollectionConfig(chaincodeName, explicitConfigs[0], nil); err != nil {
+		return nil, nil, err
+	}
+	if len(explicitConfigs) == 1 {
+		return current, nil, nil
+	}
+	if previous, err = r.resolveCollectionConfig(chaincodeName, explicitConfigs[1], nil); err != nil {
+		return nil, nil, err
+	}
+	return current, previous, nil
+}
+
+// ConfigBlockNumbers implements function from the interface `Retriever`. It returns, in the increasing order, the block
+// numbers at which a collection config of the chaincode is committed. Unlike the function `AllCollectionConfigs`, the
+// stored collection configs are not read or unmarshalled and hence, this is cheap even for a long history
+func (r *retriever) ConfigBlockNumbers(chaincodeName string) ([]uint64, error) {
+	return r.dbHandle.blockNumsOf(r.namespace, constructCollectionConfigKey(chaincodeName))
+}
+
+// ConfigChangedBetween implements function from the interface `Retriever`. It returns true if a collection config of the chaincode
+// is committed at a block in the range (fromBlock, toBlock], i.e., if the collection config in effect at the toBlock differs from the
+// one in effect at the fromBlock. Only the presence of a key in the range is checked and hence, this is cheap enough to be polled
+// frequently. If the index is enabled (see function `WithBlockIndex`), a range above the latest collection config is answered from
+// the memory. After a pruning, the changes below the oldest retained collection config (see function `OldestConfigBlock`) are not reported
+func (r *retriever) ConfigChangedBetween(chaincodeName string, fromBlock, toBlock uint64) (bool, error) {
+	if fromBlock > toBlock {
+		return false, errors.Errorf("invalid block range: start block [%d] is greater than end block [%d]", fromBlock, toBlock)
+	}
+	if fromBlock == toBlock {
+		return false, nil
+	}
+	if r.blockIndex.enabled {
+		blockRange, err := r.blockIndex.get(r.ledgerID, chaincodeName, r.dbHandle)
+		if err != nil || blockRange == nil || blockRange.latest <= fromBlock {
+			return false, err
+		}
+	}
+	return r.dbHandle.hasEntryInRange(r.namespace, constructCollectionConfigKey(chaincodeName), fromBlock+1, toBlock)
+}
+
+// OldestConfigBlock implements function from the interface `Retriever`. It returns the lowest block number at which a collection
+// config of the chaincode is retained in the config history. Without pruning, this is the block of the first collection config of
+// the chaincode; after a pruning (see function `Mgr.PruneAllBelow`), the queries for the blocks below the returned block cannot be
+// answered for the chaincode. If the index is enabled (see function `WithBlockIndex`), this is answered from the memory.
+// The returned bool is false if no collection config of the chaincode is retained
+func (r *retriever) OldestConfigBlock(chaincodeName string) (uint64, bool, error) {
+	if r.blockIndex.enabled {
+		blockRange, err := r.blockIndex.get(r.ledgerID, chaincodeName, r.dbHandle)
+		if err != nil || blockRange == nil {
+			return 0, false, err
+		}
+		return blockRange.oldest, true, nil
+	}
+	return r.dbHandle.oldestBlockNum(r.namespace, constructCollectionConfigKey(chaincodeName))
+}
+
+// LatestConfigBlock implements function from the interface `Retriever`. It returns the highest block number at which a collection
+// config of the chaincode is committed, which also establishes whether the chaincode has a collection config at all. If the index
+// is enabled (see function `WithBlockIndex`), this is answered from the memory. The returned bool is false if the chaincode has no
+// collection config
+func (r *retriever) LatestConfigBlock(chaincodeName string) (uint64, bool, error) {
+	if r.blockIndex.enabled {
+		blockRange, err := r.blockIndex.get(r.ledgerID, chaincodeName, r.dbHandle)
+		if err != nil || blockRange == nil {
+			return 0, false, err
+		}
+		return blockRange.latest, true, nil
+	}
+	kv, err := r.dbHandle.mostRecentEntryBelowWithPointer(math.MaxUint64, r.namespace, constructCollectionConfigKey(chaincodeName))
+	if err != nil || kv == nil {
+		return 0, false, err
+	}
+	return kv.blockNum, true, nil
+}
+
+// encodeCursor encodes the block number of the last returned entry. The entries are keyed by the block number
+// and hence, the cursor remains valid even if new entries are added in the meantime
+func encodeCursor(lastSeenBlockNum uint64) string {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, lastSeenBlockNum)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(cursor string) (uint64, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || len(b) != 8 {
+		return 0, errors.Errorf("invalid cursor [%s]", cursor)
+	}
+	return binary.BigEndian.Uint64(b), nil
+}
@@ -0,0 +1,373 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/mock"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollectionConfigsInRange(t *testing.T) {
+	dbPath := "/tmp/fabric/core/ledger/confighistory"
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	env := newTestEnv(t, dbPath, mockCCInfoProvider)
+	mgr := env.mgr
+	defer env.cleanup()
+
+	for _, ccName := range []string{"chaincode1", "chaincode2"} {
+		for _, blockNum := range []uint64{0, 10, 20, 30, 40} {
+			testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, ccName,
+				sampleCollectionConfigPackage(ccName, blockNum))
+			assert.NoError(t, mgr.HandleStateUpdates(&ledger.StateUpdateTrigger{
+				LedgerID:           "ledger1",
+				CommittingBlockNum: blockNum},
+			))
+		}
+	}
+	retriever := mgr.GetRetriever("ledger1", &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 100}})
+
+	blockNums := func(page *CollectionConfigPage) []uint64 {
+		var nums []uint64
+		for _, info := range page.Configs {
+			assert.True(t, proto.Equal(sampleCollectionConfigPackage("chaincode1", info.CommittingBlockNum), info.CollectionConfig))
+			nums = append(nums, info.CommittingBlockNum)
+		}
+		return nums
+	}
+
+	t.Run("without-limit", func(t *testing.T) {
+		page, err := retriever.CollectionConfigsInRange("chaincode1", 10, 30, 0, "", NewestFirst)
+		assert.NoError(t, err)
+		assert.Equal(t, []uint64{30, 20, 10}, blockNums(page))
+		assert.False(t, page.Truncated)
+		assert.Empty(t, page.NextCursor)
+
+		page, err = retriever.AllCollectionConfigs("chaincode1", 0, "", NewestFirst)
+		assert.NoError(t, err)
+		assert.Equal(t, []uint64{40, 30, 20, 10, 0}, blockNums(page))
+		assert.False(t, page.Truncated)
+
+		page, err = retriever.CollectionConfigsInRange("chaincode1", 11, 19, 0, "", NewestFirst)
+		assert.NoError(t, err)
+		assert.Nil(t, page.Configs)
+	})
+
+	t.Run("with-limit", func(t *testing.T) {
+		var allBlockNums []uint64
+		cursor := ""
+		for numPages := 1; ; numPages++ {
+			page, err := retriever.AllCollectionConfigs("chaincode1", 2, cursor, NewestFirst)
+			assert.NoError(t, err)
+			allBlockNums = append(allBlockNums, blockNums(page)...)
+			if !page.Truncated {
+				assert.Equal(t, 3, numPages)
+				break
+			}
+			cursor = page.NextCursor
+		}
+		assert.Equal(t, []uint64{40, 30, 20, 10, 0}, allBlockNums)
+
+		// the limit equal to the number of entries does not truncate the result
+		page, err := retriever.CollectionConfigsInRange("chaincode1", 10, 30, 3, "", NewestFirst)
+		assert.NoError(t, err)
+		assert.Equal(t, []uint64{30, 20, 10}, blockNums(page))
+		assert.False(t, page.Truncated)
+	})
+
+	t.Run("cursor-is-stable", func(t *testing.T) {
+		page, err := retriever.AllCollectionConfigs("chaincode1", 2, "", NewestFirst)
+		assert.NoError(t, err)
+		assert.Equal(t, []uint64{40, 30}, blockNums(page))
+		assert.True(t, page.Truncated)
+
+		// a new entry committed in the meantime does not affect the resumption of the query
+		testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, "chaincode1",
+			sampleCollectionConfigPackage("chaincode1", 50))
+		assert.NoError(t, mgr.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: 50}))
+		page, err = retriever.AllCollectionConfigs("chaincode1", 2, page.NextCursor, NewestFirst)
+		assert.NoError(t, err)
+		assert.Equal(t, []uint64{20, 10}, blockNums(page))
+
+		// a cursor beyond the start of the range yields an empty page
+		page, err = retriever.CollectionConfigsInRange("chaincode1", 30, 50, 2, encodeCursor(30), NewestFirst)
+		assert.NoError(t, err)
+		assert.Nil(t, page.Configs)
+		assert.False(t, page.Truncated)
+	})
+
+	t.Run("oldest-first", func(t *testing.T) {
+		page, err := retriever.CollectionConfigsInRange("chaincode1", 10, 30, 0, "", OldestFirst)
+		assert.NoError(t, err)
+		assert.Equal(t, []uint64{10, 20, 30}, blockNums(page))
+		assert.False(t, page.Truncated)
+
+		var allBlockNums []uint64
+		cursor := ""
+		for {
+			page, err := retriever.AllCollectionConfigs("chaincode1", 2, cursor, OldestFirst)
+			assert.NoError(t, err)
+			allBlockNums = append(allBlockNums, blockNums(page)...)
+			if !page.Truncated {
+				break
+			}
+			cursor = page.NextCursor
+		}
+		assert.Equal(t, []uint64{0, 10, 20, 30, 40, 50}, allBlockNums)
+
+		// a cursor supplied to a query in the opposite direction pages back from the last returned entry
+		page, err = retriever.AllCollectionConfigs("chaincode1", 2, "", OldestFirst)
+		assert.NoError(t, err)
+		assert.Equal(t, []uint64{0, 10}, blockNums(page))
+		page, err = retriever.AllCollectionConfigs("chaincode1", 2, page.NextCursor, NewestFirst)
+		assert.NoError(t, err)
+		assert.Equal(t, []uint64{0}, blockNums(page))
+
+		// a cursor beyond the end of the range yields an empty page
+		page, err = retriever.CollectionConfigsInRange("chaincode1", 10, 30, 2, encodeCursor(30), OldestFirst)
+		assert.NoError(t, err)
+		assert.Nil(t, page.Configs)
+	})
+
+	t.Run("invalid-input", func(t *testing.T) {
+		_, err := retriever.AllCollectionConfigs("chaincode1", 2, "garbage!", NewestFirst)
+		assert.EqualError(t, err, "invalid cursor [garbage!]")
+		_, err = retriever.AllCollectionConfigs("chaincode1", 2, "AAAA", NewestFirst)
+		assert.EqualError(t, err, "invalid cursor [AAAA]")
+		_, err = retriever.CollectionConfigsInRange("chaincode1", 20, 10, 2, "", NewestFirst)
+		assert.EqualError(t, err, "invalid block range: start block [20] is greater than end block [10]")
+		_, err = retriever.AllCollectionConfigs("chaincode1", 2, "", Direction(5))
+		assert.EqualError(t, err, "invalid direction [Direction(5)]")
+	})
+}
+
+func TestCollectionConfigsOldestFirstAcrossStores(t *testing.T) {
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	// the recording store does not implement the interface `ReverseIterable`
+	storeProviders := map[string]StoreProvider{
+		"reverse-iterable": NewMemStoreProvider(),
+		"fallback":         &recordingStoreProvider{StoreProvider: NewMemStoreProvider()},
+	}
+	for name, storeProvider := range storeProviders {
+		t.Run(name, func(t *testing.T) {
+			mgr := NewMgrWithStore(mockCCInfoProvider, storeProvider).(*mgr)
+			defer mgr.Close()
+			for _, blockNum := range []uint64{10, 20, 30} {
+				testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, "chaincode1",
+					sampleCollectionConfigPackage("coll", blockNum))
+				assert.NoError(t, mgr.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: blockNum}))
+			}
+			retriever := mgr.GetRetriever("ledger1", &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 100}})
+			retrievers := []Retriever{retriever}
+			// the recording store does not support the snapshots either
+			if snapshot, err := retriever.Snapshot(); err == nil {
+				defer snapshot.Close()
+				retrievers = append(retrievers, snapshot)
+			}
+			for _, r := range retrievers {
+				page, err := r.AllCollectionConfigs("chaincode1", 2, "", OldestFirst)
+				assert.NoError(t, err)
+				assert.Equal(t, []string{"coll-10"}, collNames(page.Configs[0]))
+				assert.Equal(t, []string{"coll-20"}, collNames(page.Configs[1]))
+				assert.True(t, page.Truncated)
+				page, err = r.AllCollectionConfigs("chaincode1", 2, page.NextCursor, OldestFirst)
+				assert.NoError(t, err)
+				assert.Len(t, page.Configs, 1)
+				assert.Equal(t, []string{"coll-30"}, collNames(page.Configs[0]))
+				assert.False(t, page.Truncated)
+			}
+		})
+	}
+
+	t.Run("closed", func(t *testing.T) {
+		mgr := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(NewMemStoreProvider()))
+		retriever := mgr.GetRetriever("ledger1", &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 100}})
+		mgr.Close()
+		_, err := retriever.AllCollectionConfigs("chaincode1", 2, "", OldestFirst)
+		assert.Equal(t, ErrMgrClosed, errors.Cause(err))
+	})
+}
+
+func TestConfigBlockNumbers(t *testing.T) {
+	dbPath := "/tmp/fabric/core/ledger/confighistory"
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	deleteTestPath(t, dbPath)
+	defer deleteTestPath(t, dbPath)
+	mgr := newMgr(mockCCInfoProvider, dbPath)
+	defer mgr.Close()
+
+	for _, ccName := range []string{"chaincode1", "chaincode10", "chaincode2"} {
+		for _, blockNum := range []uint64{0, 10, 255, 256, 1000} {
+			testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, ccName,
+				sampleCollectionConfigPackage(ccName, blockNum))
+			assert.NoError(t, mgr.HandleStateUpdates(&ledger.StateUpdateTrigger{
+				LedgerID:           "ledger1",
+				CommittingBlockNum: blockNum},
+			))
+		}
+	}
+	// a value that cannot be unmarshalled does not affect the block numbers, as the values are not read
+	batch := newBatch()
+	batch.add(collectionConfigNamespace, constructCollectionConfigKey("chaincode1"), 500, []byte("garbage"))
+	assert.NoError(t, mgr.dbProvider.getDB("ledger1").writeBatch(batch, true))
+
+	retriever := mgr.GetRetriever("ledger1", &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 2000}})
+	blockNums, err := retriever.ConfigBlockNumbers("chaincode1")
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{0, 10, 255, 256, 500, 1000}, blockNums)
+	blockNums, err = retriever.ConfigBlockNumbers("chaincode2")
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{0, 10, 255, 256, 1000}, blockNums)
+	blockNums, err = retriever.ConfigBlockNumbers("chaincode3")
+	assert.NoError(t, err)
+	assert.Nil(t, blockNums)
+}
+
+func TestOldestConfigBlock(t *testing.T) {
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	mgr := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(NewMemStoreProvider()))
+	defer mgr.Close()
+
+	for _, ccName := range []string{"chaincode1", "chaincode10"} {
+		for _, blockNum := range []uint64{5, 10, 20, 30} {
+			testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, ccName,
+				sampleCollectionConfigPackage(ccName, blockNum))
+			assert.NoError(t, mgr.HandleStateUpdates(&ledger.StateUpdateTrigger{
+				LedgerID:           "ledger1",
+				CommittingBlockNum: blockNum},
+			))
+		}
+	}
+	retriever := mgr.GetRetriever("ledger1", &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 100}})
+	blockNum, ok, err := retriever.OldestConfigBlock("chaincode1")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(5), blockNum)
+
+	// the most recent entry at or below the pruning boundary is retained
+	_, err = mgr.PruneAllBelow(map[string]uint64{"ledger1": 25})
+	assert.NoError(t, err)
+	blockNum, ok, err = retriever.OldestConfigBlock("chaincode1")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(20), blockNum)
+
+	_, ok, err = retriever.OldestConfigBlock("chaincode2")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestConfigChangedBetween(t *testing.T) {
+	for _, withIndex := range []bool{false, true} {
+		var opts []Option
+		if withIndex {
+			opts = append(opts, WithBlockIndex())
+		}
+		mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+		mgr := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(NewMemStoreProvider()), opts...)
+		defer mgr.Close()
+
+		for _, ccName := range []string{"chaincode1", "chaincode10"} {
+			for _, blockNum := range []uint64{10, 20} {
+				testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, ccName,
+					sampleCollectionConfigPackage(ccName, blockNum))
+				assert.NoError(t, mgr.HandleStateUpdates(&ledger.StateUpdateTrigger{
+					LedgerID:           "ledger1",
+					CommittingBlockNum: blockNum},
+				))
+			}
+		}
+		// a value that cannot be unmarshalled does not affect the result, as the values are not read
+		batch := newBatch()
+		batch.add(collectionConfigNamespace, constructCollectionConfigKey("chaincode10"), 30, []byte("garbage"))
+		assert.NoError(t, mgr.dbProvider.getDB("ledger1").writeBatch(batch, true))
+
+		retriever := mgr.GetRetriever("ledger1", &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 100}})
+		testCases := []struct {
+			chaincodeName      string
+			fromBlock, toBlock uint64
+			expectedChanged    bool
+		}{
+			{"chaincode1", 0, 9, false},
+			{"chaincode1", 0, 10, true},
+			{"chaincode1", 10, 19, false},
+			{"chaincode1", 10, 20, true},
+			{"chaincode1", 5, 50, true},
+			{"chaincode1", 20, 50, false},
+			{"chaincode1", 20, 20, false},
+			{"chaincode10", 20, 30, true},
+			{"chaincode10", 30, 50, false},
+			{"chaincode2", 0, 50, false},
+		}
+		for _, testCase := range testCases {
+			changed, err := retriever.ConfigChangedBetween(testCase.chaincodeName, testCase.fromBlock, testCase.toBlock)
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.expectedChanged, changed, "index=%t, %+v", withIndex, testCase)
+		}
+
+		_, err := retriever.ConfigChangedBetween("chaincode1", 20, 10)
+		assert.EqualError(t, err, "invalid block range: start block [20] is greater than end block [10]")
+	}
+}
+
+func TestCollectionConfigWithPrevious(t *testing.T) {
+	dbPath := "/tmp/fabric/core/ledger/confighistory"
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	env := newTestEnv(t, dbPath, mockCCInfoProvider)
+	mgr := env.mgr
+	defer env.cleanup()
+
+	for _, ccName := range []string{"chaincode1", "chaincode2"} {
+		for _, blockNum := range []uint64{0, 10, 20} {
+			testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, ccName,
+				sampleCollectionConfigPackage(ccName, blockNum))
+			assert.NoError(t, mgr.HandleStateUpdates(&ledger.StateUpdateTrigger{
+				LedgerID:           "ledger1",
+				CommittingBlockNum: blockNum},
+			))
+		}
+	}
+	retriever := mgr.GetRetriever("ledger1", &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 100}})
+
+	blockNumOf := func(info *ledger.CollectionConfigInfo) interface{} {
+		if info == nil {
+			return nil
+		}
+		assert.True(t, proto.Equal(sampleCollectionConfigPackage("chaincode1", info.CommittingBlockNum), info.CollectionConfig))
+		return info.CommittingBlockNum
+	}
+	testCases := []struct {
+		blockNum                          uint64
+		expectedCurrent, expectedPrevious interface{}
+	}{
+		{99, uint64(20), uint64(10)},
+		{20, uint64(20), uint64(10)},
+		{19, uint64(10), uint64(0)},
+		{10, uint64(10), uint64(0)},
+		{5, uint64(0), nil},
+		{0, uint64(0), nil},
+	}
+	for _, testCase := range testCases {
+		current, previous, err := retriever.CollectionConfigWithPrevious(testCase.blockNum, "chaincode1")
+		assert.NoError(t, err)
+		assert.Equal(t, testCase.expectedCurrent, blockNumOf(current), "block [%d]", testCase.blockNum)
+		assert.Equal(t, testCase.expectedPrevious, blockNumOf(previous), "block [%d]", testCase.blockNum)
+	}
+
+	current, previous, err := retriever.CollectionConfigWithPrevious(50, "chaincode3")
+	assert.NoError(t, err)
+	assert.Nil(t, current)
+	assert.Nil(t, previous)
+
+	_, _, err = retriever.CollectionConfigWithPrevious(100, "chaincode1")
+	assert.EqualError(t, err, "The maximum block number committed [99] is less than the requested block number [100]")
+}
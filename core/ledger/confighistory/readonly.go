@@ -0,0 +1,85 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric/common/ledger/util/leveldbhelper"
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/pkg/errors"
+)
+
+// ErrReadOnly is returned by the writes to a config history db opened in the read-only mode (see function `NewReadOnlyRetriever`)
+var ErrReadOnly = errors.New("the config history db is opened in the read-only mode")
+
+// ReadOnlyRetriever is a `Retriever` over a config history db opened in the read-only mode (see function `NewReadOnlyRetriever`).
+// The retriever should be closed after the use, for closing the db
+type ReadOnlyRetriever interface {
+	Retriever
+	// Close closes the db. The retriever should not be used after the close
+	Close()
+}
+
+type readOnlyRetrieverImpl struct {
+	Retriever
+	mgr *mgr
+}
+
+// Close implements function from the interface `ReadOnlyRetriever`
+func (r *readOnlyRetrieverImpl) Close() {
+	r.mgr.Close()
+}
+
+// NewReadOnlyRetriever returns a retriever for the config history of the given ledger in the leveldb at the given path, opened in
+// the read-only mode. This is intended for serving the analytical queries from a copy of the config history db (e.g., on a snapshot
+// of the file system of a peer), so that these do not load the store of the live peer. The db is expected to exist and should not
+// be in use by another process, which holds a lock on it. All the queries are supported, whereas the writes to the db fail with
+// `ErrReadOnly`. The height of the ledger, for the checks against the last committed block, and the info of the deployed chaincodes
+// are obtained from the given `LedgerInfoRetriever` and `DeployedChaincodeInfoProvider`
+func NewReadOnlyRetriever(
+	dbPath, ledgerID string,
+	ledgerInfoRetriever LedgerInfoRetriever,
+	ccInfoProvider ledger.DeployedChaincodeInfoProvider,
+	options ...Option,
+) (ReadOnlyRetriever, error) {
+	provider, err := leveldbhelper.NewReadOnlyProvider(dbPath)
+	if err != nil {
+		return nil, errors.WithMessage(err, fmt.Sprintf("error while opening the config history db at [%s] in the read-only mode", dbPath))
+	}
+	m := newMgrWithDBProvider(ccInfoProvider, newDBProviderWithStore(&readOnlyStoreProvider{&leveldbStoreProvider{provider}}), options...)
+	return &readOnlyRetrieverImpl{Retriever: m.GetRetriever(ledgerID, ledgerInfoRetriever), mgr: m}, nil
+}
+
+type readOnlyStoreProvider struct {
+	*leveldbStoreProvider
+}
+
+// GetStore implements function from the interface `StoreProvider`
+func (p *readOnlyStoreProvider) GetStore(ledgerID string) Store {
+	return &readOnlyStore{&leveldbStore{p.GetDBHandle(ledgerID)}}
+}
+
+// readOnlyStore rejects the writes upfront, instead of relying on the leveldb to fail these
+type readOnlyStore struct {
+	*leveldbStore
+}
+
+// WriteBatch implements function from the interface `Store`
+func (s *readOnlyStore) WriteBatch(batch *leveldbhelper.UpdateBatch, sync bool) error {
+	return ErrReadOnly
+}
+
+// DeleteRange implements function from the interface `RangeDeleter`
+func (s *readOnlyStore) DeleteRange(startKey []byte, endKey []byte, sync bool) (int, error) {
+	return 0, ErrReadOnly
+}
+
+// Compact implements function from the interface `Compactor`
+func (s *readOnlyStore) Compact() error {
+	return ErrReadOnly
+}
@@ -0,0 +1,71 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadOnlyRetriever(t *testing.T) {
+	dbPath := "/tmp/fabric/core/ledger/confighistory"
+	deleteTestPath(t, dbPath)
+	defer deleteTestPath(t, dbPath)
+	mockCCInfoProvider := &implicitCollsCCInfoProvider{}
+	ledgerInfoRetriever := &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 100}}
+
+	m := newMgr(mockCCInfoProvider, dbPath)
+	for _, blockNum := range []uint64{10, 20} {
+		testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(&mockCCInfoProvider.DeployedChaincodeInfoProvider, "chaincode1",
+			sampleCollectionConfigPackage("coll", blockNum))
+		assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: blockNum}))
+	}
+
+	// the db is locked while the live `Mgr` holds it open
+	_, err := NewReadOnlyRetriever(dbPath, "ledger1", ledgerInfoRetriever, mockCCInfoProvider)
+	assert.Error(t, err)
+	m.Close()
+
+	retriever, err := NewReadOnlyRetriever(dbPath, "ledger1", ledgerInfoRetriever, mockCCInfoProvider)
+	assert.NoError(t, err)
+	defer retriever.Close()
+	collConfig, err := retriever.MostRecentCollectionConfigBelow(50, "chaincode1")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(20), collConfig.CommittingBlockNum)
+	assert.Equal(t, []string{"coll-20"}, collNames(collConfig))
+	blockNums, err := retriever.ConfigBlockNumbers("chaincode1")
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{10, 20}, blockNums)
+	snapshot, err := retriever.Snapshot()
+	assert.NoError(t, err)
+	collConfig, err = snapshot.CollectionConfigAt(10, "chaincode1")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"coll-10"}, collNames(collConfig))
+	snapshot.Close()
+
+	// the implicit collections are resolved from the given provider
+	mockCCInfoProvider.ImplicitCollectionsReturns([]*common.StaticCollectionConfig{sampleImplicitCollection("org1")}, nil)
+	collConfig, err = retriever.CollectionConfigAt(20, "chaincode1")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"coll-20", "_implicit_org_org1"}, collNames(collConfig))
+
+	dbHandle := retriever.(*readOnlyRetrieverImpl).mgr.dbProvider.getDB("ledger1")
+	batch := newBatch()
+	batch.add(collectionConfigNamespace, constructCollectionConfigKey("chaincode1"), 30, []byte("value"))
+	assert.Equal(t, ErrReadOnly, dbHandle.writeBatch(batch, true))
+	_, err = dbHandle.deleteAll()
+	assert.Equal(t, ErrReadOnly, errors.Cause(err))
+
+	t.Run("missing-db", func(t *testing.T) {
+		_, err := NewReadOnlyRetriever(dbPath+"-missing", "ledger1", ledgerInfoRetriever, mockCCInfoProvider)
+		assert.Contains(t, err.Error(), "error while opening the config history db at [/tmp/fabric/core/ledger/confighistory-missing] in the read-only mode")
+	})
+}
@@ -0,0 +1,227 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"fmt"
+	"sort"
+
+	commonledger "github.com/hyperledger/fabric/common/ledger"
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/rwsetutil"
+	ledgerutil "github.com/hyperledger/fabric/core/ledger/util"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/ledger/queryresult"
+	"github.com/hyperledger/fabric/protos/ledger/rwset/kvrwset"
+	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/pkg/errors"
+)
+
+// BlockIterator supplies the committed blocks of a ledger, in the increasing order of block numbers
+type BlockIterator interface {
+	// Next returns the next block. A nil block is returned after the last block
+	Next() (*common.Block, error)
+}
+
+// RebuildFromBlocks implements function in the interface 'Mgr'. It replaces the config history by replaying the blocks from genesis
+func (m *mgr) RebuildFromBlocks(ledgerID string, blockIter BlockIterator) error {
+	if err := m.WaitForPendingWrites(); err != nil {
+		return err
+	}
+	dbHandle := m.dbProvider.getDB(ledgerID)
+	numDeleted, err := dbHandle.deleteAll()
+	if err != nil {
+		return err
+	}
+	m.cache.removeLedger(ledgerID)
+	// the index is invalidated again when the rebuild ends, as the queries in the meantime may load a partial index
+	m.blockIndex.invalidate(ledgerID)
+	defer m.blockIndex.invalidate(ledgerID)
+	logger.Infof("Rebuilding config history of ledger [%s], discarded [%d] existing entries", ledgerID, numDeleted)
+
+	interestedNamespaces := map[string]bool{}
+	for _, ns := range m.InterestedInNamespaces() {
+		interestedNamespaces[ns] = true
+	}
+	state := &replayedState{kvs: map[string]map[string][]byte{}}
+	numBlocks := 0
+	for {
+		block, err := blockIter.Next()
+		if err != nil {
+			return errors.WithMessage(err, "error while retrieving the block to replay")
+		}
+		if block == nil {
+			break
+		}
+		blockNum := block.Header.Number
+		updates, submitters, err := extractStateUpdates(block, interestedNamespaces)
+		if err != nil {
+			return errors.WithMessage(err, fmt.Sprintf("error while extracting the state updates from block [%d]", blockNum))
+		}
+		numBlocks++
+		if len(updates) == 0 {
+			continue
+		}
+		if err := state.apply(updates); err != nil {
+			return err
+		}
+		req, err := m.prepareWriteRequest(&ledger.StateUpdateTrigger{
+			LedgerID:                ledgerID,
+			StateUpdates:            updates,
+			CommittingBlockNum:      blockNum,
+			PostCommitQueryExecutor: state,
+			Submitters:              submitters,
+		})
+		if err != nil {
+			return errors.WithMessage(err, fmt.Sprintf("error while replaying block [%d]", blockNum))
+		}
+		if req == nil {
+			continue
+		}
+		if err := dbHandle.writeBatch(req.batch, m.syncWrites); err != nil {
+			return err
+		}
+	}
+	logger.Infof("Rebuilt config history of ledger [%s] from [%d] blocks", ledgerID, numBlocks)
+	return nil
+}
+
+// extractStateUpdates returns the final writes of the valid endorser transactions of the block to the given namespaces
+func extractStateUpdates(block *common.Block, namespaces map[string]bool) (ledger.StateUpdates, map[string]map[string]*ledger.TxSubmitter, error) {
+	txsFilter := ledgerutil.TxValidationFlags(block.Metadata.Metadata[common.BlockMetadataIndex_TRANSACTIONS_FILTER])
+	writes := map[string][]*kvrwset.KVWrite{}
+	indexes := map[string]map[string]int{}
+	submitters := map[string]map[string]*ledger.TxSubmitter{}
+	for txNum, envBytes := range block.Data.Data {
+		if txNum >= len(txsFilter) || txsFilter.IsInvalid(txNum) {
+			continue
+		}
+		env, err := utils.GetEnvelopeFromBlock(envBytes)
+		if err != nil {
+			return nil, nil, err
+		}
+		payload, err := utils.GetPayload(env)
+		if err != nil {
+			return nil, nil, err
+		}
+		chdr, err := utils.UnmarshalChannelHeader(payload.Header.ChannelHeader)
+		if err != nil {
+			return nil, nil, err
+		}
+		if common.HeaderType(chdr.Type) != common.HeaderType_ENDORSER_TRANSACTION {
+			continue
+		}
+		submitter, err := payloadSubmitter(payload)
+		if err != nil {
+			logger.Warningf("Error while extracting the submitter of transaction [%d] in block [%d]: %s", txNum, block.Header.Number, err)
+		}
+		respPayload, err := utils.GetActionFromEnvelope(envBytes)
+		if err != nil {
+			return nil, nil, err
+		}
+		txRWSet := &rwsetutil.TxRwSet{}
+		if err := txRWSet.FromProtoBytes(respPayload.Results); err != nil {
+			return nil, nil, err
+		}
+		for _, nsRWSet := range txRWSet.NsRwSets {
+			ns := nsRWSet.NameSpace
+			if !namespaces[ns] {
+				continue
+			}
+			if indexes[ns] == nil {
+				indexes[ns] = map[string]int{}
+			}
+			for _, kvWrite := range nsRWSet.KvRwSet.Writes {
+				if submitter != nil {
+					if submitters[ns] == nil {
+						submitters[ns] = map[string]*ledger.TxSubmitter{}
+					}
+					submitters[ns][kvWrite.Key] = submitter
+				} else {
+					delete(submitters[ns], kvWrite.Key)
+				}
+				if i, ok := indexes[ns][kvWrite.Key]; ok {
+					writes[ns][i] = kvWrite
+					continue
+				}
+				indexes[ns][kvWrite.Key] = len(writes[ns])
+				writes[ns] = append(writes[ns], kvWrite)
+			}
+		}
+	}
+	updates := ledger.StateUpdates{}
+	for ns, nsWrites := range writes {
+		updates[ns] = nsWrites
+	}
+	return updates, submitters, nil
+}
+
+func payloadSubmitter(payload *common.Payload) (*ledger.TxSubmitter, error) {
+	sigHdr, err := utils.GetSignatureHeader(payload.Header.SignatureHeader)
+	if err != nil {
+		return nil, err
+	}
+	return ledger.NewTxSubmitter(sigHdr.Creator)
+}
+
+// replayedState is an in-memory `ledger.SimpleQueryExecutor` over the namespaces of interest, built by replaying the blocks
+type replayedState struct {
+	kvs map[string]map[string][]byte
+}
+
+func (s *replayedState) apply(updates ledger.StateUpdates) error {
+	kvWrites, err := convertToKVWrites(updates)
+	if err != nil {
+		return err
+	}
+	for ns, nsWrites := range kvWrites {
+		if s.kvs[ns] == nil {
+			s.kvs[ns] = map[string][]byte{}
+		}
+		for _, kvWrite := range nsWrites {
+			if kvWrite.IsDelete {
+				delete(s.kvs[ns], kvWrite.Key)
+				continue
+			}
+			s.kvs[ns][kvWrite.Key] = kvWrite.Value
+		}
+	}
+	return nil
+}
+
+// GetState implements function in the interface `ledger.SimpleQueryExecutor`
+func (s *replayedState) GetState(namespace string, key string) ([]byte, error) {
+	return s.kvs[namespace][key], nil
+}
+
+// GetStateRangeScanIterator implements function in the interface `ledger.SimpleQueryExecutor`
+func (s *replayedState) GetStateRangeScanIterator(namespace string, startKey string, endKey string) (commonledger.ResultsIterator, error) {
+	var results []*queryresult.KV
+	for key, value := range s.kvs[namespace] {
+		if key >= startKey && (endKey == "" || key < endKey) {
+			results = append(results, &queryresult.KV{Namespace: namespace, Key: key, Value: value})
+		}
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Key < results[j].Key })
+	return &kvsIterator{results: results}, nil
+}
+
+type kvsIterator struct {
+	results []*queryresult.KV
+}
+
+func (itr *kvsIterator) Next() (commonledger.QueryResult, error) {
+	if len(itr.results) == 0 {
+		return nil, nil
+	}
+	next := itr.results[0]
+	itr.results = itr.results[1:]
+	return next, nil
+}
+
+func (itr *kvsIterator) Close() {
+}
@@ -0,0 +1,190 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/common/ledger/testutil"
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/rwsetutil"
+	"github.com/hyperledger/fabric/core/ledger/mock"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/ledger/rwset/kvrwset"
+	"github.com/hyperledger/fabric/protos/peer"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRebuildFromBlocks(t *testing.T) {
+	ccInfoProvider := newLsccLikeCCInfoProvider()
+	m := newMgrWithDBProvider(ccInfoProvider, newDBProviderWithStore(NewMemStoreProvider()))
+	defer m.Close()
+
+	collConfigBytes := func(ccName string, blockNum uint64) []byte {
+		b, err := proto.Marshal(sampleCollectionConfigPackage(ccName, blockNum))
+		assert.NoError(t, err)
+		return b
+	}
+	// each block is a list of transactions and each transaction is a list of writes
+	type write struct {
+		ns, key string
+		value   []byte
+	}
+	blocksWrites := [][][]write{
+		{},
+		{{{"lscc", "cc1", []byte("cc1-data")}, {"lscc", "cc1~collection", collConfigBytes("cc1", 1)}}},
+		{{{"lscc", "cc2", []byte("cc2-data")}}, {{"lscc", "cc3", []byte("cc3-data")}, {"lscc", "cc3~collection", collConfigBytes("cc3", 2)}}},
+		{{{"ns1", "key1", []byte("value1")}}},
+		{{{"lscc", "cc1", []byte("cc1-data-upgraded")}}, {{"lscc", "cc2~collection", collConfigBytes("cc2", 4)}}},
+		{{{"lscc", "cc1~collection", collConfigBytes("cc1", 4)}}, {{"lscc", "cc1~collection", collConfigBytes("cc1", 5)}}},
+	}
+	// the second transaction in block 2 is invalid
+	invalidTxs := map[uint64]int{2: 1}
+
+	var blocks []*common.Block
+	incrementalState := &replayedState{kvs: map[string]map[string][]byte{}}
+	for blockNum, txs := range blocksWrites {
+		var simulationResults [][]byte
+		var lsccWrites []*kvrwset.KVWrite
+		for txNum, txWrites := range txs {
+			invalidTxNum, ok := invalidTxs[uint64(blockNum)]
+			valid := !ok || invalidTxNum != txNum
+			builder := rwsetutil.NewRWSetBuilder()
+			for _, w := range txWrites {
+				builder.AddToWriteSet(w.ns, w.key, w.value)
+				if valid && w.ns == "lscc" {
+					lsccWrites = append(lsccWrites, &kvrwset.KVWrite{Key: w.key, Value: w.value})
+				}
+			}
+			txSimulationResults, err := builder.GetTxSimulationResults()
+			assert.NoError(t, err)
+			pubSimulationBytes, err := txSimulationResults.GetPubSimulationBytes()
+			assert.NoError(t, err)
+			simulationResults = append(simulationResults, pubSimulationBytes)
+		}
+		block := testutil.ConstructBlock(t, uint64(blockNum), nil, simulationResults, false)
+		if txNum, ok := invalidTxs[uint64(blockNum)]; ok {
+			block.Metadata.Metadata[common.BlockMetadataIndex_TRANSACTIONS_FILTER][txNum] = byte(peer.TxValidationCode_MVCC_READ_CONFLICT)
+		}
+		blocks = append(blocks, block)
+
+		// record the config history incrementally, as during the commit of the blocks
+		if len(lsccWrites) == 0 {
+			continue
+		}
+		updates := ledger.StateUpdates{"lscc": lsccWrites}
+		assert.NoError(t, incrementalState.apply(updates))
+		assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{
+			LedgerID:                "ledger1",
+			StateUpdates:            updates,
+			CommittingBlockNum:      uint64(blockNum),
+			PostCommitQueryExecutor: incrementalState,
+		}))
+	}
+
+	// stale entries in the ledger being rebuilt are discarded
+	testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(&ccInfoProvider.DeployedChaincodeInfoProvider, "stale-cc",
+		sampleCollectionConfigPackage("stale-cc", 3))
+	assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger2", CommittingBlockNum: 3}))
+	ccInfoProvider.equipLsccLikeStubs()
+
+	assert.NoError(t, m.RebuildFromBlocks("ledger2", &sliceBlockIterator{blocks: blocks}))
+
+	// the rebuilt config history is indistinguishable from the one built incrementally
+	original, rebuilt := &bytes.Buffer{}, &bytes.Buffer{}
+	assert.NoError(t, m.ExportConfigHistory("ledger1", original))
+	assert.NoError(t, m.ExportConfigHistory("ledger2", rebuilt))
+	assert.Equal(t, original.Bytes(), rebuilt.Bytes())
+
+	retriever := m.GetRetriever("ledger2", &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 6}})
+	for _, ccName := range []string{"cc1", "cc2", "cc3", "stale-cc"} {
+		collConfigs, err := retriever.AllCollectionConfigs(ccName, 0, "", NewestFirst)
+		assert.NoError(t, err)
+		var blockNums []uint64
+		for _, collConfig := range collConfigs.Configs {
+			blockNums = append(blockNums, collConfig.CommittingBlockNum)
+		}
+		expectedBlockNums := map[string][]uint64{
+			"cc1": {5, 4, 1},
+			"cc2": {4},
+		}[ccName]
+		assert.Equal(t, expectedBlockNums, blockNums, "chaincode [%s]", ccName)
+	}
+	collConfig, err := retriever.CollectionConfigAt(5, "cc1")
+	assert.NoError(t, err)
+	assert.True(t, proto.Equal(sampleCollectionConfigPackage("cc1", 5), collConfig.CollectionConfig))
+	collConfig, err = retriever.CollectionConfigAt(4, "cc1")
+	assert.NoError(t, err)
+	assert.True(t, proto.Equal(sampleCollectionConfigPackage("cc1", 1), collConfig.CollectionConfig))
+
+	t.Run("iterator-failure", func(t *testing.T) {
+		err := m.RebuildFromBlocks("ledger3", &sliceBlockIterator{blocks: blocks[:2], err: errors.New("iterator-failure")})
+		assert.EqualError(t, err, "error while retrieving the block to replay: iterator-failure")
+	})
+}
+
+// lsccLikeCCInfoProvider is a `ledger.DeployedChaincodeInfoProvider` that, like the one of lscc, derives
+// the chaincode info from the state of the namespace "lscc"
+type lsccLikeCCInfoProvider struct {
+	mock.DeployedChaincodeInfoProvider
+}
+
+func newLsccLikeCCInfoProvider() *lsccLikeCCInfoProvider {
+	p := &lsccLikeCCInfoProvider{}
+	p.NamespacesReturns([]string{"lscc"})
+	p.equipLsccLikeStubs()
+	return p
+}
+
+func (p *lsccLikeCCInfoProvider) equipLsccLikeStubs() {
+	p.UpdatedChaincodesStub = func(stateUpdates map[string][]*kvrwset.KVWrite) ([]*ledger.ChaincodeLifecycleInfo, error) {
+		ccNames := map[string]bool{}
+		for _, kvWrite := range stateUpdates["lscc"] {
+			ccNames[strings.TrimSuffix(kvWrite.Key, "~collection")] = true
+		}
+		var sortedCCNames []string
+		for ccName := range ccNames {
+			sortedCCNames = append(sortedCCNames, ccName)
+		}
+		sort.Strings(sortedCCNames)
+		var lifecycleInfo []*ledger.ChaincodeLifecycleInfo
+		for _, ccName := range sortedCCNames {
+			lifecycleInfo = append(lifecycleInfo, &ledger.ChaincodeLifecycleInfo{Name: ccName})
+		}
+		return lifecycleInfo, nil
+	}
+	p.ChaincodeInfoStub = func(chaincodeName string, qe ledger.SimpleQueryExecutor) (*ledger.DeployedChaincodeInfo, error) {
+		collConfigBytes, err := qe.GetState("lscc", chaincodeName+"~collection")
+		if err != nil || collConfigBytes == nil {
+			return &ledger.DeployedChaincodeInfo{Name: chaincodeName}, err
+		}
+		collConfigPkg := &common.CollectionConfigPackage{}
+		if err := proto.Unmarshal(collConfigBytes, collConfigPkg); err != nil {
+			return nil, err
+		}
+		return &ledger.DeployedChaincodeInfo{Name: chaincodeName, CollectionConfigPkg: collConfigPkg}, nil
+	}
+}
+
+type sliceBlockIterator struct {
+	blocks []*common.Block
+	err    error
+}
+
+func (itr *sliceBlockIterator) Next() (*common.Block, error) {
+	if len(itr.blocks) == 0 {
+		return nil, itr.err
+	}
+	block := itr.blocks[0]
+	itr.blocks = itr.blocks[1:]
+	return block, nil
+}
@@ -0,0 +1,102 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/pkg/errors"
+)
+
+// ReconcileReport is the outcome of the function `Reconcile`
+type ReconcileReport struct {
+	// NumChecked is the number of chaincodes for which the config history was checked against the state
+	NumChecked int
+	// Mismatches contains, in the order of the chaincode names, the chaincodes for which the config history diverges from the state
+	Mismatches []*ReconcileMismatch
+}
+
+// Consistent returns true if the config history of none of the chaincodes diverges from the state
+func (r *ReconcileReport) Consistent() bool {
+	return len(r.Mismatches) == 0
+}
+
+// ReconcileMismatch describes a chaincode whose most recent collection config in the config history differs from the one in the state
+type ReconcileMismatch struct {
+	ChaincodeName string
+	// StoredBlockNum and StoredConfig are the block number and the collection config of the most recent entry in the config history
+	StoredBlockNum uint64
+	StoredConfig   *common.CollectionConfigPackage
+	// CurrentConfig is the collection config of the chaincode in the state. This is nil if the chaincode is no longer
+	// present in the state or has no collection config
+	CurrentConfig *common.CollectionConfigPackage
+}
+
+// String returns a single line representation of the mismatch
+func (m *ReconcileMismatch) String() string {
+	return fmt.Sprintf("chaincode=%s, storedBlockNum=%d, storedConfig=[%s], currentConfig=[%s]",
+		m.ChaincodeName, m.StoredBlockNum, proto.CompactTextString(m.StoredConfig), proto.CompactTextString(m.CurrentConfig))
+}
+
+// Reconcile implements function in the interface 'Mgr'. For each chaincode that has a collection config in the config
+// history of the given ledger, it compares the most recent stored collection config against the collection config in the
+// current state of the ledger, as returned by the function `ChaincodeInfo` of the `ledger.DeployedChaincodeInfoProvider`.
+// The state is read via a query executor obtained from the given ledgerInfoRetriever. This is intended to be invoked on
+// a restart of the peer for catching a config history that diverged from the state due to a crash. The mismatches are
+// only reported; it is left to the operator to decide whether to rebuild the config history (see function `RebuildFromBlocks`)
+func (m *mgr) Reconcile(ledgerID string, ledgerInfoRetriever LedgerInfoRetriever) (*ReconcileReport, error) {
+	if err := m.WaitForPendingWrites(); err != nil {
+		return nil, err
+	}
+	dbHandle := m.dbProvider.getDB(ledgerID)
+	keys, err := dbHandle.keysWithPrefix(collectionConfigNamespace, "")
+	if err != nil {
+		return nil, err
+	}
+	qe, err := ledgerInfoRetriever.NewQueryExecutor()
+	if err != nil {
+		return nil, err
+	}
+	defer qe.Done()
+
+	report := &ReconcileReport{}
+	for _, key := range keys {
+		chaincodeName, ok := chaincodeNameFromKey(key)
+		if !ok {
+			continue
+		}
+		stored, err := latestCollectionConfig(dbHandle, collectionConfigNamespace, chaincodeName)
+		if err != nil {
+			return nil, err
+		}
+		ccInfo, err := m.chaincodeInfo(collectionConfigNamespace, chaincodeName, qe)
+		if err != nil {
+			return nil, errors.WithMessage(err, fmt.Sprintf("error while retrieving the chaincode info of chaincode [%s]", chaincodeName))
+		}
+		report.NumChecked++
+		var current *common.CollectionConfigPackage
+		if ccInfo != nil {
+			current = ccInfo.CollectionConfigPkg
+		}
+		if current != nil && proto.Equal(stored.CollectionConfig, current) {
+			continue
+		}
+		mismatch := &ReconcileMismatch{
+			ChaincodeName:  chaincodeName,
+			StoredBlockNum: stored.CommittingBlockNum,
+			StoredConfig:   stored.CollectionConfig,
+			CurrentConfig:  current,
+		}
+		logger.Warningf("Config history of ledger [%s] diverges from the state: %s", ledgerID, mismatch)
+		report.Mismatches = append(report.Mismatches, mismatch)
+	}
+	logger.Infof("Reconciled config history of ledger [%s] with the state: [%d] chaincodes checked, [%d] mismatches found",
+		ledgerID, report.NumChecked, len(report.Mismatches))
+	return report, nil
+}
@@ -0,0 +1,79 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/mock"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReconcile(t *testing.T) {
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(NewMemStoreProvider()))
+	defer m.Close()
+	ledgerInfoRetriever := &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 100}}
+
+	for _, ccName := range []string{"chaincode1", "chaincode2", "chaincode3", "chaincode4"} {
+		for _, blockNum := range []uint64{10, 20} {
+			testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, ccName,
+				sampleCollectionConfigPackage(ccName, blockNum))
+			assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: blockNum}))
+		}
+	}
+
+	// the state as it would be if the commit of the block 30 crashed after updating the state but before updating the config history
+	currentState := map[string]*ledger.DeployedChaincodeInfo{
+		"chaincode1": {Name: "chaincode1", CollectionConfigPkg: sampleCollectionConfigPackage("chaincode1", 20)},
+		"chaincode2": {Name: "chaincode2", CollectionConfigPkg: sampleCollectionConfigPackage("chaincode2", 30)},
+		"chaincode3": {Name: "chaincode3"},
+		"chaincode4": {Name: "chaincode4", CollectionConfigPkg: sampleCollectionConfigPackage("chaincode4", 20)},
+	}
+	mockCCInfoProvider.ChaincodeInfoStub = func(ccName string, qe ledger.SimpleQueryExecutor) (*ledger.DeployedChaincodeInfo, error) {
+		return currentState[ccName], nil
+	}
+
+	report, err := m.Reconcile("ledger1", ledgerInfoRetriever)
+	assert.NoError(t, err)
+	assert.False(t, report.Consistent())
+	assert.Equal(t, 4, report.NumChecked)
+	assert.Len(t, report.Mismatches, 2)
+	assert.Equal(t, "chaincode2", report.Mismatches[0].ChaincodeName)
+	assert.Equal(t, uint64(20), report.Mismatches[0].StoredBlockNum)
+	assert.True(t, proto.Equal(sampleCollectionConfigPackage("chaincode2", 20), report.Mismatches[0].StoredConfig))
+	assert.True(t, proto.Equal(sampleCollectionConfigPackage("chaincode2", 30), report.Mismatches[0].CurrentConfig))
+	assert.Equal(t, "chaincode3", report.Mismatches[1].ChaincodeName)
+	assert.Nil(t, report.Mismatches[1].CurrentConfig)
+
+	// the mismatches are only reported and the config history is left untouched
+	collConfig, err := m.GetRetriever("ledger1", ledgerInfoRetriever).MostRecentCollectionConfigBelow(100, "chaincode2")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(20), collConfig.CommittingBlockNum)
+
+	delete(currentState, "chaincode4")
+	currentState["chaincode2"].CollectionConfigPkg = sampleCollectionConfigPackage("chaincode2", 20)
+	currentState["chaincode3"].CollectionConfigPkg = sampleCollectionConfigPackage("chaincode3", 20)
+	report, err = m.Reconcile("ledger1", ledgerInfoRetriever)
+	assert.NoError(t, err)
+	assert.Len(t, report.Mismatches, 1)
+	assert.Equal(t, "chaincode4", report.Mismatches[0].ChaincodeName)
+	assert.Nil(t, report.Mismatches[0].CurrentConfig)
+
+	report, err = m.Reconcile("ledger2", ledgerInfoRetriever)
+	assert.NoError(t, err)
+	assert.True(t, report.Consistent())
+	assert.Equal(t, 0, report.NumChecked)
+
+	mockCCInfoProvider.ChaincodeInfoReturns(nil, errors.New("chaincode-info-error"))
+	_, err = m.Reconcile("ledger1", ledgerInfoRetriever)
+	assert.EqualError(t, err, "error while retrieving the chaincode info of chaincode [chaincode1]: chaincode-info-error")
+}
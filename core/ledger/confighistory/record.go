@@ -0,0 +1,61 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/protos/common"
+	chpb "github.com/hyperledger/fabric/protos/ledger/confighistory"
+	"github.com/pkg/errors"
+)
+
+// A collection config is persisted either as a bare marshalled `common.CollectionConfigPackage`, as in version 1.2, or as a
+// record, i.e., a marshalled `CollectionConfigRecord` preceded by the recordMarker and the format version. A marshalled message
+// never begins with a zero byte (the field number zero is invalid) and hence, the two are told apart by the first byte. The
+// format version is bumped only for a change that the older decoders cannot skip over; the new fields in the record do not need it
+const (
+	recordMarker        = byte(0x00)
+	recordFormatVersion = byte(1)
+)
+
+// encodeCollectionConfig encodes the given marshalled collection config for persisting in the db. If asRecord is false,
+// the collection config is persisted as is, which the peers that predate the records can decode
+func encodeCollectionConfig(collConfig *common.CollectionConfigPackage, configBytes []byte, asRecord bool) ([]byte, error) {
+	if !asRecord {
+		return configBytes, nil
+	}
+	recordBytes, err := marshalDeterministically(&chpb.CollectionConfigRecord{CollectionConfig: collConfig})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return append([]byte{recordMarker, recordFormatVersion}, recordBytes...), nil
+}
+
+// decodeCollectionConfig decodes a collection config persisted in the db, in either of the formats
+func decodeCollectionConfig(value []byte) (*common.CollectionConfigPackage, error) {
+	if len(value) == 0 || value[0] != recordMarker {
+		collConfig := &common.CollectionConfigPackage{}
+		if err := proto.Unmarshal(value, collConfig); err != nil {
+			return nil, err
+		}
+		return collConfig, nil
+	}
+	if len(value) < 2 {
+		return nil, errors.New("truncated collection config record")
+	}
+	if value[1] != recordFormatVersion {
+		return nil, errors.Errorf("unsupported format version [%d] of the collection config record", value[1])
+	}
+	record := &chpb.CollectionConfigRecord{}
+	if err := proto.Unmarshal(value[2:], record); err != nil {
+		return nil, err
+	}
+	if record.CollectionConfig == nil {
+		return &common.CollectionConfigPackage{}, nil
+	}
+	return record.CollectionConfig, nil
+}
@@ -0,0 +1,151 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/common/cauthdsl"
+	"github.com/hyperledger/fabric/common/ledger/util/leveldbhelper"
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/mock"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollectionConfigRecord(t *testing.T) {
+	collConfigPkg := sampleCollectionConfigPackage("coll", 10)
+	configBytes, err := marshalDeterministically(collConfigPkg)
+	assert.NoError(t, err)
+
+	t.Run("encode-decode", func(t *testing.T) {
+		value, err := encodeCollectionConfig(collConfigPkg, configBytes, false)
+		assert.NoError(t, err)
+		assert.Equal(t, configBytes, value)
+
+		value, err = encodeCollectionConfig(collConfigPkg, configBytes, true)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte{recordMarker, recordFormatVersion}, value[:2])
+		decoded, err := decodeCollectionConfig(value)
+		assert.NoError(t, err)
+		assert.True(t, proto.Equal(collConfigPkg, decoded))
+
+		// the legacy values, including an empty collection config, are decoded as is
+		for _, legacyValue := range [][]byte{configBytes, {}} {
+			decoded, err := decodeCollectionConfig(legacyValue)
+			assert.NoError(t, err)
+			expected := &common.CollectionConfigPackage{}
+			assert.NoError(t, proto.Unmarshal(legacyValue, expected))
+			assert.True(t, proto.Equal(expected, decoded))
+		}
+	})
+
+	t.Run("invalid-record", func(t *testing.T) {
+		_, err := decodeCollectionConfig([]byte{recordMarker})
+		assert.EqualError(t, err, "truncated collection config record")
+		_, err = decodeCollectionConfig([]byte{recordMarker, 2, 0x0a})
+		assert.EqualError(t, err, "unsupported format version [2] of the collection config record")
+		_, err = decodeCollectionConfig([]byte{recordMarker, recordFormatVersion, 0x0a, 0x05})
+		assert.Error(t, err)
+	})
+
+	t.Run("mixed-formats", func(t *testing.T) {
+		mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+		storeProvider := NewMemStoreProvider()
+		commit := func(m *mgr, blockNum uint64) {
+			testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, "chaincode1",
+				sampleCollectionConfigPackage("coll", blockNum))
+			assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: blockNum}))
+		}
+		// the first mgr is not closed, as closing it would discard the in-memory stores
+		m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(storeProvider))
+		commit(m, 10)
+		m = newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(storeProvider), WithCollectionConfigRecords(true))
+		defer m.Close()
+		commit(m, 20)
+
+		retriever := m.GetRetriever("ledger1", &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 100}})
+		for _, blockNum := range []uint64{10, 20} {
+			_, value, err := retriever.RawEntryAt(blockNum, "chaincode1")
+			assert.NoError(t, err)
+			assert.Equal(t, blockNum == 20, value[0] == recordMarker)
+			collConfig, err := retriever.CollectionConfigAt(blockNum, "chaincode1")
+			assert.NoError(t, err)
+			assert.True(t, proto.Equal(sampleCollectionConfigPackage("coll", blockNum), collConfig.CollectionConfig))
+		}
+
+		stream := &collectingStream{}
+		assert.NoError(t, m.StreamConfigHistory("ledger1", stream))
+		assert.Len(t, stream.sent, 2)
+		for _, msg := range stream.sent {
+			assert.True(t, proto.Equal(sampleCollectionConfigPackage("coll", msg.BlockNum), msg.CollectionConfig))
+		}
+	})
+}
+
+func TestLegacyEntries(t *testing.T) {
+	// an entry as written by version 1.2 for the collection config of chaincode "mycc" committed at block 10,
+	// i.e., a bare marshalled collection config package under the composite key in the namespace "lscc"
+	legacyKey := []byte("slscc\x00mycc~collection\xff\xff\xff\xff\xff\xff\xff\xf5")
+	legacyValue, err := hex.DecodeString("0a3b0a390a05636f6c6c31122a0a28120c120a080112020800120208011a0b12090a07" +
+		"4f7267314d53501a0b12090a074f7267324d5350180120022864")
+	assert.NoError(t, err)
+	assert.Equal(t, legacyKey, encodeCompositeKey(collectionConfigNamespace, constructCollectionConfigKey("mycc"), 10))
+
+	expectedCollConfigPkg := collConfigPkg(&common.StaticCollectionConfig{
+		Name: "coll1",
+		MemberOrgsPolicy: &common.CollectionPolicyConfig{
+			Payload: &common.CollectionPolicyConfig_SignaturePolicy{
+				SignaturePolicy: cauthdsl.SignedByAnyMember([]string{"Org1MSP", "Org2MSP"}),
+			},
+		},
+		RequiredPeerCount: 1,
+		MaximumPeerCount:  2,
+		BlockToLive:       100,
+	})
+
+	testCases := []struct {
+		name string
+		opts []Option
+	}{
+		{"default", nil},
+		{"with-records", []Option{WithCollectionConfigRecords(true)}},
+		{"with-cache-and-index", []Option{WithCacheSize(10), WithBlockIndex()}},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			storeProvider := NewMemStoreProvider()
+			batch := leveldbhelper.NewUpdateBatch()
+			batch.Put(legacyKey, legacyValue)
+			assert.NoError(t, storeProvider.GetStore("ledger1").WriteBatch(batch, true))
+			m := newMgrWithDBProvider(&mock.DeployedChaincodeInfoProvider{}, newDBProviderWithStore(storeProvider), testCase.opts...)
+			defer m.Close()
+			retriever := m.GetRetriever("ledger1", &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 100}})
+
+			collConfig, err := retriever.CollectionConfigAt(10, "mycc")
+			assert.NoError(t, err)
+			assert.Equal(t, uint64(10), collConfig.CommittingBlockNum)
+			assert.True(t, proto.Equal(expectedCollConfigPkg, collConfig.CollectionConfig))
+
+			collConfig, err = retriever.MostRecentCollectionConfigBelow(50, "mycc")
+			assert.NoError(t, err)
+			assert.Equal(t, uint64(10), collConfig.CommittingBlockNum)
+			assert.True(t, proto.Equal(expectedCollConfigPkg, collConfig.CollectionConfig))
+
+			collConfig, err = retriever.MostRecentCollectionConfigBelow(10, "mycc")
+			assert.NoError(t, err)
+			assert.Nil(t, collConfig)
+
+			blockNum, ok, err := retriever.LatestConfigBlock("mycc")
+			assert.NoError(t, err)
+			assert.True(t, ok)
+			assert.Equal(t, uint64(10), blockNum)
+		})
+	}
+}
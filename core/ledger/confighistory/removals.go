@@ -0,0 +1,115 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"math"
+
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/pkg/errors"
+)
+
+// CollectionRemovalEvent reports a collection of a chaincode that is present in a version of the collection config and
+// absent in the next version
+type CollectionRemovalEvent struct {
+	CollectionName string
+	// PreviousBlockNum is the block at which the last version that contains the collection is committed
+	PreviousBlockNum uint64
+	// BlockNum is the block at which the version that does not contain the collection is committed
+	BlockNum uint64
+}
+
+// DetectCollectionRemovals implements function from the interface `Retriever`. It walks the versions of the collection config of
+// the chaincode that are committed at the blocks in the range [fromBlock, toBlock] (both inclusive) and returns an event for each
+// collection that is present in a version and absent in the next version, in the increasing order of the block at which the
+// collection disappeared. The version in effect before `fromBlock` is compared with the first version in the range, so that a
+// removal committed at `fromBlock` is reported. Only the persisted (explicit) collections are considered; the implicit collections
+// are derived from the latest state and hence, do not change across the versions
+func (r *retriever) DetectCollectionRemovals(chaincodeName string, fromBlock, toBlock uint64) ([]CollectionRemovalEvent, error) {
+	if fromBlock > toBlock {
+		return nil, errors.Errorf("invalid block range: start block [%d] is greater than end block [%d]", fromBlock, toBlock)
+	}
+	key := constructCollectionConfigKey(chaincodeName)
+	kvs, _, err := r.dbHandle.entriesInRange(r.namespace, key, fromBlock, toBlock, 0, NewestFirst)
+	if err != nil {
+		return nil, err
+	}
+	if fromBlock > 0 {
+		kv, err := r.dbHandle.mostRecentEntryBelow(fromBlock, r.namespace, key)
+		if err != nil {
+			return nil, err
+		}
+		if kv != nil {
+			kvs = append(kvs, kv)
+		}
+	}
+
+	var events []CollectionRemovalEvent
+	var previous *ledger.CollectionConfigInfo
+	// the entries are in the decreasing order of block numbers
+	for i := len(kvs) - 1; i >= 0; i-- {
+		current, err := compositeKVToCollectionConfig(kvs[i])
+		if err != nil {
+			return nil, err
+		}
+		if previous != nil {
+			currentColls := staticCollectionNames(current)
+			for _, collName := range orderedStaticCollectionNames(previous) {
+				if !currentColls[collName] {
+					events = append(events, CollectionRemovalEvent{
+						CollectionName:   collName,
+						PreviousBlockNum: previous.CommittingBlockNum,
+						BlockNum:         current.CommittingBlockNum,
+					})
+				}
+			}
+		}
+		previous = current
+	}
+	return events, nil
+}
+
+// CollectionIntroducedAt implements function from the interface `Retriever`. It walks the versions of the collection config
+// of the chaincode from the oldest and returns the block at which the first version that contains the given collection is
+// committed. If the collection is removed and added back later, the block of the first addition is returned. As in the function
+// `DetectCollectionRemovals`, only the persisted (explicit) collections are considered. After a pruning, the walk starts from
+// the oldest retained version (see function `OldestConfigBlock`). The returned bool is false if no version contains the collection
+func (r *retriever) CollectionIntroducedAt(chaincodeName, collectionName string) (uint64, bool, error) {
+	kvs, _, err := r.dbHandle.entriesInRange(r.namespace, constructCollectionConfigKey(chaincodeName), 0, math.MaxUint64, 0, OldestFirst)
+	if err != nil {
+		return 0, false, err
+	}
+	for _, kv := range kvs {
+		collConfig, err := compositeKVToCollectionConfig(kv)
+		if err != nil {
+			return 0, false, err
+		}
+		if staticCollectionNames(collConfig)[collectionName] {
+			return collConfig.CommittingBlockNum, true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+func staticCollectionNames(info *ledger.CollectionConfigInfo) map[string]bool {
+	names := map[string]bool{}
+	for _, name := range orderedStaticCollectionNames(info) {
+		names[name] = true
+	}
+	return names
+}
+
+// orderedStaticCollectionNames returns the names of the static collections, in the order in which they appear in the config
+func orderedStaticCollectionNames(info *ledger.CollectionConfigInfo) []string {
+	var names []string
+	for _, collConfig := range info.CollectionConfig.GetConfig() {
+		if staticCollConfig := collConfig.GetStaticCollectionConfig(); staticCollConfig != nil {
+			names = append(names, staticCollConfig.Name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,115 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/mock"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectCollectionRemovals(t *testing.T) {
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(NewMemStoreProvider()))
+	defer m.Close()
+
+	collConfigPkg := func(collNames ...string) *common.CollectionConfigPackage {
+		pkg := &common.CollectionConfigPackage{}
+		for _, collName := range collNames {
+			pkg.Config = append(pkg.Config, &common.CollectionConfig{Payload: &common.CollectionConfig_StaticCollectionConfig{
+				StaticCollectionConfig: &common.StaticCollectionConfig{Name: collName}}})
+		}
+		return pkg
+	}
+	for _, version := range []struct {
+		blockNum  uint64
+		collNames []string
+	}{
+		{10, []string{"coll1", "coll2", "coll3"}},
+		{20, []string{"coll1", "coll3", "coll4"}},
+		{30, []string{"coll1", "coll3", "coll4", "coll5"}},
+		{40, []string{"coll4"}},
+	} {
+		testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, "chaincode1", collConfigPkg(version.collNames...))
+		assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: version.blockNum}))
+	}
+	retriever := m.GetRetriever("ledger1", &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 100}})
+
+	events, err := retriever.DetectCollectionRemovals("chaincode1", 0, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, []CollectionRemovalEvent{
+		{CollectionName: "coll2", PreviousBlockNum: 10, BlockNum: 20},
+		{CollectionName: "coll1", PreviousBlockNum: 30, BlockNum: 40},
+		{CollectionName: "coll3", PreviousBlockNum: 30, BlockNum: 40},
+		{CollectionName: "coll5", PreviousBlockNum: 30, BlockNum: 40},
+	}, events)
+
+	// the version in effect before the start of the range is compared with the first version in the range
+	events, err = retriever.DetectCollectionRemovals("chaincode1", 20, 35)
+	assert.NoError(t, err)
+	assert.Equal(t, []CollectionRemovalEvent{{CollectionName: "coll2", PreviousBlockNum: 10, BlockNum: 20}}, events)
+
+	events, err = retriever.DetectCollectionRemovals("chaincode1", 21, 35)
+	assert.NoError(t, err)
+	assert.Nil(t, events)
+
+	events, err = retriever.DetectCollectionRemovals("chaincode2", 0, 100)
+	assert.NoError(t, err)
+	assert.Nil(t, events)
+
+	_, err = retriever.DetectCollectionRemovals("chaincode1", 50, 40)
+	assert.EqualError(t, err, "invalid block range: start block [50] is greater than end block [40]")
+}
+
+func TestCollectionIntroducedAt(t *testing.T) {
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(NewMemStoreProvider()))
+	defer m.Close()
+
+	for _, version := range []struct {
+		blockNum      uint64
+		collConfigPkg *common.CollectionConfigPackage
+	}{
+		{10, collConfigPkg(coll("coll1", nil, 0), coll("coll2", nil, 0))},
+		{20, collConfigPkg(coll("coll1", nil, 0), coll("coll3", nil, 0))},
+		{30, collConfigPkg(coll("coll1", nil, 0), coll("coll2", nil, 0), coll("coll3", nil, 0))},
+	} {
+		testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, "chaincode1", version.collConfigPkg)
+		assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: version.blockNum}))
+	}
+	retriever := m.GetRetriever("ledger1", &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 100}})
+
+	testCases := []struct {
+		chaincodeName, collectionName string
+		expectedBlockNum              uint64
+		expectedFound                 bool
+	}{
+		{"chaincode1", "coll1", 10, true},
+		// removed at block 20 and added back at block 30
+		{"chaincode1", "coll2", 10, true},
+		{"chaincode1", "coll3", 20, true},
+		{"chaincode1", "coll4", 0, false},
+		{"chaincode2", "coll1", 0, false},
+	}
+	for _, testCase := range testCases {
+		blockNum, found, err := retriever.CollectionIntroducedAt(testCase.chaincodeName, testCase.collectionName)
+		assert.NoError(t, err)
+		assert.Equal(t, testCase.expectedFound, found, "%+v", testCase)
+		assert.Equal(t, testCase.expectedBlockNum, blockNum, "%+v", testCase)
+	}
+
+	// after a pruning, the walk starts from the oldest retained version
+	_, err := m.PruneAllBelow(map[string]uint64{"ledger1": 25})
+	assert.NoError(t, err)
+	blockNum, found, err := retriever.CollectionIntroducedAt("chaincode1", "coll2")
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, uint64(30), blockNum)
+}
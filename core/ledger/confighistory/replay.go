@@ -0,0 +1,71 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"bytes"
+
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/pkg/errors"
+)
+
+// skipReplayedConfigs returns the given collection configs except the ones for which the db already contains an entry at the
+// committing block, as is the case when a block is committed again during the recovery of the ledger. An existing entry with the
+// same collection config makes the write redundant. An existing entry with a different collection config indicates that the
+// chaincode info provider is not deterministic; this is logged as a warning and the existing entry is retained, unless the
+// overwrites are enabled (see function `WithReplayOverwrites`). The collection configs are compared by their deterministically
+// marshalled bytes, so that an entry written in either of the formats (see function `WithCollectionConfigRecords`) is recognized
+func (m *mgr) skipReplayedConfigs(ledgerID string, ccInfosByNamespace map[string][]*ledger.DeployedChaincodeInfo, committingBlockNum uint64) (
+	map[string][]*ledger.DeployedChaincodeInfo, error) {
+	dbHandle := m.dbProvider.getDB(ledgerID)
+	remaining := map[string][]*ledger.DeployedChaincodeInfo{}
+	for ns, ccInfos := range ccInfosByNamespace {
+		for _, ccInfo := range ccInfos {
+			existing, err := dbHandle.entryAt(committingBlockNum, ns, constructCollectionConfigKey(ccInfo.Name))
+			if err != nil {
+				return nil, err
+			}
+			if existing == nil {
+				remaining[ns] = append(remaining[ns], ccInfo)
+				continue
+			}
+			identical, err := sameCollectionConfig(existing.value, ccInfo)
+			if err != nil {
+				return nil, err
+			}
+			switch {
+			case identical:
+				logger.Debugf("Skipping the collection config of chaincode [%s] for block [%d] of ledger [%s] as it is already recorded",
+					ccInfo.Name, committingBlockNum, ledgerID)
+			case m.replayOverwrites:
+				logger.Warningf("Overwriting the recorded collection config of chaincode [%s] for block [%d] of ledger [%s] with a different one, "+
+					"the chaincode info provider may not be deterministic", ccInfo.Name, committingBlockNum, ledgerID)
+				remaining[ns] = append(remaining[ns], ccInfo)
+			default:
+				logger.Warningf("Retaining the recorded collection config of chaincode [%s] for block [%d] of ledger [%s] that differs from the one "+
+					"being recorded again, the chaincode info provider may not be deterministic", ccInfo.Name, committingBlockNum, ledgerID)
+			}
+		}
+	}
+	return remaining, nil
+}
+
+func sameCollectionConfig(existingValue []byte, ccInfo *ledger.DeployedChaincodeInfo) (bool, error) {
+	existingConfig, err := decodeCollectionConfig(existingValue)
+	if err != nil {
+		return false, errors.Wrap(err, "error while decoding the recorded collection config")
+	}
+	existingBytes, err := marshalDeterministically(existingConfig)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	configBytes, err := marshalDeterministically(ccInfo.CollectionConfigPkg)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	return bytes.Equal(existingBytes, configBytes), nil
+}
@@ -0,0 +1,111 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"math"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/mock"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandleStateUpdatesReplay(t *testing.T) {
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	commit := func(m *mgr, blockNum uint64, collConfigPkg *common.CollectionConfigPackage) error {
+		testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, "chaincode1", collConfigPkg)
+		return m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: blockNum})
+	}
+	dummyLedgerInfoRetriever := &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 100}}
+
+	t.Run("identical", func(t *testing.T) {
+		m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(NewMemStoreProvider()), WithMonotonicityCheck(true))
+		defer m.Close()
+		assert.NoError(t, commit(m, 10, sampleCollectionConfigPackage("coll", 10)))
+		assert.NoError(t, commit(m, 20, sampleCollectionConfigPackage("coll", 20)))
+		entry, err := m.dbProvider.getDB("ledger1").entryAt(10, collectionConfigNamespace, constructCollectionConfigKey("chaincode1"))
+		assert.NoError(t, err)
+
+		// the replay of the block is not written and hence, does not move the latest collection config back to the block
+		ch, cancel := m.WatchChaincode("ledger1", "chaincode1")
+		defer cancel()
+		for i := 0; i < 2; i++ {
+			assert.NoError(t, commit(m, 10, sampleCollectionConfigPackage("coll", 10)))
+		}
+		assert.Len(t, ch, 0)
+		replayedEntry, err := m.dbProvider.getDB("ledger1").entryAt(10, collectionConfigNamespace, constructCollectionConfigKey("chaincode1"))
+		assert.NoError(t, err)
+		assert.Equal(t, entry, replayedEntry)
+		retriever := m.GetRetriever("ledger1", dummyLedgerInfoRetriever)
+		blockNums, err := retriever.ConfigBlockNumbers("chaincode1")
+		assert.NoError(t, err)
+		assert.Equal(t, []uint64{10, 20}, blockNums)
+		collConfig, err := retriever.MostRecentCollectionConfigBelow(math.MaxUint64, "chaincode1")
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(20), collConfig.CommittingBlockNum)
+	})
+
+	t.Run("identical-across-formats", func(t *testing.T) {
+		m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(NewMemStoreProvider()))
+		defer m.Close()
+		assert.NoError(t, commit(m, 10, sampleCollectionConfigPackage("coll", 10)))
+		// the block is committed again after the records are enabled
+		m.writeRecords = true
+		entry, err := m.dbProvider.getDB("ledger1").entryAt(10, collectionConfigNamespace, constructCollectionConfigKey("chaincode1"))
+		assert.NoError(t, err)
+		assert.NoError(t, commit(m, 10, sampleCollectionConfigPackage("coll", 10)))
+		replayedEntry, err := m.dbProvider.getDB("ledger1").entryAt(10, collectionConfigNamespace, constructCollectionConfigKey("chaincode1"))
+		assert.NoError(t, err)
+		assert.Equal(t, entry, replayedEntry)
+	})
+
+	t.Run("different", func(t *testing.T) {
+		m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(NewMemStoreProvider()))
+		defer m.Close()
+		assert.NoError(t, commit(m, 10, sampleCollectionConfigPackage("coll", 10)))
+		assert.NoError(t, commit(m, 10, sampleCollectionConfigPackage("coll", 11)))
+		collConfig, err := m.GetRetriever("ledger1", dummyLedgerInfoRetriever).ExplicitCollectionConfigAt(10, "chaincode1")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"coll-10"}, collNames(collConfig))
+	})
+
+	t.Run("different-with-overwrites", func(t *testing.T) {
+		m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(NewMemStoreProvider()), WithReplayOverwrites())
+		defer m.Close()
+		assert.NoError(t, commit(m, 10, sampleCollectionConfigPackage("coll", 10)))
+		assert.NoError(t, commit(m, 10, sampleCollectionConfigPackage("coll", 11)))
+		collConfig, err := m.GetRetriever("ledger1", dummyLedgerInfoRetriever).ExplicitCollectionConfigAt(10, "chaincode1")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"coll-11"}, collNames(collConfig))
+	})
+
+	t.Run("older-with-overwrites", func(t *testing.T) {
+		m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(NewMemStoreProvider()), WithReplayOverwrites(), WithCacheSize(10))
+		defer m.Close()
+		assert.NoError(t, commit(m, 10, sampleCollectionConfigPackage("coll", 10)))
+		assert.NoError(t, commit(m, 20, sampleCollectionConfigPackage("coll", 20)))
+		// the replay of the older block does not move the latest pointer, nor the cached collection config, back to the block
+		ch, cancel := m.WatchChaincode("ledger1", "chaincode1")
+		defer cancel()
+		assert.NoError(t, commit(m, 10, sampleCollectionConfigPackage("coll", 11)))
+		assert.Len(t, ch, 0)
+		latest, ok, err := m.dbProvider.getDB("ledger1").latestBlockNum(collectionConfigNamespace, constructCollectionConfigKey("chaincode1"))
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, uint64(20), latest)
+		retriever := m.GetRetriever("ledger1", dummyLedgerInfoRetriever)
+		collConfig, err := retriever.MostRecentCollectionConfigBelow(math.MaxUint64, "chaincode1")
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(20), collConfig.CommittingBlockNum)
+		assert.Equal(t, []string{"coll-20"}, collNames(collConfig))
+		collConfig, err = retriever.MostRecentCollectionConfigBelow(20, "chaincode1")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"coll-11"}, collNames(collConfig))
+	})
+}
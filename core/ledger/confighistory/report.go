@@ -0,0 +1,88 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ConfigReport describes the collections of a chaincode that are in effect at a block, for display to the operators
+type ConfigReport struct {
+	LedgerID      string
+	ChaincodeName string
+	BlockNum      uint64
+	// CommittingBlockNum is the block at which the explicit collection config in effect was committed. This is zero
+	// if the chaincode has no explicit collection config at the block
+	CommittingBlockNum  uint64
+	ExplicitCollections []CollectionSummary
+	ImplicitCollections []CollectionSummary
+	// BlockToLive contains the block-to-live of the explicit and the implicit collections that are purged automatically,
+	// keyed by the collection name
+	BlockToLive map[string]uint64
+}
+
+// String returns a multi-line representation of the report, with a line for each of the collections
+func (r *ConfigReport) String() string {
+	var lines []string
+	lines = append(lines, fmt.Sprintf("ledger=%s, chaincode=%s, block=%d, committingBlock=%d",
+		r.LedgerID, r.ChaincodeName, r.BlockNum, r.CommittingBlockNum))
+	for _, s := range r.ExplicitCollections {
+		lines = append(lines, "explicit: "+s.String())
+	}
+	for _, s := range r.ImplicitCollections {
+		lines = append(lines, "implicit: "+s.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ConfigReportAt implements function from the interface `Retriever`. It returns, in a single report, the explicit and the implicit
+// collections of the chaincode that are in effect at the given block (i.e., as per the explicit collection config committed at or
+// below the block), along with the block at which the explicit collection config was committed. The collections are summarized as
+// by the function `SummarizeCollectionConfig`, except that the type of the implicit collections is `ImplicitCollection`. The implicit
+// collections are computed from the current state of the ledger, same as for the other queries. A nil report is returned if the
+// chaincode has neither an explicit nor an implicit collection
+func (r *retriever) ConfigReportAt(blockNum uint64, chaincodeName string) (*ConfigReport, error) {
+	if err := r.checkBlockCommitted(blockNum); err != nil {
+		return nil, err
+	}
+	// the block is committed and hence, it is lower than the max uint64
+	explicitConfig, err := r.explicitMostRecentCollectionConfigBelow(blockNum+1, chaincodeName)
+	if err != nil {
+		return nil, r.withContext(err, chaincodeName, blockNum)
+	}
+	implicitConfig, err := r.addImplicitCollections(chaincodeName, nil, nil)
+	if err != nil {
+		return nil, r.withContext(err, chaincodeName, blockNum)
+	}
+	if explicitConfig == nil && implicitConfig == nil {
+		return nil, r.checkChaincodeDeployed(blockNum, chaincodeName)
+	}
+
+	report := &ConfigReport{
+		LedgerID:            r.ledgerID,
+		ChaincodeName:       chaincodeName,
+		BlockNum:            blockNum,
+		ExplicitCollections: SummarizeCollectionConfig(explicitConfig),
+		ImplicitCollections: SummarizeCollectionConfig(implicitConfig),
+		BlockToLive:         map[string]uint64{},
+	}
+	if explicitConfig != nil {
+		report.CommittingBlockNum = explicitConfig.CommittingBlockNum
+	}
+	for i := range report.ImplicitCollections {
+		report.ImplicitCollections[i].Type = ImplicitCollection
+	}
+	for _, summaries := range [][]CollectionSummary{report.ExplicitCollections, report.ImplicitCollections} {
+		for _, s := range summaries {
+			if s.BlockToLive > 0 {
+				report.BlockToLive[s.Name] = s.BlockToLive
+			}
+		}
+	}
+	return report, nil
+}
@@ -0,0 +1,91 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/common/cauthdsl"
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigReportAt(t *testing.T) {
+	mockCCInfoProvider := &implicitCollsCCInfoProvider{}
+	m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(NewMemStoreProvider()), WithChaincodeNotDeployedErrors())
+	defer m.Close()
+
+	coll1 := coll("coll1", cauthdsl.SignedByAnyMember([]string{"org1", "org2"}), 100)
+	coll1.RequiredPeerCount, coll1.MaximumPeerCount = 1, 2
+	for _, version := range []struct {
+		blockNum      uint64
+		collConfigPkg *common.CollectionConfigPackage
+	}{
+		{10, collConfigPkg(coll("coll1", cauthdsl.SignedByAnyMember([]string{"org1"}), 0))},
+		{20, collConfigPkg(coll1, coll("coll2", nil, 0))},
+	} {
+		testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(&mockCCInfoProvider.DeployedChaincodeInfoProvider, "chaincode1", version.collConfigPkg)
+		assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: version.blockNum}))
+	}
+	implicitColl := sampleImplicitCollection("org1")
+	implicitColl.BlockToLive = 5
+	mockCCInfoProvider.ImplicitCollectionsReturns([]*common.StaticCollectionConfig{implicitColl}, nil)
+	retriever := m.GetRetriever("ledger1", &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 100}})
+
+	report, err := retriever.ConfigReportAt(50, "chaincode1")
+	assert.NoError(t, err)
+	assert.Equal(t,
+		&ConfigReport{
+			LedgerID:           "ledger1",
+			ChaincodeName:      "chaincode1",
+			BlockNum:           50,
+			CommittingBlockNum: 20,
+			ExplicitCollections: []CollectionSummary{
+				{Name: "coll1", Type: StaticCollection, MemberOrgs: []string{"org1", "org2"}, RequiredPeerCount: 1, MaximumPeerCount: 2, BlockToLive: 100},
+				{Name: "coll2", Type: StaticCollection},
+			},
+			ImplicitCollections: []CollectionSummary{
+				{Name: "_implicit_org_org1", Type: ImplicitCollection, MemberOrgs: []string{"org1"}, BlockToLive: 5},
+			},
+			BlockToLive: map[string]uint64{"coll1": 100, "_implicit_org_org1": 5},
+		},
+		report,
+	)
+	assert.Equal(t,
+		"ledger=ledger1, chaincode=chaincode1, block=50, committingBlock=20\n"+
+			"explicit: collection=coll1, type=static, memberOrgs=[org1,org2], requiredPeerCount=1, maximumPeerCount=2, blockToLive=100\n"+
+			"explicit: collection=coll2, type=static, memberOrgs=[], requiredPeerCount=0, maximumPeerCount=0, blockToLive=0\n"+
+			"implicit: collection=_implicit_org_org1, type=implicit, memberOrgs=[org1], requiredPeerCount=0, maximumPeerCount=0, blockToLive=5",
+		report.String(),
+	)
+
+	report, err = retriever.ConfigReportAt(15, "chaincode1")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(10), report.CommittingBlockNum)
+	assert.Equal(t, []CollectionSummary{{Name: "coll1", Type: StaticCollection, MemberOrgs: []string{"org1"}}}, report.ExplicitCollections)
+
+	// only the implicit collections are in effect before the first explicit collection config
+	report, err = retriever.ConfigReportAt(5, "chaincode1")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), report.CommittingBlockNum)
+	assert.Nil(t, report.ExplicitCollections)
+	assert.Len(t, report.ImplicitCollections, 1)
+
+	t.Run("not-deployed", func(t *testing.T) {
+		mockCCInfoProvider.ImplicitCollectionsReturns(nil, nil)
+		mockCCInfoProvider.ChaincodeInfoReturns(nil, nil)
+		report, err := retriever.ConfigReportAt(50, "chaincode2")
+		assert.Equal(t, &ledger.ErrChaincodeNotDeployed{ChaincodeName: "chaincode2", BlockNum: 50}, err)
+		assert.Nil(t, report)
+	})
+
+	t.Run("block-not-committed", func(t *testing.T) {
+		_, err := retriever.ConfigReportAt(100, "chaincode1")
+		assert.IsType(t, &ledger.ErrCollectionConfigNotYetAvailable{}, err)
+	})
+}
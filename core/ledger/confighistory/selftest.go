@@ -0,0 +1,64 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// selfTestMaxEntries bounds the number of the entries read by the function `SelfTest`, so that the test costs the same
+// irrespective of the size of the config history
+const selfTestMaxEntries = 10
+
+// SelfTest implements function in the interface 'Mgr'. It reads the first few entries of the config history of the given
+// ledger and verifies that the keys are well-formed and that the collection configs among these can be decoded. This is
+// intended to be invoked when a ledger is opened, so that a store that cannot be read fails the opening with an actionable
+// error instead of failing the first query during an endorsement. The test is read-only and reads a bounded number of entries.
+// A ledger without any config history passes the test
+func (m *mgr) SelfTest(ledgerID string) error {
+	if err := m.dbProvider.getDB(ledgerID).selfTest(); err != nil {
+		return errors.WithMessage(err, fmt.Sprintf("self-test of the config history of ledger [%s] failed; the config history db "+
+			"may be corrupted and can be rebuilt from the blocks (see function `RebuildFromBlocks`)", ledgerID))
+	}
+	return nil
+}
+
+// selfTest verifies the keys of the first few entries of the db and, separately, decodes the first few collection configs
+// of the default namespace, which the first scan may not reach
+func (d *db) selfTest() error {
+	if err := d.selfTestRange(nil, nil); err != nil {
+		return err
+	}
+	return d.selfTestRange(encodeNamespaceRange(collectionConfigNamespace))
+}
+
+func (d *db) selfTestRange(startKey, endKey []byte) error {
+	itr := d.GetIterator(startKey, endKey)
+	defer itr.Release()
+	for i := 0; i < selfTestMaxEntries && itr.Next(); i++ {
+		keyBytes := itr.Key()
+		if len(keyBytes) < len(keyPrefix)+1+8 || !bytes.HasPrefix(keyBytes, []byte(keyPrefix)) ||
+			bytes.IndexByte(keyBytes[len(keyPrefix):len(keyBytes)-8], separatorByte) < 0 {
+			return errors.Errorf("malformed key [%#v] in the config history db", keyBytes)
+		}
+		compositeKey := decodeCompositeKey(keyBytes)
+		if compositeKey.ns != collectionConfigNamespace {
+			continue
+		}
+		if _, err := compositeKVToCollectionConfig(&compositeKV{compositeKey, itr.Value()}); err != nil {
+			return errors.WithMessage(err, fmt.Sprintf("error while decoding the collection config of key [%s] at block [%d]",
+				compositeKey.key, compositeKey.blockNum))
+		}
+	}
+	if err := itr.Error(); err != nil {
+		return errors.Wrap(err, "error while iterating the config history db")
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/common/ledger/util/leveldbhelper"
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/mock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelfTest(t *testing.T) {
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, "chaincode1", sampleCollectionConfigPackage("coll", 10))
+	m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(NewMemStoreProvider()))
+
+	// a ledger without any config history passes the test
+	assert.NoError(t, m.SelfTest("ledger1"))
+	for blockNum := uint64(1); blockNum <= 2*selfTestMaxEntries; blockNum++ {
+		assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: blockNum}))
+	}
+	assert.NoError(t, m.SelfTest("ledger1"))
+
+	t.Run("undecodable-collection-config", func(t *testing.T) {
+		dbHandle := m.dbProvider.getDB("ledger2")
+		batch := newBatch()
+		batch.add(collectionConfigNamespace, constructCollectionConfigKey("chaincode1"), 10, []byte("garbage"))
+		assert.NoError(t, dbHandle.writeBatch(batch, true))
+		err := m.SelfTest("ledger2")
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "self-test of the config history of ledger [ledger2] failed")
+		assert.Contains(t, err.Error(), "error while decoding the collection config of key [chaincode1~collection] at block [10]")
+	})
+
+	t.Run("malformed-key", func(t *testing.T) {
+		dbHandle := m.dbProvider.getDB("ledger3")
+		batch := leveldbhelper.NewUpdateBatch()
+		batch.Put([]byte("s-malformed"), []byte("value"))
+		assert.NoError(t, dbHandle.WriteBatch(batch, true))
+		err := m.SelfTest("ledger3")
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "malformed key")
+	})
+
+	m.Close()
+	assert.Equal(t, ErrMgrClosed, errors.Cause(m.SelfTest("ledger1")))
+}
@@ -0,0 +1,115 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"sync"
+
+	"github.com/hyperledger/fabric/common/ledger/util/leveldbhelper"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/pkg/errors"
+)
+
+// SnapshotRetriever is a `Retriever` whose queries observe the config history as of the time the retriever is created
+// (see function `Retriever.Snapshot`). The retriever should be closed after the use, for releasing the snapshot
+type SnapshotRetriever interface {
+	Retriever
+	// Close releases the snapshot. The retriever should not be used after the close
+	Close()
+}
+
+// Snapshot implements function from the interface `Retriever`. It returns a retriever that serves all the queries from
+// a snapshot of the config history db taken at the time of this call and hence, the results of a series of queries remain
+// mutually consistent even if blocks are committed in the meantime. The height of the ledger is frozen at the same time,
+// so that the checks against the last committed block are consistent with the snapshot. The snapshot does not block the
+// commits, however, the db retains the overwritten and the deleted entries until the snapshot is released. The info of the
+// deployed chaincodes (e.g., for computing the implicit collections) is still read from the current state of the ledger.
+// The queries via the snapshot bypass the cache and the index
+func (r *retriever) Snapshot() (SnapshotRetriever, error) {
+	if _, ok := r.dbHandle.Store.(Snapshotter); !ok {
+		return nil, errors.Errorf("the store of the config history for ledger [%s] does not support snapshots", r.ledgerID)
+	}
+	// the height is captured before the snapshot, so that the snapshot includes the entries of all the blocks below the height
+	info, err := r.ledgerInfoRetriever.GetBlockchainInfo()
+	if err != nil {
+		return nil, err
+	}
+	snapshotDB, storeSnapshot, err := r.dbHandle.newSnapshotDB()
+	if err != nil {
+		return nil, err
+	}
+	snapshotRetriever := *r
+	snapshotRetriever.dbHandle = snapshotDB
+	snapshotRetriever.ledgerInfoRetriever = &frozenLedgerInfoRetriever{r.ledgerInfoRetriever, info}
+	snapshotRetriever.cache = newConfigCache(0)
+	snapshotRetriever.blockIndex = newBlockIndex(false)
+	snapshotRetriever.watchers = nil
+	return &snapshotRetrieverImpl{retriever: &snapshotRetriever, snapshot: storeSnapshot}, nil
+}
+
+// newSnapshotDB returns a read-only db over a snapshot of the store, along with the snapshot, which should be released after
+// the use. A nil db is returned if the store does not implement the interface `Snapshotter`
+func (d *db) newSnapshotDB() (*db, StoreSnapshot, error) {
+	snapshotter, ok := d.Store.(Snapshotter)
+	if !ok {
+		return nil, nil, nil
+	}
+	release, err := d.checkOpen()
+	defer release()
+	if err != nil {
+		return nil, nil, err
+	}
+	storeSnapshot, err := snapshotter.GetSnapshot()
+	if err != nil {
+		return nil, nil, errors.WithMessage(err, "error while taking a snapshot of the config history")
+	}
+	return &db{Store: &snapshotStore{storeSnapshot}, state: d.state}, storeSnapshot, nil
+}
+
+type snapshotRetrieverImpl struct {
+	*retriever
+	snapshot  StoreSnapshot
+	closeOnce sync.Once
+}
+
+// Snapshot implements function from the interface `Retriever`. The queries of a `SnapshotRetriever` are already served
+// from a snapshot and hence, a snapshot of it is not supported
+func (s *snapshotRetrieverImpl) Snapshot() (SnapshotRetriever, error) {
+	return nil, errors.Errorf("the retriever for ledger [%s] is already a snapshot", s.ledgerID)
+}
+
+// Close implements function from the interface `SnapshotRetriever`
+func (s *snapshotRetrieverImpl) Close() {
+	s.closeOnce.Do(s.snapshot.Release)
+}
+
+// snapshotStore adapts a `StoreSnapshot` to the interface `Store`, for reusing the query functions of the type `db`
+type snapshotStore struct {
+	StoreSnapshot
+}
+
+// GetReverseIterator implements function from the interface `ReverseIterable`
+func (s *snapshotStore) GetReverseIterator(startKey []byte, endKey []byte) Iterator {
+	if reverseIterable, ok := s.StoreSnapshot.(ReverseIterable); ok {
+		return reverseIterable.GetReverseIterator(startKey, endKey)
+	}
+	return reversedIterator(s.StoreSnapshot.GetIterator(startKey, endKey))
+}
+
+// WriteBatch implements function from the interface `Store`
+func (s *snapshotStore) WriteBatch(batch *leveldbhelper.UpdateBatch, sync bool) error {
+	return errors.New("the snapshot of the config history is read-only")
+}
+
+type frozenLedgerInfoRetriever struct {
+	LedgerInfoRetriever
+	info *common.BlockchainInfo
+}
+
+func (f *frozenLedgerInfoRetriever) GetBlockchainInfo() (*common.BlockchainInfo, error) {
+	return f.info, nil
+}
@@ -0,0 +1,86 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/mock"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshot(t *testing.T) {
+	dbPath := "/tmp/fabric/core/ledger/confighistory"
+	deleteTestPath(t, dbPath)
+	defer deleteTestPath(t, dbPath)
+
+	testSnapshot := func(t *testing.T, m *mgr) {
+		mockCCInfoProvider := m.ccInfoProvider.(*mock.DeployedChaincodeInfoProvider)
+		ledgerInfoRetriever := &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 21}}
+		commit := func(chaincodeName string, blockNum uint64) {
+			testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, chaincodeName,
+				sampleCollectionConfigPackage("coll", blockNum))
+			assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: blockNum}))
+		}
+		commit("chaincode1", 10)
+		commit("chaincode1", 20)
+		r := m.GetRetriever("ledger1", ledgerInfoRetriever)
+
+		snapshot, err := r.Snapshot()
+		assert.NoError(t, err)
+		defer snapshot.Close()
+		commit("chaincode1", 30)
+		commit("chaincode2", 30)
+		ledgerInfoRetriever.info = &common.BlockchainInfo{Height: 31}
+
+		collConfig, err := r.MostRecentCollectionConfigBelow(100, "chaincode1")
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(30), collConfig.CommittingBlockNum)
+
+		collConfig, err = snapshot.MostRecentCollectionConfigBelow(100, "chaincode1")
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(20), collConfig.CommittingBlockNum)
+		assert.Equal(t, []string{"coll-20"}, collNames(collConfig))
+		collConfig, err = snapshot.MostRecentCollectionConfigBelow(100, "chaincode2")
+		assert.NoError(t, err)
+		assert.Nil(t, collConfig)
+		blockNums, err := snapshot.ConfigBlockNumbers("chaincode1")
+		assert.NoError(t, err)
+		assert.Equal(t, []uint64{10, 20}, blockNums)
+		// the height of the ledger is frozen along with the config history
+		_, err = snapshot.CollectionConfigAt(30, "chaincode1")
+		assert.IsType(t, &ledger.ErrCollectionConfigNotYetAvailable{}, err)
+
+		_, err = snapshot.Snapshot()
+		assert.EqualError(t, err, "the retriever for ledger [ledger1] is already a snapshot")
+		snapshot.Close()
+		// closing again is a no-op
+		snapshot.Close()
+	}
+
+	t.Run("leveldb", func(t *testing.T) {
+		m := newMgr(&mock.DeployedChaincodeInfoProvider{}, dbPath, WithCacheSize(10), WithBlockIndex())
+		defer m.Close()
+		testSnapshot(t, m)
+	})
+
+	t.Run("memstore", func(t *testing.T) {
+		m := newMgrWithDBProvider(&mock.DeployedChaincodeInfoProvider{}, newDBProviderWithStore(NewMemStoreProvider()), WithCacheSize(10))
+		defer m.Close()
+		testSnapshot(t, m)
+	})
+
+	t.Run("not-supported", func(t *testing.T) {
+		storeProvider := &recordingStoreProvider{StoreProvider: NewMemStoreProvider()}
+		m := newMgrWithDBProvider(&mock.DeployedChaincodeInfoProvider{}, newDBProviderWithStore(storeProvider))
+		defer m.Close()
+		_, err := m.GetRetriever("ledger1", &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 21}}).Snapshot()
+		assert.EqualError(t, err, "the store of the config history for ledger [ledger1] does not support snapshots")
+	})
+}
@@ -0,0 +1,195 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"github.com/hyperledger/fabric/common/ledger/util/leveldbhelper"
+)
+
+// StoreProvider provides the key-value stores in which the config history of the ledgers is persisted.
+// The default implementation keeps the stores for all the ledgers in a single leveldb. An alternative
+// implementation can be supplied via the function `NewMgrWithStore`
+type StoreProvider interface {
+	// GetStore returns the store for the given ledger id
+	GetStore(ledgerID string) Store
+	// Close releases the resources held by the provider and its stores
+	Close()
+}
+
+// StoreLister may optionally be implemented by a `StoreProvider` for supporting the function `Mgr.LedgersWithHistory`
+// for the ledgers whose store has not been obtained since the provider was created
+type StoreLister interface {
+	// LedgerIDs returns the ids of the ledgers whose stores contain at least one key
+	LedgerIDs() ([]string, error)
+}
+
+// Store is a key-value store that holds the config history of a single ledger.
+// The encoding of the keys and the values is managed by this package and the store is expected
+// to treat them as opaque bytes, ordering the keys lexicographically
+type Store interface {
+	// Get returns the value for the given key, or nil if the key does not exist
+	Get(key []byte) ([]byte, error)
+	// WriteBatch atomically applies the updates present in the batch. A nil value in the batch represents a delete.
+	// The param sync indicates whether the write is to be flushed to the durable storage before returning
+	WriteBatch(batch *leveldbhelper.UpdateBatch, sync bool) error
+	// GetIterator returns an iterator over the keys between the startKey (inclusive) and the endKey (exclusive),
+	// in the increasing order of keys. A nil startKey represents the first available key and a nil endKey
+	// represents a logical key after the last available key. The iterator should be released after the use.
+	// The iterator is expected to present the range as of the time of this call, unaffected by the subsequent writes.
+	// Multiple iterators may be open at the same time, on different goroutines, along with the concurrent writes;
+	// however, an iterator itself is used by a single goroutine. A series of reads that need to observe the same state,
+	// across the iterators, are served from a snapshot (see interface `Snapshotter`)
+	GetIterator(startKey []byte, endKey []byte) Iterator
+}
+
+// SizeEstimator may optionally be implemented by a `Store` for supporting the function `Mgr.ApproximateSize`
+type SizeEstimator interface {
+	// ApproximateSize returns the approximate size, in bytes, of the storage used by the store
+	ApproximateSize() (uint64, error)
+}
+
+// Compactor may optionally be implemented by a `Store` for supporting the compaction on close (see function `WithCompactionOnClose`)
+type Compactor interface {
+	// Compact compacts the storage used by the store, reclaiming the space held by the deleted and the overwritten entries
+	Compact() error
+}
+
+// RangeDeleter may optionally be implemented by a `Store` for deleting the ranges of keys more efficiently than via a batch
+// with a delete for each key. The pruning and the deletion of the config history use it, if available
+type RangeDeleter interface {
+	// DeleteRange atomically deletes the keys between the startKey (inclusive) and the endKey (exclusive), with the same
+	// semantics of the nil keys as in the function `Store.GetIterator`, and returns the number of keys deleted
+	DeleteRange(startKey []byte, endKey []byte, sync bool) (int, error)
+}
+
+// ReverseIterable may optionally be implemented by a `Store`, or a `StoreSnapshot`, for iterating over a range of keys in the
+// decreasing order as efficiently as in the increasing order. The queries that return the entries oldest-first use it, if available;
+// otherwise, the range is read in the increasing order and then reversed in the memory
+type ReverseIterable interface {
+	// GetReverseIterator is same as the function `Store.GetIterator` except that the keys are presented in the decreasing order
+	GetReverseIterator(startKey []byte, endKey []byte) Iterator
+}
+
+// Snapshotter may optionally be implemented by a `Store` for supporting the function `Retriever.Snapshot`
+type Snapshotter interface {
+	// GetSnapshot returns a read-only view of the current state of the store, which is not affected by the subsequent writes.
+	// The snapshot should be released after the use
+	GetSnapshot() (StoreSnapshot, error)
+}
+
+// StoreSnapshot is a point-in-time, read-only view of a `Store`
+type StoreSnapshot interface {
+	// Get is same as the function `Store.Get`, as of the time the snapshot was taken
+	Get(key []byte) ([]byte, error)
+	// GetIterator is same as the function `Store.GetIterator`, as of the time the snapshot was taken
+	GetIterator(startKey []byte, endKey []byte) Iterator
+	// Release releases the resources held by the snapshot
+	Release()
+}
+
+// Iterator iterates over a range of keys in a `Store`
+type Iterator interface {
+	// Next moves the iterator to the next key and returns false if there is no more key
+	Next() bool
+	// Key returns the key at the current position
+	Key() []byte
+	// Value returns the value at the current position
+	Value() []byte
+	// Error returns the error, if any, that was encountered during the iteration
+	Error() error
+	// Release releases the resources held by the iterator
+	Release()
+}
+
+// leveldbStoreProvider keeps the config histories of all the ledgers in a single leveldb, opened when the provider is created.
+// The store of a ledger is a handle that prefixes the keys with the ledger id and hence, obtaining the store of a ledger does
+// not open any file and the number of open files does not grow with the number of ledgers
+type leveldbStoreProvider struct {
+	*leveldbhelper.Provider
+}
+
+type leveldbStore struct {
+	*leveldbhelper.DBHandle
+}
+
+func newLeveldbStoreProvider(dbPath string) *leveldbStoreProvider {
+	return &leveldbStoreProvider{leveldbhelper.NewProvider(&leveldbhelper.Conf{DBPath: dbPath})}
+}
+
+// LedgerIDs implements function from the interface `StoreLister`
+func (p *leveldbStoreProvider) LedgerIDs() ([]string, error) {
+	return p.DBNames()
+}
+
+// GetStore implements function from the interface `StoreProvider`
+func (p *leveldbStoreProvider) GetStore(ledgerID string) Store {
+	return &leveldbStore{p.GetDBHandle(ledgerID)}
+}
+
+// GetIterator implements function from the interface `Store`. A leveldb iterator reads from an implicit snapshot of the db
+// taken at the time of its creation
+func (s *leveldbStore) GetIterator(startKey []byte, endKey []byte) Iterator {
+	return s.DBHandle.GetIterator(startKey, endKey)
+}
+
+// GetReverseIterator implements function from the interface `ReverseIterable`
+func (s *leveldbStore) GetReverseIterator(startKey []byte, endKey []byte) Iterator {
+	return &leveldbReverseIterator{Iterator: s.DBHandle.GetIterator(startKey, endKey)}
+}
+
+// GetSnapshot implements function from the interface `Snapshotter`
+func (s *leveldbStore) GetSnapshot() (StoreSnapshot, error) {
+	snapshot, err := s.DBHandle.GetSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	return &leveldbStoreSnapshot{snapshot}, nil
+}
+
+type leveldbStoreSnapshot struct {
+	*leveldbhelper.Snapshot
+}
+
+// GetIterator implements function from the interface `StoreSnapshot`
+func (s *leveldbStoreSnapshot) GetIterator(startKey []byte, endKey []byte) Iterator {
+	return s.Snapshot.GetIterator(startKey, endKey)
+}
+
+// GetReverseIterator implements function from the interface `ReverseIterable`
+func (s *leveldbStoreSnapshot) GetReverseIterator(startKey []byte, endKey []byte) Iterator {
+	return &leveldbReverseIterator{Iterator: s.Snapshot.GetIterator(startKey, endKey)}
+}
+
+// leveldbReverseIterator moves a leveldb iterator backwards, starting from the last key of its range
+type leveldbReverseIterator struct {
+	*leveldbhelper.Iterator
+	started bool
+}
+
+func (itr *leveldbReverseIterator) Next() bool {
+	if !itr.started {
+		itr.started = true
+		return itr.Iterator.Last()
+	}
+	return itr.Iterator.Prev()
+}
+
+// reversedIterator reads the remaining keys of the given iterator and returns an iterator that presents these in the reverse
+// order. The given iterator is released
+func reversedIterator(itr Iterator) Iterator {
+	defer itr.Release()
+	reversed := &memIterator{index: -1}
+	for itr.Next() {
+		reversed.keys = append(reversed.keys, string(itr.Key()))
+		reversed.values = append(reversed.values, append([]byte(nil), itr.Value()...))
+	}
+	if reversed.err = itr.Error(); reversed.err != nil {
+		reversed.keys, reversed.values = nil, nil
+	}
+	reverseMemIterator(reversed)
+	return reversed
+}
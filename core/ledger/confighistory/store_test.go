@@ -0,0 +1,203 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"bytes"
+	"io/ioutil"
+	"sync"
+	"testing"
+
+	"github.com/hyperledger/fabric/common/ledger/util/leveldbhelper"
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/mock"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewMgrWithStore(t *testing.T) {
+	dbPath := "/tmp/fabric/core/ledger/confighistory"
+	deleteTestPath(t, dbPath)
+	defer deleteTestPath(t, dbPath)
+	storeProvider := &recordingStoreProvider{StoreProvider: newLeveldbStoreProvider(dbPath)}
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	mgr := NewMgrWithStore(mockCCInfoProvider, storeProvider)
+
+	testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, "chaincode1", sampleCollectionConfigPackage("coll", 10))
+	assert.NoError(t, mgr.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: 10}))
+	retriever := mgr.GetRetriever("ledger1", &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 100}})
+	collConfig, err := retriever.CollectionConfigAt(10, "chaincode1")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(10), collConfig.CommittingBlockNum)
+	collConfig, err = retriever.MostRecentCollectionConfigBelow(20, "chaincode1")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(10), collConfig.CommittingBlockNum)
+
+	assert.Equal(t, []string{"ledger1"}, storeProvider.storesRequested)
+	assert.Equal(t, 1, storeProvider.stores[0].numWrites)
+	// the write checks for an already recorded collection config and for the latest pointer with a get each, the most recent
+	// collection config is read via the latest pointer of the chaincode, without an iterator, and the annotation of each of the
+	// returned versions is looked up with a get
+	assert.Equal(t, 7, storeProvider.stores[0].numGets)
+	assert.Equal(t, 0, storeProvider.stores[0].numIterators)
+	mgr.Close()
+	assert.True(t, storeProvider.closed)
+}
+
+type recordingStoreProvider struct {
+	StoreProvider
+	storesRequested []string
+	stores          []*recordingStore
+	closed          bool
+}
+
+func (p *recordingStoreProvider) GetStore(ledgerID string) Store {
+	p.storesRequested = append(p.storesRequested, ledgerID)
+	s := &recordingStore{Store: p.StoreProvider.GetStore(ledgerID)}
+	p.stores = append(p.stores, s)
+	return s
+}
+
+func (p *recordingStoreProvider) Close() {
+	p.closed = true
+	p.StoreProvider.Close()
+}
+
+type recordingStore struct {
+	Store
+	numGets, numWrites, numIterators int
+}
+
+func (s *recordingStore) Get(key []byte) ([]byte, error) {
+	s.numGets++
+	return s.Store.Get(key)
+}
+
+func (s *recordingStore) WriteBatch(batch *leveldbhelper.UpdateBatch, sync bool) error {
+	s.numWrites++
+	return s.Store.WriteBatch(batch, sync)
+}
+
+func (s *recordingStore) GetIterator(startKey []byte, endKey []byte) Iterator {
+	s.numIterators++
+	return s.Store.GetIterator(startKey, endKey)
+}
+
+func TestConcurrentIteratorsAndWrites(t *testing.T) {
+	dbPath := "/tmp/fabric/core/ledger/confighistory"
+	testCases := []struct {
+		name             string
+		newStoreProvider func() StoreProvider
+	}{
+		{"leveldb", func() StoreProvider { return newLeveldbStoreProvider(dbPath) }},
+		{"memstore", NewMemStoreProvider},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			deleteTestPath(t, dbPath)
+			defer deleteTestPath(t, dbPath)
+			mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+			testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, "chaincode1",
+				sampleCollectionConfigPackage("coll", 1))
+			m := NewMgrWithStore(mockCCInfoProvider, testCase.newStoreProvider())
+			defer m.Close()
+			retriever := m.GetRetriever("ledger1", &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 1000}})
+
+			const numBlocks = 200
+			// each view of the config history is expected to contain the blocks 1..n, for some n
+			checkBlockNums := func(blockNums []uint64) {
+				for i, blockNum := range blockNums {
+					if !assert.Equal(t, uint64(i+1), blockNum) {
+						return
+					}
+				}
+			}
+			readers := []func(){
+				func() {
+					blockNums, err := retriever.ConfigBlockNumbers("chaincode1")
+					assert.NoError(t, err)
+					checkBlockNums(blockNums)
+				},
+				func() {
+					assert.NoError(t, m.ExportConfigHistory("ledger1", ioutil.Discard))
+				},
+				func() {
+					var blockNums []uint64
+					assert.NoError(t, m.ForEachConfigEntry(func(ledgerID, chaincodeName string, info *ledger.CollectionConfigInfo) error {
+						blockNums = append([]uint64{info.CommittingBlockNum}, blockNums...)
+						return nil
+					}))
+					checkBlockNums(blockNums)
+				},
+				func() {
+					snapshot, err := retriever.Snapshot()
+					if !assert.NoError(t, err) {
+						return
+					}
+					defer snapshot.Close()
+					// the queries via a snapshot are mutually consistent
+					blockNums, err := snapshot.ConfigBlockNumbers("chaincode1")
+					assert.NoError(t, err)
+					checkBlockNums(blockNums)
+					latest, ok, err := snapshot.LatestConfigBlock("chaincode1")
+					assert.NoError(t, err)
+					assert.Equal(t, len(blockNums) > 0, ok)
+					if ok {
+						assert.Equal(t, blockNums[len(blockNums)-1], latest)
+					}
+				},
+			}
+
+			done := make(chan struct{})
+			var wg sync.WaitGroup
+			for _, reader := range readers {
+				for i := 0; i < 2; i++ {
+					wg.Add(1)
+					go func(reader func()) {
+						defer wg.Done()
+						for {
+							select {
+							case <-done:
+								return
+							default:
+								reader()
+							}
+						}
+					}(reader)
+				}
+			}
+			for blockNum := uint64(1); blockNum <= numBlocks; blockNum++ {
+				assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: blockNum}))
+			}
+			close(done)
+			wg.Wait()
+
+			// the iterators open at the same time are independent of each other
+			dbHandle := m.(*mgr).dbProvider.getDB("ledger1")
+			itr1 := dbHandle.GetIterator(nil, nil)
+			defer itr1.Release()
+			assert.True(t, itr1.Next())
+			batch := newBatch()
+			batch.add(collectionConfigNamespace, constructCollectionConfigKey("chaincode1"), numBlocks+1, []byte("value"))
+			assert.NoError(t, dbHandle.writeBatch(batch, true))
+			itr2 := dbHandle.GetIterator(nil, nil)
+			defer itr2.Release()
+			numKeys1, numKeys2 := 1, 0
+			for itr2.Next() {
+				numKeys2++
+			}
+			for itr1.Next() {
+				numKeys1++
+			}
+			assert.Equal(t, numKeys1+1, numKeys2)
+
+			export := &bytes.Buffer{}
+			assert.NoError(t, m.ExportConfigHistory("ledger1", export))
+			assert.NoError(t, m.ImportConfigHistory("ledger2", export))
+		})
+	}
+}
@@ -0,0 +1,53 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	chpb "github.com/hyperledger/fabric/protos/ledger/confighistory"
+	"github.com/pkg/errors"
+)
+
+// ConfigHistoryStream is the sending side of a stream of the versions of the collection configs.
+// The server side of a gRPC server-streaming call that returns `CollectionConfigVersion` messages
+// satisfies this interface
+type ConfigHistoryStream interface {
+	Send(*chpb.CollectionConfigVersion) error
+}
+
+// StreamConfigHistory implements function in the interface 'Mgr'. It sends one message per stored version of the collection
+// config of each chaincode of the given ledger, in the order of the keys in the db, i.e., the versions are grouped by chaincode
+// and, for a chaincode, appear in the decreasing order of block numbers. The messages are sent one at a time and a message
+// is read from the db only after the previous one is accepted by the stream; so, a stream that blocks in `Send` (e.g., a gRPC
+// stream under flow control) slows down the reading accordingly. The streaming stops at the first error returned by the stream
+func (m *mgr) StreamConfigHistory(ledgerID string, stream ConfigHistoryStream) error {
+	if err := m.WaitForPendingWrites(); err != nil {
+		return err
+	}
+	startKey, endKey := encodeNamespaceRange(collectionConfigNamespace)
+	itr := m.dbProvider.getDB(ledgerID).GetIterator(startKey, endKey)
+	defer itr.Release()
+	for itr.Next() {
+		k := decodeCompositeKey(itr.Key())
+		chaincodeName, ok := chaincodeNameFromCollectionConfigKey(k.ns, k.key)
+		if !ok {
+			continue
+		}
+		collConfig, err := decodeCollectionConfig(itr.Value())
+		if err != nil {
+			return errors.Wrapf(err, "error unmarshalling the collection config of chaincode [%s] committed at block [%d]",
+				chaincodeName, k.blockNum)
+		}
+		if err := stream.Send(&chpb.CollectionConfigVersion{
+			ChaincodeName:    chaincodeName,
+			BlockNum:         k.blockNum,
+			CollectionConfig: collConfig,
+		}); err != nil {
+			return errors.WithMessage(err, "error while sending the collection config")
+		}
+	}
+	return errors.Wrap(itr.Error(), "error while iterating the config history db")
+}
@@ -0,0 +1,84 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/mock"
+	chpb "github.com/hyperledger/fabric/protos/ledger/confighistory"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamConfigHistory(t *testing.T) {
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(NewMemStoreProvider()))
+	defer m.Close()
+
+	updates := []struct {
+		ccName   string
+		blockNum uint64
+	}{
+		{"chaincode2", 5}, {"chaincode1", 10}, {"chaincode2", 15}, {"chaincode1", 20},
+	}
+	for _, update := range updates {
+		testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, update.ccName,
+			sampleCollectionConfigPackage(update.ccName, update.blockNum))
+		assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{
+			LedgerID:           "ledger1",
+			CommittingBlockNum: update.blockNum},
+		))
+	}
+	// an annotation is not a stored version of a collection config and is not streamed
+	annotationBatch := newBatch()
+	annotationBatch.add(annotationNamespace, constructCollectionConfigKey("chaincode1"), 20, []byte("note"))
+	assert.NoError(t, m.dbProvider.getDB("ledger1").writeBatch(annotationBatch, true))
+
+	stream := &collectingStream{}
+	assert.NoError(t, m.StreamConfigHistory("ledger1", stream))
+	expected := []struct {
+		ccName   string
+		blockNum uint64
+	}{
+		{"chaincode1", 20}, {"chaincode1", 10}, {"chaincode2", 15}, {"chaincode2", 5},
+	}
+	assert.Len(t, stream.sent, len(expected))
+	for i, e := range expected {
+		assert.Equal(t, e.ccName, stream.sent[i].ChaincodeName)
+		assert.Equal(t, e.blockNum, stream.sent[i].BlockNum)
+		assert.True(t, proto.Equal(sampleCollectionConfigPackage(e.ccName, e.blockNum), stream.sent[i].CollectionConfig))
+	}
+
+	t.Run("empty-ledger", func(t *testing.T) {
+		stream := &collectingStream{}
+		assert.NoError(t, m.StreamConfigHistory("ledger2", stream))
+		assert.Empty(t, stream.sent)
+	})
+
+	t.Run("stream-failure", func(t *testing.T) {
+		stream := &collectingStream{failAfter: 2}
+		err := m.StreamConfigHistory("ledger1", stream)
+		assert.EqualError(t, err, "error while sending the collection config: stream-failure")
+		assert.Len(t, stream.sent, 2)
+	})
+}
+
+type collectingStream struct {
+	sent      []*chpb.CollectionConfigVersion
+	failAfter int
+}
+
+func (s *collectingStream) Send(msg *chpb.CollectionConfigVersion) error {
+	if s.failAfter > 0 && len(s.sent) == s.failAfter {
+		return errors.New("stream-failure")
+	}
+	s.sent = append(s.sent, msg)
+	return nil
+}
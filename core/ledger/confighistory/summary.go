@@ -0,0 +1,90 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/protos/common"
+)
+
+// CollectionType is the kind of a collection, as reported in the summaries of the collections
+type CollectionType string
+
+const (
+	// StaticCollection is the type of a collection defined via a `common.StaticCollectionConfig`
+	StaticCollection CollectionType = "static"
+	// ImplicitCollection is the type of an implicit collection of an org, which is synthesized from the state of the ledger
+	ImplicitCollection CollectionType = "implicit"
+	// UnknownCollection is the type of a collection config whose payload is not set, which is also the case for a payload
+	// of a type that is introduced in a later version of the proto, as it is retained only as the unrecognized bytes
+	UnknownCollection CollectionType = "unknown"
+)
+
+// CollectionSummary contains the salient attributes of a collection, for display to the operators. For a collection
+// that is not a static collection, only the type is set
+type CollectionSummary struct {
+	Name              string
+	Type              CollectionType
+	MemberOrgs        []string
+	RequiredPeerCount int32
+	MaximumPeerCount  int32
+	BlockToLive       uint64
+}
+
+// String returns a single line representation of the summary
+func (s *CollectionSummary) String() string {
+	return fmt.Sprintf("collection=%s, type=%s, memberOrgs=[%s], requiredPeerCount=%d, maximumPeerCount=%d, blockToLive=%d",
+		s.Name, s.Type, strings.Join(s.MemberOrgs, ","), s.RequiredPeerCount, s.MaximumPeerCount, s.BlockToLive)
+}
+
+// collectionTypeOf returns the type of the given collection config. A payload of a type other than the ones known to this
+// package is reported by the name of its type in the proto, so that a new type shows up in the summaries instead of being dropped
+func collectionTypeOf(collConfig *common.CollectionConfig) CollectionType {
+	switch payload := collConfig.GetPayload().(type) {
+	case *common.CollectionConfig_StaticCollectionConfig:
+		return StaticCollection
+	case nil:
+		return UnknownCollection
+	default:
+		return CollectionType(strings.TrimPrefix(fmt.Sprintf("%T", payload), "*common.CollectionConfig_"))
+	}
+}
+
+// SummarizeCollectionConfig returns a summary for each of the collections present in the given collection config, in the
+// order in which the collections appear in the config. The member orgs are the MSP IDs that appear in the principals of the
+// signature policy of the collection. If the member orgs cannot be extracted from the policy, a warning is logged and the
+// member orgs are left empty in the summary of that collection. A collection config that is not a static collection is
+// summarized by its type alone (see function `collectionTypeOf`)
+func SummarizeCollectionConfig(info *ledger.CollectionConfigInfo) []CollectionSummary {
+	if info == nil {
+		return nil
+	}
+	var summaries []CollectionSummary
+	for _, collConfig := range info.CollectionConfig.GetConfig() {
+		staticCollConfig := collConfig.GetStaticCollectionConfig()
+		if staticCollConfig == nil {
+			summaries = append(summaries, CollectionSummary{Type: collectionTypeOf(collConfig)})
+			continue
+		}
+		orgs, err := memberOrgs(staticCollConfig)
+		if err != nil {
+			logger.Warningf("Error while summarizing collection [%s]: %s", staticCollConfig.Name, err)
+		}
+		summaries = append(summaries, CollectionSummary{
+			Name:              staticCollConfig.Name,
+			Type:              StaticCollection,
+			MemberOrgs:        orgs,
+			RequiredPeerCount: staticCollConfig.RequiredPeerCount,
+			MaximumPeerCount:  staticCollConfig.MaximumPeerCount,
+			BlockToLive:       staticCollConfig.BlockToLive,
+		})
+	}
+	return summaries
+}
@@ -0,0 +1,95 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/common/cauthdsl"
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/mock"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/msp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSummarizeCollectionConfig(t *testing.T) {
+	nestedPolicy := envelope(
+		cauthdsl.Or(
+			cauthdsl.SignedBy(0),
+			cauthdsl.And(cauthdsl.SignedBy(1), cauthdsl.SignedBy(2)),
+		),
+		memberPrincipal("org1"), memberPrincipal("org2"), memberPrincipal("org3"),
+	)
+	coll1 := coll("coll1", nestedPolicy, 100)
+	coll1.RequiredPeerCount, coll1.MaximumPeerCount = 1, 3
+	coll2 := coll("coll2", cauthdsl.SignedByAnyMember([]string{"org2", "org2"}), 0)
+	badColl := coll("bad-coll", envelope(nil, &msp.MSPPrincipal{PrincipalClassification: msp.MSPPrincipal_ANONYMITY}), 5)
+	pkg := collConfigPkg(coll1, coll2, badColl)
+	// a collection config without a known payload is summarized by its type
+	pkg.Config = append(pkg.Config, &common.CollectionConfig{})
+
+	summaries := SummarizeCollectionConfig(&ledger.CollectionConfigInfo{CollectionConfig: pkg, CommittingBlockNum: 10})
+	assert.Equal(t,
+		[]CollectionSummary{
+			{Name: "coll1", Type: StaticCollection, MemberOrgs: []string{"org1", "org2", "org3"}, RequiredPeerCount: 1, MaximumPeerCount: 3, BlockToLive: 100},
+			{Name: "coll2", Type: StaticCollection, MemberOrgs: []string{"org2"}},
+			{Name: "bad-coll", Type: StaticCollection, BlockToLive: 5},
+			{Type: UnknownCollection},
+		},
+		summaries,
+	)
+	assert.Equal(t,
+		"collection=coll1, type=static, memberOrgs=[org1,org2,org3], requiredPeerCount=1, maximumPeerCount=3, blockToLive=100",
+		summaries[0].String(),
+	)
+	assert.Equal(t,
+		"collection=, type=unknown, memberOrgs=[], requiredPeerCount=0, maximumPeerCount=0, blockToLive=0",
+		summaries[3].String(),
+	)
+
+	assert.Nil(t, SummarizeCollectionConfig(nil))
+	assert.Nil(t, SummarizeCollectionConfig(&ledger.CollectionConfigInfo{}))
+}
+
+func TestCollectionTypeOf(t *testing.T) {
+	// the variants are listed from the oneof of the proto, so that a variant added later fails the test until it is handled here
+	_, _, _, variants := (&common.CollectionConfig{}).XXX_OneofFuncs()
+	for _, variant := range variants {
+		switch variant.(type) {
+		case *common.CollectionConfig_StaticCollectionConfig:
+			assert.Equal(t, StaticCollection, collectionTypeOf(collConfigPkg(coll("coll1", nil, 0)).Config[0]))
+		default:
+			t.Errorf("no test case for the collection config of type %T", variant)
+		}
+	}
+	assert.Equal(t, UnknownCollection, collectionTypeOf(&common.CollectionConfig{}))
+	assert.Equal(t, UnknownCollection, collectionTypeOf(nil))
+}
+
+func TestUnknownCollectionTypeRetrieval(t *testing.T) {
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(NewMemStoreProvider()))
+	defer m.Close()
+
+	// a payload of a type unknown to this version of the proto (field number 2) is retained as the unrecognized bytes
+	unknownColl := &common.CollectionConfig{XXX_unrecognized: []byte{0x12, 0x02, 0x0a, 0x00}}
+	pkg := collConfigPkg(coll("coll1", nil, 0))
+	pkg.Config = append(pkg.Config, unknownColl)
+	testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, "chaincode1", pkg)
+	assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: 10}))
+
+	retriever := m.GetRetriever("ledger1", &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 100}})
+	collConfig, err := retriever.CollectionConfigAt(10, "chaincode1")
+	assert.NoError(t, err)
+	assert.Len(t, collConfig.CollectionConfig.Config, 2)
+	assert.Equal(t, unknownColl.XXX_unrecognized, collConfig.CollectionConfig.Config[1].XXX_unrecognized)
+	assert.Equal(t,
+		[]CollectionSummary{{Name: "coll1", Type: StaticCollection}, {Type: UnknownCollection}},
+		SummarizeCollectionConfig(collConfig),
+	)
+}
@@ -0,0 +1,96 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"time"
+
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/pkg/errors"
+)
+
+// CollectionConfigAtTime implements function from the interface `Retriever`. It returns the collection config that was
+// active at the given time, i.e., the most recent collection config committed at or before the highest block that was
+// committed at or before the given time. The time of a block is the timestamp in the channel header of its first
+// transaction and the block times are expected to be non-decreasing with the block numbers. If the given time is before
+// the time of the genesis block, nil is returned. If the given time is after the time of the latest block, the latest
+// block is used. As in function `MostRecentCollectionConfigBelow`, the implicit collections are included
+func (r *retriever) CollectionConfigAtTime(t time.Time, chaincodeName string) (*ledger.CollectionConfigInfo, error) {
+	blockNum, found, err := r.blockNumAtTime(t)
+	if err != nil || !found {
+		return nil, err
+	}
+	return r.mostRecentCollectionConfigBelow(blockNum+1, chaincodeName, nil)
+}
+
+// blockNumAtTime performs a binary search over the blocks for the highest block committed at or before the given time.
+// The returned bool is false if there is no such block
+func (r *retriever) blockNumAtTime(t time.Time) (uint64, bool, error) {
+	info, err := r.ledgerInfoRetriever.GetBlockchainInfo()
+	if err != nil {
+		return 0, false, err
+	}
+	if info.Height == 0 {
+		return 0, false, nil
+	}
+	isAtOrBefore := func(blockNum uint64) (bool, error) {
+		blockTime, err := r.blockTime(blockNum)
+		if err != nil {
+			return false, err
+		}
+		return !blockTime.After(t), nil
+	}
+
+	low, high := uint64(0), info.Height-1
+	if ok, err := isAtOrBefore(low); err != nil || !ok {
+		return 0, false, err
+	}
+	if ok, err := isAtOrBefore(high); err != nil || ok {
+		return high, err == nil, err
+	}
+	// invariant: the block `low` is committed at or before the time t and the block `high` is committed after the time t
+	for high-low > 1 {
+		mid := low + (high-low)/2
+		ok, err := isAtOrBefore(mid)
+		if err != nil {
+			return 0, false, err
+		}
+		if ok {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+	return low, true, nil
+}
+
+func (r *retriever) blockTime(blockNum uint64) (time.Time, error) {
+	block, err := r.ledgerInfoRetriever.GetBlockByNumber(blockNum)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return blockTimestamp(block)
+}
+
+// blockTimestamp returns the timestamp in the channel header of the first transaction in the block
+func blockTimestamp(block *common.Block) (time.Time, error) {
+	env, err := utils.ExtractEnvelope(block, 0)
+	if err != nil {
+		return time.Time{}, err
+	}
+	chdr, err := utils.ChannelHeader(env)
+	if err != nil {
+		return time.Time{}, err
+	}
+	ts := chdr.GetTimestamp()
+	if ts == nil {
+		return time.Time{}, errors.Errorf("the first transaction in block [%d] does not contain a timestamp", block.Header.Number)
+	}
+	return time.Unix(ts.Seconds, int64(ts.Nanos)), nil
+}
@@ -0,0 +1,101 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/mock"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollectionConfigAtTime(t *testing.T) {
+	dbPath := "/tmp/fabric/core/ledger/confighistory"
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	env := newTestEnv(t, dbPath, mockCCInfoProvider)
+	mgr := env.mgr
+	defer env.cleanup()
+
+	for _, blockNum := range []uint64{10, 20} {
+		testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, "chaincode1",
+			sampleCollectionConfigPackage("coll", blockNum))
+		assert.NoError(t, mgr.HandleStateUpdates(&ledger.StateUpdateTrigger{
+			LedgerID:           "ledger1",
+			CommittingBlockNum: blockNum},
+		))
+	}
+
+	// block i is committed at the time (1000 + 10*i) seconds
+	blockTime := func(blockNum uint64) time.Time {
+		return time.Unix(1000+10*int64(blockNum), 0)
+	}
+	dummyLedgerInfoRetriever := &dummyLedgerInfoRetriever{
+		info:   &common.BlockchainInfo{Height: 30},
+		blocks: map[uint64]*common.Block{},
+	}
+	for blockNum := uint64(0); blockNum < 30; blockNum++ {
+		dummyLedgerInfoRetriever.blocks[blockNum] = sampleBlockWithTimestamp(blockNum, blockTime(blockNum))
+	}
+	retriever := mgr.GetRetriever("ledger1", dummyLedgerInfoRetriever)
+
+	testcases := []struct {
+		name             string
+		time             time.Time
+		expectedBlockNum uint64
+	}{
+		{"at-config-block", blockTime(10), 10},
+		{"just-before-next-config-block", blockTime(20).Add(-time.Nanosecond), 10},
+		{"between-config-blocks", blockTime(15).Add(5 * time.Second), 10},
+		{"at-second-config-block", blockTime(20), 20},
+		{"at-latest-block", blockTime(29), 20},
+		{"after-latest-block", blockTime(100), 20},
+	}
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			collConfig, err := retriever.CollectionConfigAtTime(testcase.time, "chaincode1")
+			assert.NoError(t, err)
+			assert.Equal(t, testcase.expectedBlockNum, collConfig.CommittingBlockNum)
+		})
+	}
+
+	for _, tm := range []time.Time{blockTime(0).Add(-time.Second), blockTime(9)} {
+		collConfig, err := retriever.CollectionConfigAtTime(tm, "chaincode1")
+		assert.NoError(t, err)
+		assert.Nil(t, collConfig)
+	}
+
+	delete(dummyLedgerInfoRetriever.blocks, 29)
+	_, err := retriever.CollectionConfigAtTime(blockTime(15), "chaincode1")
+	assert.EqualError(t, err, "block [29] not found")
+
+	dummyLedgerInfoRetriever.blocks[29] = &common.Block{Header: &common.BlockHeader{Number: 29}, Data: &common.BlockData{}}
+	_, err = retriever.CollectionConfigAtTime(blockTime(15), "chaincode1")
+	assert.Error(t, err)
+
+	dummyLedgerInfoRetriever.info.Height = 0
+	collConfig, err := retriever.CollectionConfigAtTime(blockTime(15), "chaincode1")
+	assert.NoError(t, err)
+	assert.Nil(t, collConfig)
+}
+
+func sampleBlockWithTimestamp(blockNum uint64, t time.Time) *common.Block {
+	chdr := &common.ChannelHeader{
+		ChannelId: "ledger1",
+		Timestamp: &timestamp.Timestamp{Seconds: t.Unix(), Nanos: int32(t.Nanosecond())},
+	}
+	payload := &common.Payload{Header: &common.Header{ChannelHeader: utils.MarshalOrPanic(chdr)}}
+	env := &common.Envelope{Payload: utils.MarshalOrPanic(payload)}
+	return &common.Block{
+		Header: &common.BlockHeader{Number: blockNum},
+		Data:   &common.BlockData{Data: [][]byte{utils.MarshalOrPanic(env)}},
+	}
+}
@@ -0,0 +1,83 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"context"
+)
+
+// The attributes with which the spans are annotated
+const (
+	spanAttrLedgerID      = "ledger_id"
+	spanAttrChaincodeName = "chaincode"
+	spanAttrBlockNum      = "block_num"
+)
+
+// Tracer creates the spans that trace the operations of the config history. An adapter to the tracing library used
+// by the peer (e.g., OpenTelemetry) is expected to implement this interface and can be injected via `WithTracer`
+type Tracer interface {
+	// Start starts a span for the given operation as a child of the span, if any, in the given context
+	// and returns a context that carries the started span
+	Start(ctx context.Context, operation string) (context.Context, Span)
+}
+
+// Span is a traced operation
+type Span interface {
+	// SetAttribute annotates the span with the given key and value
+	SetAttribute(key string, value interface{})
+	// SetError records the failure of the operation
+	SetError(err error)
+	// End completes the span
+	End()
+}
+
+// WithTracer sets the tracer used for creating a span around the functions `HandleStateUpdates`, `CollectionConfigAt`,
+// and `MostRecentCollectionConfigBelow`. The spans are annotated with the ledger id, the block number and, for the
+// retrieval functions, the chaincode name. If not set, a no-op tracer is used
+func WithTracer(tracer Tracer) Option {
+	return func(m *mgr) {
+		m.tracer = tracer
+	}
+}
+
+// startSpan starts a span for the operation and annotates it with the ledger id, the chaincode name (unless empty), and
+// the block number. When the no-op tracer is in use, the attributes are not set and nothing is allocated
+func startSpan(ctx context.Context, tracer Tracer, operation, ledgerID, chaincodeName string, blockNum uint64) (context.Context, Span) {
+	if _, ok := tracer.(noopTracer); ok {
+		return ctx, noopSpan{}
+	}
+	ctx, span := tracer.Start(ctx, operation)
+	span.SetAttribute(spanAttrLedgerID, ledgerID)
+	if chaincodeName != "" {
+		span.SetAttribute(spanAttrChaincodeName, chaincodeName)
+	}
+	span.SetAttribute(spanAttrBlockNum, blockNum)
+	return ctx, span
+}
+
+// endSpan ends the span after recording the error, if any, pointed to by errPtr. It is intended to be deferred
+// by the traced function with a pointer to its named error result
+func endSpan(span Span, errPtr *error) {
+	if *errPtr != nil {
+		span.SetError(*errPtr)
+	}
+	span.End()
+}
+
+type noopTracer struct{}
+
+func (noopTracer) Start(ctx context.Context, operation string) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(key string, value interface{}) {}
+
+func (noopSpan) SetError(err error) {}
+
+func (noopSpan) End() {}
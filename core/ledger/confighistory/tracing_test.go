@@ -0,0 +1,119 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/mock"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTracing(t *testing.T) {
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	tracer := &recordingTracer{}
+	m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(NewMemStoreProvider()), WithTracer(tracer))
+	defer m.Close()
+
+	testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, "chaincode1",
+		sampleCollectionConfigPackage("chaincode1", 10))
+	assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: 10}))
+	mockCCInfoProvider.UpdatedChaincodesReturns(nil, errors.New("lookup-failure"))
+	assert.EqualError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: 11}), "lookup-failure")
+
+	retriever := m.GetRetriever("ledger1", &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 20}})
+	parentCtx := context.WithValue(context.Background(), parentKey{}, "parent-span")
+	_, err := retriever.CollectionConfigAtContext(parentCtx, 10, "chaincode1")
+	assert.NoError(t, err)
+	_, err = retriever.MostRecentCollectionConfigBelow(15, "chaincode1")
+	assert.NoError(t, err)
+	_, err = retriever.CollectionConfigAt(30, "chaincode1")
+	assert.Error(t, err)
+
+	expectedSpans := []*recordedSpan{
+		{
+			operation: "confighistory.HandleStateUpdates",
+			attrs:     map[string]interface{}{"ledger_id": "ledger1", "block_num": uint64(10)},
+			ended:     true,
+		},
+		{
+			operation: "confighistory.HandleStateUpdates",
+			attrs:     map[string]interface{}{"ledger_id": "ledger1", "block_num": uint64(11)},
+			err:       "lookup-failure",
+			ended:     true,
+		},
+		{
+			operation: "confighistory.CollectionConfigAt",
+			parent:    "parent-span",
+			attrs:     map[string]interface{}{"ledger_id": "ledger1", "chaincode": "chaincode1", "block_num": uint64(10)},
+			ended:     true,
+		},
+		{
+			operation: "confighistory.MostRecentCollectionConfigBelow",
+			attrs:     map[string]interface{}{"ledger_id": "ledger1", "chaincode": "chaincode1", "block_num": uint64(15)},
+			ended:     true,
+		},
+		{
+			operation: "confighistory.CollectionConfigAt",
+			attrs:     map[string]interface{}{"ledger_id": "ledger1", "chaincode": "chaincode1", "block_num": uint64(30)},
+			err:       "The maximum block number committed [19] is less than the requested block number [30]",
+			ended:     true,
+		},
+	}
+	assert.Equal(t, expectedSpans, tracer.spans)
+}
+
+func TestNoopTracerDoesNotAllocate(t *testing.T) {
+	ctx := context.Background()
+	allocs := testing.AllocsPerRun(100, func() {
+		var err error
+		_, span := startSpan(ctx, noopTracer{}, "operation", "ledger1", "chaincode1", 10)
+		endSpan(span, &err)
+	})
+	assert.Equal(t, float64(0), allocs)
+}
+
+type parentKey struct{}
+
+type recordingTracer struct {
+	mux   sync.Mutex
+	spans []*recordedSpan
+}
+
+func (tr *recordingTracer) Start(ctx context.Context, operation string) (context.Context, Span) {
+	tr.mux.Lock()
+	defer tr.mux.Unlock()
+	parent, _ := ctx.Value(parentKey{}).(string)
+	span := &recordedSpan{operation: operation, parent: parent, attrs: map[string]interface{}{}}
+	tr.spans = append(tr.spans, span)
+	return context.WithValue(ctx, parentKey{}, operation), span
+}
+
+type recordedSpan struct {
+	operation string
+	parent    string
+	attrs     map[string]interface{}
+	err       string
+	ended     bool
+}
+
+func (s *recordedSpan) SetAttribute(key string, value interface{}) {
+	s.attrs[key] = value
+}
+
+func (s *recordedSpan) SetError(err error) {
+	s.err = err.Error()
+}
+
+func (s *recordedSpan) End() {
+	s.ended = true
+}
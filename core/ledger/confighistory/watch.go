@@ -0,0 +1,222 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/pkg/errors"
+)
+
+// watchChannelSize is the number of the collection config changes that can be buffered for a watcher
+const watchChannelSize = 10
+
+// CollectionConfigListener is invoked with each collection config persisted in the config history of the default namespace
+// (see function `Mgr.RegisterListener`)
+type CollectionConfigListener func(ledgerID, chaincodeName string, blockNum uint64, collConfig *common.CollectionConfigPackage)
+
+// watchers maintains the subscriptions to the collection config changes of individual chaincodes and, for the change
+// feeds (see function `ConfigChangesSince`), to the collection config changes of all the chaincodes of a ledger, along
+// with the listeners that are invoked for the collection config changes of all the ledgers
+type watchers struct {
+	mux       sync.RWMutex
+	byKey     map[cacheKey]map[*watcher]struct{}
+	byLedger  map[string]map[*ledgerWatcher]struct{}
+	listeners []CollectionConfigListener
+}
+
+type watcher struct {
+	ch        chan *ledger.CollectionConfigInfo
+	closeOnce sync.Once
+}
+
+type ledgerWatcher struct {
+	ch        chan *ledger.ConfigChangeRecord
+	closeOnce sync.Once
+}
+
+func newWatchers() *watchers {
+	return &watchers{
+		byKey:    map[cacheKey]map[*watcher]struct{}{},
+		byLedger: map[string]map[*ledgerWatcher]struct{}{},
+	}
+}
+
+func (w *watcher) close() {
+	w.closeOnce.Do(func() { close(w.ch) })
+}
+
+func (w *ledgerWatcher) close() {
+	w.closeOnce.Do(func() { close(w.ch) })
+}
+
+// WatchChaincode implements function in the interface 'Mgr'. The returned channel receives the collection config of
+// the given chaincode each time a new collection config of the chaincode is persisted. The channel is buffered and, in
+// order to not hold up the commit of the blocks, a change is dropped (with a warning) if the buffer of a slow watcher is
+// full; a watcher that cannot afford to miss a change should re-query the retriever on receiving a change. The returned
+// function cancels the subscription and closes the channel. All the channels are closed when the `Mgr` is closed
+func (m *mgr) WatchChaincode(ledgerID, chaincodeName string) (<-chan *ledger.CollectionConfigInfo, func()) {
+	return m.watchers.watch(ledgerID, chaincodeName)
+}
+
+// WatchChaincode implements function from the interface `Retriever` and, along with the function `ConfigChangesSince`,
+// the interface `ledger.ConfigHistoryWatcher`. This is the same as the function `Mgr.WatchChaincode` for the ledger of
+// the retriever and is supported only by the retrievers of the default namespace that are not snapshots
+func (r *retriever) WatchChaincode(chaincodeName string) (<-chan *ledger.CollectionConfigInfo, func(), error) {
+	if r.watchers == nil {
+		return nil, nil, errors.Errorf("watching the config changes of ledger [%s] is not supported by this retriever", r.ledgerID)
+	}
+	ch, cancel := r.watchers.watch(r.ledgerID, chaincodeName)
+	return ch, cancel, nil
+}
+
+// watch subscribes to the collection config changes of the given chaincode
+func (ws *watchers) watch(ledgerID, chaincodeName string) (<-chan *ledger.CollectionConfigInfo, func()) {
+	key := cacheKey{ledgerID, chaincodeName}
+	w := &watcher{ch: make(chan *ledger.CollectionConfigInfo, watchChannelSize)}
+	ws.mux.Lock()
+	defer ws.mux.Unlock()
+	if ws.byKey[key] == nil {
+		ws.byKey[key] = map[*watcher]struct{}{}
+	}
+	ws.byKey[key][w] = struct{}{}
+
+	cancel := func() {
+		ws.mux.Lock()
+		defer ws.mux.Unlock()
+		delete(ws.byKey[key], w)
+		if len(ws.byKey[key]) == 0 {
+			delete(ws.byKey, key)
+		}
+		w.close()
+	}
+	return w.ch, cancel
+}
+
+// RegisterListener implements function in the interface 'Mgr'. The listener is invoked with the collection config of a chaincode
+// each time a new collection config of the chaincode is persisted in any of the ledgers, so that the components such as the
+// distributors of the private data can react to a change in the membership of a collection without polling. Unlike the watchers
+// (see function `WatchChaincode`), the listeners are invoked synchronously, after the config history of the block is persisted
+// and in the order of the chaincode names, and hence, never miss a change. For the same reason, a listener holds up the commit
+// of the block (or, with the asynchronous writes, the writes of the subsequent blocks) and should return quickly, handing off
+// any expensive processing; it must not write to the config history. Each listener receives its own copy of the collection config.
+// The listeners cannot be unregistered and are dropped when the `Mgr` is closed
+func (m *mgr) RegisterListener(listener CollectionConfigListener) {
+	m.watchers.mux.Lock()
+	defer m.watchers.mux.Unlock()
+	m.watchers.listeners = append(m.watchers.listeners, listener)
+}
+
+// notifyListeners invokes the listeners with the collection configs committed at the given block
+func (ws *watchers) notifyListeners(ledgerID string, blockNum uint64, collConfigs map[string]*common.CollectionConfigPackage) {
+	ws.mux.RLock()
+	listeners := ws.listeners
+	ws.mux.RUnlock()
+	if len(listeners) == 0 {
+		return
+	}
+	ccNames := make([]string, 0, len(collConfigs))
+	for ccName := range collConfigs {
+		ccNames = append(ccNames, ccName)
+	}
+	sort.Strings(ccNames)
+	for _, ccName := range ccNames {
+		for _, listener := range listeners {
+			listener(ledgerID, ccName, blockNum, proto.Clone(collConfigs[ccName]).(*common.CollectionConfigPackage))
+		}
+	}
+}
+
+// notify sends the collection config to the watchers of the chaincode, without blocking
+func (ws *watchers) notify(ledgerID, chaincodeName string, info *ledger.CollectionConfigInfo) {
+	ws.mux.RLock()
+	defer ws.mux.RUnlock()
+	for w := range ws.byKey[cacheKey{ledgerID, chaincodeName}] {
+		select {
+		case w.ch <- copyCollectionConfigInfo(info):
+		default:
+			logger.Warningf("Dropping the notification of the collection config of chaincode [%s] committed at block [%d] in ledger [%s] for a slow watcher",
+				chaincodeName, info.CommittingBlockNum, ledgerID)
+		}
+	}
+}
+
+// watchLedger subscribes to the collection config changes of all the chaincodes of the given ledger
+func (ws *watchers) watchLedger(ledgerID string) *ledgerWatcher {
+	w := &ledgerWatcher{ch: make(chan *ledger.ConfigChangeRecord, changeFeedBufferSize)}
+	ws.mux.Lock()
+	defer ws.mux.Unlock()
+	if ws.byLedger[ledgerID] == nil {
+		ws.byLedger[ledgerID] = map[*ledgerWatcher]struct{}{}
+	}
+	ws.byLedger[ledgerID][w] = struct{}{}
+	return w
+}
+
+// cancelLedgerWatch cancels the given subscription and closes its channel
+func (ws *watchers) cancelLedgerWatch(ledgerID string, w *ledgerWatcher) {
+	ws.mux.Lock()
+	defer ws.mux.Unlock()
+	delete(ws.byLedger[ledgerID], w)
+	if len(ws.byLedger[ledgerID]) == 0 {
+		delete(ws.byLedger, ledgerID)
+	}
+	w.close()
+}
+
+// notifyLedger sends the collection configs committed at the given block to the watchers of the ledger, in the order of
+// the chaincode names. Unlike the watchers of a chaincode, a watcher of a ledger whose buffer is full is not skipped, as
+// that would leave a gap in its change feed; instead, its subscription is cancelled, which ends the change feed
+func (ws *watchers) notifyLedger(ledgerID string, blockNum uint64, collConfigs map[string]*common.CollectionConfigPackage) {
+	ccNames := make([]string, 0, len(collConfigs))
+	for ccName := range collConfigs {
+		ccNames = append(ccNames, ccName)
+	}
+	sort.Strings(ccNames)
+	var overflowed []*ledgerWatcher
+	ws.mux.RLock()
+	for w := range ws.byLedger[ledgerID] {
+		for _, ccName := range ccNames {
+			info := &ledger.CollectionConfigInfo{CollectionConfig: collConfigs[ccName], CommittingBlockNum: blockNum}
+			select {
+			case w.ch <- &ledger.ConfigChangeRecord{ChaincodeName: ccName, Info: copyCollectionConfigInfo(info)}:
+				continue
+			default:
+			}
+			logger.Warningf("Ending the change feed of a slow watcher of ledger [%s] at block [%d]", ledgerID, blockNum)
+			overflowed = append(overflowed, w)
+			break
+		}
+	}
+	ws.mux.RUnlock()
+	for _, w := range overflowed {
+		ws.cancelLedgerWatch(ledgerID, w)
+	}
+}
+
+// closeAll closes the channels of all the watchers
+func (ws *watchers) closeAll() {
+	ws.mux.Lock()
+	defer ws.mux.Unlock()
+	for _, watchersOfKey := range ws.byKey {
+		for w := range watchersOfKey {
+			w.close()
+		}
+	}
+	for _, watchersOfLedger := range ws.byLedger {
+		for w := range watchersOfLedger {
+			w.close()
+		}
+	}
+	ws.byKey = map[cacheKey]map[*watcher]struct{}{}
+	ws.byLedger = map[string]map[*ledgerWatcher]struct{}{}
+	ws.listeners = nil
+}
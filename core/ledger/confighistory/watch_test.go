@@ -0,0 +1,133 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package confighistory
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/mock"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatchChaincode(t *testing.T) {
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(NewMemStoreProvider()))
+	commitBlock := func(ledgerID, ccName string, blockNum uint64) {
+		testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, ccName,
+			sampleCollectionConfigPackage(ccName, blockNum))
+		assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: ledgerID, CommittingBlockNum: blockNum}))
+	}
+
+	ch1, cancel1 := m.WatchChaincode("ledger1", "chaincode1")
+	ch2, _ := m.WatchChaincode("ledger1", "chaincode1")
+	otherCh, _ := m.WatchChaincode("ledger1", "chaincode2")
+
+	commitBlock("ledger1", "chaincode1", 10)
+	// the changes of the same chaincode in another ledger are not delivered
+	commitBlock("ledger2", "chaincode1", 11)
+	for _, ch := range []<-chan *ledger.CollectionConfigInfo{ch1, ch2} {
+		info := <-ch
+		assert.Equal(t, uint64(10), info.CommittingBlockNum)
+		assert.True(t, proto.Equal(sampleCollectionConfigPackage("chaincode1", 10), info.CollectionConfig))
+		assert.Len(t, ch, 0)
+	}
+	assert.Len(t, otherCh, 0)
+
+	// the cancelled watcher does not receive further changes
+	cancel1()
+	cancel1()
+	_, ok := <-ch1
+	assert.False(t, ok)
+	commitBlock("ledger1", "chaincode1", 12)
+	info := <-ch2
+	assert.Equal(t, uint64(12), info.CommittingBlockNum)
+
+	// the changes are dropped for a slow watcher
+	for blockNum := uint64(100); blockNum < 100+watchChannelSize+5; blockNum++ {
+		commitBlock("ledger1", "chaincode1", blockNum)
+	}
+	assert.Len(t, ch2, watchChannelSize)
+	for i := 0; i < watchChannelSize; i++ {
+		info := <-ch2
+		assert.Equal(t, uint64(100+i), info.CommittingBlockNum)
+	}
+
+	m.Close()
+	_, ok = <-ch2
+	assert.False(t, ok)
+	_, ok = <-otherCh
+	assert.False(t, ok)
+}
+
+func TestRetrieverWatchChaincode(t *testing.T) {
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(NewMemStoreProvider()))
+	defer m.Close()
+	var watcher ledger.ConfigHistoryWatcher = m.GetRetriever("ledger1", &dummyLedgerInfoRetriever{})
+	ch, cancel, err := watcher.WatchChaincode("chaincode1")
+	assert.NoError(t, err)
+	defer cancel()
+
+	testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, "chaincode1",
+		sampleCollectionConfigPackage("chaincode1", 10))
+	assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: 10}))
+	info := <-ch
+	assert.Equal(t, uint64(10), info.CommittingBlockNum)
+	assert.True(t, proto.Equal(sampleCollectionConfigPackage("chaincode1", 10), info.CollectionConfig))
+
+	_, _, err = m.GetRetrieverForNamespace("ledger1", "_lifecycle", &dummyLedgerInfoRetriever{}).WatchChaincode("chaincode1")
+	assert.EqualError(t, err, "watching the config changes of ledger [ledger1] is not supported by this retriever")
+}
+
+func TestRegisterListener(t *testing.T) {
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	m := newMgrWithDBProvider(mockCCInfoProvider, newDBProviderWithStore(NewMemStoreProvider()))
+	defer m.Close()
+	commitBlock := func(ledgerID, ccName string, blockNum uint64) {
+		testutilEquipMockCCInfoProviderToReturnDesiredCollConfig(mockCCInfoProvider, ccName,
+			sampleCollectionConfigPackage(ccName, blockNum))
+		assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: ledgerID, CommittingBlockNum: blockNum}))
+	}
+
+	type notification struct {
+		ledgerID, ccName string
+		blockNum         uint64
+		collConfig       *common.CollectionConfigPackage
+	}
+	var received1, received2 []*notification
+	m.RegisterListener(func(ledgerID, ccName string, blockNum uint64, collConfig *common.CollectionConfigPackage) {
+		received1 = append(received1, &notification{ledgerID, ccName, blockNum, collConfig})
+		// a listener gets its own copy of the collection config
+		collConfig.Config = nil
+	})
+	m.RegisterListener(func(ledgerID, ccName string, blockNum uint64, collConfig *common.CollectionConfigPackage) {
+		received2 = append(received2, &notification{ledgerID, ccName, blockNum, collConfig})
+	})
+
+	commitBlock("ledger1", "chaincode1", 10)
+	commitBlock("ledger2", "chaincode2", 11)
+	// a block that does not update any collection config is not notified
+	mockCCInfoProvider.UpdatedChaincodesReturns(nil, nil)
+	assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: 12}))
+
+	assert.Len(t, received1, 2)
+	assert.Len(t, received2, 2)
+	for i, expected := range []*notification{{"ledger1", "chaincode1", 10, nil}, {"ledger2", "chaincode2", 11, nil}} {
+		assert.Equal(t, expected.ledgerID, received2[i].ledgerID)
+		assert.Equal(t, expected.ccName, received2[i].ccName)
+		assert.Equal(t, expected.blockNum, received2[i].blockNum)
+		assert.True(t, proto.Equal(sampleCollectionConfigPackage(expected.ccName, expected.blockNum), received2[i].collConfig))
+	}
+	// the persisted collection config is not affected by the listeners
+	collConfig, err := m.GetRetriever("ledger1", &dummyLedgerInfoRetriever{info: &common.BlockchainInfo{Height: 20}}).
+		ExplicitCollectionConfigAt(10, "chaincode1")
+	assert.NoError(t, err)
+	assert.True(t, proto.Equal(sampleCollectionConfigPackage("chaincode1", 10), collConfig.CollectionConfig))
+}
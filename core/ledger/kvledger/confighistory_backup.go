@@ -0,0 +1,37 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package kvledger
+
+import (
+	"io"
+
+	"github.com/hyperledger/fabric/common/metrics/disabled"
+)
+
+// ExportConfigHistory writes the config history of the given ledger to the writer, in the versioned format of the function
+// `confighistory.Mgr.ExportConfigHistory`, so that the config history can be backed up independently of the leveldb files.
+// The config history db is opened by this function and hence, this is meant for the offline use, while the peer is stopped
+func ExportConfigHistory(ledgerID string, w io.Writer) error {
+	configHistoryMgr, err := newConfigHistoryMgr(nil, &disabled.Provider{})
+	if err != nil {
+		return err
+	}
+	defer configHistoryMgr.Close()
+	return configHistoryMgr.ExportConfigHistory(ledgerID, w)
+}
+
+// ImportConfigHistory loads the config history of the given ledger from the reader, as written by the function `ExportConfigHistory`,
+// for restoring the config history on a rebuilt peer. The config history of the ledger is expected to be empty. As with the function
+// `ExportConfigHistory`, this is meant for the offline use, while the peer is stopped
+func ImportConfigHistory(ledgerID string, r io.Reader) error {
+	configHistoryMgr, err := newConfigHistoryMgr(nil, &disabled.Provider{})
+	if err != nil {
+		return err
+	}
+	defer configHistoryMgr.Close()
+	return configHistoryMgr.ImportConfigHistory(ledgerID, r)
+}
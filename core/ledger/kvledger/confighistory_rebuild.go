@@ -0,0 +1,121 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package kvledger
+
+import (
+	"github.com/hyperledger/fabric/common/ledger/blkstorage"
+	"github.com/hyperledger/fabric/common/metrics"
+	"github.com/hyperledger/fabric/common/metrics/disabled"
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/confighistory"
+	"github.com/hyperledger/fabric/core/ledger/ledgerconfig"
+	"github.com/hyperledger/fabric/core/ledger/ledgerstorage"
+	"github.com/hyperledger/fabric/core/ledger/util/couchdb"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/pkg/errors"
+)
+
+// RebuildConfigHistory regenerates the config history of the given ledgers, or of all the ledgers of the peer if none is given,
+// by replaying the blocks from the block store (see function `confighistory.Mgr.RebuildFromBlocks`). This is the recovery path for
+// a config history db that is lost or corrupted, such as the one that fails the self-test when the ledger is opened. The existing
+// config history of each of the ledgers is discarded first. The `DeployedChaincodeInfoProvider` should be the one that the peer
+// uses for committing the blocks, so that the rebuilt entries are identical to the ones recorded during the commits. The dbs of
+// the peer are opened by this function and hence, this is meant for the offline use, while the peer is stopped
+func RebuildConfigHistory(ccInfoProvider ledger.DeployedChaincodeInfoProvider, ledgerIDs ...string) error {
+	idStore := openIDStore(ledgerconfig.GetLedgerProviderPath())
+	defer idStore.close()
+	if len(ledgerIDs) == 0 {
+		var err error
+		if ledgerIDs, err = idStore.getAllLedgerIds(); err != nil {
+			return err
+		}
+	}
+	for _, ledgerID := range ledgerIDs {
+		exists, err := idStore.ledgerIDExists(ledgerID)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return errors.Wrapf(ErrNonExistingLedgerID, "cannot rebuild config history of ledger [%s]", ledgerID)
+		}
+	}
+
+	// the block files are not archived while being read for the rebuild
+	blockStoreProvider := ledgerstorage.NewFetchOnlyBlockStoreProvider()
+	defer blockStoreProvider.Close()
+	configHistoryMgr, err := newConfigHistoryMgr(ccInfoProvider, &disabled.Provider{}, configHistoryRecordingOptions()...)
+	if err != nil {
+		return err
+	}
+	defer configHistoryMgr.Close()
+	for _, ledgerID := range ledgerIDs {
+		blockStore, err := blockStoreProvider.OpenBlockStore(ledgerID)
+		if err != nil {
+			return err
+		}
+		err = rebuildConfigHistory(configHistoryMgr, ledgerID, blockStore)
+		blockStore.Shutdown()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func rebuildConfigHistory(configHistoryMgr confighistory.Mgr, ledgerID string, blockStore blkstorage.BlockStore) error {
+	info, err := blockStore.GetBlockchainInfo()
+	if err != nil {
+		return err
+	}
+	logger.Infof("Rebuilding config history of ledger [%s] from [%d] blocks", ledgerID, info.Height)
+	return configHistoryMgr.RebuildFromBlocks(ledgerID, &blockStoreIterator{blockStore: blockStore, height: info.Height})
+}
+
+// newConfigHistoryMgr constructs the config history manager over the storage configured for the peer, i.e., the local leveldb
+// or the CouchDB instance of the state db. The metrics provider is used for the CouchDB instance, if the latter is configured
+func newConfigHistoryMgr(ccInfoProvider ledger.DeployedChaincodeInfoProvider, metricsProvider metrics.Provider,
+	options ...confighistory.Option) (confighistory.Mgr, error) {
+	if !ledgerconfig.IsConfigHistoryCouchDBEnabled() {
+		return confighistory.NewMgr(ccInfoProvider, options...), nil
+	}
+	couchDBDef := couchdb.GetCouchDBDefinition()
+	couchInstance, err := couchdb.CreateCouchInstance(couchDBDef.URL, couchDBDef.Username, couchDBDef.Password,
+		couchDBDef.MaxRetries, couchDBDef.MaxRetriesOnStartup, couchDBDef.RequestTimeout, couchDBDef.CreateGlobalChangesDB, metricsProvider)
+	if err != nil {
+		return nil, errors.WithMessage(err, "error while connecting to CouchDB for the config history")
+	}
+	return confighistory.NewMgrWithStore(ccInfoProvider, confighistory.NewCouchDBStoreProvider(couchInstance), options...), nil
+}
+
+// configHistoryRecordingOptions returns the options of the config history manager that affect the entries recorded for a block
+func configHistoryRecordingOptions() []confighistory.Option {
+	return []confighistory.Option{
+		confighistory.WithMaxCollectionConfigSize(ledgerconfig.GetConfigHistoryMaxCollectionConfigSize()),
+		confighistory.WithTrackedNamespaces(ledgerconfig.GetConfigHistoryNamespaces()),
+		confighistory.WithCollectionConfigRecords(ledgerconfig.IsConfigHistoryCollectionConfigRecordsEnabled()),
+	}
+}
+
+// blockStoreIterator implements interface `confighistory.BlockIterator` over the blocks below the given height. Unlike the
+// iterator returned by the function `RetrieveBlocks` of the block store, this does not block after the last block
+type blockStoreIterator struct {
+	blockStore blkstorage.BlockStore
+	next       uint64
+	height     uint64
+}
+
+func (itr *blockStoreIterator) Next() (*common.Block, error) {
+	if itr.next >= itr.height {
+		return nil, nil
+	}
+	block, err := itr.blockStore.RetrieveBlockByNumber(itr.next)
+	if err != nil {
+		return nil, err
+	}
+	itr.next++
+	return block, nil
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/hyperledger/fabric/core/ledger/util"
 	"github.com/hyperledger/fabric/protos/common"
 	putils "github.com/hyperledger/fabric/protos/utils"
+	"github.com/pkg/errors"
 )
 
 var logger = flogging.MustGetLogger("historyleveldb")
@@ -178,6 +179,19 @@ func (historyDB *historyDB) GetLastSavepoint() (*version.Height, error) {
 	return height, nil
 }
 
+// ImportSavepoint records the given height as the savepoint of the history db of a ledger created from a snapshot. The
+// history of the keys starts with the blocks after the snapshot. The history db is expected not to have a savepoint
+func (historyDB *historyDB) ImportSavepoint(height *version.Height) error {
+	savepoint, err := historyDB.GetLastSavepoint()
+	if err != nil {
+		return err
+	}
+	if savepoint != nil {
+		return errors.Errorf("history database is not empty, the savepoint is at block [%d]", savepoint.BlockNum)
+	}
+	return historyDB.db.Put(savePointKey, height.ToBytes(), true)
+}
+
 // ShouldRecover implements method in interface kvledger.Recoverer
 func (historyDB *historyDB) ShouldRecover(lastAvailableBlock uint64) (bool, uint64, error) {
 	if !ledgerconfig.IsHistoryDBEnabled() {
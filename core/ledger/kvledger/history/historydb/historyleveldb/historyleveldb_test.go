@@ -16,6 +16,7 @@ import (
 	"github.com/hyperledger/fabric/common/ledger/testutil"
 	util2 "github.com/hyperledger/fabric/common/util"
 	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/version"
 	"github.com/hyperledger/fabric/core/ledger/util"
 	"github.com/hyperledger/fabric/protos/common"
 	"github.com/hyperledger/fabric/protos/ledger/queryresult"
@@ -94,6 +95,24 @@ func TestSavepoint(t *testing.T) {
 	assert.Equal(t, uint64(3), blockNum)
 }
 
+func TestImportSavepoint(t *testing.T) {
+	env := newTestHistoryEnv(t)
+	defer env.cleanup()
+	historyDB := env.testHistoryDB.(*historyDB)
+
+	assert.NoError(t, historyDB.ImportSavepoint(version.NewHeight(10, 0)))
+	savepoint, err := historyDB.GetLastSavepoint()
+	assert.NoError(t, err)
+	assert.Equal(t, version.NewHeight(10, 0), savepoint)
+	// the blocks up to the snapshot are not recovered
+	status, _, err := historyDB.ShouldRecover(10)
+	assert.NoError(t, err)
+	assert.False(t, status)
+
+	assert.EqualError(t, historyDB.ImportSavepoint(version.NewHeight(10, 0)),
+		"history database is not empty, the savepoint is at block [10]")
+}
+
 func TestHistory(t *testing.T) {
 	env := newTestHistoryEnv(t)
 	defer env.cleanup()
@@ -41,6 +41,11 @@ type kvLedger struct {
 	configHistoryRetriever ledger.ConfigHistoryRetriever
 	blockAPIsRWLock        *sync.RWMutex
 	stats                  *ledgerStats
+	stateDB                privacyenabledstate.DB
+	configHistoryMgr       confighistory.Mgr
+	snapshotRequests       *snapshotRequests
+	// snapshotsWG tracks the snapshots being completed in the background
+	snapshotsWG sync.WaitGroup
 }
 
 // NewKVLedger constructs new `KVLedger`
@@ -58,7 +63,8 @@ func newKVLedger(
 	logger.Debugf("Creating KVLedger ledgerID=%s: ", ledgerID)
 	// Create a kvLedger for this chain/ledger, which encasulates the underlying
 	// id store, blockstore, txmgr (state database), history database
-	l := &kvLedger{ledgerID: ledgerID, blockStore: blockStore, historyDB: historyDB, blockAPIsRWLock: &sync.RWMutex{}}
+	l := &kvLedger{ledgerID: ledgerID, blockStore: blockStore, historyDB: historyDB, blockAPIsRWLock: &sync.RWMutex{},
+		stateDB: versionedDB, configHistoryMgr: configHistoryMgr, snapshotRequests: newSnapshotRequests()}
 
 	// TODO Move the function `GetChaincodeEventListener` to ledger interface and
 	// this functionality of regiserting for events to ledgermgmt package so that this
@@ -77,6 +83,10 @@ func newKVLedger(
 	if err := l.recoverDBs(); err != nil {
 		panic(errors.WithMessage(err, "error during state DB recovery"))
 	}
+	// a config history that cannot be read fails the opening of the ledger rather than the first query during an endorsement
+	if err := configHistoryMgr.SelfTest(ledgerID); err != nil {
+		return nil, errors.WithMessage(err, "config history is unusable and can be rebuilt via the command 'peer node rebuild-dbs --confighistory' while the peer is stopped")
+	}
 	l.configHistoryRetriever = configHistoryMgr.GetRetriever(ledgerID, l)
 
 	info, err := l.GetBlockchainInfo()
@@ -329,6 +339,8 @@ func (l *kvLedger) CommitWithPvtData(pvtdataAndBlock *ledger.BlockAndPvtData) er
 			panic(errors.WithMessage(err, "Error during commit to history db"))
 		}
 	}
+	// the snapshots are started while the commits are blocked, so that the files reflect the state at the requested block
+	l.processSnapshotRequests(blockNo)
 
 	elapsedCommitWithPvtData := time.Since(startBlockProcessing)
 
@@ -436,6 +448,7 @@ func (l *kvLedger) GetMissingPvtDataTracker() (ledger.MissingPvtDataTracker, err
 
 // Close closes `KVLedger`
 func (l *kvLedger) Close() {
+	l.snapshotsWG.Wait()
 	l.blockStore.Shutdown()
 	l.txtmgmt.Shutdown()
 }
@@ -11,6 +11,7 @@ import (
 	"fmt"
 
 	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/common/ledger/blkstorage"
 	"github.com/hyperledger/fabric/common/ledger/util/leveldbhelper"
 	"github.com/hyperledger/fabric/core/ledger"
 	"github.com/hyperledger/fabric/core/ledger/confighistory"
@@ -18,6 +19,7 @@ import (
 	"github.com/hyperledger/fabric/core/ledger/kvledger/history/historydb"
 	"github.com/hyperledger/fabric/core/ledger/kvledger/history/historydb/historyleveldb"
 	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/privacyenabledstate"
+	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/version"
 	"github.com/hyperledger/fabric/core/ledger/ledgerconfig"
 	"github.com/hyperledger/fabric/core/ledger/ledgerstorage"
 	"github.com/hyperledger/fabric/protos/common"
@@ -69,8 +71,24 @@ func NewProvider() (ledger.PeerLedgerProvider, error) {
 
 // Initialize implements the corresponding method from interface ledger.PeerLedgerProvider
 func (provider *Provider) Initialize(initializer *ledger.Initializer) error {
-	var err error
-	configHistoryMgr := confighistory.NewMgr(initializer.DeployedChaincodeInfoProvider)
+	configHistoryMgr, err := newConfigHistoryMgr(
+		initializer.DeployedChaincodeInfoProvider,
+		initializer.MetricsProvider,
+		append(configHistoryRecordingOptions(),
+			confighistory.WithMetricsProvider(initializer.MetricsProvider),
+			confighistory.WithSizeMetrics(initializer.MetricsProvider, ledgerconfig.GetConfigHistorySizeMetricsInterval()),
+			confighistory.WithSyncWrites(ledgerconfig.IsConfigHistorySyncWritesEnabled()),
+			confighistory.WithCompactionOnClose(ledgerconfig.GetConfigHistoryCompactOnCloseTimeout()),
+			confighistory.WithLogSampling(ledgerconfig.GetConfigHistoryLogSampling()),
+			confighistory.WithRetention(ledgerconfig.GetConfigHistoryRetentionBlocks()),
+		)...,
+	)
+	if err != nil {
+		return err
+	}
+	for _, listener := range initializer.CollectionConfigListeners {
+		configHistoryMgr.RegisterListener(listener.HandleCollectionConfigChange)
+	}
 	collElgNotifier := &collElgNotifier{
 		initializer.DeployedChaincodeInfoProvider,
 		initializer.MembershipInfoProvider,
@@ -131,6 +149,91 @@ func (provider *Provider) Create(genesisBlock *common.Block) (ledger.PeerLedger,
 	return lgr, nil
 }
 
+// CreateFromSnapshot implements the function in the interface `ledger.SnapshotLedgerCreator`. Like the function `Create`, this sets
+// the under construction flag before importing the snapshot into the config history, the state database, the history database,
+// and the block store, in this order. Upon a successful import, the flag is removed and the ledger is added to the created ledgers
+// list. As the block store is the last to be created, the function 'recoverUnderConstructionLedger' treats a ledger whose block
+// store is created from a snapshot as created
+func (provider *Provider) CreateFromSnapshot(snapshotDir string) (ledger.PeerLedger, string, error) {
+	if ledgerconfig.IsCouchDBEnabled() {
+		return nil, "", errors.New("creating a ledger from a snapshot is not supported with CouchDB as the state database")
+	}
+	metadata, err := loadSnapshotMetadata(snapshotDir)
+	if err != nil {
+		return nil, "", err
+	}
+	snapshotInfo, err := metadata.snapshotInfo()
+	if err != nil {
+		return nil, "", err
+	}
+	ledgerID := metadata.ChannelName
+	exists, err := provider.idStore.ledgerIDExists(ledgerID)
+	if err != nil {
+		return nil, "", err
+	}
+	if exists {
+		return nil, "", ErrLedgerIDExists
+	}
+	if err = provider.idStore.setUnderConstructionFlag(ledgerID); err != nil {
+		return nil, "", err
+	}
+	if err := provider.importSnapshot(ledgerID, snapshotDir, snapshotInfo); err != nil {
+		logger.Errorf("Error importing the snapshot [%s]. Unsetting under construction flag. Error: %+v", snapshotDir, err)
+		panicOnErr(provider.runCleanup(ledgerID), "Error running cleanup for ledger id [%s]", ledgerID)
+		panicOnErr(provider.idStore.unsetUnderConstructionFlag(), "Error while unsetting under construction flag")
+		return nil, "", err
+	}
+	lgr, err := provider.openInternal(ledgerID)
+	if err != nil {
+		return nil, "", err
+	}
+	bcInfo, err := lgr.GetBlockchainInfo()
+	if err != nil {
+		lgr.Close()
+		return nil, "", err
+	}
+	panicOnErr(provider.idStore.createLedgerIDFromSnapshot(ledgerID, bcInfo), "Error while marking ledger as created")
+	logger.Infof("Created ledger [%s] from the snapshot at block [%d]", ledgerID, snapshotInfo.LastBlockNum)
+	return lgr, ledgerID, nil
+}
+
+// importSnapshot loads the config history, the state database, and the savepoint of the history database of the given ledger
+// from the snapshot in the given dir, and creates the block store of the ledger with the ids of the transactions in the snapshot
+func (provider *Provider) importSnapshot(ledgerID, snapshotDir string, snapshotInfo *blkstorage.SnapshotInfo) error {
+	if err := provider.configHistoryMgr.ImportFromSnapshot(ledgerID, snapshotDir); err != nil {
+		return err
+	}
+	savepoint := version.NewHeight(snapshotInfo.LastBlockNum, 0)
+	vDB, err := provider.vdbProvider.GetDBHandle(ledgerID)
+	if err != nil {
+		return err
+	}
+	stateImporter, ok := vDB.(stateImporter)
+	if !ok {
+		return errors.New("creating a ledger from a snapshot is not supported by the state database")
+	}
+	if err := stateImporter.ImportPubStateAndPvtStateHashes(snapshotDir, savepoint); err != nil {
+		return err
+	}
+	historyDB, err := provider.historydbProvider.GetDBHandle(ledgerID)
+	if err != nil {
+		return err
+	}
+	historySavepointImporter, ok := historyDB.(historySavepointImporter)
+	if !ok {
+		return errors.New("creating a ledger from a snapshot is not supported by the history database")
+	}
+	if err := historySavepointImporter.ImportSavepoint(savepoint); err != nil {
+		return err
+	}
+	txIDs, err := openTxIDsReader(snapshotDir)
+	if err != nil {
+		return err
+	}
+	defer txIDs.close()
+	return provider.ledgerStoreProvider.BootstrapFromSnapshot(ledgerID, snapshotInfo, txIDs)
+}
+
 // Open implements the corresponding method from interface ledger.PeerLedgerProvider
 func (provider *Provider) Open(ledgerID string) (ledger.PeerLedger, error) {
 	logger.Debugf("Open() opening kvledger: %s", ledgerID)
@@ -201,8 +304,9 @@ func (provider *Provider) Close() {
 
 // recoverUnderConstructionLedger checks whether the under construction flag is set - this would be the case
 // if a crash had happened during creation of ledger and the ledger creation could have been left in intermediate
-// state. Recovery checks if the ledger was created and the genesis block was committed successfully then it completes
-// the last step of adding the ledger id to the list of created ledgers. Else, it clears the under construction flag
+// state. Recovery checks if the ledger was created and the genesis block was committed successfully, or the block store
+// was created from a snapshot, then it completes the last step of adding the ledger id to the list of created ledgers.
+// Else, it clears the under construction flag
 func (provider *Provider) recoverUnderConstructionLedger() {
 	logger.Debugf("Recovering under construction ledger")
 	ledgerID, err := provider.idStore.getUnderConstructionFlag()
@@ -216,8 +320,14 @@ func (provider *Provider) recoverUnderConstructionLedger() {
 	panicOnErr(err, "Error while opening under construction ledger [%s]", ledgerID)
 	bcInfo, err := ledger.GetBlockchainInfo()
 	panicOnErr(err, "Error while getting blockchain info for the under construction ledger [%s]", ledgerID)
+	snapshotInfo := ledger.(*kvLedger).blockStore.BootstrappingSnapshotInfo()
 	ledger.Close()
 
+	if snapshotInfo != nil {
+		logger.Infof("Block store was created from the snapshot. Hence, marking the peer ledger as created")
+		panicOnErr(provider.idStore.createLedgerIDFromSnapshot(ledgerID, bcInfo), "Error while adding ledgerID [%s] to created list", ledgerID)
+		return
+	}
 	switch bcInfo.Height {
 	case 0:
 		logger.Infof("Genesis block was not committed. Hence, the peer ledger not created. unsetting the under construction flag")
@@ -285,6 +395,15 @@ func (s *idStore) getUnderConstructionFlag() (string, error) {
 }
 
 func (s *idStore) createLedgerID(ledgerID string, gb *common.Block) error {
+	return s.putLedgerID(ledgerID, gb)
+}
+
+// createLedgerIDFromSnapshot records the blockchain info at the last block of the snapshot in place of the genesis block
+func (s *idStore) createLedgerIDFromSnapshot(ledgerID string, bcInfo *common.BlockchainInfo) error {
+	return s.putLedgerID(ledgerID, bcInfo)
+}
+
+func (s *idStore) putLedgerID(ledgerID string, msg proto.Message) error {
 	key := s.encodeLedgerKey(ledgerID)
 	var val []byte
 	var err error
@@ -294,7 +413,7 @@ func (s *idStore) createLedgerID(ledgerID string, gb *common.Block) error {
 	if val != nil {
 		return ErrLedgerIDExists
 	}
-	if val, err = proto.Marshal(gb); err != nil {
+	if val, err = proto.Marshal(msg); err != nil {
 		return err
 	}
 	batch := &leveldb.Batch{}
@@ -300,6 +300,59 @@ func TestLedgerBackup(t *testing.T) {
 	assert.Equal(t, []byte("value4"), result2.(*queryresult.KeyModification).Value)
 }
 
+func TestCollectionConfigListenersAndWatchers(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.cleanup()
+	provider, err := NewProvider()
+	assert.NoError(t, err)
+	defer provider.Close()
+	mockCCInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	listener := &collConfigListener{}
+	provider.Initialize(&lgr.Initializer{
+		DeployedChaincodeInfoProvider: mockCCInfoProvider,
+		MetricsProvider:               &disabled.Provider{},
+		CollectionConfigListeners:     []lgr.CollectionConfigListener{listener},
+	})
+	gb, _ := configtxtest.MakeGenesisBlock(constructTestLedgerID(0))
+	l, err := provider.Create(gb)
+	assert.NoError(t, err)
+	defer l.Close()
+	configHistoryRetriever, err := l.GetConfigHistoryRetriever()
+	assert.NoError(t, err)
+	watcher, ok := configHistoryRetriever.(lgr.ConfigHistoryWatcher)
+	assert.True(t, ok)
+	ch, cancel, err := watcher.WatchChaincode("cc1")
+	assert.NoError(t, err)
+	defer cancel()
+
+	collConfigPkg := &common.CollectionConfigPackage{Config: []*common.CollectionConfig{{
+		Payload: &common.CollectionConfig_StaticCollectionConfig{
+			StaticCollectionConfig: &common.StaticCollectionConfig{Name: "coll1", BlockToLive: 10},
+		},
+	}}}
+	mockCCInfoProvider.UpdatedChaincodesReturns([]*lgr.ChaincodeLifecycleInfo{{Name: "cc1"}}, nil)
+	mockCCInfoProvider.ChaincodeInfoReturns(&lgr.DeployedChaincodeInfo{Name: "cc1", CollectionConfigPkg: collConfigPkg}, nil)
+	assert.NoError(t, provider.(*Provider).configHistoryMgr.HandleStateUpdates(
+		&lgr.StateUpdateTrigger{LedgerID: constructTestLedgerID(0), CommittingBlockNum: 1}))
+
+	info := <-ch
+	assert.Equal(t, uint64(1), info.CommittingBlockNum)
+	assert.True(t, proto.Equal(collConfigPkg, info.CollectionConfig))
+	assert.Equal(t, []string{constructTestLedgerID(0) + "/cc1/1"}, listener.notifications)
+	assert.True(t, proto.Equal(collConfigPkg, listener.collConfig))
+}
+
+type collConfigListener struct {
+	notifications []string
+	collConfig    *common.CollectionConfigPackage
+}
+
+func (l *collConfigListener) HandleCollectionConfigChange(ledgerID, chaincodeName string, blockNum uint64,
+	collConfig *common.CollectionConfigPackage) {
+	l.notifications = append(l.notifications, fmt.Sprintf("%s/%s/%d", ledgerID, chaincodeName, blockNum))
+	l.collConfig = collConfig
+}
+
 func constructTestLedgerID(i int) string {
 	return fmt.Sprintf("ledger_%06d", i)
 }
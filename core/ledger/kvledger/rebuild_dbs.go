@@ -0,0 +1,85 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package kvledger
+
+import (
+	"github.com/hyperledger/fabric/common/ledger/util/leveldbhelper"
+	"github.com/hyperledger/fabric/core/ledger/kvledger/bookkeeping"
+	"github.com/hyperledger/fabric/core/ledger/ledgerconfig"
+	"github.com/pkg/errors"
+)
+
+// RebuildDBs clears the state db, the history db, and the bookkeeping of the given ledgers, or of all the ledgers of the peer if
+// none is given. As the savepoints of the state db and the history db are cleared along with these, the dbs are rebuilt by
+// recommitting the blocks from the block store when a ledger is opened next time. The block store, the pvt data store, and the
+// config history are retained. Only the leveldb based state db is supported. The dbs of the peer are opened by this function and
+// hence, this is meant for the offline use, while the peer is stopped
+func RebuildDBs(ledgerIDs ...string) error {
+	if ledgerconfig.IsCouchDBEnabled() {
+		return errors.New("rebuilding the dbs is not supported with CouchDB as the state db")
+	}
+	idStore := openIDStore(ledgerconfig.GetLedgerProviderPath())
+	defer idStore.close()
+	if len(ledgerIDs) == 0 {
+		var err error
+		if ledgerIDs, err = idStore.getAllLedgerIds(); err != nil {
+			return err
+		}
+	}
+	for _, ledgerID := range ledgerIDs {
+		exists, err := idStore.ledgerIDExists(ledgerID)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return errors.Wrapf(ErrNonExistingLedgerID, "cannot rebuild dbs of ledger [%s]", ledgerID)
+		}
+	}
+	for _, ledgerID := range ledgerIDs {
+		if err := clearDBs(ledgerID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// savepointKey is the key under which the state db and the history db of a ledger record their savepoints
+var savepointKey = []byte{0x00}
+
+// maxKeysPerDeleteBatch bounds the number of the keys deleted in a single write while clearing a db
+var maxKeysPerDeleteBatch = 10000
+
+// clearDBs removes all the data of the given ledger from the state db, the history db, and the bookkeeping. The savepoints of the
+// state db and the history db are removed first, so that the dbs are rebuilt fully even if the clearing is interrupted. The rest
+// of the data is removed in the writes of up to `maxKeysPerDeleteBatch` keys each
+func clearDBs(ledgerID string) error {
+	logger.Infof("Clearing the state db, the history db, and the bookkeeping of ledger [%s]", ledgerID)
+	var dbHandles []*leveldbhelper.DBHandle
+	for _, dbPath := range []string{ledgerconfig.GetStateLevelDBPath(), ledgerconfig.GetHistoryLevelDBPath()} {
+		dbProvider := leveldbhelper.NewProvider(&leveldbhelper.Conf{DBPath: dbPath})
+		defer dbProvider.Close()
+		dbHandles = append(dbHandles, dbProvider.GetDBHandle(ledgerID))
+	}
+	for _, dbHandle := range dbHandles {
+		if err := dbHandle.Delete(savepointKey, true); err != nil {
+			return errors.WithMessage(err, "error while removing the savepoint")
+		}
+	}
+	for _, dbHandle := range dbHandles {
+		if _, err := dbHandle.DeleteRangeInBatches(nil, nil, maxKeysPerDeleteBatch, true); err != nil {
+			return errors.WithMessage(err, "error while clearing the db")
+		}
+	}
+	bookkeepingProvider := bookkeeping.NewProvider()
+	defer bookkeepingProvider.Close()
+	for _, cat := range []bookkeeping.Category{bookkeeping.PvtdataExpiry, bookkeeping.MetadataPresenceIndicator} {
+		if _, err := bookkeepingProvider.GetDBHandle(ledgerID, cat).DeleteRangeInBatches(nil, nil, maxKeysPerDeleteBatch, true); err != nil {
+			return errors.WithMessage(err, "error while clearing the bookkeeping")
+		}
+	}
+	return nil
+}
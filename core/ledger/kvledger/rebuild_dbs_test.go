@@ -0,0 +1,85 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package kvledger
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/common/ledger/testutil"
+	"github.com/hyperledger/fabric/common/ledger/util/leveldbhelper"
+	lgr "github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/ledgerconfig"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRebuildDBs(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.cleanup()
+	provider := testutilNewProvider(t)
+	for _, ledgerID := range []string{"ledger1", "ledger2"} {
+		bg, gb := testutil.NewBlockGenerator(t, ledgerID, false)
+		ledger, err := provider.Create(gb)
+		assert.NoError(t, err)
+		for _, value := range []string{"value1", "value2"} {
+			simulator, err := ledger.NewTxSimulator("txid-" + value)
+			assert.NoError(t, err)
+			assert.NoError(t, simulator.SetState("ns1", "key1", []byte(value)))
+			simulator.Done()
+			simRes, err := simulator.GetTxSimulationResults()
+			assert.NoError(t, err)
+			pubSimBytes, err := simRes.GetPubSimulationBytes()
+			assert.NoError(t, err)
+			assert.NoError(t, ledger.CommitWithPvtData(&lgr.BlockAndPvtData{Block: bg.NextBlock([][]byte{pubSimBytes})}))
+		}
+		ledger.Close()
+	}
+	provider.Close()
+
+	assert.Equal(t, ErrNonExistingLedgerID, errors.Cause(RebuildDBs("ledger1", "nonExistingLedger")))
+	// the dbs hold more keys than a single delete batch
+	defer func(max int) { maxKeysPerDeleteBatch = max }(maxKeysPerDeleteBatch)
+	maxKeysPerDeleteBatch = 2
+	assert.NoError(t, RebuildDBs("ledger1"))
+	for _, dbPath := range []string{ledgerconfig.GetStateLevelDBPath(), ledgerconfig.GetHistoryLevelDBPath()} {
+		dbProvider := leveldbhelper.NewProvider(&leveldbhelper.Conf{DBPath: dbPath})
+		for _, ledgerID := range []string{"ledger1", "ledger2"} {
+			itr := dbProvider.GetDBHandle(ledgerID).GetIterator(nil, nil)
+			assert.Equal(t, ledgerID == "ledger2", itr.Next(), "db [%s] of ledger [%s]", dbPath, ledgerID)
+			itr.Release()
+		}
+		dbProvider.Close()
+	}
+
+	// the cleared dbs are rebuilt from the blocks when the ledger is opened
+	provider = testutilNewProvider(t)
+	defer provider.Close()
+	ledger, err := provider.Open("ledger1")
+	assert.NoError(t, err)
+	defer ledger.Close()
+	qe, err := ledger.NewQueryExecutor()
+	assert.NoError(t, err)
+	value, err := qe.GetState("ns1", "key1")
+	qe.Done()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("value2"), value)
+	hqe, err := ledger.NewHistoryQueryExecutor()
+	assert.NoError(t, err)
+	itr, err := hqe.GetHistoryForKey("ns1", "key1")
+	assert.NoError(t, err)
+	defer itr.Close()
+	numHistoryEntries := 0
+	for {
+		entry, err := itr.Next()
+		assert.NoError(t, err)
+		if entry == nil {
+			break
+		}
+		numHistoryEntries++
+	}
+	assert.Equal(t, 2, numHistoryEntries)
+}
@@ -0,0 +1,57 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package kvledger
+
+import (
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/ledgerconfig"
+	"github.com/hyperledger/fabric/core/ledger/ledgerstorage"
+	"github.com/pkg/errors"
+)
+
+// RollbackKVLedger rolls back the given ledger to the given block, i.e., removes the blocks after the given block along with
+// their pvt data. The state db, the history db, and the bookkeeping of the ledger are cleared, so that these are rebuilt from
+// the remaining blocks when the ledger is opened next time, and the config history is rebuilt right away (see function
+// `RebuildConfigHistory`). Only the leveldb based state db is supported. If the rollback is interrupted, it should be run
+// again. The dbs of the peer are opened by this function and hence, this is meant for the offline use, while the peer is stopped
+func RollbackKVLedger(ccInfoProvider ledger.DeployedChaincodeInfoProvider, ledgerID string, blockNum uint64) error {
+	if ledgerconfig.IsCouchDBEnabled() {
+		return errors.New("rollback is not supported with CouchDB as the state db")
+	}
+	idStore := openIDStore(ledgerconfig.GetLedgerProviderPath())
+	exists, err := idStore.ledgerIDExists(ledgerID)
+	idStore.close()
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errors.Wrapf(ErrNonExistingLedgerID, "cannot roll back ledger [%s]", ledgerID)
+	}
+	if err := validateRollbackTarget(ledgerID, blockNum); err != nil {
+		return err
+	}
+
+	if err := clearDBs(ledgerID); err != nil {
+		return err
+	}
+	if err := ledgerstorage.Rollback(ledgerID, blockNum); err != nil {
+		return err
+	}
+	return RebuildConfigHistory(ccInfoProvider, ledgerID)
+}
+
+func validateRollbackTarget(ledgerID string, blockNum uint64) error {
+	info, err := ledgerstorage.GetBlockchainInfo(ledgerID)
+	if err != nil {
+		return err
+	}
+	if blockNum >= info.Height {
+		return errors.Errorf("target block number [%d] should be less than the height [%d] of ledger [%s]",
+			blockNum, info.Height, ledgerID)
+	}
+	return nil
+}
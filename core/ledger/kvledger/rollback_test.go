@@ -0,0 +1,89 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package kvledger
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/common/ledger/testutil"
+	lgr "github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/mock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRollbackKVLedger(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.cleanup()
+	provider := testutilNewProvider(t)
+	_, gb := testutil.NewBlockGenerator(t, "testLedger", false)
+	ledger, err := provider.Create(gb)
+	assert.NoError(t, err)
+
+	commitBlock := func(ledger lgr.PeerLedger, txid, value string) {
+		simulator, err := ledger.NewTxSimulator(txid)
+		assert.NoError(t, err)
+		assert.NoError(t, simulator.SetState("ns1", "key1", []byte(value)))
+		simulator.Done()
+		simRes, err := simulator.GetTxSimulationResults()
+		assert.NoError(t, err)
+		pubSimBytes, err := simRes.GetPubSimulationBytes()
+		assert.NoError(t, err)
+		bcInfo, err := ledger.GetBlockchainInfo()
+		assert.NoError(t, err)
+		block := testutil.ConstructBlock(t, bcInfo.Height, bcInfo.CurrentBlockHash, [][]byte{pubSimBytes}, false)
+		assert.NoError(t, ledger.CommitWithPvtData(&lgr.BlockAndPvtData{Block: block}))
+	}
+	for _, value := range []string{"value1", "value2", "value3", "value4"} {
+		commitBlock(ledger, "txid-"+value, value)
+	}
+	ledger.Close()
+	provider.Close()
+
+	ccInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	assert.Equal(t, ErrNonExistingLedgerID, errors.Cause(RollbackKVLedger(ccInfoProvider, "nonExistingLedger", 1)))
+	assert.EqualError(t, RollbackKVLedger(ccInfoProvider, "testLedger", 5),
+		"target block number [5] should be less than the height [5] of ledger [testLedger]")
+	assert.NoError(t, RollbackKVLedger(ccInfoProvider, "testLedger", 2))
+
+	// the state and the history are rebuilt from the remaining blocks when the ledger is opened
+	provider = testutilNewProvider(t)
+	defer provider.Close()
+	ledger, err = provider.Open("testLedger")
+	assert.NoError(t, err)
+	defer ledger.Close()
+	bcInfo, err := ledger.GetBlockchainInfo()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), bcInfo.Height)
+	qe, err := ledger.NewQueryExecutor()
+	assert.NoError(t, err)
+	value, err := qe.GetState("ns1", "key1")
+	qe.Done()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("value2"), value)
+	hqe, err := ledger.NewHistoryQueryExecutor()
+	assert.NoError(t, err)
+	itr, err := hqe.GetHistoryForKey("ns1", "key1")
+	assert.NoError(t, err)
+	numHistoryEntries := 0
+	for {
+		entry, err := itr.Next()
+		assert.NoError(t, err)
+		if entry == nil {
+			break
+		}
+		numHistoryEntries++
+	}
+	itr.Close()
+	assert.Equal(t, 2, numHistoryEntries)
+
+	// the rolled back blocks can be replaced by the new ones
+	commitBlock(ledger, "txid-value5", "value5")
+	bcInfo, err = ledger.GetBlockchainInfo()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(4), bcInfo.Height)
+}
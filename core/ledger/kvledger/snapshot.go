@@ -0,0 +1,431 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package kvledger
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/hyperledger/fabric/common/ledger/blkstorage"
+	"github.com/hyperledger/fabric/core/ledger/confighistory"
+	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/privacyenabledstate"
+	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/version"
+	"github.com/hyperledger/fabric/core/ledger/ledgerconfig"
+	"github.com/hyperledger/fabric/core/ledger/util"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/pkg/errors"
+)
+
+const (
+	// TxIDsDataFileName is the name of the data file of a snapshot that holds the ids of the transactions in the ledger
+	TxIDsDataFileName = "txids.data"
+	// SnapshotMetadataFileName is the name of the file of a snapshot that holds the metadata of the snapshot, including
+	// the hashes of the data files
+	SnapshotMetadataFileName = "_snapshot_signable_metadata.json"
+
+	snapshotFileFormat = byte(1)
+)
+
+// snapshotMetadata is the content of the file `SnapshotMetadataFileName`
+type snapshotMetadata struct {
+	ChannelName            string            `json:"channel_name"`
+	LastBlockNumber        uint64            `json:"last_block_number"`
+	LastBlockHashInHex     string            `json:"last_block_hash"`
+	PreviousBlockHashInHex string            `json:"previous_block_hash"`
+	StateDBType            string            `json:"state_db_type"`
+	FilesHashesInHex       map[string]string `json:"snapshot_files_raw_hashes"`
+}
+
+// stateExporter is implemented by the state db that supports exporting its contents to a snapshot
+type stateExporter interface {
+	ExportPubStateAndPvtStateHashes(dir string) (map[string][]byte, error)
+}
+
+// stateImporter is implemented by the state db that supports loading its contents from a snapshot
+type stateImporter interface {
+	ImportPubStateAndPvtStateHashes(dir string, savepoint *version.Height) error
+}
+
+// historySavepointImporter is implemented by the history db that supports starting the history of the keys with the blocks
+// after a snapshot
+type historySavepointImporter interface {
+	ImportSavepoint(height *version.Height) error
+}
+
+// snapshotRequests maintains the block numbers for which a snapshot has been requested but not yet generated, along with
+// the block numbers whose snapshots are being generated in the background
+type snapshotRequests struct {
+	lock       sync.Mutex
+	blockNums  map[uint64]struct{}
+	inProgress map[uint64]struct{}
+}
+
+func newSnapshotRequests() *snapshotRequests {
+	return &snapshotRequests{blockNums: map[uint64]struct{}{}, inProgress: map[uint64]struct{}{}}
+}
+
+func (r *snapshotRequests) add(blockNum uint64) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if _, ok := r.blockNums[blockNum]; ok {
+		return errors.Errorf("duplicate snapshot request for block number [%d]", blockNum)
+	}
+	r.blockNums[blockNum] = struct{}{}
+	return nil
+}
+
+// remove removes the request for the given block number and returns whether such a request existed
+func (r *snapshotRequests) remove(blockNum uint64) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if _, ok := r.blockNums[blockNum]; !ok {
+		return false
+	}
+	delete(r.blockNums, blockNum)
+	return true
+}
+
+// markInProgress records that the snapshot for the given block is being generated. An error is returned if it already is
+func (r *snapshotRequests) markInProgress(blockNum uint64) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if _, ok := r.inProgress[blockNum]; ok {
+		return errors.Errorf("snapshot for block number [%d] is being generated", blockNum)
+	}
+	r.inProgress[blockNum] = struct{}{}
+	return nil
+}
+
+func (r *snapshotRequests) markDone(blockNum uint64) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	delete(r.inProgress, blockNum)
+}
+
+func (r *snapshotRequests) list() []uint64 {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	blockNums := make([]uint64, 0, len(r.blockNums))
+	for blockNum := range r.blockNums {
+		blockNums = append(blockNums, blockNum)
+	}
+	sort.Slice(blockNums, func(i, j int) bool { return blockNums[i] < blockNums[j] })
+	return blockNums
+}
+
+// SubmitSnapshotRequest implements the function in the interface `ledger.SnapshotRequester`. A request for the last committed
+// block (or for the block number zero) is processed right away, whereas a request for a future block is processed when the
+// block gets committed. A request for a block that is already superseded by a later block is rejected, as the state of the
+// ledger at that block is no longer available. In either case, the snapshot is completed in the background (see function
+// `generateSnapshot`)
+func (l *kvLedger) SubmitSnapshotRequest(blockNum uint64) error {
+	if ledgerconfig.IsCouchDBEnabled() {
+		return errors.New("generating a snapshot is not supported with CouchDB as the state database")
+	}
+	// holding the read lock prevents the commit of a block between the check of the height and the recording of the request
+	l.blockAPIsRWLock.RLock()
+	defer l.blockAPIsRWLock.RUnlock()
+	lastCommittedBlockNum, err := l.lastCommittedBlockNum()
+	if err != nil {
+		return err
+	}
+	switch {
+	case blockNum == 0 || blockNum == lastCommittedBlockNum:
+		return l.generateSnapshot(lastCommittedBlockNum)
+	case blockNum < lastCommittedBlockNum:
+		return errors.Errorf("requested snapshot for block number [%d] cannot be less than the last committed block number [%d]",
+			blockNum, lastCommittedBlockNum)
+	default:
+		return l.snapshotRequests.add(blockNum)
+	}
+}
+
+// PendingSnapshotRequests implements the function in the interface `ledger.SnapshotRequester`
+func (l *kvLedger) PendingSnapshotRequests() ([]uint64, error) {
+	return l.snapshotRequests.list(), nil
+}
+
+// processSnapshotRequests starts generating the snapshot if one has been requested for the given block. This is expected to be
+// invoked right after the commit of the block, while the commits are still blocked. A failure in generating the snapshot does not
+// fail the commit of the block, which has already been made durable
+func (l *kvLedger) processSnapshotRequests(blockNum uint64) {
+	if !l.snapshotRequests.remove(blockNum) {
+		return
+	}
+	if err := l.generateSnapshot(blockNum); err != nil {
+		logger.Errorf("[%s] Error while generating the snapshot for block [%d]: %+v", l.ledgerID, blockNum, err)
+	}
+}
+
+func (l *kvLedger) lastCommittedBlockNum() (uint64, error) {
+	info, err := l.blockStore.GetBlockchainInfo()
+	if err != nil {
+		return 0, err
+	}
+	if info.Height == 0 {
+		return 0, errors.Errorf("ledger [%s] does not have any committed block", l.ledgerID)
+	}
+	return info.Height - 1, nil
+}
+
+// generateSnapshot generates the snapshot for the given block in two phases. The caller is expected to block the commits and to
+// ensure that the given block is the last committed block, so that the state db and the config history, which are exported by
+// this function into a temporary directory, reflect the given block. The ids of the transactions are exported afterwards, in
+// the background, as the blocks up to the given block are not modified by the later commits; this way, the commits are not held
+// up for the scan of all the blocks. Once all the files are in place, the directory is renamed to
+// `<snapshots path>/completed/<ledger id>/<block number>`. A failure in the background is logged
+func (l *kvLedger) generateSnapshot(blockNum uint64) error {
+	stateExporter, ok := l.stateDB.(stateExporter)
+	if !ok {
+		return errors.New("generating a snapshot is not supported by the state database")
+	}
+	info, err := l.blockStore.GetBlockchainInfo()
+	if err != nil {
+		return err
+	}
+
+	completedDir := filepath.Join(ledgerconfig.GetSnapshotsPath(), "completed", l.ledgerID, strconv.FormatUint(blockNum, 10))
+	if _, err := os.Stat(completedDir); err == nil {
+		return errors.Errorf("snapshot for block number [%d] already exists at [%s]", blockNum, completedDir)
+	} else if !os.IsNotExist(err) {
+		return errors.Wrapf(err, "error while checking the snapshot directory [%s]", completedDir)
+	}
+	if err := l.snapshotRequests.markInProgress(blockNum); err != nil {
+		return err
+	}
+	tempDir, filesHashes, err := l.exportConsistentFiles(stateExporter, blockNum)
+	if err != nil {
+		l.snapshotRequests.markDone(blockNum)
+		return err
+	}
+
+	l.snapshotsWG.Add(1)
+	go func() {
+		defer l.snapshotsWG.Done()
+		defer l.snapshotRequests.markDone(blockNum)
+		if err := l.completeSnapshot(tempDir, completedDir, blockNum, info, filesHashes); err != nil {
+			os.RemoveAll(tempDir)
+			logger.Errorf("[%s] Error while generating the snapshot for block [%d]: %+v", l.ledgerID, blockNum, err)
+		}
+	}()
+	return nil
+}
+
+// exportConsistentFiles exports the state db and the config history into a new temporary directory. On a failure, the
+// directory is removed
+func (l *kvLedger) exportConsistentFiles(stateExporter stateExporter, blockNum uint64) (string, map[string][]byte, error) {
+	tempRoot := filepath.Join(ledgerconfig.GetSnapshotsPath(), "temp")
+	if err := os.MkdirAll(tempRoot, 0755); err != nil {
+		return "", nil, errors.Wrapf(err, "error while creating the directory [%s]", tempRoot)
+	}
+	tempDir, err := ioutil.TempDir(tempRoot, fmt.Sprintf("%s_%d_", l.ledgerID, blockNum))
+	if err != nil {
+		return "", nil, errors.Wrapf(err, "error while creating a temporary directory in [%s]", tempRoot)
+	}
+	filesHashes, err := stateExporter.ExportPubStateAndPvtStateHashes(tempDir)
+	if err == nil {
+		err = l.configHistoryMgr.ExportSnapshot(l.ledgerID, tempDir)
+	}
+	if err == nil {
+		filesHashes[confighistory.SnapshotDataFileName], err = util.SnapshotFileHash(
+			filepath.Join(tempDir, confighistory.SnapshotDataFileName))
+	}
+	if err != nil {
+		os.RemoveAll(tempDir)
+		return "", nil, err
+	}
+	return tempDir, filesHashes, nil
+}
+
+// completeSnapshot exports the ids of the transactions and the metadata into the given temporary directory and renames it
+// to the given directory of the completed snapshot
+func (l *kvLedger) completeSnapshot(tempDir, completedDir string, blockNum uint64, info *common.BlockchainInfo,
+	filesHashes map[string][]byte) error {
+	var err error
+	if filesHashes[TxIDsDataFileName], err = l.exportTxIDs(tempDir, blockNum); err != nil {
+		return err
+	}
+	metadata := &snapshotMetadata{
+		ChannelName:            l.ledgerID,
+		LastBlockNumber:        blockNum,
+		LastBlockHashInHex:     hex.EncodeToString(info.CurrentBlockHash),
+		PreviousBlockHashInHex: hex.EncodeToString(info.PreviousBlockHash),
+		StateDBType:            "goleveldb",
+		FilesHashesInHex:       map[string]string{},
+	}
+	for fileName, hash := range filesHashes {
+		metadata.FilesHashesInHex[fileName] = hex.EncodeToString(hash)
+	}
+	if err := writeSnapshotMetadata(filepath.Join(tempDir, SnapshotMetadataFileName), metadata); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(completedDir), 0755); err != nil {
+		return errors.Wrapf(err, "error while creating the directory [%s]", filepath.Dir(completedDir))
+	}
+	if err := os.Rename(tempDir, completedDir); err != nil {
+		return errors.Wrapf(err, "error while moving the snapshot to [%s]", completedDir)
+	}
+	logger.Infof("[%s] Generated the snapshot for block [%d] at [%s]", l.ledgerID, blockNum, completedDir)
+	return nil
+}
+
+// exportTxIDs writes the ids of the transactions in the blocks up to the given block, in the order of their appearance
+// and without the duplicates, to the data file `TxIDsDataFileName`. For a ledger created from a snapshot, the ids of the
+// transactions that precede that snapshot come first, followed by the ids in the blocks after that snapshot
+func (l *kvLedger) exportTxIDs(dir string, lastBlockNum uint64) ([]byte, error) {
+	w, err := util.CreateSnapshotFile(filepath.Join(dir, TxIDsDataFileName), snapshotFileFormat)
+	if err != nil {
+		return nil, err
+	}
+	defer w.Close()
+	seen := map[string]struct{}{}
+	firstBlockNum := uint64(0)
+	if snapshotInfo := l.blockStore.BootstrappingSnapshotInfo(); snapshotInfo != nil {
+		if err := l.blockStore.ForEachTxIDBeforeSnapshot(func(txID string) error {
+			seen[txID] = struct{}{}
+			return w.EncodeBytes([]byte(txID))
+		}); err != nil {
+			return nil, err
+		}
+		firstBlockNum = snapshotInfo.LastBlockNum + 1
+	}
+	for blockNum := firstBlockNum; blockNum <= lastBlockNum; blockNum++ {
+		block, err := l.blockStore.RetrieveBlockByNumber(blockNum)
+		if err != nil {
+			return nil, err
+		}
+		for txIndex, envBytes := range block.Data.Data {
+			env, err := utils.GetEnvelopeFromBlock(envBytes)
+			if err != nil {
+				return nil, errors.WithMessage(err, fmt.Sprintf("error while reading the transaction [%d] in block [%d]", txIndex, blockNum))
+			}
+			chdr, err := utils.ChannelHeader(env)
+			if err != nil {
+				return nil, errors.WithMessage(err, fmt.Sprintf("error while reading the transaction [%d] in block [%d]", txIndex, blockNum))
+			}
+			if chdr.TxId == "" {
+				continue
+			}
+			if _, ok := seen[chdr.TxId]; ok {
+				continue
+			}
+			seen[chdr.TxId] = struct{}{}
+			if err := w.EncodeBytes([]byte(chdr.TxId)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return w.Done()
+}
+
+// loadSnapshotMetadata reads the metadata of the snapshot in the given dir and verifies the hashes of the data files
+// against the ones recorded in the metadata
+func loadSnapshotMetadata(snapshotDir string) (*snapshotMetadata, error) {
+	path := filepath.Join(snapshotDir, SnapshotMetadataFileName)
+	metadataBytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error while reading the snapshot metadata file [%s]", path)
+	}
+	metadata := &snapshotMetadata{}
+	if err := json.Unmarshal(metadataBytes, metadata); err != nil {
+		return nil, errors.Wrapf(err, "error while unmarshalling the snapshot metadata file [%s]", path)
+	}
+	if metadata.ChannelName == "" {
+		return nil, errors.Errorf("snapshot metadata file [%s] does not record the channel name", path)
+	}
+	for _, fileName := range []string{
+		privacyenabledstate.PubStateDataFileName,
+		privacyenabledstate.PvtStateHashesFileName,
+		confighistory.SnapshotDataFileName,
+		TxIDsDataFileName,
+	} {
+		expectedHashInHex, ok := metadata.FilesHashesInHex[fileName]
+		if !ok {
+			return nil, errors.Errorf("snapshot metadata file [%s] does not record the hash of the file [%s]", path, fileName)
+		}
+		hash, err := util.SnapshotFileHash(filepath.Join(snapshotDir, fileName))
+		if err != nil {
+			return nil, err
+		}
+		if hex.EncodeToString(hash) != expectedHashInHex {
+			return nil, errors.Errorf("hash of the snapshot file [%s] does not match the hash recorded in the snapshot metadata", fileName)
+		}
+	}
+	return metadata, nil
+}
+
+// snapshotInfo returns the info of the last block of the snapshot that is recorded in the metadata
+func (m *snapshotMetadata) snapshotInfo() (*blkstorage.SnapshotInfo, error) {
+	lastBlockHash, err := hex.DecodeString(m.LastBlockHashInHex)
+	if err != nil {
+		return nil, errors.Wrap(err, "error while decoding the hash of the last block in the snapshot metadata")
+	}
+	previousBlockHash, err := hex.DecodeString(m.PreviousBlockHashInHex)
+	if err != nil {
+		return nil, errors.Wrap(err, "error while decoding the hash of the previous block in the snapshot metadata")
+	}
+	return &blkstorage.SnapshotInfo{
+		LastBlockNum:      m.LastBlockNumber,
+		LastBlockHash:     lastBlockHash,
+		PreviousBlockHash: previousBlockHash,
+	}, nil
+}
+
+// txIDsReader reads the ids of the transactions from the data file `TxIDsDataFileName`
+type txIDsReader struct {
+	r *util.SnapshotFileReader
+}
+
+func openTxIDsReader(snapshotDir string) (*txIDsReader, error) {
+	r, err := util.OpenSnapshotFile(filepath.Join(snapshotDir, TxIDsDataFileName), snapshotFileFormat)
+	if err != nil {
+		return nil, err
+	}
+	return &txIDsReader{r}, nil
+}
+
+// Next implements the function in the interface `blkstorage.TxIDsIterator`
+func (r *txIDsReader) Next() (string, error) {
+	hasMore, err := r.r.HasMore()
+	if err != nil || !hasMore {
+		return "", err
+	}
+	txID, err := r.r.DecodeBytes()
+	if err != nil {
+		return "", err
+	}
+	return string(txID), nil
+}
+
+func (r *txIDsReader) close() {
+	r.r.Close()
+}
+
+func writeSnapshotMetadata(path string, metadata *snapshotMetadata) error {
+	metadataBytes, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "error while marshalling the snapshot metadata")
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return errors.Wrapf(err, "error while creating the snapshot metadata file [%s]", path)
+	}
+	defer file.Close()
+	if _, err := file.Write(metadataBytes); err != nil {
+		return errors.Wrapf(err, "error while writing the snapshot metadata file [%s]", path)
+	}
+	return errors.Wrapf(file.Sync(), "error while syncing the snapshot metadata file [%s]", path)
+}
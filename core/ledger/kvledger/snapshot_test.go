@@ -0,0 +1,309 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package kvledger
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/common/ledger/testutil"
+	lgr "github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/confighistory"
+	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/privacyenabledstate"
+	"github.com/hyperledger/fabric/core/ledger/ledgerconfig"
+	putils "github.com/hyperledger/fabric/protos/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshotGeneration(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.cleanup()
+	provider := testutilNewProvider(t)
+	defer provider.Close()
+
+	bg, gb := testutil.NewBlockGenerator(t, "testLedger", false)
+	ledger, err := provider.Create(gb)
+	assert.NoError(t, err)
+	defer ledger.Close()
+	snapshotRequester := ledger.(lgr.SnapshotRequester)
+	genesisEnv, err := putils.GetEnvelopeFromBlock(gb.Data.Data[0])
+	assert.NoError(t, err)
+	genesisChdr, err := putils.ChannelHeader(genesisEnv)
+	assert.NoError(t, err)
+
+	commitBlock := func(txid, value string) {
+		simulator, err := ledger.NewTxSimulator(txid)
+		assert.NoError(t, err)
+		assert.NoError(t, simulator.SetState("ns1", "key1", []byte(value)))
+		simulator.Done()
+		simRes, err := simulator.GetTxSimulationResults()
+		assert.NoError(t, err)
+		pubSimBytes, err := simRes.GetPubSimulationBytes()
+		assert.NoError(t, err)
+		block := bg.NextBlockWithTxid([][]byte{pubSimBytes}, []string{txid})
+		assert.NoError(t, ledger.CommitWithPvtData(&lgr.BlockAndPvtData{Block: block}))
+	}
+	commitBlock("txid1", "value1")
+
+	// a request for the last committed block is processed right away and completed in the background
+	kvLedger := ledger.(*kvLedger)
+	assert.NoError(t, kvLedger.snapshotRequests.markInProgress(1))
+	assert.EqualError(t, snapshotRequester.SubmitSnapshotRequest(0), "snapshot for block number [1] is being generated")
+	kvLedger.snapshotRequests.markDone(1)
+	assert.NoError(t, snapshotRequester.SubmitSnapshotRequest(0))
+	kvLedger.snapshotsWG.Wait()
+	verifySnapshot(t, ledger, 1, []string{genesisChdr.TxId, "txid1"})
+	assert.Contains(t, snapshotRequester.SubmitSnapshotRequest(1).Error(), "snapshot for block number [1] already exists")
+
+	// a request for a future block is processed when the block gets committed
+	assert.NoError(t, snapshotRequester.SubmitSnapshotRequest(3))
+	assert.Contains(t, snapshotRequester.SubmitSnapshotRequest(3).Error(), "duplicate snapshot request for block number [3]")
+	assert.NoError(t, snapshotRequester.SubmitSnapshotRequest(5))
+	pendingRequests, err := snapshotRequester.PendingSnapshotRequests()
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{3, 5}, pendingRequests)
+
+	commitBlock("txid2", "value2")
+	commitBlock("txid3", "value3")
+	kvLedger.snapshotsWG.Wait()
+	verifySnapshot(t, ledger, 3, []string{genesisChdr.TxId, "txid1", "txid2", "txid3"})
+	pendingRequests, err = snapshotRequester.PendingSnapshotRequests()
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{5}, pendingRequests)
+
+	assert.Contains(t, snapshotRequester.SubmitSnapshotRequest(2).Error(),
+		"requested snapshot for block number [2] cannot be less than the last committed block number [3]")
+	// no temporary directories are left behind
+	tempDirs, err := ioutil.ReadDir(filepath.Join(ledgerconfig.GetSnapshotsPath(), "temp"))
+	assert.NoError(t, err)
+	assert.Len(t, tempDirs, 0)
+}
+
+func TestCreateFromSnapshot(t *testing.T) {
+	sourceEnv := newTestEnv(t)
+	defer sourceEnv.cleanup()
+	sourceProvider := testutilNewProvider(t)
+	bg, gb := testutil.NewBlockGenerator(t, "testLedger", false)
+	sourceLedger, err := sourceProvider.Create(gb)
+	assert.NoError(t, err)
+	genesisEnv, err := putils.GetEnvelopeFromBlock(gb.Data.Data[0])
+	assert.NoError(t, err)
+	genesisChdr, err := putils.ChannelHeader(genesisEnv)
+	assert.NoError(t, err)
+	commitBlock := func(ledger lgr.PeerLedger, txid, key, value string) {
+		simulator, err := ledger.NewTxSimulator(txid)
+		assert.NoError(t, err)
+		assert.NoError(t, simulator.SetState("ns1", key, []byte(value)))
+		simulator.Done()
+		simRes, err := simulator.GetTxSimulationResults()
+		assert.NoError(t, err)
+		pubSimBytes, err := simRes.GetPubSimulationBytes()
+		assert.NoError(t, err)
+		block := bg.NextBlockWithTxid([][]byte{pubSimBytes}, []string{txid})
+		assert.NoError(t, ledger.CommitWithPvtData(&lgr.BlockAndPvtData{Block: block}))
+	}
+	commitBlock(sourceLedger, "txid1", "key1", "value1")
+	commitBlock(sourceLedger, "txid2", "key2", "value2")
+	assert.NoError(t, sourceLedger.(lgr.SnapshotRequester).SubmitSnapshotRequest(0))
+	sourceLedger.(*kvLedger).snapshotsWG.Wait()
+	sourceBCInfo, err := sourceLedger.GetBlockchainInfo()
+	assert.NoError(t, err)
+	sourceLedger.Close()
+	sourceProvider.Close()
+	snapshotDir := filepath.Join(ledgerconfig.GetSnapshotsPath(), "completed", "testLedger", "2")
+
+	env := newTestEnv(t)
+	defer env.cleanup()
+	provider := testutilNewProvider(t)
+	ledger, ledgerID, err := provider.(lgr.SnapshotLedgerCreator).CreateFromSnapshot(snapshotDir)
+	assert.NoError(t, err)
+	assert.Equal(t, "testLedger", ledgerID)
+	bcInfo, err := ledger.GetBlockchainInfo()
+	assert.NoError(t, err)
+	assert.True(t, proto.Equal(sourceBCInfo, bcInfo))
+	_, _, err = provider.(lgr.SnapshotLedgerCreator).CreateFromSnapshot(snapshotDir)
+	assert.Equal(t, ErrLedgerIDExists, err)
+
+	qe, err := ledger.NewQueryExecutor()
+	assert.NoError(t, err)
+	value, err := qe.GetState("ns1", "key2")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("value2"), value)
+	qe.Done()
+	_, err = ledger.GetTransactionByID("txid1")
+	assert.Equal(t, lgr.TxBeforeSnapshotErr("txid1"), err)
+	historySavepoint, err := ledger.(*kvLedger).historyDB.GetLastSavepoint()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), historySavepoint.BlockNum)
+
+	// the blocks after the snapshot are committed as usual and a snapshot of the ledger carries over the ids of the
+	// transactions in the snapshot from which the ledger was created
+	commitBlock(ledger, "txid3", "key1", "value3")
+	qe, err = ledger.NewQueryExecutor()
+	assert.NoError(t, err)
+	value, err = qe.GetState("ns1", "key1")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("value3"), value)
+	qe.Done()
+	assert.NoError(t, ledger.(lgr.SnapshotRequester).SubmitSnapshotRequest(0))
+	ledger.(*kvLedger).snapshotsWG.Wait()
+	txIDsBeforeSnapshot := []string{genesisChdr.TxId, "txid1", "txid2"}
+	sort.Strings(txIDsBeforeSnapshot)
+	verifySnapshot(t, ledger, 3, append(txIDsBeforeSnapshot, "txid3"))
+	ledger.Close()
+	provider.Close()
+
+	provider = testutilNewProvider(t)
+	defer provider.Close()
+	ledger, err = provider.Open("testLedger")
+	assert.NoError(t, err)
+	defer ledger.Close()
+	bcInfo, err = ledger.GetBlockchainInfo()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(4), bcInfo.Height)
+}
+
+func TestCreateFromSnapshotRecovery(t *testing.T) {
+	sourceEnv := newTestEnv(t)
+	defer sourceEnv.cleanup()
+	sourceProvider := testutilNewProvider(t)
+	_, gb := testutil.NewBlockGenerator(t, "testLedger", false)
+	sourceLedger, err := sourceProvider.Create(gb)
+	assert.NoError(t, err)
+	assert.NoError(t, sourceLedger.(lgr.SnapshotRequester).SubmitSnapshotRequest(0))
+	sourceLedger.(*kvLedger).snapshotsWG.Wait()
+	sourceLedger.Close()
+	sourceProvider.Close()
+	snapshotDir := filepath.Join(ledgerconfig.GetSnapshotsPath(), "completed", "testLedger", "0")
+
+	env := newTestEnv(t)
+	defer env.cleanup()
+	provider := testutilNewProvider(t).(*Provider)
+	_, _, err = provider.CreateFromSnapshot(filepath.Join(env.path, "non-existent-dir"))
+	assert.Contains(t, err.Error(), "error while reading the snapshot metadata file")
+
+	// a crash after the snapshot is imported leaves the ledger as under construction
+	metadata, err := loadSnapshotMetadata(snapshotDir)
+	assert.NoError(t, err)
+	snapshotInfo, err := metadata.snapshotInfo()
+	assert.NoError(t, err)
+	assert.NoError(t, provider.idStore.setUnderConstructionFlag("testLedger"))
+	assert.NoError(t, provider.importSnapshot("testLedger", snapshotDir, snapshotInfo))
+	provider.Close()
+
+	provider = testutilNewProvider(t).(*Provider)
+	defer provider.Close()
+	flag, err := provider.idStore.getUnderConstructionFlag()
+	assert.NoError(t, err)
+	assert.Equal(t, "", flag)
+	exists, err := provider.Exists("testLedger")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+	ledger, err := provider.Open("testLedger")
+	assert.NoError(t, err)
+	defer ledger.Close()
+	bcInfo, err := ledger.GetBlockchainInfo()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), bcInfo.Height)
+	assert.Equal(t, gb.Header.Hash(), bcInfo.CurrentBlockHash)
+}
+
+func TestLoadSnapshotMetadata(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.cleanup()
+	provider := testutilNewProvider(t)
+	defer provider.Close()
+	_, gb := testutil.NewBlockGenerator(t, "testLedger", false)
+	ledger, err := provider.Create(gb)
+	assert.NoError(t, err)
+	defer ledger.Close()
+	assert.NoError(t, ledger.(lgr.SnapshotRequester).SubmitSnapshotRequest(0))
+	ledger.(*kvLedger).snapshotsWG.Wait()
+	snapshotDir := filepath.Join(ledgerconfig.GetSnapshotsPath(), "completed", "testLedger", "0")
+
+	metadata, err := loadSnapshotMetadata(snapshotDir)
+	assert.NoError(t, err)
+	assert.Equal(t, "testLedger", metadata.ChannelName)
+	snapshotInfo, err := metadata.snapshotInfo()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), snapshotInfo.LastBlockNum)
+	assert.Equal(t, gb.Header.Hash(), snapshotInfo.LastBlockHash)
+
+	txIDsFile := filepath.Join(snapshotDir, TxIDsDataFileName)
+	assert.NoError(t, os.Chmod(txIDsFile, 0644))
+	f, err := os.OpenFile(txIDsFile, os.O_APPEND|os.O_WRONLY, 0644)
+	assert.NoError(t, err)
+	_, err = f.Write([]byte("extra"))
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+	_, err = loadSnapshotMetadata(snapshotDir)
+	assert.EqualError(t, err, "hash of the snapshot file [txids.data] does not match the hash recorded in the snapshot metadata")
+}
+
+func verifySnapshot(t *testing.T, ledger lgr.PeerLedger, blockNum uint64, expectedTxIDs []string) {
+	snapshotDir := filepath.Join(ledgerconfig.GetSnapshotsPath(), "completed", "testLedger", strconv.FormatUint(blockNum, 10))
+	bcInfo, err := ledger.GetBlockchainInfo()
+	assert.NoError(t, err)
+
+	metadataBytes, err := ioutil.ReadFile(filepath.Join(snapshotDir, SnapshotMetadataFileName))
+	assert.NoError(t, err)
+	metadata := &snapshotMetadata{}
+	assert.NoError(t, json.Unmarshal(metadataBytes, metadata))
+	assert.Equal(t, "testLedger", metadata.ChannelName)
+	assert.Equal(t, blockNum, metadata.LastBlockNumber)
+	assert.Equal(t, hex.EncodeToString(bcInfo.CurrentBlockHash), metadata.LastBlockHashInHex)
+	assert.Equal(t, hex.EncodeToString(bcInfo.PreviousBlockHash), metadata.PreviousBlockHashInHex)
+	assert.Equal(t, "goleveldb", metadata.StateDBType)
+
+	expectedFiles := []string{
+		privacyenabledstate.PubStateDataFileName,
+		privacyenabledstate.PvtStateHashesFileName,
+		TxIDsDataFileName,
+		confighistory.SnapshotDataFileName,
+	}
+	assert.Len(t, metadata.FilesHashesInHex, len(expectedFiles))
+	for _, fileName := range expectedFiles {
+		contents, err := ioutil.ReadFile(filepath.Join(snapshotDir, fileName))
+		assert.NoError(t, err)
+		hash := sha256.Sum256(contents)
+		assert.Equal(t, hex.EncodeToString(hash[:]), metadata.FilesHashesInHex[fileName])
+	}
+	assert.Equal(t, expectedTxIDs, readTxIDs(t, filepath.Join(snapshotDir, TxIDsDataFileName)))
+}
+
+func readTxIDs(t *testing.T, path string) []string {
+	f, err := os.Open(path)
+	assert.NoError(t, err)
+	defer f.Close()
+	r := bufio.NewReader(f)
+	format, err := r.ReadByte()
+	assert.NoError(t, err)
+	assert.Equal(t, snapshotFileFormat, format)
+	var txIDs []string
+	for {
+		length, err := binary.ReadUvarint(r)
+		if err == io.EOF {
+			return txIDs
+		}
+		assert.NoError(t, err)
+		txID := make([]byte, length)
+		_, err = io.ReadFull(r, txID)
+		assert.NoError(t, err)
+		txIDs = append(txIDs, string(txID))
+	}
+}
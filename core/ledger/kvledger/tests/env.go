@@ -19,6 +19,7 @@ import (
 	"github.com/hyperledger/fabric/common/ledger/blkstorage/fsblkstorage"
 	"github.com/hyperledger/fabric/common/ledger/util"
 	"github.com/hyperledger/fabric/core/common/privdata"
+	"github.com/hyperledger/fabric/core/ledger/kvledger"
 	"github.com/hyperledger/fabric/core/ledger/ledgerconfig"
 	"github.com/hyperledger/fabric/core/ledger/ledgermgmt"
 	"github.com/hyperledger/fabric/core/peer"
@@ -86,6 +87,18 @@ func (e *env) closeAllLedgersAndDrop(flags rebuildable) {
 	}
 }
 
+// closeAllLedgersAndRebuildConfigHistory drops the config history and rebuilds it from the blocks while the ledgers are closed
+func (e *env) closeAllLedgersAndRebuildConfigHistory() {
+	closeLedgerMgmt()
+	defer initLedgerMgmt()
+
+	configHistory := getConfigHistoryDBPath()
+	logger.Infof("Deleting configHistory db path [%s]", configHistory)
+	e.verifyNonEmptyDirExists(configHistory)
+	e.assert.NoError(os.RemoveAll(configHistory))
+	e.assert.NoError(kvledger.RebuildConfigHistory(&lscc.DeployedCCInfoProvider{}))
+}
+
 func (e *env) verifyRebuilablesExist(flags rebuildable) {
 	if flags&rebuildableStatedb == rebuildableBlockIndex {
 		e.verifyNonEmptyDirExists(getBlockIndexDBPath())
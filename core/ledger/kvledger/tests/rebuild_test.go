@@ -41,6 +41,15 @@ func TestRebuildComponents(t *testing.T) {
 		},
 	)
 
+	t.Run("rebuild config history offline",
+		func(t *testing.T) {
+			env.closeAllLedgersAndRebuildConfigHistory()
+			h1, h2 := newTestHelperOpenLgr("ledger1", t), newTestHelperOpenLgr("ledger2", t)
+			dataHelper.verifyLedgerContent(h1)
+			dataHelper.verifyLedgerContent(h2)
+		},
+	)
+
 	t.Run("rebuild statedb and block index",
 		func(t *testing.T) {
 			env.closeAllLedgersAndDrop(rebuildableStatedb + rebuildableBlockIndex)
@@ -0,0 +1,180 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package privacyenabledstate
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/statedb"
+	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/version"
+	"github.com/hyperledger/fabric/core/ledger/util"
+	"github.com/pkg/errors"
+)
+
+const (
+	// PubStateDataFileName is the name of the data file of a snapshot that holds the public state
+	PubStateDataFileName = "public_state.data"
+	// PvtStateHashesFileName is the name of the data file of a snapshot that holds the hashes of the private state
+	PvtStateHashesFileName = "private_state_hashes.data"
+
+	snapshotFileFormat = byte(1)
+)
+
+var maxEntriesPerImportBatch = 10000
+
+// ExportPubStateAndPvtStateHashes writes the public state and the hashes of the private state to the data files `PubStateDataFileName`
+// and `PvtStateHashesFileName` in the given directory and returns the SHA-256 of each of the files, keyed by the file name. The private
+// state itself is not exported. Each entry is encoded as the namespace, the key, the value, the metadata, and the version bytes, in
+// the order of the namespaces and the keys; the namespaces of the hashes are the ones derived from the chaincode and the collection.
+// The caller is expected to block the commits during the export, so that the files reflect the same height of the ledger. This is
+// supported only if the wrapped db implements the interface `statedb.FullScanner`
+func (s *CommonStorageDB) ExportPubStateAndPvtStateHashes(dir string) (map[string][]byte, error) {
+	fullScanner, ok := s.VersionedDB.(statedb.FullScanner)
+	if !ok {
+		return nil, errors.New("exporting the state is not supported by the state database")
+	}
+	itr, err := fullScanner.GetFullScanIterator(isPvtDataNs)
+	if err != nil {
+		return nil, err
+	}
+	defer itr.Close()
+
+	pubStateWriter, err := util.CreateSnapshotFile(filepath.Join(dir, PubStateDataFileName), snapshotFileFormat)
+	if err != nil {
+		return nil, err
+	}
+	defer pubStateWriter.Close()
+	pvtStateHashesWriter, err := util.CreateSnapshotFile(filepath.Join(dir, PvtStateHashesFileName), snapshotFileFormat)
+	if err != nil {
+		return nil, err
+	}
+	defer pvtStateHashesWriter.Close()
+
+	for {
+		result, err := itr.Next()
+		if err != nil {
+			return nil, err
+		}
+		if result == nil {
+			break
+		}
+		kv := result.(*statedb.VersionedKV)
+		w := pubStateWriter
+		if isHashedDataNs(kv.Namespace) {
+			w = pvtStateHashesWriter
+		}
+		if err := writeSnapshotEntry(w, kv); err != nil {
+			return nil, err
+		}
+	}
+
+	pubStateHash, err := pubStateWriter.Done()
+	if err != nil {
+		return nil, err
+	}
+	pvtStateHashesHash, err := pvtStateHashesWriter.Done()
+	if err != nil {
+		return nil, err
+	}
+	return map[string][]byte{
+		PubStateDataFileName:   pubStateHash,
+		PvtStateHashesFileName: pvtStateHashesHash,
+	}, nil
+}
+
+// ImportPubStateAndPvtStateHashes loads the public state and the hashes of the private state from the data files written by the
+// function `ExportPubStateAndPvtStateHashes` in the given directory, and records the given height as the savepoint. The entries are
+// applied in batches and the savepoint only with the last batch; an interrupted import leaves the db without a savepoint and can
+// be run again. The db is expected not to have a savepoint, i.e., not to have committed any block
+func (s *CommonStorageDB) ImportPubStateAndPvtStateHashes(dir string, savepoint *version.Height) error {
+	if _, ok := s.VersionedDB.(statedb.FullScanner); !ok {
+		return errors.New("importing the state is not supported by the state database")
+	}
+	existingSavepoint, err := s.GetLatestSavePoint()
+	if err != nil {
+		return err
+	}
+	if existingSavepoint != nil {
+		return errors.Errorf("state database is not empty, the savepoint is at block [%d]", existingSavepoint.BlockNum)
+	}
+	updates := NewUpdateBatch()
+	numEntries := 0
+	for _, fileName := range []string{PubStateDataFileName, PvtStateHashesFileName} {
+		r, err := util.OpenSnapshotFile(filepath.Join(dir, fileName), snapshotFileFormat)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		for {
+			hasMore, err := r.HasMore()
+			if err != nil {
+				return err
+			}
+			if !hasMore {
+				break
+			}
+			if err := readSnapshotEntry(r, updates); err != nil {
+				return err
+			}
+			if numEntries++; numEntries%maxEntriesPerImportBatch != 0 {
+				continue
+			}
+			if err := s.ApplyPrivacyAwareUpdates(updates, nil); err != nil {
+				return err
+			}
+			updates = NewUpdateBatch()
+		}
+	}
+	if err := s.ApplyPrivacyAwareUpdates(updates, savepoint); err != nil {
+		return err
+	}
+	logger.Infof("Imported [%d] entries of the public state and the hashes of the private state", numEntries)
+	return nil
+}
+
+// readSnapshotEntry reads an entry written by the function `writeSnapshotEntry` into the given batch. An entry in a namespace
+// of the hashes of the private state is added as the hash of the key in the collection
+func readSnapshotEntry(r *util.SnapshotFileReader, updates *UpdateBatch) error {
+	fields := make([][]byte, 5)
+	for i := range fields {
+		field, err := r.DecodeBytes()
+		if err != nil {
+			return err
+		}
+		fields[i] = field
+	}
+	namespace, key, value, metadata := string(fields[0]), fields[1], fields[2], fields[3]
+	if len(metadata) == 0 {
+		metadata = nil
+	}
+	ver, _ := version.NewHeightFromBytes(fields[4])
+	if !isHashedDataNs(namespace) {
+		updates.PubUpdates.PutValAndMetadata(namespace, string(key), value, metadata, ver)
+		return nil
+	}
+	nsAndColl := strings.SplitN(namespace, nsJoiner+hashDataPrefix, 2)
+	updates.HashUpdates.PutValHashAndMetadata(nsAndColl[0], nsAndColl[1], key, value, metadata, ver)
+	return nil
+}
+
+func writeSnapshotEntry(w *util.SnapshotFileWriter, kv *statedb.VersionedKV) error {
+	for _, field := range [][]byte{[]byte(kv.Namespace), []byte(kv.Key), kv.Value, kv.Metadata, kv.Version.ToBytes()} {
+		if err := w.EncodeBytes(field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isPvtDataNs(namespace string) bool {
+	return strings.Contains(namespace, nsJoiner+pvtDataPrefix)
+}
+
+func isHashedDataNs(namespace string) bool {
+	return strings.Contains(namespace, nsJoiner+hashDataPrefix)
+}
@@ -0,0 +1,145 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package privacyenabledstate
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/version"
+	"github.com/hyperledger/fabric/core/ledger/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportPubStateAndPvtStateHashes(t *testing.T) {
+	env := &LevelDBCommonStorageTestEnv{}
+	env.Init(t)
+	defer env.Cleanup()
+	db := env.GetDBHandle("test-ledger-id")
+	snapshotDir, err := ioutil.TempDir("", "statesnapshot")
+	assert.NoError(t, err)
+	defer os.RemoveAll(snapshotDir)
+
+	updates := NewUpdateBatch()
+	updates.PubUpdates.Put("ns1", "key1", []byte("value1"), version.NewHeight(1, 1))
+	updates.PubUpdates.PutValAndMetadata("ns2", "key2", []byte("value2"), []byte("metadata2"), version.NewHeight(1, 2))
+	putPvtUpdates(t, updates, "ns1", "coll1", "key1", []byte("pvt_value1"), version.NewHeight(1, 3))
+	assert.NoError(t, db.ApplyPrivacyAwareUpdates(updates, version.NewHeight(1, 3)))
+
+	hashes, err := db.(*CommonStorageDB).ExportPubStateAndPvtStateHashes(snapshotDir)
+	assert.NoError(t, err)
+	assert.Len(t, hashes, 2)
+	for fileName, hash := range hashes {
+		contents, err := ioutil.ReadFile(filepath.Join(snapshotDir, fileName))
+		assert.NoError(t, err)
+		expectedHash := sha256.Sum256(contents)
+		assert.Equal(t, expectedHash[:], hash)
+	}
+
+	assert.Equal(t, [][][]byte{
+		{[]byte("ns1"), []byte("key1"), []byte("value1"), {}, version.NewHeight(1, 1).ToBytes()},
+		{[]byte("ns2"), []byte("key2"), []byte("value2"), []byte("metadata2"), version.NewHeight(1, 2).ToBytes()},
+	}, readSnapshotEntries(t, filepath.Join(snapshotDir, PubStateDataFileName)))
+	// the private state itself is not exported
+	assert.Equal(t, [][][]byte{
+		{[]byte(deriveHashedDataNs("ns1", "coll1")), util.ComputeStringHash("key1"), util.ComputeHash([]byte("pvt_value1")), {},
+			version.NewHeight(1, 3).ToBytes()},
+	}, readSnapshotEntries(t, filepath.Join(snapshotDir, PvtStateHashesFileName)))
+
+	// the existing files are not overwritten
+	_, err = db.(*CommonStorageDB).ExportPubStateAndPvtStateHashes(snapshotDir)
+	assert.Error(t, err)
+}
+
+func TestImportPubStateAndPvtStateHashes(t *testing.T) {
+	defer func(max int) { maxEntriesPerImportBatch = max }(maxEntriesPerImportBatch)
+	maxEntriesPerImportBatch = 2
+	env := &LevelDBCommonStorageTestEnv{}
+	env.Init(t)
+	defer env.Cleanup()
+	sourceDB := env.GetDBHandle("source-ledger-id")
+	snapshotDir, err := ioutil.TempDir("", "statesnapshot")
+	assert.NoError(t, err)
+	defer os.RemoveAll(snapshotDir)
+
+	updates := NewUpdateBatch()
+	updates.PubUpdates.Put("ns1", "key1", []byte("value1"), version.NewHeight(1, 1))
+	updates.PubUpdates.PutValAndMetadata("ns2", "key2", []byte("value2"), []byte("metadata2"), version.NewHeight(1, 2))
+	updates.PubUpdates.Put("ns2", "key3", []byte("value3"), version.NewHeight(2, 1))
+	putPvtUpdates(t, updates, "ns1", "coll1", "key1", []byte("pvt_value1"), version.NewHeight(1, 3))
+	updates.HashUpdates.PutValHashAndMetadata("ns1", "coll2", util.ComputeStringHash("key2"),
+		util.ComputeHash([]byte("pvt_value2")), []byte("pvt_metadata2"), version.NewHeight(2, 2))
+	assert.NoError(t, sourceDB.ApplyPrivacyAwareUpdates(updates, version.NewHeight(2, 2)))
+	_, err = sourceDB.(*CommonStorageDB).ExportPubStateAndPvtStateHashes(snapshotDir)
+	assert.NoError(t, err)
+
+	db := env.GetDBHandle("test-ledger-id").(*CommonStorageDB)
+	assert.NoError(t, db.ImportPubStateAndPvtStateHashes(snapshotDir, version.NewHeight(2, 2)))
+	savepoint, err := db.GetLatestSavePoint()
+	assert.NoError(t, err)
+	assert.Equal(t, version.NewHeight(2, 2), savepoint)
+
+	vv, err := db.GetState("ns1", "key1")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("value1"), vv.Value)
+	assert.Equal(t, version.NewHeight(1, 1), vv.Version)
+	assert.Nil(t, vv.Metadata)
+	vv, err = db.GetState("ns2", "key2")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("value2"), vv.Value)
+	assert.Equal(t, []byte("metadata2"), vv.Metadata)
+	vv, err = db.GetState("ns2", "key3")
+	assert.NoError(t, err)
+	assert.Equal(t, version.NewHeight(2, 1), vv.Version)
+
+	vv, err = db.GetValueHash("ns1", "coll1", util.ComputeStringHash("key1"))
+	assert.NoError(t, err)
+	assert.Equal(t, util.ComputeHash([]byte("pvt_value1")), vv.Value)
+	assert.Equal(t, version.NewHeight(1, 3), vv.Version)
+	// the private state itself is not available after the import
+	vv, err = db.GetPrivateData("ns1", "coll1", "key1")
+	assert.NoError(t, err)
+	assert.Nil(t, vv)
+	metadata, err := db.GetPrivateDataMetadataByHash("ns1", "coll2", util.ComputeStringHash("key2"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("pvt_metadata2"), metadata)
+
+	assert.EqualError(t, db.ImportPubStateAndPvtStateHashes(snapshotDir, version.NewHeight(2, 2)),
+		"state database is not empty, the savepoint is at block [2]")
+}
+
+func readSnapshotEntries(t *testing.T, path string) [][][]byte {
+	f, err := os.Open(path)
+	assert.NoError(t, err)
+	defer f.Close()
+	r := bufio.NewReader(f)
+	format, err := r.ReadByte()
+	assert.NoError(t, err)
+	assert.Equal(t, snapshotFileFormat, format)
+	var entries [][][]byte
+	for {
+		var entry [][]byte
+		for i := 0; i < 5; i++ {
+			length, err := binary.ReadUvarint(r)
+			if err == io.EOF && i == 0 {
+				return entries
+			}
+			assert.NoError(t, err)
+			field := make([]byte, length)
+			_, err = io.ReadFull(r, field)
+			assert.NoError(t, err)
+			entry = append(entry, field)
+		}
+		entries = append(entries, entry)
+	}
+}
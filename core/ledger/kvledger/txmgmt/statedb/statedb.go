@@ -88,6 +88,15 @@ type IndexCapable interface {
 	ProcessIndexesForChaincodeDeploy(namespace string, fileEntries []*ccprovider.TarFileEntry) error
 }
 
+// FullScanner interface provides an additional function for
+// databases capable of iterating over the keys of all the namespaces
+type FullScanner interface {
+	// GetFullScanIterator returns an iterator over the keys of all the namespaces, in the order of the namespaces
+	// and, within a namespace, in the order of the keys. The namespaces for which skipNamespace returns true are
+	// skipped. The returned ResultsIterator contains results of type *VersionedKV
+	GetFullScanIterator(skipNamespace func(namespace string) bool) (ResultsIterator, error)
+}
+
 // CompositeKey encloses Namespace and Key components
 type CompositeKey struct {
 	Namespace string
@@ -212,6 +212,11 @@ func (vdb *versionedDB) GetLatestSavePoint() (*version.Height, error) {
 	return version, nil
 }
 
+// GetFullScanIterator implements method in FullScanner interface. The savepoint is not included in the results
+func (vdb *versionedDB) GetFullScanIterator(skipNamespace func(namespace string) bool) (statedb.ResultsIterator, error) {
+	return &fullScanner{vdb.db.GetIterator(nil, nil), skipNamespace}, nil
+}
+
 func constructCompositeKey(ns string, key string) []byte {
 	return append(append([]byte(ns), compositeKeySep...), []byte(key)...)
 }
@@ -275,3 +280,36 @@ func (scanner *kvScanner) GetBookmarkAndClose() string {
 	scanner.Close()
 	return retval
 }
+
+type fullScanner struct {
+	dbItr         iterator.Iterator
+	skipNamespace func(namespace string) bool
+}
+
+func (scanner *fullScanner) Next() (statedb.QueryResult, error) {
+	for scanner.dbItr.Next() {
+		dbKey := scanner.dbItr.Key()
+		if bytes.Equal(dbKey, savePointKey) {
+			continue
+		}
+		namespace, key := splitCompositeKey(dbKey)
+		if scanner.skipNamespace != nil && scanner.skipNamespace(namespace) {
+			continue
+		}
+		dbVal := scanner.dbItr.Value()
+		dbValCopy := make([]byte, len(dbVal))
+		copy(dbValCopy, dbVal)
+		vv, err := decodeValue(dbValCopy)
+		if err != nil {
+			return nil, err
+		}
+		return &statedb.VersionedKV{
+			CompositeKey:   statedb.CompositeKey{Namespace: namespace, Key: key},
+			VersionedValue: *vv}, nil
+	}
+	return nil, errors.Wrap(scanner.dbItr.Error(), "error while iterating the state db")
+}
+
+func (scanner *fullScanner) Close() {
+	scanner.dbItr.Release()
+}
@@ -136,3 +136,48 @@ func TestApplyUpdatesWithNilHeight(t *testing.T) {
 	defer env.Cleanup()
 	commontests.TestApplyUpdatesWithNilHeight(t, env.DBProvider)
 }
+
+func TestFullScanIterator(t *testing.T) {
+	env := NewTestVDBEnv(t)
+	defer env.Cleanup()
+	db, err := env.DBProvider.GetDBHandle("testfullscaniterator")
+	assert.NoError(t, err)
+	// the keys of another db are not included
+	otherDB, err := env.DBProvider.GetDBHandle("testfullscaniterator1")
+	assert.NoError(t, err)
+
+	batch := statedb.NewUpdateBatch()
+	batch.Put("ns2", "key1", []byte("value1"), version.NewHeight(1, 1))
+	batch.Put("ns1", "key2", []byte("value2"), version.NewHeight(1, 2))
+	batch.PutValAndMetadata("ns1", "key1", []byte("value1"), []byte("metadata1"), version.NewHeight(1, 3))
+	batch.Put("ns3", "key1", []byte("value1"), version.NewHeight(1, 4))
+	assert.NoError(t, db.ApplyUpdates(batch, version.NewHeight(1, 4)))
+	assert.NoError(t, otherDB.ApplyUpdates(batch, version.NewHeight(1, 4)))
+
+	itr, err := db.(statedb.FullScanner).GetFullScanIterator(func(namespace string) bool { return namespace == "ns3" })
+	assert.NoError(t, err)
+	defer itr.Close()
+	var results []*statedb.VersionedKV
+	for {
+		result, err := itr.Next()
+		assert.NoError(t, err)
+		if result == nil {
+			break
+		}
+		results = append(results, result.(*statedb.VersionedKV))
+	}
+	assert.Equal(t, []*statedb.VersionedKV{
+		{
+			CompositeKey:   statedb.CompositeKey{Namespace: "ns1", Key: "key1"},
+			VersionedValue: statedb.VersionedValue{Value: []byte("value1"), Metadata: []byte("metadata1"), Version: version.NewHeight(1, 3)},
+		},
+		{
+			CompositeKey:   statedb.CompositeKey{Namespace: "ns1", Key: "key2"},
+			VersionedValue: statedb.VersionedValue{Value: []byte("value2"), Version: version.NewHeight(1, 2)},
+		},
+		{
+			CompositeKey:   statedb.CompositeKey{Namespace: "ns2", Key: "key1"},
+			VersionedValue: statedb.VersionedValue{Value: []byte("value1"), Version: version.NewHeight(1, 1)},
+		},
+	}, results)
+}
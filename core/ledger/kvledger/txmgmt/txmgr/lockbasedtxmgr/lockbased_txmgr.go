@@ -25,6 +25,7 @@ import (
 	"github.com/hyperledger/fabric/protos/common"
 	"github.com/hyperledger/fabric/protos/ledger/rwset"
 	"github.com/hyperledger/fabric/protos/ledger/rwset/kvrwset"
+	"github.com/hyperledger/fabric/protos/utils"
 )
 
 var logger = flogging.MustGetLogger("lockbasedtxmgr")
@@ -421,6 +422,7 @@ func (txmgr *LockBasedTxMgr) invokeNamespaceListeners() error {
 			CommittingBlockNum:          txmgr.current.blockNum(),
 			CommittedStateQueryExecutor: committedStateQueryExecuter,
 			PostCommitQueryExecutor:     postCommitQueryExecuter,
+			Submitters:                  extractSubmitters(txmgr.current.block, txmgr.current.batch, stateUpdatesForListener),
 		}
 		if err := listener.HandleStateUpdates(trigger); err != nil {
 			return err
@@ -555,6 +557,62 @@ func extractStateUpdates(batch *privacyenabledstate.UpdateBatch, namespaces []st
 	return stateupdates
 }
 
+// extractSubmitters returns the submitters of the transactions in the block that made the final writes to the keys present in
+// the state updates. The transaction that made the final write to a key is identified by the version of the key in the batch.
+// A key is skipped if the submitter of its transaction cannot be determined
+func extractSubmitters(block *common.Block, batch *privacyenabledstate.UpdateBatch, stateUpdates ledger.StateUpdates) map[string]map[string]*ledger.TxSubmitter {
+	submitters := map[string]map[string]*ledger.TxSubmitter{}
+	submittersByTxNum := map[uint64]*ledger.TxSubmitter{}
+	for ns, updates := range stateUpdates {
+		for _, kvWrite := range updates.([]*kvrwset.KVWrite) {
+			versionedValue := batch.PubUpdates.Get(ns, kvWrite.Key)
+			if versionedValue == nil || versionedValue.Version == nil {
+				continue
+			}
+			txNum := versionedValue.Version.TxNum
+			submitter, ok := submittersByTxNum[txNum]
+			if !ok {
+				var err error
+				if submitter, err = txSubmitter(block, txNum); err != nil {
+					logger.Warningf("Error while extracting the submitter of transaction [%d] in block [%d]: %s", txNum, block.Header.Number, err)
+				}
+				submittersByTxNum[txNum] = submitter
+			}
+			if submitter == nil {
+				continue
+			}
+			if submitters[ns] == nil {
+				submitters[ns] = map[string]*ledger.TxSubmitter{}
+			}
+			submitters[ns][kvWrite.Key] = submitter
+		}
+	}
+	return submitters
+}
+
+func txSubmitter(block *common.Block, txNum uint64) (*ledger.TxSubmitter, error) {
+	if txNum >= uint64(len(block.Data.Data)) {
+		logger.Debugf("Transaction [%d] is not present in block [%d]", txNum, block.Header.Number)
+		return nil, nil
+	}
+	env, err := utils.GetEnvelopeFromBlock(block.Data.Data[txNum])
+	if err != nil {
+		return nil, err
+	}
+	payload, err := utils.GetPayload(env)
+	if err != nil {
+		return nil, err
+	}
+	if payload.Header == nil {
+		return nil, nil
+	}
+	sigHdr, err := utils.GetSignatureHeader(payload.Header.SignatureHeader)
+	if err != nil {
+		return nil, err
+	}
+	return ledger.NewTxSubmitter(sigHdr.Creator)
+}
+
 func (txmgr *LockBasedTxMgr) updateStateListeners() {
 	for _, l := range txmgr.current.listeners {
 		l.StateCommitDone(txmgr.ledgerid)
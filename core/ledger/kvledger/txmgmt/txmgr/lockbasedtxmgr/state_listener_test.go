@@ -9,6 +9,7 @@ import (
 	"testing"
 
 	"github.com/hyperledger/fabric/common/ledger/testutil"
+	"github.com/hyperledger/fabric/common/util"
 	"github.com/hyperledger/fabric/core/ledger"
 	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/privacyenabledstate"
 	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/version"
@@ -16,6 +17,8 @@ import (
 	"github.com/hyperledger/fabric/protos/common"
 	"github.com/hyperledger/fabric/protos/ledger/queryresult"
 	"github.com/hyperledger/fabric/protos/ledger/rwset/kvrwset"
+	"github.com/hyperledger/fabric/protos/msp"
+	"github.com/hyperledger/fabric/protos/utils"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -145,6 +148,50 @@ func TestStateListenerQueryExecutor(t *testing.T) {
 	checkQueryExecutor(t, trigger.PostCommitQueryExecutor, namespace, expectedPostCommitData)
 }
 
+func TestStateListenerSubmitters(t *testing.T) {
+	testLedgerid := "testLedger"
+	ml := new(mock.StateListener)
+	ml.InterestedInNamespacesStub = func() []string { return []string{"ns1"} }
+	testEnv := testEnvsMap[levelDBtestEnvName]
+	testEnv.init(t, testLedgerid, nil)
+	defer testEnv.cleanup()
+	txmgr := testEnv.getTxMgr().(*LockBasedTxMgr)
+	txmgr.stateListeners = []ledger.StateListener{ml}
+
+	envWithCreator := func(creator []byte) []byte {
+		return utils.MarshalOrPanic(&common.Envelope{Payload: utils.MarshalOrPanic(&common.Payload{
+			Header: &common.Header{SignatureHeader: utils.MarshalOrPanic(&common.SignatureHeader{Creator: creator})}})})
+	}
+	block := common.NewBlock(1, []byte("dummyHash"))
+	block.Data.Data = [][]byte{
+		envWithCreator(utils.MarshalOrPanic(&msp.SerializedIdentity{Mspid: "Org1MSP", IdBytes: []byte("cert1")})),
+		envWithCreator(utils.MarshalOrPanic(&msp.SerializedIdentity{Mspid: "Org2MSP", IdBytes: []byte("cert2")})),
+		envWithCreator(nil),
+		[]byte("garbage"),
+	}
+	// the key1 is written by both the first and the second transaction and the second write prevails
+	sampleBatch := privacyenabledstate.NewUpdateBatch()
+	sampleBatch.PubUpdates.Put("ns1", "key1", []byte("value1"), version.NewHeight(1, 1))
+	sampleBatch.PubUpdates.Put("ns1", "key2", []byte("value2"), version.NewHeight(1, 0))
+	sampleBatch.PubUpdates.Put("ns1", "key3", []byte("value3"), version.NewHeight(1, 2))
+	sampleBatch.PubUpdates.Put("ns1", "key4", []byte("value4"), version.NewHeight(1, 3))
+	sampleBatch.PubUpdates.Put("ns1", "key5", []byte("value5"), version.NewHeight(1, 4))
+	txmgr.current = &current{block: block, batch: sampleBatch}
+	assert.NoError(t, txmgr.invokeNamespaceListeners())
+
+	assert.Equal(t, 1, ml.HandleStateUpdatesCallCount())
+	trigger := ml.HandleStateUpdatesArgsForCall(0)
+	assert.Equal(t,
+		map[string]map[string]*ledger.TxSubmitter{
+			"ns1": {
+				"key1": {MSPID: "Org2MSP", CertHash: util.ComputeSHA256([]byte("cert2"))},
+				"key2": {MSPID: "Org1MSP", CertHash: util.ComputeSHA256([]byte("cert1"))},
+			},
+		},
+		trigger.Submitters,
+	)
+}
+
 func checkHandleStateUpdatesCallback(t *testing.T, ml *mock.StateListener, callNumber int,
 	expectedLedgerid string,
 	expectedUpdates ledger.StateUpdates,
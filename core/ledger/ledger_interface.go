@@ -13,15 +13,18 @@ import (
 	"github.com/hyperledger/fabric-lib-go/healthz"
 	commonledger "github.com/hyperledger/fabric/common/ledger"
 	"github.com/hyperledger/fabric/common/metrics"
+	"github.com/hyperledger/fabric/common/util"
 	"github.com/hyperledger/fabric/protos/common"
 	"github.com/hyperledger/fabric/protos/ledger/rwset"
 	"github.com/hyperledger/fabric/protos/ledger/rwset/kvrwset"
+	"github.com/hyperledger/fabric/protos/msp"
 	"github.com/hyperledger/fabric/protos/peer"
 )
 
 // Initializer encapsulates dependencies for PeerLedgerProvider
 type Initializer struct {
 	StateListeners                []StateListener
+	CollectionConfigListeners     []CollectionConfigListener
 	DeployedChaincodeInfoProvider DeployedChaincodeInfoProvider
 	MembershipInfoProvider        MembershipInfoProvider
 	MetricsProvider               metrics.Provider
@@ -98,6 +101,26 @@ type PeerLedger interface {
 	GetMissingPvtDataTracker() (MissingPvtDataTracker, error)
 }
 
+// SnapshotRequester is an optional interface that a `PeerLedger` implements for generating the snapshots of the ledger. A snapshot
+// consists of the public state, the hashes of the private state, the ids of the transactions, and the config history of the ledger
+// at a block, along with a metadata file that records the hashes of these files
+type SnapshotRequester interface {
+	// SubmitSnapshotRequest requests a snapshot of the ledger to be generated once the given block is committed. A block number of
+	// zero requests a snapshot at the last committed block, which is started before returning. The files that do not change with
+	// the later blocks are written in the background, and the snapshot is complete once moved to the dir of the completed snapshots
+	SubmitSnapshotRequest(blockNum uint64) error
+	// PendingSnapshotRequests returns, in the ascending order, the block numbers of the requests that are yet to be processed
+	PendingSnapshotRequests() ([]uint64, error)
+}
+
+// SnapshotLedgerCreator is an optional interface that a `PeerLedgerProvider` implements for creating a ledger from a snapshot
+// generated via the interface `SnapshotRequester`, instead of from the genesis block
+type SnapshotLedgerCreator interface {
+	// CreateFromSnapshot creates a new ledger from the snapshot in the given dir and returns the ledger along with its id.
+	// The first block to be committed to the ledger is the one after the last block of the snapshot
+	CreateFromSnapshot(snapshotDir string) (PeerLedger, string, error)
+}
+
 // ValidatedLedger represents the 'final ledger' after filtering out invalid transactions from PeerLedger.
 // Post-v1
 type ValidatedLedger interface {
@@ -360,15 +383,66 @@ type StateUpdateTrigger struct {
 	CommittingBlockNum          uint64
 	CommittedStateQueryExecutor SimpleQueryExecutor
 	PostCommitQueryExecutor     SimpleQueryExecutor
+	// Submitters contains, for the keys in the StateUpdates, the submitter of the transaction that made the final write
+	// to the key in the block, keyed by namespace and then by key. A key may be absent if its submitter is not known
+	Submitters map[string]map[string]*TxSubmitter
+}
+
+// TxSubmitter identifies the submitter of a transaction
+type TxSubmitter struct {
+	MSPID string
+	// CertHash is the SHA-256 hash of the serialized certificate of the submitter
+	CertHash []byte
+}
+
+// NewTxSubmitter returns the submitter that is identified by the given creator, as present in the signature header of
+// a transaction. A nil submitter is returned if the creator is empty
+func NewTxSubmitter(creator []byte) (*TxSubmitter, error) {
+	if len(creator) == 0 {
+		return nil, nil
+	}
+	sID := &msp.SerializedIdentity{}
+	if err := proto.Unmarshal(creator, sID); err != nil {
+		return nil, err
+	}
+	return &TxSubmitter{MSPID: sID.Mspid, CertHash: util.ComputeSHA256(sID.IdBytes)}, nil
 }
 
 // StateUpdates is the generic type to represent the state updates
 type StateUpdates map[string]interface{}
 
-// ConfigHistoryRetriever allow retrieving history of collection configs
+// ConfigHistoryRetriever allow retrieving history of collection configs and endorsement policies
 type ConfigHistoryRetriever interface {
 	CollectionConfigAt(blockNum uint64, chaincodeName string) (*CollectionConfigInfo, error)
 	MostRecentCollectionConfigBelow(blockNum uint64, chaincodeName string) (*CollectionConfigInfo, error)
+	// EndorsementPolicyAt returns the endorsement policy of the chaincode committed at exactly the given block, if any
+	EndorsementPolicyAt(blockNum uint64, chaincodeName string) (*EndorsementPolicyInfo, error)
+	// MostRecentEndorsementPolicyBelow returns the most recent endorsement policy of the chaincode committed below the given block
+	MostRecentEndorsementPolicyBelow(blockNum uint64, chaincodeName string) (*EndorsementPolicyInfo, error)
+	// GetCollectionConfigHistory returns an iterator over all the persisted versions of the collection config of the chaincode.
+	// Each of the results is a `*CollectionConfigInfo`
+	GetCollectionConfigHistory(chaincodeName string) (commonledger.ResultsIterator, error)
+}
+
+// ConfigHistoryWatcher is optionally implemented by a ConfigHistoryRetriever and allows following the changes in the
+// collection configs of the ledger as the blocks are committed
+type ConfigHistoryWatcher interface {
+	// WatchChaincode returns a channel that receives the collection config of the given chaincode each time a new one is
+	// committed, along with a function that cancels the subscription. A change may be dropped for a slow watcher, which
+	// should re-query the retriever on receiving a change
+	WatchChaincode(chaincodeName string) (<-chan *CollectionConfigInfo, func(), error)
+	// ConfigChangesSince sends the collection configs of all the chaincodes committed above the given block, in the
+	// increasing order of blocks, and, if `follow` is true, keeps sending the changes as the blocks are committed. The
+	// returned function stops the feed
+	ConfigChangesSince(sinceBlock uint64, follow bool) (<-chan *ConfigChangeRecord, func(), error)
+}
+
+// CollectionConfigListener is notified of each collection config committed to any of the ledgers. The listeners are
+// registered via `Initializer.CollectionConfigListeners`
+type CollectionConfigListener interface {
+	// HandleCollectionConfigChange is invoked synchronously, during the commit of the block, and hence, should return
+	// quickly. It must not write to the ledger
+	HandleCollectionConfigChange(ledgerID, chaincodeName string, blockNum uint64, collConfig *common.CollectionConfigPackage)
 }
 
 // MissingPvtDataTracker allows getting information about the private data that is not missing on the peer
@@ -391,6 +465,27 @@ type MissingCollectionPvtDataInfo struct {
 type CollectionConfigInfo struct {
 	CollectionConfig   *common.CollectionConfigPackage
 	CommittingBlockNum uint64
+	// ImplicitCollectionsIncomplete is set if the implicit collections of the chaincode could not be retrieved
+	// and hence, the collection config may lack some or all of these
+	ImplicitCollectionsIncomplete bool
+	// Annotation is the note attached by an operator to the collection config, if any
+	Annotation string
+}
+
+// ConfigChangeRecord is a collection config of a chaincode, as sent by the function `ConfigHistoryWatcher.ConfigChangesSince`
+type ConfigChangeRecord struct {
+	ChaincodeName string
+	Info          *CollectionConfigInfo
+}
+
+// EndorsementPolicyInfo encapsulates the endorsement policy of a chaincode and its committing block number
+type EndorsementPolicyInfo struct {
+	// EndorsementPolicy is the serialized endorsement policy of the chaincode
+	EndorsementPolicy []byte
+	// ValidationPlugin is the name of the plugin that validates the transactions of the chaincode, which is the default
+	// for the keys of the chaincode that do not have the key-level validation parameters
+	ValidationPlugin   string
+	CommittingBlockNum uint64
 }
 
 // Add adds a missing data entry to the MissingPvtDataInfo Map
@@ -423,6 +518,18 @@ func (e *ErrCollectionConfigNotYetAvailable) Error() string {
 	return e.Msg
 }
 
+// ErrChaincodeNotDeployed is an error which is returned from the functions of the config history
+// retriever that are enabled to check the existence of the requested chaincode, if the chaincode
+// has never been deployed on the channel.
+type ErrChaincodeNotDeployed struct {
+	ChaincodeName string
+	BlockNum      uint64
+}
+
+func (e *ErrChaincodeNotDeployed) Error() string {
+	return fmt.Sprintf("chaincode [%s] is not deployed at block [%d]", e.ChaincodeName, e.BlockNum)
+}
+
 // NotFoundInIndexErr is used to indicate missing entry in the index
 type NotFoundInIndexErr string
 
@@ -430,6 +537,14 @@ func (NotFoundInIndexErr) Error() string {
 	return "Entry not found in index"
 }
 
+// TxBeforeSnapshotErr is used to indicate that a transaction is committed in a block that precedes the snapshot from which
+// the ledger was created, and hence only the id of the transaction is known to the ledger
+type TxBeforeSnapshotErr string
+
+func (e TxBeforeSnapshotErr) Error() string {
+	return fmt.Sprintf("transaction [%s] is committed before the snapshot from which the ledger was created", string(e))
+}
+
 // CollConfigNotDefinedError is returned whenever an operation
 // is requested on a collection whose config has not been defined
 type CollConfigNotDefinedError struct {
@@ -474,6 +589,11 @@ type DeployedChaincodeInfo struct {
 	Hash                []byte
 	Version             string
 	CollectionConfigPkg *common.CollectionConfigPackage
+	// EndorsementPolicy is the serialized endorsement policy of the chaincode
+	EndorsementPolicy []byte
+	// ValidationPlugin is the name of the plugin that validates the transactions of the chaincode against the endorsement
+	// policy and, for the keys that have these, the key-level validation parameters
+	ValidationPlugin string
 }
 
 // ChaincodeLifecycleInfo captures the update info of a chaincode
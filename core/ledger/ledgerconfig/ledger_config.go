@@ -8,6 +8,7 @@ package ledgerconfig
 
 import (
 	"path/filepath"
+	"time"
 
 	"github.com/hyperledger/fabric/core/config"
 	"github.com/spf13/viper"
@@ -29,6 +30,7 @@ const confStateleveldb = "stateLeveldb"
 const confHistoryLeveldb = "historyLeveldb"
 const confBookkeeper = "bookkeeper"
 const confConfigHistory = "configHistory"
+const confSnapshots = "snapshots"
 const confChains = "chains"
 const confPvtdataStore = "pvtdataStore"
 const confTotalQueryLimit = "ledger.state.totalQueryLimit"
@@ -37,6 +39,18 @@ const confEnableHistoryDatabase = "ledger.history.enableHistoryDatabase"
 const confMaxBatchSize = "ledger.state.couchDBConfig.maxBatchUpdateSize"
 const confAutoWarmIndexes = "ledger.state.couchDBConfig.autoWarmIndexes"
 const confWarmIndexesAfterNBlocks = "ledger.state.couchDBConfig.warmIndexesAfterNBlocks"
+const confConfigHistorySizeMetricsInterval = "ledger.configHistory.sizeMetricsInterval"
+const confConfigHistorySyncWrites = "ledger.configHistory.syncWrites"
+const confConfigHistoryCompactOnCloseTimeout = "ledger.configHistory.compactOnCloseTimeout"
+const confConfigHistoryMaxCollectionConfigSize = "ledger.configHistory.maxCollectionConfigSize"
+const confConfigHistoryNamespaces = "ledger.configHistory.namespaces"
+const confConfigHistoryCollectionConfigRecords = "ledger.configHistory.collectionConfigRecords"
+const confConfigHistoryLogSampling = "ledger.configHistory.logSampling"
+const confConfigHistoryRetentionBlocks = "ledger.configHistory.retentionBlocks"
+const confConfigHistoryStorage = "ledger.configHistory.storage"
+const confBlockArchiveDir = "ledger.blockchain.archive.dir"
+const confBlockArchiveRetainedBlocks = "ledger.blockchain.archive.retainedBlocks"
+const confBlockArchiveS3 = "ledger.blockchain.archive.s3"
 
 var confCollElgProcMaxDbBatchSize = &conf{"ledger.pvtdataStore.collElgProcMaxDbBatchSize", 5000}
 var confCollElgProcDbBatchesInterval = &conf{"ledger.pvtdataStore.collElgProcDbBatchesInterval", 1000}
@@ -78,6 +92,11 @@ func GetInternalBookkeeperPath() string {
 	return filepath.Join(GetRootPath(), confBookkeeper)
 }
 
+// GetSnapshotsPath returns the filesystem path under which the snapshots of the ledgers are generated
+func GetSnapshotsPath() string {
+	return filepath.Join(GetRootPath(), confSnapshots)
+}
+
 // GetConfigHistoryPath returns the filesystem path that is used for maintaining history of chaincodes collection configurations
 func GetConfigHistoryPath() string {
 	return filepath.Join(GetRootPath(), confConfigHistory)
@@ -186,6 +205,120 @@ func GetWarmIndexesAfterNBlocks() int {
 	return warmAfterNBlocks
 }
 
+// GetConfigHistorySizeMetricsInterval returns the interval at which the size of the config history db is reported
+// via metrics. If unset, defaults to 5 minutes. A non-positive value disables the reporting
+func GetConfigHistorySizeMetricsInterval() time.Duration {
+	if !viper.IsSet(confConfigHistorySizeMetricsInterval) {
+		return 5 * time.Minute
+	}
+	return viper.GetDuration(confConfigHistorySizeMetricsInterval)
+}
+
+// IsConfigHistorySyncWritesEnabled returns whether the writes to the config history db are synced to the disk.
+// If unset, defaults to true
+func IsConfigHistorySyncWritesEnabled() bool {
+	if !viper.IsSet(confConfigHistorySyncWrites) {
+		return true
+	}
+	return viper.GetBool(confConfigHistorySyncWrites)
+}
+
+// GetConfigHistoryCompactOnCloseTimeout returns the maximum time for which the closing of the config history db waits
+// for its compaction. If unset, defaults to zero. A non-positive value disables the compaction on close
+func GetConfigHistoryCompactOnCloseTimeout() time.Duration {
+	return viper.GetDuration(confConfigHistoryCompactOnCloseTimeout)
+}
+
+// GetConfigHistoryMaxCollectionConfigSize returns the maximum size, in bytes, of a collection config that is recorded
+// in the config history. If unset, defaults to 16 MB. A non-positive value means no limit
+func GetConfigHistoryMaxCollectionConfigSize() int {
+	if !viper.IsSet(confConfigHistoryMaxCollectionConfigSize) {
+		return 16 * 1024 * 1024
+	}
+	return viper.GetInt(confConfigHistoryMaxCollectionConfigSize)
+}
+
+// GetConfigHistoryNamespaces returns the lifecycle namespaces whose chaincodes are tracked in the config history.
+// If unset or empty, the chaincodes of all the namespaces are tracked
+func GetConfigHistoryNamespaces() []string {
+	return viper.GetStringSlice(confConfigHistoryNamespaces)
+}
+
+// IsConfigHistoryCollectionConfigRecordsEnabled returns whether the collection configs are persisted in the config history
+// as the extensible records. If unset, defaults to false
+func IsConfigHistoryCollectionConfigRecordsEnabled() bool {
+	return viper.GetBool(confConfigHistoryCollectionConfigRecords)
+}
+
+// GetConfigHistoryLogSampling returns the sampling rate of the high-frequency log messages of the config history, i.e., only
+// one in these many occurrences of such a message is logged. If unset, defaults to 1. A non-positive value is treated as 1
+func GetConfigHistoryLogSampling() int {
+	logSampling := viper.GetInt(confConfigHistoryLogSampling)
+	if logSampling < 1 {
+		return 1
+	}
+	return logSampling
+}
+
+// GetConfigHistoryRetentionBlocks returns the number of the most recent blocks for which the config history is retained, the
+// config history of the older blocks being purged as the new blocks update it. If unset, defaults to 0, which disables the purging
+func GetConfigHistoryRetentionBlocks() uint64 {
+	retentionBlocks := viper.GetInt(confConfigHistoryRetentionBlocks)
+	if retentionBlocks < 0 {
+		return 0
+	}
+	return uint64(retentionBlocks)
+}
+
+// IsConfigHistoryCouchDBEnabled returns whether the config history is stored in the CouchDB instance configured for the state db,
+// instead of the local leveldb. If unset, defaults to false
+func IsConfigHistoryCouchDBEnabled() bool {
+	return viper.GetString(confConfigHistoryStorage) == "CouchDB"
+}
+
+// GetBlockArchiveDir returns the directory to which the older block files are archived, which is expected to be an object
+// store (e.g., S3, GCS, or MinIO) mounted as a file system. If unset, defaults to an empty string, which disables the archiving
+// unless `GetBlockArchiveS3Conf` is set
+func GetBlockArchiveDir() string {
+	return viper.GetString(confBlockArchiveDir)
+}
+
+// BlockArchiveS3Conf encapsulates the configurations for archiving the older block files to an object store that supports
+// the S3 API, such as AWS S3, MinIO, or GCS
+type BlockArchiveS3Conf struct {
+	Endpoint        string
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
+// GetBlockArchiveS3Conf returns the configurations of the S3 compatible object store to which the older block files are
+// archived. This takes precedence over `GetBlockArchiveDir`. If the endpoint is unset, returns nil
+func GetBlockArchiveS3Conf() *BlockArchiveS3Conf {
+	endpoint := viper.GetString(confBlockArchiveS3 + ".endpoint")
+	if endpoint == "" {
+		return nil
+	}
+	return &BlockArchiveS3Conf{
+		Endpoint:        endpoint,
+		Region:          viper.GetString(confBlockArchiveS3 + ".region"),
+		Bucket:          viper.GetString(confBlockArchiveS3 + ".bucket"),
+		AccessKeyID:     viper.GetString(confBlockArchiveS3 + ".accessKeyID"),
+		SecretAccessKey: viper.GetString(confBlockArchiveS3 + ".secretAccessKey"),
+	}
+}
+
+// GetBlockArchiveRetainedBlocks returns the number of the most recent blocks whose block files are not archived. If unset,
+// defaults to 0, which archives all the block files but the one that holds the last block
+func GetBlockArchiveRetainedBlocks() uint64 {
+	retainedBlocks := viper.GetInt(confBlockArchiveRetainedBlocks)
+	if retainedBlocks < 0 {
+		return 0
+	}
+	return uint64(retainedBlocks)
+}
+
 type conf struct {
 	Name       string
 	DefaultVal int
@@ -18,6 +18,7 @@ package ledgerconfig
 
 import (
 	"testing"
+	"time"
 
 	ledgertestutil "github.com/hyperledger/fabric/core/ledger/testutil"
 	"github.com/spf13/viper"
@@ -55,6 +56,7 @@ func TestLedgerConfigPathDefault(t *testing.T) {
 	assert.Equal(t, "/var/hyperledger/production/ledgersData/chains", GetBlockStorePath())
 	assert.Equal(t, "/var/hyperledger/production/ledgersData/pvtdataStore", GetPvtdataStorePath())
 	assert.Equal(t, "/var/hyperledger/production/ledgersData/bookkeeper", GetInternalBookkeeperPath())
+	assert.Equal(t, "/var/hyperledger/production/ledgersData/snapshots", GetSnapshotsPath())
 }
 
 func TestLedgerConfigPath(t *testing.T) {
@@ -68,6 +70,7 @@ func TestLedgerConfigPath(t *testing.T) {
 	assert.Equal(t, "/tmp/hyperledger/production/ledgersData/chains", GetBlockStorePath())
 	assert.Equal(t, "/tmp/hyperledger/production/ledgersData/pvtdataStore", GetPvtdataStorePath())
 	assert.Equal(t, "/tmp/hyperledger/production/ledgersData/bookkeeper", GetInternalBookkeeperPath())
+	assert.Equal(t, "/tmp/hyperledger/production/ledgersData/snapshots", GetSnapshotsPath())
 }
 
 func TestGetTotalLimitDefault(t *testing.T) {
@@ -244,6 +247,144 @@ func TestGetWarmIndexesAfterNBlocks(t *testing.T) {
 	assert.Equal(t, 10, updatedValue)
 }
 
+func TestGetConfigHistorySizeMetricsInterval(t *testing.T) {
+	viper.Reset()
+	assert.Equal(t, 5*time.Minute, GetConfigHistorySizeMetricsInterval())
+
+	setUpCoreYAMLConfig()
+	defer ledgertestutil.ResetConfigToDefaultValues()
+	assert.Equal(t, 5*time.Minute, GetConfigHistorySizeMetricsInterval())
+	viper.Set("ledger.configHistory.sizeMetricsInterval", "30s")
+	assert.Equal(t, 30*time.Second, GetConfigHistorySizeMetricsInterval())
+	viper.Set("ledger.configHistory.sizeMetricsInterval", 0)
+	assert.Equal(t, time.Duration(0), GetConfigHistorySizeMetricsInterval())
+}
+
+func TestIsConfigHistorySyncWritesEnabled(t *testing.T) {
+	viper.Reset()
+	assert.True(t, IsConfigHistorySyncWritesEnabled())
+
+	setUpCoreYAMLConfig()
+	defer ledgertestutil.ResetConfigToDefaultValues()
+	assert.True(t, IsConfigHistorySyncWritesEnabled())
+	defer viper.Set("ledger.configHistory.syncWrites", true)
+	viper.Set("ledger.configHistory.syncWrites", false)
+	assert.False(t, IsConfigHistorySyncWritesEnabled())
+}
+
+func TestGetConfigHistoryCompactOnCloseTimeout(t *testing.T) {
+	viper.Reset()
+	assert.Equal(t, time.Duration(0), GetConfigHistoryCompactOnCloseTimeout())
+
+	setUpCoreYAMLConfig()
+	defer ledgertestutil.ResetConfigToDefaultValues()
+	assert.Equal(t, time.Duration(0), GetConfigHistoryCompactOnCloseTimeout())
+	defer viper.Set("ledger.configHistory.compactOnCloseTimeout", 0)
+	viper.Set("ledger.configHistory.compactOnCloseTimeout", "30s")
+	assert.Equal(t, 30*time.Second, GetConfigHistoryCompactOnCloseTimeout())
+}
+
+func TestGetConfigHistoryMaxCollectionConfigSize(t *testing.T) {
+	viper.Reset()
+	assert.Equal(t, 16*1024*1024, GetConfigHistoryMaxCollectionConfigSize())
+
+	setUpCoreYAMLConfig()
+	defer ledgertestutil.ResetConfigToDefaultValues()
+	assert.Equal(t, 16*1024*1024, GetConfigHistoryMaxCollectionConfigSize())
+	defer viper.Set("ledger.configHistory.maxCollectionConfigSize", 16777216)
+	viper.Set("ledger.configHistory.maxCollectionConfigSize", 1024)
+	assert.Equal(t, 1024, GetConfigHistoryMaxCollectionConfigSize())
+}
+
+func TestGetConfigHistoryNamespaces(t *testing.T) {
+	viper.Reset()
+	assert.Empty(t, GetConfigHistoryNamespaces())
+
+	setUpCoreYAMLConfig()
+	defer ledgertestutil.ResetConfigToDefaultValues()
+	assert.Empty(t, GetConfigHistoryNamespaces())
+	defer viper.Set("ledger.configHistory.namespaces", []string{})
+	viper.Set("ledger.configHistory.namespaces", []string{"lscc", "_lifecycle"})
+	assert.Equal(t, []string{"lscc", "_lifecycle"}, GetConfigHistoryNamespaces())
+}
+
+func TestIsConfigHistoryCollectionConfigRecordsEnabled(t *testing.T) {
+	viper.Reset()
+	assert.False(t, IsConfigHistoryCollectionConfigRecordsEnabled())
+
+	setUpCoreYAMLConfig()
+	defer ledgertestutil.ResetConfigToDefaultValues()
+	assert.False(t, IsConfigHistoryCollectionConfigRecordsEnabled())
+	defer viper.Set("ledger.configHistory.collectionConfigRecords", false)
+	viper.Set("ledger.configHistory.collectionConfigRecords", true)
+	assert.True(t, IsConfigHistoryCollectionConfigRecordsEnabled())
+}
+
+func TestGetConfigHistoryLogSampling(t *testing.T) {
+	viper.Reset()
+	assert.Equal(t, 1, GetConfigHistoryLogSampling())
+
+	setUpCoreYAMLConfig()
+	defer ledgertestutil.ResetConfigToDefaultValues()
+	assert.Equal(t, 1, GetConfigHistoryLogSampling())
+	defer viper.Set("ledger.configHistory.logSampling", 1)
+	viper.Set("ledger.configHistory.logSampling", 100)
+	assert.Equal(t, 100, GetConfigHistoryLogSampling())
+	viper.Set("ledger.configHistory.logSampling", -1)
+	assert.Equal(t, 1, GetConfigHistoryLogSampling())
+}
+
+func TestGetConfigHistoryRetentionBlocks(t *testing.T) {
+	viper.Reset()
+	assert.Equal(t, uint64(0), GetConfigHistoryRetentionBlocks())
+
+	setUpCoreYAMLConfig()
+	defer ledgertestutil.ResetConfigToDefaultValues()
+	assert.Equal(t, uint64(0), GetConfigHistoryRetentionBlocks())
+	defer viper.Set("ledger.configHistory.retentionBlocks", 0)
+	viper.Set("ledger.configHistory.retentionBlocks", 10000)
+	assert.Equal(t, uint64(10000), GetConfigHistoryRetentionBlocks())
+	viper.Set("ledger.configHistory.retentionBlocks", -1)
+	assert.Equal(t, uint64(0), GetConfigHistoryRetentionBlocks())
+}
+
+func TestIsConfigHistoryCouchDBEnabled(t *testing.T) {
+	viper.Reset()
+	assert.False(t, IsConfigHistoryCouchDBEnabled())
+
+	setUpCoreYAMLConfig()
+	defer ledgertestutil.ResetConfigToDefaultValues()
+	assert.False(t, IsConfigHistoryCouchDBEnabled())
+	defer viper.Set("ledger.configHistory.storage", "goleveldb")
+	viper.Set("ledger.configHistory.storage", "CouchDB")
+	assert.True(t, IsConfigHistoryCouchDBEnabled())
+}
+
+func TestGetBlockArchiveConfig(t *testing.T) {
+	viper.Reset()
+	assert.Equal(t, "", GetBlockArchiveDir())
+	assert.Equal(t, uint64(0), GetBlockArchiveRetainedBlocks())
+
+	setUpCoreYAMLConfig()
+	defer ledgertestutil.ResetConfigToDefaultValues()
+	assert.Equal(t, "", GetBlockArchiveDir())
+	assert.Equal(t, uint64(100000), GetBlockArchiveRetainedBlocks())
+	defer viper.Set("ledger.blockchain.archive.dir", "")
+	defer viper.Set("ledger.blockchain.archive.retainedBlocks", 100000)
+	viper.Set("ledger.blockchain.archive.dir", "/mnt/archive")
+	viper.Set("ledger.blockchain.archive.retainedBlocks", -1)
+	assert.Equal(t, "/mnt/archive", GetBlockArchiveDir())
+	assert.Equal(t, uint64(0), GetBlockArchiveRetainedBlocks())
+
+	assert.Nil(t, GetBlockArchiveS3Conf())
+	defer viper.Set("ledger.blockchain.archive.s3.endpoint", "")
+	defer viper.Set("ledger.blockchain.archive.s3.bucket", "")
+	viper.Set("ledger.blockchain.archive.s3.endpoint", "http://minio:9000")
+	viper.Set("ledger.blockchain.archive.s3.bucket", "archive")
+	assert.Equal(t, &BlockArchiveS3Conf{Endpoint: "http://minio:9000", Region: "us-east-1", Bucket: "archive"},
+		GetBlockArchiveS3Conf())
+}
+
 func TestGetMaxBlockfileSize(t *testing.T) {
 	assert.Equal(t, 67108864, GetMaxBlockfileSize())
 }
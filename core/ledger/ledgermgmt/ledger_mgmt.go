@@ -45,6 +45,7 @@ type Initializer struct {
 	MembershipInfoProvider        ledger.MembershipInfoProvider
 	MetricsProvider               metrics.Provider
 	HealthCheckRegistry           ledger.HealthCheckRegistry
+	CollectionConfigListeners     []ledger.CollectionConfigListener
 }
 
 // Initialize initializes ledgermgmt
@@ -72,6 +73,7 @@ func initialize(initializer *Initializer) {
 	}
 	provider.Initialize(&ledger.Initializer{
 		StateListeners:                finalStateListeners,
+		CollectionConfigListeners:     initializer.CollectionConfigListeners,
 		DeployedChaincodeInfoProvider: initializer.DeployedChaincodeInfoProvider,
 		MembershipInfoProvider:        initializer.MembershipInfoProvider,
 		MetricsProvider:               initializer.MetricsProvider,
@@ -106,6 +108,30 @@ func CreateLedger(genesisBlock *common.Block) (ledger.PeerLedger, error) {
 	return l, nil
 }
 
+// CreateLedgerFromSnapshot creates a new ledger from the snapshot in the given dir, instead of from the genesis block,
+// and returns the ledger along with its id. The channel name recorded in the snapshot is treated as a ledger id
+func CreateLedgerFromSnapshot(snapshotDir string) (ledger.PeerLedger, string, error) {
+	lock.Lock()
+	defer lock.Unlock()
+	if !initialized {
+		return nil, "", ErrLedgerMgmtNotInitialized
+	}
+	snapshotLedgerCreator, ok := ledgerProvider.(ledger.SnapshotLedgerCreator)
+	if !ok {
+		return nil, "", errors.New("creating a ledger from a snapshot is not supported by the ledger provider")
+	}
+
+	logger.Infof("Creating ledger from the snapshot at [%s]", snapshotDir)
+	l, id, err := snapshotLedgerCreator.CreateFromSnapshot(snapshotDir)
+	if err != nil {
+		return nil, "", err
+	}
+	l = wrapLedger(id, l)
+	openedLedgers[id] = l
+	logger.Infof("Created ledger [%s] from the snapshot at [%s]", id, snapshotDir)
+	return l, id, nil
+}
+
 // OpenLedger returns a ledger for the given id
 func OpenLedger(id string) (ledger.PeerLedger, error) {
 	logger.Infof("Opening ledger with id = %s", id)
@@ -176,6 +202,24 @@ func (l *closableLedger) closeWithoutLock() {
 	delete(openedLedgers, l.id)
 }
 
+// SubmitSnapshotRequest passes on the request to the actual ledger, if it implements the interface `ledger.SnapshotRequester`
+func (l *closableLedger) SubmitSnapshotRequest(blockNum uint64) error {
+	snapshotRequester, ok := l.PeerLedger.(ledger.SnapshotRequester)
+	if !ok {
+		return errors.Errorf("ledger [%s] does not support the snapshots", l.id)
+	}
+	return snapshotRequester.SubmitSnapshotRequest(blockNum)
+}
+
+// PendingSnapshotRequests passes on the request to the actual ledger, if it implements the interface `ledger.SnapshotRequester`
+func (l *closableLedger) PendingSnapshotRequests() ([]uint64, error) {
+	snapshotRequester, ok := l.PeerLedger.(ledger.SnapshotRequester)
+	if !ok {
+		return nil, errors.Errorf("ledger [%s] does not support the snapshots", l.id)
+	}
+	return snapshotRequester.PendingSnapshotRequests()
+}
+
 // lscc namespace listener for chaincode instantiate transactions (which manipulates data in 'lscc' namespace)
 // this code should be later moved to peer and passed via `Initialize` function of ledgermgmt
 func addListenerForCCEventsHandler(
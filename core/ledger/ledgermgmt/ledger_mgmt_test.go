@@ -99,6 +99,26 @@ func TestLedgerMgmt(t *testing.T) {
 	Close()
 }
 
+func TestSnapshotRequests(t *testing.T) {
+	InitializeTestEnv()
+	defer CleanupTestEnv()
+	gb, _ := test.MakeGenesisBlock(constructTestLedgerID(0))
+	l, err := CreateLedger(gb)
+	assert.NoError(t, err)
+	snapshotRequester, ok := l.(ledger.SnapshotRequester)
+	assert.True(t, ok)
+	assert.NoError(t, snapshotRequester.SubmitSnapshotRequest(5))
+	pendingRequests, err := snapshotRequester.PendingSnapshotRequests()
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{5}, pendingRequests)
+
+	// a ledger that does not support the snapshots
+	snapshotRequester = &closableLedger{"ledger1", struct{ ledger.PeerLedger }{}}
+	assert.EqualError(t, snapshotRequester.SubmitSnapshotRequest(5), "ledger [ledger1] does not support the snapshots")
+	_, err = snapshotRequester.PendingSnapshotRequests()
+	assert.EqualError(t, err, "ledger [ledger1] does not support the snapshots")
+}
+
 func TestChaincodeInfoProvider(t *testing.T) {
 	InitializeTestEnv()
 	defer CleanupTestEnv()
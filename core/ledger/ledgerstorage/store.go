@@ -39,6 +39,51 @@ type Store struct {
 // NewProvider returns the handle to the provider
 func NewProvider() *Provider {
 	// Initialize the block storage
+	blockStoreConf, indexConfig := blockStoreConfig()
+	blockStoreProvider := fsblkstorage.NewProvider(blockStoreConf, indexConfig)
+
+	pvtStoreProvider := pvtdatastorage.NewProvider()
+	return &Provider{blockStoreProvider, pvtStoreProvider}
+}
+
+// Rollback removes the blocks after the given block, along with their pvt data, from the block store and the pvt data
+// store of the given ledger. This is meant for the offline use, while the stores are not opened otherwise
+func Rollback(ledgerID string, blockNum uint64) error {
+	blockStoreConf, indexConfig := blockStoreConfig()
+	if err := fsblkstorage.RollbackToBlock(blockStoreConf, indexConfig, ledgerID, blockNum); err != nil {
+		return err
+	}
+	return pvtdatastorage.RollbackToBlock(ledgerID, blockNum)
+}
+
+// GetBlockchainInfo returns the blockchain info of the given ledger from its block store. Only the block store is opened,
+// without the archiving of the block files, so that the blocks are left as is. This is meant for the offline use, while the
+// stores are not opened otherwise
+func GetBlockchainInfo(ledgerID string) (*common.BlockchainInfo, error) {
+	_, indexConfig := blockStoreConfig()
+	blockStoreProvider := fsblkstorage.NewProvider(
+		fsblkstorage.NewConf(ledgerconfig.GetBlockStorePath(), ledgerconfig.GetMaxBlockfileSize()), indexConfig)
+	defer blockStoreProvider.Close()
+	blockStore, err := blockStoreProvider.OpenBlockStore(ledgerID)
+	if err != nil {
+		return nil, err
+	}
+	defer blockStore.Shutdown()
+	return blockStore.GetBlockchainInfo()
+}
+
+// NewFetchOnlyBlockStoreProvider returns a provider of the block stores that fetches the archived block files on demand but
+// does not archive the block files. This is meant for the offline use, while the stores are not opened otherwise
+func NewFetchOnlyBlockStoreProvider() blkstorage.BlockStoreProvider {
+	blockStoreConf, indexConfig := blockStoreConfigWithArchive(true)
+	return fsblkstorage.NewProvider(blockStoreConf, indexConfig)
+}
+
+func blockStoreConfig() (*fsblkstorage.Conf, *blkstorage.IndexConfig) {
+	return blockStoreConfigWithArchive(false)
+}
+
+func blockStoreConfigWithArchive(fetchOnly bool) (*fsblkstorage.Conf, *blkstorage.IndexConfig) {
 	attrsToIndex := []blkstorage.IndexableAttr{
 		blkstorage.IndexableAttrBlockHash,
 		blkstorage.IndexableAttrBlockNum,
@@ -48,12 +93,33 @@ func NewProvider() *Provider {
 		blkstorage.IndexableAttrTxValidationCode,
 	}
 	indexConfig := &blkstorage.IndexConfig{AttrsToIndex: attrsToIndex}
-	blockStoreProvider := fsblkstorage.NewProvider(
-		fsblkstorage.NewConf(ledgerconfig.GetBlockStorePath(), ledgerconfig.GetMaxBlockfileSize()),
-		indexConfig)
+	if archiveStore := blockArchiveStore(); archiveStore != nil {
+		return fsblkstorage.NewConfWithArchive(ledgerconfig.GetBlockStorePath(), ledgerconfig.GetMaxBlockfileSize(),
+			&fsblkstorage.ArchiveConf{
+				Store:          archiveStore,
+				RetainedBlocks: ledgerconfig.GetBlockArchiveRetainedBlocks(),
+				FetchOnly:      fetchOnly,
+			}), indexConfig
+	}
+	return fsblkstorage.NewConf(ledgerconfig.GetBlockStorePath(), ledgerconfig.GetMaxBlockfileSize()), indexConfig
+}
 
-	pvtStoreProvider := pvtdatastorage.NewProvider()
-	return &Provider{blockStoreProvider, pvtStoreProvider}
+// blockArchiveStore returns the store to which the older block files are archived, preferring the S3 compatible object
+// store over the dir. Returns nil if the archiving is not enabled
+func blockArchiveStore() fsblkstorage.ArchiveStore {
+	if s3Conf := ledgerconfig.GetBlockArchiveS3Conf(); s3Conf != nil {
+		return fsblkstorage.NewS3ArchiveStore(&fsblkstorage.S3Conf{
+			Endpoint:        s3Conf.Endpoint,
+			Region:          s3Conf.Region,
+			Bucket:          s3Conf.Bucket,
+			AccessKeyID:     s3Conf.AccessKeyID,
+			SecretAccessKey: s3Conf.SecretAccessKey,
+		})
+	}
+	if archiveDir := ledgerconfig.GetBlockArchiveDir(); archiveDir != "" {
+		return fsblkstorage.NewDirArchiveStore(archiveDir)
+	}
+	return nil
 }
 
 // Open opens the store
@@ -75,12 +141,57 @@ func (p *Provider) Open(ledgerid string) (*Store, error) {
 	return store, nil
 }
 
+// snapshotBootstrapper is implemented by the block store provider that supports creating a block store from a snapshot
+type snapshotBootstrapper interface {
+	BootstrapFromSnapshot(ledgerid string, snapshotInfo *blkstorage.SnapshotInfo, txIDs blkstorage.TxIDsIterator) (blkstorage.BlockStore, error)
+}
+
+// snapshotBootstrappedStore is implemented by the block store that can be created from a snapshot
+type snapshotBootstrappedStore interface {
+	BootstrappingSnapshotInfo() *blkstorage.SnapshotInfo
+	ForEachTxIDBeforeSnapshot(f func(txID string) error) error
+}
+
+// BootstrapFromSnapshot creates the block store of the given ledger from a snapshot, with the ids of the transactions that
+// precede the snapshot. The store is opened afterwards via the function `Open`, which brings the pvt data store up to the
+// last block of the snapshot
+func (p *Provider) BootstrapFromSnapshot(ledgerid string, snapshotInfo *blkstorage.SnapshotInfo, txIDs blkstorage.TxIDsIterator) error {
+	bootstrapper, ok := p.blkStoreProvider.(snapshotBootstrapper)
+	if !ok {
+		return errors.New("creating a ledger from a snapshot is not supported by the block store")
+	}
+	blockStore, err := bootstrapper.BootstrapFromSnapshot(ledgerid, snapshotInfo, txIDs)
+	if err != nil {
+		return err
+	}
+	blockStore.Shutdown()
+	return nil
+}
+
 // Close closes the provider
 func (p *Provider) Close() {
 	p.blkStoreProvider.Close()
 	p.pvtdataStoreProvider.Close()
 }
 
+// BootstrappingSnapshotInfo returns the info of the snapshot from which the block store was created, or nil if the block
+// store was created from the genesis block
+func (s *Store) BootstrappingSnapshotInfo() *blkstorage.SnapshotInfo {
+	if b, ok := s.BlockStore.(snapshotBootstrappedStore); ok {
+		return b.BootstrappingSnapshotInfo()
+	}
+	return nil
+}
+
+// ForEachTxIDBeforeSnapshot invokes the given function for each of the ids of the transactions that precede the snapshot
+// from which the block store was created
+func (s *Store) ForEachTxIDBeforeSnapshot(f func(txID string) error) error {
+	if b, ok := s.BlockStore.(snapshotBootstrappedStore); ok {
+		return b.ForEachTxIDBeforeSnapshot(f)
+	}
+	return nil
+}
+
 // Init initializes store with essential configurations
 func (s *Store) Init(btlPolicy pvtdatapolicy.BTLPolicy) {
 	s.pvtdataStore.Init(btlPolicy)
@@ -8,6 +8,7 @@ package ledgerstorage
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/golang/protobuf/proto"
@@ -320,6 +321,105 @@ func TestAddAfterBlkStoreError(t *testing.T) {
 	assert.False(t, pvtStorePndingBatch)
 }
 
+func TestRollback(t *testing.T) {
+	testEnv := newTestEnv(t)
+	defer testEnv.cleanup()
+	provider := NewProvider()
+	store, err := provider.Open("testLedger")
+	assert.NoError(t, err)
+	store.Init(btlPolicyForSampleData())
+	sampleData := sampleDataWithPvtdataForSelectiveTx(t)
+	for _, sampleDatum := range sampleData {
+		assert.NoError(t, store.CommitWithPvtData(sampleDatum))
+	}
+	store.Shutdown()
+	provider.Close()
+
+	assert.NoError(t, Rollback("testLedger", 2))
+	provider = NewProvider()
+	defer provider.Close()
+	store, err = provider.Open("testLedger")
+	assert.NoError(t, err)
+	store.Init(btlPolicyForSampleData())
+	defer store.Shutdown()
+	bcInfo, err := store.GetBlockchainInfo()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), bcInfo.Height)
+	pvtdata, err := store.GetPvtDataByNum(2, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(pvtdata))
+	missingDataInfo, err := store.GetMissingPvtDataInfoForMostRecentBlocks(10)
+	assert.NoError(t, err)
+	assert.Empty(t, missingDataInfo)
+
+	// the rolled back blocks can be committed again
+	for _, sampleDatum := range sampleData[3:] {
+		assert.NoError(t, store.CommitWithPvtData(sampleDatum))
+	}
+	pvtdata, err = store.GetPvtDataByNum(3, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(pvtdata))
+}
+
+func TestGetBlockchainInfo(t *testing.T) {
+	testEnv := newTestEnv(t)
+	defer testEnv.cleanup()
+	provider := NewProvider()
+	store, err := provider.Open("testLedger")
+	assert.NoError(t, err)
+	store.Init(btlPolicyForSampleData())
+	for _, sampleDatum := range sampleDataWithPvtdataForSelectiveTx(t) {
+		assert.NoError(t, store.CommitWithPvtData(sampleDatum))
+	}
+	store.Shutdown()
+	provider.Close()
+
+	archiveDir := filepath.Join(ledgerconfig.GetRootPath(), "archive")
+	viper.Set("ledger.blockchain.archive.dir", archiveDir)
+	defer viper.Set("ledger.blockchain.archive.dir", "")
+	bcInfo, err := GetBlockchainInfo("testLedger")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(10), bcInfo.Height)
+	// the block store is opened without the archiving of the block files
+	_, err = os.Stat(filepath.Join(ledgerconfig.GetBlockStorePath(), fsblkstorage.ChainsDir, "testLedger", "fetched"))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(archiveDir)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestNewFetchOnlyBlockStoreProvider(t *testing.T) {
+	testEnv := newTestEnv(t)
+	defer testEnv.cleanup()
+	archiveDir := filepath.Join(ledgerconfig.GetRootPath(), "archive")
+	viper.Set("ledger.blockchain.archive.dir", archiveDir)
+	defer viper.Set("ledger.blockchain.archive.dir", "")
+
+	provider := NewFetchOnlyBlockStoreProvider()
+	defer provider.Close()
+	blockStore, err := provider.OpenBlockStore("testLedger")
+	assert.NoError(t, err)
+	defer blockStore.Shutdown()
+	blocks := testutil.ConstructTestBlocks(t, 5)
+	for _, block := range blocks {
+		assert.NoError(t, blockStore.AddBlock(block))
+	}
+	for _, block := range blocks {
+		retrievedBlock, err := blockStore.RetrieveBlockByNumber(block.Header.Number)
+		assert.NoError(t, err)
+		assert.True(t, proto.Equal(block, retrievedBlock))
+	}
+}
+
+func TestBlockArchiveStore(t *testing.T) {
+	assert.Nil(t, blockArchiveStore())
+	defer viper.Set("ledger.blockchain.archive.dir", "")
+	viper.Set("ledger.blockchain.archive.dir", "/mnt/archive")
+	assert.IsType(t, fsblkstorage.NewDirArchiveStore(""), blockArchiveStore())
+	defer viper.Set("ledger.blockchain.archive.s3.endpoint", "")
+	viper.Set("ledger.blockchain.archive.s3.endpoint", "http://minio:9000")
+	assert.IsType(t, fsblkstorage.NewS3ArchiveStore(&fsblkstorage.S3Conf{}), blockArchiveStore())
+}
+
 func TestConstructPvtdataMap(t *testing.T) {
 	assert.Nil(t, constructPvtdataMap(nil))
 }
@@ -0,0 +1,84 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package pvtdatastorage
+
+import (
+	"github.com/hyperledger/fabric/common/ledger/util/leveldbhelper"
+	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/version"
+	"github.com/hyperledger/fabric/core/ledger/ledgerconfig"
+	"github.com/hyperledger/fabric/core/ledger/util"
+	"github.com/pkg/errors"
+)
+
+// RollbackToBlock removes from the pvtdata store of the given ledger the pvt data, the expiry entries, the missing data
+// entries, and the collection eligibility entries of the blocks after the given block, and sets the given block as the
+// last committed block. The pending batch and the list of the last updated old blocks, if any, are discarded, as these
+// are generated from the blocks again once the ledger is opened. The removal is made in a single atomic write. This is
+// meant for the offline use, while the store is not opened otherwise
+func RollbackToBlock(ledgerID string, targetBlockNum uint64) error {
+	dbProvider := leveldbhelper.NewProvider(&leveldbhelper.Conf{DBPath: ledgerconfig.GetPvtdataStorePath()})
+	defer dbProvider.Close()
+	db := dbProvider.GetDBHandle(ledgerID)
+
+	lastCommittedBlockBytes, err := db.Get(lastCommittedBlkkey)
+	if err != nil {
+		return err
+	}
+	if lastCommittedBlockBytes == nil {
+		// the store is initialized from the block store once the ledger is opened
+		logger.Infof("Pvtdata store of ledger [%s] is empty", ledgerID)
+		return nil
+	}
+	if lastCommittedBlock := decodeLastCommittedBlockVal(lastCommittedBlockBytes); lastCommittedBlock < targetBlockNum {
+		return errors.Errorf("target block number [%d] should not be greater than the last committed block [%d] of the pvtdata store",
+			targetBlockNum, lastCommittedBlock)
+	}
+
+	batch := leveldbhelper.NewUpdateBatch()
+	deleteAll := func(startKey, endKey []byte, shouldDelete func(key []byte) bool) error {
+		itr := db.GetIterator(startKey, endKey)
+		defer itr.Release()
+		for itr.Next() {
+			key := itr.Key()
+			if shouldDelete == nil || shouldDelete(key) {
+				batch.Delete(append([]byte{}, key...))
+			}
+		}
+		return errors.Wrap(itr.Error(), "error while iterating the pvtdata store")
+	}
+	// the data keys are ordered by the block number
+	if err := deleteAll(append(pvtDataKeyPrefix, version.NewHeight(targetBlockNum+1, 0).ToBytes()...), expiryKeyPrefix, nil); err != nil {
+		return err
+	}
+	// the expiry keys are ordered by the expiring block, rather than the committing block
+	if err := deleteAll(expiryKeyPrefix, eligibleMissingDataKeyPrefix, func(key []byte) bool {
+		return decodeExpiryKey(key).committingBlk > targetBlockNum
+	}); err != nil {
+		return err
+	}
+	// the eligible missing data keys and the collection eligibility keys are ordered by the block number in the reverse order
+	if err := deleteAll(eligibleMissingDataKeyPrefix,
+		append(eligibleMissingDataKeyPrefix, util.EncodeReverseOrderVarUint64(targetBlockNum)...), nil); err != nil {
+		return err
+	}
+	if err := deleteAll(ineligibleMissingDataKeyPrefix, collElgKeyPrefix, func(key []byte) bool {
+		return decodeMissingDataKey(key).blkNum > targetBlockNum
+	}); err != nil {
+		return err
+	}
+	if err := deleteAll(collElgKeyPrefix, encodeCollElgKey(targetBlockNum), nil); err != nil {
+		return err
+	}
+	batch.Delete(pendingCommitKey)
+	batch.Delete(lastUpdatedOldBlocksKey)
+	batch.Put(lastCommittedBlkkey, encodeLastCommittedBlockVal(targetBlockNum))
+	if err := db.WriteBatch(batch, true); err != nil {
+		return err
+	}
+	logger.Infof("Rolled back pvtdata store of ledger [%s] to block [%d]", ledgerID, targetBlockNum)
+	return nil
+}
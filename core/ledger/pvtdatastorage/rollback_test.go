@@ -0,0 +1,92 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package pvtdatastorage
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/core/ledger"
+	btltestutil "github.com/hyperledger/fabric/core/ledger/pvtdatapolicy/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRollbackToBlock(t *testing.T) {
+	btlPolicy := btltestutil.SampleBTLPolicy(
+		map[[2]string]uint64{
+			{"ns-1", "coll-1"}: 0,
+			{"ns-1", "coll-2"}: 5,
+		},
+	)
+	env := NewTestStoreEnv(t, "TestRollbackToBlock", btlPolicy)
+	defer env.Cleanup()
+	assert := assert.New(t)
+
+	// an empty store is left as is
+	env.TestStoreProvider.Close()
+	assert.NoError(RollbackToBlock("TestRollbackToBlock", 2))
+	env.TestStoreProvider = NewProvider()
+	s, err := env.TestStoreProvider.OpenStore("TestRollbackToBlock")
+	assert.NoError(err)
+	s.Init(btlPolicy)
+	testEmpty(true, assert, s)
+
+	testData := []*ledger.TxPvtData{
+		produceSamplePvtdata(t, 2, []string{"ns-1:coll-1", "ns-1:coll-2"}),
+	}
+	assert.NoError(s.Prepare(0, nil, nil))
+	assert.NoError(s.Commit())
+	for blkNum := uint64(1); blkNum <= 4; blkNum++ {
+		missingData := make(ledger.TxMissingPvtDataMap)
+		missingData.Add(1, "ns-1", "coll-1", true)
+		missingData.Add(3, "ns-1", "coll-2", false)
+		assert.NoError(s.Prepare(blkNum, testData, missingData))
+		assert.NoError(s.Commit())
+	}
+	// a pending batch is discarded by the rollback
+	assert.NoError(s.Prepare(5, testData, nil))
+	env.TestStoreProvider.Close()
+
+	assert.EqualError(RollbackToBlock("TestRollbackToBlock", 5),
+		"target block number [5] should not be greater than the last committed block [4] of the pvtdata store")
+	assert.NoError(RollbackToBlock("TestRollbackToBlock", 2))
+	env.TestStoreProvider = NewProvider()
+	s, err = env.TestStoreProvider.OpenStore("TestRollbackToBlock")
+	assert.NoError(err)
+	s.Init(btlPolicy)
+	defer env.TestStoreProvider.Close()
+
+	testLastCommittedBlockHeight(3, assert, s)
+	testPendingBatch(false, assert, s)
+	for blkNum := uint64(1); blkNum <= 4; blkNum++ {
+		assert.Equal(blkNum <= 2, testDataKeyExists(t, s, &dataKey{nsCollBlk{"ns-1", "coll-1", blkNum}, 2}))
+		assert.Equal(blkNum <= 2, testDataKeyExists(t, s, &dataKey{nsCollBlk{"ns-1", "coll-2", blkNum}, 2}))
+		assert.Equal(blkNum <= 2, testMissingDataKeyExists(t, s, &missingDataKey{nsCollBlk{"ns-1", "coll-1", blkNum}, true}))
+		assert.Equal(blkNum <= 2, testMissingDataKeyExists(t, s, &missingDataKey{nsCollBlk{"ns-1", "coll-2", blkNum}, false}))
+	}
+	expectedMissingPvtDataInfo := make(ledger.MissingPvtDataInfo)
+	expectedMissingPvtDataInfo.Add(2, 1, "ns-1", "coll-1")
+	expectedMissingPvtDataInfo.Add(1, 1, "ns-1", "coll-1")
+	missingPvtDataInfo, err := s.GetMissingPvtDataInfoForMostRecentBlocks(10)
+	assert.NoError(err)
+	assert.Equal(expectedMissingPvtDataInfo, missingPvtDataInfo)
+	itr := s.(*store).db.GetIterator(expiryKeyPrefix, eligibleMissingDataKeyPrefix)
+	numExpiryKeys := 0
+	for itr.Next() {
+		assert.True(decodeExpiryKey(itr.Key()).committingBlk <= 2)
+		numExpiryKeys++
+	}
+	itr.Release()
+	assert.Equal(2, numExpiryKeys)
+
+	// the rolled back blocks can be committed again
+	assert.NoError(s.Prepare(3, testData, nil))
+	assert.NoError(s.Commit())
+	testLastCommittedBlockHeight(4, assert, s)
+	retrievedData, err := s.GetPvtDataByBlockNum(3, nil)
+	assert.NoError(err)
+	assert.Len(retrievedData, 1)
+}
@@ -0,0 +1,161 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package util
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/binary"
+	"hash"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// SnapshotFileWriter writes a data file of a ledger snapshot. A data file starts with a format byte, which is followed by the
+// fields written via the functions `EncodeUVarint` and `EncodeBytes`, the latter being prefixed by the length of the bytes (uvarint).
+// The SHA-256 of the contents is computed while writing, so that the hashes of the data files can be recorded in the metadata
+// of the snapshot without reading the files again
+type SnapshotFileWriter struct {
+	file      *os.File
+	bufWriter *bufio.Writer
+	out       io.Writer
+	hasher    hash.Hash
+}
+
+// CreateSnapshotFile creates a new data file of a snapshot at the given path and writes the format byte. The file is expected
+// not to exist already
+func CreateSnapshotFile(path string, format byte) (*SnapshotFileWriter, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error while creating the snapshot file [%s]", path)
+	}
+	bufWriter := bufio.NewWriter(file)
+	hasher := sha256.New()
+	w := &SnapshotFileWriter{file, bufWriter, io.MultiWriter(bufWriter, hasher), hasher}
+	if err := w.write([]byte{format}); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// EncodeUVarint writes the given number as a uvarint
+func (w *SnapshotFileWriter) EncodeUVarint(n uint64) error {
+	buf := make([]byte, binary.MaxVarintLen64)
+	return w.write(buf[:binary.PutUvarint(buf, n)])
+}
+
+// EncodeBytes writes the length of the given bytes followed by the bytes
+func (w *SnapshotFileWriter) EncodeBytes(b []byte) error {
+	if err := w.EncodeUVarint(uint64(len(b))); err != nil {
+		return err
+	}
+	return w.write(b)
+}
+
+// Done flushes and syncs the file to the disk and returns the SHA-256 of its contents. The writer should still be closed
+func (w *SnapshotFileWriter) Done() ([]byte, error) {
+	if err := w.bufWriter.Flush(); err != nil {
+		return nil, errors.Wrapf(err, "error while flushing the snapshot file [%s]", w.file.Name())
+	}
+	if err := w.file.Sync(); err != nil {
+		return nil, errors.Wrapf(err, "error while syncing the snapshot file [%s]", w.file.Name())
+	}
+	return w.hasher.Sum(nil), nil
+}
+
+// Close closes the file
+func (w *SnapshotFileWriter) Close() error {
+	return errors.Wrapf(w.file.Close(), "error while closing the snapshot file [%s]", w.file.Name())
+}
+
+func (w *SnapshotFileWriter) write(b []byte) error {
+	if _, err := w.out.Write(b); err != nil {
+		return errors.Wrapf(err, "error while writing the snapshot file [%s]", w.file.Name())
+	}
+	return nil
+}
+
+// SnapshotFileReader reads a data file of a ledger snapshot that is written by a `SnapshotFileWriter`
+type SnapshotFileReader struct {
+	file      *os.File
+	bufReader *bufio.Reader
+}
+
+// OpenSnapshotFile opens the data file of a snapshot at the given path and verifies that the format byte is the expected one
+func OpenSnapshotFile(path string, expectedFormat byte) (*SnapshotFileReader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error while opening the snapshot file [%s]", path)
+	}
+	r := &SnapshotFileReader{file, bufio.NewReader(file)}
+	format, err := r.bufReader.ReadByte()
+	if err != nil {
+		file.Close()
+		return nil, errors.Wrapf(err, "error while reading the format of the snapshot file [%s]", path)
+	}
+	if format != expectedFormat {
+		file.Close()
+		return nil, errors.Errorf("unexpected format [%d] of the snapshot file [%s], expected format [%d]", format, path, expectedFormat)
+	}
+	return r, nil
+}
+
+// HasMore returns whether any content remains to be read
+func (r *SnapshotFileReader) HasMore() (bool, error) {
+	if _, err := r.bufReader.Peek(1); err != nil {
+		if err == io.EOF {
+			return false, nil
+		}
+		return false, errors.Wrapf(err, "error while reading the snapshot file [%s]", r.file.Name())
+	}
+	return true, nil
+}
+
+// DecodeUVarint reads a number written by the function `SnapshotFileWriter.EncodeUVarint`
+func (r *SnapshotFileReader) DecodeUVarint() (uint64, error) {
+	n, err := binary.ReadUvarint(r.bufReader)
+	if err != nil {
+		return 0, errors.Wrapf(err, "error while reading the snapshot file [%s]", r.file.Name())
+	}
+	return n, nil
+}
+
+// DecodeBytes reads the bytes written by the function `SnapshotFileWriter.EncodeBytes`
+func (r *SnapshotFileReader) DecodeBytes() ([]byte, error) {
+	length, err := r.DecodeUVarint()
+	if err != nil {
+		return nil, err
+	}
+	b := make([]byte, length)
+	if _, err := io.ReadFull(r.bufReader, b); err != nil {
+		return nil, errors.Wrapf(err, "error while reading the snapshot file [%s]", r.file.Name())
+	}
+	return b, nil
+}
+
+// Close closes the file
+func (r *SnapshotFileReader) Close() error {
+	return errors.Wrapf(r.file.Close(), "error while closing the snapshot file [%s]", r.file.Name())
+}
+
+// SnapshotFileHash returns the SHA-256 of the contents of the given file, for the data files that are written by the other
+// means than a `SnapshotFileWriter`
+func SnapshotFileHash(path string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error while opening the snapshot file [%s]", path)
+	}
+	defer file.Close()
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, file); err != nil {
+		return nil, errors.Wrapf(err, "error while reading the snapshot file [%s]", path)
+	}
+	return hasher.Sum(nil), nil
+}
@@ -0,0 +1,64 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package util
+
+import (
+	"crypto/sha256"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshotFileWriterAndReader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snapshotfile")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "test.data")
+
+	w, err := CreateSnapshotFile(path, byte(5))
+	assert.NoError(t, err)
+	assert.NoError(t, w.EncodeUVarint(300))
+	assert.NoError(t, w.EncodeBytes([]byte("some-bytes")))
+	assert.NoError(t, w.EncodeBytes(nil))
+	hash, err := w.Done()
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+
+	contents, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	expectedHash := sha256.Sum256(contents)
+	assert.Equal(t, expectedHash[:], hash)
+	hash, err = SnapshotFileHash(path)
+	assert.NoError(t, err)
+	assert.Equal(t, expectedHash[:], hash)
+
+	_, err = OpenSnapshotFile(path, byte(6))
+	assert.EqualError(t, err, "unexpected format [5] of the snapshot file ["+path+"], expected format [6]")
+	r, err := OpenSnapshotFile(path, byte(5))
+	assert.NoError(t, err)
+	defer r.Close()
+	n, err := r.DecodeUVarint()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(300), n)
+	hasMore, err := r.HasMore()
+	assert.NoError(t, err)
+	assert.True(t, hasMore)
+	b, err := r.DecodeBytes()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("some-bytes"), b)
+	b, err = r.DecodeBytes()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{}, b)
+	hasMore, err = r.HasMore()
+	assert.NoError(t, err)
+	assert.False(t, hasMore)
+	_, err = r.DecodeBytes()
+	assert.Error(t, err)
+}
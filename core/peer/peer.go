@@ -235,9 +235,15 @@ func Initialize(init func(string), ccp ccprovider.ChaincodeProvider, sccp sysccp
 			continue
 		}
 		if cb, err = getCurrConfigBlockFromLedger(ledger); err != nil {
-			peerLogger.Warningf("Failed to find config block on ledger %s(%s)", cid, err)
-			peerLogger.Debugf("Error while looking for config block on ledger %s with message %s. We continue to the next ledger rather than abort.", cid, err)
-			continue
+			// the config block of a ledger created from a snapshot is not available until a config block is committed,
+			// in which case the chain is created from the channel config persisted in the statedb
+			if chanConf, confErr := retrievePersistedChannelConfig(ledger); confErr != nil || chanConf == nil {
+				peerLogger.Warningf("Failed to find config block on ledger %s(%s)", cid, err)
+				peerLogger.Debugf("Error while looking for config block on ledger %s with message %s. We continue to the next ledger rather than abort.", cid, err)
+				continue
+			}
+			peerLogger.Infof("Config block is not available on ledger %s(%s), loading the chain from the persisted channel config", cid, err)
+			cb = nil
 		}
 		// Create a chain if we get a valid ledger with config block
 		if err = createChain(cid, ledger, cb, ccp, sccp, pm); err != nil {
@@ -303,6 +309,9 @@ func createChain(cid string, ledger ledger.PeerLedger, cb *common.Block, ccp ccp
 			return err
 		}
 	} else {
+		if cb == nil {
+			return errors.Errorf("channel config of chain %s is neither persisted in the ledger nor supplied via a config block", cid)
+		}
 		// Config was only stored in the statedb starting with v1.1 binaries
 		// so if the config is not found there, extract it manually from the config block
 		envelopeConfig, err := utils.ExtractEnvelope(cb, 0)
@@ -440,6 +449,18 @@ func CreateChainFromBlock(cb *common.Block, ccp ccprovider.ChaincodeProvider, sc
 	return createChain(cid, l, cb, ccp, sccp, pluginMapper)
 }
 
+// CreateChainFromSnapshot creates a new chain from the snapshot in the given dir and returns the chain ID. The chain
+// is configured from the channel config in the snapshot, as the config block is not part of the snapshot. Hence,
+// `GetCurrConfigBlock` returns nil for this chain until a config block is committed
+func CreateChainFromSnapshot(snapshotDir string, ccp ccprovider.ChaincodeProvider, sccp sysccprovider.SystemChaincodeProvider) (string, error) {
+	l, cid, err := ledgermgmt.CreateLedgerFromSnapshot(snapshotDir)
+	if err != nil {
+		return "", errors.WithMessage(err, "cannot create ledger from snapshot")
+	}
+
+	return cid, createChain(cid, l, nil, ccp, sccp, pluginMapper)
+}
+
 // GetLedger returns the ledger of the chain with chain ID. Note that this
 // call returns nil if chain cid has not been created.
 func GetLedger(cid string) ledger.PeerLedger {
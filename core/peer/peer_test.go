@@ -8,9 +8,13 @@ package peer
 
 import (
 	"fmt"
+	"io/ioutil"
 	"math/rand"
 	"net"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	configtxtest "github.com/hyperledger/fabric/common/configtx/test"
 	"github.com/hyperledger/fabric/common/localmsp"
@@ -22,6 +26,9 @@ import (
 	"github.com/hyperledger/fabric/core/deliverservice"
 	"github.com/hyperledger/fabric/core/deliverservice/blocksprovider"
 	"github.com/hyperledger/fabric/core/handlers/validation/api"
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/ledgerconfig"
+	"github.com/hyperledger/fabric/core/ledger/ledgermgmt"
 	ledgermocks "github.com/hyperledger/fabric/core/ledger/mock"
 	"github.com/hyperledger/fabric/core/mocks/ccprovider"
 	"github.com/hyperledger/fabric/gossip/api"
@@ -32,6 +39,7 @@ import (
 	"github.com/hyperledger/fabric/peer/gossip/mocks"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/semaphore"
 	"google.golang.org/grpc"
 )
 
@@ -199,6 +207,92 @@ func TestCreateChainFromBlock(t *testing.T) {
 	chains.Unlock()
 }
 
+func TestCreateChainFromSnapshot(t *testing.T) {
+	cleanup := setupPeerFS(t)
+	defer cleanup()
+	// the one-time initialization of ledgermgmt by the function `Initialize` is used up here, so that the ledgers
+	// opened by the function `Initialize` below are the ones in the test env
+	ledgermgmt.Initialize(&ledgermgmt.Initializer{
+		CustomTxProcessors:            ConfigTxProcessors,
+		DeployedChaincodeInfoProvider: &ledgermocks.DeployedChaincodeInfoProvider{},
+		MetricsProvider:               &disabled.Provider{},
+	})
+	ledgermgmt.Close()
+	ledgermgmt.InitializeTestEnvWithInitializer(&ledgermgmt.Initializer{CustomTxProcessors: ConfigTxProcessors})
+	testChainID := fmt.Sprintf("mytestchainid-%d", rand.Int())
+	block, err := configtxtest.MakeGenesisBlock(testChainID)
+	require.NoError(t, err)
+	sourceLedger, err := ledgermgmt.CreateLedger(block)
+	require.NoError(t, err)
+	require.NoError(t, sourceLedger.(ledger.SnapshotRequester).SubmitSnapshotRequest(0))
+	completedDir := filepath.Join(ledgerconfig.GetSnapshotsPath(), "completed", testChainID, "0")
+	for i := 0; i < 100; i++ {
+		if _, err := os.Stat(completedDir); err == nil {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	snapshotParentDir, err := ioutil.TempDir("", "snapshot")
+	require.NoError(t, err)
+	defer os.RemoveAll(snapshotParentDir)
+	snapshotDir := filepath.Join(snapshotParentDir, "0")
+	require.NoError(t, os.Rename(completedDir, snapshotDir))
+	ledgermgmt.CleanupTestEnv()
+
+	// a peer that has not joined the channel creates the chain from the snapshot
+	cleanup = setupPeerFS(t)
+	defer cleanup()
+	ledgermgmt.InitializeTestEnvWithInitializer(&ledgermgmt.Initializer{CustomTxProcessors: ConfigTxProcessors})
+	defer ledgermgmt.CleanupTestEnv()
+	grpcServer := grpc.NewServer()
+	socket, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	msptesttools.LoadMSPSetupForTesting()
+	identity, _ := mgmt.GetLocalSigningIdentityOrPanic().Serialize()
+	messageCryptoService := peergossip.NewMCS(&mocks.ChannelPolicyManagerGetter{}, localmsp.NewSigner(), mgmt.NewDeserializersManager())
+	secAdv := peergossip.NewSecurityAdvisor(mgmt.NewDeserializersManager())
+	err = service.InitGossipServiceCustomDeliveryFactory(
+		identity, socket.Addr().String(), grpcServer, nil,
+		&mockDeliveryClientFactory{},
+		messageCryptoService, secAdv, func() []grpc.DialOption { return []grpc.DialOption{grpc.WithInsecure()} })
+	require.NoError(t, err)
+	go grpcServer.Serve(socket)
+	defer grpcServer.Stop()
+	pluginMapper = txvalidator.MapBasedPluginMapper(map[string]validation.PluginFactory{})
+	validationWorkersSemaphore = semaphore.NewWeighted(1)
+
+	cid, err := CreateChainFromSnapshot(snapshotDir, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, testChainID, cid)
+	l := GetLedger(testChainID)
+	require.NotNil(t, l)
+	bcInfo, err := l.GetBlockchainInfo()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), bcInfo.Height)
+	assert.Equal(t, block.Header.Hash(), bcInfo.CurrentBlockHash)
+	// the chain is configured from the channel config in the snapshot, without the config block
+	assert.NotNil(t, GetStableChannelConfig(testChainID))
+	assert.Nil(t, GetCurrConfigBlock(testChainID))
+
+	_, err = CreateChainFromSnapshot(snapshotDir, nil, nil)
+	assert.EqualError(t, err, "cannot create ledger from snapshot: LedgerID already exists")
+
+	// the chain is loaded from the persisted channel config after a restart
+	chains.Lock()
+	chains.list = map[string]*chain{}
+	chains.Unlock()
+	l.Close()
+	Initialize(nil, &ccprovider.MockCcProviderImpl{}, (&mscc.MocksccProviderFactory{}).NewSystemChaincodeProvider(), pluginMapper,
+		&platforms.Registry{}, &ledgermocks.DeployedChaincodeInfoProvider{}, nil, &disabled.Provider{})
+	assert.NotNil(t, GetLedger(testChainID))
+	assert.NotNil(t, GetStableChannelConfig(testChainID))
+
+	// cleanup the chain referenes to enable execution with -count n
+	chains.Lock()
+	chains.list = map[string]*chain{}
+	chains.Unlock()
+}
+
 func TestGetLocalIP(t *testing.T) {
 	ip := GetLocalIP()
 	t.Log(ip)
@@ -12,7 +12,9 @@ SPDX-License-Identifier: Apache-2.0
 package cscc
 
 import (
+	"encoding/json"
 	"fmt"
+	"strconv"
 
 	"github.com/golang/protobuf/proto"
 	"github.com/hyperledger/fabric/common/channelconfig"
@@ -23,6 +25,7 @@ import (
 	"github.com/hyperledger/fabric/core/chaincode/shim"
 	"github.com/hyperledger/fabric/core/common/ccprovider"
 	"github.com/hyperledger/fabric/core/common/sysccprovider"
+	"github.com/hyperledger/fabric/core/ledger"
 	"github.com/hyperledger/fabric/core/ledger/util"
 	"github.com/hyperledger/fabric/core/peer"
 	"github.com/hyperledger/fabric/core/policy"
@@ -73,10 +76,14 @@ var cnflogger = flogging.MustGetLogger("cscc")
 // These are function names from Invoke first parameter
 const (
 	JoinChain                string = "JoinChain"
+	JoinChainBySnapshot      string = "JoinChainBySnapshot"
 	GetConfigBlock           string = "GetConfigBlock"
 	GetChannels              string = "GetChannels"
 	GetConfigTree            string = "GetConfigTree"
 	SimulateConfigTreeUpdate string = "SimulateConfigTreeUpdate"
+
+	SubmitSnapshotRequest       string = "SubmitSnapshotRequest"
+	ListPendingSnapshotRequests string = "ListPendingSnapshotRequests"
 )
 
 // Init is mostly useless from an SCC perspective
@@ -93,7 +100,8 @@ func (e *PeerConfiger) Init(stub shim.ChaincodeStubInterface) pb.Response {
 // # args[0] is the function name, which must be JoinChain, GetConfigBlock or
 // UpdateConfigBlock
 // # args[1] is a configuration Block if args[0] is JoinChain or
-// UpdateConfigBlock; otherwise it is the chain id
+// UpdateConfigBlock, the snapshot directory if args[0] is JoinChainBySnapshot;
+// otherwise it is the chain id
 // TODO: Improve the scc interface to avoid marshal/unmarshal args
 func (e *PeerConfiger) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
 	args := stub.GetArgs()
@@ -162,6 +170,17 @@ func (e *PeerConfiger) InvokeNoShim(args [][]byte, sp *pb.SignedProposal) pb.Res
 		}
 
 		return joinChain(cid, block, e.ccp, e.sccp)
+	case JoinChainBySnapshot:
+		if len(args[1]) == 0 {
+			return shim.Error("Cannot join the channel, no snapshot directory provided")
+		}
+		// check local MSP Admins policy
+		// TODO: move to ACLProvider once it will support chainless ACLs
+		if err = e.policyChecker.CheckPolicyNoChannel(mgmt.Admins, sp); err != nil {
+			return shim.Error(fmt.Sprintf("access denied for [%s][%s]: [%s]", fname, args[1], err))
+		}
+
+		return joinChainBySnapshot(string(args[1]), e.ccp, e.sccp)
 	case GetConfigBlock:
 		// 2. check policy
 		if err = e.aclProvider.CheckACL(resources.Cscc_GetConfigBlock, string(args[1]), sp); err != nil {
@@ -190,7 +209,25 @@ func (e *PeerConfiger) InvokeNoShim(args [][]byte, sp *pb.SignedProposal) pb.Res
 		}
 
 		return getChannels()
+	case SubmitSnapshotRequest:
+		// 2. check local MSP Admins policy, as the snapshots are generated on the disk of the peer
+		// TODO: move to ACLProvider once it will support chainless ACLs
+		if err = e.policyChecker.CheckPolicyNoChannel(mgmt.Admins, sp); err != nil {
+			return shim.Error(fmt.Sprintf("access denied for [%s][%s]: [%s]", fname, args[1], err))
+		}
+		if len(args) < 3 {
+			return shim.Error(fmt.Sprintf("Incorrect number of arguments, %d", len(args)))
+		}
+
+		return submitSnapshotRequest(string(args[1]), string(args[2]))
+	case ListPendingSnapshotRequests:
+		// 2. check local MSP Admins policy
+		// TODO: move to ACLProvider once it will support chainless ACLs
+		if err = e.policyChecker.CheckPolicyNoChannel(mgmt.Admins, sp); err != nil {
+			return shim.Error(fmt.Sprintf("access denied for [%s][%s]: [%s]", fname, args[1], err))
+		}
 
+		return listPendingSnapshotRequests(string(args[1]))
 	}
 	return shim.Error(fmt.Sprintf("Requested function %s not found.", fname))
 }
@@ -242,6 +279,19 @@ func joinChain(chainID string, block *common.Block, ccp ccprovider.ChaincodeProv
 	return shim.Success(nil)
 }
 
+// joinChainBySnapshot will join the chain by creating its ledger from the snapshot in the given dir of the peer,
+// instead of from the genesis block
+func joinChainBySnapshot(snapshotDir string, ccp ccprovider.ChaincodeProvider, sccp sysccprovider.SystemChaincodeProvider) pb.Response {
+	chainID, err := peer.CreateChainFromSnapshot(snapshotDir, ccp, sccp)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	peer.InitChain(chainID)
+
+	return shim.Success(nil)
+}
+
 // Return the current configuration block for the specified chainID. If the
 // peer doesn't belong to the chain, return error
 func getConfigBlock(chainID []byte) pb.Response {
@@ -250,6 +300,10 @@ func getConfigBlock(chainID []byte) pb.Response {
 	}
 	block := peer.GetCurrConfigBlock(string(chainID))
 	if block == nil {
+		if peer.GetLedger(string(chainID)) != nil {
+			return shim.Error(fmt.Sprintf("Config block of chain ID %s is not available until a config block is committed, "+
+				"as the ledger was created from a snapshot", string(chainID)))
+		}
 		return shim.Error(fmt.Sprintf("Unknown chain ID, %s", string(chainID)))
 	}
 	blockBytes, err := utils.Marshal(block)
@@ -320,6 +374,56 @@ func supportByType(pc *PeerConfiger, chainID []byte, env *common.Envelope) (conf
 	return nil, errors.Errorf("invalid payload header type: %d", channelHdr.Type)
 }
 
+// submitSnapshotRequest requests a snapshot of the ledger of the given channel at the given block number. A block number of
+// zero requests a snapshot at the last committed block
+func submitSnapshotRequest(cid, blockNumber string) pb.Response {
+	snapshotRequester, err := getSnapshotRequester(cid)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	blockNum, err := strconv.ParseUint(blockNumber, 10, 64)
+	if err != nil {
+		return shim.Error(fmt.Sprintf("Invalid block number [%s]: %s", blockNumber, err))
+	}
+	if err := snapshotRequester.SubmitSnapshotRequest(blockNum); err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(nil)
+}
+
+// listPendingSnapshotRequests returns the JSON encoded block numbers of the pending snapshot requests of the ledger of the
+// given channel
+func listPendingSnapshotRequests(cid string) pb.Response {
+	snapshotRequester, err := getSnapshotRequester(cid)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	blockNums, err := snapshotRequester.PendingSnapshotRequests()
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if blockNums == nil {
+		blockNums = []uint64{}
+	}
+	blockNumsBytes, err := json.Marshal(blockNums)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(blockNumsBytes)
+}
+
+func getSnapshotRequester(cid string) (ledger.SnapshotRequester, error) {
+	l := peer.GetLedger(cid)
+	if l == nil {
+		return nil, errors.Errorf("Unknown chain ID, %s", cid)
+	}
+	snapshotRequester, ok := l.(ledger.SnapshotRequester)
+	if !ok {
+		return nil, errors.Errorf("The ledger of chain ID %s does not support the snapshots", cid)
+	}
+	return snapshotRequester, nil
+}
+
 // getChannels returns information about all channels for this peer
 func getChannels() pb.Response {
 	channelInfoArray := peer.GetChannelsInfo()
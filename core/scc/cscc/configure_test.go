@@ -353,6 +353,36 @@ func TestConfigerInvokeJoinChainCorrectParams(t *testing.T) {
 	if len(cqr.GetChannels()) != 1 {
 		t.FailNow()
 	}
+
+	// submit and list the snapshot requests
+	res = stub.MockInvokeWithSignedProposal("2", [][]byte{[]byte(SubmitSnapshotRequest), []byte("mytestchainid"), []byte("5")}, sProp)
+	assert.Equal(t, int32(shim.OK), res.Status, res.Message)
+	res = stub.MockInvokeWithSignedProposal("2", [][]byte{[]byte(ListPendingSnapshotRequests), []byte("mytestchainid")}, sProp)
+	assert.Equal(t, int32(shim.OK), res.Status, res.Message)
+	assert.Equal(t, "[5]", string(res.Payload))
+
+	res = stub.MockInvokeWithSignedProposal("2", [][]byte{[]byte(SubmitSnapshotRequest), []byte("mytestchainid")}, sProp)
+	assert.Contains(t, res.Message, "Incorrect number of arguments")
+	res = stub.MockInvokeWithSignedProposal("2", [][]byte{[]byte(SubmitSnapshotRequest), []byte("mytestchainid"), []byte("five")}, sProp)
+	assert.Contains(t, res.Message, "Invalid block number [five]")
+	res = stub.MockInvokeWithSignedProposal("2", [][]byte{[]byte(ListPendingSnapshotRequests), []byte("unknownchainid")}, sProp)
+	assert.Contains(t, res.Message, "Unknown chain ID, unknownchainid")
+
+	sProp.Signature = nil
+	res = stub.MockInvokeWithSignedProposal("3", [][]byte{[]byte(SubmitSnapshotRequest), []byte("mytestchainid"), []byte("6")}, sProp)
+	assert.Contains(t, res.Message, "access denied for [SubmitSnapshotRequest][mytestchainid]")
+	res = stub.MockInvokeWithSignedProposal("3", [][]byte{[]byte(ListPendingSnapshotRequests), []byte("mytestchainid")}, sProp)
+	assert.Contains(t, res.Message, "access denied for [ListPendingSnapshotRequests][mytestchainid]")
+	res = stub.MockInvokeWithSignedProposal("3", [][]byte{[]byte(JoinChainBySnapshot), []byte("/tmp/hyperledgertest/snapshot")}, sProp)
+	assert.Contains(t, res.Message, "access denied for [JoinChainBySnapshot][/tmp/hyperledgertest/snapshot]")
+	sProp.Signature = sProp.ProposalBytes
+
+	// join by a snapshot
+	res = stub.MockInvokeWithSignedProposal("2", [][]byte{[]byte(JoinChainBySnapshot), []byte("")}, sProp)
+	assert.Equal(t, "Cannot join the channel, no snapshot directory provided", res.Message)
+	res = stub.MockInvokeWithSignedProposal("2", [][]byte{[]byte(JoinChainBySnapshot), []byte("/tmp/hyperledgertest/snapshot")}, sProp)
+	assert.Equal(t, int32(shim.ERROR), res.Status)
+	assert.Contains(t, res.Message, "cannot create ledger from snapshot")
 }
 
 func TestGetConfigTree(t *testing.T) {
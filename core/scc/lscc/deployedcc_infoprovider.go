@@ -76,6 +76,8 @@ func (p *DeployedCCInfoProvider) ChaincodeInfo(chaincodeName string, qe ledger.S
 		Hash:                chaincodeData.Id,
 		Version:             chaincodeData.Version,
 		CollectionConfigPkg: collConfigPkg,
+		EndorsementPolicy:   chaincodeData.Policy,
+		ValidationPlugin:    chaincodeData.Vscc,
 	}, nil
 }
 
@@ -38,6 +38,8 @@ func TestChaincodeInfo(t *testing.T) {
 		Version:             "cc2_version",
 		Hash:                []byte("cc2_hash"),
 		CollectionConfigPkg: prepapreCollectionConfigPkg([]string{"cc2_coll1", "cc2_coll2"}),
+		EndorsementPolicy:   []byte("cc2_policy"),
+		ValidationPlugin:    "vscc",
 	}
 
 	mockQE := prepareMockQE(t, []*ledger.DeployedChaincodeInfo{cc1, cc2})
@@ -51,6 +53,8 @@ func TestChaincodeInfo(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, cc2.Name, ccInfo2.Name)
 	assert.True(t, proto.Equal(cc2.CollectionConfigPkg, ccInfo2.CollectionConfigPkg))
+	assert.Equal(t, []byte("cc2_policy"), ccInfo2.EndorsementPolicy)
+	assert.Equal(t, "vscc", ccInfo2.ValidationPlugin)
 
 	ccInfo3, err := ccInfoProvdier.ChaincodeInfo("cc3", mockQE)
 	assert.NoError(t, err)
@@ -91,7 +95,7 @@ func prepareMockQE(t *testing.T, deployedChaincodes []*ledger.DeployedChaincodeI
 	mockQE := &mock.QueryExecutor{}
 	lsccTable := map[string][]byte{}
 	for _, cc := range deployedChaincodes {
-		chaincodeData := &ccprovider.ChaincodeData{Name: cc.Name, Version: cc.Version, Id: cc.Hash}
+		chaincodeData := &ccprovider.ChaincodeData{Name: cc.Name, Version: cc.Version, Id: cc.Hash, Policy: cc.EndorsementPolicy, Vscc: cc.ValidationPlugin}
 		chaincodeDataBytes, err := proto.Marshal(chaincodeData)
 		assert.NoError(t, err)
 		lsccTable[cc.Name] = chaincodeDataBytes
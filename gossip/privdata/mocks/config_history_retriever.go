@@ -2,6 +2,7 @@
 
 package mocks
 
+import commonledger "github.com/hyperledger/fabric/common/ledger"
 import ledger "github.com/hyperledger/fabric/core/ledger"
 import mock "github.com/stretchr/testify/mock"
 
@@ -33,6 +34,52 @@ func (_m *ConfigHistoryRetriever) CollectionConfigAt(blockNum uint64, chaincodeN
 	return r0, r1
 }
 
+// EndorsementPolicyAt provides a mock function with given fields: blockNum, chaincodeName
+func (_m *ConfigHistoryRetriever) EndorsementPolicyAt(blockNum uint64, chaincodeName string) (*ledger.EndorsementPolicyInfo, error) {
+	ret := _m.Called(blockNum, chaincodeName)
+
+	var r0 *ledger.EndorsementPolicyInfo
+	if rf, ok := ret.Get(0).(func(uint64, string) *ledger.EndorsementPolicyInfo); ok {
+		r0 = rf(blockNum, chaincodeName)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*ledger.EndorsementPolicyInfo)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(uint64, string) error); ok {
+		r1 = rf(blockNum, chaincodeName)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetCollectionConfigHistory provides a mock function with given fields: chaincodeName
+func (_m *ConfigHistoryRetriever) GetCollectionConfigHistory(chaincodeName string) (commonledger.ResultsIterator, error) {
+	ret := _m.Called(chaincodeName)
+
+	var r0 commonledger.ResultsIterator
+	if rf, ok := ret.Get(0).(func(string) commonledger.ResultsIterator); ok {
+		r0 = rf(chaincodeName)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(commonledger.ResultsIterator)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(chaincodeName)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // MostRecentCollectionConfigBelow provides a mock function with given fields: blockNum, chaincodeName
 func (_m *ConfigHistoryRetriever) MostRecentCollectionConfigBelow(blockNum uint64, chaincodeName string) (*ledger.CollectionConfigInfo, error) {
 	ret := _m.Called(blockNum, chaincodeName)
@@ -55,3 +102,26 @@ func (_m *ConfigHistoryRetriever) MostRecentCollectionConfigBelow(blockNum uint6
 
 	return r0, r1
 }
+
+// MostRecentEndorsementPolicyBelow provides a mock function with given fields: blockNum, chaincodeName
+func (_m *ConfigHistoryRetriever) MostRecentEndorsementPolicyBelow(blockNum uint64, chaincodeName string) (*ledger.EndorsementPolicyInfo, error) {
+	ret := _m.Called(blockNum, chaincodeName)
+
+	var r0 *ledger.EndorsementPolicyInfo
+	if rf, ok := ret.Get(0).(func(uint64, string) *ledger.EndorsementPolicyInfo); ok {
+		r0 = rf(blockNum, chaincodeName)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*ledger.EndorsementPolicyInfo)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(uint64, string) error); ok {
+		r1 = rf(blockNum, chaincodeName)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
@@ -0,0 +1,59 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package channel
+
+import (
+	"errors"
+
+	"github.com/hyperledger/fabric/core/scc/cscc"
+	"github.com/hyperledger/fabric/peer/common"
+	pb "github.com/hyperledger/fabric/protos/peer"
+	"github.com/spf13/cobra"
+)
+
+const joinBySnapshotCommandDescription = "Joins the peer to a channel by creating the channel ledger from a snapshot, instead of from the genesis block."
+
+func joinBySnapshotCmd(cf *ChannelCmdFactory) *cobra.Command {
+	// Set the flags on the channel joinbysnapshot command.
+	joinBySnapshotCmd := &cobra.Command{
+		Use:   "joinbysnapshot",
+		Short: joinBySnapshotCommandDescription,
+		Long: joinBySnapshotCommandDescription + " The snapshot directory is read by the peer, hence the path is " +
+			"on the file system of the peer. Requires '--snapshotpath'.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return joinBySnapshot(cmd, args, cf)
+		},
+	}
+	flagList := []string{
+		"snapshotpath",
+	}
+	attachFlags(joinBySnapshotCmd, flagList)
+
+	return joinBySnapshotCmd
+}
+
+func joinBySnapshot(cmd *cobra.Command, args []string, cf *ChannelCmdFactory) error {
+	if snapshotPath == common.UndefinedParamValue {
+		return errors.New("Must supply snapshot path")
+	}
+	// Parsing of the command line is done so silence cmd usage
+	cmd.SilenceUsage = true
+
+	var err error
+	if cf == nil {
+		cf, err = InitCmdFactory(EndorserRequired, PeerDeliverNotRequired, OrdererNotRequired)
+		if err != nil {
+			return err
+		}
+	}
+	spec := &pb.ChaincodeSpec{
+		Type:        pb.ChaincodeSpec_Type(pb.ChaincodeSpec_Type_value["GOLANG"]),
+		ChaincodeId: &pb.ChaincodeID{Name: "cscc"},
+		Input:       &pb.ChaincodeInput{Args: [][]byte{[]byte(cscc.JoinChainBySnapshot), []byte(snapshotPath)}},
+	}
+	return submitJoinProposal(cf, spec)
+}
@@ -0,0 +1,60 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package channel
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/peer/common"
+	pb "github.com/hyperledger/fabric/protos/peer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMissingSnapshotPath(t *testing.T) {
+	defer resetFlags()
+
+	resetFlags()
+
+	cmd := joinBySnapshotCmd(nil)
+	AddFlags(cmd)
+	cmd.SetArgs([]string{})
+
+	assert.EqualError(t, cmd.Execute(), "Must supply snapshot path")
+}
+
+func TestJoinBySnapshot(t *testing.T) {
+	defer resetFlags()
+
+	InitMSP()
+	resetFlags()
+
+	signer, err := common.GetDefaultSigner()
+	assert.NoError(t, err, "Get default signer error: %v", err)
+
+	mockCF := &ChannelCmdFactory{
+		EndorserClient: common.GetMockEndorserClient(&pb.ProposalResponse{
+			Response:    &pb.Response{Status: 200},
+			Endorsement: &pb.Endorsement{},
+		}, nil),
+		BroadcastFactory: mockBroadcastClientFactory,
+		Signer:           signer,
+	}
+
+	cmd := joinBySnapshotCmd(mockCF)
+	AddFlags(cmd)
+	cmd.SetArgs([]string{"--snapshotpath", "/var/hyperledger/snapshots/mychannel/1000"})
+	assert.NoError(t, cmd.Execute(), "expected joinbysnapshot command to succeed")
+
+	mockCF.EndorserClient = common.GetMockEndorserClient(&pb.ProposalResponse{
+		Response:    &pb.Response{Status: 500},
+		Endorsement: &pb.Endorsement{},
+	}, nil)
+	cmd = joinBySnapshotCmd(mockCF)
+	AddFlags(cmd)
+	cmd.SetArgs([]string{"--snapshotpath", "/var/hyperledger/snapshots/mychannel/1000"})
+	assert.Error(t, cmd.Execute(), "expected joinbysnapshot command to fail on a bad proposal response")
+}
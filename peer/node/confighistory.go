@@ -0,0 +1,109 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package node
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/hyperledger/fabric/core/ledger/kvledger"
+	"github.com/hyperledger/fabric/peer/common"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var (
+	configHistoryChannelID string
+	configHistoryFile      string
+)
+
+func exportConfigHistoryCmd() *cobra.Command {
+	flags := nodeExportConfigHistoryCmd.Flags()
+	flags.StringVarP(&configHistoryChannelID, "channelID", "c", common.UndefinedParamValue, "Channel whose config history is to be exported")
+	flags.StringVarP(&configHistoryFile, "output", "o", common.UndefinedParamValue, "Path of the file to which the config history is written")
+	return nodeExportConfigHistoryCmd
+}
+
+func importConfigHistoryCmd() *cobra.Command {
+	flags := nodeImportConfigHistoryCmd.Flags()
+	flags.StringVarP(&configHistoryChannelID, "channelID", "c", common.UndefinedParamValue, "Channel whose config history is to be imported")
+	flags.StringVarP(&configHistoryFile, "input", "i", common.UndefinedParamValue, "Path of the file, as written by export-confighistory, from which the config history is read")
+	return nodeImportConfigHistoryCmd
+}
+
+var nodeExportConfigHistoryCmd = &cobra.Command{
+	Use:   "export-confighistory",
+	Short: "Exports the collection config history of a channel.",
+	Long:  `Exports the collection config history of a channel to a file, for backing it up. This command should be executed while the peer is stopped.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkConfigHistoryArgs(args); err != nil {
+			return err
+		}
+		// Parsing of the command line is done so silence cmd usage
+		cmd.SilenceUsage = true
+		return exportConfigHistory(configHistoryChannelID, configHistoryFile)
+	},
+}
+
+var nodeImportConfigHistoryCmd = &cobra.Command{
+	Use:   "import-confighistory",
+	Short: "Imports the collection config history of a channel.",
+	Long:  `Imports the collection config history of a channel from a file written by export-confighistory, for restoring it on a rebuilt peer. The config history of the channel on the peer is expected to be empty. This command should be executed while the peer is stopped.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkConfigHistoryArgs(args); err != nil {
+			return err
+		}
+		// Parsing of the command line is done so silence cmd usage
+		cmd.SilenceUsage = true
+		return importConfigHistory(configHistoryChannelID, configHistoryFile)
+	},
+}
+
+func checkConfigHistoryArgs(args []string) error {
+	if len(args) != 0 {
+		return fmt.Errorf("trailing args detected: %s", args)
+	}
+	if configHistoryChannelID == common.UndefinedParamValue {
+		return errors.New("must supply channel ID")
+	}
+	if configHistoryFile == common.UndefinedParamValue {
+		return errors.New("must supply the path of the file")
+	}
+	return nil
+}
+
+// exportConfigHistory writes the export to the file. On a failure, the partially written file is removed, so that it is not
+// mistaken for a backup
+func exportConfigHistory(channelID, path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return errors.Wrapf(err, "error while creating the file [%s]", path)
+	}
+	err = kvledger.ExportConfigHistory(channelID, f)
+	if closeErr := f.Close(); err == nil {
+		err = errors.Wrapf(closeErr, "error while closing the file [%s]", path)
+	}
+	if err != nil {
+		os.Remove(path)
+		return err
+	}
+	logger.Infof("Exported the config history of channel [%s] to the file [%s]", channelID, path)
+	return nil
+}
+
+func importConfigHistory(channelID, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return errors.Wrapf(err, "error while opening the file [%s]", path)
+	}
+	defer f.Close()
+	if err := kvledger.ImportConfigHistory(channelID, f); err != nil {
+		return err
+	}
+	logger.Infof("Imported the config history of channel [%s] from the file [%s]", channelID, path)
+	return nil
+}
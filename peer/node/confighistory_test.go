@@ -0,0 +1,81 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package node
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/confighistory"
+	"github.com/hyperledger/fabric/core/ledger/mock"
+	commonflags "github.com/hyperledger/fabric/peer/common"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportImportConfigHistoryCmd(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "confighistorycmd")
+	assert.NoError(t, err)
+	defer os.RemoveAll(testDir)
+	viper.Set("peer.fileSystemPath", testDir)
+	defer viper.Reset()
+
+	ccInfoProvider := &mock.DeployedChaincodeInfoProvider{}
+	ccInfoProvider.UpdatedChaincodesReturns([]*ledger.ChaincodeLifecycleInfo{{Name: "chaincode1"}}, nil)
+	ccInfoProvider.ChaincodeInfoReturns(&ledger.DeployedChaincodeInfo{
+		Name: "chaincode1",
+		CollectionConfigPkg: &common.CollectionConfigPackage{
+			Config: []*common.CollectionConfig{{
+				Payload: &common.CollectionConfig_StaticCollectionConfig{
+					StaticCollectionConfig: &common.StaticCollectionConfig{Name: "coll1"},
+				},
+			}},
+		},
+	}, nil)
+	m := confighistory.NewMgr(ccInfoProvider)
+	assert.NoError(t, m.HandleStateUpdates(&ledger.StateUpdateTrigger{LedgerID: "ledger1", CommittingBlockNum: 10}))
+	m.Close()
+
+	exportFile := filepath.Join(testDir, "confighistory.export")
+	cmd := exportConfigHistoryCmd()
+	cmd.SetArgs([]string{"-c", "ledger1", "-o", exportFile})
+	assert.NoError(t, cmd.Execute())
+
+	// the existing file is not overwritten
+	cmd.SetArgs([]string{"-c", "ledger1", "-o", exportFile})
+	assert.Error(t, cmd.Execute())
+
+	importCmd := importConfigHistoryCmd()
+	importCmd.SetArgs([]string{"-c", "ledger2", "-i", exportFile})
+	assert.NoError(t, importCmd.Execute())
+
+	// the imported history exports identically to the original
+	reexportFile := filepath.Join(testDir, "confighistory.reexport")
+	cmd.SetArgs([]string{"-c", "ledger2", "-o", reexportFile})
+	assert.NoError(t, cmd.Execute())
+	exported, err := ioutil.ReadFile(exportFile)
+	assert.NoError(t, err)
+	reexported, err := ioutil.ReadFile(reexportFile)
+	assert.NoError(t, err)
+	assert.Equal(t, exported, reexported)
+
+	// the import requires an empty config history
+	importCmd.SetArgs([]string{"-c", "ledger2", "-i", exportFile})
+	assert.Error(t, importCmd.Execute())
+
+	t.Run("missing-args", func(t *testing.T) {
+		configHistoryChannelID = commonflags.UndefinedParamValue
+		importCmd.SetArgs([]string{"-i", exportFile})
+		assert.EqualError(t, importCmd.Execute(), "must supply channel ID")
+		importCmd.SetArgs([]string{"-c", "ledger2", "-i", exportFile, "extra"})
+		assert.EqualError(t, importCmd.Execute(), "trailing args detected: [extra]")
+	})
+}
@@ -26,7 +26,7 @@ import (
 
 const (
 	nodeFuncName = "node"
-	nodeCmdDes   = "Operate a peer node: start|status."
+	nodeCmdDes   = "Operate a peer node: start|status|export-confighistory|import-confighistory|rollback|rebuild-dbs."
 )
 
 var logger = flogging.MustGetLogger("nodeCmd")
@@ -35,6 +35,10 @@ var logger = flogging.MustGetLogger("nodeCmd")
 func Cmd() *cobra.Command {
 	nodeCmd.AddCommand(startCmd())
 	nodeCmd.AddCommand(statusCmd())
+	nodeCmd.AddCommand(exportConfigHistoryCmd())
+	nodeCmd.AddCommand(importConfigHistoryCmd())
+	nodeCmd.AddCommand(rollbackCmd())
+	nodeCmd.AddCommand(rebuildDBsCmd())
 
 	return nodeCmd
 }
@@ -0,0 +1,56 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package node
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/ledger/kvledger"
+	"github.com/hyperledger/fabric/core/scc/lscc"
+	"github.com/spf13/cobra"
+)
+
+var (
+	rebuildDBsChannelIDs    []string
+	rebuildDBsConfigHistory bool
+)
+
+func rebuildDBsCmd() *cobra.Command {
+	flags := nodeRebuildDBsCmd.Flags()
+	flags.StringSliceVarP(&rebuildDBsChannelIDs, "channelID", "c", nil, "Channels whose databases are to be rebuilt, as a comma separated list or as repeated flags; all the channels if not supplied")
+	flags.BoolVarP(&rebuildDBsConfigHistory, "confighistory", "", false, "Also discard the collection config history and regenerate it by replaying the blocks from the block store")
+	return nodeRebuildDBsCmd
+}
+
+var nodeRebuildDBsCmd = &cobra.Command{
+	Use:   "rebuild-dbs",
+	Short: "Rebuilds the databases of the channels from the blocks.",
+	Long:  `Drops the state database, the history database, and the bookkeeping data of the selected channels, or of all the channels, so that these are rebuilt from the block store when the peer is started next time. With --confighistory, the collection config history of the channels is also regenerated from the block store, for recovering a config history database that is lost or corrupted. The block store and the private data store are retained. This is supported only with LevelDB as the state database. This command should be executed while the peer is stopped.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 0 {
+			return fmt.Errorf("trailing args detected: %s", args)
+		}
+		// Parsing of the command line is done so silence cmd usage
+		cmd.SilenceUsage = true
+		return rebuildDBs(rebuildDBsChannelIDs, rebuildDBsConfigHistory)
+	},
+}
+
+func rebuildDBs(channelIDs []string, configHistory bool) error {
+	if err := kvledger.RebuildDBs(channelIDs...); err != nil {
+		return err
+	}
+	logger.Infof("Dropped the databases of channels %s, which are rebuilt when the peer is started next time", channelIDs)
+	if !configHistory {
+		return nil
+	}
+	if err := kvledger.RebuildConfigHistory(&lscc.DeployedCCInfoProvider{}, channelIDs...); err != nil {
+		return err
+	}
+	logger.Infof("Rebuilt the config history of channels %s", channelIDs)
+	return nil
+}
@@ -0,0 +1,38 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package node
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRebuildDBsCmd(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "rebuilddbscmd")
+	assert.NoError(t, err)
+	defer os.RemoveAll(testDir)
+	viper.Set("peer.fileSystemPath", testDir)
+	defer viper.Reset()
+
+	cmd := rebuildDBsCmd()
+	cmd.SetArgs([]string{"extra"})
+	assert.EqualError(t, cmd.Execute(), "trailing args detected: [extra]")
+	// no channels to rebuild
+	cmd.SetArgs([]string{})
+	assert.NoError(t, cmd.Execute())
+	cmd.SetArgs([]string{"--confighistory"})
+	assert.NoError(t, cmd.Execute())
+	cmd.SetArgs([]string{"-c", "ledger1,ledger2"})
+	assert.EqualError(t, cmd.Execute(), "cannot rebuild dbs of ledger [ledger1]: LedgerID does not exist")
+	assert.Equal(t, []string{"ledger1", "ledger2"}, rebuildDBsChannelIDs)
+	rebuildDBsChannelIDs = nil
+	rebuildDBsConfigHistory = false
+}
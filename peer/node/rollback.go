@@ -0,0 +1,57 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package node
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/ledger/kvledger"
+	"github.com/hyperledger/fabric/core/scc/lscc"
+	"github.com/hyperledger/fabric/peer/common"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var (
+	rollbackChannelID   string
+	rollbackBlockNumber uint64
+)
+
+func rollbackCmd() *cobra.Command {
+	flags := nodeRollbackCmd.Flags()
+	flags.StringVarP(&rollbackChannelID, "channelID", "c", common.UndefinedParamValue, "Channel to roll back")
+	flags.Uint64VarP(&rollbackBlockNumber, "blockNumber", "b", 0, "Block number to which the channel is rolled back")
+	return nodeRollbackCmd
+}
+
+var nodeRollbackCmd = &cobra.Command{
+	Use:   "rollback",
+	Short: "Rolls back a channel.",
+	Long:  `Rolls back a channel to the specified block number, removing the later blocks along with their private data. The state and the history of the channel are rebuilt from the remaining blocks when the peer is started next time, which is supported only with LevelDB as the state database. If the rollback is interrupted, it should be executed again. This command should be executed while the peer is stopped.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 0 {
+			return fmt.Errorf("trailing args detected: %s", args)
+		}
+		if rollbackChannelID == common.UndefinedParamValue {
+			return errors.New("must supply channel ID")
+		}
+		if !cmd.Flags().Changed("blockNumber") {
+			return errors.New("must supply the block number")
+		}
+		// Parsing of the command line is done so silence cmd usage
+		cmd.SilenceUsage = true
+		return rollback(rollbackChannelID, rollbackBlockNumber)
+	},
+}
+
+func rollback(channelID string, blockNumber uint64) error {
+	if err := kvledger.RollbackKVLedger(&lscc.DeployedCCInfoProvider{}, channelID, blockNumber); err != nil {
+		return err
+	}
+	logger.Infof("Rolled back channel [%s] to block [%d]", channelID, blockNumber)
+	return nil
+}
@@ -0,0 +1,37 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package node
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	commonflags "github.com/hyperledger/fabric/peer/common"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRollbackCmd(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "rollbackcmd")
+	assert.NoError(t, err)
+	defer os.RemoveAll(testDir)
+	viper.Set("peer.fileSystemPath", testDir)
+	defer viper.Reset()
+
+	cmd := rollbackCmd()
+	cmd.SetArgs([]string{"-c", "ledger1"})
+	assert.EqualError(t, cmd.Execute(), "must supply the block number")
+	rollbackChannelID = commonflags.UndefinedParamValue
+	cmd.SetArgs([]string{"-b", "5"})
+	assert.EqualError(t, cmd.Execute(), "must supply channel ID")
+	cmd.SetArgs([]string{"-c", "ledger1", "-b", "5", "extra"})
+	assert.EqualError(t, cmd.Execute(), "trailing args detected: [extra]")
+	cmd.SetArgs([]string{"-c", "ledger1", "-b", "5"})
+	assert.EqualError(t, cmd.Execute(), "cannot roll back ledger [ledger1]: LedgerID does not exist")
+	rollbackChannelID = commonflags.UndefinedParamValue
+}
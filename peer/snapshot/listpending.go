@@ -0,0 +1,61 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/scc/cscc"
+	"github.com/hyperledger/fabric/peer/common"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func listPendingCmd(cf *SnapshotCmdFactory) *cobra.Command {
+	snapshotListPendingCmd := &cobra.Command{
+		Use:   "listpending",
+		Short: "List the pending snapshot requests of a specified channel.",
+		Long:  "List the block numbers of the pending snapshot requests of a specified channel. Requires '-c'.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return listPending(cmd, cf)
+		},
+	}
+	flagList := []string{
+		"channelID",
+	}
+	attachFlags(snapshotListPendingCmd, flagList)
+
+	return snapshotListPendingCmd
+}
+
+func listPending(cmd *cobra.Command, cf *SnapshotCmdFactory) error {
+	if channelID == common.UndefinedParamValue {
+		return errors.New("Must supply channel ID")
+	}
+	// Parsing of the command line is done so silence cmd usage
+	cmd.SilenceUsage = true
+
+	var err error
+	if cf == nil {
+		cf, err = InitCmdFactory()
+		if err != nil {
+			return err
+		}
+	}
+
+	payload, err := cf.invokeCscc([]byte(cscc.ListPendingSnapshotRequests), []byte(channelID))
+	if err != nil {
+		return err
+	}
+	var blockNums []uint64
+	if err := json.Unmarshal(payload, &blockNums); err != nil {
+		return errors.Wrap(err, "cannot read cscc response")
+	}
+	fmt.Printf("Pending snapshot requests: %v\n", blockNums)
+	return nil
+}
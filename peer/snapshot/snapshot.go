@@ -0,0 +1,135 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package snapshot
+
+import (
+	"context"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/msp"
+	"github.com/hyperledger/fabric/peer/common"
+	cb "github.com/hyperledger/fabric/protos/common"
+	pb "github.com/hyperledger/fabric/protos/peer"
+	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	snapshotFuncName = "snapshot"
+	snapshotCmdDes   = "Manage the snapshots of the channel ledgers of the peer: submit|listpending."
+)
+
+var logger = flogging.MustGetLogger("cli.snapshot")
+
+var (
+	channelID   string
+	blockNumber uint64
+)
+
+// Cmd returns the cobra command for Snapshot
+func Cmd(cf *SnapshotCmdFactory) *cobra.Command {
+	snapshotCmd.AddCommand(submitCmd(cf))
+	snapshotCmd.AddCommand(listPendingCmd(cf))
+
+	return snapshotCmd
+}
+
+var snapshotCmd = &cobra.Command{
+	Use:              snapshotFuncName,
+	Short:            snapshotCmdDes,
+	Long:             snapshotCmdDes,
+	PersistentPreRun: common.InitCmd,
+}
+
+var flags *pflag.FlagSet
+
+func init() {
+	resetFlags()
+}
+
+// Explicitly define a method to facilitate tests
+func resetFlags() {
+	flags = &pflag.FlagSet{}
+
+	flags.StringVarP(&channelID, "channelID", "c", common.UndefinedParamValue, "The channel on which this command should be executed")
+	flags.Uint64VarP(&blockNumber, "blockNumber", "b", 0, "The block number at which the snapshot is to be generated. Zero requests a snapshot at the last committed block")
+}
+
+func attachFlags(cmd *cobra.Command, names []string) {
+	cmdFlags := cmd.Flags()
+	for _, name := range names {
+		if flag := flags.Lookup(name); flag != nil {
+			cmdFlags.AddFlag(flag)
+		} else {
+			logger.Fatalf("Could not find flag '%s' to attach to commond '%s'", name, cmd.Name())
+		}
+	}
+}
+
+// SnapshotCmdFactory holds the clients used by SnapshotCmd
+type SnapshotCmdFactory struct {
+	EndorserClient pb.EndorserClient
+	Signer         msp.SigningIdentity
+}
+
+// InitCmdFactory init the SnapshotCmdFactory with the default endorser client and signer
+func InitCmdFactory() (*SnapshotCmdFactory, error) {
+	signer, err := common.GetDefaultSignerFnc()
+	if err != nil {
+		return nil, errors.WithMessage(err, "error getting default signer")
+	}
+	// creating an EndorserClient with these empty parameters will create a
+	// connection using the values of "peer.address" and
+	// "peer.tls.rootcert.file"
+	endorserClient, err := common.GetEndorserClientFnc(common.UndefinedParamValue, common.UndefinedParamValue)
+	if err != nil {
+		return nil, errors.WithMessage(err, "error getting endorser client for snapshot")
+	}
+	return &SnapshotCmdFactory{
+		EndorserClient: endorserClient,
+		Signer:         signer,
+	}, nil
+}
+
+// invokeCscc sends a proposal invoking the given function of cscc with the given arguments and returns the payload of
+// the response
+func (cf *SnapshotCmdFactory) invokeCscc(args ...[]byte) ([]byte, error) {
+	invocation := &pb.ChaincodeInvocationSpec{
+		ChaincodeSpec: &pb.ChaincodeSpec{
+			Type:        pb.ChaincodeSpec_Type(pb.ChaincodeSpec_Type_value["GOLANG"]),
+			ChaincodeId: &pb.ChaincodeID{Name: "cscc"},
+			Input:       &pb.ChaincodeInput{Args: args},
+		},
+	}
+
+	creator, err := cf.Signer.Serialize()
+	if err != nil {
+		return nil, errors.WithMessage(err, "cannot serialize the signer")
+	}
+	prop, _, err := utils.CreateProposalFromCIS(cb.HeaderType_ENDORSER_TRANSACTION, "", invocation, creator)
+	if err != nil {
+		return nil, errors.WithMessage(err, "cannot create proposal")
+	}
+	signedProp, err := utils.GetSignedProposal(prop, cf.Signer)
+	if err != nil {
+		return nil, errors.WithMessage(err, "cannot create signed proposal")
+	}
+
+	proposalResp, err := cf.EndorserClient.ProcessProposal(context.Background(), signedProp)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed sending proposal")
+	}
+	if proposalResp.Response == nil {
+		return nil, errors.New("received an empty response")
+	}
+	if proposalResp.Response.Status != 200 {
+		return nil, errors.Errorf("received bad response, status %d: %s", proposalResp.Response.Status, proposalResp.Response.Message)
+	}
+	return proposalResp.Response.Payload, nil
+}
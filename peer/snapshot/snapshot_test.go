@@ -0,0 +1,72 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package snapshot
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hyperledger/fabric/msp/mgmt/testtools"
+	"github.com/hyperledger/fabric/peer/common"
+	pb "github.com/hyperledger/fabric/protos/peer"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func newMockCmdFactory(t *testing.T, response *pb.ProposalResponse, err error) *SnapshotCmdFactory {
+	assert.NoError(t, msptesttools.LoadMSPSetupForTesting())
+	signer, signerErr := common.GetDefaultSigner()
+	assert.NoError(t, signerErr)
+	return &SnapshotCmdFactory{
+		EndorserClient: common.GetMockEndorserClient(response, err),
+		Signer:         signer,
+	}
+}
+
+func runCmd(cmd *cobra.Command, args ...string) error {
+	cmd.SetArgs(args)
+	return cmd.Execute()
+}
+
+func TestSubmit(t *testing.T) {
+	resetFlags()
+	mockResponse := &pb.ProposalResponse{
+		Response:    &pb.Response{Status: 200},
+		Endorsement: &pb.Endorsement{},
+	}
+	cf := newMockCmdFactory(t, mockResponse, nil)
+	assert.NoError(t, runCmd(submitCmd(cf), "-c", "testchannel", "-b", "5"))
+	resetFlags()
+	assert.EqualError(t, runCmd(submitCmd(cf), "-b", "5"), "Must supply channel ID")
+
+	resetFlags()
+	cf = newMockCmdFactory(t, &pb.ProposalResponse{Response: &pb.Response{Status: 500, Message: "snapshot request already exists"}}, nil)
+	err := runCmd(submitCmd(cf), "-c", "testchannel", "-b", "5")
+	assert.EqualError(t, err, "received bad response, status 500: snapshot request already exists")
+
+	resetFlags()
+	cf = newMockCmdFactory(t, nil, errors.New("connection refused"))
+	err = runCmd(submitCmd(cf), "-c", "testchannel", "-b", "5")
+	assert.EqualError(t, err, "failed sending proposal: connection refused")
+}
+
+func TestListPending(t *testing.T) {
+	resetFlags()
+	mockResponse := &pb.ProposalResponse{
+		Response:    &pb.Response{Status: 200, Payload: []byte("[5,10]")},
+		Endorsement: &pb.Endorsement{},
+	}
+	cf := newMockCmdFactory(t, mockResponse, nil)
+	assert.NoError(t, runCmd(listPendingCmd(cf), "-c", "testchannel"))
+	resetFlags()
+	assert.EqualError(t, runCmd(listPendingCmd(cf)), "Must supply channel ID")
+
+	resetFlags()
+	cf = newMockCmdFactory(t, &pb.ProposalResponse{Response: &pb.Response{Status: 200, Payload: []byte("invalid")}}, nil)
+	err := runCmd(listPendingCmd(cf), "-c", "testchannel")
+	assert.Contains(t, err.Error(), "cannot read cscc response")
+}
@@ -0,0 +1,61 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package snapshot
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/scc/cscc"
+	"github.com/hyperledger/fabric/peer/common"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func submitCmd(cf *SnapshotCmdFactory) *cobra.Command {
+	snapshotSubmitCmd := &cobra.Command{
+		Use:   "submit",
+		Short: "Submit a request for a snapshot of the ledger of a specified channel.",
+		Long:  "Submit a request for a snapshot of the ledger of a specified channel at a specified block number. Requires '-c'.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return submit(cmd, cf)
+		},
+	}
+	flagList := []string{
+		"channelID",
+		"blockNumber",
+	}
+	attachFlags(snapshotSubmitCmd, flagList)
+
+	return snapshotSubmitCmd
+}
+
+func submit(cmd *cobra.Command, cf *SnapshotCmdFactory) error {
+	if channelID == common.UndefinedParamValue {
+		return errors.New("Must supply channel ID")
+	}
+	// Parsing of the command line is done so silence cmd usage
+	cmd.SilenceUsage = true
+
+	var err error
+	if cf == nil {
+		cf, err = InitCmdFactory()
+		if err != nil {
+			return err
+		}
+	}
+
+	if _, err := cf.invokeCscc([]byte(cscc.SubmitSnapshotRequest), []byte(channelID), []byte(strconv.FormatUint(blockNumber, 10))); err != nil {
+		return err
+	}
+	if blockNumber == 0 {
+		fmt.Println("Snapshot request for the last committed block submitted successfully")
+		return nil
+	}
+	fmt.Printf("Snapshot request for block number %d submitted successfully\n", blockNumber)
+	return nil
+}
@@ -0,0 +1,147 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: ledger/confighistory/config_history.proto
+
+package confighistory // import "github.com/hyperledger/fabric/protos/ledger/confighistory"
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+import common "github.com/hyperledger/fabric/protos/common"
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
+
+// CollectionConfigVersion is a version of the collection config of a chaincode, as
+// recorded in the config history of a ledger at the block that committed it
+type CollectionConfigVersion struct {
+	ChaincodeName        string                          `protobuf:"bytes,1,opt,name=chaincode_name,json=chaincodeName,proto3" json:"chaincode_name,omitempty"`
+	BlockNum             uint64                          `protobuf:"varint,2,opt,name=block_num,json=blockNum,proto3" json:"block_num,omitempty"`
+	CollectionConfig     *common.CollectionConfigPackage `protobuf:"bytes,3,opt,name=collection_config,json=collectionConfig,proto3" json:"collection_config,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}                        `json:"-"`
+	XXX_unrecognized     []byte                          `json:"-"`
+	XXX_sizecache        int32                           `json:"-"`
+}
+
+func (m *CollectionConfigVersion) Reset()         { *m = CollectionConfigVersion{} }
+func (m *CollectionConfigVersion) String() string { return proto.CompactTextString(m) }
+func (*CollectionConfigVersion) ProtoMessage()    {}
+func (*CollectionConfigVersion) Descriptor() ([]byte, []int) {
+	return fileDescriptor_config_history_c0c8402c8058244d, []int{0}
+}
+func (m *CollectionConfigVersion) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CollectionConfigVersion.Unmarshal(m, b)
+}
+func (m *CollectionConfigVersion) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CollectionConfigVersion.Marshal(b, m, deterministic)
+}
+func (dst *CollectionConfigVersion) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CollectionConfigVersion.Merge(dst, src)
+}
+func (m *CollectionConfigVersion) XXX_Size() int {
+	return xxx_messageInfo_CollectionConfigVersion.Size(m)
+}
+func (m *CollectionConfigVersion) XXX_DiscardUnknown() {
+	xxx_messageInfo_CollectionConfigVersion.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CollectionConfigVersion proto.InternalMessageInfo
+
+func (m *CollectionConfigVersion) GetChaincodeName() string {
+	if m != nil {
+		return m.ChaincodeName
+	}
+	return ""
+}
+
+func (m *CollectionConfigVersion) GetBlockNum() uint64 {
+	if m != nil {
+		return m.BlockNum
+	}
+	return 0
+}
+
+func (m *CollectionConfigVersion) GetCollectionConfig() *common.CollectionConfigPackage {
+	if m != nil {
+		return m.CollectionConfig
+	}
+	return nil
+}
+
+// CollectionConfigRecord is the envelope in which the collection config of a chaincode
+// is persisted in the config history db. The metadata of the collection config is to
+// be added as new fields, so that the existing records remain decodable
+type CollectionConfigRecord struct {
+	CollectionConfig     *common.CollectionConfigPackage `protobuf:"bytes,1,opt,name=collection_config,json=collectionConfig,proto3" json:"collection_config,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}                        `json:"-"`
+	XXX_unrecognized     []byte                          `json:"-"`
+	XXX_sizecache        int32                           `json:"-"`
+}
+
+func (m *CollectionConfigRecord) Reset()         { *m = CollectionConfigRecord{} }
+func (m *CollectionConfigRecord) String() string { return proto.CompactTextString(m) }
+func (*CollectionConfigRecord) ProtoMessage()    {}
+func (*CollectionConfigRecord) Descriptor() ([]byte, []int) {
+	return fileDescriptor_config_history_c0c8402c8058244d, []int{1}
+}
+func (m *CollectionConfigRecord) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CollectionConfigRecord.Unmarshal(m, b)
+}
+func (m *CollectionConfigRecord) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CollectionConfigRecord.Marshal(b, m, deterministic)
+}
+func (dst *CollectionConfigRecord) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CollectionConfigRecord.Merge(dst, src)
+}
+func (m *CollectionConfigRecord) XXX_Size() int {
+	return xxx_messageInfo_CollectionConfigRecord.Size(m)
+}
+func (m *CollectionConfigRecord) XXX_DiscardUnknown() {
+	xxx_messageInfo_CollectionConfigRecord.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CollectionConfigRecord proto.InternalMessageInfo
+
+func (m *CollectionConfigRecord) GetCollectionConfig() *common.CollectionConfigPackage {
+	if m != nil {
+		return m.CollectionConfig
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterType((*CollectionConfigVersion)(nil), "confighistory.CollectionConfigVersion")
+	proto.RegisterType((*CollectionConfigRecord)(nil), "confighistory.CollectionConfigRecord")
+}
+
+func init() {
+	proto.RegisterFile("ledger/confighistory/config_history.proto", fileDescriptor_config_history_c0c8402c8058244d)
+}
+
+var fileDescriptor_config_history_c0c8402c8058244d = []byte{
+	// 261 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9c, 0x91, 0x41, 0x4b, 0xc3, 0x40,
+	0x10, 0x85, 0x59, 0x15, 0xb1, 0x2b, 0x15, 0xcd, 0xc1, 0x16, 0x3d, 0x18, 0x0a, 0x42, 0xbc, 0xec,
+	0x42, 0x3d, 0x79, 0xb5, 0x57, 0x29, 0x92, 0x83, 0x07, 0x2f, 0x61, 0x33, 0x99, 0x6c, 0x96, 0x66,
+	0x77, 0xca, 0x26, 0x39, 0xf4, 0x37, 0xf9, 0x27, 0xc5, 0x6c, 0xa8, 0x36, 0xf4, 0xe4, 0x71, 0xde,
+	0xbc, 0xc7, 0x37, 0xbc, 0xe1, 0x4f, 0x35, 0x16, 0x1a, 0xbd, 0x04, 0x72, 0xa5, 0xd1, 0x95, 0x69,
+	0x5a, 0xf2, 0xbb, 0x61, 0xca, 0x86, 0x51, 0x6c, 0x3d, 0xb5, 0x14, 0x4d, 0x0f, 0x3c, 0x77, 0x33,
+	0x20, 0x6b, 0xc9, 0x49, 0xa0, 0xba, 0x46, 0x68, 0x0d, 0xb9, 0xe0, 0x5b, 0x7c, 0x31, 0x3e, 0x5b,
+	0xed, 0xc5, 0x55, 0x1f, 0xfa, 0x40, 0xdf, 0x18, 0x72, 0xd1, 0x23, 0xbf, 0x82, 0x4a, 0x19, 0x07,
+	0x54, 0x60, 0xe6, 0x94, 0xc5, 0x39, 0x8b, 0x59, 0x32, 0x49, 0xa7, 0x7b, 0x75, 0xad, 0x2c, 0x46,
+	0xf7, 0x7c, 0x92, 0xd7, 0x04, 0x9b, 0xcc, 0x75, 0x76, 0x7e, 0x12, 0xb3, 0xe4, 0x2c, 0xbd, 0xe8,
+	0x85, 0x75, 0x67, 0xa3, 0x37, 0x7e, 0xf3, 0xcb, 0xcc, 0xc2, 0x51, 0xf3, 0xd3, 0x98, 0x25, 0x97,
+	0xcb, 0x07, 0x11, 0x8e, 0x12, 0x63, 0xfe, 0xbb, 0x82, 0x8d, 0xd2, 0x98, 0x5e, 0xc3, 0x68, 0xb1,
+	0x28, 0xf9, 0xed, 0xd8, 0x9c, 0x22, 0x90, 0x2f, 0x8e, 0x73, 0xd8, 0x3f, 0x39, 0xaf, 0xc4, 0x97,
+	0xe4, 0xb5, 0xa8, 0x76, 0x5b, 0xf4, 0xa1, 0x73, 0x51, 0xaa, 0xdc, 0x1b, 0x08, 0xad, 0x35, 0x62,
+	0x10, 0x0f, 0x4a, 0xfe, 0x7c, 0xd1, 0xa6, 0xad, 0xba, 0xfc, 0x07, 0x27, 0xff, 0x44, 0x65, 0x88,
+	0xca, 0x10, 0x95, 0xc7, 0x7e, 0x98, 0x9f, 0xf7, 0xcb, 0xe7, 0xef, 0x01, 0x00, 0x31, 0x3c, 0x43,
+	0x84, 0xe2, 0x01, 0x00, 0x00,
+}
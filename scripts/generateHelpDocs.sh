@@ -34,7 +34,7 @@ cat docs/wrappers/peer_chaincode_postscript.md >> $DOC
 DOC=docs/source/commands/peerchannel.md
 cat docs/wrappers/peer_channel_preamble.md > $DOC
 
-for x in "peer channel" "peer channel create" "peer channel fetch" "peer channel getinfo" "peer channel join" "peer channel list" "peer channel signconfigtx" "peer channel update"; do
+for x in "peer channel" "peer channel create" "peer channel fetch" "peer channel getinfo" "peer channel join" "peer channel joinbysnapshot" "peer channel list" "peer channel signconfigtx" "peer channel update"; do
   echo "" >> $DOC
   echo "##" $x >> $DOC
   echo "\`\`\`" >> $DOC
@@ -57,6 +57,19 @@ for x in "peer logging" "peer logging getlevel" "peer logging revertlevels" "pee
 done
 cat docs/wrappers/peer_logging_postscript.md >> $DOC
 
+DOC=docs/source/commands/peersnapshot.md
+cat docs/wrappers/peer_snapshot_preamble.md > $DOC
+
+for x in "peer snapshot" "peer snapshot listpending" "peer snapshot submit"; do
+  echo "" >> $DOC
+  echo "##" $x >> $DOC
+  echo "\`\`\`" >> $DOC
+  .build/bin/${x} --help 1>> $DOC 2>/dev/null
+  echo "\`\`\`" >> $DOC
+  echo "" >> $DOC
+done
+cat docs/wrappers/peer_snapshot_postscript.md >> $DOC
+
 DOC=docs/source/commands/peernode.md
 cat docs/wrappers/peer_node_preamble.md > $DOC
 